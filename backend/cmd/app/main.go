@@ -15,6 +15,7 @@ import (
 	"telegram_webapp/internal/http/middleware"
 	"telegram_webapp/internal/logger"
 	"telegram_webapp/internal/service"
+	"telegram_webapp/internal/ton"
 
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -41,6 +42,14 @@ func main() {
 	defer dbPool.Close()
 
 	r := gin.Default()
+	if cfg.ClientIPHeader != "" {
+		r.TrustedPlatform = cfg.ClientIPHeader
+	}
+	if len(cfg.TrustedProxies) > 0 {
+		if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+			logger.Fatal("invalid TRUSTED_PROXIES", "error", err)
+		}
+	}
 
 	// CORS для прода и связи фронта с бэкендом(разные домены)
 	r.Use(func(c *gin.Context) {
@@ -48,7 +57,7 @@ func main() {
 		if origin != "" {
 			c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
 			c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-			c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Idempotency-Key")
 			c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		}
 		if c.Request.Method == "OPTIONS" {
@@ -58,7 +67,7 @@ func main() {
 		c.Next()
 	})
 
-	middleware.InitRedisRateLimiter("", "", 0)
+	middleware.InitRedisRateLimiter(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB)
 	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
 	httpServer.RegisterRoutesWithConfig(r, dbPool, cfg.BotToken, Version, cfg)
@@ -75,10 +84,36 @@ func main() {
 		}
 	}()
 
+	tonNetwork := ton.NetworkMainnet
+	if os.Getenv("TON_NETWORK") == "testnet" {
+		tonNetwork = ton.NetworkTestnet
+	}
+
+	// Автозачисление депозитов на платформенный кошелёк
+	var depositWatcher *service.DepositWatcher
+	if platformWallet := os.Getenv("TON_PLATFORM_WALLET"); cfg.DepositWatcherEnabled && platformWallet != "" {
+		depositWatcher = service.NewDepositWatcher(dbPool, ton.NewClient(tonNetwork, os.Getenv("TON_API_KEY")), platformWallet, service.DepositWatcherConfig{
+			PollInterval: time.Duration(cfg.DepositPollInterval) * time.Second,
+			PageSize:     cfg.DepositPageSize,
+			Concurrency:  cfg.DepositConcurrency,
+			CoinsPerTON:  cfg.CoinsPerTON,
+		})
+		depositWatcher.OnCredited = service.NewReferralRewardService(dbPool, cfg.ReferralHoldMode, cfg.ReferralHoldGames).CheckQualification
+		depositWatcher.Start()
+		log.Info("deposit watcher started", "address", platformWallet, "page_size", cfg.DepositPageSize, "concurrency", cfg.DepositConcurrency)
+	}
+
 	// Запуск админ бота
 	var adminBot *bot.AdminBot
+	var hotWallet *service.HotWalletMonitor
 	if cfg.AdminBotEnabled && len(cfg.AdminTelegramIDs) > 0 {
 		adminService := service.NewAdminService(dbPool)
+		adminService.SetBonusWagerMultiplier(cfg.BonusWagerMultiplier)
+		adminService.SetStatsTTL(time.Duration(cfg.AdminStatsTTL) * time.Second)
+		adminService.SetDefaultGameLimits(service.GameLimits{
+			MinBet: cfg.MinBet, MaxBet: cfg.MaxBet, MinBetCoins: cfg.MinBetCoins, MaxBetCoins: cfg.MaxBetCoins,
+		})
+		adminService.SetOnGameLimitsChanged(httpServer.ReloadGameLimits)
 		var err error
 		adminBot, err = bot.NewAdminBot(cfg.BotToken, adminService, cfg.AdminTelegramIDs)
 		if err != nil {
@@ -86,9 +121,27 @@ func main() {
 		} else {
 			go adminBot.Start()
 			log.Info("admin bot started", "admin_ids", cfg.AdminTelegramIDs)
-
-			// Уведомление всем админам бота,если запрашивают вывод
-			httpServer.SetWithdrawalNotifyCallback(adminBot.NotifyAdminsNewWithdrawal)
+			adminBot.StartBroadcastScheduler()
+
+			if cfg.AdminDigestEnabled {
+				adminBot.StartDailyDigest(cfg.AdminDigestAt)
+			}
+
+			// Уведомления пользователям и админам (выводы, квесты) идут через бота
+			httpServer.SetNotifier(adminBot)
+
+			// Мониторинг баланса горячего кошелька
+			if cfg.HotWalletAddress != "" {
+				hotWallet = service.NewHotWalletMonitor(
+					ton.NewClient(tonNetwork, os.Getenv("TON_API_KEY")),
+					cfg.HotWalletAddress,
+					ton.TONToNano(cfg.HotWalletMinTON),
+					time.Duration(cfg.HotWalletCheckInterval)*time.Second,
+				)
+				adminBot.SetHotWalletMonitor(hotWallet)
+				hotWallet.Start()
+				log.Info("hot wallet monitor started", "address", cfg.HotWalletAddress, "min_ton", cfg.HotWalletMinTON)
+			}
 		}
 	}
 
@@ -98,6 +151,13 @@ func main() {
 
 	log.Info("shutting down server...")
 
+	if depositWatcher != nil {
+		depositWatcher.Stop()
+	}
+	if hotWallet != nil {
+		hotWallet.Stop()
+	}
+
 	// Graceful shutdown для бота
 	if adminBot != nil {
 		adminBot.Stop()
@@ -106,6 +166,9 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	// Shutdown не закрывает hijacked WS соединения - закрываем их сами с кодом
+	httpServer.ShutdownWS(ctx)
+
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.Fatal("server forced to shutdown", "error", err)
 	}
@@ -2,56 +2,324 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// migrationsLockID - ключ advisory lock на время применения миграций
+const migrationsLockID = 7_420_001
+
+const createSchemaMigrations = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    filename TEXT PRIMARY KEY,
+    checksum TEXT NOT NULL,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+)`
+
+type migration struct {
+	name     string
+	sql      string
+	checksum string
+}
+
+type appliedMigration struct {
+	checksum  string
+	appliedAt time.Time
+}
+
 func main() {
 	dsn := os.Getenv("DATABASE_URL")
 	if dsn == "" {
 		log.Fatal("DATABASE_URL not set")
 	}
 
-	db, err := pgxpool.New(context.Background(), dsn)
+	apply := flag.Bool("apply", false, "apply pending migrations")
+	status := flag.Bool("status", false, "list applied and pending migrations")
+	baseline := flag.Bool("baseline", false, "record all migrations as applied without running them (database already up to date)")
+	flag.Parse()
+
+	ctx := context.Background()
+	db, err := pgxpool.New(ctx, dsn)
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer db.Close()
 
-	apply := flag.Bool("apply", false, "apply migration")
-	flag.Parse()
-
 	migDir := filepath.Join("internal", "migrations")
-	files, err := os.ReadDir(migDir)
+	migrations, err := loadMigrations(migDir)
 	if err != nil {
 		log.Fatalf("read migrations dir: %v", err)
 	}
-	var hasErrors bool
-	for _, f := range files {
-		name := f.Name()
-		if !*apply {
-			fmt.Println(name)
+
+	if !*apply && !*status && !*baseline {
+		for _, m := range migrations {
+			fmt.Println(m.name)
+		}
+		return
+	}
+
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		log.Fatalf("acquire connection: %v", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, createSchemaMigrations); err != nil {
+		log.Fatalf("create schema_migrations: %v", err)
+	}
+
+	switch {
+	case *status:
+		applied, err := loadApplied(ctx, conn.Conn())
+		if err != nil {
+			log.Fatalf("read schema_migrations: %v", err)
+		}
+		printStatus(migrations, applied)
+	case *baseline:
+		n, err := baselineMigrations(ctx, conn.Conn(), migrations)
+		if err != nil {
+			log.Fatalf("ERROR: baseline: %v", err)
+		}
+		fmt.Printf("%d migration(s) recorded as applied without running\n", n)
+	default:
+		n, err := applyPending(ctx, conn.Conn(), migrations)
+		if err != nil {
+			log.Fatalf("ERROR: %v", err)
+		}
+		fmt.Printf("%d migration(s) applied, %d already up to date\n", n, len(migrations)-n)
+	}
+}
+
+// applyPending runs migrations missing from schema_migrations in order and stops
+// at the first failure (the failed file is rolled back). Returns how many ran.
+func applyPending(ctx context.Context, conn *pgx.Conn, migrations []migration) (int, error) {
+	unlock, err := lockMigrations(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	applied, err := loadApplied(ctx, conn)
+	if err != nil {
+		return 0, fmt.Errorf("read schema_migrations: %w", err)
+	}
+	if len(applied) == 0 {
+		legacy, err := hasLegacySchema(ctx, conn)
+		if err != nil {
+			return 0, err
+		}
+		if legacy {
+			return adoptLegacy(ctx, conn, migrations)
+		}
+	}
+
+	var count int
+	for _, m := range migrations {
+		if a, ok := applied[m.name]; ok {
+			if a.checksum != m.checksum {
+				log.Printf("WARNING: %s changed after it was applied (checksum %s, now %s), not re-running", m.name, short(a.checksum), short(m.checksum))
+			}
 			continue
 		}
-		b, err := os.ReadFile(filepath.Join(migDir, name))
+		if err := applyMigration(ctx, conn, m); err != nil {
+			// Следующие миграции могут зависеть от этой - дальше не идём
+			return count, fmt.Errorf("failed to apply %s, rolled back: %w", m.name, err)
+		}
+		fmt.Printf("applied %s\n", m.name)
+		count++
+	}
+	return count, nil
+}
+
+// adoptLegacy starts tracking a database created before schema_migrations
+// existed. Such a database was migrated by re-running every file on each deploy
+// with errors ignored, so it is migrated that way one last time: each file runs
+// in a savepoint, a failing one (already applied earlier) is rolled back and
+// recorded as is. Everything is one transaction, an interrupted adoption is
+// simply repeated on the next run.
+func adoptLegacy(ctx context.Context, conn *pgx.Conn, migrations []migration) (int, error) {
+	log.Printf("schema_migrations is empty but the schema exists: adopting the existing database")
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var count int
+	for _, m := range migrations {
+		sp, err := tx.Begin(ctx)
 		if err != nil {
-			log.Printf("ERROR: read file %s: %v", name, err)
-			hasErrors = true
+			return 0, err
+		}
+		if _, err := sp.Exec(ctx, m.sql); err != nil {
+			_ = sp.Rollback(ctx)
+			log.Printf("%s failed on the existing database, recording it as applied: %v", m.name, err)
+		} else {
+			if err := sp.Commit(ctx); err != nil {
+				return 0, err
+			}
+			count++
+		}
+		if err := recordMigration(ctx, tx, m); err != nil {
+			return 0, fmt.Errorf("record %s: %w", m.name, err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// baselineMigrations records every migration missing from schema_migrations as
+// applied without running it. Returns how many were recorded.
+func baselineMigrations(ctx context.Context, conn *pgx.Conn, migrations []migration) (int, error) {
+	unlock, err := lockMigrations(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	applied, err := loadApplied(ctx, conn)
+	if err != nil {
+		return 0, fmt.Errorf("read schema_migrations: %w", err)
+	}
+	var count int
+	for _, m := range migrations {
+		if _, ok := applied[m.name]; ok {
 			continue
 		}
-		if _, err := db.Exec(context.Background(), string(b)); err != nil {
-			log.Printf("ERROR: failed to apply %s: %v", name, err)
-			hasErrors = true
+		if err := recordMigration(ctx, conn, m); err != nil {
+			return count, fmt.Errorf("record %s: %w", m.name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// lockMigrations takes the session advisory lock, so two instances deploying at
+// once don't apply migrations concurrently
+func lockMigrations(ctx context.Context, conn *pgx.Conn) (func(), error) {
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationsLockID); err != nil {
+		return nil, fmt.Errorf("lock migrations: %w", err)
+	}
+	return func() {
+		_, _ = conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationsLockID)
+	}, nil
+}
+
+// hasLegacySchema reports whether the app tables exist (users is created by 001)
+func hasLegacySchema(ctx context.Context, conn *pgx.Conn) (bool, error) {
+	var exists bool
+	err := conn.QueryRow(ctx, `SELECT to_regclass('users') IS NOT NULL`).Scan(&exists)
+	return exists, err
+}
+
+// loadMigrations reads *.sql files of dir in lexical order
+func loadMigrations(dir string) ([]migration, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var migrations []migration
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".sql") {
 			continue
 		}
-		fmt.Printf("applied %s\n", name)
+		b, err := os.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read file %s: %w", f.Name(), err)
+		}
+		sum := sha256.Sum256(b)
+		migrations = append(migrations, migration{name: f.Name(), sql: string(b), checksum: hex.EncodeToString(sum[:])})
 	}
-	if hasErrors {
-		log.Println("Some migrations failed - check errors above")
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].name < migrations[j].name })
+	return migrations, nil
+}
+
+func loadApplied(ctx context.Context, conn *pgx.Conn) (map[string]appliedMigration, error) {
+	rows, err := conn.Query(ctx, `SELECT filename, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[string]appliedMigration)
+	for rows.Next() {
+		var name string
+		var a appliedMigration
+		if err := rows.Scan(&name, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[name] = a
+	}
+	return applied, rows.Err()
+}
+
+// applyMigration runs one file and records it in the same transaction, so a
+// failing file leaves neither its changes nor a schema_migrations row
+func applyMigration(ctx context.Context, conn *pgx.Conn, m migration) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, m.sql); err != nil {
+		return err
+	}
+	if err := recordMigration(ctx, tx, m); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// execer - *pgx.Conn или pgx.Tx
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// recordMigration marks m as applied
+func recordMigration(ctx context.Context, db execer, m migration) error {
+	_, err := db.Exec(ctx,
+		`INSERT INTO schema_migrations (filename, checksum) VALUES ($1, $2)`,
+		m.name, m.checksum,
+	)
+	return err
+}
+
+func printStatus(migrations []migration, applied map[string]appliedMigration) {
+	var pending int
+	for _, m := range migrations {
+		a, ok := applied[m.name]
+		switch {
+		case !ok:
+			pending++
+			fmt.Printf("pending  %s\n", m.name)
+		case a.checksum != m.checksum:
+			fmt.Printf("changed  %s (applied %s)\n", m.name, a.appliedAt.Format(time.RFC3339))
+		default:
+			fmt.Printf("applied  %s (%s)\n", m.name, a.appliedAt.Format(time.RFC3339))
+		}
+	}
+	fmt.Printf("%d applied, %d pending\n", len(migrations)-pending, pending)
+}
+
+func short(checksum string) string {
+	if len(checksum) > 12 {
+		return checksum[:12]
 	}
+	return checksum
 }
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// testConn connects to TEST_DATABASE_URL with search_path set to a fresh schema,
+// so migrations run against an empty database
+func testConn(t *testing.T) (context.Context, *pgx.Conn) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set; skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	t.Cleanup(cancel)
+
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	schema := fmt.Sprintf("migrate_test_%d", time.Now().UnixNano())
+	if _, err := conn.Exec(ctx, `CREATE SCHEMA `+schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		_, _ = conn.Exec(context.Background(), `DROP SCHEMA `+schema+` CASCADE`)
+		_ = conn.Close(context.Background())
+	})
+	if _, err := conn.Exec(ctx, `SET search_path TO `+schema); err != nil {
+		t.Fatalf("set search_path: %v", err)
+	}
+	if _, err := conn.Exec(ctx, createSchemaMigrations); err != nil {
+		t.Fatalf("create schema_migrations: %v", err)
+	}
+	return ctx, conn
+}
+
+func loadTestMigrations(t *testing.T) []migration {
+	migrations, err := loadMigrations(filepath.Join("..", "..", "internal", "migrations"))
+	if err != nil || len(migrations) == 0 {
+		t.Fatalf("load migrations: %d, %v", len(migrations), err)
+	}
+	return migrations
+}
+
+func countRows(t *testing.T, ctx context.Context, conn *pgx.Conn, table string) int {
+	var n int
+	if err := conn.QueryRow(ctx, `SELECT COUNT(*) FROM `+table).Scan(&n); err != nil {
+		t.Fatalf("count %s: %v", table, err)
+	}
+	return n
+}
+
+// База, которую старый migrate_apply довёл до 017 (есть quests_unique_definition):
+// первый -apply должен взять её под учёт, а не упасть на повторном сиде 006
+func TestApplyAdoptsDatabaseMigratedWithoutTracking(t *testing.T) {
+	ctx, conn := testConn(t)
+	migrations := loadTestMigrations(t)
+
+	// Старый инструмент: каждый файл при каждом деплое, ошибки игнорируются
+	for deploy := 0; deploy < 2; deploy++ {
+		for _, m := range migrations {
+			if m.name > "017" {
+				break
+			}
+			_, _ = conn.Exec(ctx, m.sql)
+		}
+	}
+	quests := countRows(t, ctx, conn, "quests")
+
+	if _, err := applyPending(ctx, conn, migrations); err != nil {
+		t.Fatalf("first tracked apply: %v", err)
+	}
+	if got := countRows(t, ctx, conn, "schema_migrations"); got != len(migrations) {
+		t.Fatalf("expected all %d files recorded, got %d", len(migrations), got)
+	}
+	if got := countRows(t, ctx, conn, "quests"); got != quests {
+		t.Fatalf("seed quests must not be duplicated: %d before, %d after", quests, got)
+	}
+	// Файлы после 017 действительно применены
+	var hasColumn bool
+	if err := conn.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'users' AND column_name = 'last_bonus_at')
+	`).Scan(&hasColumn); err != nil || !hasColumn {
+		t.Fatalf("expected later migrations to run (users.last_bonus_at), err=%v", err)
+	}
+
+	n, err := applyPending(ctx, conn, migrations)
+	if err != nil || n != 0 {
+		t.Fatalf("second apply must be a no-op, applied %d, err=%v", n, err)
+	}
+}
+
+func TestApplyStopsAtFailingMigration(t *testing.T) {
+	ctx, conn := testConn(t)
+	migrations := loadTestMigrations(t)
+
+	if n, err := applyPending(ctx, conn, migrations); err != nil || n != len(migrations) {
+		t.Fatalf("fresh database: applied %d of %d, err=%v", n, len(migrations), err)
+	}
+
+	broken := []migration{
+		{name: "900_broken.sql", sql: `CREATE TABLE broken_part (id INT); SELECT * FROM no_such_table;`, checksum: "x"},
+		{name: "901_after.sql", sql: `CREATE TABLE after_broken (id INT);`, checksum: "y"},
+	}
+	if _, err := applyPending(ctx, conn, append(migrations, broken...)); err == nil {
+		t.Fatal("expected the broken migration to fail")
+	}
+	var exists bool
+	_ = conn.QueryRow(ctx, `SELECT to_regclass('broken_part') IS NOT NULL OR to_regclass('after_broken') IS NOT NULL`).Scan(&exists)
+	if exists {
+		t.Fatal("failed file must be rolled back and later files not run")
+	}
+	if got := countRows(t, ctx, conn, "schema_migrations"); got != len(migrations) {
+		t.Fatalf("failed file must not be recorded, got %d rows", got)
+	}
+}
+
+func TestBaselineRecordsWithoutRunning(t *testing.T) {
+	ctx, conn := testConn(t)
+
+	ms := []migration{{name: "001_x.sql", sql: `CREATE TABLE baseline_x (id INT);`, checksum: "x"}}
+	if n, err := baselineMigrations(ctx, conn, ms); err != nil || n != 1 {
+		t.Fatalf("baseline: %d, %v", n, err)
+	}
+	var exists bool
+	_ = conn.QueryRow(ctx, `SELECT to_regclass('baseline_x') IS NOT NULL`).Scan(&exists)
+	if exists {
+		t.Fatal("baseline must not run the file")
+	}
+	if n, err := applyPending(ctx, conn, ms); err != nil || n != 0 {
+		t.Fatalf("baselined file must be skipped, applied %d, err=%v", n, err)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"telegram_webapp/internal/domain"
 	"telegram_webapp/internal/repository"
 	"telegram_webapp/internal/service"
+	"telegram_webapp/internal/ws"
 )
 
 func main() {
@@ -65,19 +66,20 @@ func main() {
         log.Fatalf("gen token B: %v", err)
     }
 
-    dialer := websocket.DefaultDialer
+    // token goes in Sec-WebSocket-Protocol, not in the URL
+    dialerA := websocket.Dialer{Subprotocols: []string{ws.TokenSubprotocol, tokenA}}
+    dialerB := websocket.Dialer{Subprotocols: []string{ws.TokenSubprotocol, tokenB}}
 
     // use 127.0.0.1 to prefer IPv4 (avoid resolving to [::1])
-    wsURLA := fmt.Sprintf("ws://127.0.0.1:%s/ws?token=%s", port, tokenA)
-    wsURLB := fmt.Sprintf("ws://127.0.0.1:%s/ws?token=%s", port, tokenB)
+    wsURL := fmt.Sprintf("ws://127.0.0.1:%s/ws", port)
 
-    connA, _, err := dialer.Dial(wsURLA, nil)
+    connA, _, err := dialerA.Dial(wsURL, nil)
     if err != nil {
         log.Fatalf("dial A: %v", err)
     }
     defer connA.Close()
 
-    connB, _, err := dialer.Dial(wsURLB, nil)
+    connB, _, err := dialerB.Dial(wsURL, nil)
     if err != nil {
         log.Fatalf("dial B: %v", err)
     }
@@ -2,22 +2,29 @@ package bot
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
 	"log/slog"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"telegram_webapp/internal/domain"
 	"telegram_webapp/internal/logger"
+	"telegram_webapp/internal/repository"
 	"telegram_webapp/internal/service"
+	"telegram_webapp/internal/ton"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/jackc/pgx/v5"
 )
 
 // QuestCreationState tracks the state of quest creation wizard
 type QuestCreationState struct {
-	Step        int    // 1=title, 2=type, 3=action, 4=target, 5=reward
+	Step        int // 1=title, 2=type, 3=action, 4=target, 5=reward
 	Title       string
 	QuestType   string
 	ActionType  string
@@ -26,14 +33,19 @@ type QuestCreationState struct {
 
 // AdminBot handles admin commands via Telegram
 type AdminBot struct {
-	bot              *tgbotapi.BotAPI
-	adminService     *service.AdminService
-	adminIDs         []int64 // Telegram user IDs who can use admin commands
-	stopCh           chan struct{}
-	wg               sync.WaitGroup
-	log              *slog.Logger
-	broadcastPending map[int64]bool                  // Track admins waiting to enter broadcast message
-	questCreation    map[int64]*QuestCreationState   // Track quest creation state per admin
+	bot             *tgbotapi.BotAPI
+	adminService    *service.AdminService
+	adminsMu        sync.RWMutex
+	adminIDs        []int64        // Telegram user IDs who can use admin commands
+	envAdmins       map[int64]bool // из ADMIN_TELEGRAM_IDS, через /removeadmin не удаляются
+	stopCh          chan struct{}
+	wg              sync.WaitGroup
+	log             *slog.Logger
+	broadcastMu     sync.Mutex
+	broadcastDrafts map[int64]*broadcastDraft     // Track admins composing a broadcast
+	broadcastRuns   map[int64]*runningBroadcast   // Broadcasts being sent by this process, by ID
+	questCreation   map[int64]*QuestCreationState // Track quest creation state per admin
+	hotWallet       *service.HotWalletMonitor     // nil if hot wallet monitoring is disabled
 }
 
 // NewAdminBot creates a new admin bot
@@ -46,15 +58,35 @@ func NewAdminBot(token string, adminService *service.AdminService, adminIDs []in
 	log := logger.With("component", "admin_bot")
 	log.Info("admin bot authorized", "username", bot.Self.UserName)
 
-	return &AdminBot{
-		bot:              bot,
-		adminService:     adminService,
-		adminIDs:         adminIDs,
-		stopCh:           make(chan struct{}),
-		log:              log,
-		broadcastPending: make(map[int64]bool),
-		questCreation:    make(map[int64]*QuestCreationState),
-	}, nil
+	b := &AdminBot{
+		bot:             bot,
+		adminService:    adminService,
+		envAdmins:       make(map[int64]bool, len(adminIDs)),
+		stopCh:          make(chan struct{}),
+		log:             log,
+		broadcastDrafts: make(map[int64]*broadcastDraft),
+		broadcastRuns:   make(map[int64]*runningBroadcast),
+		questCreation:   make(map[int64]*QuestCreationState),
+	}
+	for _, id := range adminIDs {
+		b.envAdmins[id] = true
+		b.adminIDs = append(b.adminIDs, id)
+	}
+
+	// Админы, добавленные через /addadmin; без БД бот работает с админами из env
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stored, err := adminService.ListAdmins(ctx)
+	if err != nil {
+		log.Error("failed to load admins", "error", err)
+	}
+	for _, id := range stored {
+		if !b.envAdmins[id] {
+			b.adminIDs = append(b.adminIDs, id)
+		}
+	}
+
+	return b, nil
 }
 
 // Start starts listening for commands
@@ -84,12 +116,12 @@ func (b *AdminBot) Start() {
 				continue
 			}
 
-			// Check if admin is in broadcast mode (waiting for message)
-			if b.broadcastPending[update.Message.From.ID] && !update.Message.IsCommand() {
+			// Check if admin is composing a broadcast (segment, time, message)
+			if b.broadcastDraft(update.Message.From.ID) != nil && (!update.Message.IsCommand() || update.Message.Command() == "cancel") {
 				b.wg.Add(1)
 				go func(msg *tgbotapi.Message) {
 					defer b.wg.Done()
-					b.executeBroadcast(msg)
+					b.handleBroadcastStep(msg)
 				}(update.Message)
 				continue
 			}
@@ -140,6 +172,8 @@ func (b *AdminBot) Stop() {
 
 // isAdmin checks if user is an admin
 func (b *AdminBot) isAdmin(userID int64) bool {
+	b.adminsMu.RLock()
+	defer b.adminsMu.RUnlock()
 	for _, id := range b.adminIDs {
 		if id == userID {
 			return true
@@ -148,6 +182,13 @@ func (b *AdminBot) isAdmin(userID int64) bool {
 	return false
 }
 
+// admins returns a copy of the current admin list
+func (b *AdminBot) admins() []int64 {
+	b.adminsMu.RLock()
+	defer b.adminsMu.RUnlock()
+	return append([]int64(nil), b.adminIDs...)
+}
+
 // handleCommand processes admin commands
 func (b *AdminBot) handleCommand(msg *tgbotapi.Message) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -160,22 +201,31 @@ func (b *AdminBot) handleCommand(msg *tgbotapi.Message) {
 		response = b.helpMessage()
 
 	case "stats":
-		response = b.handleStats(ctx)
+		response = b.handleStats(ctx, false)
+
+	case "refreshstats":
+		response = b.handleStats(ctx, true)
+
+	case "digest":
+		response = b.handleDigest(ctx)
 
 	case "user":
 		response = b.handleUser(ctx, msg.CommandArguments())
 
 	case "addgems":
-		response = b.handleAddGems(ctx, msg.CommandArguments())
+		response = b.handleAddGems(ctx, msg.From.ID, msg.CommandArguments())
 
 	case "setgems":
-		response = b.handleSetGems(ctx, msg.CommandArguments())
+		response = b.handleSetGems(ctx, msg.From.ID, msg.CommandArguments())
 
 	case "ban":
-		response = b.handleBan(ctx, msg.CommandArguments())
+		response = b.handleBan(ctx, msg.From.ID, msg.CommandArguments())
 
 	case "unban":
-		response = b.handleUnban(ctx, msg.CommandArguments())
+		response = b.handleUnban(ctx, msg.From.ID, msg.CommandArguments())
+
+	case "exclude":
+		response = b.handleExclude(ctx, msg.From.ID, msg.CommandArguments())
 
 	case "top":
 		response = b.handleTop(ctx, msg.CommandArguments())
@@ -186,33 +236,70 @@ func (b *AdminBot) handleCommand(msg *tgbotapi.Message) {
 	case "withdrawals":
 		response = b.handleWithdrawals(ctx)
 
+	case "withdrawals_status":
+		response = b.handleWithdrawalsStatus(ctx)
+
 	case "approve":
-		response = b.handleApproveWithdrawal(ctx, msg.CommandArguments())
+		response = b.handleApproveWithdrawal(ctx, msg.From.ID, msg.CommandArguments())
 
 	case "reject":
-		response = b.handleRejectWithdrawal(ctx, msg.CommandArguments())
+		response = b.handleRejectWithdrawal(ctx, msg.From.ID, msg.CommandArguments())
 
 	case "broadcast":
-		response = b.handleBroadcastStart(msg.Chat.ID, msg.From.ID)
+		response = b.handleBroadcastStart(msg.From.ID)
+
+	case "cancel":
+		if n := b.cancelRunningBroadcasts(msg.From.ID, 0); n > 0 {
+			response = fmt.Sprintf("Останавливаю рассылок: %d, итоги придут отдельным сообщением", n)
+		} else {
+			response = "Нечего отменять"
+		}
+
+	case "broadcasts":
+		response = b.handleBroadcasts(ctx, msg.CommandArguments())
+
+	case "cancelbroadcast":
+		response = b.handleCancelBroadcast(ctx, msg.From.ID, msg.CommandArguments())
 
 	case "users":
 		response = b.handleUsers(ctx, msg.CommandArguments())
 
+	case "find":
+		response = b.handleFind(ctx, msg.CommandArguments())
+
 	case "usergames":
 		response = b.handleUserGames(ctx, msg.CommandArguments())
 
 	case "topusergames":
 		response = b.handleTopUserGames(ctx, msg.CommandArguments())
 
+	case "gamestats":
+		response = b.handleGameStats(ctx)
+
 	case "addcoins":
-		response = b.handleAddCoins(ctx, msg.CommandArguments())
+		response = b.handleAddCoins(ctx, msg.From.ID, msg.CommandArguments())
+
+	case "addgk":
+		response = b.handleAddGK(ctx, msg.From.ID, msg.CommandArguments())
 
 	case "addadmin":
-		response = b.handleAddAdmin(msg.CommandArguments())
+		response = b.handleAddAdmin(ctx, msg.From.ID, msg.CommandArguments())
+
+	case "removeadmin":
+		response = b.handleRemoveAdmin(ctx, msg.From.ID, msg.CommandArguments())
 
 	case "referrals":
 		response = b.handleReferralStats(ctx, msg.CommandArguments())
 
+	case "reports":
+		response = b.handleReports(ctx, msg.CommandArguments())
+
+	case "auditlog":
+		response = b.handleAuditLog(ctx, msg.CommandArguments())
+
+	case "suspicious":
+		response = b.handleSuspicious(ctx, msg.CommandArguments())
+
 	case "checkquests":
 		response = b.handleCheckQuests(ctx)
 
@@ -220,10 +307,16 @@ func (b *AdminBot) handleCommand(msg *tgbotapi.Message) {
 		response = b.handleNewQuest(msg.From.ID)
 
 	case "deletequest":
-		response = b.handleDeleteQuest(ctx, msg.CommandArguments())
+		response = b.handleDeleteQuest(ctx, msg.From.ID, msg.CommandArguments())
 
 	case "togglequest":
-		response = b.handleToggleQuest(ctx, msg.CommandArguments())
+		response = b.handleToggleQuest(ctx, msg.From.ID, msg.CommandArguments())
+
+	case "setlimits":
+		response = b.handleSetLimits(ctx, msg.From.ID, msg.CommandArguments())
+
+	case "checklimits":
+		response = b.handleCheckLimits(ctx)
 
 	default:
 		response = "❌ Неизвестная команда. Используйте /help для списка команд."
@@ -242,21 +335,30 @@ func (b *AdminBot) helpMessage() string {
 	return `<b>🤖 Команды администратора</b>
 
 <b>📊 Статистика:</b>
-/stats - Статистика платформы
+/stats - Статистика платформы (кэшируется)
+/refreshstats - Пересчитать статистику сейчас
+/digest - Сводка за вчера с изменением к позавчера
 /top [лимит] - Топ пользователей по гемам
 /games - Последние игры
-/usergames &lt;@username|tg_id&gt; - Последние 10 игр пользователя
+/usergames &lt;tg_id&gt; [pvp|pve] - Последние 10 игр пользователя
 /topusergames [лимит] - Топ по победам в играх
+/gamestats - Прибыль и RTP по играм (без PvP)
 /referrals [лимит] - Топ по рефералам
+/reports [лимит] - Жалобы пользователей на рассинхрон
+/auditlog [лимит] - Последние действия админов
+/suspicious [лимит] - Аккаунты, зарегистрированные с одного IP
 
 <b>👤 Управление пользователями:</b>
 /user &lt;@username|tg_id&gt; - Информация о пользователе
 /users [страница] - Все пользователи
+/find &lt;запрос&gt; - Поиск по части username или имени
 /addgems &lt;@username|tg_id&gt; &lt;сумма&gt; - Добавить гемы
 /addcoins &lt;@username|tg_id&gt; &lt;сумма&gt; - Добавить коины
+/addgk &lt;@username|tg_id&gt; &lt;сумма&gt; - Добавить GK
 /setgems &lt;@username|tg_id&gt; &lt;сумма&gt; - Установить гемы
 /ban &lt;@username|tg_id&gt; - Заблокировать
 /unban &lt;@username|tg_id&gt; - Разблокировать
+/exclude &lt;@username|tg_id&gt; &lt;дней&gt; - Закрыть игры и вывод на N дней (0 - снять)
 
 <b>📋 Управление квестами:</b>
 /checkquests - Список всех квестов
@@ -264,20 +366,34 @@ func (b *AdminBot) helpMessage() string {
 /deletequest &lt;id&gt; - Удалить квест
 /togglequest &lt;id&gt; - Вкл/выкл квест
 
+<b>🎰 Лимиты ставок:</b>
+/checklimits - Текущие лимиты по играм
+/setlimits &lt;игра&gt; &lt;мин&gt; &lt;макс&gt; [gems|coins] - Лимиты игры
+
 <b>🔐 Управление админами:</b>
 /addadmin &lt;tg_id&gt; - Добавить админа
+/removeadmin &lt;tg_id&gt; - Удалить админа
 
 <b>💸 Выводы:</b>
 /withdrawals - Ожидающие выводы
+/withdrawals_status - Очередь выводов и баланс горячего кошелька
 /approve &lt;id&gt; [tx_hash] - Одобрить вывод
 /reject &lt;id&gt; &lt;причина&gt; - Отклонить вывод
 
 <b>📢 Рассылка:</b>
-/broadcast - Отправить сообщение всем (фото, кнопки)`
+/broadcast - Рассылка: сегмент, время, сообщение (фото, кнопки)
+/broadcasts [лимит] - Последние рассылки и итоги доставки
+/cancelbroadcast &lt;id&gt; - Отменить запланированную рассылку`
 }
 
-func (b *AdminBot) handleStats(ctx context.Context) string {
-	stats, err := b.adminService.GetStats(ctx)
+func (b *AdminBot) handleStats(ctx context.Context, refresh bool) string {
+	var stats *service.Stats
+	var err error
+	if refresh {
+		stats, err = b.adminService.RefreshStats(ctx)
+	} else {
+		stats, err = b.adminService.GetStats(ctx)
+	}
 	if err != nil {
 		return fmt.Sprintf("Ошибка: %v", err)
 	}
@@ -286,6 +402,7 @@ func (b *AdminBot) handleStats(ctx context.Context) string {
 
 <b>Пользователи:</b>
 - Всего: %d
+- Заблокировано: %d
 - Активных сегодня: %d
 - Активных за неделю: %d
 
@@ -308,8 +425,11 @@ func (b *AdminBot) handleStats(ctx context.Context) string {
 <b>Платежи:</b>
 - Всего депозитов: %d
 - Всего выведено: %d
-- Ожидает вывода: %d`,
+- Ожидает вывода: %d
+
+<i>Обновлено: %s (%s назад)</i>`,
 		stats.TotalUsers,
+		stats.BannedUsers,
 		stats.ActiveUsersToday,
 		stats.ActiveUsersWeek,
 		stats.TotalGamesPlayed,
@@ -325,6 +445,8 @@ func (b *AdminBot) handleStats(ctx context.Context) string {
 		stats.TotalDeposited,
 		stats.TotalWithdrawn,
 		stats.PendingWithdraws,
+		stats.GeneratedAt.Format("15:04:05"),
+		time.Since(stats.GeneratedAt).Round(time.Second),
 	)
 }
 
@@ -363,15 +485,15 @@ func (b *AdminBot) handleUser(ctx context.Context, args string) string {
 	)
 }
 
-func (b *AdminBot) handleAddGems(ctx context.Context, args string) string {
+func (b *AdminBot) handleAddGems(ctx context.Context, adminID int64, args string) string {
 	parts := strings.Fields(args)
 	if len(parts) != 2 {
 		return "Использование: /addgems <@username|tg_id> <сумма>"
 	}
 
-	userID, err := strconv.ParseInt(parts[0], 10, 64)
-	if err != nil {
-		return "Неверный ID пользователя"
+	userID, errMsg := b.resolveUser(ctx, parts[0])
+	if errMsg != "" {
+		return errMsg
 	}
 
 	amount, err := strconv.ParseInt(parts[1], 10, 64)
@@ -379,23 +501,23 @@ func (b *AdminBot) handleAddGems(ctx context.Context, args string) string {
 		return "Неверная сумма"
 	}
 
-	newBalance, err := b.adminService.AddUserGems(ctx, userID, amount)
+	newBalance, err := b.adminService.AddUserGems(ctx, adminID, userID, amount)
 	if err != nil {
 		return fmt.Sprintf("Ошибка: %v", err)
 	}
 
-	return fmt.Sprintf("Добавлено %d гемов пользователю %d. Новый баланс: %d", amount, userID, newBalance)
+	return fmt.Sprintf("Добавлено %d гемов пользователю %s. Новый баланс: %d", amount, html.EscapeString(parts[0]), newBalance)
 }
 
-func (b *AdminBot) handleSetGems(ctx context.Context, args string) string {
+func (b *AdminBot) handleSetGems(ctx context.Context, adminID int64, args string) string {
 	parts := strings.Fields(args)
 	if len(parts) != 2 {
 		return "Использование: /setgems <@username|tg_id> <сумма>"
 	}
 
-	userID, err := strconv.ParseInt(parts[0], 10, 64)
-	if err != nil {
-		return "Неверный ID пользователя"
+	userID, errMsg := b.resolveUser(ctx, parts[0])
+	if errMsg != "" {
+		return errMsg
 	}
 
 	amount, err := strconv.ParseInt(parts[1], 10, 64)
@@ -403,45 +525,72 @@ func (b *AdminBot) handleSetGems(ctx context.Context, args string) string {
 		return "Неверная сумма"
 	}
 
-	if err := b.adminService.SetUserGems(ctx, userID, amount); err != nil {
+	if err := b.adminService.SetUserGems(ctx, adminID, userID, amount); err != nil {
 		return fmt.Sprintf("Ошибка: %v", err)
 	}
 
-	return fmt.Sprintf("Установлено %d гемов пользователю %d", amount, userID)
+	return fmt.Sprintf("Установлено %d гемов пользователю %s", amount, html.EscapeString(parts[0]))
 }
 
-func (b *AdminBot) handleBan(ctx context.Context, args string) string {
+func (b *AdminBot) handleBan(ctx context.Context, adminID int64, args string) string {
 	if args == "" {
 		return "Использование: /ban <@username|tg_id>"
 	}
 
-	userID, err := strconv.ParseInt(args, 10, 64)
-	if err != nil {
-		return "Неверный ID пользователя"
+	userID, errMsg := b.resolveUser(ctx, args)
+	if errMsg != "" {
+		return errMsg
 	}
 
-	if err := b.adminService.BanUser(ctx, userID); err != nil {
+	if err := b.adminService.BanUser(ctx, adminID, userID); err != nil {
 		return fmt.Sprintf("Ошибка: %v", err)
 	}
 
-	return fmt.Sprintf("Пользователь %d заблокирован", userID)
+	return fmt.Sprintf("Пользователь %s заблокирован", html.EscapeString(args))
 }
 
-func (b *AdminBot) handleUnban(ctx context.Context, args string) string {
+func (b *AdminBot) handleUnban(ctx context.Context, adminID int64, args string) string {
 	if args == "" {
 		return "Использование: /unban <@username|tg_id>"
 	}
 
-	userID, err := strconv.ParseInt(args, 10, 64)
-	if err != nil {
-		return "Неверный ID пользователя"
+	userID, errMsg := b.resolveUser(ctx, args)
+	if errMsg != "" {
+		return errMsg
 	}
 
-	if err := b.adminService.UnbanUser(ctx, userID); err != nil {
+	if err := b.adminService.UnbanUser(ctx, adminID, userID); err != nil {
 		return fmt.Sprintf("Ошибка: %v", err)
 	}
 
-	return fmt.Sprintf("Пользователь %d разблокирован", userID)
+	return fmt.Sprintf("Пользователь %s разблокирован", html.EscapeString(args))
+}
+
+func (b *AdminBot) handleExclude(ctx context.Context, adminID int64, args string) string {
+	parts := strings.Fields(args)
+	if len(parts) != 2 {
+		return "Использование: /exclude <@username|tg_id> <дней>"
+	}
+
+	userID, errMsg := b.resolveUser(ctx, parts[0])
+	if errMsg != "" {
+		return errMsg
+	}
+
+	days, err := strconv.Atoi(parts[1])
+	if err != nil || days < 0 || days > 365 {
+		return "Неверное число дней (0-365)"
+	}
+
+	until, err := b.adminService.SetUserExclusion(ctx, adminID, userID, days)
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+	if until == nil {
+		return fmt.Sprintf("Исключение пользователя %s снято", html.EscapeString(parts[0]))
+	}
+
+	return fmt.Sprintf("Пользователь %s исключён из игр и вывода до %s", html.EscapeString(parts[0]), until.Format("02.01.2006 15:04"))
 }
 
 func (b *AdminBot) handleTop(ctx context.Context, args string) string {
@@ -509,6 +658,111 @@ func (b *AdminBot) handleRecentGames(ctx context.Context) string {
 	return sb.String()
 }
 
+func (b *AdminBot) handleReports(ctx context.Context, args string) string {
+	limit := 10
+	if args != "" {
+		if n, err := strconv.Atoi(args); err == nil && n > 0 && n <= 50 {
+			limit = n
+		}
+	}
+
+	reports, err := b.adminService.GetRecentSupportReports(ctx, limit)
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+
+	if len(reports) == 0 {
+		return "Нет жалоб"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<b>Жалобы на рассинхрон</b>\n\n")
+
+	for _, r := range reports {
+		sb.WriteString(fmt.Sprintf("#%d | @%s (id %d) | %s | %s\n",
+			r.ID, html.EscapeString(r.Username), r.UserID, r.Category, r.CreatedAt.Format("02.01.2006 15:04")))
+		if r.Message != "" {
+			sb.WriteString(fmt.Sprintf("%s\n", html.EscapeString(truncate(r.Message, 200))))
+		}
+		sb.WriteString(fmt.Sprintf("Сервер: <code>%s</code>\n", html.EscapeString(string(r.ServerState))))
+		if len(r.ClientState) > 0 {
+			sb.WriteString(fmt.Sprintf("Клиент: <code>%s</code>\n", html.EscapeString(truncate(string(r.ClientState), 300))))
+		}
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+func (b *AdminBot) handleAuditLog(ctx context.Context, args string) string {
+	limit := 20
+	if args != "" {
+		if n, err := strconv.Atoi(args); err == nil && n > 0 && n <= 100 {
+			limit = n
+		}
+	}
+
+	actions, err := b.adminService.GetAuditLog(ctx, limit)
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+
+	if len(actions) == 0 {
+		return "Журнал действий пуст"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<b>Действия админов</b>\n\n")
+
+	for _, a := range actions {
+		sb.WriteString(fmt.Sprintf("%s | admin %d | <b>%s</b>",
+			a.CreatedAt.Format("02.01.2006 15:04"), a.AdminTgID, html.EscapeString(a.Action)))
+		if a.TargetUserID != 0 {
+			sb.WriteString(fmt.Sprintf(" | user id %d", a.TargetUserID))
+		}
+		sb.WriteString("\n")
+		if len(a.Details) > 0 {
+			details, _ := json.Marshal(a.Details)
+			sb.WriteString(fmt.Sprintf("<code>%s</code>\n", html.EscapeString(truncate(string(details), 200))))
+		}
+	}
+
+	return sb.String()
+}
+
+func (b *AdminBot) handleSuspicious(ctx context.Context, args string) string {
+	limit := 20
+	if args != "" {
+		if n, err := strconv.Atoi(args); err == nil && n > 0 && n <= 100 {
+			limit = n
+		}
+	}
+
+	signups, err := b.adminService.GetSuspiciousSignups(ctx, limit)
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+
+	if len(signups) == 0 {
+		return "Подозрительных регистраций нет"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<b>Регистрации с общего IP</b>\n\n")
+
+	for _, sg := range signups {
+		sb.WriteString(fmt.Sprintf("%s | <b>%s</b> (tg <code>%d</code>, id %d)\n",
+			sg.CreatedAt.Format("02.01.2006 15:04"), html.EscapeString(sg.Username), sg.TgID, sg.UserID))
+		sb.WriteString(fmt.Sprintf("   IP <code>%s</code>, ещё %d акк. | 💎 %d", html.EscapeString(sg.IP), sg.SharedIPCount, sg.Gems))
+		if sg.Banned {
+			sb.WriteString(" | 🚫 забанен")
+		}
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
 func (b *AdminBot) handleWithdrawals(ctx context.Context) string {
 	withdrawals, err := b.adminService.GetPendingWithdrawals(ctx)
 	if err != nil {
@@ -524,7 +778,7 @@ func (b *AdminBot) handleWithdrawals(ctx context.Context) string {
 
 	for _, w := range withdrawals {
 		sb.WriteString(fmt.Sprintf("#%d | @%s\n", w.ID, w.Username))
-		sb.WriteString(fmt.Sprintf("Сумма: %d coins (%s)\n", w.GemsAmount, w.TonAmount))
+		sb.WriteString(fmt.Sprintf("Сумма: %d coins (%s)\n", w.CoinsAmount, w.TonAmount))
 		sb.WriteString(fmt.Sprintf("Кошелёк: <code>%s</code>\n", w.WalletAddress))
 		sb.WriteString(fmt.Sprintf("%s\n\n", w.CreatedAt.Format("02.01.2006 15:04")))
 	}
@@ -534,7 +788,42 @@ func (b *AdminBot) handleWithdrawals(ctx context.Context) string {
 	return sb.String()
 }
 
-func (b *AdminBot) handleApproveWithdrawal(ctx context.Context, args string) string {
+func (b *AdminBot) handleWithdrawalsStatus(ctx context.Context) string {
+	withdrawals, err := b.adminService.GetPendingWithdrawals(ctx)
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<b>Статус выводов</b>\n\n")
+	sb.WriteString(fmt.Sprintf("Ожидают: %d\n\n", len(withdrawals)))
+
+	if b.hotWallet == nil {
+		sb.WriteString("Мониторинг горячего кошелька выключен")
+		return sb.String()
+	}
+
+	st := b.hotWallet.Status()
+	sb.WriteString(fmt.Sprintf("Кошелёк: <code>%s</code>\n", st.Address))
+	if st.CheckedAt.IsZero() {
+		sb.WriteString("Баланс: ещё не проверялся\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("Баланс: %.4f TON (порог %.4f TON)\n", ton.NanoToTON(st.BalanceNano), ton.NanoToTON(st.ThresholdNano)))
+		sb.WriteString(fmt.Sprintf("Проверен: %s\n", st.CheckedAt.Format("02.01.2006 15:04:05")))
+	}
+	if st.LastError != "" {
+		sb.WriteString(fmt.Sprintf("Ошибка проверки: %s\n", st.LastError))
+	}
+	if st.PayoutsPaused {
+		sb.WriteString("\n⏸ Выплаты приостановлены до пополнения: /approve только с tx_hash отправленной транзакции")
+	} else {
+		sb.WriteString("\n▶️ Выплаты активны")
+	}
+
+	return sb.String()
+}
+
+func (b *AdminBot) handleApproveWithdrawal(ctx context.Context, adminID int64, args string) string {
 	parts := strings.Fields(args)
 	if len(parts) < 1 {
 		return "Использование: /approve <id> [tx_hash]"
@@ -548,21 +837,28 @@ func (b *AdminBot) handleApproveWithdrawal(ctx context.Context, args string) str
 	txHash := ""
 	if len(parts) >= 2 {
 		txHash = parts[1]
+	} else if b.hotWallet.PayoutsPaused() {
+		// С почти пустого кошелька вывод не отправить; подтверждаем только уже отправленный
+		return "Выплаты приостановлены: баланс горячего кошелька ниже порога.\nПополните кошелёк или укажите tx_hash: /approve <id> <tx_hash>"
 	} else {
 		txHash = fmt.Sprintf("manual_%d_%d", id, time.Now().Unix())
 	}
 
-	if err := b.adminService.ApproveWithdrawal(ctx, id, txHash); err != nil {
+	if err := b.adminService.ApproveWithdrawal(ctx, adminID, id, txHash); err != nil {
 		return fmt.Sprintf("Ошибка: %v", err)
 	}
 
+	if w, err := b.adminService.GetWithdrawalNotification(ctx, id); err == nil {
+		service.NotifyWithdrawalApproved(ctx, b, w)
+	}
+
 	if len(parts) >= 2 {
 		return fmt.Sprintf("Вывод #%d одобрен\nТранзакция: %s", id, txHash)
 	}
 	return fmt.Sprintf("Вывод #%d одобрен (ручное подтверждение)", id)
 }
 
-func (b *AdminBot) handleRejectWithdrawal(ctx context.Context, args string) string {
+func (b *AdminBot) handleRejectWithdrawal(ctx context.Context, adminID int64, args string) string {
 	parts := strings.SplitN(args, " ", 2)
 	if len(parts) < 2 {
 		return "Использование: /reject <id> <причина>"
@@ -575,114 +871,21 @@ func (b *AdminBot) handleRejectWithdrawal(ctx context.Context, args string) stri
 
 	reason := parts[1]
 
-	if err := b.adminService.RejectWithdrawal(ctx, id, reason); err != nil {
+	if err := b.adminService.RejectWithdrawal(ctx, adminID, id, reason); err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return fmt.Sprintf("Вывод #%d не найден", id)
+		case errors.Is(err, repository.ErrWithdrawalNotRefundable):
+			return fmt.Sprintf("Вывод #%d уже отправлен или закрыт — возврат невозможен", id)
+		}
 		return fmt.Sprintf("Ошибка: %v", err)
 	}
 
-	return fmt.Sprintf("Вывод #%d отклонён. Средства возвращены.", id)
-}
-
-func (b *AdminBot) handleBroadcastStart(chatID int64, adminID int64) string {
-	b.broadcastPending[adminID] = true
-
-	return `<b>Broadcast Mode</b>
-
-Введите сообщение для рассылки ниже.
-
-<b>Поддерживается:</b>
-- Текст с HTML разметкой
-- Фото с подписью
-- Кнопки (формат: [текст](url))
-
-Отправьте /cancel для отмены.`
-}
-
-func (b *AdminBot) executeBroadcast(msg *tgbotapi.Message) {
-	adminID := msg.From.ID
-	chatID := msg.Chat.ID
-
-	// Cancel if user sends /cancel
-	if msg.Text == "/cancel" {
-		delete(b.broadcastPending, adminID)
-		reply := tgbotapi.NewMessage(chatID, "Рассылка отменена")
-		b.bot.Send(reply)
-		return
+	if w, err := b.adminService.GetWithdrawalNotification(ctx, id); err == nil {
+		service.NotifyWithdrawalRejected(ctx, b, w, reason)
 	}
 
-	delete(b.broadcastPending, adminID)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
-
-	b.log.Info("starting broadcast", "admin_id", adminID)
-
-	userIDs, err := b.adminService.GetAllUserTgIDs(ctx)
-	if err != nil {
-		b.log.Error("failed to get user IDs", "error", err)
-		reply := tgbotapi.NewMessage(chatID, fmt.Sprintf("Ошибка: %v", err))
-		b.bot.Send(reply)
-		return
-	}
-
-	if len(userIDs) == 0 {
-		reply := tgbotapi.NewMessage(chatID, "Нет пользователей для рассылки")
-		b.bot.Send(reply)
-		return
-	}
-
-	// Send progress message
-	progressMsg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Начинаю рассылку %d пользователям...", len(userIDs)))
-	b.bot.Send(progressMsg)
-
-	sent := 0
-	failed := 0
-	blocked := 0
-
-	for _, tgID := range userIDs {
-		var err error
-
-		// Check if it's a photo message
-		if msg.Photo != nil && len(msg.Photo) > 0 {
-			// Get the largest photo
-			photo := msg.Photo[len(msg.Photo)-1]
-			photoMsg := tgbotapi.NewPhoto(tgID, tgbotapi.FileID(photo.FileID))
-			photoMsg.Caption = msg.Caption
-			photoMsg.ParseMode = "HTML"
-			_, err = b.bot.Send(photoMsg)
-		} else {
-			// Text message
-			textMsg := tgbotapi.NewMessage(tgID, msg.Text)
-			textMsg.ParseMode = "HTML"
-			textMsg.DisableWebPagePreview = true
-			_, err = b.bot.Send(textMsg)
-		}
-
-		if err != nil {
-			if strings.Contains(err.Error(), "blocked") || strings.Contains(err.Error(), "deactivated") {
-				blocked++
-			} else {
-				b.log.Error("failed to send broadcast", "tg_id", tgID, "error", err)
-			}
-			failed++
-		} else {
-			sent++
-		}
-
-		// Rate limiting - 20 messages per second
-		time.Sleep(50 * time.Millisecond)
-	}
-
-	b.log.Info("broadcast complete", "sent", sent, "failed", failed, "blocked", blocked)
-
-	result := fmt.Sprintf(`<b>Рассылка завершена</b>
-
-Отправлено: %d
-Не доставлено: %d
-Заблокировали бота: %d`, sent, failed-blocked, blocked)
-
-	reply := tgbotapi.NewMessage(chatID, result)
-	reply.ParseMode = "HTML"
-	b.bot.Send(reply)
+	return fmt.Sprintf("Вывод #%d отклонён. Средства возвращены.", id)
 }
 
 // handleUsers returns list of all users
@@ -730,26 +933,64 @@ func (b *AdminBot) handleUsers(ctx context.Context, args string) string {
 	return sb.String()
 }
 
+func (b *AdminBot) handleFind(ctx context.Context, args string) string {
+	users, err := b.adminService.SearchUsers(ctx, args, 10)
+	if errors.Is(err, service.ErrEmptySearchQuery) {
+		return "Использование: /find <запрос>"
+	}
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+	if len(users) == 0 {
+		return fmt.Sprintf("По запросу «%s» никого не найдено", html.EscapeString(args))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("<b>Найдено по «%s»: %d</b>\n\n", html.EscapeString(strings.TrimSpace(args)), len(users)))
+	for i, u := range users {
+		name := "@" + u.Username
+		if u.Username == "" {
+			name = u.FirstName
+		}
+		sb.WriteString(fmt.Sprintf("%d. %s | id:%d | tg:<code>%d</code> | gems:%d | coins:%d\n",
+			i+1, html.EscapeString(name), u.ID, u.TgID, u.Gems, u.Coins))
+	}
+	return sb.String()
+}
+
 func (b *AdminBot) handleUserGames(ctx context.Context, args string) string {
-	if args == "" {
-		return "Использование: /usergames <@username|tg_id>"
+	parts := strings.Fields(args)
+	if len(parts) == 0 || len(parts) > 2 {
+		return "Использование: /usergames <tg_id> [pvp|pve]"
 	}
 
-	tgID, err := strconv.ParseInt(args, 10, 64)
+	tgID, err := strconv.ParseInt(parts[0], 10, 64)
 	if err != nil {
 		return "Неверный Telegram ID"
 	}
 
+	var mode domain.GameMode
+	if len(parts) == 2 {
+		mode = domain.GameMode(strings.ToLower(parts[1]))
+		if !mode.Valid() {
+			return "Режим: pvp или pve"
+		}
+	}
+
 	user, err := b.adminService.GetUserByTgID(ctx, tgID)
 	if err != nil {
 		return fmt.Sprintf("Пользователь не найден: %v", err)
 	}
 
 	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("<b>Игры @%s</b>\n\n", user.Username))
+	if mode != "" {
+		sb.WriteString(fmt.Sprintf("<b>Игры @%s (%s)</b>\n\n", html.EscapeString(user.Username), mode))
+	} else {
+		sb.WriteString(fmt.Sprintf("<b>Игры @%s</b>\n\n", html.EscapeString(user.Username)))
+	}
 
 	// Get gems games
-	gemsGames, err := b.adminService.GetUserGamesByTgID(ctx, tgID, "gems", 10)
+	gemsGames, err := b.adminService.GetUserGamesByTgID(ctx, tgID, "gems", mode, 10)
 	if err != nil {
 		sb.WriteString(fmt.Sprintf("Ошибка: %v\n", err))
 	} else {
@@ -764,7 +1005,7 @@ func (b *AdminBot) handleUserGames(ctx context.Context, args string) string {
 				} else if g.Result == "lose" {
 					status = "[LOSE]"
 				}
-				sb.WriteString(fmt.Sprintf("%s %s | ставка: %d | %+d\n", status, g.GameType, g.BetAmount, g.WinAmount))
+				sb.WriteString(fmt.Sprintf("%s %s %s | ставка: %d | %+d\n", status, g.GameType, g.Mode, g.BetAmount, g.WinAmount))
 			}
 		}
 	}
@@ -772,7 +1013,7 @@ func (b *AdminBot) handleUserGames(ctx context.Context, args string) string {
 	sb.WriteString("\n")
 
 	// Get coins games
-	coinsGames, err := b.adminService.GetUserGamesByTgID(ctx, tgID, "coins", 10)
+	coinsGames, err := b.adminService.GetUserGamesByTgID(ctx, tgID, "coins", mode, 10)
 	if err != nil {
 		sb.WriteString(fmt.Sprintf("Ошибка: %v\n", err))
 	} else {
@@ -787,7 +1028,7 @@ func (b *AdminBot) handleUserGames(ctx context.Context, args string) string {
 				} else if g.Result == "lose" {
 					status = "[LOSE]"
 				}
-				sb.WriteString(fmt.Sprintf("%s %s | ставка: %d | %+d\n", status, g.GameType, g.BetAmount, g.WinAmount))
+				sb.WriteString(fmt.Sprintf("%s %s %s | ставка: %d | %+d\n", status, g.GameType, g.Mode, g.BetAmount, g.WinAmount))
 			}
 		}
 	}
@@ -828,15 +1069,38 @@ func (b *AdminBot) handleTopUserGames(ctx context.Context, args string) string {
 	return sb.String()
 }
 
-func (b *AdminBot) handleAddCoins(ctx context.Context, args string) string {
+func (b *AdminBot) handleGameStats(ctx context.Context) string {
+	stats, err := b.adminService.GetGameTypeStats(ctx)
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+
+	if len(stats) == 0 {
+		return "Игр против казино ещё не было"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<b>Игры против казино (без PvP)</b>\n\n<pre>")
+	sb.WriteString(fmt.Sprintf("%-9s %-5s %7s %11s %11s %11s %7s\n", "Игра", "Вал.", "Игр", "Ставки", "Выплаты", "Прибыль", "RTP"))
+	for _, st := range stats {
+		sb.WriteString(fmt.Sprintf("%-9s %-5s %7d %11d %11d %11d %6.1f%%\n",
+			html.EscapeString(string(st.GameType)), html.EscapeString(string(st.Currency)),
+			st.Games, st.Wagered, st.PaidOut, st.HouseProfit, st.RTP*100))
+	}
+	sb.WriteString("</pre>\nRTP = выплаты / ставки; выше 100% - казино в минусе")
+
+	return sb.String()
+}
+
+func (b *AdminBot) handleAddCoins(ctx context.Context, adminID int64, args string) string {
 	parts := strings.Fields(args)
 	if len(parts) != 2 {
 		return "Использование: /addcoins <@username|tg_id> <сумма>"
 	}
 
-	tgID, err := strconv.ParseInt(parts[0], 10, 64)
-	if err != nil {
-		return "Неверный Telegram ID"
+	userID, errMsg := b.resolveUser(ctx, parts[0])
+	if errMsg != "" {
+		return errMsg
 	}
 
 	amount, err := strconv.ParseInt(parts[1], 10, 64)
@@ -844,21 +1108,60 @@ func (b *AdminBot) handleAddCoins(ctx context.Context, args string) string {
 		return "Неверная сумма"
 	}
 
-	newBalance, err := b.adminService.AddUserCoins(ctx, tgID, amount)
+	newBalance, err := b.adminService.AddUserCoins(ctx, adminID, userID, amount)
 	if err != nil {
 		return fmt.Sprintf("Ошибка: %v", err)
 	}
 
-	return fmt.Sprintf("Добавлено %d коинов пользователю (TG: %d). Новый баланс: %d", amount, tgID, newBalance)
+	return fmt.Sprintf("Добавлено %d коинов пользователю %s. Новый баланс: %d", amount, html.EscapeString(parts[0]), newBalance)
+}
+
+// resolveUser resolves @username or tg_id; on failure returns the reply for the admin
+func (b *AdminBot) resolveUser(ctx context.Context, identifier string) (int64, string) {
+	userID, err := b.adminService.ResolveUserIdentifier(ctx, identifier)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, fmt.Sprintf("Пользователь %s не найден", html.EscapeString(identifier))
+		}
+		return 0, html.EscapeString(fmt.Sprintf("Ошибка: %v", err))
+	}
+	return userID, ""
+}
+
+func (b *AdminBot) handleAddGK(ctx context.Context, adminID int64, args string) string {
+	parts := strings.Fields(args)
+	if len(parts) != 2 {
+		return "Использование: /addgk &lt;@username|tg_id&gt; &lt;сумма&gt;"
+	}
+
+	amount, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || amount == 0 {
+		return "Неверная сумма"
+	}
+
+	userID, errMsg := b.resolveUser(ctx, parts[0])
+	if errMsg != "" {
+		return errMsg
+	}
+
+	newBalance, err := b.adminService.AddUserGK(ctx, adminID, userID, amount)
+	if err != nil {
+		if errors.Is(err, service.ErrInsufficientBalance) {
+			return "Ошибка: баланс GK не может стать отрицательным"
+		}
+		return html.EscapeString(fmt.Sprintf("Ошибка: %v", err))
+	}
+
+	return fmt.Sprintf("Добавлено %d GK пользователю %s. Новый баланс GK: %d", amount, html.EscapeString(parts[0]), newBalance)
 }
 
-func (b *AdminBot) handleAddAdmin(args string) string {
+func (b *AdminBot) handleAddAdmin(ctx context.Context, adminID int64, args string) string {
 	if args == "" {
 		return "Использование: /addadmin <tg_id>"
 	}
 
-	tgID, err := strconv.ParseInt(args, 10, 64)
-	if err != nil {
+	tgID, err := strconv.ParseInt(strings.TrimSpace(args), 10, 64)
+	if err != nil || tgID <= 0 {
 		return "Неверный Telegram ID"
 	}
 
@@ -866,10 +1169,53 @@ func (b *AdminBot) handleAddAdmin(args string) string {
 		return fmt.Sprintf("Пользователь %d уже админ", tgID)
 	}
 
+	if _, err := b.adminService.AddAdmin(ctx, adminID, tgID); err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+
+	b.adminsMu.Lock()
 	b.adminIDs = append(b.adminIDs, tgID)
-	b.log.Info("added new admin", "tg_id", tgID)
+	b.adminsMu.Unlock()
+	b.log.Info("added new admin", "tg_id", tgID, "by", adminID)
 
-	return fmt.Sprintf("Добавлен админ %d\n\nЭто временно до перезапуска. Добавьте в ADMIN_TELEGRAM_IDS для постоянного доступа.", tgID)
+	return fmt.Sprintf("Добавлен админ %d", tgID)
+}
+
+func (b *AdminBot) handleRemoveAdmin(ctx context.Context, adminID int64, args string) string {
+	if args == "" {
+		return "Использование: /removeadmin <tg_id>"
+	}
+
+	tgID, err := strconv.ParseInt(strings.TrimSpace(args), 10, 64)
+	if err != nil {
+		return "Неверный Telegram ID"
+	}
+
+	if b.envAdmins[tgID] {
+		return fmt.Sprintf("Админ %d задан в ADMIN_TELEGRAM_IDS, удалите его оттуда и перезапустите бота", tgID)
+	}
+
+	removed, err := b.adminService.RemoveAdmin(ctx, adminID, tgID)
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+
+	b.adminsMu.Lock()
+	for i, id := range b.adminIDs {
+		if id == tgID {
+			b.adminIDs = append(b.adminIDs[:i], b.adminIDs[i+1:]...)
+			removed = true
+			break
+		}
+	}
+	b.adminsMu.Unlock()
+
+	if !removed {
+		return fmt.Sprintf("Пользователь %d не админ", tgID)
+	}
+	b.log.Info("removed admin", "tg_id", tgID, "by", adminID)
+
+	return fmt.Sprintf("Удалён админ %d", tgID)
 }
 
 // SendNotification sends a notification to a specific user
@@ -880,27 +1226,53 @@ func (b *AdminBot) SendNotification(tgID int64, message string) error {
 	return err
 }
 
-// NotifyAdminsNewWithdrawal notifies all admins about a new withdrawal request
-func (b *AdminBot) NotifyAdminsNewWithdrawal(ctx context.Context, withdrawalID int64) {
-	w, err := b.adminService.GetWithdrawalNotification(ctx, withdrawalID)
+// AdminBot is the Telegram implementation of service.Notifier
+var _ service.Notifier = (*AdminBot)(nil)
+
+// NotifyUser sends a notification to a user if they have the category enabled
+func (b *AdminBot) NotifyUser(ctx context.Context, userID int64, category domain.NotificationCategory, message string) {
+	tgID, enabled, err := b.adminService.GetNotificationTarget(ctx, userID, category)
 	if err != nil {
-		b.log.Error("failed to get withdrawal for notification", "error", err)
+		b.log.Error("failed to get notification target", "user_id", userID, "error", err)
+		return
+	}
+	if !enabled {
 		return
 	}
 
-	message := fmt.Sprintf(`<b>Новый запрос на вывод!</b>
+	if err := b.SendNotification(tgID, message); err != nil {
+		b.log.Error("failed to notify user", "user_id", userID, "category", category, "error", err)
+	}
+}
 
-Пользователь: @%s (TG: %d)
-Сумма: %d coins (%.4f TON)
-Кошелек: <code>%s</code>
+// NotifyAdmins sends an HTML message to all admins
+func (b *AdminBot) NotifyAdmins(ctx context.Context, message string) {
+	b.notifyAdmins(message)
+}
+
+// SetHotWalletMonitor attaches the hot wallet monitor and alerts admins on low balance
+func (b *AdminBot) SetHotWalletMonitor(m *service.HotWalletMonitor) {
+	b.hotWallet = m
+	m.OnLow = func(ctx context.Context, st service.HotWalletStatus) {
+		b.notifyAdmins(fmt.Sprintf(`<b>⚠️ Низкий баланс горячего кошелька!</b>
 
-ID: #%d
+Баланс: %.4f TON
+Порог: %.4f TON
+Кошелек: <code>%s</code>
 
-/approve %d - одобрить
-/reject %d причина - отклонить`,
-		w.Username, w.TgID, w.CoinsAmount, w.TonAmount, w.WalletAddress, w.ID, w.ID, w.ID)
+Выплаты приостановлены до пополнения: /approve без tx_hash не пройдёт.
+/withdrawals_status - подробнее`,
+			ton.NanoToTON(st.BalanceNano), ton.NanoToTON(st.ThresholdNano), st.Address))
+	}
+	m.OnRecovered = func(ctx context.Context, st service.HotWalletStatus) {
+		b.notifyAdmins(fmt.Sprintf("✅ Горячий кошелек пополнен: %.4f TON. Выплаты возобновлены.",
+			ton.NanoToTON(st.BalanceNano)))
+	}
+}
 
-	for _, adminID := range b.adminIDs {
+// notifyAdmins sends an HTML message to all admins
+func (b *AdminBot) notifyAdmins(message string) {
+	for _, adminID := range b.admins() {
 		msg := tgbotapi.NewMessage(adminID, message)
 		msg.ParseMode = "HTML"
 		if _, err := b.bot.Send(msg); err != nil {
@@ -1127,7 +1499,7 @@ func (b *AdminBot) handleQuestCreationStep(msg *tgbotapi.Message) {
 			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 			defer cancel()
 
-			id, err := b.adminService.CreateQuest(ctx, state.QuestType, state.Title, "", state.ActionType, state.TargetCount, rewardGems, rewardCoins, rewardGK)
+			id, err := b.adminService.CreateQuest(ctx, adminID, state.QuestType, state.Title, "", state.ActionType, state.TargetCount, rewardGems, rewardCoins, rewardGK)
 			if err != nil {
 				response = fmt.Sprintf("❌ Ошибка создания: %v", err)
 			} else {
@@ -1148,7 +1520,7 @@ func (b *AdminBot) handleQuestCreationStep(msg *tgbotapi.Message) {
 	b.bot.Send(reply)
 }
 
-func (b *AdminBot) handleDeleteQuest(ctx context.Context, args string) string {
+func (b *AdminBot) handleDeleteQuest(ctx context.Context, adminID int64, args string) string {
 	if args == "" {
 		return "❌ Использование: /deletequest <id>"
 	}
@@ -1158,14 +1530,14 @@ func (b *AdminBot) handleDeleteQuest(ctx context.Context, args string) string {
 		return "❌ Неверный ID квеста"
 	}
 
-	if err := b.adminService.DeleteQuest(ctx, id); err != nil {
+	if err := b.adminService.DeleteQuest(ctx, adminID, id); err != nil {
 		return fmt.Sprintf("❌ Ошибка: %v", err)
 	}
 
 	return fmt.Sprintf("✅ Квест #%d удалён", id)
 }
 
-func (b *AdminBot) handleToggleQuest(ctx context.Context, args string) string {
+func (b *AdminBot) handleToggleQuest(ctx context.Context, adminID int64, args string) string {
 	if args == "" {
 		return "❌ Использование: /togglequest <id>"
 	}
@@ -1175,7 +1547,7 @@ func (b *AdminBot) handleToggleQuest(ctx context.Context, args string) string {
 		return "❌ Неверный ID квеста"
 	}
 
-	newStatus, err := b.adminService.ToggleQuestActive(ctx, id)
+	newStatus, err := b.adminService.ToggleQuestActive(ctx, adminID, id)
 	if err != nil {
 		return fmt.Sprintf("❌ Ошибка: %v", err)
 	}
@@ -1187,3 +1559,83 @@ func (b *AdminBot) handleToggleQuest(ctx context.Context, args string) string {
 
 	return fmt.Sprintf("📋 Квест #%d теперь %s", id, status)
 }
+
+// truncate обрезает строку до n символов для сообщений бота
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
+
+func (b *AdminBot) handleSetLimits(ctx context.Context, adminID int64, args string) string {
+	parts := strings.Fields(args)
+	if len(parts) != 3 && len(parts) != 4 {
+		return "Использование: /setlimits &lt;игра&gt; &lt;мин&gt; &lt;макс&gt; [gems|coins]"
+	}
+
+	minBet, err1 := strconv.ParseInt(parts[1], 10, 64)
+	maxBet, err2 := strconv.ParseInt(parts[2], 10, 64)
+	if err1 != nil || err2 != nil {
+		return "Неверная сумма"
+	}
+	currency := domain.CurrencyGems
+	if len(parts) == 4 {
+		currency = domain.Currency(strings.ToLower(parts[3]))
+	}
+	gt := domain.GameType(strings.ToLower(parts[0]))
+
+	if err := b.adminService.SetGameLimits(ctx, adminID, gt, currency, minBet, maxBet); err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnknownLimitGame):
+			return "❌ Неизвестная игра. Доступны: " + limitGamesList()
+		case errors.Is(err, service.ErrInvalidCurrency):
+			return "❌ Валюта: gems или coins"
+		case errors.Is(err, service.ErrInvalidLimits):
+			return "❌ Лимиты должны быть положительными, мин ≤ макс"
+		}
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+
+	return fmt.Sprintf("✅ Лимиты %s (%s): %d - %d", gt, currency, minBet, maxBet)
+}
+
+func (b *AdminBot) handleCheckLimits(ctx context.Context) string {
+	limits, overrides, err := b.adminService.GetGameLimits(ctx)
+	if err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+
+	changed := make(map[string]bool, len(overrides))
+	for _, o := range overrides {
+		changed[string(o.GameType)+"/"+string(o.Currency)] = true
+	}
+	mark := func(gt domain.GameType, currency domain.Currency) string {
+		if changed[string(gt)+"/"+string(currency)] {
+			return " ✏️"
+		}
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<b>🎰 Лимиты ставок</b>\n\n")
+	for _, gt := range service.LimitGames {
+		l := limits[gt]
+		sb.WriteString(fmt.Sprintf("<b>%s</b>\n", gt))
+		sb.WriteString(fmt.Sprintf("   💎 %d - %d%s\n", l.MinBet, l.MaxBet, mark(gt, domain.CurrencyGems)))
+		sb.WriteString(fmt.Sprintf("   🪙 %d - %d%s\n", l.MinBetCoins, l.MaxBetCoins, mark(gt, domain.CurrencyCoins)))
+	}
+	sb.WriteString("\n✏️ - изменено через /setlimits, остальное из конфига")
+
+	return sb.String()
+}
+
+// limitGamesList lists games accepted by /setlimits
+func limitGamesList() string {
+	names := make([]string, len(service.LimitGames))
+	for i, gt := range service.LimitGames {
+		names[i] = string(gt)
+	}
+	return strings.Join(names, ", ")
+}
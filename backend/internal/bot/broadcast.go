@@ -0,0 +1,561 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/service"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Шаги /broadcast
+const (
+	broadcastStepSegment = iota + 1
+	broadcastStepTime
+	broadcastStepMessage
+	broadcastStepConfirm
+)
+
+// broadcastPollInterval - как часто проверять отложенные и брошенные рассылки
+const broadcastPollInterval = time.Minute
+
+// broadcastStaleAfter - рассылка в sending без сохранения прогресса дольше этого
+// считается брошенной (процесс упал) и продолжается с сохранённого места
+const broadcastStaleAfter = 3 * time.Minute
+
+// runningBroadcast - рассылка, которую этот процесс сейчас отправляет
+type runningBroadcast struct {
+	adminID   int64
+	cancel    context.CancelFunc
+	cancelled bool // отменена админом, а не остановкой бота
+}
+
+// broadcastDraft - рассылка, которую админ сейчас составляет
+type broadcastDraft struct {
+	Step       int
+	Filter     service.BroadcastFilter
+	Recipients int
+	SendAt     time.Time // zero - сразу
+
+	Text        string // HTML с кнопками [текст](url), подпись если есть фото
+	PhotoFileID string
+}
+
+// broadcastButtonRe - кнопка [текст](url) в тексте рассылки
+var broadcastButtonRe = regexp.MustCompile(`\[([^\[\]]+)\]\(([^()\s]+)\)`)
+
+// parseBroadcastButtons cuts [label](url) tokens out of text and turns them into
+// inline buttons: buttons from one line of text form one keyboard row. Lines left
+// empty are dropped. Only absolute http(s) URLs are accepted.
+func parseBroadcastButtons(text string) (string, [][]tgbotapi.InlineKeyboardButton, error) {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var lines []string
+
+	for _, line := range strings.Split(text, "\n") {
+		matches := broadcastButtonRe.FindAllStringSubmatch(line, -1)
+		if len(matches) == 0 {
+			lines = append(lines, line)
+			continue
+		}
+
+		var row []tgbotapi.InlineKeyboardButton
+		for _, m := range matches {
+			label, link := strings.TrimSpace(m[1]), m[2]
+			u, err := url.Parse(link)
+			if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+				return "", nil, fmt.Errorf("invalid button url %q: must be http(s)://...", link)
+			}
+			if label == "" {
+				return "", nil, fmt.Errorf("empty button label for %q", link)
+			}
+			row = append(row, tgbotapi.NewInlineKeyboardButtonURL(label, link))
+		}
+		rows = append(rows, row)
+
+		if rest := strings.TrimSpace(broadcastButtonRe.ReplaceAllString(line, "")); rest != "" {
+			lines = append(lines, rest)
+		}
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n")), rows, nil
+}
+
+// broadcastMessage builds the message a recipient gets: text or photo with
+// caption, HTML, with the inline keyboard parsed from the text
+func broadcastMessage(chatID int64, text, photoFileID string) (tgbotapi.Chattable, error) {
+	clean, rows, err := parseBroadcastButtons(text)
+	if err != nil {
+		return nil, err
+	}
+
+	var markup interface{}
+	if len(rows) > 0 {
+		markup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	}
+
+	if photoFileID != "" {
+		m := tgbotapi.NewPhoto(chatID, tgbotapi.FileID(photoFileID))
+		m.Caption = clean
+		m.ParseMode = "HTML"
+		m.ReplyMarkup = markup
+		return m, nil
+	}
+
+	m := tgbotapi.NewMessage(chatID, clean)
+	m.ParseMode = "HTML"
+	m.DisableWebPagePreview = true
+	m.ReplyMarkup = markup
+	return m, nil
+}
+
+// isBlockedError reports whether the recipient can't be reached anymore
+func isBlockedError(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "blocked") || strings.Contains(msg, "deactivated")
+}
+
+// broadcastErrorKind groups send errors for logging: Telegram API errors by description, the rest as network
+func broadcastErrorKind(err error) string {
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Message
+	}
+	return "network"
+}
+
+func (b *AdminBot) broadcastDraft(adminID int64) *broadcastDraft {
+	b.broadcastMu.Lock()
+	defer b.broadcastMu.Unlock()
+	return b.broadcastDrafts[adminID]
+}
+
+func (b *AdminBot) setBroadcastDraft(adminID int64, d *broadcastDraft) {
+	b.broadcastMu.Lock()
+	defer b.broadcastMu.Unlock()
+	if d == nil {
+		delete(b.broadcastDrafts, adminID)
+		return
+	}
+	b.broadcastDrafts[adminID] = d
+}
+
+func (b *AdminBot) handleBroadcastStart(adminID int64) string {
+	b.setBroadcastDraft(adminID, &broadcastDraft{Step: broadcastStepSegment})
+
+	return `<b>Broadcast Mode</b>
+
+<b>Шаг 1/3:</b> Выберите аудиторию:
+all - все пользователи
+inactive &lt;дней&gt; - не заходили N дней
+top &lt;N&gt; - N игроков с наибольшей суммой депозитов
+nodeposit - ни разу не пополняли
+level &lt;N&gt; или level &lt;N&gt;-&lt;M&gt; - по уровню персонажа
+
+Учитываются только пользователи с включёнными промо-уведомлениями.
+Отправьте /cancel для отмены.`
+}
+
+// handleBroadcastStep processes the admin's reply to the current /broadcast step
+func (b *AdminBot) handleBroadcastStep(msg *tgbotapi.Message) {
+	adminID := msg.From.ID
+	chatID := msg.Chat.ID
+	draft := b.broadcastDraft(adminID)
+	if draft == nil {
+		return
+	}
+
+	reply := func(text string) {
+		m := tgbotapi.NewMessage(chatID, text)
+		m.ParseMode = "HTML"
+		b.bot.Send(m)
+	}
+
+	if msg.IsCommand() && msg.Command() == "cancel" {
+		b.setBroadcastDraft(adminID, nil)
+		reply("Рассылка отменена")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	switch draft.Step {
+	case broadcastStepSegment:
+		filter, err := service.ParseBroadcastFilter(msg.Text)
+		if err != nil {
+			reply(html.EscapeString(err.Error()))
+			return
+		}
+		ids, err := b.adminService.GetUserTgIDsFiltered(ctx, filter)
+		if err != nil {
+			reply(fmt.Sprintf("Ошибка: %v", err))
+			return
+		}
+		if len(ids) == 0 {
+			reply("Нет пользователей в этом сегменте, выберите другой")
+			return
+		}
+		draft.Filter = filter
+		draft.Recipients = len(ids)
+		draft.Step = broadcastStepTime
+		reply(fmt.Sprintf(`Аудитория: <b>%s</b>, сейчас %d получателей
+
+<b>Шаг 2/3:</b> Когда отправить?
+now - сразу
++2h, +30m - через указанное время
+%s - в указанное время (серверное)`,
+			html.EscapeString(filter.String()), len(ids), time.Now().Add(time.Hour).Format("2006-01-02 15:04")))
+
+	case broadcastStepTime:
+		sendAt, err := service.ParseBroadcastTime(msg.Text, time.Now())
+		if err != nil {
+			reply(html.EscapeString(err.Error()))
+			return
+		}
+		draft.SendAt = sendAt
+		draft.Step = broadcastStepMessage
+		reply(`<b>Шаг 3/3:</b> Введите сообщение для рассылки.
+
+<b>Поддерживается:</b>
+- Текст с HTML разметкой
+- Фото с подписью
+- Кнопки (формат: [текст](url))`)
+
+	case broadcastStepMessage:
+		draft.Text = msg.Text
+		draft.PhotoFileID = ""
+		if len(msg.Photo) > 0 {
+			// Самое большое фото
+			draft.PhotoFileID = msg.Photo[len(msg.Photo)-1].FileID
+			draft.Text = msg.Caption
+		}
+
+		preview, err := broadcastMessage(chatID, draft.Text, draft.PhotoFileID)
+		if err != nil {
+			reply(html.EscapeString(err.Error()) + "\n\nИсправьте и отправьте сообщение ещё раз.")
+			return
+		}
+		if _, err := b.bot.Send(preview); err != nil {
+			// Чаще всего битая HTML-разметка: получатели увидели бы ту же ошибку
+			reply(fmt.Sprintf("Не удалось показать превью: %s\n\nИсправьте и отправьте сообщение ещё раз.", html.EscapeString(err.Error())))
+			return
+		}
+
+		draft.Step = broadcastStepConfirm
+		when := "сразу"
+		if !draft.SendAt.IsZero() {
+			when = draft.SendAt.Format("02.01.2006 15:04")
+		}
+		reply(fmt.Sprintf(`☝️ Так сообщение увидят получатели.
+
+Аудитория: <b>%s</b> (%d), отправка: %s
+Отправьте <b>yes</b> для подтверждения, новое сообщение чтобы заменить, /cancel для отмены.`,
+			html.EscapeString(draft.Filter.String()), draft.Recipients, when))
+
+	case broadcastStepConfirm:
+		if answer := strings.ToLower(strings.TrimSpace(msg.Text)); answer != "yes" && answer != "да" {
+			// Новое сообщение вместо подтверждения - показываем превью заново
+			draft.Step = broadcastStepMessage
+			b.handleBroadcastStep(msg)
+			return
+		}
+		b.setBroadcastDraft(adminID, nil)
+
+		bc := &domain.Broadcast{
+			AdminTgID:   adminID,
+			Segment:     draft.Filter.String(),
+			Text:        draft.Text,
+			PhotoFileID: draft.PhotoFileID,
+			ScheduledAt: draft.SendAt,
+		}
+		if err := b.adminService.CreateBroadcast(ctx, bc); err != nil {
+			b.log.Error("failed to create broadcast", "error", err)
+			reply(fmt.Sprintf("Ошибка: %v", err))
+			return
+		}
+
+		if bc.Status == domain.BroadcastScheduled {
+			reply(fmt.Sprintf("Рассылка #%d запланирована на %s\n/cancelbroadcast %d - отменить",
+				bc.ID, bc.ScheduledAt.Format("02.01.2006 15:04"), bc.ID))
+			return
+		}
+		b.sendBroadcast(bc)
+	}
+}
+
+// sendBroadcast delivers a broadcast already marked as sending, starting from
+// bc.Progress, and stores the results. The admin who created it sees progress in
+// one message that is edited as the broadcast goes. /cancel stops it; when the bot
+// stops the broadcast stays in sending and is resumed later.
+func (b *AdminBot) sendBroadcast(bc *domain.Broadcast) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b.trackBroadcast(bc, cancel)
+	defer b.untrackBroadcast(bc.ID)
+	go func() {
+		select {
+		case <-b.stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	notify := func(text string) {
+		m := tgbotapi.NewMessage(bc.AdminTgID, text)
+		m.ParseMode = "HTML"
+		b.bot.Send(m)
+	}
+
+	b.log.Info("starting broadcast", "broadcast_id", bc.ID, "admin_id", bc.AdminTgID, "segment", bc.Segment, "from", bc.Progress)
+
+	dbCtx, dbCancel := context.WithTimeout(ctx, 30*time.Second)
+	var userIDs []int64
+	filter, err := service.ParseBroadcastFilter(bc.Segment)
+	if err == nil {
+		userIDs, err = b.adminService.GetUserTgIDsFiltered(dbCtx, filter)
+	}
+	dbCancel()
+	if err != nil {
+		b.log.Error("failed to get user IDs", "broadcast_id", bc.ID, "error", err)
+		bc.Status = domain.BroadcastFailed
+		b.saveBroadcast(bc, true)
+		notify(fmt.Sprintf("Рассылка #%d не отправлена: %v", bc.ID, err))
+		return
+	}
+
+	// Продолжение после падения: начало списка уже обработано
+	offset := bc.Progress
+	if offset > len(userIDs) {
+		offset = len(userIDs)
+	}
+	base := *bc
+	bc.Total = len(userIDs)
+
+	var progressMsgID int
+	startText := fmt.Sprintf("Рассылка #%d: %d пользователям...", bc.ID, len(userIDs))
+	if offset > 0 {
+		startText = fmt.Sprintf("Рассылка #%d продолжается с %d из %d...", bc.ID, offset, len(userIDs))
+	}
+	if sent, err := b.bot.Send(tgbotapi.NewMessage(bc.AdminTgID, startText)); err == nil {
+		progressMsgID = sent.MessageID
+	}
+
+	apply := func(done int, stats broadcastStats) {
+		bc.Progress = offset + done
+		bc.Delivered = base.Delivered + stats.Delivered
+		bc.Blocked = base.Blocked + stats.Blocked
+		bc.Errored = base.Errored + stats.Errored
+	}
+
+	send := func(tgID int64) error {
+		m, err := broadcastMessage(tgID, bc.Text, bc.PhotoFileID)
+		if err != nil {
+			return err
+		}
+		_, err = b.bot.Send(m)
+		if err != nil && !isBlockedError(err) {
+			b.log.Error("failed to send broadcast", "tg_id", tgID, "error", err)
+		}
+		return err
+	}
+
+	stats := runBroadcast(ctx, userIDs[offset:], send, broadcastRun{
+		Workers:          broadcastWorkers,
+		Rate:             broadcastRate,
+		ProgressEvery:    broadcastProgressEvery,
+		ProgressInterval: broadcastProgressInterval,
+		Progress: func(done int, stats broadcastStats) {
+			apply(done, stats)
+			b.saveBroadcast(bc, false)
+			if progressMsgID != 0 {
+				edit := tgbotapi.NewEditMessageText(bc.AdminTgID, progressMsgID,
+					fmt.Sprintf("Рассылка #%d: %d из %d (доставлено %d)", bc.ID, bc.Progress, bc.Total, bc.Delivered))
+				b.bot.Send(edit)
+			}
+		},
+	})
+	apply(stats.Done, stats)
+
+	b.log.Info("broadcast finished", "broadcast_id", bc.ID, "total", bc.Total, "delivered", bc.Delivered,
+		"blocked", bc.Blocked, "errored", bc.Errored, "skipped", stats.Skipped, "errors", stats.Errors)
+
+	title := "завершена"
+	switch {
+	case stats.Skipped == 0:
+		bc.Status = domain.BroadcastDone
+	case b.broadcastCancelledByAdmin(bc.ID):
+		bc.Status = domain.BroadcastCancelled
+		title = "отменена"
+	default:
+		// Бот останавливается: остаёмся в sending, продолжит следующий запуск
+		b.saveBroadcast(bc, false)
+		return
+	}
+	b.saveBroadcast(bc, true)
+
+	notify(fmt.Sprintf(`<b>Рассылка #%d %s</b>
+
+Всего: %d
+Доставлено: %d
+Заблокировали бота: %d
+Ошибки отправки: %d
+Не отправлено: %d`, bc.ID, title, bc.Total, bc.Delivered, bc.Blocked, bc.Errored, stats.Skipped))
+}
+
+// saveBroadcast stores progress, or the final status when final is set
+func (b *AdminBot) saveBroadcast(bc *domain.Broadcast, final bool) {
+	// Свой ctx: рассылку могли отменить, а итоги сохранить нужно
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var err error
+	if final {
+		err = b.adminService.FinishBroadcast(ctx, bc)
+	} else {
+		err = b.adminService.SaveBroadcastProgress(ctx, bc)
+	}
+	if err != nil {
+		b.log.Error("failed to save broadcast", "broadcast_id", bc.ID, "final", final, "error", err)
+	}
+}
+
+func (b *AdminBot) trackBroadcast(bc *domain.Broadcast, cancel context.CancelFunc) {
+	b.broadcastMu.Lock()
+	defer b.broadcastMu.Unlock()
+	b.broadcastRuns[bc.ID] = &runningBroadcast{adminID: bc.AdminTgID, cancel: cancel}
+}
+
+func (b *AdminBot) untrackBroadcast(id int64) {
+	b.broadcastMu.Lock()
+	defer b.broadcastMu.Unlock()
+	delete(b.broadcastRuns, id)
+}
+
+func (b *AdminBot) broadcastCancelledByAdmin(id int64) bool {
+	b.broadcastMu.Lock()
+	defer b.broadcastMu.Unlock()
+	r := b.broadcastRuns[id]
+	return r != nil && r.cancelled
+}
+
+// cancelRunningBroadcasts stops broadcasts this process is sending: the given one,
+// or with id 0 all started by adminID. Returns how many were stopped.
+func (b *AdminBot) cancelRunningBroadcasts(adminID, id int64) int {
+	b.broadcastMu.Lock()
+	defer b.broadcastMu.Unlock()
+
+	n := 0
+	for rid, r := range b.broadcastRuns {
+		if (id != 0 && rid == id) || (id == 0 && r.adminID == adminID) {
+			if !r.cancelled {
+				r.cancelled = true
+				r.cancel()
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// StartBroadcastScheduler sends scheduled broadcasts when their time comes and
+// resumes broadcasts left unfinished by a crash. Stops together with the bot.
+func (b *AdminBot) StartBroadcastScheduler() {
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		ticker := time.NewTicker(broadcastPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-b.stopCh:
+				return
+			case <-ticker.C:
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			due, err := b.adminService.ClaimDueBroadcasts(ctx)
+			if err != nil {
+				b.log.Error("failed to claim scheduled broadcasts", "error", err)
+			}
+			stale, err := b.adminService.ClaimStaleBroadcasts(ctx, broadcastStaleAfter)
+			if err != nil {
+				b.log.Error("failed to claim stale broadcasts", "error", err)
+			}
+			cancel()
+
+			for _, bc := range append(due, stale...) {
+				b.wg.Add(1)
+				go func(bc *domain.Broadcast) {
+					defer b.wg.Done()
+					b.sendBroadcast(bc)
+				}(bc)
+			}
+		}
+	}()
+}
+
+func (b *AdminBot) handleBroadcasts(ctx context.Context, args string) string {
+	limit := 10
+	if args != "" {
+		if n, err := strconv.Atoi(args); err == nil && n > 0 && n <= 50 {
+			limit = n
+		}
+	}
+
+	list, err := b.adminService.GetRecentBroadcasts(ctx, limit)
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+	if len(list) == 0 {
+		return "Рассылок ещё не было"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<b>Рассылки</b>\n\n")
+	for _, bc := range list {
+		sb.WriteString(fmt.Sprintf("#%d | %s | %s | admin %d\n",
+			bc.ID, bc.Status, html.EscapeString(bc.Segment), bc.AdminTgID))
+		switch bc.Status {
+		case domain.BroadcastScheduled:
+			sb.WriteString(fmt.Sprintf("Запланирована на %s\n", bc.ScheduledAt.Format("02.01.2006 15:04")))
+		case domain.BroadcastDone:
+			sb.WriteString(fmt.Sprintf("Доставлено %d из %d, заблокировали %d, ошибок %d\n",
+				bc.Delivered, bc.Total, bc.Blocked, bc.Errored))
+		}
+		if bc.Text != "" {
+			sb.WriteString(html.EscapeString(truncate(bc.Text, 80)) + "\n")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func (b *AdminBot) handleCancelBroadcast(ctx context.Context, adminID int64, args string) string {
+	id, err := strconv.ParseInt(strings.TrimSpace(args), 10, 64)
+	if err != nil {
+		return "Использование: /cancelbroadcast <id>"
+	}
+
+	if b.cancelRunningBroadcasts(adminID, id) > 0 {
+		b.adminService.LogAction(ctx, adminID, domain.AdminActionCancelBroadcast, 0, map[string]interface{}{"broadcast_id": id})
+		return fmt.Sprintf("Рассылка #%d останавливается, итоги придут отдельным сообщением", id)
+	}
+
+	cancelled, err := b.adminService.CancelBroadcast(ctx, adminID, id)
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+	if !cancelled {
+		return fmt.Sprintf("Рассылка #%d не запланирована (уже отправлена или отменена)", id)
+	}
+	return fmt.Sprintf("Рассылка #%d отменена", id)
+}
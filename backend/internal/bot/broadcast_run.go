@@ -0,0 +1,176 @@
+package bot
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Отправка рассылки: Telegram пускает ~30 сообщений в секунду на бота
+const (
+	broadcastWorkers          = 8
+	broadcastRate             = 25 // сообщений в секунду, с запасом до лимита Telegram
+	broadcastProgressEvery    = 200
+	broadcastProgressInterval = 10 * time.Second
+)
+
+// broadcastStats - итоги рассылки: Delivered + Blocked + Errored + Skipped == Total
+type broadcastStats struct {
+	Total     int
+	Delivered int
+	Blocked   int            // пользователь заблокировал бота или удалил аккаунт
+	Errored   int            // остальные ошибки отправки
+	Skipped   int            // не отправлено: рассылку отменили или бот остановился
+	Done      int            // с начала списка обработано без пропусков, отсюда продолжать
+	Errors    map[string]int // Errored по типу ошибки
+}
+
+// broadcastRun configures runBroadcast. Zero Rate means no rate limit.
+type broadcastRun struct {
+	Workers int
+	Rate    float64 // сообщений в секунду на всех воркеров
+
+	// Progress is called every ProgressEvery results or ProgressInterval, whichever
+	// comes first. done is how many recipients from the start of the list are
+	// finished without gaps: resuming from it sends nobody twice except in-flight ones.
+	Progress         func(done int, stats broadcastStats)
+	ProgressEvery    int
+	ProgressInterval time.Duration
+}
+
+// runBroadcast sends to every recipient via send with a bounded worker pool and a
+// shared rate limit. Cancelling ctx stops new sends; sends already started finish.
+func runBroadcast(ctx context.Context, tgIDs []int64, send func(tgID int64) error, run broadcastRun) broadcastStats {
+	stats := broadcastStats{Total: len(tgIDs), Errors: map[string]int{}}
+
+	workers := run.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	var limiter *tokenBucket
+	if run.Rate > 0 {
+		limiter = newTokenBucket(run.Rate, workers)
+		defer limiter.Stop()
+	}
+
+	var (
+		mu           sync.Mutex
+		finished     = make([]bool, len(tgIDs))
+		done         int // все получатели до этого индекса обработаны
+		results      int
+		lastProgress = time.Now()
+	)
+
+	record := func(i int, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case err == nil:
+			stats.Delivered++
+		case isBlockedError(err):
+			stats.Blocked++
+		default:
+			stats.Errored++
+			stats.Errors[broadcastErrorKind(err)]++
+		}
+
+		finished[i] = true
+		for done < len(finished) && finished[done] {
+			done++
+		}
+		results++
+
+		if run.Progress == nil {
+			return
+		}
+		due := run.ProgressEvery > 0 && results%run.ProgressEvery == 0
+		if run.ProgressInterval > 0 && time.Since(lastProgress) >= run.ProgressInterval {
+			due = true
+		}
+		if due && done < len(finished) {
+			lastProgress = time.Now()
+			run.Progress(done, stats)
+		}
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if limiter != nil && limiter.Wait(ctx) != nil {
+					continue
+				}
+				record(i, send(tgIDs[i]))
+			}
+		}()
+	}
+
+dispatch:
+	for i := range tgIDs {
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case jobs <- i:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	stats.Skipped = stats.Total - stats.Delivered - stats.Blocked - stats.Errored
+	stats.Done = done
+	return stats
+}
+
+// tokenBucket allows rate events per second with bursts up to burst
+type tokenBucket struct {
+	tokens chan struct{}
+	stop   chan struct{}
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	tb := &tokenBucket{
+		tokens: make(chan struct{}, burst),
+		stop:   make(chan struct{}),
+	}
+	tb.tokens <- struct{}{}
+
+	interval := time.Duration(float64(time.Second) / rate)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-tb.stop:
+				return
+			case <-ticker.C:
+				select {
+				case tb.tokens <- struct{}{}:
+				default: // ведро полное
+				}
+			}
+		}
+	}()
+	return tb
+}
+
+// Wait blocks until a token is available or ctx is done
+func (tb *tokenBucket) Wait(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-tb.tokens:
+		return nil
+	}
+}
+
+func (tb *tokenBucket) Stop() {
+	close(tb.stop)
+}
@@ -0,0 +1,130 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestRunBroadcastCountersSumToTotal(t *testing.T) {
+	results := map[int64]error{
+		1: nil,
+		2: &tgbotapi.Error{Code: 403, Message: "Forbidden: bot was blocked by the user"},
+		3: &tgbotapi.Error{Code: 403, Message: "Forbidden: user is deactivated"},
+		4: &tgbotapi.Error{Code: 400, Message: "Bad Request: chat not found"},
+		5: &tgbotapi.Error{Code: 400, Message: "Bad Request: chat not found"},
+		6: errors.New("dial tcp: i/o timeout"),
+		7: nil,
+	}
+	ids := []int64{1, 2, 3, 4, 5, 6, 7}
+
+	var calls atomic.Int32
+	stats := runBroadcast(context.Background(), ids, func(tgID int64) error {
+		calls.Add(1)
+		return results[tgID]
+	}, broadcastRun{Workers: 3})
+
+	if int(calls.Load()) != len(ids) {
+		t.Fatalf("expected %d sends, got %d", len(ids), calls.Load())
+	}
+	if stats.Total != 7 || stats.Delivered != 2 || stats.Blocked != 2 || stats.Errored != 3 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if stats.Skipped != 0 || stats.Done != len(ids) {
+		t.Fatalf("expected nothing skipped: %+v", stats)
+	}
+	if stats.Delivered+stats.Blocked+stats.Errored != stats.Total {
+		t.Fatalf("counters don't sum to total: %+v", stats)
+	}
+	if stats.Errors["Bad Request: chat not found"] != 2 || stats.Errors["network"] != 1 || len(stats.Errors) != 2 {
+		t.Fatalf("unexpected error tallies: %v", stats.Errors)
+	}
+}
+
+func TestParseBroadcastButtons(t *testing.T) {
+	text := "<b>Бонус!</b>\n[Играть](https://t.me/bot/app) [Канал](https://t.me/channel)\nЗаходи [Сайт](http://example.com/promo?a=1)"
+
+	clean, rows, err := parseBroadcastButtons(text)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if clean != "<b>Бонус!</b>\nЗаходи" {
+		t.Fatalf("unexpected text: %q", clean)
+	}
+	if len(rows) != 2 || len(rows[0]) != 2 || len(rows[1]) != 1 {
+		t.Fatalf("unexpected layout: %+v", rows)
+	}
+	if rows[0][0].Text != "Играть" || *rows[0][0].URL != "https://t.me/bot/app" || *rows[1][0].URL != "http://example.com/promo?a=1" {
+		t.Fatalf("unexpected buttons: %+v", rows)
+	}
+
+	// Без кнопок текст не меняется
+	if clean, rows, err := parseBroadcastButtons("просто [текст] без ссылок"); err != nil || rows != nil || clean != "просто [текст] без ссылок" {
+		t.Fatalf("plain text: %q %+v %v", clean, rows, err)
+	}
+
+	for _, bad := range []string{"[x](javascript:void)", "[x](ftp://host/file)", "[x](/relative)", "[ ](https://t.me)"} {
+		if _, _, err := parseBroadcastButtons(bad); err == nil {
+			t.Fatalf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestRunBroadcastCancelStopsAndReportsProgress(t *testing.T) {
+	ids := make([]int64, 100)
+	for i := range ids {
+		ids[i] = int64(i + 1)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var sent atomic.Int32
+	var progress []int
+	stats := runBroadcast(ctx, ids, func(tgID int64) error {
+		if sent.Add(1) == 30 {
+			cancel()
+		}
+		return nil
+	}, broadcastRun{
+		Workers:       4,
+		ProgressEvery: 10,
+		Progress:      func(done int, _ broadcastStats) { progress = append(progress, done) },
+	})
+
+	if stats.Skipped == 0 || stats.Delivered+stats.Skipped != stats.Total {
+		t.Fatalf("expected a partial broadcast: %+v", stats)
+	}
+	// Точка продолжения не дальше реально отправленного
+	if stats.Done > stats.Delivered || stats.Done < stats.Delivered-4 {
+		t.Fatalf("resume point %d doesn't match %d delivered", stats.Done, stats.Delivered)
+	}
+	if len(progress) == 0 {
+		t.Fatal("expected progress callbacks")
+	}
+	for i := 1; i < len(progress); i++ {
+		if progress[i] < progress[i-1] {
+			t.Fatalf("progress went backwards: %v", progress)
+		}
+	}
+}
+
+func TestRunBroadcastRateLimit(t *testing.T) {
+	ids := []int64{1, 2, 3, 4, 5, 6}
+
+	start := time.Now()
+	stats := runBroadcast(context.Background(), ids, func(int64) error { return nil }, broadcastRun{Workers: 1, Rate: 100})
+	elapsed := time.Since(start)
+
+	if stats.Delivered != len(ids) {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	// Первый токен сразу, остальные по одному в 10ms
+	if elapsed < 40*time.Millisecond {
+		t.Fatalf("rate limit not applied: %d sends took %v", len(ids), elapsed)
+	}
+}
@@ -0,0 +1,118 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"telegram_webapp/internal/service"
+)
+
+// StartDailyDigest sends admins a daily summary at the given time of day
+// (offset from local midnight). Stops together with the bot.
+func (b *AdminBot) StartDailyDigest(at time.Duration) {
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for {
+			wait := time.Until(nextDigestRun(time.Now(), at))
+			b.log.Info("next admin digest scheduled", "in", wait.Round(time.Second))
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-b.stopCh:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			text, err := b.buildDigest(ctx)
+			cancel()
+			if err != nil {
+				b.log.Error("failed to build admin digest", "error", err)
+				continue
+			}
+			b.notifyAdmins(text)
+		}
+	}()
+}
+
+// nextDigestRun returns the next moment after now at offset at from midnight
+func nextDigestRun(now time.Time, at time.Duration) time.Time {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	next := midnight.Add(at)
+	if !next.After(now) {
+		next = midnight.AddDate(0, 0, 1).Add(at)
+	}
+	return next
+}
+
+func (b *AdminBot) handleDigest(ctx context.Context) string {
+	text, err := b.buildDigest(ctx)
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+	return text
+}
+
+func (b *AdminBot) buildDigest(ctx context.Context) (string, error) {
+	d, err := b.adminService.GetDailyDigest(ctx, time.Now())
+	if err != nil {
+		return "", err
+	}
+	return formatDigest(d), nil
+}
+
+func formatDigest(d *service.Digest) string {
+	cur, prev := d.Day, d.Previous
+	return fmt.Sprintf(`<b>Сводка за %s</b>
+
+<b>Пользователи:</b>
+- Новых: %d %s
+- Всего: %d
+
+<b>Игры:</b>
+- Сыграно: %d %s
+- Поставлено (coins): %d %s
+- Прибыль казино (coins): %d %s
+
+<b>Платежи:</b>
+- Депозитов: %d %s
+- Зачислено (coins): %d %s
+- Выводов обработано: %d %s
+- Выведено (coins): %d %s
+- Ожидает вывода: %d
+
+<i>В скобках - изменение к %s</i>`,
+		cur.Day.Format("02.01.2006"),
+		cur.NewUsers, digestDelta(cur.NewUsers, prev.NewUsers),
+		d.TotalUsers,
+		cur.GamesPlayed, digestDelta(cur.GamesPlayed, prev.GamesPlayed),
+		cur.WageredCoins, digestDelta(cur.WageredCoins, prev.WageredCoins),
+		cur.HouseProfitCoins, digestDelta(cur.HouseProfitCoins, prev.HouseProfitCoins),
+		cur.Deposits, digestDelta(cur.Deposits, prev.Deposits),
+		cur.DepositedCoins, digestDelta(cur.DepositedCoins, prev.DepositedCoins),
+		cur.WithdrawalsProcessed, digestDelta(cur.WithdrawalsProcessed, prev.WithdrawalsProcessed),
+		cur.WithdrawnCoins, digestDelta(cur.WithdrawnCoins, prev.WithdrawnCoins),
+		d.PendingWithdraws,
+		prev.Day.Format("02.01"),
+	)
+}
+
+// digestDelta formats day-over-day change like "(+12, +15%)"
+func digestDelta(cur, prev int64) string {
+	diff := cur - prev
+	if prev == 0 {
+		return fmt.Sprintf("(%+d)", diff)
+	}
+	pct := float64(diff) * 100 / float64(abs64(prev))
+	return fmt.Sprintf("(%+d, %+.0f%%)", diff, pct)
+}
+
+func abs64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
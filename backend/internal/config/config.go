@@ -4,8 +4,12 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/game"
 	"telegram_webapp/internal/logger"
+	"telegram_webapp/internal/ton"
 
 	"github.com/joho/godotenv"
 )
@@ -19,12 +23,140 @@ type Config struct {
 	JWTSecret        string
 	AdminTelegramIDs []int64 // добавить в env tg id админов бота
 	AdminBotEnabled  bool
+	AdminStatsTTL    int // секунды кэша /stats
+
+	// Ежедневная сводка админам
+	AdminDigestEnabled bool
+	AdminDigestAt      time.Duration // время отправки от полуночи (время сервера)
 
 	// Game limits
 	MaxBet         int64
 	MinBet         int64
+	MaxBetCoins    int64
+	MinBetCoins    int64
 	GameRateLimit  int
 	GameRateWindow int
+	GameRateLimits map[string]int // лимит отдельных /game/* маршрутов, см. GAME_RATE_LIMITS
+
+	// Provably fair
+	FairRNGEnabled bool
+
+	// Простая Mines: мины из 12 клеток и преимущество казино
+	MinesCount     int
+	MinesHouseEdge float64 // доля, 0.05 = 5%
+
+	// Mines Pro: минимальный интервал между открытиями клеток, мс (0 - без ограничения)
+	MinesProRevealIntervalMs int
+	// Mines Pro: через сколько минут без хода игра закрывается автоматически
+	MinesProAbandonTTLMinutes int
+
+	// CoinFlip/RPS: выплата с учётом ставки до вычета преимущества казино
+	CoinFlipMultiplier float64
+	RPSMultiplier      float64
+	PvEHouseEdge       float64 // доля, 0.02 = 2%
+	RPSProMultiplier   float64 // выплата за выигранный матч best-of-N
+
+	// Защита от невезения (выключена по умолчанию, RTP не меняется)
+	LuckProtectionEnabled bool
+	LuckLossStreak        int
+	LuckWinStreak         int
+	LuckBoostPercent      float64
+	LuckMaxCredit         float64
+
+	// Переопределение анимаций по игре (остальные - domain.DefaultAnimations)
+	GameAnimations map[string]domain.Animation
+
+	// Бонусные средства выводятся только после отыгрыша x множитель (0 - без отыгрыша)
+	BonusWagerMultiplier int
+
+	// Бонус за возвращение после неактивности (0 гемов - выключен)
+	ComebackInactiveDays int
+	ComebackBonusGems    int64
+
+	// Бонус gems при низком балансе (/profile/bonus)
+	LowBalanceBonus domain.BonusConfig
+
+	// Награда за реферала: none | games | deposit
+	ReferralHoldMode  string
+	ReferralHoldGames int // для режима games
+
+	// Доля рефереру с комиссии за вывод
+	ReferralCommission domain.ReferralCommission
+
+	// Hot wallet monitor (пустой адрес - выключен)
+	HotWalletAddress       string
+	HotWalletMinTON        float64
+	HotWalletCheckInterval int // секунды
+
+	// Deposit watcher (автозачисление депозитов)
+	DepositWatcherEnabled bool
+	DepositPollInterval   int // секунды
+	DepositPageSize       int
+	DepositConcurrency    int
+
+	// Курс coins за 1 TON; фиксируется в депозите/выводе на момент создания
+	CoinsPerTON int
+
+	// Комиссия за вывод: фиксированная или процент с минимумом
+	WithdrawFee ton.WithdrawFee
+
+	// Защита от фермы аккаунтов: регистрации с одного IP
+	SignupIPLimit     int // 0 - не помечать
+	SignupIPWindow    int // часы
+	SignupFlaggedGems int64
+
+	// Откуда брать IP клиента: прокси, которым верим X-Forwarded-For, или
+	// заголовок платформы (CF-Connecting-IP, Fly-Client-IP). Пусто - как у gin
+	TrustedProxies []string
+	ClientIPHeader string
+
+	// Redis для rate limit (пустой адрес - лимитер пропускает всё)
+	RedisAddr     string
+	RedisPassword string
+	RedisDB       int
+
+	// WebSocket
+	WSMaxRooms           int
+	WSMatchTimeout       int            // секунды ожидания соперника, 0 - без лимита
+	WSMatchTimeoutByGame map[string]int // переопределение по типу игры
+	WSBotFallback        int            // секунды ожидания, после которых играет бот, 0 - выкл
+
+	WSTurnWarning    int // секунды до авто-хода, когда шлём turn_warning, 0 - выкл
+	WSRematchWindow  int // секунды после игры, когда принимается rematch, 0 - выкл
+	WSReconnectGrace int // секунды на переподключение посреди игры до техпоражения, 0 - сразу
+
+	WSAllowedOrigins  []string // Origin, с которых можно открыть сокет; пусто - любой
+	WSAllowQueryToken bool     // принимать JWT в ?token= (попадает в логи)
+	WSMaxConnsPerUser int      // открытых сокетов на пользователя, 0 - без лимита
+
+	// Комиссия платформы с банка PvP-игры
+	RakePercent   float64 // 0-100, 0 - победитель получает весь банк
+	RakeAccountID int64   // users.id, на который зачисляется platform_rake
+
+	// PvP Mines: размер поля и число мин
+	MinesPvPCells int
+	MinesPvPMines int
+
+	TxMetaMaxBytes int // лимит на сериализованную meta транзакции
+
+	MaxBodyBytes     int64 // лимит тела запроса, больше - 413
+	MaxRequestAmount int64 // потолок |amount|/|delta|/ставки в теле запроса
+
+	GamesExportMaxRows int // потолок строк в выгрузке истории игр
+
+	IdempotencyTTL int // секунды, сколько хранится ответ на запрос с Idempotency-Key
+
+	// Happy hours: буст выплат игр и наград квестов по расписанию (UTC)
+	HappyHours      []game.HappyHour
+	HappyHourMaxRTP float64 // буст не поднимает RTP игры выше этого
+
+	// Переводы gems между игроками
+	TransferMin      int64
+	TransferMax      int64 // 0 - без верхней границы
+	TransferDailyCap int64 // 0 - без дневного лимита
+
+	TransferMinAccountAge time.Duration // 0 - без ограничения
+	TransferMinWagered    int64         // 0 - без ограничения
 }
 
 // Загрузка конфига из env
@@ -75,6 +207,22 @@ func Load() *Config {
 
 	adminBotEnabled := os.Getenv("ADMIN_BOT_ENABLED") == "true"
 
+	adminStatsTTL := 300 // /stats пересчитывается раз в 5 минут
+	if v := os.Getenv("ADMIN_STATS_CACHE_SECONDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			adminStatsTTL = n
+		}
+	}
+
+	adminDigestEnabled := os.Getenv("ADMIN_DIGEST_ENABLED") == "true"
+
+	adminDigestAt := 9 * time.Hour // 09:00
+	if v := os.Getenv("ADMIN_DIGEST_TIME"); v != "" {
+		if t, err := time.Parse("15:04", v); err == nil {
+			adminDigestAt = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+		}
+	}
+
 	// Game limits (по умолчанию)
 	maxBet := int64(100000) //максимум 100к
 	if v := os.Getenv("MAX_BET"); v != "" {
@@ -90,6 +238,21 @@ func Load() *Config {
 		}
 	}
 
+	// Лимиты для ставок в coins (PvP), отдельно от gems
+	maxBetCoins := int64(1000)
+	if v := os.Getenv("MAX_BET_COINS"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			maxBetCoins = n
+		}
+	}
+
+	minBetCoins := int64(1)
+	if v := os.Getenv("MIN_BET_COINS"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			minBetCoins = n
+		}
+	}
+
 	gameRateLimit := 60 // макс действий за ->
 	if v := os.Getenv("GAME_RATE_LIMIT"); v != "" {
 		if n, err := strconv.Atoi(v); err == nil && n > 0 {
@@ -104,6 +267,524 @@ func Load() *Config {
 		}
 	}
 
+	// Лимиты отдельных маршрутов поверх GAME_RATE_LIMIT (по умолчанию выше для reveal/flip/move)
+	// Формат: mines-pro/reveal=300,dice=120 !! ЧЕРЕЗ ЗАПЯТУЮ В ENV !!
+	gameRateLimits := make(map[string]int)
+	if v := os.Getenv("GAME_RATE_LIMITS"); v != "" {
+		for _, pair := range strings.Split(v, ",") {
+			parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			if n, err := strconv.Atoi(strings.TrimSpace(parts[1])); err == nil && n > 0 {
+				gameRateLimits[strings.TrimSpace(parts[0])] = n
+			}
+		}
+	}
+
+	fairRNGEnabled := os.Getenv("FAIR_RNG_ENABLED") == "true" // выключено по умолчанию
+
+	minesCount := 4 // мин из 12 клеток
+	if v := os.Getenv("MINES_COUNT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 1 && n <= 11 {
+			minesCount = n
+		}
+	}
+
+	minesHouseEdge := 0.05 // 5% - множитель 1.42 при 4 минах
+	if v := os.Getenv("MINES_HOUSE_EDGE_PERCENT"); v != "" {
+		if n, err := strconv.ParseFloat(v, 64); err == nil && n > 0 && n < 100 {
+			minesHouseEdge = n / 100
+		}
+	}
+
+	minesProRevealIntervalMs := 0 // выключено, например 200 против скриптов
+	if v := os.Getenv("MINES_PRO_MIN_REVEAL_MS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			minesProRevealIntervalMs = n
+		}
+	}
+
+	minesProAbandonTTLMinutes := 60 // брошенная игра: кэшаут или возврат ставки
+	if v := os.Getenv("MINES_PRO_ABANDON_TTL_MINUTES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			minesProAbandonTTLMinutes = n
+		}
+	}
+
+	coinFlipMultiplier := 2.0 // до вычета преимущества казино
+	if v := os.Getenv("COINFLIP_MULTIPLIER"); v != "" {
+		if n, err := strconv.ParseFloat(v, 64); err == nil && n >= 1 {
+			coinFlipMultiplier = n
+		}
+	}
+
+	rpsMultiplier := 2.0 // выплата за победу, ничья - проигрыш ставки
+	if v := os.Getenv("RPS_MULTIPLIER"); v != "" {
+		if n, err := strconv.ParseFloat(v, 64); err == nil && n >= 1 {
+			rpsMultiplier = n
+		}
+	}
+
+	pveHouseEdge := 0.02 // 2% - множитель 1.96 вместо x2
+	if v := os.Getenv("PVE_HOUSE_EDGE_PERCENT"); v != "" {
+		if n, err := strconv.ParseFloat(v, 64); err == nil && n >= 0 && n < 100 {
+			pveHouseEdge = n / 100
+		}
+	}
+
+	rpsProMultiplier := 1.9 // матч выигрывается в 50% случаев
+	if v := os.Getenv("RPS_PRO_MULTIPLIER"); v != "" {
+		if n, err := strconv.ParseFloat(v, 64); err == nil && n >= 1 {
+			rpsProMultiplier = n
+		}
+	}
+
+	luckProtectionEnabled := os.Getenv("LUCK_PROTECTION_ENABLED") == "true"
+
+	luckLossStreak := 5 // проигрышей подряд до повышенного шанса
+	if v := os.Getenv("LUCK_LOSS_STREAK"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			luckLossStreak = n
+		}
+	}
+
+	luckWinStreak := 3 // побед подряд, после которых шанс снижается до погашения
+	if v := os.Getenv("LUCK_WIN_STREAK"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			luckWinStreak = n
+		}
+	}
+
+	luckBoostPercent := 5.0 // +5 п.п. к шансу
+	if v := os.Getenv("LUCK_BOOST_PERCENT"); v != "" {
+		if n, err := strconv.ParseFloat(v, 64); err == nil && n > 0 && n < 50 {
+			luckBoostPercent = n
+		}
+	}
+
+	luckMaxCredit := 5000.0 // гемов ожидаемого выигрыша в долг на игрока
+	if v := os.Getenv("LUCK_MAX_CREDIT"); v != "" {
+		if n, err := strconv.ParseFloat(v, 64); err == nil && n >= 0 {
+			luckMaxCredit = n
+		}
+	}
+
+	// Формат: wheel=5000:ease-out-cubic,dice=1200 !! ЧЕРЕЗ ЗАПЯТУЮ В ENV !!
+	gameAnimations := make(map[string]domain.Animation)
+	if v := os.Getenv("GAME_ANIMATIONS"); v != "" {
+		for _, pair := range strings.Split(v, ",") {
+			parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			spec := strings.SplitN(parts[1], ":", 2)
+			ms, err := strconv.Atoi(strings.TrimSpace(spec[0]))
+			if err != nil || ms < 0 {
+				continue
+			}
+			anim := domain.Animation{DurationMs: ms}
+			if len(spec) == 2 {
+				anim.Easing = strings.TrimSpace(spec[1])
+			}
+			gameAnimations[strings.TrimSpace(parts[0])] = anim
+		}
+	}
+
+	bonusWagerMultiplier := domain.DefaultBonusWagerMultiplier
+	if v := os.Getenv("BONUS_WAGER_MULTIPLIER"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			bonusWagerMultiplier = n
+		}
+	}
+
+	comebackInactiveDays := 14 // не играл и не заходил 2 недели
+	if v := os.Getenv("COMEBACK_INACTIVE_DAYS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			comebackInactiveDays = n
+		}
+	}
+
+	comebackBonusGems := int64(1000)
+	if v := os.Getenv("COMEBACK_BONUS_GEMS"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			comebackBonusGems = n
+		}
+	}
+
+	lowBalanceBonus := domain.DefaultBonusConfig()
+	if v := os.Getenv("BONUS_AMOUNT"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			lowBalanceBonus.Amount = n
+		}
+	}
+	if v := os.Getenv("BONUS_THRESHOLD"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			lowBalanceBonus.Threshold = n
+		}
+	}
+	if v := os.Getenv("BONUS_COOLDOWN_HOURS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			lowBalanceBonus.Cooldown = time.Duration(n) * time.Hour
+		}
+	}
+
+	transferMin := int64(domain.DefaultTransferMin)
+	if v := os.Getenv("TRANSFER_MIN"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			transferMin = n
+		}
+	}
+
+	transferMax := int64(domain.DefaultTransferMax)
+	if v := os.Getenv("TRANSFER_MAX"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			transferMax = n
+		}
+	}
+
+	// Сколько gems один игрок может отправить за сутки (UTC)
+	transferDailyCap := int64(domain.DefaultTransferDailyCap)
+	if v := os.Getenv("TRANSFER_DAILY_CAP"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			transferDailyCap = n
+		}
+	}
+
+	// Анти-ферма: свежий аккаунт без ставок не может сразу слить gems на другой
+	transferMinAccountAge := domain.DefaultTransferMinAccountAge
+	if v := os.Getenv("TRANSFER_MIN_ACCOUNT_AGE_HOURS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			transferMinAccountAge = time.Duration(n) * time.Hour
+		}
+	}
+
+	transferMinWagered := int64(domain.DefaultTransferMinWagered)
+	if v := os.Getenv("TRANSFER_MIN_WAGERED"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			transferMinWagered = n
+		}
+	}
+
+	coinsPerTON := ton.CoinsPerTON
+	if v := os.Getenv("COINS_PER_TON"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			coinsPerTON = n
+		}
+	}
+
+	withdrawFee := ton.DefaultWithdrawFee()
+	switch v := ton.WithdrawFeeMode(strings.ToLower(os.Getenv("WITHDRAW_FEE_MODE"))); v {
+	case ton.WithdrawFeeModeFixed, ton.WithdrawFeeModePercent:
+		withdrawFee.Mode = v
+	case "":
+	default:
+		logger.Warn("invalid WITHDRAW_FEE_MODE, using fixed", "value", v)
+	}
+	if v := os.Getenv("WITHDRAW_FEE_COINS"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			withdrawFee.FixedCoins = n
+		}
+	}
+	if v := os.Getenv("WITHDRAW_FEE_PERCENT"); v != "" {
+		if n, err := strconv.ParseFloat(v, 64); err == nil && n >= 0 && n <= 100 {
+			withdrawFee.Percent = n
+		}
+	}
+	if v := os.Getenv("WITHDRAW_FEE_MIN_COINS"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			withdrawFee.MinCoins = n
+		}
+	}
+	// Комиссия съедала бы весь минимальный вывод: пользователь отдал бы coins за 0 TON
+	if fee := ton.CalculateWithdrawFeeCoins(ton.MinWithdrawCoins, withdrawFee); fee >= ton.MinWithdrawCoins {
+		logger.Fatal("withdraw fee must be below the minimum withdrawal", "fee_coins", fee, "min_withdraw_coins", ton.MinWithdrawCoins)
+	}
+
+	referralHoldMode := "none" // по умолчанию награда сразу
+	if v := os.Getenv("REFERRAL_HOLD_MODE"); v != "" {
+		referralHoldMode = strings.ToLower(strings.TrimSpace(v))
+	}
+
+	referralHoldGames := 3 // реферал должен сыграть 3 игры
+	if v := os.Getenv("REFERRAL_HOLD_GAMES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			referralHoldGames = n
+		}
+	}
+
+	referralCommission := domain.DefaultReferralCommission()
+	if v := os.Getenv("REFERRAL_COMMISSION_PERCENT"); v != "" {
+		if n, err := strconv.ParseFloat(v, 64); err == nil && n >= 0 && n <= 100 {
+			referralCommission.Percent = n
+		}
+	}
+	switch v := os.Getenv("REFERRAL_COMMISSION_ROUNDING"); v {
+	case domain.CommissionRoundFloor, domain.CommissionRoundCeil, domain.CommissionRoundHalf:
+		referralCommission.Rounding = v
+	case "":
+	default:
+		logger.Warn("invalid REFERRAL_COMMISSION_ROUNDING, using round", "value", v)
+	}
+	if v := os.Getenv("REFERRAL_COMMISSION_MIN"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			referralCommission.MinCoins = n
+		}
+	}
+
+	wsMaxRooms := 1000 // выше - хаб помечается degraded в readyz
+	if v := os.Getenv("WS_MAX_ROOMS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			wsMaxRooms = n
+		}
+	}
+
+	wsMatchTimeout := 60 // ждём соперника не дольше минуты
+	if v := os.Getenv("WS_MATCH_TIMEOUT_SECONDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			wsMatchTimeout = n
+		}
+	}
+
+	// Формат: rps=30,mines=90 !! ЧЕРЕЗ ЗАПЯТУЮ В ENV !!
+	wsMatchTimeoutByGame := make(map[string]int)
+	if v := os.Getenv("WS_MATCH_TIMEOUTS"); v != "" {
+		for _, pair := range strings.Split(v, ",") {
+			parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			if n, err := strconv.Atoi(strings.TrimSpace(parts[1])); err == nil && n >= 0 {
+				wsMatchTimeoutByGame[strings.TrimSpace(parts[0])] = n
+			}
+		}
+	}
+
+	// 0 - бот не подключается, игрок ждёт до WS_MATCH_TIMEOUT_SECONDS
+	wsBotFallback := 0
+	if v := os.Getenv("WS_BOT_FALLBACK_SECONDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			wsBotFallback = n
+		}
+	}
+
+	wsTurnWarning := 3 // предупреждаем за 3 секунды до хода бота
+	if v := os.Getenv("WS_TURN_WARNING_SECONDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			wsTurnWarning = n
+		}
+	}
+
+	wsRematchWindow := 15
+	if v := os.Getenv("WS_REMATCH_WINDOW_SECONDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			wsRematchWindow = n
+		}
+	}
+
+	rakePercent := 0.0
+	if v := os.Getenv("PVP_RAKE_PERCENT"); v != "" {
+		if n, err := strconv.ParseFloat(v, 64); err == nil && n >= 0 && n < 100 {
+			rakePercent = n
+		}
+	}
+	var rakeAccountID int64
+	if v := os.Getenv("PVP_RAKE_ACCOUNT_ID"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			rakeAccountID = n
+		}
+	}
+	// Иначе комиссия удерживается с победителя и пропадает без записи
+	if rakePercent > 0 && rakeAccountID == 0 {
+		logger.Fatal("PVP_RAKE_PERCENT requires PVP_RAKE_ACCOUNT_ID")
+	}
+
+	wsReconnectGrace := 15
+	if v := os.Getenv("WS_RECONNECT_GRACE_SECONDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			wsReconnectGrace = n
+		}
+	}
+
+	var wsAllowedOrigins []string
+	origins := os.Getenv("WS_ALLOWED_ORIGINS")
+	if origins == "" {
+		origins = os.Getenv("ALLOWED_ORIGIN")
+	}
+	for _, o := range strings.Split(origins, ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			wsAllowedOrigins = append(wsAllowedOrigins, o)
+		}
+	}
+
+	wsMaxConnsPerUser := 3 // запас на переподключение, пока старый сокет не закрылся
+	if v := os.Getenv("WS_MAX_CONNS_PER_USER"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			wsMaxConnsPerUser = n
+		}
+	}
+
+	minesPvPCells := 12
+	if v := os.Getenv("MINES_PVP_CELLS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 2 {
+			minesPvPCells = n
+		}
+	}
+
+	minesPvPMines := 4
+	if v := os.Getenv("MINES_PVP_MINES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 1 {
+			minesPvPMines = n
+		}
+	}
+	if minesPvPMines >= minesPvPCells {
+		// хотя бы одна клетка должна быть безопасной - иначе значения по умолчанию
+		minesPvPCells, minesPvPMines = 12, 4
+	}
+
+	var happyHours []game.HappyHour
+	if v := os.Getenv("HAPPY_HOURS"); v != "" {
+		parsed, err := game.ParseHappyHours(v)
+		if err != nil {
+			logger.Warn("HAPPY_HOURS ignored", "error", err)
+		} else {
+			happyHours = parsed
+		}
+	}
+
+	happyHourMaxRTP := game.DefaultHappyHourMaxRTP
+	if v := os.Getenv("HAPPY_HOUR_MAX_RTP"); v != "" {
+		if n, err := strconv.ParseFloat(v, 64); err == nil && n > 0 && n <= 1 {
+			happyHourMaxRTP = n
+		}
+	}
+
+	txMetaMaxBytes := 8 * 1024
+	if v := os.Getenv("TX_META_MAX_KB"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			txMetaMaxBytes = n * 1024
+		}
+	}
+
+	maxBodyBytes := int64(64 * 1024)
+	if v := os.Getenv("MAX_BODY_KB"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			maxBodyBytes = n * 1024
+		}
+	}
+
+	maxRequestAmount := int64(1_000_000_000)
+	if v := os.Getenv("MAX_REQUEST_AMOUNT"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			maxRequestAmount = n
+		}
+	}
+
+	gamesExportMaxRows := 10000
+	if v := os.Getenv("GAMES_EXPORT_MAX_ROWS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			gamesExportMaxRows = n
+		}
+	}
+
+	idempotencyTTL := 3600 // повтор с тем же ключом в течение часа вернёт сохранённый ответ
+	if v := os.Getenv("IDEMPOTENCY_TTL_SECONDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			idempotencyTTL = n
+		}
+	}
+
+	hotWalletAddress := os.Getenv("HOT_WALLET_ADDRESS")
+	if hotWalletAddress == "" {
+		hotWalletAddress = os.Getenv("TON_PLATFORM_WALLET") // выплаты идут с платформенного кошелька
+	}
+
+	hotWalletMinTON := 10.0 // ниже - алерт админам и пауза автовыплат
+	if v := os.Getenv("HOT_WALLET_MIN_TON"); v != "" {
+		if n, err := strconv.ParseFloat(v, 64); err == nil && n >= 0 {
+			hotWalletMinTON = n
+		}
+	}
+
+	hotWalletCheckInterval := 300 // проверка раз в 5 минут
+	if v := os.Getenv("HOT_WALLET_CHECK_SECONDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			hotWalletCheckInterval = n
+		}
+	}
+
+	// Сканер депозитов включён, если задан платформенный кошелёк; DEPOSIT_WATCHER_ENABLED=false - выключить
+	depositWatcherEnabled := os.Getenv("TON_PLATFORM_WALLET") != ""
+	if v := os.Getenv("DEPOSIT_WATCHER_ENABLED"); v != "" {
+		depositWatcherEnabled = v == "true"
+	}
+
+	depositPollInterval := 30 // опрос TON API раз в 30 секунд
+	if v := os.Getenv("DEPOSIT_POLL_SECONDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			depositPollInterval = n
+		}
+	}
+
+	depositPageSize := 50 // транзакций за запрос и за пачку зачислений
+	if v := os.Getenv("DEPOSIT_PAGE_SIZE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			depositPageSize = n
+		}
+	}
+
+	depositConcurrency := 4 // параллельных зачислений в пачке
+	if v := os.Getenv("DEPOSIT_CONCURRENCY"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			depositConcurrency = n
+		}
+	}
+
+	// Сколько аккаунтов с одного IP за окно считаются нормой, следующие помечаются
+	signupIPLimit := 0
+	if v := os.Getenv("SIGNUP_IP_LIMIT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			signupIPLimit = n
+		}
+	}
+
+	signupIPWindow := 24
+	if v := os.Getenv("SIGNUP_IP_WINDOW_HOURS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			signupIPWindow = n
+		}
+	}
+
+	signupFlaggedGems := int64(0) // помеченный аккаунт начинает с нуля
+	if v := os.Getenv("SIGNUP_FLAGGED_GEMS"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			signupFlaggedGems = n
+		}
+	}
+
+	var trustedProxies []string
+	if v := os.Getenv("TRUSTED_PROXIES"); v != "" {
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				trustedProxies = append(trustedProxies, p)
+			}
+		}
+	}
+	clientIPHeader := strings.TrimSpace(os.Getenv("CLIENT_IP_HEADER"))
+
+	// gin по умолчанию верит X-Forwarded-For от кого угодно: подставив туда
+	// случайный адрес, ферма обходит лимит регистраций с одного IP
+	if signupIPLimit > 0 && len(trustedProxies) == 0 && clientIPHeader == "" {
+		logger.Fatal("SIGNUP_IP_LIMIT requires TRUSTED_PROXIES or CLIENT_IP_HEADER")
+	}
+
+	redisDB := 0
+	if v := os.Getenv("REDIS_DB"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			redisDB = n
+		}
+	}
+
 	return &Config{
 		AppPort:          port,
 		DatabaseURL:      dbURL,
@@ -113,9 +794,103 @@ func Load() *Config {
 		JWTSecret:        jwtSecret,
 		AdminTelegramIDs: adminIDs,
 		AdminBotEnabled:  adminBotEnabled,
+		AdminStatsTTL:    adminStatsTTL,
 		MaxBet:           maxBet,
 		MinBet:           minBet,
+		MaxBetCoins:      maxBetCoins,
+		MinBetCoins:      minBetCoins,
 		GameRateLimit:    gameRateLimit,
 		GameRateWindow:   gameRateWindow,
+		GameRateLimits:   gameRateLimits,
+		FairRNGEnabled:   fairRNGEnabled,
+		MinesCount:       minesCount,
+		MinesHouseEdge:   minesHouseEdge,
+
+		MinesProRevealIntervalMs:  minesProRevealIntervalMs,
+		MinesProAbandonTTLMinutes: minesProAbandonTTLMinutes,
+
+		CoinFlipMultiplier: coinFlipMultiplier,
+		RPSMultiplier:      rpsMultiplier,
+		PvEHouseEdge:       pveHouseEdge,
+		RPSProMultiplier:   rpsProMultiplier,
+
+		AdminDigestEnabled: adminDigestEnabled,
+		AdminDigestAt:      adminDigestAt,
+
+		GameAnimations: gameAnimations,
+
+		LuckProtectionEnabled: luckProtectionEnabled,
+		LuckLossStreak:        luckLossStreak,
+		LuckWinStreak:         luckWinStreak,
+		LuckBoostPercent:      luckBoostPercent,
+		LuckMaxCredit:         luckMaxCredit,
+
+		BonusWagerMultiplier: bonusWagerMultiplier,
+		ComebackInactiveDays: comebackInactiveDays,
+		ComebackBonusGems:    comebackBonusGems,
+		LowBalanceBonus:      lowBalanceBonus,
+		TransferMin:          transferMin,
+		TransferMax:          transferMax,
+		TransferDailyCap:     transferDailyCap,
+
+		TransferMinAccountAge: transferMinAccountAge,
+		TransferMinWagered:    transferMinWagered,
+
+		ReferralHoldMode:   referralHoldMode,
+		ReferralHoldGames:  referralHoldGames,
+		ReferralCommission: referralCommission,
+		WSMaxRooms:         wsMaxRooms,
+
+		WSMatchTimeout:       wsMatchTimeout,
+		WSMatchTimeoutByGame: wsMatchTimeoutByGame,
+		WSBotFallback:        wsBotFallback,
+
+		WSTurnWarning:    wsTurnWarning,
+		WSRematchWindow:  wsRematchWindow,
+		WSReconnectGrace: wsReconnectGrace,
+		RakePercent:      rakePercent,
+		RakeAccountID:    rakeAccountID,
+
+		WSAllowedOrigins:  wsAllowedOrigins,
+		WSAllowQueryToken: os.Getenv("WS_ALLOW_QUERY_TOKEN") == "true",
+		WSMaxConnsPerUser: wsMaxConnsPerUser,
+
+		MinesPvPCells: minesPvPCells,
+		MinesPvPMines: minesPvPMines,
+
+		TxMetaMaxBytes: txMetaMaxBytes,
+
+		MaxBodyBytes:     maxBodyBytes,
+		MaxRequestAmount: maxRequestAmount,
+
+		GamesExportMaxRows: gamesExportMaxRows,
+
+		IdempotencyTTL: idempotencyTTL,
+
+		HappyHours:      happyHours,
+		HappyHourMaxRTP: happyHourMaxRTP,
+
+		HotWalletAddress:       hotWalletAddress,
+		HotWalletMinTON:        hotWalletMinTON,
+		HotWalletCheckInterval: hotWalletCheckInterval,
+
+		DepositWatcherEnabled: depositWatcherEnabled,
+		DepositPollInterval:   depositPollInterval,
+		DepositPageSize:       depositPageSize,
+		DepositConcurrency:    depositConcurrency,
+
+		CoinsPerTON: coinsPerTON,
+		WithdrawFee: withdrawFee,
+
+		SignupIPLimit:     signupIPLimit,
+		SignupIPWindow:    signupIPWindow,
+		SignupFlaggedGems: signupFlaggedGems,
+
+		TrustedProxies: trustedProxies,
+		ClientIPHeader: clientIPHeader,
+
+		RedisAddr:     os.Getenv("REDIS_ADDR"),
+		RedisPassword: os.Getenv("REDIS_PASSWORD"),
+		RedisDB:       redisDB,
 	}
 }
@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+// Действия админов, попадающие в admin_actions
+const (
+	AdminActionAddGems           = "add_gems"
+	AdminActionSetGems           = "set_gems"
+	AdminActionAddCoins          = "add_coins"
+	AdminActionAddGK             = "add_gk"
+	AdminActionBan               = "ban"
+	AdminActionUnban             = "unban"
+	AdminActionExclude           = "exclude"
+	AdminActionApproveWithdrawal = "approve_withdrawal"
+	AdminActionRejectWithdrawal  = "reject_withdrawal"
+	AdminActionCreateQuest       = "create_quest"
+	AdminActionDeleteQuest       = "delete_quest"
+	AdminActionToggleQuest       = "toggle_quest"
+	AdminActionSetLimits         = "set_limits"
+	AdminActionAddAdmin          = "add_admin"
+	AdminActionRemoveAdmin       = "remove_admin"
+	AdminActionBroadcast         = "broadcast"
+	AdminActionCancelBroadcast   = "cancel_broadcast"
+)
+
+// AdminAction is one entry of the admin audit log. TargetUserID is 0 when the
+// action isn't about a user (quests, limits).
+type AdminAction struct {
+	ID           int64                  `db:"id" json:"id"`
+	AdminTgID    int64                  `db:"admin_tg_id" json:"admin_tg_id"`
+	Action       string                 `db:"action" json:"action"`
+	TargetUserID int64                  `db:"target_user_id" json:"target_user_id,omitempty"`
+	Details      map[string]interface{} `db:"details" json:"details,omitempty"`
+	CreatedAt    time.Time              `db:"created_at" json:"created_at"`
+}
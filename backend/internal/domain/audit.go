@@ -48,6 +48,7 @@ const (
 	AuditActionBalanceCredit = "balance_credit"
 	AuditActionBalanceDebit  = "balance_debit"
 	AuditActionBonusClaim    = "bonus_claim"
+	AuditActionTransfer      = "transfer"
 
 	// Admin actions
 	AuditActionAdminSetGems  = "admin_set_gems"
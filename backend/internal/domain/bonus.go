@@ -0,0 +1,19 @@
+package domain
+
+import "time"
+
+// DefaultBonusWagerMultiplier - промо-coins (админские начисления, награды квестов)
+// выводятся только после того, как поставлено x5 от начисленного
+const DefaultBonusWagerMultiplier = 5
+
+// BonusConfig - бонус gems при низком балансе
+type BonusConfig struct {
+	Amount    int64         // сколько gems начисляется
+	Threshold int64         // бонус доступен, пока gems меньше порога
+	Cooldown  time.Duration // между бонусами одного пользователя, 0 - без ограничения
+}
+
+// DefaultBonusConfig - 10000 gems при балансе меньше 100, не чаще раза в 6 часов
+func DefaultBonusConfig() BonusConfig {
+	return BonusConfig{Amount: 10000, Threshold: 100, Cooldown: 6 * time.Hour}
+}
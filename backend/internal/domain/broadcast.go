@@ -0,0 +1,34 @@
+package domain
+
+import "time"
+
+// Статусы рассылки
+const (
+	BroadcastScheduled = "scheduled"
+	BroadcastSending   = "sending"
+	BroadcastDone      = "done"
+	BroadcastCancelled = "cancelled"
+	BroadcastFailed    = "failed"
+)
+
+// Broadcast is an admin broadcast: the message, its audience and delivery results.
+// Delivered + Blocked + Errored == Total once it is done; a cancelled broadcast
+// stops at Progress.
+type Broadcast struct {
+	ID          int64      `db:"id" json:"id"`
+	AdminTgID   int64      `db:"admin_tg_id" json:"admin_tg_id"`
+	Segment     string     `db:"segment" json:"segment"`
+	Text        string     `db:"text" json:"text"`
+	PhotoFileID string     `db:"photo_file_id" json:"photo_file_id,omitempty"`
+	Status      string     `db:"status" json:"status"`
+	ScheduledAt time.Time  `db:"scheduled_at" json:"scheduled_at"`
+	StartedAt   *time.Time `db:"started_at" json:"started_at,omitempty"`
+	FinishedAt  *time.Time `db:"finished_at" json:"finished_at,omitempty"`
+	Total       int        `db:"total" json:"total"`
+	Delivered   int        `db:"delivered" json:"delivered"`
+	Blocked     int        `db:"blocked" json:"blocked"`
+	Errored     int        `db:"errored" json:"errored"`
+	Progress    int        `db:"progress" json:"progress"` // получателей с начала списка уже обработано
+	ProgressAt  *time.Time `db:"progress_at" json:"progress_at,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+}
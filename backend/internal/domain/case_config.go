@@ -0,0 +1,13 @@
+package domain
+
+import "encoding/json"
+
+// CaseConfig - кейс из таблицы case_configs, Prizes - JSON таблица призов
+type CaseConfig struct {
+	CaseID    string          `json:"case_id"`
+	Name      string          `json:"name"`
+	Cost      int64           `json:"cost"`
+	Prizes    json.RawMessage `json:"prizes"`
+	Active    bool            `json:"active"`
+	SortOrder int             `json:"sort_order"`
+}
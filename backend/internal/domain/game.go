@@ -13,17 +13,62 @@ const (
 	GameTypeCase     GameType = "case"
 	GameTypeDice     GameType = "dice"
 	GameTypeWheel    GameType = "wheel"
+	GameTypeRPSPro   GameType = "rps_pro"
 )
 
-// GameMode - режим игры
+// Valid reports whether the game type is one of the known games
+func (gt GameType) Valid() bool {
+	switch gt {
+	case GameTypeRPS, GameTypeMines, GameTypeMinesPro, GameTypeCoinflip,
+		GameTypeCase, GameTypeDice, GameTypeWheel, GameTypeRPSPro:
+		return true
+	}
+	return false
+}
+
+// GameLimit - лимит ставок игры в одной валюте из таблицы game_limits,
+// переопределяет общие MIN_BET/MAX_BET без редеплоя
+type GameLimit struct {
+	GameType  GameType  `json:"game_type"`
+	Currency  Currency  `json:"currency"`
+	MinBet    int64     `json:"min_bet"`
+	MaxBet    int64     `json:"max_bet"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Animation - рекомендуемая анимация раскрытия результата, чтобы клиент не расходился с сервером
+type Animation struct {
+	DurationMs int    `json:"duration_ms"`
+	Easing     string `json:"easing"`
+}
+
+// DefaultAnimations returns built-in reveal timings per game
+func DefaultAnimations() map[GameType]Animation {
+	return map[GameType]Animation{
+		GameTypeRPS:      {DurationMs: 1000, Easing: "ease-out"},
+		GameTypeMines:    {DurationMs: 600, Easing: "ease-in-out"},
+		GameTypeMinesPro: {DurationMs: 300, Easing: "ease-out"},
+		GameTypeCoinflip: {DurationMs: 1200, Easing: "ease-in-out"},
+		GameTypeCase:     {DurationMs: 4000, Easing: "ease-out-cubic"},
+		GameTypeDice:     {DurationMs: 1500, Easing: "ease-out"},
+		GameTypeWheel:    {DurationMs: 5000, Easing: "ease-out-cubic"},
+		GameTypeRPSPro:   {DurationMs: 1000, Easing: "ease-out"},
+	}
+}
+
+// GameMode - режим игры: против казино (включая кейсы и бота в WS-комнате) или против игрока
 type GameMode string
 
 const (
-	GameModePVP  GameMode = "pvp"
-	GameModePVE  GameMode = "pve"
-	GameModeSolo GameMode = "solo"
+	GameModePVP GameMode = "pvp"
+	GameModePVE GameMode = "pve"
 )
 
+// Valid reports whether the mode is one of the known modes
+func (m GameMode) Valid() bool {
+	return m == GameModePVP || m == GameModePVE
+}
+
 // GameResult - результат игры
 type GameResult string
 
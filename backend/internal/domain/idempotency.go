@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// IdempotencyRecord - сохранённый ответ на запрос с заголовком Idempotency-Key
+type IdempotencyRecord struct {
+	UserID      int64     `db:"user_id" json:"user_id"`
+	Key         string    `db:"key" json:"key"`
+	RequestHash string    `db:"request_hash" json:"request_hash"` // sha256 метода, пути и тела
+	StatusCode  int       `db:"status_code" json:"status_code"`   // 0 - запрос ещё выполняется
+	Response    []byte    `db:"response" json:"-"`
+	ExpiresAt   time.Time `db:"expires_at" json:"expires_at"`
+}
+
+// Pending reports whether the original request hasn't finished yet
+func (r *IdempotencyRecord) Pending() bool {
+	return r.StatusCode == 0
+}
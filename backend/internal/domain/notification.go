@@ -0,0 +1,54 @@
+package domain
+
+import "time"
+
+// NotificationCategory - категория уведомлений пользователю
+type NotificationCategory string
+
+const (
+	NotificationBigWin         NotificationCategory = "big_win"
+	NotificationWithdrawal     NotificationCategory = "withdrawal"
+	NotificationQuest          NotificationCategory = "quest"
+	NotificationPromo          NotificationCategory = "promo"
+	NotificationStreakReminder NotificationCategory = "streak_reminder"
+)
+
+// NotificationPrefs holds per-category notification toggles for a user
+type NotificationPrefs struct {
+	UserID         int64     `db:"user_id" json:"-"`
+	BigWin         bool      `db:"big_win" json:"big_win"`
+	Withdrawal     bool      `db:"withdrawal" json:"withdrawal"`
+	Quest          bool      `db:"quest" json:"quest"`
+	Promo          bool      `db:"promo" json:"promo"`
+	StreakReminder bool      `db:"streak_reminder" json:"streak_reminder"`
+	UpdatedAt      time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// DefaultNotificationPrefs returns preferences for users without a stored row
+func DefaultNotificationPrefs(userID int64) *NotificationPrefs {
+	return &NotificationPrefs{
+		UserID:         userID,
+		BigWin:         true,
+		Withdrawal:     true,
+		Quest:          true,
+		Promo:          true,
+		StreakReminder: false,
+	}
+}
+
+// Allows reports whether the user wants notifications of the given category
+func (p *NotificationPrefs) Allows(category NotificationCategory) bool {
+	switch category {
+	case NotificationBigWin:
+		return p.BigWin
+	case NotificationWithdrawal:
+		return p.Withdrawal
+	case NotificationQuest:
+		return p.Quest
+	case NotificationPromo:
+		return p.Promo
+	case NotificationStreakReminder:
+		return p.StreakReminder
+	}
+	return false
+}
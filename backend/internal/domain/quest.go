@@ -31,12 +31,21 @@ type Quest struct {
 	ActionType  ActionType `db:"action_type" json:"action_type"`
 	TargetCount int        `db:"target_count" json:"target_count"`
 	RewardGems  int64      `db:"reward_gems" json:"reward_gems"`
+	RewardCoins int64      `db:"reward_coins" json:"reward_coins"`
+	RewardGK    int64      `db:"reward_gk" json:"reward_gk"`
 	IsActive    bool       `db:"is_active" json:"is_active"`
 	SortOrder   int        `db:"sort_order" json:"sort_order"`
 	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
 	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
 }
 
+//Награда за задание во всех валютах
+type QuestReward struct {
+	Gems  int64 `json:"gems"`
+	Coins int64 `json:"coins"`
+	GK    int64 `json:"gk"`
+}
+
 //Прогресс пользователя по заданию
 type UserQuest struct {
 	ID              int64      `db:"id" json:"id"`
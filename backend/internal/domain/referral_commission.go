@@ -0,0 +1,67 @@
+package domain
+
+import "math"
+
+// Округление комиссии реферера
+const (
+	CommissionRoundFloor = "floor"
+	CommissionRoundCeil  = "ceil"
+	CommissionRoundHalf  = "round" // половина округляется вверх
+)
+
+// ReferralCommission - доля комиссии за вывод, которая уходит рефереру
+type ReferralCommission struct {
+	Percent  float64 // доля комиссии, 0-100
+	Rounding string  // floor | ceil | round
+	MinCoins int64   // минимум при ненулевой комиссии, 0 - без минимума
+}
+
+// DefaultReferralCommission - 50% комиссии с округлением половины вверх,
+// чтобы комиссия в 1 coin не давала рефереру 0
+func DefaultReferralCommission() ReferralCommission {
+	return ReferralCommission{Percent: 50, Rounding: CommissionRoundHalf}
+}
+
+// Compute returns the referrer's share of a withdrawal fee. Percent is applied in
+// basis points so the rounding is exact; the minimum applies only to a non-zero fee
+// and the result never exceeds the fee itself.
+func (rc ReferralCommission) Compute(feeCoins int64) int64 {
+	if feeCoins <= 0 || rc.Percent <= 0 {
+		return 0
+	}
+
+	bps := int64(math.Round(rc.Percent * 100))
+	num := feeCoins * bps
+	var commission int64
+	switch rc.Rounding {
+	case CommissionRoundFloor:
+		commission = num / 10000
+	case CommissionRoundCeil:
+		commission = (num + 9999) / 10000
+	default:
+		commission = (num + 5000) / 10000
+	}
+
+	if commission < rc.MinCoins {
+		commission = rc.MinCoins
+	}
+	if commission > feeCoins {
+		commission = feeCoins
+	}
+	return commission
+}
+
+// Meta describes the computation for the referral_commission transaction
+func (rc ReferralCommission) Meta(feeCoins, commission int64) map[string]interface{} {
+	rounding := rc.Rounding
+	if rounding != CommissionRoundFloor && rounding != CommissionRoundCeil {
+		rounding = CommissionRoundHalf
+	}
+	return map[string]interface{}{
+		"total_fee":      feeCoins,
+		"commission_pct": rc.Percent,
+		"rounding":       rounding,
+		"min_coins":      rc.MinCoins,
+		"commission":     commission,
+	}
+}
@@ -0,0 +1,36 @@
+package domain
+
+import "testing"
+
+func TestReferralCommissionRounding(t *testing.T) {
+	cases := []struct {
+		name string
+		rc   ReferralCommission
+		fee  int64
+		want int64
+	}{
+		{"default pays half of 1 coin fee", DefaultReferralCommission(), 1, 1},
+		{"default odd fee", DefaultReferralCommission(), 3, 2},
+		{"default even fee", DefaultReferralCommission(), 4, 2},
+		{"floor keeps old behaviour", ReferralCommission{Percent: 50, Rounding: CommissionRoundFloor}, 1, 0},
+		{"floor odd fee", ReferralCommission{Percent: 50, Rounding: CommissionRoundFloor}, 3, 1},
+		{"ceil odd fee", ReferralCommission{Percent: 50, Rounding: CommissionRoundCeil}, 3, 2},
+		{"ceil exact", ReferralCommission{Percent: 50, Rounding: CommissionRoundCeil}, 4, 2},
+		{"fractional percent", ReferralCommission{Percent: 12.5, Rounding: CommissionRoundFloor}, 17, 2},
+		{"minimum lifts floor zero", ReferralCommission{Percent: 10, Rounding: CommissionRoundFloor, MinCoins: 1}, 3, 1},
+		{"minimum capped by fee", ReferralCommission{Percent: 50, MinCoins: 5}, 2, 2},
+		{"no fee no commission", ReferralCommission{Percent: 50, MinCoins: 5}, 0, 0},
+		{"zero percent", ReferralCommission{Percent: 0, MinCoins: 5}, 10, 0},
+		{"never above fee", ReferralCommission{Percent: 100, Rounding: CommissionRoundCeil}, 7, 7},
+	}
+	for _, c := range cases {
+		if got := c.rc.Compute(c.fee); got != c.want {
+			t.Errorf("%s: Compute(%d) = %d, want %d", c.name, c.fee, got, c.want)
+		}
+	}
+
+	meta := DefaultReferralCommission().Meta(3, 2)
+	if meta["commission_pct"] != 50.0 || meta["rounding"] != CommissionRoundHalf || meta["commission"] != int64(2) {
+		t.Fatalf("unexpected meta: %v", meta)
+	}
+}
@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SupportCategory - тип рассинхрона, о котором сообщает клиент
+type SupportCategory string
+
+const (
+	SupportBalance    SupportCategory = "balance"
+	SupportGameState  SupportCategory = "game_state"
+	SupportDeposit    SupportCategory = "deposit"
+	SupportWithdrawal SupportCategory = "withdrawal"
+	SupportOther      SupportCategory = "other"
+)
+
+// SupportReport is a client desync report with the server balance snapshot for comparison
+type SupportReport struct {
+	ID          int64           `db:"id" json:"id"`
+	UserID      int64           `db:"user_id" json:"user_id"`
+	Username    string          `db:"-" json:"username,omitempty"`
+	Category    SupportCategory `db:"category" json:"category"`
+	Message     string          `db:"message" json:"message"`
+	ClientState json.RawMessage `db:"client_state" json:"client_state,omitempty"`
+	ServerState json.RawMessage `db:"server_state" json:"server_state"`
+	Status      string          `db:"status" json:"status"`
+	CreatedAt   time.Time       `db:"created_at" json:"created_at"`
+}
@@ -10,3 +10,13 @@ type Transaction struct {
 	Meta      map[string]interface{} `db:"meta" json:"meta,omitempty"`
 	CreatedAt time.Time              `db:"created_at" json:"created_at"`
 }
+
+// Default transfer limits, overridden by TRANSFER_MIN / TRANSFER_MAX / TRANSFER_DAILY_CAP /
+// TRANSFER_MIN_ACCOUNT_AGE_HOURS / TRANSFER_MIN_WAGERED
+const (
+	DefaultTransferMin           = 100
+	DefaultTransferMax           = 100000
+	DefaultTransferDailyCap      = 200000
+	DefaultTransferMinAccountAge = 72 * time.Hour
+	DefaultTransferMinWagered    = 10000
+)
@@ -21,8 +21,20 @@ type Currency string
 const (
 	CurrencyGems  Currency = "gems"
 	CurrencyCoins Currency = "coins"
+	CurrencyGK    Currency = "gk" // только апгрейды, в играх не ставится
 )
 
+// BalanceColumn returns the users column holding the balance in this currency
+func (c Currency) BalanceColumn() string {
+	switch c {
+	case CurrencyCoins:
+		return "coins"
+	case CurrencyGK:
+		return "gk"
+	}
+	return "gems"
+}
+
 // Exchange rates
 const (
 	CoinsPerTON       = 10    //
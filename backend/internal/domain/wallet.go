@@ -72,8 +72,14 @@ const (
 	WithdrawalStatusCompleted  WithdrawalStatus = "completed"
 	WithdrawalStatusFailed     WithdrawalStatus = "failed"
 	WithdrawalStatusCancelled  WithdrawalStatus = "cancelled"
+	WithdrawalStatusRejected   WithdrawalStatus = "rejected"
 )
 
+// Refundable reports whether held coins can still be returned - nothing was sent on-chain yet
+func (s WithdrawalStatus) Refundable() bool {
+	return s == WithdrawalStatusPending || s == WithdrawalStatusProcessing
+}
+
 // DepositInfo is returned to user when they want to deposit
 type DepositInfo struct {
 	PlatformAddress string `json:"platform_address"`
@@ -90,11 +96,11 @@ type WithdrawRequest struct {
 // WithdrawEstimate shows user what they'll receive
 type WithdrawEstimate struct {
 	CoinsAmount   int64   `json:"coins_amount"`
-	FeeCoins      int64   `json:"fee_coins"`       // Fixed fee in coins
+	FeeCoins      int64   `json:"fee_coins"`       // Fee in coins
 	NetCoins      int64   `json:"net_coins"`       // After fee
 	TonAmount     string  `json:"ton_amount"`      // human readable
 	TonAmountNano int64   `json:"ton_amount_nano"` // in nanoTON
 	ExchangeRate  int     `json:"exchange_rate"`   // 10 coins per TON
-	FeePercent    float64 `json:"fee_percent"`     // Legacy field (0 for fixed fee)
-	FeeTON        float64 `json:"fee_ton"`         // Fixed fee in TON (0.1)
+	FeePercent    float64 `json:"fee_percent"`     // Percent fee mode, 0 for a fixed fee
+	FeeTON        float64 `json:"fee_ton"`         // Fee in TON
 }
@@ -0,0 +1,12 @@
+package domain
+
+// WheelSegment - сегмент колеса фортуны из таблицы wheel_segments.
+// Вероятность выпадения = weight / сумма весов активных сегментов
+type WheelSegment struct {
+	ID         int64   `json:"id"`
+	Multiplier float64 `json:"multiplier"`
+	Color      string  `json:"color"`
+	Label      string  `json:"label"`
+	Weight     int     `json:"weight"`
+	Active     bool    `json:"active"`
+}
@@ -1,9 +1,7 @@
 package game
 
 import (
-	"crypto/rand"
 	"errors"
-	"math/big"
 	"sync"
 	"time"
 )
@@ -21,6 +19,8 @@ type CoinFlipProGame struct {
 	FlipHistory  []bool    `json:"flip_history"` // true = win, false = lose
 	CreatedAt    time.Time `json:"created_at"`
 	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+	Fair         *FairProof `json:"fair,omitempty"` // раунд provably fair, nil если выключен
+	rng          Randomizer
 	mu           sync.RWMutex
 }
 
@@ -31,6 +31,15 @@ const (
 	CoinFlipProStatusLost    = "lost"
 )
 
+// CoinFlipProWinChance - вероятность выиграть один бросок
+const CoinFlipProWinChance = 0.5
+
+// CoinFlipProMinCashoutRound - забрать выигрыш можно после стольких выигранных бросков
+const CoinFlipProMinCashoutRound = 1
+
+// ErrCoinFlipProCashoutTooEarly - выиграно меньше CoinFlipProMinCashoutRound бросков
+var ErrCoinFlipProCashoutTooEarly = errors.New("must win more rounds before cashing out")
+
 // Multipliers for each round (beautiful round numbers)
 var CoinFlipProMultipliers = []float64{
 	1.0,   // Start (no flips yet)
@@ -46,8 +55,8 @@ var CoinFlipProMultipliers = []float64{
 	100.0, // Round 10
 }
 
-// NewCoinFlipProGame creates a new multi-round coin flip game
-func NewCoinFlipProGame(id string, userID int64, bet int64) (*CoinFlipProGame, error) {
+// NewCoinFlipProGame creates a new multi-round coin flip game; nil rng uses crypto/rand
+func NewCoinFlipProGame(id string, userID int64, bet int64, rng Randomizer) (*CoinFlipProGame, error) {
 	if bet <= 0 {
 		return nil, errors.New("bet must be positive")
 	}
@@ -62,6 +71,7 @@ func NewCoinFlipProGame(id string, userID int64, bet int64) (*CoinFlipProGame, e
 		Status:       CoinFlipProStatusActive,
 		FlipHistory:  []bool{},
 		CreatedAt:    time.Now(),
+		rng:          orDefault(rng),
 	}, nil
 }
 
@@ -71,19 +81,15 @@ func (g *CoinFlipProGame) Flip() (win bool, err error) {
 	defer g.mu.Unlock()
 
 	if g.Status != CoinFlipProStatusActive {
-		return false, errors.New("game is not active")
+		return false, ErrGameNotActive
 	}
 
 	if g.CurrentRound >= g.MaxRounds {
 		return false, errors.New("all rounds completed")
 	}
 
-	// 50/50 coin flip using crypto/rand
-	n, err := rand.Int(rand.Reader, big.NewInt(2))
-	if err != nil {
-		return false, err
-	}
-	win = n.Int64() == 0
+	// 50/50 coin flip (CoinFlipProWinChance), one draw per flip
+	win = g.rng.Intn(2) == 0
 
 	g.FlipHistory = append(g.FlipHistory, win)
 
@@ -115,11 +121,11 @@ func (g *CoinFlipProGame) CashOut() (int64, error) {
 	defer g.mu.Unlock()
 
 	if g.Status != CoinFlipProStatusActive {
-		return 0, errors.New("game is not active")
+		return 0, ErrGameNotActive
 	}
 
-	if g.CurrentRound == 0 {
-		return 0, errors.New("must win at least one round before cashing out")
+	if g.CurrentRound < CoinFlipProMinCashoutRound {
+		return 0, ErrCoinFlipProCashoutTooEarly
 	}
 
 	g.Status = CoinFlipProStatusCashedOut
@@ -140,7 +146,7 @@ func (g *CoinFlipProGame) GetState() map[string]interface{} {
 		nextMultiplier = CoinFlipProMultipliers[g.CurrentRound+1]
 	}
 
-	return map[string]interface{}{
+	state := map[string]interface{}{
 		"id":              g.ID,
 		"bet":             g.Bet,
 		"current_round":   g.CurrentRound,
@@ -152,6 +158,10 @@ func (g *CoinFlipProGame) GetState() map[string]interface{} {
 		"potential_win":   int64(float64(g.Bet) * g.Multiplier),
 		"flip_history":    g.FlipHistory,
 	}
+	if g.Fair != nil {
+		state["fair"] = g.Fair
+	}
+	return state
 }
 
 // IsActive returns whether the game is still active
@@ -171,13 +181,19 @@ func (g *CoinFlipProGame) GetProfit() int64 {
 	return -g.Bet // Lost
 }
 
-// GetMultiplierTable returns the multiplier table for display
+// GetMultiplierTable returns the multiplier table for display: the multiplier after
+// each won round, how much that win stacks on the previous one (step) and the chance
+// to get that far from the start
 func GetCoinFlipProMultiplierTable() []map[string]interface{} {
 	table := make([]map[string]interface{}, CoinFlipProMaxRounds)
+	chance := 1.0
 	for i := 0; i < CoinFlipProMaxRounds; i++ {
+		chance *= CoinFlipProWinChance
 		table[i] = map[string]interface{}{
-			"round":      i + 1,
-			"multiplier": CoinFlipProMultipliers[i+1],
+			"round":        i + 1,
+			"multiplier":   CoinFlipProMultipliers[i+1],
+			"step":         CoinFlipProMultipliers[i+1] / CoinFlipProMultipliers[i],
+			"reach_chance": chance,
 		}
 	}
 	return table
@@ -0,0 +1,66 @@
+package game
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCoinFlipProMultipliersIncreasing(t *testing.T) {
+	if len(CoinFlipProMultipliers) != CoinFlipProMaxRounds+1 {
+		t.Fatalf("expected %d multipliers (start + each round), got %d", CoinFlipProMaxRounds+1, len(CoinFlipProMultipliers))
+	}
+	for i := 1; i < len(CoinFlipProMultipliers); i++ {
+		if CoinFlipProMultipliers[i] <= CoinFlipProMultipliers[i-1] {
+			t.Fatalf("multiplier for round %d (%v) is not above round %d (%v)",
+				i, CoinFlipProMultipliers[i], i-1, CoinFlipProMultipliers[i-1])
+		}
+	}
+
+	table := GetCoinFlipProMultiplierTable()
+	if len(table) != CoinFlipProMaxRounds {
+		t.Fatalf("expected %d rows, got %d", CoinFlipProMaxRounds, len(table))
+	}
+	prevChance := 1.0
+	for i, row := range table {
+		if row["round"] != i+1 || row["multiplier"] != CoinFlipProMultipliers[i+1] {
+			t.Fatalf("row %d: unexpected %v", i, row)
+		}
+		if step := row["step"].(float64); step <= 1 {
+			t.Fatalf("row %d: a won round must raise the multiplier, step %v", i, step)
+		}
+		chance := row["reach_chance"].(float64)
+		if chance >= prevChance {
+			t.Fatalf("row %d: reach chance %v should drop below %v", i, chance, prevChance)
+		}
+		prevChance = chance
+	}
+}
+
+func TestCoinFlipProCashOutNeedsMinRounds(t *testing.T) {
+	// Intn(2) == 0 - выигранный бросок
+	ints := make([]int64, CoinFlipProMinCashoutRound)
+	g, err := NewCoinFlipProGame("test", 1, 100, &stubRandomizer{ints: ints})
+	if err != nil {
+		t.Fatalf("new game: %v", err)
+	}
+
+	for round := 0; round < CoinFlipProMinCashoutRound; round++ {
+		if _, err := g.CashOut(); !errors.Is(err, ErrCoinFlipProCashoutTooEarly) {
+			t.Fatalf("round %d: expected ErrCoinFlipProCashoutTooEarly, got %v", round, err)
+		}
+		if !g.IsActive() {
+			t.Fatalf("round %d: refused cashout must keep the game active", round)
+		}
+		if win, err := g.Flip(); err != nil || !win {
+			t.Fatalf("round %d: expected a won flip, got %v (%v)", round, win, err)
+		}
+	}
+
+	win, err := g.CashOut()
+	if err != nil {
+		t.Fatalf("cashout after %d rounds: %v", CoinFlipProMinCashoutRound, err)
+	}
+	if want := int64(100 * CoinFlipProMultipliers[CoinFlipProMinCashoutRound]); win != want {
+		t.Fatalf("expected %d, got %d", want, win)
+	}
+}
@@ -1,9 +1,6 @@
 package game
 
-import (
-	"crypto/rand"
-	"math/big"
-)
+import "math"
 
 // DiceGame represents a single dice roll game (1-6 dice)
 type DiceGame struct {
@@ -14,6 +11,8 @@ type DiceGame struct {
 	Won        bool    `json:"won"`         // Whether player won
 	// Legacy fields for backward compatibility
 	RollOver   bool    `json:"roll_over,omitempty"`
+
+	rng Randomizer
 }
 
 const (
@@ -28,8 +27,8 @@ const (
 	DiceMultiplierRange = 1.8  // 1/2 chance = 1.8x (house edge)
 )
 
-// NewDiceGame creates a new dice game with the given parameters
-func NewDiceGame(target int, mode string) *DiceGame {
+// NewDiceGame creates a new dice game with the given parameters; nil rng uses crypto/rand
+func NewDiceGame(target int, mode string, rng Randomizer) *DiceGame {
 	// Validate mode
 	if mode != DiceModeExact && mode != DiceModeLow && mode != DiceModeHigh {
 		mode = DiceModeExact // Default to exact mode
@@ -60,6 +59,7 @@ func NewDiceGame(target int, mode string) *DiceGame {
 		Target:     target,
 		Mode:       mode,
 		Multiplier: multiplier,
+		rng:        orDefault(rng),
 	}
 	return g
 }
@@ -88,15 +88,7 @@ func (g *DiceGame) WinChance() float64 {
 
 // Roll performs the dice roll and returns the result (1-6)
 func (g *DiceGame) Roll() int {
-	// Generate cryptographically secure random number (1-6)
-	max := big.NewInt(DiceSides)
-	n, err := rand.Int(rand.Reader, max)
-	if err != nil {
-		// Fallback - should never happen
-		n = big.NewInt(0)
-	}
-
-	g.Result = int(n.Int64()) + 1 // Convert 0-5 to 1-6
+	g.Result = g.rng.Intn(DiceSides) + 1 // Convert 0-5 to 1-6
 
 	// Determine win/loss based on mode
 	switch g.Mode {
@@ -134,3 +126,8 @@ func (g *DiceGame) ToDetails() map[string]interface{} {
 		"win_chance": g.WinChance(),
 	}
 }
+
+// DiceMaxRTP returns the expected return per unit bet of the best dice mode
+func DiceMaxRTP() float64 {
+	return math.Max(DiceMultiplierExact/DiceSides, DiceMultiplierRange/2)
+}
@@ -2,10 +2,20 @@ package game
 
 import "fmt"
 
-type Factory struct{}
+type Factory struct {
+	mines MinesConfig
+}
 
 func NewFactory() *Factory {
-	return &Factory{}
+	return &Factory{mines: DefaultMinesConfig()}
+}
+
+// NewFactoryWithMines creates a factory whose Mines games use the given board config
+func NewFactoryWithMines(cfg MinesConfig) *Factory {
+	if !cfg.Valid() {
+		cfg = DefaultMinesConfig()
+	}
+	return &Factory{mines: cfg}
 }
 
 func (f *Factory) CreateGame(gameType GameType, roomID string, players [2]int64) (Game, error) {
@@ -13,7 +23,7 @@ func (f *Factory) CreateGame(gameType GameType, roomID string, players [2]int64)
 	case TypeRPS:
 		return NewRPSGame(roomID, players), nil
 	case TypeMines:
-		return NewMinesGame(roomID, players), nil
+		return NewMinesGameWithConfig(roomID, players, f.mines), nil
 	default:
 		return nil, fmt.Errorf("unknown game type: %s", gameType)
 	}
@@ -0,0 +1,54 @@
+package game
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"strconv"
+)
+
+// FairProof is the client-facing data of a provably fair game. The outcome is
+// decided by FairRandomizer with these inputs; the server seed behind
+// Commitment is revealed when the player rotates it (see FairRNG in service).
+type FairProof struct {
+	Commitment string `json:"commitment"`  // sha256(server_seed), hex
+	ClientSeed string `json:"client_seed"` // seed mixed into every draw
+	Nonce      uint64 `json:"nonce"`       // game counter for this seed pair
+}
+
+// FairRandomizer is the Randomizer of one provably fair game. Draw i of the
+// game is the first 53 bits of HMAC-SHA256(server_seed, "client_seed:nonce:i")
+// as a float in [0, 1); Intn(n) and Int63n(n) are floor(draw * n).
+// It is not safe for concurrent use, like the game it drives.
+type FairRandomizer struct {
+	serverSeed []byte
+	prefix     string
+	draws      uint64
+}
+
+// NewFairRandomizer creates the draw stream of game nonce for the seed pair
+func NewFairRandomizer(serverSeed []byte, clientSeed string, nonce uint64) *FairRandomizer {
+	return &FairRandomizer{
+		serverSeed: serverSeed,
+		prefix:     clientSeed + ":" + strconv.FormatUint(nonce, 10) + ":",
+	}
+}
+
+// Float64 returns the next draw in [0, 1)
+func (r *FairRandomizer) Float64() float64 {
+	mac := hmac.New(sha256.New, r.serverSeed)
+	mac.Write([]byte(r.prefix + strconv.FormatUint(r.draws, 10)))
+	r.draws++
+	sum := mac.Sum(nil)
+	return float64(binary.BigEndian.Uint64(sum[:8])>>11) / (1 << 53)
+}
+
+// Intn returns floor(draw * n) in [0, n)
+func (r *FairRandomizer) Intn(n int) int {
+	return int(r.Float64() * float64(n))
+}
+
+// Int63n returns floor(draw * n) in [0, n)
+func (r *FairRandomizer) Int63n(n int64) int64 {
+	return int64(r.Float64() * float64(n))
+}
@@ -0,0 +1,42 @@
+package game
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+)
+
+func TestFairRandomizerIsReproducible(t *testing.T) {
+	seed := []byte("server-seed")
+	a := NewFairRandomizer(seed, "client", 7)
+	b := NewFairRandomizer(seed, "client", 7)
+	for i := 0; i < 20; i++ {
+		if x, y := a.Float64(), b.Float64(); x != y || x < 0 || x >= 1 {
+			t.Fatalf("draw %d: %v vs %v", i, x, y)
+		}
+	}
+
+	// Другой nonce - другая игра
+	if NewFairRandomizer(seed, "client", 8).Float64() == NewFairRandomizer(seed, "client", 7).Float64() {
+		t.Fatal("different nonces must give different draws")
+	}
+}
+
+func TestFairRandomizerMatchesPublishedFormula(t *testing.T) {
+	seed := []byte("server-seed")
+	r := NewFairRandomizer(seed, "client", 3)
+	r.Float64()
+
+	// Вторая выборка игры = HMAC-SHA256(server_seed, "client:3:1")
+	mac := hmac.New(sha256.New, seed)
+	mac.Write([]byte("client:3:1"))
+	want := float64(binary.BigEndian.Uint64(mac.Sum(nil)[:8])>>11) / (1 << 53)
+	if got := r.Float64(); got != want {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	if n := NewFairRandomizer(seed, "client", 3).Intn(6); n != int(NewFairRandomizer(seed, "client", 3).Float64()*6) {
+		t.Fatalf("Intn must be floor(draw*n), got %d", n)
+	}
+}
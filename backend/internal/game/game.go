@@ -25,10 +25,14 @@ type Game interface {
 	TurnTimeout() time.Duration
 	HandleMove(playerID int64, data interface{}) error
 	IsRoundComplete() bool
+	// HasMoved reports whether the player already acted in the current phase/round
+	HasMoved(playerID int64) bool
 
 	// Result checking
 	CheckResult() *GameResult
 	IsFinished() bool
+	// ForceFinish ends the game early with a winner (e.g. opponent left); false if already finished
+	ForceFinish(winnerID int64, reason string) bool
 
 	// Serialization for client
 	SerializeState(playerID int64) interface{}
@@ -0,0 +1,145 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HappyHourQuests - ключ окна для наград квестов (остальные ключи - типы игр)
+const HappyHourQuests = "quests"
+
+// HappyHourKeys - ключи, которые что-то бустят: игры, чьи выплаты умножает
+// GameService, и квесты. Окно с другим ключом молча ничего бы не делало.
+var HappyHourKeys = []string{"coinflip", "rps", "mines", HappyHourQuests}
+
+// DefaultHappyHourMaxRTP - выше этого RTP буст не поднимает выплату
+const DefaultHappyHourMaxRTP = 0.99
+
+// HappyHour - окно, в которое выплаты игры (или награды квестов) умножаются на Multiplier.
+// Время в UTC, окно вида 22:00-02:00 переходит через полночь.
+type HappyHour struct {
+	Game       string         `json:"game"`
+	Days       []time.Weekday `json:"days,omitempty"` // пусто - каждый день
+	From       string         `json:"from"`           // HH:MM
+	To         string         `json:"to"`             // HH:MM
+	Multiplier float64        `json:"multiplier"`
+
+	from, to time.Duration
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ParseHappyHours parses HAPPY_HOURS: comma separated <game>@[<days>/]<HH:MM>-<HH:MM>=<multiplier>,
+// days joined with "+", e.g. "coinflip@18:00-20:00=1.02,quests@sat+sun/12:00-14:00=1.5"
+func ParseHappyHours(spec string) ([]HappyHour, error) {
+	var out []HappyHour
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		h, err := parseHappyHour(item)
+		if err != nil {
+			return nil, fmt.Errorf("happy hour %q: %w", item, err)
+		}
+		out = append(out, h)
+	}
+	return out, nil
+}
+
+func parseHappyHour(item string) (HappyHour, error) {
+	var h HappyHour
+	key, rest, ok := strings.Cut(item, "@")
+	if !ok || key == "" {
+		return h, fmt.Errorf("expected <game>@<window>=<multiplier>")
+	}
+	window, mult, ok := strings.Cut(rest, "=")
+	if !ok {
+		return h, fmt.Errorf("missing multiplier")
+	}
+	m, err := strconv.ParseFloat(strings.TrimSpace(mult), 64)
+	if err != nil || m < 1 {
+		return h, fmt.Errorf("multiplier must be a number >= 1")
+	}
+	h.Game, h.Multiplier = strings.TrimSpace(key), m
+	if !isHappyHourKey(h.Game) {
+		return h, fmt.Errorf("unknown game %q, expected one of %s", h.Game, strings.Join(HappyHourKeys, ", "))
+	}
+
+	if days, hours, ok := strings.Cut(window, "/"); ok {
+		for _, d := range strings.Split(days, "+") {
+			wd, ok := weekdays[strings.ToLower(strings.TrimSpace(d))]
+			if !ok {
+				return h, fmt.Errorf("unknown day %q", d)
+			}
+			h.Days = append(h.Days, wd)
+		}
+		window = hours
+	}
+
+	from, to, ok := strings.Cut(window, "-")
+	if !ok {
+		return h, fmt.Errorf("expected HH:MM-HH:MM")
+	}
+	if h.from, err = parseClock(from); err != nil {
+		return h, err
+	}
+	if h.to, err = parseClock(to); err != nil {
+		return h, err
+	}
+	if h.from == h.to {
+		return h, fmt.Errorf("empty window")
+	}
+	h.From, h.To = strings.TrimSpace(from), strings.TrimSpace(to)
+	return h, nil
+}
+
+func isHappyHourKey(key string) bool {
+	for _, k := range HappyHourKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClock parses HH:MM into an offset from midnight
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// ActiveAt reports whether the window covers now (UTC)
+func (h HappyHour) ActiveAt(now time.Time) bool {
+	now = now.UTC()
+	offset := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute
+	day := now.Weekday()
+	if h.from > h.to && offset < h.to {
+		// хвост окна после полуночи относится к вчерашнему дню
+		day = (day + 6) % 7
+	}
+	if len(h.Days) > 0 {
+		found := false
+		for _, d := range h.Days {
+			if d == day {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if h.from < h.to {
+		return offset >= h.from && offset < h.to
+	}
+	return offset >= h.from || offset < h.to
+}
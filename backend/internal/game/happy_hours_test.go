@@ -0,0 +1,23 @@
+package game
+
+import "testing"
+
+func TestParseHappyHours(t *testing.T) {
+	hh, err := ParseHappyHours("coinflip@18:00-20:00=1.02, quests@sat+sun/22:00-02:00=1.5")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(hh) != 2 || hh[0].Game != "coinflip" || hh[1].Multiplier != 1.5 || len(hh[1].Days) != 2 {
+		t.Fatalf("unexpected windows: %+v", hh)
+	}
+
+	for _, bad := range []string{
+		"coinflip=1.1", "mines@18:00-20:00", "mines@18:00-20:00=0.5", "mines@xyz/18:00-20:00=1.1", "mines@18:00-18:00=1.1",
+		"dice@18:00-20:00=1.1",     // dice не бустится
+		"coinfilp@18:00-20:00=1.1", // опечатка
+	} {
+		if _, err := ParseHappyHours(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
@@ -7,14 +7,31 @@ import (
 	"time"
 )
 
+// MinesConfig - размер поля и число мин в PvP Mines
+type MinesConfig struct {
+	Cells int `json:"cells"`
+	Mines int `json:"mines"`
+}
+
+// DefaultMinesConfig - 4 мины на поле из 12 клеток
+func DefaultMinesConfig() MinesConfig {
+	return MinesConfig{Cells: 12, Mines: 4}
+}
+
+// Valid reports whether at least one cell on the board stays safe
+func (c MinesConfig) Valid() bool {
+	return c.Mines >= 1 && c.Mines < c.Cells
+}
+
 type MinesGame struct {
-	id       string
-	players  [2]int64
-	boards   map[int64]*Board
-	moves    map[int64]int
-	round    int
-	result   *GameResult
-	mu       sync.RWMutex
+	id      string
+	config  MinesConfig
+	players [2]int64
+	boards  map[int64]*Board
+	moves   map[int64]int
+	round   int
+	result  *GameResult
+	mu      sync.RWMutex
 	// History of moves for each player: playerID -> []MoveResult
 	moveHistory map[int64][]MoveResult
 	// Last round result for sending to clients
@@ -22,9 +39,9 @@ type MinesGame struct {
 }
 
 type MoveResult struct {
-	Cell   int  `json:"cell"`
+	Cell    int  `json:"cell"`
 	HitMine bool `json:"hit_mine"`
-	Round  int  `json:"round"`
+	Round   int  `json:"round"`
 }
 
 type RoundResult struct {
@@ -33,12 +50,21 @@ type RoundResult struct {
 }
 
 type Board struct {
-	mines [12]bool
+	mines []bool
 }
 
 func NewMinesGame(id string, players [2]int64) *MinesGame {
+	return NewMinesGameWithConfig(id, players, DefaultMinesConfig())
+}
+
+// NewMinesGameWithConfig creates a game with a custom board; invalid config falls back to default
+func NewMinesGameWithConfig(id string, players [2]int64, cfg MinesConfig) *MinesGame {
+	if !cfg.Valid() {
+		cfg = DefaultMinesConfig()
+	}
 	g := &MinesGame{
 		id:          id,
+		config:      cfg,
 		players:     players,
 		boards:      make(map[int64]*Board),
 		moves:       make(map[int64]int),
@@ -51,15 +77,22 @@ func NewMinesGame(id string, players [2]int64) *MinesGame {
 	return g
 }
 
-func (g *MinesGame) Type() GameType { return TypeMines }
-func (g *MinesGame) Players() [2]int64 { return g.players }
+func (g *MinesGame) Type() GameType              { return TypeMines }
+func (g *MinesGame) Players() [2]int64           { return g.players }
 func (g *MinesGame) SetupTimeout() time.Duration { return 10 * time.Second }
-func (g *MinesGame) TurnTimeout() time.Duration { return 10 * time.Second }
+func (g *MinesGame) TurnTimeout() time.Duration  { return 10 * time.Second }
+func (g *MinesGame) Config() MinesConfig         { return g.config }
 
 func (g *MinesGame) SetSecondPlayer(playerID int64) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 	g.players[1] = playerID
+	// Мины, расставленные по таймауту за ещё пустое место (id 0), достаются новому игроку
+	if board, ok := g.boards[0]; ok && playerID != 0 {
+		g.boards[playerID] = board
+		delete(g.boards, 0)
+		delete(g.moveHistory, 0)
+	}
 	// Initialize move history for the new player
 	if g.moveHistory[playerID] == nil {
 		g.moveHistory[playerID] = []MoveResult{}
@@ -98,7 +131,7 @@ func (g *MinesGame) isSetupCompleteUnlocked() bool {
 }
 
 func (g *MinesGame) HandleSetup(playerID int64, data interface{}) error {
-	return g.HandleMove(playerID, data)  // Setup = тот же HandleMove
+	return g.HandleMove(playerID, data) // Setup = тот же HandleMove
 }
 
 func (g *MinesGame) HandleMove(playerID int64, data interface{}) error {
@@ -110,13 +143,13 @@ func (g *MinesGame) HandleMove(playerID int64, data interface{}) error {
 	// Setup phase - placing mines
 	if !g.isSetupCompleteUnlocked() {
 		positions, ok := data.([]int)
-		if !ok || len(positions) != 4 {
+		if !ok || !g.validSetup(positions) {
 			log.Printf("MinesGame.HandleMove: invalid setup data, using bot positions")
 			// Бот расставляет мины случайно
 			positions = []int{}
 			used := make(map[int]bool)
-			for len(positions) < 4 {
-				pos := rand.Intn(12) + 1
+			for len(positions) < g.config.Mines {
+				pos := rand.Intn(g.config.Cells) + 1
 				if !used[pos] {
 					used[pos] = true
 					positions = append(positions, pos)
@@ -124,11 +157,9 @@ func (g *MinesGame) HandleMove(playerID int64, data interface{}) error {
 			}
 		}
 
-		board := &Board{}
+		board := &Board{mines: make([]bool, g.config.Cells)}
 		for _, pos := range positions {
-			if pos >= 1 && pos <= 12 {
-				board.mines[pos-1] = true
-			}
+			board.mines[pos-1] = true
 		}
 		g.boards[playerID] = board
 		log.Printf("MinesGame.HandleMove: player=%d placed mines at %v, boards=%d", playerID, positions, len(g.boards))
@@ -137,10 +168,10 @@ func (g *MinesGame) HandleMove(playerID int64, data interface{}) error {
 
 	// Playing phase - selecting cell on opponent's board
 	position, ok := data.(int)
-	if !ok || position < 1 || position > 12 {
+	if !ok || position < 1 || position > g.config.Cells {
 		log.Printf("MinesGame.HandleMove: invalid move data, using random position")
 		// Бот выбирает случайную клетку
-		position = rand.Intn(12) + 1
+		position = rand.Intn(g.config.Cells) + 1
 	}
 
 	g.moves[playerID] = position
@@ -148,16 +179,46 @@ func (g *MinesGame) HandleMove(playerID int64, data interface{}) error {
 	return nil
 }
 
+// validSetup - ровно config.Mines разных клеток в пределах поля
+func (g *MinesGame) validSetup(positions []int) bool {
+	if len(positions) != g.config.Mines {
+		return false
+	}
+	used := make(map[int]bool, len(positions))
+	for _, pos := range positions {
+		if pos < 1 || pos > g.config.Cells || used[pos] {
+			return false
+		}
+		used[pos] = true
+	}
+	return true
+}
+
 func (g *MinesGame) IsRoundComplete() bool {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
 	return len(g.moves) == 2
 }
 
+// HasMoved - в фазе расстановки: поставил ли мины, в игре: выбрал ли клетку
+func (g *MinesGame) HasMoved(playerID int64) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if !g.isSetupCompleteUnlocked() {
+		return g.boards[playerID] != nil
+	}
+	_, ok := g.moves[playerID]
+	return ok
+}
+
 func (g *MinesGame) CheckResult() *GameResult {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
+	if g.result != nil {
+		return g.result
+	}
+
 	if len(g.moves) < 2 {
 		log.Printf("MinesGame.CheckResult: waiting for moves (have %d)", len(g.moves))
 		return nil
@@ -224,6 +285,8 @@ func (g *MinesGame) getResultDetails() map[string]interface{} {
 	return map[string]interface{}{
 		"rounds":      g.round,
 		"moveHistory": g.moveHistory,
+		"cells":       g.config.Cells,
+		"mines":       g.config.Mines,
 	}
 }
 
@@ -248,6 +311,19 @@ func (g *MinesGame) GetRound() int {
 	return g.round
 }
 
+// ForceFinish ends the game with winnerID without playing remaining rounds
+func (g *MinesGame) ForceFinish(winnerID int64, reason string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.result != nil {
+		return false
+	}
+	details := g.getResultDetails()
+	details["reason"] = reason
+	g.result = &GameResult{WinnerID: &winnerID, Reason: reason, Details: details}
+	return true
+}
 
 func (g *MinesGame) IsFinished() bool {
 	g.mu.RLock()
@@ -260,8 +336,10 @@ func (g *MinesGame) SerializeState(playerID int64) interface{} {
 	defer g.mu.RUnlock()
 
 	return map[string]interface{}{
-		"type":  "mines",
-		"round": g.round,
+		"type":   "mines",
+		"round":  g.round,
 		"result": g.result,
+		"cells":  g.config.Cells,
+		"mines":  g.config.Mines,
 	}
-}
\ No newline at end of file
+}
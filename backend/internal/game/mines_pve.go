@@ -1,10 +1,8 @@
 package game
 
 import (
-	"crypto/rand"
 	"errors"
 	"math"
-	"math/big"
 	"sync"
 	"time"
 )
@@ -12,20 +10,23 @@ import (
 // MinesPvEGame represents an advanced single-player mines game
 // Player can reveal multiple cells and cash out at any time
 type MinesPvEGame struct {
-	ID            string    `json:"id"`
-	UserID        int64     `json:"user_id"`
-	BoardSize     int       `json:"board_size"`      // Default 25 (5x5)
-	MinesCount    int       `json:"mines_count"`     // 1-24 mines
-	Bet           int64     `json:"bet"`
-	Mines         []int     `json:"-"`               // Mine positions (hidden from client)
-	RevealedCells []int     `json:"revealed_cells"`  // Cells player has revealed
-	Multiplier    float64   `json:"multiplier"`      // Current multiplier
-	NextMultiplier float64  `json:"next_multiplier"` // Multiplier if next cell is safe
-	Status        string    `json:"status"`          // active, cashed_out, exploded
-	WinAmount     int64     `json:"win_amount"`      // Amount won (0 if exploded)
-	CreatedAt     time.Time `json:"created_at"`
-	FinishedAt    *time.Time `json:"finished_at,omitempty"`
-	mu            sync.RWMutex
+	ID             string     `json:"id"`
+	UserID         int64      `json:"user_id"`
+	BoardSize      int        `json:"board_size"`  // Default 25 (5x5)
+	MinesCount     int        `json:"mines_count"` // 1-24 mines
+	Bet            int64      `json:"bet"`
+	Currency       string     `json:"currency"`        // gems или coins, в ней же выплата
+	Mines          []int      `json:"-"`               // Mine positions (hidden from client)
+	RevealedCells  []int      `json:"revealed_cells"`  // Cells player has revealed
+	Multiplier     float64    `json:"multiplier"`      // Current multiplier
+	NextMultiplier float64    `json:"next_multiplier"` // Multiplier if next cell is safe
+	Status         string     `json:"status"`          // active, cashed_out, exploded
+	WinAmount      int64      `json:"win_amount"`      // Amount won (0 if exploded)
+	CreatedAt      time.Time  `json:"created_at"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+	LastRevealAt   time.Time  `json:"-"`              // для ограничения частоты открытий
+	Fair           *FairProof `json:"fair,omitempty"` // раунд provably fair, nil если выключен
+	mu             sync.RWMutex
 }
 
 const (
@@ -35,10 +36,18 @@ const (
 	MinesProStatusActive    = "active"
 	MinesProStatusCashedOut = "cashed_out"
 	MinesProStatusExploded  = "exploded"
+	MinesProStatusRefunded  = "refunded" // брошена без открытых ячеек, ставка возвращена
 )
 
-// NewMinesPvEGame creates a new Mines Pro game
-func NewMinesPvEGame(id string, userID int64, bet int64, minesCount int) (*MinesPvEGame, error) {
+var (
+	ErrGameNotActive    = errors.New("game is not active")
+	ErrInvalidCell      = errors.New("invalid cell position")
+	ErrCellRevealed     = errors.New("cell already revealed")
+	ErrNothingToCashOut = errors.New("must reveal at least one cell before cashing out")
+)
+
+// NewMinesPvEGame creates a new Mines Pro game; nil rng uses crypto/rand
+func NewMinesPvEGame(id string, userID int64, bet int64, minesCount int, rng Randomizer) (*MinesPvEGame, error) {
 	if minesCount < MinesProMinMines || minesCount > MinesProMaxMines {
 		return nil, errors.New("mines count must be between 1 and 24")
 	}
@@ -52,6 +61,7 @@ func NewMinesPvEGame(id string, userID int64, bet int64, minesCount int) (*Mines
 		BoardSize:     MinesProBoardSize,
 		MinesCount:    minesCount,
 		Bet:           bet,
+		Currency:      "gems",
 		RevealedCells: []int{},
 		Multiplier:    1.0,
 		Status:        MinesProStatusActive,
@@ -59,7 +69,7 @@ func NewMinesPvEGame(id string, userID int64, bet int64, minesCount int) (*Mines
 	}
 
 	// Generate random mine positions
-	g.Mines = g.generateMines()
+	g.Mines = g.generateMines(orDefault(rng))
 
 	// Calculate initial next multiplier
 	g.NextMultiplier = g.calculateNextMultiplier()
@@ -68,17 +78,12 @@ func NewMinesPvEGame(id string, userID int64, bet int64, minesCount int) (*Mines
 }
 
 // generateMines generates random mine positions
-func (g *MinesPvEGame) generateMines() []int {
+func (g *MinesPvEGame) generateMines(rng Randomizer) []int {
 	mines := make([]int, 0, g.MinesCount)
 	used := make(map[int]bool)
 
 	for len(mines) < g.MinesCount {
-		max := big.NewInt(int64(g.BoardSize))
-		n, err := rand.Int(rand.Reader, max)
-		if err != nil {
-			n = big.NewInt(int64(len(mines))) // Fallback
-		}
-		pos := int(n.Int64())
+		pos := rng.Intn(g.BoardSize)
 		if !used[pos] {
 			used[pos] = true
 			mines = append(mines, pos)
@@ -139,23 +144,36 @@ func (g *MinesPvEGame) calculateNextMultiplier() float64 {
 	return math.Floor(multiplier*100) / 100
 }
 
+// TryRevealSlot records a reveal attempt at now unless the previous one was less
+// than minInterval ago. Returns false when the reveal must be refused.
+func (g *MinesPvEGame) TryRevealSlot(minInterval time.Duration, now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if minInterval > 0 && !g.LastRevealAt.IsZero() && now.Sub(g.LastRevealAt) < minInterval {
+		return false
+	}
+	g.LastRevealAt = now
+	return true
+}
+
 // Reveal attempts to reveal a cell
 func (g *MinesPvEGame) Reveal(cell int) (hitMine bool, err error) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
 	if g.Status != MinesProStatusActive {
-		return false, errors.New("game is not active")
+		return false, ErrGameNotActive
 	}
 
 	if cell < 0 || cell >= g.BoardSize {
-		return false, errors.New("invalid cell position")
+		return false, ErrInvalidCell
 	}
 
 	// Check if already revealed
 	for _, c := range g.RevealedCells {
 		if c == cell {
-			return false, errors.New("cell already revealed")
+			return false, ErrCellRevealed
 		}
 	}
 
@@ -194,11 +212,11 @@ func (g *MinesPvEGame) CashOut() (int64, error) {
 	defer g.mu.Unlock()
 
 	if g.Status != MinesProStatusActive {
-		return 0, errors.New("game is not active")
+		return 0, ErrGameNotActive
 	}
 
 	if len(g.RevealedCells) == 0 {
-		return 0, errors.New("must reveal at least one cell before cashing out")
+		return 0, ErrNothingToCashOut
 	}
 
 	g.Status = MinesProStatusCashedOut
@@ -219,6 +237,7 @@ func (g *MinesPvEGame) GetState() map[string]interface{} {
 		"board_size":      g.BoardSize,
 		"mines_count":     g.MinesCount,
 		"bet":             g.Bet,
+		"currency":        g.Currency,
 		"revealed_cells":  g.RevealedCells,
 		"multiplier":      g.Multiplier,
 		"next_multiplier": g.NextMultiplier,
@@ -231,10 +250,116 @@ func (g *MinesPvEGame) GetState() map[string]interface{} {
 	if g.Status != MinesProStatusActive {
 		state["mines"] = g.Mines
 	}
+	if g.Fair != nil {
+		state["fair"] = g.Fair
+	}
 
 	return state
 }
 
+// MinesPvESnapshot is the persisted state of a Mines Pro game (mines_pro_games row)
+type MinesPvESnapshot struct {
+	ID            string
+	UserID        int64
+	BoardSize     int
+	MinesCount    int
+	Bet           int64
+	Currency      string
+	Mines         []int
+	RevealedCells []int
+	Status        string
+	WinAmount     int64
+	CreatedAt     time.Time
+	LastRevealAt  *time.Time
+	FinishedAt    *time.Time
+}
+
+// Snapshot returns a copy of the game state for storage
+func (g *MinesPvEGame) Snapshot() MinesPvESnapshot {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	snap := MinesPvESnapshot{
+		ID:            g.ID,
+		UserID:        g.UserID,
+		BoardSize:     g.BoardSize,
+		MinesCount:    g.MinesCount,
+		Bet:           g.Bet,
+		Currency:      g.Currency,
+		Mines:         append([]int(nil), g.Mines...),
+		RevealedCells: append([]int{}, g.RevealedCells...),
+		Status:        g.Status,
+		WinAmount:     g.WinAmount,
+		CreatedAt:     g.CreatedAt,
+		FinishedAt:    g.FinishedAt,
+	}
+	if !g.LastRevealAt.IsZero() {
+		t := g.LastRevealAt
+		snap.LastRevealAt = &t
+	}
+	return snap
+}
+
+// RestoreMinesPvEGame rebuilds a game from its snapshot, multipliers are recomputed
+func RestoreMinesPvEGame(snap MinesPvESnapshot) (*MinesPvEGame, error) {
+	if snap.BoardSize <= 0 || snap.MinesCount < MinesProMinMines || snap.MinesCount >= snap.BoardSize || len(snap.Mines) != snap.MinesCount {
+		return nil, errors.New("invalid mines pro snapshot")
+	}
+
+	g := &MinesPvEGame{
+		ID:            snap.ID,
+		UserID:        snap.UserID,
+		BoardSize:     snap.BoardSize,
+		MinesCount:    snap.MinesCount,
+		Bet:           snap.Bet,
+		Currency:      snap.Currency,
+		Mines:         append([]int(nil), snap.Mines...),
+		RevealedCells: append([]int{}, snap.RevealedCells...),
+		Status:        snap.Status,
+		WinAmount:     snap.WinAmount,
+		CreatedAt:     snap.CreatedAt,
+		FinishedAt:    snap.FinishedAt,
+	}
+	if snap.LastRevealAt != nil {
+		g.LastRevealAt = *snap.LastRevealAt
+	}
+	g.Multiplier = g.calculateMultiplier()
+	g.NextMultiplier = g.calculateNextMultiplier()
+	return g, nil
+}
+
+// IdleSince returns when the player last acted in the game
+func (g *MinesPvEGame) IdleSince() time.Time {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.LastRevealAt.After(g.CreatedAt) {
+		return g.LastRevealAt
+	}
+	return g.CreatedAt
+}
+
+// Abandon settles a game the player walked away from: cashes out at the current
+// multiplier if any cell is revealed, otherwise refunds the bet. Returns the
+// amount to credit; false if the game was no longer active.
+func (g *MinesPvEGame) Abandon() (int64, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Status != MinesProStatusActive {
+		return 0, false
+	}
+	if len(g.RevealedCells) == 0 {
+		g.Status = MinesProStatusRefunded
+		g.WinAmount = g.Bet
+	} else {
+		g.Status = MinesProStatusCashedOut
+		g.WinAmount = int64(float64(g.Bet) * g.Multiplier)
+	}
+	now := time.Now()
+	g.FinishedAt = &now
+	return g.WinAmount, true
+}
+
 // IsActive returns whether the game is still active
 func (g *MinesPvEGame) IsActive() bool {
 	g.mu.RLock()
@@ -246,8 +371,11 @@ func (g *MinesPvEGame) IsActive() bool {
 func (g *MinesPvEGame) GetProfit() int64 {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
-	if g.Status == MinesProStatusCashedOut {
+	switch g.Status {
+	case MinesProStatusCashedOut:
 		return g.WinAmount - g.Bet
+	case MinesProStatusRefunded:
+		return 0
 	}
 	return -g.Bet // Lost
 }
@@ -257,14 +385,33 @@ func (g *MinesPvEGame) ToDetails() map[string]interface{} {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
 
-	return map[string]interface{}{
+	details := map[string]interface{}{
 		"board_size":     g.BoardSize,
 		"mines_count":    g.MinesCount,
+		"currency":       g.Currency,
 		"mines":          g.Mines,
 		"revealed_cells": g.RevealedCells,
 		"multiplier":     g.Multiplier,
 		"status":         g.Status,
 	}
+	if g.Fair != nil {
+		details["fair"] = g.Fair
+	}
+	return details
+}
+
+// FairMinesMultiplier returns the zero-edge payout for revealing reveals safe
+// cells in a row on a board with minesCount mines: the inverse of the chance
+// that every pick is safe
+func FairMinesMultiplier(boardSize, minesCount, reveals int) float64 {
+	safeCells := boardSize - minesCount
+	multiplier := 1.0
+	for i := 0; i < reveals; i++ {
+		totalRemaining := float64(boardSize - i)
+		safeRemaining := float64(safeCells - i)
+		multiplier *= totalRemaining / safeRemaining
+	}
+	return multiplier
 }
 
 // MultiplierTable returns a table of multipliers for different reveal counts
@@ -275,14 +422,21 @@ func MultiplierTable(minesCount int) []float64 {
 	table := make([]float64, safeCells)
 
 	for reveals := 1; reveals <= safeCells; reveals++ {
-		multiplier := 1.0
-		for i := 0; i < reveals; i++ {
-			totalRemaining := float64(boardSize - i)
-			safeRemaining := float64(safeCells - i)
-			multiplier *= totalRemaining / safeRemaining
-		}
-		table[reveals-1] = math.Floor(multiplier*100) / 100
+		table[reveals-1] = math.Floor(FairMinesMultiplier(boardSize, minesCount, reveals)*100) / 100
 	}
 
 	return table
 }
+
+// MinesProMaxRTP returns the expected return per unit bet of the best mines
+// count and cashout point: the fair multiplier is the inverse of the chance to
+// get there, so only the flooring in MultiplierTable keeps it below 1
+func MinesProMaxRTP() float64 {
+	best := 0.0
+	for mines := MinesProMinMines; mines <= MinesProMaxMines; mines++ {
+		for reveals, paid := range MultiplierTable(mines) {
+			best = math.Max(best, paid/FairMinesMultiplier(MinesProBoardSize, mines, reveals+1))
+		}
+	}
+	return best
+}
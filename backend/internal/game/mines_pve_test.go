@@ -0,0 +1,93 @@
+package game
+
+import "testing"
+
+// safeCells returns the cells of g without a mine
+func safeCells(g *MinesPvEGame) []int {
+	mines := make(map[int]bool)
+	for _, m := range g.Mines {
+		mines[m] = true
+	}
+	var safe []int
+	for cell := 0; cell < g.BoardSize; cell++ {
+		if !mines[cell] {
+			safe = append(safe, cell)
+		}
+	}
+	return safe
+}
+
+func TestMinesPvESnapshotRestore(t *testing.T) {
+	g, err := NewMinesPvEGame("test", 1, 100, 3, nil)
+	if err != nil {
+		t.Fatalf("new game: %v", err)
+	}
+	safe := safeCells(g)
+	for _, cell := range safe[:2] {
+		if _, err := g.Reveal(cell); err != nil {
+			t.Fatalf("reveal: %v", err)
+		}
+	}
+
+	restored, err := RestoreMinesPvEGame(g.Snapshot())
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if restored.Multiplier != g.Multiplier || restored.NextMultiplier != g.NextMultiplier {
+		t.Fatalf("expected multipliers %v/%v, got %v/%v", g.Multiplier, g.NextMultiplier, restored.Multiplier, restored.NextMultiplier)
+	}
+	if len(restored.RevealedCells) != 2 || !restored.IsActive() {
+		t.Fatalf("expected active game with 2 revealed cells, got %s with %d", restored.Status, len(restored.RevealedCells))
+	}
+
+	// Восстановленная игра продолжается с того же места
+	if _, err := restored.Reveal(safe[0]); err == nil {
+		t.Fatalf("expected error revealing an already revealed cell")
+	}
+	if _, err := restored.Reveal(safe[2]); err != nil {
+		t.Fatalf("reveal after restore: %v", err)
+	}
+}
+
+func TestMinesPvEAbandon(t *testing.T) {
+	g, err := NewMinesPvEGame("test", 1, 100, 3, nil)
+	if err != nil {
+		t.Fatalf("new game: %v", err)
+	}
+	if amount, ok := g.Abandon(); !ok || amount != 100 || g.Status != MinesProStatusRefunded {
+		t.Fatalf("expected refund of 100, got %d (%s)", amount, g.Status)
+	}
+	if g.GetProfit() != 0 {
+		t.Fatalf("expected zero profit for refund, got %d", g.GetProfit())
+	}
+	if _, ok := g.Abandon(); ok {
+		t.Fatalf("expected finished game not to be abandoned twice")
+	}
+
+	g, _ = NewMinesPvEGame("test", 1, 100, 3, nil)
+	if _, err := g.Reveal(safeCells(g)[0]); err != nil {
+		t.Fatalf("reveal: %v", err)
+	}
+	want := int64(float64(g.Bet) * g.Multiplier)
+	if amount, ok := g.Abandon(); !ok || amount != want || g.Status != MinesProStatusCashedOut {
+		t.Fatalf("expected cashout of %d, got %d (%s)", want, amount, g.Status)
+	}
+}
+
+func TestMinesPvEGenerateMinesWithStub(t *testing.T) {
+	// Повторная позиция пропускается
+	g, err := NewMinesPvEGame("test", 1, 100, 3, &stubRandomizer{ints: []int64{3, 3, 7, 24}})
+	if err != nil {
+		t.Fatalf("new game: %v", err)
+	}
+	if len(g.Mines) != 3 || g.Mines[0] != 3 || g.Mines[1] != 7 || g.Mines[2] != 24 {
+		t.Fatalf("expected mines [3 7 24], got %v", g.Mines)
+	}
+
+	if _, err := g.Reveal(0); err != nil || !g.IsActive() {
+		t.Fatalf("expected safe reveal, got %v (%s)", err, g.Status)
+	}
+	if _, err := g.Reveal(7); err != nil || g.Status != MinesProStatusExploded {
+		t.Fatalf("expected explosion on cell 7, got %v (%s)", err, g.Status)
+	}
+}
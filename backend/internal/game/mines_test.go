@@ -0,0 +1,75 @@
+package game
+
+import "testing"
+
+func TestMinesGameUsesConfiguredBoard(t *testing.T) {
+	cfg := MinesConfig{Cells: 16, Mines: 6}
+	g := NewMinesGameWithConfig("test", [2]int64{1, 2}, cfg)
+
+	// Неверное число мин - бот расставляет сам, но по конфигу
+	if err := g.HandleMove(1, []int{1, 2, 3, 4}); err != nil {
+		t.Fatalf("setup p1: %v", err)
+	}
+	if err := g.HandleMove(2, []int{11, 12, 13, 14, 15, 16}); err != nil {
+		t.Fatalf("setup p2: %v", err)
+	}
+	if !g.IsSetupComplete() {
+		t.Fatal("expected setup to be complete")
+	}
+
+	for id, board := range g.boards {
+		if len(board.mines) != cfg.Cells {
+			t.Fatalf("player %d: expected %d cells, got %d", id, cfg.Cells, len(board.mines))
+		}
+		placed := 0
+		for _, m := range board.mines {
+			if m {
+				placed++
+			}
+		}
+		if placed != cfg.Mines {
+			t.Fatalf("player %d: expected %d mines, got %d", id, cfg.Mines, placed)
+		}
+	}
+
+	// Клетка 16 существует только на увеличенном поле
+	if err := g.HandleMove(1, 16); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	if g.moves[1] != 16 {
+		t.Fatalf("expected move to cell 16, got %d", g.moves[1])
+	}
+}
+
+func TestMinesConfigRequiresSafeCell(t *testing.T) {
+	if (MinesConfig{Cells: 4, Mines: 4}).Valid() {
+		t.Fatal("expected config without safe cells to be invalid")
+	}
+	g := NewMinesGameWithConfig("test", [2]int64{1, 2}, MinesConfig{Cells: 4, Mines: 4})
+	if g.Config() != DefaultMinesConfig() {
+		t.Fatalf("expected fallback to default config, got %+v", g.Config())
+	}
+}
+
+func TestMinesSecondPlayerTakesTimeoutBoard(t *testing.T) {
+	g := NewMinesGame("test", [2]int64{1, 0})
+
+	// Таймаут расстановки, пока второго игрока ещё нет
+	g.HandleMove(1, nil)
+	g.HandleMove(0, nil)
+	g.SetSecondPlayer(2)
+
+	if !g.IsSetupComplete() || g.boards[2] == nil {
+		t.Fatal("expected the placeholder board to move to the second player")
+	}
+	if _, ok := g.boards[0]; ok {
+		t.Fatal("placeholder board should be gone")
+	}
+
+	g.HandleMove(1, 1)
+	g.HandleMove(2, 1)
+	g.CheckResult() // раньше падало на пустой доске второго игрока
+	if len(g.GetMoveHistory(2)) != 1 {
+		t.Fatalf("expected a round in the second player's history, got %v", g.GetMoveHistory(2))
+	}
+}
@@ -0,0 +1,48 @@
+package game
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"math/rand"
+)
+
+// Randomizer is the source of game outcomes. Production code uses
+// NewCryptoRandomizer; tests inject a deterministic stub.
+type Randomizer interface {
+	Float64() float64 // [0, 1)
+	Intn(n int) int   // [0, n)
+	Int63n(n int64) int64
+}
+
+// cryptoSource - rand.Source64 поверх crypto/rand, безопасен для конкурентного использования
+type cryptoSource struct{}
+
+func (cryptoSource) Seed(int64) {}
+
+func (s cryptoSource) Int63() int64 {
+	return int64(s.Uint64() & (1<<63 - 1))
+}
+
+func (cryptoSource) Uint64() uint64 {
+	var b [8]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		panic("game: crypto/rand unavailable: " + err.Error())
+	}
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+// NewCryptoRandomizer returns a Randomizer backed by crypto/rand.
+// It is safe for concurrent use.
+func NewCryptoRandomizer() Randomizer {
+	return rand.New(cryptoSource{})
+}
+
+// defaultRandomizer используется, когда вызывающий передал nil
+var defaultRandomizer = NewCryptoRandomizer()
+
+func orDefault(rng Randomizer) Randomizer {
+	if rng == nil {
+		return defaultRandomizer
+	}
+	return rng
+}
@@ -0,0 +1,63 @@
+package game
+
+import "testing"
+
+// stubRandomizer replays fixed values; Intn/Int63n take them modulo n
+type stubRandomizer struct {
+	floats []float64
+	ints   []int64
+}
+
+func (r *stubRandomizer) Float64() float64 {
+	f := r.floats[0]
+	r.floats = r.floats[1:]
+	return f
+}
+
+func (r *stubRandomizer) Int63n(n int64) int64 {
+	v := r.ints[0]
+	r.ints = r.ints[1:]
+	return v % n
+}
+
+func (r *stubRandomizer) Intn(n int) int {
+	return int(r.Int63n(int64(n)))
+}
+
+func TestDiceRollWithStub(t *testing.T) {
+	cases := []struct {
+		mode   string
+		target int
+		roll   int64
+		result int
+		won    bool
+		payout int64
+	}{
+		{DiceModeExact, 3, 2, 3, true, 550},
+		{DiceModeExact, 3, 3, 4, false, 0},
+		{DiceModeLow, 0, 2, 3, true, 180},
+		{DiceModeHigh, 0, 2, 3, false, 0},
+		{DiceModeHigh, 0, 5, 6, true, 180},
+	}
+	for _, tc := range cases {
+		g := NewDiceGame(tc.target, tc.mode, &stubRandomizer{ints: []int64{tc.roll}})
+		if got := g.Roll(); got != tc.result || g.Won != tc.won {
+			t.Fatalf("%s/%d: expected %d (won=%v), got %d (won=%v)", tc.mode, tc.target, tc.result, tc.won, got, g.Won)
+		}
+		if got := g.CalculateWinAmount(100); got != tc.payout {
+			t.Fatalf("%s/%d: expected payout %d, got %d", tc.mode, tc.target, tc.payout, got)
+		}
+	}
+}
+
+func TestCryptoRandomizerRange(t *testing.T) {
+	rng := NewCryptoRandomizer()
+	for i := 0; i < 1000; i++ {
+		if f := rng.Float64(); f < 0 || f >= 1 {
+			t.Fatalf("Float64 out of range: %v", f)
+		}
+		if n := rng.Intn(6); n < 0 || n >= 6 {
+			t.Fatalf("Intn out of range: %d", n)
+		}
+	}
+}
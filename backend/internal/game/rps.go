@@ -82,10 +82,21 @@ func (g *RPSGame) IsRoundComplete() bool {
 	return len(g.moves) == 2
 }
 
+func (g *RPSGame) HasMoved(playerID int64) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	_, ok := g.moves[playerID]
+	return ok
+}
+
 func (g *RPSGame) CheckResult() *GameResult {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
+	if g.result != nil {
+		return g.result
+	}
+
 	if len(g.moves) < 2 {
 		log.Printf("RPSGame.CheckResult: not enough moves yet: moves=%v", g.moves)
 		return nil
@@ -147,6 +158,25 @@ func (g *RPSGame) CheckResult() *GameResult {
 	return nil
 }
 
+// ForceFinish ends the game with winnerID without playing remaining rounds
+func (g *RPSGame) ForceFinish(winnerID int64, reason string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.result != nil {
+		return false
+	}
+	g.result = &GameResult{
+		WinnerID: &winnerID,
+		Reason:   reason,
+		Details: map[string]interface{}{
+			"rounds": g.round,
+			"reason": reason,
+		},
+	}
+	return true
+}
+
 func (g *RPSGame) IsFinished() bool {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
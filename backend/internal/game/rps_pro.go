@@ -0,0 +1,222 @@
+package game
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// RPSProGame is a best-of-N rock-paper-scissors match against the bot
+type RPSProGame struct {
+	ID         string        `json:"id"`
+	UserID     int64         `json:"user_id"`
+	Bet        int64         `json:"bet"`
+	Currency   string        `json:"currency"` // gems или coins
+	BestOf     int           `json:"best_of"`
+	PlayerWins int           `json:"player_wins"`
+	BotWins    int           `json:"bot_wins"`
+	Multiplier float64       `json:"multiplier"`
+	Status     string        `json:"status"` // active, won, lost
+	WinAmount  int64         `json:"win_amount"`
+	Rounds     []RPSProRound `json:"rounds"`
+	CreatedAt  time.Time     `json:"created_at"`
+	LastMoveAt time.Time     `json:"last_move_at"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+	mu         sync.RWMutex
+}
+
+// RPSProRound is one round of the match, draws don't count towards wins
+type RPSProRound struct {
+	Round  int    `json:"round"`
+	Player string `json:"player"`
+	Bot    string `json:"bot"`
+	Result string `json:"result"` // win, lose, draw
+}
+
+const (
+	RPSProStatusActive = "active"
+	RPSProStatusWon    = "won"
+	RPSProStatusLost   = "lost"
+
+	// RPSProMaxRounds caps a match so endless draws can't keep it alive
+	RPSProMaxRounds = 30
+)
+
+// RPSProBestOf - допустимые форматы матча
+var RPSProBestOf = []int{3, 5}
+
+var rpsMoves = []string{"rock", "paper", "scissors"}
+
+// NewRPSProGame creates a new best-of-N match
+func NewRPSProGame(id string, userID int64, bet int64, bestOf int, multiplier float64) (*RPSProGame, error) {
+	if bet <= 0 {
+		return nil, errors.New("bet must be positive")
+	}
+	valid := false
+	for _, n := range RPSProBestOf {
+		if n == bestOf {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return nil, errors.New("best_of must be 3 or 5")
+	}
+
+	now := time.Now()
+	return &RPSProGame{
+		ID:         id,
+		UserID:     userID,
+		Bet:        bet,
+		Currency:   "gems",
+		BestOf:     bestOf,
+		Multiplier: multiplier,
+		Status:     RPSProStatusActive,
+		Rounds:     []RPSProRound{},
+		CreatedAt:  now,
+		LastMoveAt: now,
+	}, nil
+}
+
+// WinsNeeded returns how many round wins end the match
+func (g *RPSProGame) WinsNeeded() int {
+	return g.BestOf/2 + 1
+}
+
+// Play plays one round with the player's move against a random bot move.
+// finished is true only for the call that ended the match, so the caller
+// settles it exactly once.
+func (g *RPSProGame) Play(move string) (round RPSProRound, finished bool, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Status != RPSProStatusActive {
+		return RPSProRound{}, false, ErrGameNotActive
+	}
+	if move != "rock" && move != "paper" && move != "scissors" {
+		return RPSProRound{}, false, errors.New("invalid move")
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(rpsMoves))))
+	if err != nil {
+		return RPSProRound{}, false, err
+	}
+	bot := rpsMoves[n.Int64()]
+
+	round = RPSProRound{Round: len(g.Rounds) + 1, Player: move, Bot: bot}
+	switch {
+	case move == bot:
+		round.Result = "draw"
+	case (move == "rock" && bot == "scissors") ||
+		(move == "paper" && bot == "rock") ||
+		(move == "scissors" && bot == "paper"):
+		round.Result = "win"
+		g.PlayerWins++
+	default:
+		round.Result = "lose"
+		g.BotWins++
+	}
+	g.Rounds = append(g.Rounds, round)
+	g.LastMoveAt = time.Now()
+
+	switch {
+	case g.PlayerWins >= g.WinsNeeded():
+		g.finish(RPSProStatusWon)
+	case g.BotWins >= g.WinsNeeded():
+		g.finish(RPSProStatusLost)
+	case len(g.Rounds) >= RPSProMaxRounds:
+		// Лимит раундов: побеждает тот, кто впереди, при равенстве ставка проиграна
+		if g.PlayerWins > g.BotWins {
+			g.finish(RPSProStatusWon)
+		} else {
+			g.finish(RPSProStatusLost)
+		}
+	}
+
+	return round, g.Status != RPSProStatusActive, nil
+}
+
+// Forfeit ends an abandoned match as lost
+func (g *RPSProGame) Forfeit() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.Status != RPSProStatusActive {
+		return false
+	}
+	g.finish(RPSProStatusLost)
+	return true
+}
+
+// finish - caller must hold lock
+func (g *RPSProGame) finish(status string) {
+	g.Status = status
+	if status == RPSProStatusWon {
+		g.WinAmount = int64(float64(g.Bet) * g.Multiplier)
+	} else {
+		g.WinAmount = 0
+	}
+	now := time.Now()
+	g.FinishedAt = &now
+}
+
+// IdleSince returns when the player last acted
+func (g *RPSProGame) IdleSince() time.Time {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.LastMoveAt
+}
+
+// GetState returns the current match state (safe for client)
+func (g *RPSProGame) GetState() map[string]interface{} {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return map[string]interface{}{
+		"id":            g.ID,
+		"bet":           g.Bet,
+		"currency":      g.Currency,
+		"best_of":       g.BestOf,
+		"wins_needed":   g.WinsNeeded(),
+		"player_wins":   g.PlayerWins,
+		"bot_wins":      g.BotWins,
+		"multiplier":    g.Multiplier,
+		"status":        g.Status,
+		"win_amount":    g.WinAmount,
+		"potential_win": int64(float64(g.Bet) * g.Multiplier),
+		"rounds":        g.Rounds,
+	}
+}
+
+// IsActive returns whether the match is still being played
+func (g *RPSProGame) IsActive() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.Status == RPSProStatusActive
+}
+
+// GetProfit returns net profit (win - bet)
+func (g *RPSProGame) GetProfit() int64 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.Status == RPSProStatusWon {
+		return g.WinAmount - g.Bet
+	}
+	return -g.Bet
+}
+
+// ToDetails returns match details for game history
+func (g *RPSProGame) ToDetails() map[string]interface{} {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return map[string]interface{}{
+		"game_id":     g.ID,
+		"currency":    g.Currency,
+		"best_of":     g.BestOf,
+		"player_wins": g.PlayerWins,
+		"bot_wins":    g.BotWins,
+		"multiplier":  g.Multiplier,
+		"rounds":      g.Rounds,
+	}
+}
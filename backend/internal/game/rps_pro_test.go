@@ -0,0 +1,81 @@
+package game
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRPSProMatchEndsAtRequiredWins(t *testing.T) {
+	for _, bestOf := range RPSProBestOf {
+		g, err := NewRPSProGame("test", 1, 100, bestOf, 1.9)
+		if err != nil {
+			t.Fatalf("best_of=%d: %v", bestOf, err)
+		}
+		for g.IsActive() {
+			if _, _, err := g.Play("rock"); err != nil {
+				t.Fatalf("best_of=%d: play: %v", bestOf, err)
+			}
+		}
+
+		need := bestOf/2 + 1
+		switch g.Status {
+		case RPSProStatusWon:
+			if g.PlayerWins < need && len(g.Rounds) < RPSProMaxRounds {
+				t.Fatalf("best_of=%d: won with %d wins", bestOf, g.PlayerWins)
+			}
+			if g.WinAmount != 190 {
+				t.Fatalf("best_of=%d: expected win amount 190, got %d", bestOf, g.WinAmount)
+			}
+		case RPSProStatusLost:
+			if g.BotWins < need && len(g.Rounds) < RPSProMaxRounds {
+				t.Fatalf("best_of=%d: lost with %d bot wins", bestOf, g.BotWins)
+			}
+			if g.GetProfit() != -100 {
+				t.Fatalf("best_of=%d: expected profit -100, got %d", bestOf, g.GetProfit())
+			}
+		}
+		if _, _, err := g.Play("rock"); err == nil {
+			t.Fatalf("best_of=%d: expected error playing a finished match", bestOf)
+		}
+	}
+}
+
+func TestRPSProRejectsInvalidInput(t *testing.T) {
+	if _, err := NewRPSProGame("test", 1, 100, 4, 1.9); err == nil {
+		t.Fatal("expected error for best_of=4")
+	}
+	g, _ := NewRPSProGame("test", 1, 100, 3, 1.9)
+	if _, _, err := g.Play("lizard"); err == nil {
+		t.Fatal("expected error for invalid move")
+	}
+	if !g.Forfeit() || g.Status != RPSProStatusLost || g.Forfeit() {
+		t.Fatalf("expected forfeit to end the match once, status %s", g.Status)
+	}
+}
+
+func TestRPSProConcurrentMovesFinishOnce(t *testing.T) {
+	g, err := NewRPSProGame("test", 1, 100, 3, 1.9)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var finished atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for g.IsActive() {
+				if _, done, _ := g.Play("paper"); done {
+					finished.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := finished.Load(); got != 1 {
+		t.Fatalf("expected exactly one move to finish the match, got %d", got)
+	}
+}
@@ -1,9 +1,9 @@
 package game
 
-import (
-	"crypto/rand"
-	"math/big"
-)
+import "errors"
+
+// ErrWheelWeights - у активных сегментов нет положительного суммарного веса
+var ErrWheelWeights = errors.New("wheel: segment weights must sum to a positive number")
 
 // WheelSegment represents a single segment on the wheel
 type WheelSegment struct {
@@ -12,6 +12,7 @@ type WheelSegment struct {
 	Color       string  `json:"color"`
 	Probability float64 `json:"probability"` // 0.0 - 1.0
 	Label       string  `json:"label"`
+	Weight      int     `json:"weight,omitempty"` // вес из wheel_segments, 0 для встроенной таблицы
 }
 
 // WheelGame represents a single wheel spin
@@ -19,6 +20,8 @@ type WheelGame struct {
 	Segments []WheelSegment `json:"segments"`
 	Result   *WheelSegment  `json:"result"`
 	SpinAngle float64       `json:"spin_angle"` // Final angle for frontend animation
+
+	rng Randomizer
 }
 
 // DefaultWheelSegments returns the default wheel configuration
@@ -35,55 +38,64 @@ func DefaultWheelSegments() []WheelSegment {
 	}
 }
 
-// NewWheelGame creates a new wheel game with default segments
-func NewWheelGame() *WheelGame {
-	return &WheelGame{
-		Segments: DefaultWheelSegments(),
-	}
+// NewWheelGame creates a new wheel game with default segments; nil rng uses crypto/rand
+func NewWheelGame(rng Randomizer) *WheelGame {
+	return NewWheelGameWithSegments(DefaultWheelSegments(), rng)
 }
 
 // NewWheelGameWithSegments creates a wheel game with custom segments
-func NewWheelGameWithSegments(segments []WheelSegment) *WheelGame {
+func NewWheelGameWithSegments(segments []WheelSegment, rng Randomizer) *WheelGame {
 	return &WheelGame{
 		Segments: segments,
+		rng:      orDefault(rng),
 	}
 }
 
-// Spin performs the wheel spin and returns the winning segment
-func (g *WheelGame) Spin() *WheelSegment {
-	// Generate cryptographically secure random number
-	max := big.NewInt(1000000) // 0.000001 precision
-	n, err := rand.Int(rand.Reader, max)
-	if err != nil {
-		n = big.NewInt(500000)
+// WheelSegmentsFromWeights sets each segment's probability to weight / total weight.
+// Weights must be non-negative and sum to a positive number.
+func WheelSegmentsFromWeights(segments []WheelSegment) ([]WheelSegment, error) {
+	total := 0
+	for _, seg := range segments {
+		if seg.Weight < 0 || seg.Multiplier < 0 {
+			return nil, ErrWheelWeights
+		}
+		total += seg.Weight
+	}
+	if total <= 0 {
+		return nil, ErrWheelWeights
 	}
 
-	random := float64(n.Int64()) / 1000000.0 // 0.0 - 0.999999
+	out := make([]WheelSegment, len(segments))
+	for i, seg := range segments {
+		seg.Probability = float64(seg.Weight) / float64(total)
+		out[i] = seg
+	}
+	return out, nil
+}
+
+// Spin performs the wheel spin and returns the winning segment
+func (g *WheelGame) Spin() *WheelSegment {
+	random := float64(g.rng.Int63n(1000000)) / 1000000.0 // 0.0 - 0.999999, шаг 0.000001
 
 	// Find winning segment based on probability distribution
 	cumulative := 0.0
+	index := len(g.Segments) - 1 // fallback to last segment if something went wrong
 	for i := range g.Segments {
 		cumulative += g.Segments[i].Probability
 		if random < cumulative {
-			g.Result = &g.Segments[i]
+			index = i
 			break
 		}
 	}
-
-	// Fallback to last segment if something went wrong
-	if g.Result == nil {
-		g.Result = &g.Segments[len(g.Segments)-1]
-	}
+	g.Result = &g.Segments[index]
 
 	// Calculate spin angle for frontend animation
-	// Each segment takes 360/numSegments degrees
+	// Each segment takes 360/numSegments degrees; ids from the DB aren't positions
 	segmentAngle := 360.0 / float64(len(g.Segments))
-	baseAngle := float64(g.Result.ID-1) * segmentAngle
+	baseAngle := float64(index) * segmentAngle
 
 	// Add random offset within segment + multiple full rotations
-	offsetMax := big.NewInt(int64(segmentAngle * 100))
-	offsetN, _ := rand.Int(rand.Reader, offsetMax)
-	offset := float64(offsetN.Int64()) / 100.0
+	offset := float64(g.rng.Int63n(int64(segmentAngle*100))) / 100.0
 
 	rotations := 5 // Number of full rotations for animation
 	g.SpinAngle = float64(rotations*360) + baseAngle + offset
@@ -0,0 +1,70 @@
+package game
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestWheelSegmentsFromWeights(t *testing.T) {
+	segments, err := WheelSegmentsFromWeights([]WheelSegment{
+		{ID: 10, Multiplier: 0, Weight: 3},
+		{ID: 20, Multiplier: 2, Weight: 1},
+		{ID: 30, Multiplier: 100, Weight: 0},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if segments[0].Probability != 0.75 || segments[1].Probability != 0.25 || segments[2].Probability != 0 {
+		t.Fatalf("unexpected probabilities: %+v", segments)
+	}
+
+	g := NewWheelGameWithSegments(segments, nil)
+	if got := g.GetExpectedReturn(); math.Abs(got-0.5) > 1e-9 {
+		t.Fatalf("expected return 0.5, got %v", got)
+	}
+
+	// Сегмент с нулевым весом никогда не выпадает; угол считается по позиции, а не по id
+	for i := 0; i < 200; i++ {
+		res := g.Spin()
+		if res.ID == 30 {
+			t.Fatalf("zero-weight segment won")
+		}
+		if g.SpinAngle < 360*5 || g.SpinAngle >= 360*6 {
+			t.Fatalf("spin angle out of range: %v", g.SpinAngle)
+		}
+	}
+
+	for _, bad := range [][]WheelSegment{
+		nil,
+		{{Multiplier: 1, Weight: 0}},
+		{{Multiplier: 1, Weight: 5}, {Multiplier: 2, Weight: -1}},
+	} {
+		if _, err := WheelSegmentsFromWeights(bad); !errors.Is(err, ErrWheelWeights) {
+			t.Fatalf("expected ErrWheelWeights for %+v, got %v", bad, err)
+		}
+	}
+}
+
+func TestWheelSpinWithStub(t *testing.T) {
+	segments, err := WheelSegmentsFromWeights([]WheelSegment{
+		{ID: 10, Multiplier: 0, Weight: 3},
+		{ID: 20, Multiplier: 2, Weight: 1},
+		{ID: 30, Multiplier: 100, Weight: 0},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 0.8 попадает во второй сегмент (0.75..1.0), смещение 0.5 градуса внутри него
+	g := NewWheelGameWithSegments(segments, &stubRandomizer{ints: []int64{800000, 50}})
+	if res := g.Spin(); res.ID != 20 {
+		t.Fatalf("expected segment 20, got %d", res.ID)
+	}
+	if got := g.CalculateWinAmount(100); got != 200 {
+		t.Fatalf("expected payout 200, got %d", got)
+	}
+	if want := 360*5 + 120 + 0.5; g.SpinAngle != want {
+		t.Fatalf("expected spin angle %v, got %v", want, g.SpinAngle)
+	}
+}
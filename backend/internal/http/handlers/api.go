@@ -1,9 +1,9 @@
 package handlers
 
 import (
-	"context"
 	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"telegram_webapp/internal/domain"
@@ -62,21 +62,17 @@ func (h *Handler) UpdateBalance(c *gin.Context) {
 	}
 
 	var req struct {
-		Delta int64 `json:"delta"`
+		Delta int64 `json:"delta" binding:"amount"`
 	}
-	if err := c.BindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
 		return
 	}
 
 	ctx := c.Request.Context()
 	result, err := h.GameService.UpdateBalance(ctx, userID, req.Delta)
 	if err != nil {
-		if errors.Is(err, service.ErrInsufficientBalance) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "insufficient balance"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		respondError(c, err)
 		return
 	}
 
@@ -93,23 +89,33 @@ func (h *Handler) AddHistory(c *gin.Context) {
 
 	var req struct {
 		Type   string                 `json:"type"`
-		Amount int64                  `json:"amount"`
+		Amount int64                  `json:"amount" binding:"amount"`
 		Meta   map[string]interface{} `json:"meta"`
 	}
-	if err := c.BindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
 		return
 	}
 
 	ctx := c.Request.Context()
 	if err := h.GameService.AddTransaction(ctx, userID, req.Type, req.Amount, req.Meta); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		if errors.Is(err, repository.ErrMetaTooLarge) {
+			err = invalidGameRequest("meta too large")
+		}
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
 
-// GetHistory returns recent transactions for the current user
+// История без параметров отдаёт последние 200 как раньше; явный limit ограничен 100
+const (
+	historyDefaultLimit = 200
+	historyMaxLimit     = 100
+)
+
+// GetHistory returns the current user's transactions, newest first.
+// Query: limit, before_id (cursor from next_cursor), type.
 func (h *Handler) GetHistory(c *gin.Context) {
 	userID, ok := getUserID(c)
 	if !ok {
@@ -117,13 +123,48 @@ func (h *Handler) GetHistory(c *gin.Context) {
 		return
 	}
 
+	filter := repository.TransactionFilter{Limit: historyDefaultLimit, Type: c.Query("type")}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		if n > historyMaxLimit {
+			n = historyMaxLimit
+		}
+		filter.Limit = n
+	}
+	if v := c.Query("before_id"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid before_id"})
+			return
+		}
+		filter.BeforeID = n
+	}
+	if len(filter.Type) > 50 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid type"})
+		return
+	}
+
+	// Берём на одну больше, чтобы понять, есть ли следующая страница
+	limit := filter.Limit
+	filter.Limit++
+
 	ctx := c.Request.Context()
-	transactions, err := h.GameService.GetTransactionHistory(ctx, userID, 200)
+	transactions, err := h.TransactionRepo.List(ctx, userID, filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
 		return
 	}
 
+	var nextCursor *int64
+	if len(transactions) > limit {
+		transactions = transactions[:limit]
+		nextCursor = &transactions[limit-1].ID
+	}
+
 	var out []map[string]interface{}
 	for _, tx := range transactions {
 		out = append(out, map[string]interface{}{
@@ -134,10 +175,10 @@ func (h *Handler) GetHistory(c *gin.Context) {
 			"date":   tx.CreatedAt,
 		})
 	}
-	c.JSON(http.StatusOK, gin.H{"history": out})
+	c.JSON(http.StatusOK, gin.H{"history": out, "next_cursor": nextCursor})
 }
 
-// CoinFlip performs a server-side coin flip: 50/50. Expects {bet:int}
+// CoinFlip performs a server-side coin flip: 50/50, win pays bet*multiplier (see /game/limits). Expects {bet:int}
 func (h *Handler) CoinFlip(c *gin.Context) {
 	userID, ok := getUserID(c)
 	if !ok {
@@ -146,28 +187,32 @@ func (h *Handler) CoinFlip(c *gin.Context) {
 	}
 
 	var req struct {
-		Bet int64 `json:"bet"`
+		Bet      int64  `json:"bet" binding:"amount"`
+		Currency string `json:"currency"`
 	}
-	if err := c.BindJSON(&req); err != nil || req.Bet <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid bet"})
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if req.Bet <= 0 {
+		respondError(c, service.ErrInvalidBet)
+		return
+	}
+	currency, err := service.ParseGameCurrency(req.Currency)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
 	ctx := c.Request.Context()
-	result, meta, err := h.GameService.PlayCoinFlip(ctx, userID, req.Bet)
+	result, meta, err := h.GameService.PlayCoinFlip(ctx, userID, req.Bet, currency)
 	if err != nil {
-		if errors.Is(err, service.ErrInsufficientBalance) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "insufficient balance"})
-			return
-		}
-		if errors.Is(err, service.ErrBetTooLow) || errors.Is(err, service.ErrBetTooHigh) || errors.Is(err, service.ErrInvalidBet) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		respondError(c, err)
 		return
 	}
 
+	resp := withFair(withBalance(gin.H{"win": result.Win, "awarded": result.Awarded}, currency, result.NewBalance), meta)
+
 	// Record game history
 	var gameResult domain.GameResult
 	if result.Win {
@@ -175,12 +220,12 @@ func (h *Handler) CoinFlip(c *gin.Context) {
 	} else {
 		gameResult = domain.GameResultLose
 	}
-	go h.RecordGameResultWithTimeout(userID, domain.GameTypeCoinflip, domain.GameModePVE, gameResult, req.Bet, result.Awarded-req.Bet, meta)
+	go h.RecordGameResult(userID, domain.GameTypeCoinflip, domain.GameModePVE, gameResult, req.Bet, result.Awarded-req.Bet, currency, meta)
 
 	// Audit log
 	h.AuditService.LogGame(ctx, userID, "coinflip", req.Bet, result.Awarded-req.Bet, result.Win, meta)
 
-	c.JSON(http.StatusOK, gin.H{"win": result.Win, "awarded": result.Awarded, "gems": result.NewBalance})
+	c.JSON(http.StatusOK, resp)
 }
 
 // RPS: server-side rock-paper-scissors PvE
@@ -192,29 +237,38 @@ func (h *Handler) RPS(c *gin.Context) {
 	}
 
 	var req struct {
-		Move string `json:"move"`
-		Bet  int64  `json:"bet"`
+		Move     string `json:"move"`
+		Bet      int64  `json:"bet" binding:"amount"`
+		Currency string `json:"currency"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
 	}
-	if err := c.BindJSON(&req); err != nil || (req.Move != "rock" && req.Move != "paper" && req.Move != "scissors") {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+	if req.Move != "rock" && req.Move != "paper" && req.Move != "scissors" {
+		respondError(c, errGameInvalidRequest)
+		return
+	}
+	currency, err := service.ParseGameCurrency(req.Currency)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
 	ctx := c.Request.Context()
-	result, meta, err := h.GameService.PlayRPS(ctx, userID, req.Move, req.Bet)
+	result, meta, err := h.GameService.PlayRPS(ctx, userID, req.Move, req.Bet, currency)
 	if err != nil {
-		if errors.Is(err, service.ErrInsufficientBalance) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "insufficient balance"})
-			return
-		}
-		if errors.Is(err, service.ErrBetTooLow) || errors.Is(err, service.ErrBetTooHigh) || errors.Is(err, service.ErrInvalidBet) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		respondError(c, err)
 		return
 	}
 
+	resp := withFair(withBalance(gin.H{
+		"move":    result.UserMove,
+		"bot":     result.BotMove,
+		"result":  result.Result,
+		"awarded": result.Awarded,
+	}, currency, result.NewBalance), meta)
+
 	// Record game history
 	var gameResult domain.GameResult
 	if result.Result == 1 {
@@ -225,21 +279,16 @@ func (h *Handler) RPS(c *gin.Context) {
 		gameResult = domain.GameResultLose
 	}
 	netAmount := result.Awarded - req.Bet
-	go h.RecordGameResultWithTimeout(userID, domain.GameTypeRPS, domain.GameModePVE, gameResult, req.Bet, netAmount, meta)
+	go h.RecordGameResult(userID, domain.GameTypeRPS, domain.GameModePVE, gameResult, req.Bet, netAmount, currency, meta)
 
 	// Audit log
 	h.AuditService.LogGame(ctx, userID, "rps", req.Bet, netAmount, result.Result == 1, meta)
 
-	c.JSON(http.StatusOK, gin.H{
-		"move":    result.UserMove,
-		"bot":     result.BotMove,
-		"result":  result.Result,
-		"awarded": result.Awarded,
-		"gems":    result.NewBalance,
-	})
+	c.JSON(http.StatusOK, resp)
 }
 
-// Mines PvE simple: user picks a cell 1..12; server places 4 mines. If pick is safe, user wins bet*2, else loses.
+// Mines PvE simple: user picks a cell 1..12; server places 4 mines (configurable). If pick is safe,
+// user wins bet*multiplier (fair odds minus house edge, see /game/mines/info), else loses.
 func (h *Handler) Mines(c *gin.Context) {
 	userID, ok := getUserID(c)
 	if !ok {
@@ -248,29 +297,34 @@ func (h *Handler) Mines(c *gin.Context) {
 	}
 
 	var req struct {
-		Pick int   `json:"pick"`
-		Bet  int64 `json:"bet"`
+		Pick       int    `json:"pick"`
+		MinesCount int    `json:"mines_count"` // 0 - по умолчанию
+		Bet        int64  `json:"bet" binding:"amount"`
+		Currency   string `json:"currency"`
 	}
-	if err := c.BindJSON(&req); err != nil || req.Pick < 1 || req.Pick > 12 || req.Bet <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if req.Pick < 1 || req.Pick > h.GameService.MinesConfig().Cells || req.Bet <= 0 {
+		respondError(c, errGameInvalidRequest)
+		return
+	}
+	currency, err := service.ParseGameCurrency(req.Currency)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
 	ctx := c.Request.Context()
-	result, meta, err := h.GameService.PlayMines(ctx, userID, req.Pick, req.Bet)
+	result, meta, err := h.GameService.PlayMines(ctx, userID, req.Pick, req.MinesCount, req.Bet, currency)
 	if err != nil {
-		if errors.Is(err, service.ErrInsufficientBalance) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "insufficient balance"})
-			return
-		}
-		if errors.Is(err, service.ErrBetTooLow) || errors.Is(err, service.ErrBetTooHigh) || errors.Is(err, service.ErrInvalidBet) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		respondError(c, err)
 		return
 	}
 
+	resp := withFair(withBalance(gin.H{"win": result.Win, "awarded": result.Awarded, "mines_count": meta["mines_count"], "multiplier": meta["multiplier"]}, currency, result.NewBalance), meta)
+
 	// Record game history
 	var gameResult domain.GameResult
 	if result.Win {
@@ -279,15 +333,77 @@ func (h *Handler) Mines(c *gin.Context) {
 		gameResult = domain.GameResultLose
 	}
 	netAmount := result.Awarded - req.Bet
-	go h.RecordGameResultWithTimeout(userID, domain.GameTypeMines, domain.GameModePVE, gameResult, req.Bet, netAmount, meta)
+	go h.RecordGameResult(userID, domain.GameTypeMines, domain.GameModePVE, gameResult, req.Bet, netAmount, currency, meta)
 
 	// Audit log
 	h.AuditService.LogGame(ctx, userID, "mines", req.Bet, netAmount, result.Win, meta)
 
-	c.JSON(http.StatusOK, gin.H{"win": result.Win, "awarded": result.Awarded, "gems": result.NewBalance})
+	c.JSON(http.StatusOK, resp)
+}
+
+// MinesInfo returns simple Mines board and payout so the client doesn't hardcode 2x;
+// multipliers lists the payout for every allowed mines_count
+func (h *Handler) MinesInfo(c *gin.Context) {
+	cfg := h.GameService.MinesConfig()
+	multipliers := make(map[int]float64, cfg.Cells-1)
+	for mines := 1; mines < cfg.Cells; mines++ {
+		board, _ := cfg.WithMines(mines)
+		multipliers[mines] = board.Multiplier()
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"cells":       cfg.Cells,
+		"mines":       cfg.Mines,
+		"min_mines":   1,
+		"max_mines":   cfg.Cells - 1,
+		"multiplier":  cfg.Multiplier(),
+		"multipliers": multipliers,
+		"win_chance":  cfg.WinChance(),
+		"house_edge":  cfg.HouseEdge,
+		"rtp":         cfg.RTP(),
+		"animation":   h.animationFor(domain.GameTypeMines),
+	})
+}
+
+// CaseSpinRequest - кейс и цена, которую видел игрок (оба необязательны)
+type CaseSpinRequest struct {
+	CaseID string `json:"case_id"` // пусто - первый кейс из /game/case/info
+	Cost   int64  `json:"cost" binding:"omitempty,min=1,amount"`
+}
+
+// CaseInfo returns the available cases with their authoritative cost and prize
+// odds. Top-level cost/prizes describe the first case for clients without case
+// choice and are absent when no case is active.
+func (h *Handler) CaseInfo(c *gin.Context) {
+	cases := h.Cases.Cases()
+	list := make([]gin.H, 0, len(cases))
+	for _, cfg := range cases {
+		list = append(list, gin.H{
+			"case_id":        cfg.ID,
+			"name":           cfg.Name,
+			"cost":           cfg.Cost,
+			"prizes":         cfg.Prizes,
+			"expected_prize": cfg.ExpectedPrize(),
+		})
+	}
+
+	if len(cases) == 0 {
+		// Все кейсы выключены в case_configs
+		c.JSON(http.StatusOK, gin.H{"cases": list, "animation": h.animationFor(domain.GameTypeCase)})
+		return
+	}
+
+	first := cases[0]
+	c.JSON(http.StatusOK, gin.H{
+		"cases":          list,
+		"case_id":        first.ID,
+		"cost":           first.Cost,
+		"prizes":         first.Prizes,
+		"expected_prize": first.ExpectedPrize(),
+		"animation":      h.animationFor(domain.GameTypeCase),
+	})
 }
 
-// CaseSpin performs a server-side case/roulette spin with fixed prize distribution
+// CaseSpin performs a server-side spin of the requested case with the prize table from CaseInfo
 func (h *Handler) CaseSpin(c *gin.Context) {
 	userID, ok := getUserID(c)
 	if !ok {
@@ -295,17 +411,34 @@ func (h *Handler) CaseSpin(c *gin.Context) {
 		return
 	}
 
-	const cost int64 = 100
+	var req CaseSpinRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondBindError(c, err)
+			return
+		}
+	}
+
+	cfg, err := h.Cases.Get(req.CaseID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
 	ctx := c.Request.Context()
-	result, meta, err := h.GameService.PlayCaseSpin(ctx, userID)
+	result, meta, err := h.GameService.PlayCaseSpin(ctx, userID, cfg, req.Cost)
 	if err != nil {
-		if errors.Is(err, service.ErrInsufficientBalance) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "insufficient balance"})
+		if errors.Is(err, service.ErrCaseCostMismatch) {
+			// Клиент показал устаревшую цену - отдаём актуальную
+			c.JSON(http.StatusConflict, gin.H{"error": "case cost changed", "code": "CASE_COST_CHANGED", "cost": cfg.Cost})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		respondError(c, err)
 		return
 	}
+	cost := result.Cost
+
+	resp := withFair(gin.H{"prize": result.Prize, "prize_id": result.PrizeID, "case_id": result.CaseID, "gems": result.NewBalance}, meta)
 
 	// Record game history
 	netAmount := result.Prize - cost
@@ -315,71 +448,52 @@ func (h *Handler) CaseSpin(c *gin.Context) {
 	} else {
 		gameResult = domain.GameResultLose
 	}
-	go h.RecordGameResultWithTimeout(userID, domain.GameTypeCase, domain.GameModeSolo, gameResult, cost, netAmount, meta)
+	go h.RecordGameResult(userID, domain.GameTypeCase, domain.GameModePVE, gameResult, cost, netAmount, domain.CurrencyGems, meta)
 
 	// Audit log
 	h.AuditService.LogGame(ctx, userID, "case", cost, netAmount, netAmount >= 0, meta)
 
-	c.JSON(http.StatusOK, gin.H{"prize": result.Prize, "case_id": result.CaseID, "gems": result.NewBalance})
-}
-
-// RecordGameResultWithTimeout records game result with proper context timeout
-func (h *Handler) RecordGameResultWithTimeout(userID int64, gameType domain.GameType, mode domain.GameMode, result domain.GameResult, bet int64, winAmount int64, details map[string]interface{}) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	gh := &domain.GameHistory{
-		UserID:    userID,
-		GameType:  gameType,
-		Mode:      mode,
-		Result:    result,
-		BetAmount: bet,
-		WinAmount: winAmount,
-		Details:   details,
-	}
-
-	_ = h.GameHistoryRepo.Create(ctx, gh)
-
-	// Update quests
-	h.updateQuestsWithContext(ctx, userID, string(gameType), string(result))
+	c.JSON(http.StatusOK, resp)
 }
 
-// updateQuestsWithContext updates quests with provided context
-func (h *Handler) updateQuestsWithContext(ctx context.Context, userID int64, gameType string, result string) {
-	// Получаем все активные квесты
-	quests, err := h.QuestRepo.GetActiveQuests(ctx)
-	if err != nil {
-		return
-	}
-
-	for _, quest := range quests {
-		// Проверяем соответствие типа игры
-		if quest.GameType != nil && *quest.GameType != "any" && *quest.GameType != gameType {
-			continue
-		}
-
-		// Проверяем тип действия
-		shouldIncrement := false
-		switch quest.ActionType {
-		case domain.ActionTypePlay:
-			shouldIncrement = true
-		case domain.ActionTypeWin:
-			shouldIncrement = (result == "win")
-		case domain.ActionTypeLose:
-			shouldIncrement = (result == "lose")
-		}
-
-		if shouldIncrement {
-			_ = h.QuestRepo.IncrementProgress(ctx, userID, quest, 1)
+// gameLimitsJSON renders per-game limits in the same shape as the global ones
+func gameLimitsJSON(limits map[domain.GameType]service.GameLimits) gin.H {
+	out := gin.H{}
+	for gt, l := range limits {
+		out[string(gt)] = gin.H{
+			"min_bet":       l.MinBet,
+			"max_bet":       l.MaxBet,
+			"min_bet_coins": l.MinBetCoins,
+			"max_bet_coins": l.MaxBetCoins,
 		}
 	}
+	return out
 }
 
 // GameLimits returns current bet limits for games
 func (h *Handler) GameLimits(c *gin.Context) {
 	limits := h.GameService.GetLimits()
+	payouts := h.GameService.Payouts()
+	happy := h.GameService.HappyHours()
 	c.JSON(http.StatusOK, gin.H{
-		"min_bet": limits.MinBet,
-		"max_bet": limits.MaxBet,
+		"min_bet":       limits.MinBet,
+		"max_bet":       limits.MaxBet,
+		"min_bet_coins": limits.MinBetCoins,
+		"max_bet_coins": limits.MaxBetCoins,
+		// Лимиты по играм с учётом переопределений из /setlimits
+		"games": gameLimitsJSON(h.GameService.GameLimitsByGame()),
+		// Раскрываем защиту от невезения, если она включена (coinflip, mines)
+		"luck_protection": h.GameService.LuckProtection(),
+		// Буст выплат по расписанию: все окна и действующие сейчас множители
+		"happy_hours": gin.H{
+			"windows": happy.Windows(),
+			"active":  happy.Active(time.Now()),
+			"max_rtp": happy.MaxRTP(),
+		},
+		"payouts": gin.H{
+			"coinflip":   payouts.CoinFlipMultiplier(),
+			"rps":        payouts.RPSMultiplier(),
+			"house_edge": payouts.HouseEdge,
+		},
 	})
 }
@@ -2,19 +2,22 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/logger"
 	"telegram_webapp/internal/repository"
 	"telegram_webapp/internal/service"
 
 	"github.com/gin-gonic/gin"
-	
+	"github.com/jackc/pgx/v5"
 )
 
 
@@ -24,7 +27,7 @@ type AuthRequest struct {
 
 func (h *Handler) Auth(c *gin.Context) {
 	var req AuthRequest
-	if err := c.BindJSON(&req); err != nil {
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
 		return
 	}
@@ -62,14 +65,21 @@ func (h *Handler) Auth(c *gin.Context) {
 			}
 		}
 
-		token, err := service.GenerateJWT(user.ID)
+		if banned, _ := repo.IsBanned(ctx, user.ID); banned {
+			c.JSON(http.StatusForbidden, gin.H{"error": "banned"})
+			return
+		}
+
+		token, refreshToken, err := issueTokens(user.ID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "token generation failed"})
 			return
 		}
 
 		c.JSON(http.StatusOK, gin.H{
-			"token": token,
+			"token":         token,
+			"refresh_token": refreshToken,
+			"expires_in":    int(service.AccessTokenTTL().Seconds()),
 			"user": gin.H{
 				"id":         user.ID,
 				"tg_id":      user.TgID,
@@ -112,6 +122,11 @@ func (h *Handler) Auth(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user json"})
 		return
 	}
+	// tg_id берётся только из подписанных данных, нулевой id не принимаем
+	if tgUser.ID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
 
 	repo := repository.NewUserRepository(h.DB)
 	ctx := c.Request.Context()
@@ -130,6 +145,32 @@ func (h *Handler) Auth(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create user"})
 			return
 		}
+
+		// Много аккаунтов с одного IP - урезаем стартовый баланс, регистрацию не блокируем
+		var authDate time.Time
+		if ts, err := strconv.ParseInt(values.Get("auth_date"), 10, 64); err == nil {
+			authDate = time.Unix(ts, 0)
+		}
+		if check, err := h.SignupGuard.OnSignup(ctx, user.ID, c.ClientIP(), authDate); err != nil {
+			logger.Error("signup check failed", "user_id", user.ID, "error", err)
+		} else {
+			user.Gems = check.Gems
+		}
+	}
+
+	// Заблокированный пользователь не получает токен
+	if banned, _ := repo.IsBanned(ctx, user.ID); banned {
+		c.JSON(http.StatusForbidden, gin.H{"error": "banned"})
+		return
+	}
+
+	// Бонус за возвращение: считается по старому last_login_at, поэтому до выдачи токена
+	var comebackBonus int64
+	if !isNewUser {
+		if granted, err := h.ComebackBonus.OnLogin(ctx, user.ID); err == nil && granted > 0 {
+			comebackBonus = granted
+			user.Gems += granted
+		}
 	}
 
 	// Handle referral from startapp parameter (format: ref_CODE)
@@ -142,19 +183,23 @@ func (h *Handler) Auth(c *gin.Context) {
 			referrerID, err := referralRepo.GetUserByReferralCode(ctx, refCode)
 			if err == nil && referrerID != user.ID {
 				// Create referral relationship
-				_ = referralRepo.CreateReferral(ctx, referrerID, user.ID)
+				if created, err := referralRepo.CreateReferral(ctx, referrerID, user.ID); err == nil && created {
+					h.ReferralRewards.OnReferralCreated(ctx, user.ID)
+				}
 			}
 		}
 	}
 
-	token, err := service.GenerateJWT(user.ID)
+	token, refreshToken, err := issueTokens(user.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "token generation failed"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"token": token,
+		"token":         token,
+		"refresh_token": refreshToken,
+		"expires_in":    int(service.AccessTokenTTL().Seconds()),
 		"user": gin.H{
 			"id":         user.ID,
 			"tg_id":      user.TgID,
@@ -162,5 +207,70 @@ func (h *Handler) Auth(c *gin.Context) {
 			"first_name": user.FirstName,
 			"gems":       user.Gems,
 		},
+		"comeback_bonus": comebackBonus,
+	})
+}
+
+// issueTokens returns a new access token and refresh token for the user
+func issueTokens(userID int64) (string, string, error) {
+	token, err := service.GenerateJWT(userID)
+	if err != nil {
+		return "", "", err
+	}
+	refreshToken, err := service.GenerateRefreshToken(userID)
+	if err != nil {
+		return "", "", err
+	}
+	return token, refreshToken, nil
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RefreshToken issues a new token pair for a valid refresh token. An expired
+// refresh token answers 401 refresh_token_expired - the client has to call /auth again.
+func (h *Handler) RefreshToken(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "refresh_token required"})
+		return
+	}
+
+	userID, err := service.ParseRefreshToken(req.RefreshToken)
+	if errors.Is(err, service.ErrTokenExpired) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "refresh_token_expired"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
+		return
+	}
+
+	// Заблокированный или удалённый пользователь новый токен не получает
+	banned, err := h.UserRepo.IsBanned(c.Request.Context(), userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	if banned {
+		c.JSON(http.StatusForbidden, gin.H{"error": "banned"})
+		return
+	}
+
+	token, refreshToken, err := issueTokens(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "token generation failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":         token,
+		"refresh_token": refreshToken,
+		"expires_in":    int(service.AccessTokenTTL().Seconds()),
 	})
 }
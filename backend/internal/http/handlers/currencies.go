@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/service"
+	"telegram_webapp/internal/ton"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currenciesCacheSeconds - метаданные меняются только с конфигом, клиент может кэшировать
+const currenciesCacheSeconds = 60
+
+// Currencies returns display info for each currency, where it is accepted and exchange paths.
+// Values come from the same limits and TON constants the game and TON handlers use.
+func (h *Handler) Currencies(c *gin.Context) {
+	limits := h.GameService.GetLimits()
+
+	currencies := []gin.H{
+		{
+			"code":         domain.CurrencyGems,
+			"name":         "Gems",
+			"icon":         "💎",
+			"description":  "Free currency earned in games, quests and bonuses",
+			"withdrawable": false,
+			"accepted_in": []string{
+				string(domain.GameTypeCoinflip), string(domain.GameTypeRPS), string(domain.GameTypeMines),
+				string(domain.GameTypeMinesPro), string(domain.GameTypeDice), string(domain.GameTypeWheel),
+				string(domain.GameTypeCase), "pvp",
+			},
+			"min_bet": limits.MinBet,
+			"max_bet": limits.MaxBet,
+		},
+		{
+			"code":         domain.CurrencyCoins,
+			"name":         "Coins",
+			"icon":         "🪙",
+			"description":  "Premium currency bought with TON, withdrawable",
+			"withdrawable": true,
+			"accepted_in":  append(h.coinGames(c.Request.Context()), "pvp", "withdrawal"),
+			"min_bet":      limits.MinBetCoins,
+			"max_bet":      limits.MaxBetCoins,
+		},
+		{
+			"code":         domain.CurrencyGK,
+			"name":         "GK",
+			"icon":         "⚡",
+			"description":  "Upgrade currency earned from referrals",
+			"withdrawable": false,
+			"accepted_in":  []string{"upgrade"},
+		},
+	}
+
+	exchanges := []gin.H{
+		{
+			"from":       "ton",
+			"to":         domain.CurrencyCoins,
+			"rate":       h.CoinsPerTON,
+			"action":     "deposit",
+			"min_amount": ton.NanoToTON(ton.MinDepositNano),
+		},
+		{
+			"from":       domain.CurrencyCoins,
+			"to":         "ton",
+			"rate":       1.0 / float64(h.CoinsPerTON),
+			"action":     "withdrawal",
+			"min_amount": ton.MinWithdrawCoins,
+			"fee_coins":  ton.CalculateWithdrawFeeCoins(ton.MinWithdrawCoins, h.WithdrawFee),
+		},
+		{
+			"from":   "referral",
+			"to":     domain.CurrencyGK,
+			"action": "referral_reward",
+			"rates":  ReferralGKRewards,
+		},
+	}
+
+	c.Header("Cache-Control", "public, max-age="+strconv.Itoa(currenciesCacheSeconds))
+	c.JSON(http.StatusOK, gin.H{
+		"currencies":    currencies,
+		"exchanges":     exchanges,
+		"cache_seconds": currenciesCacheSeconds,
+	})
+}
+
+// coinGames lists the PvE games that accept coins right now: only those where
+// the house keeps an edge with the current payouts (see service.CoinsAllowed)
+func (h *Handler) coinGames(ctx context.Context) []string {
+	var out []string
+	for _, gt := range service.LimitGames {
+		var rtp float64
+		switch gt {
+		case domain.GameTypeWheel:
+			wheelGame, err := h.buildWheelGame(ctx, nil)
+			if err != nil {
+				continue
+			}
+			rtp = wheelGame.GetExpectedReturn()
+		case domain.GameTypeRPSPro:
+			rtp = h.RPSProService.ExpectedReturn()
+		default:
+			var ok bool
+			if rtp, ok = h.GameService.ExpectedReturn(gt); !ok {
+				continue
+			}
+		}
+		if service.CoinsAllowed(rtp) {
+			out = append(out, string(gt))
+		}
+	}
+	return out
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FairRotateRequest - новый client seed (пусто - сгенерирует сервер)
+type FairRotateRequest struct {
+	ClientSeed string `json:"client_seed" binding:"max=64"`
+}
+
+// FairSeed returns the commitment of the seed pair the next games will use and
+// the last revealed seed, so outcomes can be checked before and after playing
+func (h *Handler) FairSeed(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	current, previous, err := h.FairRNG.Seed(userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"current": current, "previous": previous})
+}
+
+// FairRotate reveals the current server seed and starts a new seed pair.
+// Refused while a Mines Pro or CoinFlip Pro game is played on the seed.
+func (h *Handler) FairRotate(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	// Тело необязательно
+	var req FairRotateRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondBindError(c, err)
+		return
+	}
+
+	revealed, current, err := h.FairRNG.Rotate(userID, req.ClientSeed)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"revealed": revealed, "current": current})
+}
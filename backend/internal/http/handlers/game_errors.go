@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"telegram_webapp/internal/game"
+	"telegram_webapp/internal/logger"
+	"telegram_webapp/internal/repository"
+	"telegram_webapp/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GameError is a game failure the client can react to: Code is stable and
+// machine-readable, Message is for display.
+type GameError struct {
+	Code    string
+	Status  int
+	Message string
+}
+
+func (e *GameError) Error() string {
+	return e.Message
+}
+
+var (
+	errGameInvalidRequest = invalidGameRequest("invalid request")
+	errGameInternal       = &GameError{Code: "INTERNAL_ERROR", Status: http.StatusInternalServerError, Message: "internal error"}
+)
+
+// invalidGameRequest - INVALID_REQUEST с пояснением, что не так в запросе
+func invalidGameRequest(message string) *GameError {
+	return &GameError{Code: "INVALID_REQUEST", Status: http.StatusBadRequest, Message: message}
+}
+
+// gameErrorCodes maps service and game sentinel errors to their codes
+var gameErrorCodes = []struct {
+	err    error
+	code   string
+	status int
+}{
+	{service.ErrInsufficientBalance, "INSUFFICIENT_BALANCE", http.StatusBadRequest},
+	{service.ErrBetTooLow, "BET_TOO_LOW", http.StatusBadRequest},
+	{service.ErrBetTooHigh, "BET_TOO_HIGH", http.StatusBadRequest},
+	{service.ErrInvalidBet, "INVALID_BET", http.StatusBadRequest},
+	{service.ErrInvalidPick, "INVALID_PICK", http.StatusBadRequest},
+	{service.ErrInvalidMinesCount, "INVALID_MINES_COUNT", http.StatusBadRequest},
+	{service.ErrInvalidCurrency, "INVALID_CURRENCY", http.StatusBadRequest},
+	{service.ErrCoinsNotAccepted, "COINS_NOT_ACCEPTED", http.StatusBadRequest},
+	{service.ErrUnknownCase, "UNKNOWN_CASE", http.StatusBadRequest},
+	{game.ErrInvalidCell, "INVALID_CELL", http.StatusBadRequest},
+	{game.ErrCellRevealed, "CELL_ALREADY_REVEALED", http.StatusConflict},
+	{game.ErrNothingToCashOut, "NOTHING_TO_CASH_OUT", http.StatusConflict},
+	{game.ErrCoinFlipProCashoutTooEarly, "NOTHING_TO_CASH_OUT", http.StatusConflict},
+	{game.ErrGameNotActive, "GAME_NOT_ACTIVE", http.StatusConflict},
+	{service.ErrNoActiveGame, "GAME_NOT_ACTIVE", http.StatusConflict},
+	{service.ErrActiveGame, "ACTIVE_GAME_EXISTS", http.StatusConflict},
+	{repository.ErrActiveMinesProGame, "ACTIVE_GAME_EXISTS", http.StatusConflict},
+	{service.ErrRevealTooFast, "REVEAL_TOO_FAST", http.StatusTooManyRequests},
+	{service.ErrFairRNGDisabled, "FAIR_RNG_DISABLED", http.StatusNotFound},
+	{service.ErrFairSeedInUse, "SEED_IN_USE", http.StatusConflict},
+}
+
+// toGameError returns the GameError for err; unknown errors become INTERNAL_ERROR
+func toGameError(err error) *GameError {
+	var ge *GameError
+	if errors.As(err, &ge) {
+		return ge
+	}
+	for _, m := range gameErrorCodes {
+		if errors.Is(err, m.err) {
+			return &GameError{Code: m.code, Status: m.status, Message: m.err.Error()}
+		}
+	}
+	return errGameInternal
+}
+
+// respondError answers {"error": message, "code": CODE} with the error's status.
+// Internal errors are logged and their details are not sent to the client.
+func respondError(c *gin.Context, err error) {
+	ge := toGameError(err)
+	if ge == errGameInternal && err != errGameInternal {
+		logger.Error("game request failed", "error", err, "path", c.FullPath())
+	}
+	c.JSON(ge.Status, gin.H{"error": ge.Message, "code": ge.Code})
+}
@@ -2,13 +2,15 @@ package handlers
 
 import (
 	"context"
+	"log"
 	"time"
 
 	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/metrics"
 )
 
 // RecordGameResult записывает результат игры в историю и обновляет квесты
-func (h *Handler) RecordGameResult(userID int64, gameType domain.GameType, mode domain.GameMode, result domain.GameResult, betAmount, winAmount int64, details map[string]interface{}) {
+func (h *Handler) RecordGameResult(userID int64, gameType domain.GameType, mode domain.GameMode, result domain.GameResult, betAmount, winAmount int64, currency domain.Currency, details map[string]interface{}) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -20,12 +22,18 @@ func (h *Handler) RecordGameResult(userID int64, gameType domain.GameType, mode
 		Result:    result,
 		BetAmount: betAmount,
 		WinAmount: winAmount,
+		Currency:  currency,
 		Details:   details,
 	}
 	_ = h.GameHistoryRepo.Create(ctx, gh)
+	metrics.ObserveGame(string(gameType), string(result), string(currency), betAmount, winAmount)
+	h.addCoinsWager(ctx, userID, currency, betAmount)
 
-	// Обновляем прогресс квестов с тем же контекстом
-	h.updateQuestsAfterGameWithCtx(ctx, userID, string(gameType), string(result))
+	// Прогресс квестов обновляется в фоне
+	h.Quests.OnGamePlayed(ctx, userID, gameType, result, currency, betAmount, winAmount)
+
+	// Реферал мог набрать нужное число игр
+	h.ReferralRewards.OnGamePlayed(ctx, userID)
 }
 
 // RecordPVPGameResult записывает результат PvP игры для обоих игроков
@@ -87,7 +95,20 @@ func (h *Handler) RecordPVPGameResult(playerA, playerB int64, gameType domain.Ga
 	}
 	_ = h.GameHistoryRepo.Create(ctx, ghB)
 
-	// Обновляем квесты для обоих с тем же контекстом
-	h.updateQuestsAfterGameWithCtx(ctx, playerA, string(gameType), string(resultA))
-	h.updateQuestsAfterGameWithCtx(ctx, playerB, string(gameType), string(resultB))
+	// Обновляем квесты для обоих
+	h.Quests.OnGamePlayed(ctx, playerA, gameType, resultA, domain.CurrencyGems, betAmount, winAmountA)
+	h.Quests.OnGamePlayed(ctx, playerB, gameType, resultB, domain.CurrencyGems, betAmount, winAmountB)
+
+	h.ReferralRewards.OnGamePlayed(ctx, playerA)
+	h.ReferralRewards.OnGamePlayed(ctx, playerB)
+}
+
+// addCoinsWager counts a settled coins bet towards bonus wagering, like PvP rooms do
+func (h *Handler) addCoinsWager(ctx context.Context, userID int64, currency domain.Currency, bet int64) {
+	if currency != domain.CurrencyCoins || bet <= 0 || h.BonusRepo == nil {
+		return
+	}
+	if err := h.BonusRepo.AddWager(ctx, userID, currency, bet); err != nil {
+		log.Printf("failed to add bonus wager user=%d: %v", userID, err)
+	}
 }
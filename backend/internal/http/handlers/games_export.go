@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultGamesExportMaxRows - потолок выгрузки истории, если GAMES_EXPORT_MAX_ROWS не задан
+const DefaultGamesExportMaxRows = 10000
+
+// gamesExportFlushEvery - через сколько строк отдаём накопленное клиенту
+const gamesExportFlushEvery = 200
+
+// gamesExportTimeout - сколько может длиться одна выгрузка, включая запись медленному клиенту
+const gamesExportTimeout = 2 * time.Minute
+
+var gamesExportCSVHeader = []string{"id", "game_type", "mode", "result", "bet_amount", "win_amount", "currency", "created_at"}
+
+// gameExportRow - строка JSON-выгрузки; в CSV details не попадают
+type gameExportRow struct {
+	ID        int64                  `json:"id"`
+	GameType  domain.GameType        `json:"game_type"`
+	Mode      domain.GameMode        `json:"mode"`
+	Result    domain.GameResult      `json:"result"`
+	BetAmount int64                  `json:"bet_amount"`
+	WinAmount int64                  `json:"win_amount"`
+	Currency  domain.Currency        `json:"currency"`
+	CreatedAt time.Time              `json:"created_at"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// ExportMyGames streams the caller's game history as a CSV or JSON attachment,
+// newest first and at most GamesExportMaxRows rows. ?format=csv (default) | json.
+// Rows are read in pages and flushed in batches; the whole export, database reads
+// and writes to the client, has to finish within gamesExportTimeout.
+func (h *Handler) ExportMyGames(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or json"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), gamesExportTimeout)
+	defer cancel()
+	// Запись клиенту ограничена тем же сроком; если writer не умеет дедлайны, хватает ctx
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(gamesExportTimeout))
+
+	filename := fmt.Sprintf("games-%d-%s.%s", userID, time.Now().UTC().Format("20060102"), format)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Header("Cache-Control", "no-store")
+
+	var (
+		write  func(*domain.GameHistory) error
+		finish func() error
+		rows   int
+	)
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		w := csv.NewWriter(c.Writer)
+		write = func(gh *domain.GameHistory) error {
+			if rows == 0 {
+				if err := w.Write(gamesExportCSVHeader); err != nil {
+					return err
+				}
+			}
+			if err := w.Write([]string{
+				strconv.FormatInt(gh.ID, 10),
+				string(gh.GameType),
+				string(gh.Mode),
+				string(gh.Result),
+				strconv.FormatInt(gh.BetAmount, 10),
+				strconv.FormatInt(gh.WinAmount, 10),
+				string(gh.Currency),
+				gh.CreatedAt.UTC().Format(time.RFC3339),
+			}); err != nil {
+				return err
+			}
+			if (rows+1)%gamesExportFlushEvery == 0 {
+				w.Flush()
+				c.Writer.Flush()
+			}
+			return w.Error()
+		}
+		finish = func() error {
+			if rows == 0 {
+				if err := w.Write(gamesExportCSVHeader); err != nil {
+					return err
+				}
+			}
+			w.Flush()
+			return w.Error()
+		}
+	} else {
+		c.Header("Content-Type", "application/json; charset=utf-8")
+		write = func(gh *domain.GameHistory) error {
+			data, err := json.Marshal(gameExportRow{
+				ID:        gh.ID,
+				GameType:  gh.GameType,
+				Mode:      gh.Mode,
+				Result:    gh.Result,
+				BetAmount: gh.BetAmount,
+				WinAmount: gh.WinAmount,
+				Currency:  gh.Currency,
+				CreatedAt: gh.CreatedAt,
+				Details:   gh.Details,
+			})
+			if err != nil {
+				return err
+			}
+			sep := ",\n"
+			if rows == 0 {
+				sep = "[\n"
+			}
+			if _, err := c.Writer.WriteString(sep); err != nil {
+				return err
+			}
+			if _, err := c.Writer.Write(data); err != nil {
+				return err
+			}
+			if (rows+1)%gamesExportFlushEvery == 0 {
+				c.Writer.Flush()
+			}
+			return nil
+		}
+		finish = func() error {
+			end := "\n]\n"
+			if rows == 0 {
+				end = "[]\n"
+			}
+			_, err := c.Writer.WriteString(end)
+			return err
+		}
+	}
+
+	err := h.GameHistoryRepo.StreamByUser(ctx, userID, h.GamesExportMaxRows, func(gh *domain.GameHistory) error {
+		if err := write(gh); err != nil {
+			return err
+		}
+		rows++
+		return nil
+	})
+	if err == nil {
+		err = finish()
+	}
+	if err != nil {
+		if !c.Writer.Written() {
+			c.Header("Content-Disposition", "")
+			c.Header("Content-Type", "")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export games"})
+			return
+		}
+		// Заголовки уже ушли - обрываем выгрузку, клиент получит неполный файл
+		logger.Error("games export interrupted", "user_id", userID, "rows", rows, "error", err)
+		return
+	}
+	c.Writer.Flush()
+}
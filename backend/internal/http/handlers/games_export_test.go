@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// exportRequest runs ExportMyGames for userID (0 - without authentication)
+func exportRequest(h *Handler, userID int64, query string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/me/games/export"+query, nil)
+	if userID != 0 {
+		c.Set("user_id", userID)
+	}
+	h.ExportMyGames(c)
+	return w
+}
+
+func TestExportMyGamesRejectsBadRequests(t *testing.T) {
+	h := &Handler{}
+
+	if w := exportRequest(h, 0, ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without user, got %d", w.Code)
+	}
+	if w := exportRequest(h, 1, "?format=xml"); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown format, got %d", w.Code)
+	}
+}
+
+// Integration-style test: runs only if TEST_DATABASE_URL env is set.
+func TestExportMyGames(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set; skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	db, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+
+	u := &domain.User{TgID: time.Now().UnixNano(), Username: "games_export_test"}
+	if err := repository.NewUserRepository(db).Create(ctx, u); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	defer db.Exec(context.Background(), `DELETE FROM users WHERE id = $1`, u.ID)
+	defer db.Exec(context.Background(), `DELETE FROM game_history WHERE user_id = $1`, u.ID)
+
+	games := repository.NewGameHistoryRepository(db)
+	for i, bet := range []int64{10, 20, 30} {
+		gh := &domain.GameHistory{
+			UserID:    u.ID,
+			GameType:  domain.GameTypeDice,
+			Mode:      domain.GameModePVE,
+			Result:    domain.GameResultLose,
+			BetAmount: bet,
+			WinAmount: -bet,
+			Currency:  domain.CurrencyGems,
+			Details:   map[string]interface{}{"n": i},
+		}
+		if err := games.Create(ctx, gh); err != nil {
+			t.Fatalf("create game: %v", err)
+		}
+	}
+
+	// CSV: заголовок и не больше GamesExportMaxRows строк, новые первыми
+	h := &Handler{GameHistoryRepo: games, GamesExportMaxRows: 2}
+	w := exportRequest(h, u.ID, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("csv export: status %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("unexpected content type %q", w.Header().Get("Content-Type"))
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected header and 2 rows, got %d records", len(records))
+	}
+	if strings.Join(records[0], ",") != strings.Join(gamesExportCSVHeader, ",") {
+		t.Fatalf("unexpected header %v", records[0])
+	}
+	if records[1][4] != "30" || records[2][4] != "20" {
+		t.Fatalf("expected newest games first, got bets %s, %s", records[1][4], records[2][4])
+	}
+
+	// JSON без потолка: все игры с details
+	h.GamesExportMaxRows = 0
+	w = exportRequest(h, u.ID, "?format=json")
+	if w.Code != http.StatusOK {
+		t.Fatalf("json export: status %d: %s", w.Code, w.Body.String())
+	}
+	var rows []gameExportRow
+	if err := json.Unmarshal(w.Body.Bytes(), &rows); err != nil {
+		t.Fatalf("parse json: %v", err)
+	}
+	if len(rows) != 3 || rows[2].BetAmount != 10 || rows[2].Details == nil {
+		t.Fatalf("unexpected json export: %+v", rows)
+	}
+
+	// Пользователь без игр получает пустую выгрузку
+	w = exportRequest(h, u.ID+1_000_000_000, "?format=json")
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Fatalf("expected empty json export, got %d %q", w.Code, w.Body.String())
+	}
+}
@@ -1,11 +1,16 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 
 	"telegram_webapp/internal/domain"
 	"telegram_webapp/internal/game"
+	"telegram_webapp/internal/logger"
 	"telegram_webapp/internal/repository"
+	"telegram_webapp/internal/service"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5"
@@ -13,9 +18,10 @@ import (
 
 // DiceRequest represents the dice game request (1-6 dice)
 type DiceRequest struct {
-	Bet    int64  `json:"bet" binding:"required,min=1"`
-	Target int    `json:"target"` // Required for "exact" mode, ignored for range modes
-	Mode   string `json:"mode" binding:"required,oneof=exact low high"`
+	Bet      int64  `json:"bet" binding:"required,min=1,amount"`
+	Target   int    `json:"target"` // Required for "exact" mode, ignored for range modes
+	Mode     string `json:"mode" binding:"required,oneof=exact low high"`
+	Currency string `json:"currency"` // gems (по умолчанию) или coins
 }
 
 // DiceResponse represents the dice game response (1-6 dice)
@@ -27,7 +33,11 @@ type DiceResponse struct {
 	WinChance  float64 `json:"win_chance"`
 	Won        bool    `json:"won"`
 	WinAmount  int64   `json:"win_amount"`
-	Gems       int64   `json:"gems"`
+	Gems       *int64  `json:"gems,omitempty"`
+	Coins      *int64  `json:"coins,omitempty"`
+
+	Currency domain.Currency `json:"currency"`
+	Fair     *game.FairProof `json:"fair,omitempty"`
 }
 
 // Dice handles the dice game endpoint
@@ -40,60 +50,67 @@ func (h *Handler) Dice(c *gin.Context) {
 
 	var req DiceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		respondBindError(c, err)
 		return
 	}
 
 	// Validate mode
 	if req.Mode != game.DiceModeExact && req.Mode != game.DiceModeLow && req.Mode != game.DiceModeHigh {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be 'exact', 'low', or 'high'"})
+		respondError(c, invalidGameRequest("mode must be 'exact', 'low', or 'high'"))
 		return
 	}
 
 	// Validate target for exact mode
 	if req.Mode == game.DiceModeExact {
 		if req.Target < game.DiceMinTarget || req.Target > game.DiceMaxTarget {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "target must be between 1 and 6 for exact mode"})
+			respondError(c, invalidGameRequest("target must be between 1 and 6 for exact mode"))
 			return
 		}
 	}
 
+	currency, ok := h.parsePvEBet(c, domain.GameTypeDice, req.Bet, req.Currency)
+	if !ok {
+		return
+	}
+	col := currency.BalanceColumn()
+
 	ctx := c.Request.Context()
 
 	// Start transaction
 	tx, err := h.DB.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		respondError(c, err)
 		return
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
 	// Lock and check balance
 	var balance int64
-	if err := tx.QueryRow(ctx, `SELECT gems FROM users WHERE id=$1 FOR UPDATE`, userID).Scan(&balance); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+	if err := tx.QueryRow(ctx, fmt.Sprintf(`SELECT %s FROM users WHERE id=$1 FOR UPDATE`, col), userID).Scan(&balance); err != nil {
+		respondError(c, err)
 		return
 	}
 	if balance < req.Bet {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "insufficient balance"})
+		respondError(c, service.ErrInsufficientBalance)
 		return
 	}
 
 	// Deduct bet
-	if _, err := tx.Exec(ctx, `UPDATE users SET gems = gems - $1 WHERE id=$2`, req.Bet, userID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+	if _, err := tx.Exec(ctx, fmt.Sprintf(`UPDATE users SET %[1]s = %[1]s - $1 WHERE id=$2`, col), req.Bet, userID); err != nil {
+		respondError(c, err)
 		return
 	}
 
 	// Play the game (1-6 dice with mode)
-	diceGame := game.NewDiceGame(req.Target, req.Mode)
+	rng, fair := h.GameService.RoundRandomizer(userID)
+	diceGame := game.NewDiceGame(req.Target, req.Mode, rng)
 	diceGame.Roll()
 
 	// Calculate winnings
 	winAmount := diceGame.CalculateWinAmount(req.Bet)
 	if winAmount > 0 {
-		if _, err := tx.Exec(ctx, `UPDATE users SET gems = gems + $1 WHERE id=$2`, winAmount, userID); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		if _, err := tx.Exec(ctx, fmt.Sprintf(`UPDATE users SET %[1]s = %[1]s + $1 WHERE id=$2`, col), winAmount, userID); err != nil {
+			respondError(c, err)
 			return
 		}
 	}
@@ -103,6 +120,10 @@ func (h *Handler) Dice(c *gin.Context) {
 	meta := diceGame.ToDetails()
 	meta["bet"] = req.Bet
 	meta["win_amount"] = winAmount
+	meta["currency"] = currency
+	if fair != nil {
+		meta["fair"] = fair
+	}
 	txRecord := &domain.Transaction{
 		UserID: userID,
 		Type:   "dice",
@@ -110,19 +131,19 @@ func (h *Handler) Dice(c *gin.Context) {
 		Meta:   meta,
 	}
 	if err := h.TransactionRepo.CreateWithTx(ctx, tx, txRecord); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		respondError(c, err)
 		return
 	}
 
 	// Get new balance
 	var newBalance int64
-	if err := tx.QueryRow(ctx, `SELECT gems FROM users WHERE id=$1`, userID).Scan(&newBalance); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+	if err := tx.QueryRow(ctx, fmt.Sprintf(`SELECT %s FROM users WHERE id=$1`, col), userID).Scan(&newBalance); err != nil {
+		respondError(c, err)
 		return
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		respondError(c, err)
 		return
 	}
 
@@ -133,9 +154,9 @@ func (h *Handler) Dice(c *gin.Context) {
 	} else {
 		gameResult = domain.GameResultLose
 	}
-	go h.RecordGameResult(userID, domain.GameTypeDice, domain.GameModePVE, gameResult, req.Bet, netAmount, meta)
+	go h.RecordGameResult(userID, domain.GameTypeDice, domain.GameModePVE, gameResult, req.Bet, netAmount, currency, meta)
 
-	c.JSON(http.StatusOK, DiceResponse{
+	resp := DiceResponse{
 		Target:     diceGame.Target,
 		Result:     diceGame.Result,
 		Mode:       diceGame.Mode,
@@ -143,8 +164,30 @@ func (h *Handler) Dice(c *gin.Context) {
 		WinChance:  diceGame.WinChance(),
 		Won:        diceGame.Won,
 		WinAmount:  winAmount,
-		Gems:       newBalance,
-	})
+		Currency:   currency,
+		Fair:       fair,
+	}
+	if currency == domain.CurrencyCoins {
+		resp.Coins = &newBalance
+	} else {
+		resp.Gems = &newBalance
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// parsePvEBet resolves the optional request currency and checks the bet against
+// the game's limits in it, answering 400 itself when either is invalid
+func (h *Handler) parsePvEBet(c *gin.Context, gt domain.GameType, bet int64, rawCurrency string) (domain.Currency, bool) {
+	currency, err := service.ParseGameCurrency(rawCurrency)
+	if err != nil {
+		respondError(c, err)
+		return "", false
+	}
+	if err := h.GameService.ValidateBetForGame(gt, bet, currency); err != nil {
+		respondError(c, err)
+		return "", false
+	}
+	return currency, true
 }
 
 // DiceInfo returns dice game configuration info (1-6 dice)
@@ -176,12 +219,14 @@ func (h *Handler) DiceInfo(c *gin.Context) {
 				"win_chance":  50.0,
 			},
 		},
+		"animation": h.animationFor(domain.GameTypeDice),
 	})
 }
 
 // WheelRequest represents the wheel game request
 type WheelRequest struct {
-	Bet int64 `json:"bet" binding:"required,min=1"`
+	Bet      int64  `json:"bet" binding:"required,min=1,amount"`
+	Currency string `json:"currency"` // gems (по умолчанию) или coins
 }
 
 // WheelResponse represents the wheel game response
@@ -192,7 +237,11 @@ type WheelResponse struct {
 	Label      string  `json:"label"`
 	SpinAngle  float64 `json:"spin_angle"`
 	WinAmount  int64   `json:"win_amount"`
-	Gems       int64   `json:"gems"`
+	Gems       *int64  `json:"gems,omitempty"`
+	Coins      *int64  `json:"coins,omitempty"`
+
+	Currency domain.Currency `json:"currency"`
+	Fair     *game.FairProof `json:"fair,omitempty"`
 }
 
 // Wheel handles the wheel of fortune game endpoint
@@ -205,46 +254,62 @@ func (h *Handler) Wheel(c *gin.Context) {
 
 	var req WheelRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		respondBindError(c, err)
+		return
+	}
+
+	currency, ok := h.parsePvEBet(c, domain.GameTypeWheel, req.Bet, req.Currency)
+	if !ok {
 		return
 	}
+	col := currency.BalanceColumn()
 
 	ctx := c.Request.Context()
 
+	rng, fair := h.GameService.RoundRandomizer(userID)
+	wheelGame, ok := h.loadWheelGame(c, rng)
+	if !ok {
+		return
+	}
+	// RTP колеса задаёт таблица из БД, поэтому coins проверяются по живому конфигу
+	if currency == domain.CurrencyCoins && !service.CoinsAllowed(wheelGame.GetExpectedReturn()) {
+		respondError(c, service.ErrCoinsNotAccepted)
+		return
+	}
+
 	// Start transaction
 	tx, err := h.DB.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		respondError(c, err)
 		return
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
 	// Lock and check balance
 	var balance int64
-	if err := tx.QueryRow(ctx, `SELECT gems FROM users WHERE id=$1 FOR UPDATE`, userID).Scan(&balance); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+	if err := tx.QueryRow(ctx, fmt.Sprintf(`SELECT %s FROM users WHERE id=$1 FOR UPDATE`, col), userID).Scan(&balance); err != nil {
+		respondError(c, err)
 		return
 	}
 	if balance < req.Bet {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "insufficient balance"})
+		respondError(c, service.ErrInsufficientBalance)
 		return
 	}
 
 	// Deduct bet
-	if _, err := tx.Exec(ctx, `UPDATE users SET gems = gems - $1 WHERE id=$2`, req.Bet, userID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+	if _, err := tx.Exec(ctx, fmt.Sprintf(`UPDATE users SET %[1]s = %[1]s - $1 WHERE id=$2`, col), req.Bet, userID); err != nil {
+		respondError(c, err)
 		return
 	}
 
 	// Play the game
-	wheelGame := game.NewWheelGame()
 	result := wheelGame.Spin()
 
 	// Calculate winnings
 	winAmount := wheelGame.CalculateWinAmount(req.Bet)
 	if winAmount > 0 {
-		if _, err := tx.Exec(ctx, `UPDATE users SET gems = gems + $1 WHERE id=$2`, winAmount, userID); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		if _, err := tx.Exec(ctx, fmt.Sprintf(`UPDATE users SET %[1]s = %[1]s + $1 WHERE id=$2`, col), winAmount, userID); err != nil {
+			respondError(c, err)
 			return
 		}
 	}
@@ -254,6 +319,10 @@ func (h *Handler) Wheel(c *gin.Context) {
 	meta := wheelGame.ToDetails()
 	meta["bet"] = req.Bet
 	meta["win_amount"] = winAmount
+	meta["currency"] = currency
+	if fair != nil {
+		meta["fair"] = fair
+	}
 	txRecord := &domain.Transaction{
 		UserID: userID,
 		Type:   "wheel",
@@ -261,19 +330,19 @@ func (h *Handler) Wheel(c *gin.Context) {
 		Meta:   meta,
 	}
 	if err := h.TransactionRepo.CreateWithTx(ctx, tx, txRecord); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		respondError(c, err)
 		return
 	}
 
 	// Get new balance
 	var newBalance int64
-	if err := tx.QueryRow(ctx, `SELECT gems FROM users WHERE id=$1`, userID).Scan(&newBalance); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+	if err := tx.QueryRow(ctx, fmt.Sprintf(`SELECT %s FROM users WHERE id=$1`, col), userID).Scan(&newBalance); err != nil {
+		respondError(c, err)
 		return
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		respondError(c, err)
 		return
 	}
 
@@ -284,35 +353,97 @@ func (h *Handler) Wheel(c *gin.Context) {
 	} else {
 		gameResult = domain.GameResultLose
 	}
-	go h.RecordGameResult(userID, domain.GameTypeWheel, domain.GameModePVE, gameResult, req.Bet, netAmount, meta)
+	go h.RecordGameResult(userID, domain.GameTypeWheel, domain.GameModePVE, gameResult, req.Bet, netAmount, currency, meta)
 
-	c.JSON(http.StatusOK, WheelResponse{
+	resp := WheelResponse{
 		SegmentID:  result.ID,
 		Multiplier: result.Multiplier,
 		Color:      result.Color,
 		Label:      result.Label,
 		SpinAngle:  wheelGame.SpinAngle,
 		WinAmount:  winAmount,
-		Gems:       newBalance,
-	})
+		Currency:   currency,
+		Fair:       fair,
+	}
+	if currency == domain.CurrencyCoins {
+		resp.Coins = &newBalance
+	} else {
+		resp.Gems = &newBalance
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 // WheelInfo returns wheel configuration for frontend
 func (h *Handler) WheelInfo(c *gin.Context) {
-	wheelGame := game.NewWheelGame()
+	wheelGame, ok := h.loadWheelGame(c, h.GameService.Randomizer())
+	if !ok {
+		return
+	}
 
+	expected := wheelGame.GetExpectedReturn()
 	c.JSON(http.StatusOK, gin.H{
 		"segments":        wheelGame.Segments,
-		"expected_return": wheelGame.GetExpectedReturn(),
+		"expected_return": expected,
+		"house_edge":      1 - expected,
+		"coins_accepted":  service.CoinsAllowed(expected),
+		"animation":       h.animationFor(domain.GameTypeWheel),
 	})
 }
 
+// errWheelNotConfigured - в wheel_segments битая таблица весов
+var errWheelNotConfigured = errors.New("wheel is not configured")
+
+// loadWheelGame is buildWheelGame that writes the error response on failure
+func (h *Handler) loadWheelGame(c *gin.Context, rng game.Randomizer) (*game.WheelGame, bool) {
+	wheelGame, err := h.buildWheelGame(c.Request.Context(), rng)
+	switch {
+	case errors.Is(err, errWheelNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return nil, false
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return nil, false
+	}
+	return wheelGame, true
+}
+
+// buildWheelGame builds the wheel from active wheel_segments rows, or from the
+// built-in segments when the table is empty, spinning with rng
+func (h *Handler) buildWheelGame(ctx context.Context, rng game.Randomizer) (*game.WheelGame, error) {
+	rows, err := h.WheelConfigRepo.GetActiveSegments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return game.NewWheelGame(rng), nil
+	}
+
+	segments := make([]game.WheelSegment, len(rows))
+	for i, r := range rows {
+		segments[i] = game.WheelSegment{
+			ID:         int(r.ID),
+			Multiplier: r.Multiplier,
+			Color:      r.Color,
+			Label:      r.Label,
+			Weight:     r.Weight,
+		}
+	}
+	segments, err = game.WheelSegmentsFromWeights(segments)
+	if err != nil {
+		// Не подменяем таблицу выплат молча - колесо недоступно, пока конфиг не исправят
+		logger.Error("invalid wheel_segments config", "error", err)
+		return nil, errWheelNotConfigured
+	}
+	return game.NewWheelGameWithSegments(segments, rng), nil
+}
+
 // ============ MINES PRO ============
 
 // MinesProStartRequest represents the start game request
 type MinesProStartRequest struct {
-	Bet        int64 `json:"bet" binding:"required,min=1"`
-	MinesCount int   `json:"mines_count" binding:"required,min=1,max=24"`
+	Bet        int64  `json:"bet" binding:"required,min=1,amount"`
+	MinesCount int    `json:"mines_count" binding:"required,min=1,max=24"`
+	Currency   string `json:"currency"` // gems (по умолчанию) или coins
 }
 
 // MinesProRevealRequest represents the reveal cell request
@@ -330,14 +461,19 @@ func (h *Handler) MinesProStart(c *gin.Context) {
 
 	var req MinesProStartRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		respondBindError(c, err)
+		return
+	}
+
+	currency, ok := h.parsePvEBet(c, domain.GameTypeMinesPro, req.Bet, req.Currency)
+	if !ok {
 		return
 	}
 
 	ctx := c.Request.Context()
-	g, err := h.MinesProService.StartGame(ctx, userID, req.Bet, req.MinesCount)
+	g, err := h.MinesProService.StartGame(ctx, userID, req.Bet, req.MinesCount, currency)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -354,57 +490,32 @@ func (h *Handler) MinesProReveal(c *gin.Context) {
 
 	var req MinesProRevealRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		respondBindError(c, err)
 		return
 	}
 
 	if req.Cell == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "cell is required"})
+		respondError(c, invalidGameRequest("cell is required"))
 		return
 	}
 
 	ctx := c.Request.Context()
 	hitMine, g, err := h.MinesProService.RevealCell(ctx, userID, *req.Cell)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	state := g.GetState()
 	state["hit_mine"] = hitMine
+	currency := domain.Currency(g.Currency)
 
 	// Record game if finished
 	if !g.IsActive() {
-		var result domain.GameResult
-		if g.Status == game.MinesProStatusCashedOut {
-			result = domain.GameResultWin
-		} else {
-			result = domain.GameResultLose
-		}
-		go h.RecordGameResult(userID, domain.GameTypeMinesPro, domain.GameModePVE, result, g.Bet, g.GetProfit(), g.ToDetails())
-
-		// Record transaction
-		meta := g.ToDetails()
-		meta["bet"] = g.Bet
-		meta["win_amount"] = g.WinAmount
-		txRecord := &domain.Transaction{
-			UserID: userID,
-			Type:   "mines_pro",
-			Amount: g.GetProfit(),
-			Meta:   meta,
-		}
-		_ = h.TransactionRepo.Create(ctx, txRecord)
-	}
-
-	// Get current balance
-	user, _ := repository.NewUserRepository(h.DB).GetByID(ctx, userID)
-	var balance int64
-	if user != nil {
-		balance = user.Gems
+		h.recordMinesPro(ctx, g)
 	}
-	state["gems"] = balance
 
-	c.JSON(http.StatusOK, state)
+	c.JSON(http.StatusOK, withBalance(state, currency, h.balanceOf(ctx, userID, currency)))
 }
 
 // MinesProCashOut cashes out the active game
@@ -418,36 +529,14 @@ func (h *Handler) MinesProCashOut(c *gin.Context) {
 	ctx := c.Request.Context()
 	g, err := h.MinesProService.CashOut(ctx, userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	// Record game history
-	go h.RecordGameResult(userID, domain.GameTypeMinesPro, domain.GameModePVE, domain.GameResultWin, g.Bet, g.GetProfit(), g.ToDetails())
-
-	// Record transaction
-	meta := g.ToDetails()
-	meta["bet"] = g.Bet
-	meta["win_amount"] = g.WinAmount
-	txRecord := &domain.Transaction{
-		UserID: userID,
-		Type:   "mines_pro",
-		Amount: g.GetProfit(),
-		Meta:   meta,
-	}
-	_ = h.TransactionRepo.Create(ctx, txRecord)
-
-	// Get current balance
-	user, _ := repository.NewUserRepository(h.DB).GetByID(ctx, userID)
-	var balance int64
-	if user != nil {
-		balance = user.Gems
-	}
-
-	state := g.GetState()
-	state["gems"] = balance
+	currency := domain.Currency(g.Currency)
+	h.recordMinesPro(ctx, g)
 
-	c.JSON(http.StatusOK, state)
+	c.JSON(http.StatusOK, withBalance(g.GetState(), currency, h.balanceOf(ctx, userID, currency)))
 }
 
 // MinesProState returns the current game state
@@ -458,7 +547,7 @@ func (h *Handler) MinesProState(c *gin.Context) {
 		return
 	}
 
-	g := h.MinesProService.GetActiveGame(userID)
+	g := h.MinesProService.GetActiveGame(c.Request.Context(), userID)
 	if g == nil {
 		c.JSON(http.StatusOK, gin.H{"active": false})
 		return
@@ -469,6 +558,31 @@ func (h *Handler) MinesProState(c *gin.Context) {
 	c.JSON(http.StatusOK, state)
 }
 
+// recordMinesPro saves a finished game to game history and transactions.
+// Refunded games (abandoned without a reveal) aren't recorded as played.
+func (h *Handler) recordMinesPro(ctx context.Context, g *game.MinesPvEGame) {
+	var result domain.GameResult
+	switch g.Status {
+	case game.MinesProStatusCashedOut:
+		result = domain.GameResultWin
+	case game.MinesProStatusExploded:
+		result = domain.GameResultLose
+	default:
+		return
+	}
+	go h.RecordGameResult(g.UserID, domain.GameTypeMinesPro, domain.GameModePVE, result, g.Bet, g.GetProfit(), domain.Currency(g.Currency), g.ToDetails())
+
+	meta := g.ToDetails()
+	meta["bet"] = g.Bet
+	meta["win_amount"] = g.WinAmount
+	_ = h.TransactionRepo.Create(ctx, &domain.Transaction{
+		UserID: g.UserID,
+		Type:   "mines_pro",
+		Amount: g.GetProfit(),
+		Meta:   meta,
+	})
+}
+
 // MinesProInfo returns game configuration
 func (h *Handler) MinesProInfo(c *gin.Context) {
 	// Multiplier tables for different mine counts
@@ -482,6 +596,7 @@ func (h *Handler) MinesProInfo(c *gin.Context) {
 		"min_mines":         game.MinesProMinMines,
 		"max_mines":         game.MinesProMaxMines,
 		"multiplier_tables": tables,
+		"animation":         h.animationFor(domain.GameTypeMinesPro),
 	})
 }
 
@@ -489,7 +604,7 @@ func (h *Handler) MinesProInfo(c *gin.Context) {
 
 // CoinFlipProStartRequest represents the start game request
 type CoinFlipProStartRequest struct {
-	Bet int64 `json:"bet" binding:"required,min=1"`
+	Bet int64 `json:"bet" binding:"required,min=1,amount"`
 }
 
 // CoinFlipProStart starts a new CoinFlip Pro game
@@ -502,14 +617,14 @@ func (h *Handler) CoinFlipProStart(c *gin.Context) {
 
 	var req CoinFlipProStartRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		respondBindError(c, err)
 		return
 	}
 
 	ctx := c.Request.Context()
 	g, err := h.CoinFlipProService.StartGame(ctx, userID, req.Bet)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -527,7 +642,7 @@ func (h *Handler) CoinFlipProFlip(c *gin.Context) {
 	ctx := c.Request.Context()
 	win, g, err := h.CoinFlipProService.Flip(ctx, userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -547,7 +662,7 @@ func (h *Handler) CoinFlipProFlip(c *gin.Context) {
 			"multiplier":   g.Multiplier,
 			"flip_history": g.FlipHistory,
 		}
-		go h.RecordGameResult(userID, domain.GameTypeCoinflip, domain.GameModePVE, result, g.Bet, g.GetProfit(), details)
+		go h.RecordGameResult(userID, domain.GameTypeCoinflip, domain.GameModePVE, result, g.Bet, g.GetProfit(), domain.CurrencyGems, details)
 
 		// Record transaction
 		meta := details
@@ -584,7 +699,7 @@ func (h *Handler) CoinFlipProCashOut(c *gin.Context) {
 	ctx := c.Request.Context()
 	g, err := h.CoinFlipProService.CashOut(ctx, userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -594,7 +709,7 @@ func (h *Handler) CoinFlipProCashOut(c *gin.Context) {
 		"multiplier":   g.Multiplier,
 		"flip_history": g.FlipHistory,
 	}
-	go h.RecordGameResult(userID, domain.GameTypeCoinflip, domain.GameModePVE, domain.GameResultWin, g.Bet, g.GetProfit(), details)
+	go h.RecordGameResult(userID, domain.GameTypeCoinflip, domain.GameModePVE, domain.GameResultWin, g.Bet, g.GetProfit(), domain.CurrencyGems, details)
 
 	// Record transaction
 	meta := details
@@ -640,11 +755,191 @@ func (h *Handler) CoinFlipProState(c *gin.Context) {
 	c.JSON(http.StatusOK, state)
 }
 
-// CoinFlipProInfo returns game configuration
+// CoinFlipProInfo returns game configuration: multiplier per won round, win chance of
+// a flip and cashout rules. With a token it also returns the caller's active game.
 func (h *Handler) CoinFlipProInfo(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"max_rounds":  game.CoinFlipProMaxRounds,
-		"multipliers": game.GetCoinFlipProMultiplierTable(),
-	})
+	resp := gin.H{
+		"max_rounds":        game.CoinFlipProMaxRounds,
+		"win_chance":        game.CoinFlipProWinChance,
+		"multipliers":       game.GetCoinFlipProMultiplierTable(),
+		"min_cashout_round": game.CoinFlipProMinCashoutRound,
+		"auto_cashout":      true, // на последнем раунде выигрыш забирается сам
+		"animation":         h.animationFor(domain.GameTypeCoinflip),
+	}
+
+	if userID, ok := getUserID(c); ok {
+		if g := h.CoinFlipProService.GetActiveGame(userID); g != nil {
+			resp["active_game"] = g.GetState()
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// ============ ACTIVE GAMES ============
+
+// activeGameLookup returns the state of the user's in-progress game, nil if none
+type activeGameLookup struct {
+	key   string // as in /game/<key>/state
+	state func(ctx context.Context, userID int64) map[string]interface{}
+}
+
+// activeGameLookups - one entry per pro game type; a new pro game only needs
+// an entry here to show up in /me/active-games
+func (h *Handler) activeGameLookups() []activeGameLookup {
+	return []activeGameLookup{
+		{"mines-pro", func(ctx context.Context, userID int64) map[string]interface{} {
+			if g := h.MinesProService.GetActiveGame(ctx, userID); g != nil {
+				return g.GetState()
+			}
+			return nil
+		}},
+		{"coinflip-pro", func(ctx context.Context, userID int64) map[string]interface{} {
+			if g := h.CoinFlipProService.GetActiveGame(userID); g != nil {
+				return g.GetState()
+			}
+			return nil
+		}},
+		{"rps-pro", func(ctx context.Context, userID int64) map[string]interface{} {
+			if g := h.RPSProService.GetActiveGame(userID); g != nil {
+				return g.GetState()
+			}
+			return nil
+		}},
+	}
+}
+
+// MyActiveGames returns all in-progress pro games so the client can resume the right screen
+func (h *Handler) MyActiveGames(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	games := []gin.H{}
+	for _, l := range h.activeGameLookups() {
+		if state := l.state(c.Request.Context(), userID); state != nil {
+			games = append(games, gin.H{"game": l.key, "state": state})
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"games": games})
+}
+
+// ============ RPS PRO ============
+
+// RPSProStartRequest represents the start match request
+type RPSProStartRequest struct {
+	Bet      int64  `json:"bet" binding:"required,min=1,amount"`
+	BestOf   int    `json:"best_of" binding:"required,oneof=3 5"`
+	Currency string `json:"currency"` // gems (по умолчанию) или coins
+}
+
+// RPSProMoveRequest represents a round move
+type RPSProMoveRequest struct {
+	Move string `json:"move" binding:"required,oneof=rock paper scissors"`
+}
+
+// RPSProStart locks the bet and starts a best-of-N match against the bot
+func (h *Handler) RPSProStart(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req RPSProStartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	currency, ok := h.parsePvEBet(c, domain.GameTypeRPSPro, req.Bet, req.Currency)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	g, err := h.RPSProService.StartGame(ctx, userID, req.Bet, req.BestOf, currency)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, withBalance(g.GetState(), currency, h.balanceOf(ctx, userID, currency)))
+}
+
+// RPSProMove plays one round in the active match
+func (h *Handler) RPSProMove(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req RPSProMoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	round, g, finished, err := h.RPSProService.Move(ctx, userID, req.Move)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	state := g.GetState()
+	state["round"] = round
+	currency := domain.Currency(g.Currency)
+
+	if finished {
+		h.recordRPSPro(ctx, g)
+	}
+
+	c.JSON(http.StatusOK, withBalance(state, currency, h.balanceOf(ctx, userID, currency)))
 }
 
+// RPSProState returns the active match, or payout settings if there is none
+func (h *Handler) RPSProState(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	g := h.RPSProService.GetActiveGame(userID)
+	if g == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"active":     false,
+			"best_of":    game.RPSProBestOf,
+			"multiplier": h.RPSProService.Multiplier(),
+			"animation":  h.animationFor(domain.GameTypeRPSPro),
+		})
+		return
+	}
+
+	state := g.GetState()
+	state["active"] = true
+	c.JSON(http.StatusOK, state)
+}
+
+// recordRPSPro saves a finished match to game history and transactions
+func (h *Handler) recordRPSPro(ctx context.Context, g *game.RPSProGame) {
+	result := domain.GameResultLose
+	if g.Status == game.RPSProStatusWon {
+		result = domain.GameResultWin
+	}
+	go h.RecordGameResult(g.UserID, domain.GameTypeRPSPro, domain.GameModePVE, result, g.Bet, g.GetProfit(), domain.Currency(g.Currency), g.ToDetails())
+
+	meta := g.ToDetails()
+	meta["bet"] = g.Bet
+	meta["win_amount"] = g.WinAmount
+	_ = h.TransactionRepo.Create(ctx, &domain.Transaction{
+		UserID: g.UserID,
+		Type:   "rps_pro",
+		Amount: g.GetProfit(),
+		Meta:   meta,
+	})
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"telegram_webapp/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestProGamesWithoutActiveGameUseErrorEnvelope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &Handler{
+		CoinFlipProService: service.NewCoinFlipProService(nil),
+		RPSProService:      service.NewRPSProService(nil),
+	}
+
+	cases := []struct {
+		name    string
+		body    string
+		handler gin.HandlerFunc
+	}{
+		{"coinflip pro flip", "", h.CoinFlipProFlip},
+		{"rps pro move", `{"move":"rock"}`, h.RPSProMove},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Set("user_id", int64(1))
+		tc.handler(c)
+
+		var resp struct {
+			Error string `json:"error"`
+			Code  string `json:"code"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: parse response: %v", tc.name, err)
+		}
+		if w.Code != http.StatusConflict || resp.Code != "GAME_NOT_ACTIVE" {
+			t.Fatalf("%s: expected 409 GAME_NOT_ACTIVE, got %d %+v", tc.name, w.Code, resp)
+		}
+	}
+}
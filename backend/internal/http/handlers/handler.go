@@ -1,16 +1,64 @@
 package handlers
 
 import (
+	"context"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/game"
 	"telegram_webapp/internal/repository"
 	"telegram_webapp/internal/service"
+	"telegram_webapp/internal/ton"
 
+	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // HandlerConfig holds configuration for handler
 type HandlerConfig struct {
-	MinBet int64
-	MaxBet int64
+	MinBet         int64
+	MaxBet         int64
+	MinBetCoins    int64
+	MaxBetCoins    int64
+	FairRNGEnabled bool
+
+	MinesCount     int     // мин в простой Mines (из 12 клеток)
+	MinesHouseEdge float64 // доля, 0.05 = 5%
+
+	MinesProRevealInterval time.Duration // 0 - без ограничения
+	MinesProAbandonTTL     time.Duration // 0 - по умолчанию (1 час)
+
+	RPSProMultiplier float64 // выплата за выигранный матч, 0 - по умолчанию
+
+	Payouts service.Payouts // множители CoinFlip/RPS, нулевое значение - по умолчанию
+
+	Animations map[string]domain.Animation // переопределения по типу игры
+
+	LuckProtection service.LuckProtectionConfig
+
+	ReferralHoldMode  string
+	ReferralHoldGames int
+
+	ReferralCommission domain.ReferralCommission
+
+	ComebackInactiveDays int
+	ComebackBonusGems    int64
+
+	LowBalanceBonus domain.BonusConfig // нулевое значение - domain.DefaultBonusConfig()
+
+	BonusWagerMultiplier int // отыгрыш coins из наград квестов, 0 - без отыгрыша
+
+	HappyHours      []game.HappyHour
+	HappyHourMaxRTP float64 // 0 - game.DefaultHappyHourMaxRTP
+
+	TransferLimits service.TransferLimits // нулевое значение - service.DefaultTransferLimits()
+
+	CoinsPerTON int             // 0 - ton.CoinsPerTON
+	WithdrawFee ton.WithdrawFee // пустой Mode - ton.DefaultWithdrawFee()
+
+	SignupGuard service.SignupGuardConfig
+
+	GamesExportMaxRows int // 0 - DefaultGamesExportMaxRows
 }
 
 type Handler struct {
@@ -18,43 +66,185 @@ type Handler struct {
 	BotToken           string
 	GameHistoryRepo    *repository.GameHistoryRepository
 	QuestRepo          *repository.QuestRepository
+	Quests             *service.QuestService
 	TransactionRepo    *repository.TransactionRepository
 	UserRepo           *repository.UserRepository
 	MinesProService    *service.MinesProService
 	CoinFlipProService *service.CoinFlipProService
+	RPSProService      *service.RPSProService
 	GameService        *service.GameService
 	AuditService       *service.AuditService
+	NotificationRepo   *repository.NotificationRepository
+	FairRNG            *service.FairRNG
+	BonusRepo          *repository.BonusWageringRepository
+	ReferralRewards    *service.ReferralRewardService
+	SupportRepo        *repository.SupportRepository
+	Animations         map[domain.GameType]domain.Animation
+	ComebackBonus      *service.ComebackBonusService
+	Notifier           service.Notifier
+	ReferralCommission domain.ReferralCommission
+	WheelConfigRepo    *repository.WheelConfigRepository
+	Cases              *service.CaseService
+	Balance            *service.BalanceService
+	TransferLimits     service.TransferLimits
+	CoinsPerTON        int
+	WithdrawFee        ton.WithdrawFee
+	SignupGuard        *service.SignupGuard
+	GamesExportMaxRows int
 }
 
 func NewHandler(db *pgxpool.Pool, botToken string) *Handler {
-	return &Handler{
+	h := &Handler{
 		DB:                 db,
 		BotToken:           botToken,
 		GameHistoryRepo:    repository.NewGameHistoryRepository(db),
 		QuestRepo:          repository.NewQuestRepository(db),
+		Quests:             service.NewQuestService(db),
 		TransactionRepo:    repository.NewTransactionRepository(db),
 		UserRepo:           repository.NewUserRepository(db),
 		MinesProService:    service.NewMinesProService(db),
 		CoinFlipProService: service.NewCoinFlipProService(db),
+		RPSProService:      service.NewRPSProService(db),
 		GameService:        service.NewGameService(db),
 		AuditService:       service.NewAuditService(db),
+		NotificationRepo:   repository.NewNotificationRepository(db),
+		FairRNG:            service.NewFairRNG(false),
+		BonusRepo:          repository.NewBonusWageringRepository(db),
+		ReferralRewards:    service.NewReferralRewardService(db, service.ReferralHoldNone, 0),
+		SupportRepo:        repository.NewSupportRepository(db),
+		Animations:         domain.DefaultAnimations(),
+		ComebackBonus:      service.NewComebackBonusService(db, service.DefaultComebackInactivity, service.DefaultComebackBonusGems),
+		Notifier:           service.NopNotifier{},
+		ReferralCommission: domain.DefaultReferralCommission(),
+		WheelConfigRepo:    repository.NewWheelConfigRepository(db),
+		Cases:              service.NewCaseService(db),
+		Balance:            service.NewBalanceService(db),
+		TransferLimits:     service.DefaultTransferLimits(),
+		CoinsPerTON:        ton.CoinsPerTON,
+		WithdrawFee:        ton.DefaultWithdrawFee(),
+		SignupGuard:        service.NewSignupGuard(db, service.SignupGuardConfig{}),
+		GamesExportMaxRows: DefaultGamesExportMaxRows,
 	}
+	h.RPSProService.SetOnAbandon(func(g *game.RPSProGame) { h.recordRPSPro(context.Background(), g) })
+	h.MinesProService.SetOnAbandon(func(g *game.MinesPvEGame) { h.recordMinesPro(context.Background(), g) })
+	return h
 }
 
 // NewHandlerWithConfig creates a handler with custom configuration
 func NewHandlerWithConfig(db *pgxpool.Pool, botToken string, cfg HandlerConfig) *Handler {
-	return &Handler{
+	limits := service.GameLimits{
+		MinBet:      cfg.MinBet,
+		MaxBet:      cfg.MaxBet,
+		MinBetCoins: cfg.MinBetCoins,
+		MaxBetCoins: cfg.MaxBetCoins,
+	}
+	gameService := service.NewGameServiceWithLimits(db, limits)
+	mines := service.DefaultMinesConfig()
+	if cfg.MinesCount > 0 {
+		mines.Mines = cfg.MinesCount
+	}
+	if cfg.MinesHouseEdge > 0 {
+		mines.HouseEdge = cfg.MinesHouseEdge
+	}
+	gameService.SetMinesConfig(mines)
+	gameService.SetPayouts(cfg.Payouts)
+	gameService.SetLuckProtection(cfg.LuckProtection)
+	if len(cfg.HappyHours) > 0 {
+		gameService.SetHappyHours(service.NewHappyHours(cfg.HappyHours, cfg.HappyHourMaxRTP))
+	}
+
+	// Один fair RNG на все игры: nonce общий для сида пользователя
+	fair := service.NewFairRNG(cfg.FairRNGEnabled)
+	gameService.SetFairRNG(fair)
+
+	minesPro := service.NewMinesProService(db)
+	minesPro.SetMinRevealInterval(cfg.MinesProRevealInterval)
+	minesPro.SetAbandonTTL(cfg.MinesProAbandonTTL)
+	minesPro.SetFairRNG(fair)
+	coinFlipPro := service.NewCoinFlipProService(db)
+	coinFlipPro.SetFairRNG(fair)
+
+	rpsPro := service.NewRPSProService(db)
+	if cfg.RPSProMultiplier > 0 {
+		rpsPro.SetMultiplier(cfg.RPSProMultiplier)
+	}
+
+	animations := domain.DefaultAnimations()
+	for gt, a := range cfg.Animations {
+		base := animations[domain.GameType(gt)]
+		base.DurationMs = a.DurationMs
+		if a.Easing != "" {
+			base.Easing = a.Easing
+		}
+		animations[domain.GameType(gt)] = base
+	}
+
+	transferLimits := cfg.TransferLimits
+	if transferLimits == (service.TransferLimits{}) {
+		transferLimits = service.DefaultTransferLimits()
+	}
+
+	exportMaxRows := cfg.GamesExportMaxRows
+	if exportMaxRows <= 0 {
+		exportMaxRows = DefaultGamesExportMaxRows
+	}
+	coinsPerTON := cfg.CoinsPerTON
+	if coinsPerTON <= 0 {
+		coinsPerTON = ton.CoinsPerTON
+	}
+	balance := service.NewBalanceService(db)
+	if cfg.LowBalanceBonus != (domain.BonusConfig{}) {
+		balance.SetBonusConfig(cfg.LowBalanceBonus)
+	}
+	withdrawFee := cfg.WithdrawFee
+	if withdrawFee.Mode == "" {
+		withdrawFee = ton.DefaultWithdrawFee()
+	}
+
+	h := &Handler{
 		DB:                 db,
 		BotToken:           botToken,
 		GameHistoryRepo:    repository.NewGameHistoryRepository(db),
 		QuestRepo:          repository.NewQuestRepository(db),
+		Quests:             service.NewQuestService(db),
 		TransactionRepo:    repository.NewTransactionRepository(db),
 		UserRepo:           repository.NewUserRepository(db),
-		MinesProService:    service.NewMinesProService(db),
-		CoinFlipProService: service.NewCoinFlipProService(db),
-		GameService:        service.NewGameServiceWithLimits(db, cfg.MinBet, cfg.MaxBet),
+		MinesProService:    minesPro,
+		CoinFlipProService: coinFlipPro,
+		RPSProService:      rpsPro,
+		GameService:        gameService,
 		AuditService:       service.NewAuditService(db),
+		NotificationRepo:   repository.NewNotificationRepository(db),
+		FairRNG:            fair,
+		BonusRepo:          repository.NewBonusWageringRepository(db),
+		ReferralRewards:    service.NewReferralRewardService(db, cfg.ReferralHoldMode, cfg.ReferralHoldGames),
+		SupportRepo:        repository.NewSupportRepository(db),
+		Animations:         animations,
+		ComebackBonus:      service.NewComebackBonusService(db, time.Duration(cfg.ComebackInactiveDays)*24*time.Hour, cfg.ComebackBonusGems),
+		Notifier:           service.NopNotifier{},
+		ReferralCommission: cfg.ReferralCommission,
+		WheelConfigRepo:    repository.NewWheelConfigRepository(db),
+		Cases:              service.NewCaseService(db),
+		Balance:            balance,
+		TransferLimits:     transferLimits,
+		CoinsPerTON:        coinsPerTON,
+		WithdrawFee:        withdrawFee,
+		SignupGuard:        service.NewSignupGuard(db, cfg.SignupGuard),
+		GamesExportMaxRows: exportMaxRows,
+	}
+	h.Quests.SetBonusWagerMultiplier(cfg.BonusWagerMultiplier)
+	// Брошенный матч засчитывается как поражение и попадает в историю
+	h.RPSProService.SetOnAbandon(func(g *game.RPSProGame) { h.recordRPSPro(context.Background(), g) })
+	h.MinesProService.SetOnAbandon(func(g *game.MinesPvEGame) { h.recordMinesPro(context.Background(), g) })
+	return h
+}
+
+// animationFor returns reveal timing for the game's /info response
+func (h *Handler) animationFor(gt domain.GameType) domain.Animation {
+	if a, ok := h.Animations[gt]; ok {
+		return a
 	}
+	return domain.Animation{DurationMs: 1000, Easing: "ease-out"}
 }
 
 // getUserID извлекает user_id из контекста Gin
@@ -72,3 +262,30 @@ func getUserID(c interface{ Get(string) (any, bool) }) (int64, bool) {
 		return 0, false
 	}
 }
+
+// withFair copies the provably fair proof of the game from meta into the response
+// (present only when fair RNG is enabled)
+func withFair(resp gin.H, meta map[string]interface{}) gin.H {
+	if proof, ok := meta["fair"]; ok {
+		resp["fair"] = proof
+	}
+	return resp
+}
+
+// withBalance adds the post-game balance under the currency name ("gems" or "coins")
+func withBalance(resp gin.H, currency domain.Currency, balance int64) gin.H {
+	resp[string(currency)] = balance
+	resp["currency"] = currency
+	return resp
+}
+
+// balanceOf returns the user's balance in currency, 0 if it can't be read
+func (h *Handler) balanceOf(ctx context.Context, userID int64, currency domain.Currency) int64 {
+	var balance int64
+	if currency == domain.CurrencyCoins {
+		balance, _ = h.UserRepo.GetCoins(ctx, userID)
+	} else {
+		balance, _ = h.UserRepo.GetGems(ctx, userID)
+	}
+	return balance
+}
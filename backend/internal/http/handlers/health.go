@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"net/http"
 	"runtime"
+	"strconv"
 	"time"
 
+	"telegram_webapp/internal/ws"
+
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -16,8 +19,16 @@ type HealthHandler struct {
 	db        *pgxpool.Pool
 	startTime time.Time
 	version   string
+
+	hub        *ws.Hub
+	maxWSRooms int // порог комнат, выше которого хаб считается degraded
+
+	pingRedis func(ctx context.Context) error // nil - Redis не используется
 }
 
+// readinessTimeout - на каждую зависимость, чтобы зависший пинг не держал пробу
+const readinessTimeout = 2 * time.Second
+
 // NewHealthHandler creates a new health handler
 func NewHealthHandler(db *pgxpool.Pool, version string) *HealthHandler {
 	return &HealthHandler{
@@ -27,6 +38,17 @@ func NewHealthHandler(db *pgxpool.Pool, version string) *HealthHandler {
 	}
 }
 
+// SetHub attaches the WebSocket hub to readiness checks
+func (h *HealthHandler) SetHub(hub *ws.Hub, maxRooms int) {
+	h.hub = hub
+	h.maxWSRooms = maxRooms
+}
+
+// SetRedis adds a Redis ping to readiness checks
+func (h *HealthHandler) SetRedis(ping func(ctx context.Context) error) {
+	h.pingRedis = ping
+}
+
 // HealthResponse represents health check response
 type HealthResponse struct {
 	Status    string            `json:"status"`
@@ -34,6 +56,7 @@ type HealthResponse struct {
 	Uptime    string            `json:"uptime,omitempty"`
 	Timestamp string            `json:"timestamp"`
 	Checks    map[string]string `json:"checks,omitempty"`
+	Failed    []string          `json:"failed,omitempty"` // зависимости, из-за которых 503
 }
 
 // Liveness returns simple alive status (for k8s liveness probe)
@@ -41,20 +64,29 @@ func (h *HealthHandler) Liveness(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
-// Readiness returns detailed health status (for k8s readiness probe)
+// Readiness returns detailed health status (for k8s readiness probe): 503 if the
+// database (SELECT 1) or, when the rate limiter uses it, Redis (PING) doesn't answer
 func (h *HealthHandler) Readiness(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
-	defer cancel()
-
 	checks := make(map[string]string)
-	allHealthy := true
+	var failed []string
+
+	check := func(name string, fn func(ctx context.Context) error) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+		defer cancel()
+		if err := fn(ctx); err != nil {
+			checks[name] = "unhealthy: " + err.Error()
+			failed = append(failed, name)
+			return
+		}
+		checks[name] = "healthy"
+	}
 
-	// Database check
-	if err := h.db.Ping(ctx); err != nil {
-		checks["database"] = "unhealthy: " + err.Error()
-		allHealthy = false
-	} else {
-		checks["database"] = "healthy"
+	check("database", func(ctx context.Context) error {
+		var one int
+		return h.db.QueryRow(ctx, "SELECT 1").Scan(&one)
+	})
+	if h.pingRedis != nil {
+		check("redis", h.pingRedis)
 	}
 
 	// Memory check
@@ -62,11 +94,37 @@ func (h *HealthHandler) Readiness(c *gin.Context) {
 	runtime.ReadMemStats(&m)
 	checks["memory_alloc_mb"] = formatMB(m.Alloc)
 
+	// WebSocket hub check (degraded doesn't fail readiness)
+	degraded := false
+	if h.hub != nil {
+		stats := h.hub.Stats()
+		checks["ws_rooms"] = strconv.Itoa(stats.Rooms)
+		checks["ws_waiting"] = strconv.Itoa(stats.Waiting)
+		if stats.CleanupRunning {
+			checks["ws_cleanup"] = "running"
+		} else {
+			checks["ws_cleanup"] = "stopped"
+		}
+
+		switch {
+		case h.maxWSRooms > 0 && stats.Rooms > h.maxWSRooms:
+			checks["ws_hub"] = fmt.Sprintf("degraded: %d rooms exceeds limit %d", stats.Rooms, h.maxWSRooms)
+			degraded = true
+		case !stats.CleanupRunning:
+			checks["ws_hub"] = "degraded: cleanup not running"
+			degraded = true
+		default:
+			checks["ws_hub"] = "healthy"
+		}
+	}
+
 	status := "healthy"
 	statusCode := http.StatusOK
-	if !allHealthy {
+	if len(failed) > 0 {
 		status = "unhealthy"
 		statusCode = http.StatusServiceUnavailable
+	} else if degraded {
+		status = "degraded"
 	}
 
 	c.JSON(statusCode, HealthResponse{
@@ -75,6 +133,7 @@ func (h *HealthHandler) Readiness(c *gin.Context) {
 		Uptime:    time.Since(h.startTime).Round(time.Second).String(),
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
 		Checks:    checks,
+		Failed:    failed,
 	})
 }
 
@@ -6,7 +6,8 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// GetLeaderboard returns the monthly top 100 users
+// GetLeaderboard returns the monthly top 100 users; users with leaderboard_visible=false
+// keep their rank but are shown as "Anonymous Player"
 func (h *Handler) GetLeaderboard(c *gin.Context) {
 	top, err := h.UserRepo.GetMonthlyTop(c.Request.Context(), 100)
 	if err != nil {
@@ -43,3 +44,47 @@ func (h *Handler) GetMyRank(c *gin.Context) {
 		"wins_count": winsCount,
 	})
 }
+
+// GetLeaderboardPrivacy returns whether the current user is shown by name in leaderboards
+func (h *Handler) GetLeaderboardPrivacy(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	visible, err := h.UserRepo.GetLeaderboardVisible(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get privacy settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"leaderboard_visible": visible})
+}
+
+// UpdateLeaderboardPrivacyRequest toggles the name on public leaderboards
+type UpdateLeaderboardPrivacyRequest struct {
+	LeaderboardVisible *bool `json:"leaderboard_visible" binding:"required"`
+}
+
+// UpdateLeaderboardPrivacy shows or hides the current user's name in leaderboards
+func (h *Handler) UpdateLeaderboardPrivacy(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req UpdateLeaderboardPrivacyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if err := h.UserRepo.SetLeaderboardVisible(c.Request.Context(), userID, *req.LeaderboardVisible); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update privacy settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"leaderboard_visible": *req.LeaderboardVisible})
+}
@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"net/http"
+	"time"
 
+	"telegram_webapp/internal/domain"
 	"telegram_webapp/internal/repository"
+	"telegram_webapp/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -34,13 +37,63 @@ func (h *Handler) Me(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"id":         user.ID,
-		"tg_id":      user.TgID,
-		"username":   user.Username,
-		"first_name": user.FirstName,
-		"created_at": user.CreatedAt,
-		"gems":       user.Gems,
-		"coins":      user.Coins,
-	})
+	// Неотыгранные бонусные coins (нельзя вывести)
+	wagering, err := h.BonusRepo.GetStatus(ctx, userID, domain.CurrencyCoins)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get bonus wagering"})
+		return
+	}
+
+	resp := gin.H{
+		"id":             user.ID,
+		"tg_id":          user.TgID,
+		"username":       user.Username,
+		"first_name":     user.FirstName,
+		"created_at":     user.CreatedAt,
+		"gems":           user.Gems,
+		"coins":          user.Coins,
+		"bonus_wagering": wagering,
+	}
+
+	// Активное самоисключение: игры и вывод недоступны до этого времени
+	excludedUntil, err := repo.ExcludedUntil(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get self-exclusion"})
+		return
+	}
+	if excludedUntil != nil {
+		resp["excluded_until"] = excludedUntil
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// SelfExclude locks the caller out of games and withdrawals for the requested
+// period ("24h", "7d", "30d", ...). A repeated request can only extend an active
+// exclusion; there is no way for the user to lift it early.
+func (h *Handler) SelfExclude(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req struct {
+		Duration string `json:"duration"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	d, err := service.ParseExclusionDuration(req.Duration)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	until, err := h.UserRepo.SelfExclude(c.Request.Context(), userID, time.Now().Add(d))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to set self-exclusion"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"excluded_until": until})
 }
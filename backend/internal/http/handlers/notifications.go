@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetNotificationPrefs возвращает настройки уведомлений пользователя
+func (h *Handler) GetNotificationPrefs(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	prefs, err := h.NotificationRepo.GetPrefs(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get notification settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"notifications": prefs})
+}
+
+// UpdateNotificationPrefsRequest - частичное обновление, отсутствующие поля не меняются
+type UpdateNotificationPrefsRequest struct {
+	BigWin         *bool `json:"big_win"`
+	Withdrawal     *bool `json:"withdrawal"`
+	Quest          *bool `json:"quest"`
+	Promo          *bool `json:"promo"`
+	StreakReminder *bool `json:"streak_reminder"`
+}
+
+// UpdateNotificationPrefs обновляет настройки уведомлений пользователя
+func (h *Handler) UpdateNotificationPrefs(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req UpdateNotificationPrefsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	prefs, err := h.NotificationRepo.GetPrefs(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get notification settings"})
+		return
+	}
+
+	if req.BigWin != nil {
+		prefs.BigWin = *req.BigWin
+	}
+	if req.Withdrawal != nil {
+		prefs.Withdrawal = *req.Withdrawal
+	}
+	if req.Quest != nil {
+		prefs.Quest = *req.Quest
+	}
+	if req.Promo != nil {
+		prefs.Promo = *req.Promo
+	}
+	if req.StreakReminder != nil {
+		prefs.StreakReminder = *req.StreakReminder
+	}
+
+	if err := h.NotificationRepo.SavePrefs(ctx, prefs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save notification settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"notifications": prefs})
+}
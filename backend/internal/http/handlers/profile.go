@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"errors"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
 
 	"telegram_webapp/internal/domain"
 	"telegram_webapp/internal/repository"
+	"telegram_webapp/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -29,7 +32,7 @@ func (h *Handler) Profile(c *gin.Context) {
 
 	// Get user stats for the last month
 	since := time.Now().AddDate(0, -1, 0)
-	stats, _ := h.GameHistoryRepo.GetUserStats(ctx, id, since)
+	stats, _ := h.GameHistoryRepo.GetUserStats(ctx, id, since, "")
 
 	c.JSON(http.StatusOK, gin.H{
 		"id":         user.ID,
@@ -50,10 +53,17 @@ func (h *Handler) MyGames(c *gin.Context) {
 		return
 	}
 
+	// ?mode=pvp|pve - только игры против игроков или против казино
+	mode := domain.GameMode(c.Query("mode"))
+	if mode != "" && !mode.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be pvp or pve"})
+		return
+	}
+
 	ctx := c.Request.Context()
 
 	// Get game history
-	games, err := h.GameHistoryRepo.GetByUser(ctx, userID, 100)
+	games, err := h.GameHistoryRepo.GetByUser(ctx, userID, mode, 100)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get games"})
 		return
@@ -61,11 +71,148 @@ func (h *Handler) MyGames(c *gin.Context) {
 
 	// Get stats for the last month
 	since := time.Now().AddDate(0, -1, 0)
-	stats, _ := h.GameHistoryRepo.GetUserStats(ctx, userID, since)
+	stats, _ := h.GameHistoryRepo.GetUserStats(ctx, userID, since, "")
 
 	c.JSON(http.StatusOK, gin.H{"games": games, "stats": stats})
 }
 
+// pnlSince returns the start of a P&L period (rolling window); zero time means all time
+func pnlSince(period string, now time.Time) (time.Time, bool) {
+	switch period {
+	case "", "all":
+		return time.Time{}, true
+	case "day":
+		return now.Add(-24 * time.Hour), true
+	case "week":
+		return now.AddDate(0, 0, -7), true
+	case "month":
+		return now.AddDate(0, -1, 0), true
+	}
+	return time.Time{}, false
+}
+
+// MyPnL returns the caller's net game profit per currency for a period.
+// Only game results count towards net_profit; TON deposits and withdrawals are
+// reported separately as coin cash flow, bonuses and rewards are not included.
+func (h *Handler) MyPnL(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	period := c.DefaultQuery("period", "all")
+	since, ok := pnlSince(period, time.Now())
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "period must be day, week, month or all"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	games, err := h.GameHistoryRepo.GetNetProfit(ctx, userID, since, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	fillCurrencies(games)
+
+	deposited, err := h.TransactionRepo.SumByTypes(ctx, userID, []string{"ton_deposit"}, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	// Удержание при заявке минус возврат при отклонении = реально выведено (включая ожидающие)
+	withdrawn, err := h.TransactionRepo.SumByTypes(ctx, userID, []string{"withdraw_hold", "withdraw_refund"}, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+
+	resp := gin.H{
+		"period": period,
+		"games":  games,
+		"coins_flow": gin.H{
+			"deposited": deposited,
+			"withdrawn": -withdrawn,
+		},
+	}
+	if !since.IsZero() {
+		resp["since"] = since
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// fillCurrencies adds zero rows for currencies the user hasn't played in
+func fillCurrencies(games map[domain.Currency]*repository.CurrencyPnL) {
+	for _, cur := range []domain.Currency{domain.CurrencyGems, domain.CurrencyCoins} {
+		if games[cur] == nil {
+			games[cur] = &repository.CurrencyPnL{}
+		}
+	}
+}
+
+// MyStats returns the caller's game statistics for a period, optionally for one
+// game, with the win rate (wins among all games, %) and the net profit per
+// currency: gems and coins are never added together
+func (h *Handler) MyStats(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	period := c.DefaultQuery("period", "all")
+	since, ok := pnlSince(period, time.Now())
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "period must be day, week, month or all"})
+		return
+	}
+
+	gameType := domain.GameType(c.Query("game_type"))
+	if gameType != "" && !gameType.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown game_type"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	stats, err := h.GameHistoryRepo.GetUserStats(ctx, userID, since, gameType)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	byCurrency, err := h.GameHistoryRepo.GetNetProfit(ctx, userID, since, gameType)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	fillCurrencies(byCurrency)
+	netProfit := make(map[domain.Currency]int64, len(byCurrency))
+	for cur, p := range byCurrency {
+		netProfit[cur] = p.NetProfit
+	}
+
+	var winRate float64
+	if stats.TotalGames > 0 {
+		winRate = math.Round(float64(stats.Wins)*10000/float64(stats.TotalGames)) / 100
+	}
+
+	resp := gin.H{
+		"period":     period,
+		"stats":      stats,
+		"win_rate":   winRate,
+		"net_profit": netProfit,
+	}
+	if gameType != "" {
+		resp["game_type"] = gameType
+	}
+	if !since.IsZero() {
+		resp["since"] = since
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
 func (h *Handler) TopUsers(c *gin.Context) {
 	ctx := c.Request.Context()
 
@@ -101,7 +248,7 @@ func (h *Handler) CreateTask(c *gin.Context) {
 		Title       string `json:"title"`
 		Description string `json:"description"`
 	}
-	if err := c.BindJSON(&req); err != nil {
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
 		return
 	}
@@ -114,7 +261,8 @@ func (h *Handler) CreateTask(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"task": task})
 }
 
-// ClaimBonus gives 10000 gems to users with 0 balance (one-time)
+// ClaimBonus gives bonus gems to users whose balance is below the threshold, at
+// most once per cooldown (see domain.BonusConfig)
 func (h *Handler) ClaimBonus(c *gin.Context) {
 	userID, ok := getUserID(c)
 	if !ok {
@@ -122,14 +270,36 @@ func (h *Handler) ClaimBonus(c *gin.Context) {
 		return
 	}
 
-	ctx := c.Request.Context()
-	_, err := h.DB.Exec(ctx, `UPDATE users SET gems = gems + 10000 WHERE id = $1 AND gems < 100`, userID)
+	bonus := h.Balance.BonusConfig()
+	gems, err := h.Balance.ClaimBonus(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to claim bonus"})
+		var cooldown *service.ErrBonusOnCooldown
+		switch {
+		case errors.As(err, &cooldown):
+			secs := int64(math.Ceil(cooldown.Remaining.Seconds()))
+			c.Header("Retry-After", strconv.FormatInt(secs, 10))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":               "bonus_cooldown",
+				"retry_after_seconds": secs,
+				"next_bonus_at":       time.Now().Add(cooldown.Remaining).UTC(),
+			})
+		case errors.Is(err, service.ErrBonusBalanceTooHigh):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "balance_too_high", "threshold": bonus.Threshold})
+		case errors.Is(err, service.ErrBonusFlaggedSignup):
+			c.JSON(http.StatusForbidden, gin.H{"error": "bonus_unavailable"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to claim bonus"})
+		}
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"ok": true, "message": "Bonus claimed!"})
+	c.JSON(http.StatusOK, gin.H{
+		"ok":               true,
+		"message":          "Bonus claimed!",
+		"amount":           bonus.Amount,
+		"gems":             gems,
+		"cooldown_seconds": int64(bonus.Cooldown.Seconds()),
+	})
 }
 
 func (h *Handler) CompleteTask(c *gin.Context) {
@@ -1,14 +1,17 @@
 package handlers
 
 import (
-	"context"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/game"
+	"telegram_webapp/internal/service"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
 )
 
 // GetQuests возвращает все активные квесты
@@ -85,16 +88,15 @@ func (h *Handler) GetMyQuests(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"quests": result})
 }
 
-// ClaimQuestReward забирает награду за выполненный квест
-func (h *Handler) ClaimQuestReward(c *gin.Context) {
+// GetMyQuest возвращает один активный квест с прогрессом пользователя за текущий период
+func (h *Handler) GetMyQuest(c *gin.Context) {
 	userID, ok := getUserID(c)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
 		return
 	}
 
-	userQuestIDStr := c.Param("id")
-	userQuestID, err := strconv.ParseInt(userQuestIDStr, 10, 64)
+	questID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid quest id"})
 		return
@@ -102,65 +104,82 @@ func (h *Handler) ClaimQuestReward(c *gin.Context) {
 
 	ctx := c.Request.Context()
 
-	// Забираем награду
-	rewardGems, err := h.QuestRepo.ClaimReward(ctx, userQuestID)
+	quest, err := h.QuestRepo.GetQuestByID(ctx, questID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && !quest.IsActive) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "quest not found"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot claim reward"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get quest"})
 		return
 	}
 
-	// Начисляем гемы пользователю
-	var newBalance int64
-	err = h.DB.QueryRow(ctx,
-		`UPDATE users SET gems = gems + $1 WHERE id = $2 RETURNING gems`,
-		rewardGems, userID,
-	).Scan(&newBalance)
+	uq, err := h.QuestRepo.GetUserQuestForPeriod(ctx, userID, quest)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update balance"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get quest progress"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"reward": rewardGems,
-		"gems":   newBalance,
-	})
-}
+	qwp := QuestWithProgress{
+		Quest:       quest,
+		TargetCount: quest.TargetCount,
+	}
+	if uq != nil {
+		qwp.CurrentCount = uq.CurrentCount
+		qwp.Completed = uq.Completed
+		qwp.RewardClaimed = uq.RewardClaimed
+		qwp.UserQuestID = &uq.ID
+		qwp.Progress = uq.Progress(quest.TargetCount)
+	}
 
-// updateQuestsAfterGame вызывается после каждой игры для обновления прогресса квестов
-// Deprecated: use updateQuestsAfterGameWithCtx instead
-func (h *Handler) updateQuestsAfterGame(userID int64, gameType string, result string) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	h.updateQuestsAfterGameWithCtx(ctx, userID, gameType, result)
+	c.JSON(http.StatusOK, gin.H{"quest": qwp})
 }
 
-// updateQuestsAfterGameWithCtx вызывается после каждой игры для обновления прогресса квестов
-func (h *Handler) updateQuestsAfterGameWithCtx(ctx context.Context, userID int64, gameType string, result string) {
-	// Получаем все активные квесты
-	quests, err := h.QuestRepo.GetActiveQuests(ctx)
+// ClaimQuestReward забирает награду за выполненный квест
+func (h *Handler) ClaimQuestReward(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	userQuestIDStr := c.Param("id")
+	userQuestID, err := strconv.ParseInt(userQuestIDStr, 10, 64)
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid quest id"})
 		return
 	}
 
-	for _, quest := range quests {
-		// Проверяем соответствие типа игры
-		if quest.GameType != nil && *quest.GameType != "any" && *quest.GameType != gameType {
-			continue
-		}
+	ctx := c.Request.Context()
 
-		// Проверяем тип действия
-		shouldIncrement := false
-		switch quest.ActionType {
-		case domain.ActionTypePlay:
-			shouldIncrement = true
-		case domain.ActionTypeWin:
-			shouldIncrement = (result == "win")
-		case domain.ActionTypeLose:
-			shouldIncrement = (result == "lose")
-		}
+	// Happy hour для квестов умножает награду
+	boost := h.GameService.HappyHours().Boost(game.HappyHourQuests, time.Now())
 
-		if shouldIncrement {
-			_ = h.QuestRepo.IncrementProgress(ctx, userID, quest, 1)
-		}
+	// Отмечаем квест и начисляем gems/coins/GK одной транзакцией
+	claim, err := h.Quests.ClaimReward(ctx, userID, userQuestID, boost)
+	if errors.Is(err, service.ErrQuestAlreadyClaimed) {
+		c.JSON(http.StatusConflict, gin.H{"error": "already_claimed", "message": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrQuestNotClaimable) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot claim reward"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update balance"})
+		return
+	}
+
+	resp := gin.H{
+		"reward":       claim.Reward.Gems,
+		"reward_coins": claim.Reward.Coins,
+		"reward_gk":    claim.Reward.GK,
+		"gems":         claim.Gems,
+		"coins":        claim.Coins,
+		"gk":           claim.GK,
+	}
+	if boost > 1 {
+		resp["happy_hour"] = boost
 	}
+	c.JSON(http.StatusOK, resp)
 }
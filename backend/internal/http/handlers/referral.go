@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"unicode/utf8"
 
 	"telegram_webapp/internal/repository"
+	"telegram_webapp/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,13 +15,14 @@ import (
 // ReferralHandler handles referral-related requests
 type ReferralHandler struct {
 	repo            *repository.ReferralRepository
+	rewards         *service.ReferralRewardService
 	botUsername     string
 	webAppShortName string
 }
 
 // NewReferralHandler creates a new referral handler
-func NewReferralHandler(repo *repository.ReferralRepository, botUsername, webAppShortName string) *ReferralHandler {
-	return &ReferralHandler{repo: repo, botUsername: botUsername, webAppShortName: webAppShortName}
+func NewReferralHandler(repo *repository.ReferralRepository, rewards *service.ReferralRewardService, botUsername, webAppShortName string) *ReferralHandler {
+	return &ReferralHandler{repo: repo, rewards: rewards, botUsername: botUsername, webAppShortName: webAppShortName}
 }
 
 // GetReferralCode returns user's referral code (generates if needed)
@@ -59,6 +64,7 @@ func (h *ReferralHandler) GetReferralStats(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"stats":     stats,
 		"referrals": referrals,
+		"hold_mode": h.rewards.Mode(),
 	})
 }
 
@@ -76,7 +82,7 @@ func (h *ReferralHandler) ApplyReferralCode(c *gin.Context) {
 
 	var req ApplyReferralRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "code is required"})
+		respondBindError(c, err)
 		return
 	}
 
@@ -106,11 +112,26 @@ func (h *ReferralHandler) ApplyReferralCode(c *gin.Context) {
 	}
 
 	// Create referral
-	err = h.repo.CreateReferral(c.Request.Context(), referrerID, userID)
+	created, err := h.repo.CreateReferral(c.Request.Context(), referrerID, userID)
+	if errors.Is(err, repository.ErrSelfReferral) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot use your own code"})
+		return
+	}
+	if errors.Is(err, repository.ErrReferralCycle) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "referral_cycle", "message": "the code owner was invited by you or your referrals"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to apply referral"})
 		return
 	}
+	if !created {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "already referred"})
+		return
+	}
+
+	// Награда сразу или после квалификации, в зависимости от REFERRAL_HOLD_MODE
+	h.rewards.OnReferralCreated(c.Request.Context(), userID)
 
 	c.JSON(http.StatusOK, gin.H{"message": "referral applied successfully"})
 }
@@ -139,3 +160,67 @@ func (h *ReferralHandler) GetReferralLink(c *gin.Context) {
 		"link": link,
 	})
 }
+
+// Пагинация дерева рефералов
+const (
+	referralTreeDefaultLimit = 20
+	referralTreeMaxLimit     = 100
+)
+
+// GetReferralTree returns a page of user's direct referrals with their activity
+func (h *ReferralHandler) GetReferralTree(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	page := 1
+	if v := c.Query("page"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			page = n
+		}
+	}
+	limit := referralTreeDefaultLimit
+	if v := c.Query("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			limit = n
+		}
+	}
+	if limit > referralTreeMaxLimit {
+		limit = referralTreeMaxLimit
+	}
+
+	entries, total, err := h.repo.GetReferralTree(c.Request.Context(), userID, limit, (page-1)*limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get referral tree"})
+		return
+	}
+
+	referrals := make([]gin.H, 0, len(entries))
+	for _, e := range entries {
+		referrals = append(referrals, gin.H{
+			"name":           anonymizeName(e.Name),
+			"joined_at":      e.JoinedAt,
+			"has_played":     e.HasPlayed,
+			"has_deposited":  e.HasDeposited,
+			"earnings_coins": e.EarningsCoins,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"referrals": referrals,
+		"page":      page,
+		"limit":     limit,
+		"total":     total,
+	})
+}
+
+// anonymizeName оставляет только первую букву имени
+func anonymizeName(name string) string {
+	r, _ := utf8.DecodeRuneInString(name)
+	if r == utf8.RuneError {
+		return "***"
+	}
+	return string(r) + "***"
+}
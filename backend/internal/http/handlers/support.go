@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"telegram_webapp/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	supportReportsPerHour = 5    // больше - 429, чтобы не заспамили поддержку
+	maxSupportClientState = 8192 // байт снимка клиента
+)
+
+// SupportReportRequest - жалоба на рассинхрон с снимком состояния клиента
+type SupportReportRequest struct {
+	Category    domain.SupportCategory `json:"category" binding:"required,oneof=balance game_state deposit withdrawal other"`
+	Message     string                 `json:"message" binding:"max=1000"`
+	ClientState json.RawMessage        `json:"client_state"`
+}
+
+// CreateSupportReport сохраняет жалобу вместе со снимком баланса на сервере
+func (h *Handler) CreateSupportReport(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req SupportReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if len(req.ClientState) > maxSupportClientState {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "client_state too large"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	sent, err := h.SupportRepo.CountSince(ctx, userID, time.Now().Add(-time.Hour))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	if sent >= supportReportsPerHour {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many reports, try again later"})
+		return
+	}
+
+	user, err := h.UserRepo.GetByID(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	// Снимок сервера для сравнения с тем, что видит клиент
+	server := gin.H{
+		"gems":        user.Gems,
+		"coins":       user.Coins,
+		"captured_at": time.Now().UTC(),
+	}
+	if g := h.MinesProService.GetActiveGame(ctx, userID); g != nil {
+		server["mines_pro"] = g.GetState()
+	}
+	if g := h.CoinFlipProService.GetActiveGame(userID); g != nil {
+		server["coinflip_pro"] = g.GetState()
+	}
+	serverState, _ := json.Marshal(server)
+
+	report := &domain.SupportReport{
+		UserID:      userID,
+		Category:    req.Category,
+		Message:     req.Message,
+		ClientState: req.ClientState,
+		ServerState: serverState,
+	}
+	if err := h.SupportRepo.Create(ctx, report); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"report_id": report.ID, "status": report.Status})
+}
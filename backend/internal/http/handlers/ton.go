@@ -2,34 +2,37 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 
 	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/logger"
 	"telegram_webapp/internal/repository"
+	"telegram_webapp/internal/service"
 	"telegram_webapp/internal/ton"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5"
 )
 
-// WithdrawalNotifyFunc is a callback for notifying about new withdrawals
-type WithdrawalNotifyFunc func(ctx context.Context, withdrawalID int64)
-
 // TonHandler handles TON-related endpoints
 type TonHandler struct {
-	DB                 *repository.WalletRepository
-	DepositRepo        *repository.DepositRepository
-	WithdrawalRepo     *repository.WithdrawalRepository
-	ReferralRepo       *repository.ReferralRepository
-	UserRepo           *repository.UserRepository
-	TonClient          *ton.Client
-	PlatformWallet     string
-	AllowedDomain      string
-	MainDB             *Handler
-	OnWithdrawalCreate WithdrawalNotifyFunc // Callback for withdrawal notifications
+	DB             *repository.WalletRepository
+	DepositRepo    *repository.DepositRepository
+	WithdrawalRepo *repository.WithdrawalRepository
+	ReferralRepo   *repository.ReferralRepository
+	UserRepo       *repository.UserRepository
+	TonClient      *ton.Client
+	PlatformWallet string
+	AllowedDomain  string
+	MainDB         *Handler
+	CoinsPerTON    int // текущий курс, сохраняется в каждом депозите и выводе
+	WithdrawFee    ton.WithdrawFee
+
+	// Вывод только на кошелёк с проверенным TON Connect proof
+	RequireVerifiedWallet bool
 }
 
 // NewTonHandler creates a new TON handler
@@ -39,6 +42,14 @@ func NewTonHandler(h *Handler) *TonHandler {
 		network = ton.NetworkTestnet
 	}
 
+	// Без домена proof не проверяется и ни один кошелёк не станет подтверждённым:
+	// все выводы отвечали бы wallet_not_verified
+	allowedDomain := os.Getenv("TON_ALLOWED_DOMAIN")
+	requireVerified := requireVerifiedWallet()
+	if requireVerified && allowedDomain == "" {
+		logger.Fatal("TON_REQUIRE_VERIFIED_WALLET requires TON_ALLOWED_DOMAIN")
+	}
+
 	return &TonHandler{
 		DB:             repository.NewWalletRepository(h.DB),
 		DepositRepo:    repository.NewDepositRepository(h.DB),
@@ -47,11 +58,24 @@ func NewTonHandler(h *Handler) *TonHandler {
 		UserRepo:       repository.NewUserRepository(h.DB),
 		TonClient:      ton.NewClient(network, os.Getenv("TON_API_KEY")),
 		PlatformWallet: os.Getenv("TON_PLATFORM_WALLET"),
-		AllowedDomain:  os.Getenv("TON_ALLOWED_DOMAIN"),
+		AllowedDomain:  allowedDomain,
 		MainDB:         h,
+		CoinsPerTON:    h.CoinsPerTON,
+		WithdrawFee:    h.WithdrawFee,
+
+		RequireVerifiedWallet: requireVerified,
 	}
 }
 
+// requireVerifiedWallet reads TON_REQUIRE_VERIFIED_WALLET; by default it is on
+// everywhere except DEV_MODE, where wallets are linked without a proof
+func requireVerifiedWallet() bool {
+	if v := os.Getenv("TON_REQUIRE_VERIFIED_WALLET"); v != "" {
+		return v == "true"
+	}
+	return os.Getenv("DEV_MODE") != "true"
+}
+
 // ConnectWalletRequest represents wallet connection request
 type ConnectWalletRequest struct {
 	Account ton.WalletAccount `json:"account"`
@@ -68,7 +92,7 @@ func (h *TonHandler) ConnectWallet(c *gin.Context) {
 
 	var req ConnectWalletRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		respondBindError(c, err)
 		return
 	}
 
@@ -110,10 +134,9 @@ func (h *TonHandler) ConnectWallet(c *gin.Context) {
 			return
 		}
 		isVerified = true
-	} else {
-		// In dev mode, trust the connection
-		isVerified = true
 	}
+	// Без проверки proof кошелёк привязывается, но не считается подтверждённым:
+	// при TON_REQUIRE_VERIFIED_WALLET вывод на него запрещён
 
 	// Normalize address
 	rawAddress, _ := ton.NormalizeAddress(req.Account.Address)
@@ -217,7 +240,7 @@ func (h *TonHandler) GetDepositInfo(c *gin.Context) {
 		PlatformAddress: platformAddress,
 		Memo:            memo,
 		MinAmountTON:    fmt.Sprintf("%.2f", ton.NanoToTON(ton.MinDepositNano)),
-		ExchangeRate:    ton.CoinsPerTON, // 10 coins per TON
+		ExchangeRate:    h.CoinsPerTON,
 	})
 }
 
@@ -241,7 +264,7 @@ func (h *TonHandler) GetDeposits(c *gin.Context) {
 
 // WithdrawRequest represents withdrawal request (coins only - premium currency)
 type WithdrawRequestBody struct {
-	CoinsAmount int64 `json:"coins_amount" binding:"required,min=10"` // Minimum 10 coins (1 TON)
+	CoinsAmount int64 `json:"coins_amount" binding:"required,min=10,amount"` // Minimum 10 coins (1 TON)
 }
 
 // RequestWithdrawal creates a new withdrawal request (coins only)
@@ -254,7 +277,7 @@ func (h *TonHandler) RequestWithdrawal(c *gin.Context, db *pgx.Conn) {
 
 	var req WithdrawRequestBody
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		respondBindError(c, err)
 		return
 	}
 
@@ -270,6 +293,10 @@ func (h *TonHandler) RequestWithdrawal(c *gin.Context, db *pgx.Conn) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "no wallet linked"})
 		return
 	}
+	if h.RequireVerifiedWallet && !wallet.IsVerified {
+		c.JSON(http.StatusForbidden, gin.H{"error": "wallet_not_verified", "message": "reconnect the wallet with TON Connect proof to withdraw"})
+		return
+	}
 
 	// Check for existing pending withdrawal
 	hasPending, err := h.WithdrawalRepo.HasPendingWithdrawal(ctx, userID)
@@ -278,7 +305,32 @@ func (h *TonHandler) RequestWithdrawal(c *gin.Context, db *pgx.Conn) {
 		return
 	}
 	if hasPending {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "you already have a pending withdrawal"})
+		c.JSON(http.StatusConflict, gin.H{"error": "already_pending", "message": "you already have a pending withdrawal"})
+		return
+	}
+
+	// Only unlocked (wagered) coins can be withdrawn
+	user, err := h.UserRepo.GetByID(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	wagering, err := h.MainDB.BonusRepo.GetStatus(ctx, userID, domain.CurrencyCoins)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	withdrawable := user.Coins - wagering.LockedAmount
+	if withdrawable < 0 {
+		withdrawable = 0
+	}
+	if req.CoinsAmount > withdrawable {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":           "insufficient withdrawable balance",
+			"withdrawable":    withdrawable,
+			"locked_bonus":    wagering.LockedAmount,
+			"remaining_wager": wagering.RemainingWager,
+		})
 		return
 	}
 
@@ -288,8 +340,7 @@ func (h *TonHandler) RequestWithdrawal(c *gin.Context, db *pgx.Conn) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
 		return
 	}
-	if todayTotal+req.CoinsAmount > ton.MaxWithdrawCoinsPerDay {
-		remaining := ton.MaxWithdrawCoinsPerDay - todayTotal
+	if remaining := ton.RemainingWithdrawCoinsToday(todayTotal); req.CoinsAmount > remaining {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":           "daily withdrawal limit exceeded",
 			"remaining_today": remaining,
@@ -297,58 +348,72 @@ func (h *TonHandler) RequestWithdrawal(c *gin.Context, db *pgx.Conn) {
 		return
 	}
 
-	// Calculate amounts (5% fee)
-	feeCoins := ton.CalculateWithdrawFeeCoins(req.CoinsAmount)
-	netCoins := ton.CalculateWithdrawNetCoins(req.CoinsAmount)
-	tonAmountNano := ton.CoinsToNano(netCoins)
+	feeCoins := ton.CalculateWithdrawFeeCoins(req.CoinsAmount, h.WithdrawFee)
+	netCoins := ton.CalculateWithdrawNetCoins(req.CoinsAmount, h.WithdrawFee)
+	if netCoins <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "amount does not cover the withdrawal fee", "fee_coins": feeCoins})
+		return
+	}
+	tonAmountNano := ton.NanoForCoins(netCoins, h.CoinsPerTON)
 
-	// Create withdrawal (balance check and deduction should be done in a service/transaction)
+	// Coins are held in the same transaction that creates the withdrawal
 	withdrawal := &domain.Withdrawal{
 		UserID:        userID,
 		WalletAddress: wallet.Address,
 		CoinsAmount:   req.CoinsAmount,
 		TonAmountNano: tonAmountNano,
 		FeeCoins:      feeCoins,
-		ExchangeRate:  ton.CoinsPerTON,
+		ExchangeRate:  h.CoinsPerTON,
 		Status:        domain.WithdrawalStatusPending,
 	}
 
-	if err := h.WithdrawalRepo.Create(ctx, withdrawal); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create withdrawal"})
+	// Share of the fee for the referrer is credited in the same transaction as the
+	// withdrawal, once per withdrawal id
+	var commission *repository.ReferralCommissionCredit
+	referrerID, err := h.ReferralRepo.GetReferrerID(ctx, userID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
 		return
 	}
-
-	// Notify admins about new withdrawal
-	if h.OnWithdrawalCreate != nil {
-		go h.OnWithdrawalCreate(ctx, withdrawal.ID)
+	if referrerID > 0 {
+		if amount := h.MainDB.ReferralCommission.Compute(feeCoins); amount > 0 {
+			commission = &repository.ReferralCommissionCredit{
+				ReferrerID: referrerID,
+				Amount:     amount,
+				Meta:       h.MainDB.ReferralCommission.Meta(feeCoins, amount),
+			}
+		}
 	}
 
-	// Give 50% of fee to referrer (if user was referred)
-	referrerID, err := h.ReferralRepo.GetReferrerID(ctx, userID)
-	if err == nil && referrerID > 0 {
-		// 50% of fee goes to referrer
-		referrerCommission := feeCoins / 2
-		if referrerCommission > 0 {
-			// Add coins to referrer
-			_, _ = h.UserRepo.UpdateCoins(ctx, referrerID, referrerCommission)
-			// Track referral earnings
-			_ = h.UserRepo.AddReferralEarnings(ctx, referrerID, referrerCommission)
-
-			// Record transaction for referrer
-			meta := map[string]interface{}{
-				"type":           "referral_commission",
-				"from_user_id":   userID,
-				"withdrawal_id":  withdrawal.ID,
-				"total_fee":      feeCoins,
-				"commission_pct": 50,
-			}
-			metaB, _ := json.Marshal(meta)
-			_, _ = h.MainDB.DB.Exec(ctx,
-				`INSERT INTO transactions (user_id, type, amount, meta) VALUES ($1, $2, $3, $4)`,
-				referrerID, "referral_commission", referrerCommission, metaB)
+	// Параллельный запрос мог успеть создать вывод после проверки выше
+	if err := h.WithdrawalRepo.CreatePendingWithCommission(ctx, withdrawal, commission); err != nil {
+		if errors.Is(err, repository.ErrWithdrawalPending) {
+			c.JSON(http.StatusConflict, gin.H{"error": "already_pending", "message": "you already have a pending withdrawal"})
+			return
+		}
+		if errors.Is(err, repository.ErrInsufficientFunds) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "insufficient withdrawable balance"})
+			return
 		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create withdrawal"})
+		return
 	}
 
+	// Notify admins about new withdrawal
+	username := user.Username
+	if username == "" {
+		username = user.FirstName
+	}
+	go service.NotifyWithdrawalCreated(context.Background(), h.MainDB.Notifier, &service.WithdrawalNotification{
+		ID:            withdrawal.ID,
+		UserID:        userID,
+		Username:      username,
+		TgID:          user.TgID,
+		WalletAddress: withdrawal.WalletAddress,
+		CoinsAmount:   withdrawal.CoinsAmount,
+		TonAmount:     ton.NanoToTON(withdrawal.TonAmountNano),
+	})
+
 	c.JSON(http.StatusOK, gin.H{
 		"withdrawal": withdrawal,
 		"estimate": domain.WithdrawEstimate{
@@ -357,9 +422,9 @@ func (h *TonHandler) RequestWithdrawal(c *gin.Context, db *pgx.Conn) {
 			NetCoins:      netCoins,
 			TonAmount:     fmt.Sprintf("%.4f", ton.NanoToTON(tonAmountNano)),
 			TonAmountNano: tonAmountNano,
-			ExchangeRate:  ton.CoinsPerTON,
-			FeePercent:    0, // No longer percentage-based, using fixed fee
-			FeeTON:        ton.CoinsToTON(feeCoins),
+			ExchangeRate:  h.CoinsPerTON,
+			FeePercent:    h.feePercent(),
+			FeeTON:        ton.NanoToTON(ton.NanoForCoins(feeCoins, h.CoinsPerTON)),
 		},
 	})
 }
@@ -368,7 +433,7 @@ func (h *TonHandler) RequestWithdrawal(c *gin.Context, db *pgx.Conn) {
 func (h *TonHandler) GetWithdrawEstimate(c *gin.Context) {
 	var req WithdrawRequestBody
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		respondBindError(c, err)
 		return
 	}
 
@@ -376,14 +441,18 @@ func (h *TonHandler) GetWithdrawEstimate(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":      "minimum withdrawal is 10 coins (1 TON)",
 			"min_coins":  ton.MinWithdrawCoins,
-			"min_ton":    fmt.Sprintf("%.2f", ton.NanoToTON(ton.CoinsToNano(ton.MinWithdrawCoins))),
+			"min_ton":    fmt.Sprintf("%.2f", ton.NanoToTON(ton.NanoForCoins(ton.MinWithdrawCoins, h.CoinsPerTON))),
 		})
 		return
 	}
 
-	feeCoins := ton.CalculateWithdrawFeeCoins(req.CoinsAmount)
-	netCoins := ton.CalculateWithdrawNetCoins(req.CoinsAmount)
-	tonAmountNano := ton.CoinsToNano(netCoins)
+	feeCoins := ton.CalculateWithdrawFeeCoins(req.CoinsAmount, h.WithdrawFee)
+	netCoins := ton.CalculateWithdrawNetCoins(req.CoinsAmount, h.WithdrawFee)
+	if netCoins <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "amount does not cover the withdrawal fee", "fee_coins": feeCoins})
+		return
+	}
+	tonAmountNano := ton.NanoForCoins(netCoins, h.CoinsPerTON)
 
 	c.JSON(http.StatusOK, domain.WithdrawEstimate{
 		CoinsAmount:   req.CoinsAmount,
@@ -391,9 +460,9 @@ func (h *TonHandler) GetWithdrawEstimate(c *gin.Context) {
 		NetCoins:      netCoins,
 		TonAmount:     fmt.Sprintf("%.4f", ton.NanoToTON(tonAmountNano)),
 		TonAmountNano: tonAmountNano,
-		ExchangeRate:  ton.CoinsPerTON,
-		FeePercent:    0, // No longer percentage-based, using fixed fee
-		FeeTON:        ton.CoinsToTON(feeCoins),
+		ExchangeRate:  h.CoinsPerTON,
+		FeePercent:    h.feePercent(),
+		FeeTON:        ton.NanoToTON(ton.NanoForCoins(feeCoins, h.CoinsPerTON)),
 	})
 }
 
@@ -411,11 +480,17 @@ func (h *TonHandler) GetWithdrawals(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
 		return
 	}
+	// За всё время - только реально отправленные выводы
+	totalWithdrawn, err := h.WithdrawalRepo.GetTotalCoinsWithdrawn(ctx, userID, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{"withdrawals": withdrawals})
+	c.JSON(http.StatusOK, gin.H{"withdrawals": withdrawals, "total_withdrawn": totalWithdrawn})
 }
 
-// CancelWithdrawal cancels a pending withdrawal
+// CancelWithdrawal cancels a pending withdrawal and refunds the held coins
 func (h *TonHandler) CancelWithdrawal(c *gin.Context) {
 	userID, ok := getUserID(c)
 	if !ok {
@@ -427,7 +502,7 @@ func (h *TonHandler) CancelWithdrawal(c *gin.Context) {
 		WithdrawalID int64 `json:"withdrawal_id" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		respondBindError(c, err)
 		return
 	}
 
@@ -444,18 +519,31 @@ func (h *TonHandler) CancelWithdrawal(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
 
-// GetTonConfig returns TON configuration for frontend
+// feePercent is the percent for WithdrawEstimate; 0 for a fixed fee
+func (h *TonHandler) feePercent() float64 {
+	if h.WithdrawFee.Mode == ton.WithdrawFeeModePercent {
+		return h.WithdrawFee.Percent
+	}
+	return 0
+}
+
+// GetTonConfig returns TON configuration for frontend.
+// withdraw_fee_coins / withdraw_fee_ton - комиссия с минимального вывода.
 func (h *TonHandler) GetTonConfig(c *gin.Context) {
+	minFee := ton.CalculateWithdrawFeeCoins(ton.MinWithdrawCoins, h.WithdrawFee)
 	c.JSON(http.StatusOK, gin.H{
 		"platform_wallet":            h.PlatformWallet,
-		"coins_per_ton":              ton.CoinsPerTON, // 10 coins = 1 TON
+		"coins_per_ton":              h.CoinsPerTON,
 		"min_deposit_ton":            fmt.Sprintf("%.2f", ton.NanoToTON(ton.MinDepositNano)),
 		"min_withdraw_coins":         ton.MinWithdrawCoins,
-		"withdraw_fee_coins":         ton.WithdrawFeeCoinsFixed, // 1 coin = 0.1 TON
-		"withdraw_fee_ton":           ton.CoinsToTON(ton.WithdrawFeeCoinsFixed), // 0.1 TON
-		"withdraw_fee_percent":       0, // No longer percentage-based
+		"withdraw_fee_mode":          h.WithdrawFee.Mode,
+		"withdraw_fee_coins":         minFee,
+		"withdraw_fee_ton":           ton.NanoToTON(ton.NanoForCoins(minFee, h.CoinsPerTON)),
+		"withdraw_fee_percent":       h.feePercent(),
+		"withdraw_fee_min_coins":     h.WithdrawFee.MinCoins,
 		"max_withdraw_coins_per_day": ton.MaxWithdrawCoinsPerDay,
 		"network":                    os.Getenv("TON_NETWORK"),
+		"require_verified_wallet":    h.RequireVerifiedWallet,
 	})
 }
 
@@ -474,11 +562,11 @@ func (h *TonHandler) RecordManualDeposit(c *gin.Context, handler *Handler) {
 	}
 
 	var req struct {
-		AmountTON float64 `json:"amount_ton" binding:"required,min=0.1"`
+		AmountTON float64 `json:"amount_ton" binding:"required,min=0.1,amount"`
 		TxHash    string  `json:"tx_hash" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		respondBindError(c, err)
 		return
 	}
 
@@ -507,42 +595,30 @@ func (h *TonHandler) RecordManualDeposit(c *gin.Context, handler *Handler) {
 		walletAddr = wallet.Address
 	}
 
-	amountNano := ton.TONToNano(req.AmountTON)
-	coinsCredited := ton.NanoToCoins(amountNano)
-
+	// Курс фиксируется в депозите, монеты считаются по нему
 	deposit := &domain.Deposit{
 		UserID:        userID,
 		WalletAddress: walletAddr,
-		AmountNano:    amountNano,
-		CoinsCredited: coinsCredited,
-		ExchangeRate:  ton.CoinsPerTON,
+		AmountNano:    ton.TONToNano(req.AmountTON),
+		ExchangeRate:  h.CoinsPerTON,
 		TxHash:        req.TxHash,
 		Status:        domain.DepositStatusConfirmed,
 		Processed:     true,
 	}
 
-	if err := h.DepositRepo.Create(ctx, deposit); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create deposit"})
+	credited, err := h.DepositRepo.CreditDeposit(ctx, deposit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to credit deposit"})
 		return
 	}
-
-	// Credit coins to user
-	_, err = handler.DB.Exec(ctx, `UPDATE users SET coins = coins + $1 WHERE id = $2`, coinsCredited, userID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to credit coins"})
+	if !credited {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "transaction already processed"})
 		return
 	}
+	coinsCredited := deposit.CoinsCredited
 
-	// Record transaction
-	meta := map[string]interface{}{
-		"deposit_id":     deposit.ID,
-		"tx_hash":        req.TxHash,
-		"ton_amount":     req.AmountTON,
-		"coins_credited": coinsCredited,
-	}
-	metaB, _ := json.Marshal(meta)
-	_, _ = handler.DB.Exec(ctx, `INSERT INTO transactions (user_id,type,amount,meta) VALUES ($1,$2,$3,$4)`,
-		userID, "ton_deposit", coinsCredited, metaB)
+	// Первый депозит может квалифицировать реферала
+	handler.ReferralRewards.CheckQualification(ctx, userID)
 
 	c.JSON(http.StatusOK, gin.H{
 		"deposit":        deposit,
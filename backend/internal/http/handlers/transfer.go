@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Transfer sends gems from the caller to another player identified by Telegram ID.
+// Amount is bounded by TransferLimits, including a daily cap per sender and the
+// anti-farm rules (account age, wagered gems, no promo gems); both
+// transactions carry purpose "p2p_transfer" and the transfer is written to the audit log.
+func (h *Handler) Transfer(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req struct {
+		ToTgID int64 `json:"to_tg_id"`
+		Amount int64 `json:"amount" binding:"amount"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.ToTgID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	if err := h.TransferLimits.Check(req.Amount); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"min":   h.TransferLimits.Min,
+			"max":   h.TransferLimits.Max,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	recipient, err := h.UserRepo.GetByTgID(ctx, req.ToTgID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "recipient not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	if recipient.ID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": service.ErrSelfTransfer.Error()})
+		return
+	}
+	banned, err := h.UserRepo.IsBanned(ctx, recipient.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	if banned {
+		c.JSON(http.StatusForbidden, gin.H{"error": "recipient is banned"})
+		return
+	}
+
+	meta := map[string]interface{}{
+		"purpose":  "p2p_transfer",
+		"to_tg_id": recipient.TgID,
+		"ip":       c.ClientIP(),
+	}
+	fromBalance, toBalance, err := h.Balance.Transfer(ctx, userID, recipient.ID, req.Amount, h.TransferLimits, meta)
+	switch {
+	case errors.Is(err, service.ErrInsufficientFunds):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "insufficient balance"})
+		return
+	case errors.Is(err, service.ErrTransferDailyCap):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error(), "daily_cap": h.TransferLimits.DailyCap})
+		return
+	case errors.Is(err, service.ErrTransferAccountTooNew):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "min_account_age_hours": int(h.TransferLimits.MinAccountAge.Hours())})
+		return
+	case errors.Is(err, service.ErrTransferNotWagered):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "min_wagered": h.TransferLimits.MinWagered})
+		return
+	case errors.Is(err, service.ErrTransferPromoGems):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrSelfTransfer),
+		errors.Is(err, service.ErrTransferTooLow),
+		errors.Is(err, service.ErrTransferTooHigh),
+		errors.Is(err, service.ErrInvalidAmount):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "recipient not found"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "transfer failed"})
+		return
+	}
+
+	h.AuditService.LogWithRequest(ctx, userID, domain.AuditActionTransfer, domain.AuditCategoryBalance, c.ClientIP(), c.GetHeader("User-Agent"), map[string]interface{}{
+		"to_user_id": recipient.ID,
+		"to_tg_id":   recipient.TgID,
+		"amount":     req.Amount,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"ok":                true,
+		"amount":            req.Amount,
+		"gems":              fromBalance,
+		"recipient_gems":    toBalance,
+		"recipient_user_id": recipient.ID,
+	})
+}
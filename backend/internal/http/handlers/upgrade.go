@@ -140,7 +140,7 @@ func (h *UpgradeHandler) UpgradeCharacter(c *gin.Context) {
 
 	var req UpgradeRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		respondBindError(c, err)
 		return
 	}
 
@@ -200,7 +200,7 @@ func (h *UpgradeHandler) ClaimReferralReward(c *gin.Context) {
 
 	var req ClaimRewardRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		respondBindError(c, err)
 		return
 	}
 
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// DefaultMaxRequestAmount - потолок |amount| в теле запроса, см. SetMaxRequestAmount
+const DefaultMaxRequestAmount = 1_000_000_000
+
+var maxRequestAmount atomic.Int64
+
+func init() {
+	maxRequestAmount.Store(DefaultMaxRequestAmount)
+
+	// Report json field names (bet, target) instead of Go struct names (Bet, Target)
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+			if name == "" || name == "-" {
+				return f.Name
+			}
+			return name
+		})
+		_ = v.RegisterValidation("amount", validateAmount)
+	}
+}
+
+// SetMaxRequestAmount sets the ceiling for fields tagged binding:"amount"; non-positive values are ignored
+func SetMaxRequestAmount(n int64) {
+	if n > 0 {
+		maxRequestAmount.Store(n)
+	}
+}
+
+// validateAmount - тег amount: модуль суммы не больше потолка, у float ещё не NaN/Inf
+func validateAmount(fl validator.FieldLevel) bool {
+	limit := maxRequestAmount.Load()
+	f := fl.Field()
+	switch f.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := f.Int()
+		return n >= -limit && n <= limit
+	case reflect.Float32, reflect.Float64:
+		x := f.Float()
+		return !math.IsNaN(x) && !math.IsInf(x, 0) && math.Abs(x) <= float64(limit)
+	}
+	return true
+}
+
+// respondBindError отвечает 400 на ошибку ShouldBindJSON в том же виде, что и
+// respondError: {"error": message, "code": CODE}.
+// Validation failures become VALIDATION_FAILED with fields:{bet:"required"},
+// malformed bodies become INVALID_REQUEST without parser details,
+// a body over the BodyLimit middleware cap becomes 413 BODY_TOO_LARGE.
+func respondBindError(c *gin.Context, err error) {
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+		fields := make(map[string]string, len(verrs))
+		for _, fe := range verrs {
+			fields[fe.Field()] = fe.Tag()
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "code": "VALIDATION_FAILED", "fields": fields})
+		return
+	}
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "request body too large", "code": "BODY_TOO_LARGE"})
+		return
+	}
+
+	respondError(c, errGameInvalidRequest)
+}
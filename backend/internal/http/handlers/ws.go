@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
-	"os"
 	"strconv"
 
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/game"
 	"telegram_webapp/internal/service"
 	"telegram_webapp/internal/ws"
 
@@ -15,19 +17,51 @@ import (
 
 func (h *Handler) WS(hub *ws.Hub) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// JWT from query
-		token := c.Query("token")
+		// Чужой сайт не получает даже close-фрейм: апгрейд с его Origin не делаем
+		if !hub.CheckOrigin(c.Request) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "origin not allowed"})
+			return
+		}
+		upgrader := hub.Upgrader()
+
+		// JWT из Sec-WebSocket-Protocol ("bearer", <token>); ?token= только если разрешён
+		token, fromQuery := hub.RequestToken(c.Request)
+		if fromQuery {
+			rejectWS(c, upgrader, http.StatusUnauthorized, ws.CloseUnauthorized, "unauthorized", "token must be sent in Sec-WebSocket-Protocol")
+			return
+		}
 		if token == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "token required"})
+			rejectWS(c, upgrader, http.StatusUnauthorized, ws.CloseUnauthorized, "unauthorized", "token required")
 			return
 		}
 
 		userID, err := service.ParseJWT(token)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			rejectWS(c, upgrader, http.StatusUnauthorized, ws.CloseUnauthorized, "unauthorized", "invalid token")
+			return
+		}
+
+		if banned, _ := h.UserRepo.IsBanned(c.Request.Context(), userID); banned {
+			rejectWS(c, upgrader, http.StatusForbidden, ws.CloseKicked, "banned", "banned")
+			return
+		}
+		if until, _ := h.UserRepo.ExcludedUntil(c.Request.Context(), userID); until != nil {
+			rejectWS(c, upgrader, http.StatusForbidden, ws.CloseKicked, "self_excluded", "self_excluded")
 			return
 		}
 
+		if !hub.AcquireConn(userID) {
+			rejectWS(c, upgrader, http.StatusTooManyRequests, ws.CloseTooManyConns, "too_many_connections", "too many connections")
+			return
+		}
+		// Слот освобождает клиент при закрытии; до этого - любой ранний выход
+		release := true
+		defer func() {
+			if release {
+				hub.ReleaseConn(userID)
+			}
+		}()
+
 		// Get game type from query (default: rps)
 		gameType := c.Query("game")
 		if gameType == "" {
@@ -43,17 +77,28 @@ func (h *Handler) WS(hub *ws.Hub) gin.HandlerFunc {
 		}
 		currency := c.Query("currency")
 		if currency == "" {
-			currency = "gems" // default currency
+			currency = string(domain.CurrencyGems) // default currency
+		}
+		if currency != string(domain.CurrencyGems) && currency != string(domain.CurrencyCoins) {
+			rejectWS(c, upgrader, http.StatusBadRequest, ws.CloseBadRequest, "bad_request", "invalid currency")
+			return
 		}
 
-		allowedOrigin := os.Getenv("ALLOWED_ORIGIN")
-		upgrader := websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				if allowedOrigin == "" {
-					return true
+		// Ставку здесь только проверяем: списывает её комната, когда соперник найден (Hub.CheckStake)
+		if betAmount > 0 {
+			if err := h.GameService.ValidateBetForGame(domain.GameType(gameType), betAmount, domain.Currency(currency)); err != nil {
+				rejectWS(c, upgrader, http.StatusBadRequest, ws.CloseBadRequest, "bad_request", err.Error())
+				return
+			}
+			if err := hub.CheckStake(c.Request.Context(), userID, betAmount, currency); err != nil {
+				if errors.Is(err, service.ErrInsufficientFunds) {
+					rejectWS(c, upgrader, http.StatusBadRequest, ws.CloseBadRequest, "insufficient_balance", "insufficient balance")
+					return
 				}
-				return r.Header.Get("Origin") == allowedOrigin
-			},
+				log.Printf("ws: failed to read balance user=%d: %v", userID, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check balance"})
+				return
+			}
 		}
 
 		// WebSocket upgrade
@@ -65,8 +110,54 @@ func (h *Handler) WS(hub *ws.Hub) gin.HandlerFunc {
 
 		// Create client with game type, bet amount and currency
 		client := ws.NewClient(userID, conn, hub, gameType, betAmount, currency)
+		client.SetOnClose(func() { hub.ReleaseConn(userID) })
+		release = false
 
 		// Start client (matchmaking, room, read/write)
 		go client.Run()
 	}
 }
+
+// rejectWS refuses a connection. Browsers can't read the HTTP status of a failed
+// handshake, so real WebSocket requests are upgraded and closed with a close code
+// (see ws/close.go); plain HTTP callers get the usual JSON error.
+func rejectWS(c *gin.Context, upgrader websocket.Upgrader, status, code int, reason, message string) {
+	if !websocket.IsWebSocketUpgrade(c.Request) {
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Println("ws upgrade error:", err)
+		return
+	}
+	ws.CloseConn(conn, code, reason)
+}
+
+// WSQueueStats returns how many players wait for an opponent per bet and currency,
+// so the UI can suggest bets that match instantly. Optional ?game_type=rps|mines.
+func (h *Handler) WSQueueStats(hub *ws.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		gameType := game.GameType(c.Query("game_type"))
+		if gameType != "" && gameType != game.TypeRPS && gameType != game.TypeMines {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid game_type"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"queues": hub.QueueStats(gameType)})
+	}
+}
+
+// MatchmakingStats returns waiting players per game/bet tier together with the
+// number of rooms playing right now. Optional ?game_type=rps|mines.
+func (h *Handler) MatchmakingStats(hub *ws.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		gameType := game.GameType(c.Query("game_type"))
+		if gameType != "" && gameType != game.TypeRPS && gameType != game.TypeMines {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid game_type"})
+			return
+		}
+
+		c.JSON(http.StatusOK, hub.MatchmakingStats(gameType))
+	}
+}
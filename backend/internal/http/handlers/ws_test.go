@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/repository"
+	"telegram_webapp/internal/service"
+	"telegram_webapp/internal/ws"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// waitFor polls cond until it holds or the deadline passes
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+// readWSUntil reads messages until one of the given type arrives
+func readWSUntil(t *testing.T, conn *websocket.Conn, msgType string) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	for {
+		_, raw, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("waiting for %q: %v", msgType, err)
+		}
+		var msg struct {
+			Type string `json:"type"`
+		}
+		if json.Unmarshal(raw, &msg) == nil && msg.Type == msgType {
+			return
+		}
+	}
+}
+
+// Integration-style test: runs only if TEST_DATABASE_URL env is set.
+func TestWSTakesOneStakePerMatch(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set; skipping integration test")
+	}
+	t.Setenv("JWT_SECRET", "ws-test-secret")
+	service.InitJWT()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	db, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+
+	users := repository.NewUserRepository(db)
+	const start, bet = 1000, 100
+	var ids [2]int64
+	for i := range ids {
+		u := &domain.User{TgID: time.Now().UnixNano(), Username: fmt.Sprintf("ws_stake_test_%d", i)}
+		if err := users.Create(ctx, u); err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+		ids[i] = u.ID
+		defer db.Exec(context.Background(), `DELETE FROM users WHERE id = $1`, u.ID)
+		defer db.Exec(context.Background(), `DELETE FROM transactions WHERE user_id = $1`, u.ID)
+		if _, err := db.Exec(ctx, `UPDATE users SET gems = $2 WHERE id = $1`, u.ID, start); err != nil {
+			t.Fatalf("set gems: %v", err)
+		}
+	}
+	gems := func(uid int64) int64 {
+		t.Helper()
+		g, err := users.GetGems(ctx, uid)
+		if err != nil {
+			t.Fatalf("get gems: %v", err)
+		}
+		return g
+	}
+
+	hub := ws.NewHubWithUserRepo(nil, nil, users)
+	hub.Balance = service.NewBalanceService(db)
+	h := &Handler{UserRepo: users, GameService: service.NewGameService(db)}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/ws", h.WS(hub))
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	dial := func(uid int64) *websocket.Conn {
+		t.Helper()
+		token, err := service.GenerateJWT(uid)
+		if err != nil {
+			t.Fatalf("jwt: %v", err)
+		}
+		url := fmt.Sprintf("ws%s/ws?game=rps&bet=%d&currency=gems", strings.TrimPrefix(srv.URL, "http"), bet)
+		dialer := websocket.Dialer{Subprotocols: []string{ws.TokenSubprotocol, token}}
+		conn, _, err := dialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("dial user %d: %v", uid, err)
+		}
+		return conn
+	}
+	waiting := func() int { return hub.MatchmakingStats("").Waiting }
+
+	// Ожидание соперника ничего не списывает, уход из очереди - тоже
+	conn := dial(ids[0])
+	waitFor(t, "player in queue", func() bool { return waiting() == 1 })
+	if got := gems(ids[0]); got != start {
+		t.Fatalf("waiting player: expected balance %d, got %d", start, got)
+	}
+	conn.Close()
+	waitFor(t, "empty queue", func() bool { return waiting() == 0 })
+	if got := gems(ids[0]); got != start {
+		t.Fatalf("player left the queue: expected balance %d, got %d", start, got)
+	}
+
+	// Матч удерживает ровно одну ставку с каждого
+	var conns [2]*websocket.Conn
+	for i, uid := range ids {
+		conns[i] = dial(uid)
+		defer conns[i].Close()
+	}
+	for _, c := range conns {
+		readWSUntil(t, c, "matched")
+	}
+	for _, uid := range ids {
+		if got := gems(uid); got != start-bet {
+			t.Fatalf("user %d in a match: expected balance %d, got %d", uid, start-bet, got)
+		}
+	}
+}
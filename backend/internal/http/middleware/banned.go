@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BanChecker reports whether a user is banned (e.g. UserRepository.IsBanned)
+type BanChecker func(ctx context.Context, userID int64) (bool, error)
+
+// CheckBanned rejects banned users with 403. Must run after JWT.
+// Lookup errors fail open: a database hiccup shouldn't lock everyone out.
+func CheckBanned(isBanned BanChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		id, ok := userID.(int64)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid user"})
+			return
+		}
+
+		if banned, err := isBanned(c.Request.Context(), id); err == nil && banned {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "banned"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultMaxBodyBytes - самый большой легитимный запрос - /auth с init_data до 4 КБ
+const DefaultMaxBodyBytes = 64 * 1024
+
+// BodyLimit rejects requests whose Content-Length exceeds maxBytes with 413 and caps
+// the body reader, so a body without a length fails to bind instead of being read whole.
+func BodyLimit(maxBytes int64) gin.HandlerFunc {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBodyBytes
+	}
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > maxBytes {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "request body too large", "code": "BODY_TOO_LARGE"})
+			return
+		}
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBodyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(BodyLimit(16))
+	r.POST("/", func(c *gin.Context) {
+		if _, err := io.ReadAll(c.Request.Body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "read"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	send := func(body string, chunked bool) int {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		if chunked {
+			req.ContentLength = -1 // длина неизвестна, лимит ловит чтение
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := send(`{"a":1}`, false); code != http.StatusOK {
+		t.Fatalf("small body: expected 200, got %d", code)
+	}
+	if code := send(strings.Repeat("x", 17), false); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized body: expected 413, got %d", code)
+	}
+	if code := send(strings.Repeat("x", 17), true); code != http.StatusBadRequest {
+		t.Fatalf("oversized body without length: expected read error, got %d", code)
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExclusionChecker returns the end of the user's active self-exclusion, nil if
+// there is none (e.g. UserRepository.ExcludedUntil)
+type ExclusionChecker func(ctx context.Context, userID int64) (*time.Time, error)
+
+// CheckSelfExcluded rejects self-excluded users with 403 until the exclusion ends.
+// Must run after JWT. Lookup errors fail open, same as CheckBanned.
+func CheckSelfExcluded(excludedUntil ExclusionChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		id, ok := userID.(int64)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid user"})
+			return
+		}
+
+		if until, err := excludedUntil(c.Request.Context(), id); err == nil && until != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "self_excluded", "excluded_until": until})
+			return
+		}
+		c.Next()
+	}
+}
@@ -4,14 +4,40 @@ import (
 	"context"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// DefaultGameRouteLimits returns built-in per-route ceilings for game steps that
+// are called many times per game; other routes use the common limit
+func DefaultGameRouteLimits() map[string]int {
+	return map[string]int{
+		"mines-pro/reveal":  300,
+		"coinflip-pro/flip": 180,
+		"rps-pro/move":      180,
+	}
+}
+
+// gameRouteLimit returns the route name after /game/ (the same for /api and
+// /api/v1) and its limit: perRoute override or the common maxGames
+func gameRouteLimit(fullPath string, maxGames int, perRoute map[string]int) (string, int) {
+	route := fullPath
+	if i := strings.Index(fullPath, "/game/"); i >= 0 {
+		route = fullPath[i+len("/game/"):]
+	}
+	if n, ok := perRoute[route]; ok && n > 0 {
+		return route, n
+	}
+	return route, maxGames
+}
+
 // GameRateLimit limits game plays per user (not per IP) using Redis.
-// Uses JWT user ID from context. Requires JWT middleware to run before this.
-func GameRateLimit(maxGames int, window time.Duration) gin.HandlerFunc {
+// Every game route has its own window per user, so a burst on dice doesn't use up
+// the coinflip quota; perRoute overrides maxGames for a route ("dice",
+// "mines-pro/reveal", ...). Uses JWT user ID from context. Requires JWT middleware to run before this.
+func GameRateLimit(maxGames int, window time.Duration, perRoute map[string]int) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if redisClient == nil {
 			// Redis not configured, fail-open
@@ -33,8 +59,10 @@ func GameRateLimit(maxGames int, window time.Duration) gin.HandlerFunc {
 			return
 		}
 
-		// Create user-specific key for game rate limiting
-		key := "game_rl:" + strconv.FormatInt(userID, 10) + ":" + strconv.FormatInt(int64(window.Seconds()), 10)
+		route, limit := gameRouteLimit(c.FullPath(), maxGames, perRoute)
+
+		// Create user+route specific key for game rate limiting
+		key := "game_rl:" + route + ":" + strconv.FormatInt(userID, 10) + ":" + strconv.FormatInt(int64(window.Seconds()), 10)
 		ctx := context.Background()
 
 		val, err := redisClient.Incr(ctx, key).Result()
@@ -50,11 +78,11 @@ func GameRateLimit(maxGames int, window time.Duration) gin.HandlerFunc {
 		}
 
 		// Set headers for client info
-		c.Header("X-GameRateLimit-Limit", strconv.Itoa(maxGames))
-		c.Header("X-GameRateLimit-Remaining", strconv.FormatInt(max(0, int64(maxGames)-val), 10))
+		c.Header("X-GameRateLimit-Limit", strconv.Itoa(limit))
+		c.Header("X-GameRateLimit-Remaining", strconv.FormatInt(max(0, int64(limit)-val), 10))
 
-		if val > int64(maxGames) {
-			RLBlocked.WithLabelValues("game:" + c.FullPath()).Inc()
+		if val > int64(limit) {
+			RLBlocked.WithLabelValues("game:" + route).Inc()
 			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
 				"error":       "game rate limit exceeded",
 				"retry_after": int(window.Seconds()),
@@ -62,7 +90,7 @@ func GameRateLimit(maxGames int, window time.Duration) gin.HandlerFunc {
 			return
 		}
 
-		RLRequests.WithLabelValues("game:" + c.FullPath()).Inc()
+		RLRequests.WithLabelValues("game:" + route).Inc()
 		c.Next()
 	}
 }
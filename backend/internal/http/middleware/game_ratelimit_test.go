@@ -0,0 +1,25 @@
+package middleware
+
+import "testing"
+
+func TestGameRouteLimit(t *testing.T) {
+	perRoute := DefaultGameRouteLimits()
+	cases := []struct {
+		path  string
+		route string
+		limit int
+	}{
+		{"/api/v1/game/dice", "dice", 60},
+		{"/api/game/dice", "dice", 60},
+		{"/api/v1/game/coinflip", "coinflip", 60},
+		{"/api/v1/game/mines-pro/start", "mines-pro/start", 60},
+		{"/api/v1/game/mines-pro/reveal", "mines-pro/reveal", 300},
+	}
+	for _, tc := range cases {
+		route, limit := gameRouteLimit(tc.path, 60, perRoute)
+		if route != tc.route || limit != tc.limit {
+			t.Fatalf("%s: expected %s/%d, got %s/%d", tc.path, tc.route, tc.limit, route, limit)
+		}
+	}
+
+}
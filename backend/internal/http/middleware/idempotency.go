@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"telegram_webapp/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyKeyHeader - заголовок, по которому повтор запроса не выполняется второй раз
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const maxIdempotencyKeyLen = 255
+
+// IdempotencyStore keeps responses of requests sent with Idempotency-Key
+// (implemented by repository.IdempotencyRepository)
+type IdempotencyStore interface {
+	// Reserve returns nil if the key was free, otherwise the existing record
+	Reserve(ctx context.Context, userID int64, key, requestHash string, ttl time.Duration) (*domain.IdempotencyRecord, error)
+	Complete(ctx context.Context, userID int64, key string, statusCode int, response []byte) error
+	Release(ctx context.Context, userID int64, key string) error
+}
+
+// bodyRecorder copies the response body so it can be stored for replays
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency replays the stored response when a request is retried with the same
+// Idempotency-Key instead of running the handler (and placing the bet) again.
+// The same key with a different body returns 409. Requests without the header
+// pass through. Must run after JWT.
+func Idempotency(store IdempotencyStore, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "idempotency key too long"})
+			return
+		}
+
+		userIDVal, exists := c.Get("user_id")
+		userID, ok := userIDVal.(int64)
+		if !exists || !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		sum := sha256.New()
+		sum.Write([]byte(c.Request.Method + " " + c.FullPath() + "\n"))
+		sum.Write(body)
+		requestHash := hex.EncodeToString(sum.Sum(nil))
+
+		ctx := c.Request.Context()
+		rec, err := store.Reserve(ctx, userID, key, requestHash, ttl)
+		if err != nil {
+			// Клиент явно просил защиту от дублей - без хранилища ставку не принимаем
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "idempotency store unavailable"})
+			return
+		}
+		if rec != nil {
+			switch {
+			case rec.RequestHash != requestHash:
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "idempotency key reused with a different request"})
+			case rec.Pending():
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "request with this idempotency key is still in progress"})
+			default:
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(rec.StatusCode, "application/json; charset=utf-8", rec.Response)
+				c.Abort()
+			}
+			return
+		}
+
+		rw := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = rw
+		c.Next()
+
+		// Свой контекст: запрос мог быть отменён клиентом уже после ставки
+		saveCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if status := rw.Status(); status >= http.StatusInternalServerError {
+			// Ответ не сохраняем - повтор с тем же ключом выполнится заново
+			store.Release(saveCtx, userID, key)
+		} else {
+			store.Complete(saveCtx, userID, key, status, rw.body.Bytes())
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"telegram_webapp/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+type memIdempotencyStore struct {
+	mu   sync.Mutex
+	recs map[string]*domain.IdempotencyRecord
+}
+
+func (s *memIdempotencyStore) Reserve(_ context.Context, userID int64, key, requestHash string, ttl time.Duration) (*domain.IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rec, ok := s.recs[key]; ok {
+		cp := *rec
+		return &cp, nil
+	}
+	s.recs[key] = &domain.IdempotencyRecord{UserID: userID, Key: key, RequestHash: requestHash, ExpiresAt: time.Now().Add(ttl)}
+	return nil, nil
+}
+
+func (s *memIdempotencyStore) Complete(_ context.Context, _ int64, key string, statusCode int, response []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recs[key].StatusCode = statusCode
+	s.recs[key].Response = append([]byte(nil), response...)
+	return nil
+}
+
+func (s *memIdempotencyStore) Release(_ context.Context, _ int64, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.recs, key)
+	return nil
+}
+
+func TestIdempotencyReplaysResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &memIdempotencyStore{recs: map[string]*domain.IdempotencyRecord{}}
+
+	bets := 0
+	failNext := false
+	r := gin.New()
+	r.POST("/game/dice", func(c *gin.Context) { c.Set("user_id", int64(1)) }, Idempotency(store, time.Minute), func(c *gin.Context) {
+		if failNext {
+			failNext = false
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			return
+		}
+		bets++
+		c.JSON(http.StatusOK, gin.H{"bet": bets})
+	})
+
+	do := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/game/dice", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := do("k1", `{"bet":10}`)
+	retry := do("k1", `{"bet":10}`)
+	if bets != 1 {
+		t.Fatalf("expected one bet, got %d", bets)
+	}
+	if retry.Code != http.StatusOK || retry.Body.String() != first.Body.String() || retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("expected replayed response %q, got %d %q", first.Body.String(), retry.Code, retry.Body.String())
+	}
+
+	if w := do("k1", `{"bet":20}`); w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a different body, got %d", w.Code)
+	}
+
+	// Без ключа каждый запрос - новая ставка
+	do("", `{"bet":10}`)
+	do("", `{"bet":10}`)
+	if bets != 3 {
+		t.Fatalf("expected 3 bets, got %d", bets)
+	}
+
+	// 5xx не сохраняется - повтор выполняется заново
+	failNext = true
+	if w := do("k2", `{"bet":10}`); w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+	if w := do("k2", `{"bet":10}`); w.Code != http.StatusOK || bets != 4 {
+		t.Fatalf("expected retry after 500 to run, got %d, bets %d", w.Code, bets)
+	}
+
+	// Исходный запрос ещё выполняется
+	store.recs["k3"] = &domain.IdempotencyRecord{Key: "k3", RequestHash: store.recs["k2"].RequestHash}
+	if w := do("k3", `{"bet":10}`); w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for in-flight key, got %d", w.Code)
+	}
+}
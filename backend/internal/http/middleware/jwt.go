@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
@@ -28,6 +29,12 @@ func JWT() gin.HandlerFunc {
 		token := parts[1]
 
 		userID, err := service.ParseJWT(token)
+		if errors.Is(err, service.ErrTokenExpired) {
+			// Клиент обновляет токен через /auth/refresh, а не проходит авторизацию заново
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "token_expired"})
+			c.Abort()
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 			c.Abort()
@@ -39,3 +46,17 @@ func JWT() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// OptionalJWT sets user_id when a valid Bearer token is present and lets the request
+// through either way; for public endpoints that add per-user data
+func OptionalJWT() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parts := strings.Split(c.GetHeader("Authorization"), " ")
+		if len(parts) == 2 && parts[0] == "Bearer" {
+			if userID, err := service.ParseJWT(parts[1]); err == nil {
+				c.Set("user_id", userID)
+			}
+		}
+		c.Next()
+	}
+}
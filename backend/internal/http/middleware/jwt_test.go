@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"telegram_webapp/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestJWTMiddleware(t *testing.T) {
+	t.Setenv("JWT_SECRET", "middleware-test-secret")
+	service.InitJWT()
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/me", JWT(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user_id": c.GetInt64("user_id")})
+	})
+
+	valid, err := service.GenerateJWT(42)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	refresh, err := service.GenerateRefreshToken(42)
+	if err != nil {
+		t.Fatalf("generate refresh: %v", err)
+	}
+	expired, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": 42,
+		"typ":     "access",
+		"exp":     time.Now().Add(-time.Minute).Unix(),
+	}).SignedString([]byte("middleware-test-secret"))
+	if err != nil {
+		t.Fatalf("sign expired: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		header string
+		status int
+		error  string
+	}{
+		{"valid", "Bearer " + valid, http.StatusOK, ""},
+		{"expired", "Bearer " + expired, http.StatusUnauthorized, "token_expired"},
+		{"malformed", "Bearer not.a.jwt", http.StatusUnauthorized, "invalid token"},
+		{"refresh token as access", "Bearer " + refresh, http.StatusUnauthorized, "invalid token"},
+		{"missing", "", http.StatusUnauthorized, "missing token"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tc.status {
+			t.Fatalf("%s: expected status %d, got %d (%s)", tc.name, tc.status, w.Code, w.Body.String())
+		}
+		var body map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: decode body: %v", tc.name, err)
+		}
+		if tc.error != "" && body["error"] != tc.error {
+			t.Fatalf("%s: expected error %q, got %v", tc.name, tc.error, body["error"])
+		}
+		if tc.status == http.StatusOK && body["user_id"] != float64(42) {
+			t.Fatalf("%s: expected user 42, got %v", tc.name, body["user_id"])
+		}
+	}
+}
@@ -28,6 +28,19 @@ func InitRedisRateLimiter(addr, password string, db int) {
     }
 }
 
+// RedisEnabled reports whether the rate limiter uses Redis
+func RedisEnabled() bool {
+    return redisClient != nil
+}
+
+// PingRedis checks the shared Redis client; nil if Redis is not configured
+func PingRedis(ctx context.Context) error {
+    if redisClient == nil {
+        return nil
+    }
+    return redisClient.Ping(ctx).Err()
+}
+
 // RedisRateLimit implements a simple fixed-window rate limiter using Redis INCR/EXPIRE.
 // key format: rl:<window_seconds>:<identifier>
 func RedisRateLimit(maxRequests int, window time.Duration) gin.HandlerFunc {
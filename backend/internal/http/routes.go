@@ -1,45 +1,133 @@
 package http
 
 import (
+	"context"
 	"os"
 	"strconv"
 	"time"
 
 	"telegram_webapp/internal/config"
+	"telegram_webapp/internal/game"
 	"telegram_webapp/internal/http/handlers"
 	"telegram_webapp/internal/http/middleware"
+	"telegram_webapp/internal/logger"
 	"telegram_webapp/internal/repository"
+	"telegram_webapp/internal/service"
 	"telegram_webapp/internal/ws"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-// Global reference to ton handler for setting callbacks
-var globalTonHandler *handlers.TonHandler
+// Global reference to API handler for injecting the notifier
+var globalHandler *handlers.Handler
+
+// Global reference to PvP hub for graceful shutdown
+var globalHub *ws.Hub
 
 func RegisterRoutes(r *gin.Engine, db *pgxpool.Pool, botToken string, version string) {
 	RegisterRoutesWithConfig(r, db, botToken, version, nil)
 }
 
-// SetWithdrawalNotifyCallback sets the callback for withdrawal notifications
-func SetWithdrawalNotifyCallback(callback handlers.WithdrawalNotifyFunc) {
-	if globalTonHandler != nil {
-		globalTonHandler.OnWithdrawalCreate = callback
+// ShutdownWS closes PvP connections with a server_shutdown close code
+func ShutdownWS(ctx context.Context) {
+	if globalHub != nil {
+		globalHub.Shutdown(ctx)
+	}
+}
+
+// SetNotifier sets the channel for user and admin notifications (withdrawals, quests)
+func SetNotifier(n service.Notifier) {
+	if globalHandler != nil {
+		globalHandler.Notifier = n
+		globalHandler.Quests.SetNotifier(n)
+	}
+}
+
+// ReloadGameLimits makes game handlers pick up per-game bet limits changed via the admin bot
+func ReloadGameLimits(ctx context.Context) error {
+	if globalHandler == nil {
+		return nil
 	}
+	return globalHandler.GameService.ReloadLimits(ctx)
 }
 
 func RegisterRoutesWithConfig(r *gin.Engine, db *pgxpool.Pool, botToken string, version string, cfg *config.Config) {
 	var h *handlers.Handler
 	if cfg != nil {
 		h = handlers.NewHandlerWithConfig(db, botToken, handlers.HandlerConfig{
-			MinBet: cfg.MinBet,
-			MaxBet: cfg.MaxBet,
+			MinBet:         cfg.MinBet,
+			MaxBet:         cfg.MaxBet,
+			MinBetCoins:    cfg.MinBetCoins,
+			MaxBetCoins:    cfg.MaxBetCoins,
+			FairRNGEnabled: cfg.FairRNGEnabled,
+
+			MinesCount:     cfg.MinesCount,
+			MinesHouseEdge: cfg.MinesHouseEdge,
+
+			MinesProRevealInterval: time.Duration(cfg.MinesProRevealIntervalMs) * time.Millisecond,
+			MinesProAbandonTTL:     time.Duration(cfg.MinesProAbandonTTLMinutes) * time.Minute,
+
+			RPSProMultiplier: cfg.RPSProMultiplier,
+
+			Payouts: service.Payouts{
+				CoinFlip:  cfg.CoinFlipMultiplier,
+				RPS:       cfg.RPSMultiplier,
+				HouseEdge: cfg.PvEHouseEdge,
+			},
+
+			Animations: cfg.GameAnimations,
+			LuckProtection: service.LuckProtectionConfig{
+				Enabled:    cfg.LuckProtectionEnabled,
+				LossStreak: cfg.LuckLossStreak,
+				WinStreak:  cfg.LuckWinStreak,
+				Boost:      cfg.LuckBoostPercent / 100,
+				MaxCredit:  cfg.LuckMaxCredit,
+			},
+			ReferralHoldMode:  cfg.ReferralHoldMode,
+			ReferralHoldGames: cfg.ReferralHoldGames,
+
+			ReferralCommission: cfg.ReferralCommission,
+
+			ComebackInactiveDays: cfg.ComebackInactiveDays,
+			ComebackBonusGems:    cfg.ComebackBonusGems,
+			LowBalanceBonus:      cfg.LowBalanceBonus,
+
+			BonusWagerMultiplier: cfg.BonusWagerMultiplier,
+
+			HappyHours:      cfg.HappyHours,
+			HappyHourMaxRTP: cfg.HappyHourMaxRTP,
+
+			TransferLimits: service.TransferLimits{
+				Min:      cfg.TransferMin,
+				Max:      cfg.TransferMax,
+				DailyCap: cfg.TransferDailyCap,
+
+				MinAccountAge: cfg.TransferMinAccountAge,
+				MinWagered:    cfg.TransferMinWagered,
+			},
+
+			CoinsPerTON: cfg.CoinsPerTON,
+			WithdrawFee: cfg.WithdrawFee,
+
+			SignupGuard: service.SignupGuardConfig{
+				MaxPerIP:    cfg.SignupIPLimit,
+				Window:      time.Duration(cfg.SignupIPWindow) * time.Hour,
+				FlaggedGems: cfg.SignupFlaggedGems,
+			},
+
+			GamesExportMaxRows: cfg.GamesExportMaxRows,
 		})
+		repository.SetMaxMetaBytes(cfg.TxMetaMaxBytes)
+		handlers.SetMaxRequestAmount(cfg.MaxRequestAmount)
 	} else {
 		h = handlers.NewHandler(db, botToken)
 	}
+	globalHandler = h
 	healthHandler := handlers.NewHealthHandler(db, version)
+	if middleware.RedisEnabled() {
+		healthHandler.SetRedis(middleware.PingRedis)
+	}
 
 	// read limits from env, with safe defaults
 	apiRateLimit := 10
@@ -68,6 +156,13 @@ func RegisterRoutesWithConfig(r *gin.Engine, db *pgxpool.Pool, botToken string,
 		}
 	}
 
+	// Тело запроса больше лимита - 413 до разбора JSON
+	maxBodyBytes := int64(middleware.DefaultMaxBodyBytes)
+	if cfg != nil {
+		maxBodyBytes = cfg.MaxBodyBytes
+	}
+	r.Use(middleware.BodyLimit(maxBodyBytes))
+
 	// Health checks (no rate limiting)
 	r.GET("/health", healthHandler.Health)
 	r.GET("/healthz", healthHandler.Liveness)
@@ -76,15 +171,32 @@ func RegisterRoutesWithConfig(r *gin.Engine, db *pgxpool.Pool, botToken string,
 	// Game rate limiting (per user)
 	gameRateLimit := 60
 	gameRateWindow := time.Minute
+	gameRateLimits := middleware.DefaultGameRouteLimits()
 	if cfg != nil {
 		gameRateLimit = cfg.GameRateLimit
 		gameRateWindow = time.Duration(cfg.GameRateWindow) * time.Second
+		for route, n := range cfg.GameRateLimits {
+			gameRateLimits[route] = n
+		}
+	}
+	// Один middleware на обе группы: у /api и /api/v1 общее окно на маршрут
+	gameRL := middleware.GameRateLimit(gameRateLimit, gameRateWindow, gameRateLimits)
+
+	// Повтор ставки с тем же Idempotency-Key возвращает сохранённый ответ
+	idempotencyTTL := time.Hour
+	if cfg != nil {
+		idempotencyTTL = time.Duration(cfg.IdempotencyTTL) * time.Second
 	}
+	idempotencyRepo := repository.NewIdempotencyRepository(db)
+	idem := middleware.Idempotency(idempotencyRepo, idempotencyTTL)
+	go purgeIdempotencyKeys(idempotencyRepo)
+	go reloadGameLimits(h.GameService)
+	go reloadCases(h.Cases)
 
 	// API v1 routes
 	v1 := r.Group("/api/v1")
 	v1.Use(middleware.RedisRateLimit(apiRateLimit, apiRateWindow))
-	registerAPIRoutes(v1, h, authRateLimit, authRateWindow, gameRateLimit, gameRateWindow)
+	registerAPIRoutes(v1, h, authRateLimit, authRateWindow, gameRL, idem)
 
 	// Legacy /api routes (redirect to v1 for backward compatibility)
 	api := r.Group("/api")
@@ -92,14 +204,43 @@ func RegisterRoutesWithConfig(r *gin.Engine, db *pgxpool.Pool, botToken string,
 
 	// Keep old health endpoint for backward compatibility
 	api.GET("/health", healthHandler.Health)
-	registerAPIRoutes(api, h, authRateLimit, authRateWindow, gameRateLimit, gameRateWindow)
+	registerAPIRoutes(api, h, authRateLimit, authRateWindow, gameRL, idem)
 
 	// WebSocket for PvP games
 	gameRepo := repository.NewGameRepository(db)
 	gameHistoryRepo := repository.NewGameHistoryRepository(db)
-	hub := ws.NewHub(gameRepo, gameHistoryRepo)
+	hub := ws.NewHubWithUserRepo(gameRepo, gameHistoryRepo, h.UserRepo)
+	hub.BonusRepo = h.BonusRepo
+	hub.Quests = h.Quests
+	hub.Balance = service.NewBalanceService(db)
+	wsMaxRooms := 1000
+	hub.SetDefaultMatchTimeout(60 * time.Second)
+	hub.SetTurnWarning(3 * time.Second)
+	hub.SetRematchWindow(15 * time.Second)
+	hub.SetReconnectGrace(15 * time.Second)
+	hub.SetMaxConnsPerUser(3)
+	if cfg != nil {
+		wsMaxRooms = cfg.WSMaxRooms
+		hub.SetDefaultMatchTimeout(time.Duration(cfg.WSMatchTimeout) * time.Second)
+		for gameType, secs := range cfg.WSMatchTimeoutByGame {
+			hub.SetMatchTimeout(game.GameType(gameType), time.Duration(secs)*time.Second)
+		}
+		hub.SetBotFallback(time.Duration(cfg.WSBotFallback) * time.Second)
+		hub.SetTurnWarning(time.Duration(cfg.WSTurnWarning) * time.Second)
+		hub.SetRematchWindow(time.Duration(cfg.WSRematchWindow) * time.Second)
+		hub.SetReconnectGrace(time.Duration(cfg.WSReconnectGrace) * time.Second)
+		hub.SetAllowedOrigins(cfg.WSAllowedOrigins)
+		hub.SetAllowQueryToken(cfg.WSAllowQueryToken)
+		hub.SetMaxConnsPerUser(cfg.WSMaxConnsPerUser)
+		hub.SetRake(cfg.RakePercent, cfg.RakeAccountID)
+		hub.SetMinesConfig(game.MinesConfig{Cells: cfg.MinesPvPCells, Mines: cfg.MinesPvPMines})
+	}
 	hub.StartCleanup()
+	globalHub = hub
+	healthHandler.SetHub(hub, wsMaxRooms)
 	r.GET("/ws", h.WS(hub))
+	v1.GET("/ws/queue-stats", h.WSQueueStats(hub))
+	v1.GET("/matchmaking/stats", h.MatchmakingStats(hub))
 
 	// Frontend static files
 	r.StaticFS("/assets", gin.Dir("../frontend", false))
@@ -108,57 +249,88 @@ func RegisterRoutesWithConfig(r *gin.Engine, db *pgxpool.Pool, botToken string,
 	})
 }
 
-func registerAPIRoutes(api *gin.RouterGroup, h *handlers.Handler, authRateLimit int, authRateWindow time.Duration, gameRateLimit int, gameRateWindow time.Duration) {
+func registerAPIRoutes(api *gin.RouterGroup, h *handlers.Handler, authRateLimit int, authRateWindow time.Duration, gameRL, idem gin.HandlerFunc) {
 	// Auth
 	api.POST("/auth", middleware.RedisRateLimit(authRateLimit, authRateWindow), h.Auth)
+	api.POST("/auth/refresh", middleware.RedisRateLimit(authRateLimit, authRateWindow), h.RefreshToken)
+
+	// Заблокированным запрещены все операции с балансом
+	notBanned := middleware.CheckBanned(h.UserRepo.IsBanned)
+	// Самоисключение блокирует ставки и вывод (cashout начатой игры разрешён)
+	notExcluded := middleware.CheckSelfExcluded(h.UserRepo.ExcludedUntil)
 
 	// User profile
 	api.GET("/me", middleware.JWT(), h.Me)
 	api.GET("/profile", middleware.JWT(), h.MyProfile)
-	api.POST("/profile/balance", middleware.JWT(), h.UpdateBalance)
-	api.POST("/profile/bonus", middleware.JWT(), h.ClaimBonus)
+	api.POST("/profile/balance", middleware.JWT(), notBanned, h.UpdateBalance)
+	api.POST("/profile/bonus", middleware.JWT(), notBanned, h.ClaimBonus)
+	api.POST("/profile/self-exclude", middleware.JWT(), h.SelfExclude)
+	api.POST("/profile/transfer", middleware.JWT(), notBanned, notExcluded, middleware.GameRateLimitByType("transfer", 5, time.Minute), h.Transfer)
 	api.GET("/profile/:id", h.Profile)
 
+	// Notification preferences
+	api.GET("/me/notifications", middleware.JWT(), h.GetNotificationPrefs)
+	api.PUT("/me/notifications", middleware.JWT(), h.UpdateNotificationPrefs)
+
+	// Support: жалобы на рассинхрон клиента
+	api.POST("/support/report", middleware.JWT(), h.CreateSupportReport)
+
 	// History
 	api.POST("/history", middleware.JWT(), h.AddHistory)
 	api.GET("/history", middleware.JWT(), h.GetHistory)
 
 	// Games history and stats
 	api.GET("/me/games", middleware.JWT(), h.MyGames)
+	api.GET("/me/games/export", middleware.JWT(), h.ExportMyGames)
+	api.GET("/me/active-games", middleware.JWT(), h.MyActiveGames)
+	api.GET("/me/pnl", middleware.JWT(), h.MyPnL)
+	api.GET("/me/stats", middleware.JWT(), h.MyStats)
 	api.GET("/top", h.TopUsers)
 
-	// Game rate limiter middleware (per user, not per IP)
-	gameRL := middleware.GameRateLimit(gameRateLimit, gameRateWindow)
-
+	// gameRL - лимит на пользователя и маршрут (не на IP)
 	// Server-side game endpoints (PvE) with game rate limiting
-	api.POST("/game/coinflip", middleware.JWT(), gameRL, h.CoinFlip)
-	api.POST("/game/rps", middleware.JWT(), gameRL, h.RPS)
-	api.POST("/game/mines", middleware.JWT(), gameRL, h.Mines)
-	api.POST("/game/case", middleware.JWT(), gameRL, h.CaseSpin)
+	api.POST("/game/coinflip", middleware.JWT(), notBanned, notExcluded, gameRL, idem, h.CoinFlip)
+	api.POST("/game/rps", middleware.JWT(), notBanned, notExcluded, gameRL, idem, h.RPS)
+	api.POST("/game/mines", middleware.JWT(), notBanned, notExcluded, gameRL, idem, h.Mines)
+	api.GET("/game/mines/info", h.MinesInfo)
+	api.POST("/game/case", middleware.JWT(), notBanned, notExcluded, gameRL, idem, h.CaseSpin)
+	api.GET("/game/case/info", h.CaseInfo)
 
 	// New PvE games with game rate limiting
-	api.POST("/game/dice", middleware.JWT(), gameRL, h.Dice)
+	api.POST("/game/dice", middleware.JWT(), notBanned, notExcluded, gameRL, idem, h.Dice)
 	api.GET("/game/dice/info", h.DiceInfo)
-	api.POST("/game/wheel", middleware.JWT(), gameRL, h.Wheel)
+	api.POST("/game/wheel", middleware.JWT(), notBanned, notExcluded, gameRL, idem, h.Wheel)
 	api.GET("/game/wheel/info", h.WheelInfo)
 
 	// Mines Pro (advanced multi-round mines) with game rate limiting
-	api.POST("/game/mines-pro/start", middleware.JWT(), gameRL, h.MinesProStart)
-	api.POST("/game/mines-pro/reveal", middleware.JWT(), gameRL, h.MinesProReveal)
-	api.POST("/game/mines-pro/cashout", middleware.JWT(), h.MinesProCashOut)
+	api.POST("/game/mines-pro/start", middleware.JWT(), notBanned, notExcluded, gameRL, idem, h.MinesProStart)
+	api.POST("/game/mines-pro/reveal", middleware.JWT(), notBanned, notExcluded, gameRL, idem, h.MinesProReveal)
+	api.POST("/game/mines-pro/cashout", middleware.JWT(), notBanned, idem, h.MinesProCashOut)
 	api.GET("/game/mines-pro/state", middleware.JWT(), h.MinesProState)
 	api.GET("/game/mines-pro/info", h.MinesProInfo)
 
 	// CoinFlip Pro (multi-round coinflip) with game rate limiting
-	api.POST("/game/coinflip-pro/start", middleware.JWT(), gameRL, h.CoinFlipProStart)
-	api.POST("/game/coinflip-pro/flip", middleware.JWT(), gameRL, h.CoinFlipProFlip)
-	api.POST("/game/coinflip-pro/cashout", middleware.JWT(), h.CoinFlipProCashOut)
+	api.POST("/game/coinflip-pro/start", middleware.JWT(), notBanned, notExcluded, gameRL, idem, h.CoinFlipProStart)
+	api.POST("/game/coinflip-pro/flip", middleware.JWT(), notBanned, notExcluded, gameRL, idem, h.CoinFlipProFlip)
+	api.POST("/game/coinflip-pro/cashout", middleware.JWT(), notBanned, idem, h.CoinFlipProCashOut)
 	api.GET("/game/coinflip-pro/state", middleware.JWT(), h.CoinFlipProState)
-	api.GET("/game/coinflip-pro/info", h.CoinFlipProInfo)
+	api.GET("/game/coinflip-pro/info", middleware.OptionalJWT(), h.CoinFlipProInfo)
+
+	// RPS Pro (best-of-3/5 against the bot) with game rate limiting
+	api.POST("/game/rps-pro/start", middleware.JWT(), notBanned, notExcluded, gameRL, idem, h.RPSProStart)
+	api.POST("/game/rps-pro/move", middleware.JWT(), notBanned, notExcluded, gameRL, idem, h.RPSProMove)
+	api.GET("/game/rps-pro/state", middleware.JWT(), h.RPSProState)
+
+	// Provably fair: commitment of the next games, seed rotation (reveal)
+	api.GET("/fair/seed", middleware.JWT(), h.FairSeed)
+	api.POST("/fair/rotate", middleware.JWT(), h.FairRotate)
 
 	// Game limits info endpoint
 	api.GET("/game/limits", h.GameLimits)
 
+	// Currencies, where they are accepted and exchange paths
+	api.GET("/currencies", h.Currencies)
+
 	// Tasks (old system)
 	api.GET("/tasks", h.ListTasks)
 	api.POST("/tasks", middleware.JWT(), h.CreateTask)
@@ -167,7 +339,8 @@ func registerAPIRoutes(api *gin.RouterGroup, h *handlers.Handler, authRateLimit
 	// Quests (new reward system)
 	api.GET("/quests", h.GetQuests)
 	api.GET("/me/quests", middleware.JWT(), h.GetMyQuests)
-	api.POST("/quests/:id/claim", middleware.JWT(), h.ClaimQuestReward)
+	api.GET("/me/quests/:id", middleware.JWT(), h.GetMyQuest)
+	api.POST("/quests/:id/claim", middleware.JWT(), notBanned, h.ClaimQuestReward)
 
 	// Referral system
 	referralRepo := repository.NewReferralRepository(h.DB)
@@ -179,13 +352,14 @@ func registerAPIRoutes(api *gin.RouterGroup, h *handlers.Handler, authRateLimit
 	if webAppShortName == "" {
 		webAppShortName = "app"
 	}
-	referralHandler := handlers.NewReferralHandler(referralRepo, botUsername, webAppShortName)
+	referralHandler := handlers.NewReferralHandler(referralRepo, h.ReferralRewards, botUsername, webAppShortName)
 	referral := api.Group("/referral")
 	referral.Use(middleware.JWT())
 	{
 		referral.GET("/code", referralHandler.GetReferralCode)
 		referral.GET("/link", referralHandler.GetReferralLink)
 		referral.GET("/stats", referralHandler.GetReferralStats)
+		referral.GET("/tree", referralHandler.GetReferralTree)
 		referral.POST("/apply", referralHandler.ApplyReferralCode)
 	}
 
@@ -196,17 +370,18 @@ func registerAPIRoutes(api *gin.RouterGroup, h *handlers.Handler, authRateLimit
 	{
 		upgrade.GET("/info", upgradeHandler.GetUpgradeInfo)
 		upgrade.GET("/status", middleware.JWT(), upgradeHandler.GetMyUpgradeStatus)
-		upgrade.POST("/level", middleware.JWT(), upgradeHandler.UpgradeCharacter)
-		upgrade.POST("/claim-reward", middleware.JWT(), upgradeHandler.ClaimReferralReward)
+		upgrade.POST("/level", middleware.JWT(), notBanned, upgradeHandler.UpgradeCharacter)
+		upgrade.POST("/claim-reward", middleware.JWT(), notBanned, upgradeHandler.ClaimReferralReward)
 	}
 
 	// Leaderboard (monthly top 100 + user rank)
 	api.GET("/leaderboard", h.GetLeaderboard)
 	api.GET("/leaderboard/rank", middleware.JWT(), h.GetMyRank)
+	api.GET("/me/privacy", middleware.JWT(), h.GetLeaderboardPrivacy)
+	api.PUT("/me/privacy", middleware.JWT(), h.UpdateLeaderboardPrivacy)
 
 	// TON Connect & Payments
 	tonHandler := handlers.NewTonHandler(h)
-	globalTonHandler = tonHandler // Store for callback setup
 	ton := api.Group("/ton")
 	{
 		// Wallet management
@@ -218,16 +393,59 @@ func registerAPIRoutes(api *gin.RouterGroup, h *handlers.Handler, authRateLimit
 		// Deposits
 		ton.GET("/deposit/info", middleware.JWT(), tonHandler.GetDepositInfo)
 		ton.GET("/deposits", middleware.JWT(), tonHandler.GetDeposits)
-		ton.POST("/deposit/manual", middleware.JWT(), func(c *gin.Context) {
+		ton.POST("/deposit/manual", middleware.JWT(), notBanned, func(c *gin.Context) {
 			tonHandler.RecordManualDeposit(c, h)
 		})
 
 		// Withdrawals
 		ton.POST("/withdraw/estimate", middleware.JWT(), tonHandler.GetWithdrawEstimate)
-		ton.POST("/withdraw", middleware.JWT(), func(c *gin.Context) {
+		ton.POST("/withdraw", middleware.JWT(), notBanned, notExcluded, func(c *gin.Context) {
 			tonHandler.RequestWithdrawal(c, nil)
 		})
 		ton.GET("/withdrawals", middleware.JWT(), tonHandler.GetWithdrawals)
-		ton.POST("/withdraw/cancel", middleware.JWT(), tonHandler.CancelWithdrawal)
+		ton.POST("/withdraw/cancel", middleware.JWT(), notBanned, tonHandler.CancelWithdrawal)
+	}
+}
+
+// purgeIdempotencyKeys periodically deletes expired Idempotency-Key responses
+func purgeIdempotencyKeys(repo *repository.IdempotencyRepository) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		if _, err := repo.DeleteExpired(ctx); err != nil {
+			logger.Warn("idempotency keys cleanup failed", "error", err)
+		}
+		cancel()
+	}
+}
+
+// reloadCases loads case_configs at startup and then periodically, so tuned
+// odds are picked up without a restart
+func reloadCases(cs *service.CaseService) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := cs.Reload(ctx); err != nil {
+			logger.Warn("case configs reload failed", "error", err)
+		}
+		cancel()
+		<-ticker.C
+	}
+}
+
+// reloadGameLimits loads per-game bet limits at startup and then periodically,
+// so overrides set on another instance are picked up too
+func reloadGameLimits(gs *service.GameService) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := gs.ReloadLimits(ctx); err != nil {
+			logger.Warn("game limits reload failed", "error", err)
+		}
+		cancel()
+		<-ticker.C
 	}
 }
@@ -0,0 +1,73 @@
+// Package metrics holds the Prometheus collectors for game outcomes.
+// Operators derive RTP as 1 - game_house_profit / game_wagered_total.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	GamesPlayed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "games_played_total",
+			Help: "Finished games by game type, result and currency",
+		},
+		[]string{"game", "result", "currency"},
+	)
+	GameBetAmount = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "game_bet_amount",
+			Help: "Bet size per game",
+			// 1 .. ~260k: покрывает и coins (от 1), и gems (до 100000)
+			Buckets: prometheus.ExponentialBuckets(1, 4, 10),
+		},
+		[]string{"game"},
+	)
+	GameWagered = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "game_wagered_total",
+			Help: "Sum of bets by game type and currency",
+		},
+		[]string{"game", "currency"},
+	)
+	// Может уходить в минус, поэтому gauge, а не counter
+	GameHouseProfit = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "game_house_profit",
+			Help: "Net house profit (bets minus payouts) by game type and currency",
+		},
+		[]string{"game", "currency"},
+	)
+	PvPRake = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pvp_rake_total",
+			Help: "House cut credited to the rake account by game type and currency",
+		},
+		[]string{"game", "currency"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(GamesPlayed, GameBetAmount, GameWagered, GameHouseProfit, PvPRake)
+}
+
+// ObserveGame records a finished game. net is the player's result net of the
+// bet (winAmount in game_history), so the house earns -net.
+func ObserveGame(game, result, currency string, bet, net int64) {
+	GamesPlayed.WithLabelValues(game, result, currency).Inc()
+	if bet <= 0 {
+		return
+	}
+	GameBetAmount.WithLabelValues(game).Observe(float64(bet))
+	GameWagered.WithLabelValues(game, currency).Add(float64(bet))
+	GameHouseProfit.WithLabelValues(game, currency).Add(float64(-net))
+}
+
+// ObserveRake records the house cut taken from a PvP pot. It is already part of
+// game_house_profit through the players' net results.
+func ObserveRake(game, currency string, amount int64) {
+	if amount <= 0 {
+		return
+	}
+	PvPRake.WithLabelValues(game, currency).Add(float64(amount))
+}
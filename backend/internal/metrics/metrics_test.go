@@ -0,0 +1,24 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserveGame(t *testing.T) {
+	ObserveGame("dice", "win", "gems", 100, 450)
+	ObserveGame("dice", "lose", "gems", 100, -100)
+	ObserveGame("dice", "lose", "gems", 200, -200)
+
+	if got := testutil.ToFloat64(GamesPlayed.WithLabelValues("dice", "lose", "gems")); got != 2 {
+		t.Fatalf("expected 2 lost games, got %v", got)
+	}
+	if got := testutil.ToFloat64(GameWagered.WithLabelValues("dice", "gems")); got != 400 {
+		t.Fatalf("expected 400 wagered, got %v", got)
+	}
+	// Выигрыш 450 минус проигранные 300
+	if got := testutil.ToFloat64(GameHouseProfit.WithLabelValues("dice", "gems")); got != -150 {
+		t.Fatalf("expected house profit -150, got %v", got)
+	}
+}
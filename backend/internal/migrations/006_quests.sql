@@ -86,4 +86,6 @@ INSERT INTO quests (quest_type, title, description, game_type, action_type, targ
     ('one_time', 'Первая победа', 'Одержи свою первую победу', 'any', 'win', 1, 200, 101),
     ('one_time', 'Первый кейс', 'Открой свой первый кейс', 'case', 'play', 1, 50, 102),
     ('one_time', 'Опытный игрок', 'Сыграй 100 игр', 'any', 'play', 100, 1000, 103),
-    ('one_time', 'Легенда', 'Одержи 50 побед', 'any', 'win', 50, 2000, 104);
+    ('one_time', 'Легенда', 'Одержи 50 побед', 'any', 'win', 50, 2000, 104)
+-- После 017 на quests есть quests_unique_definition: повторный прогон не дублирует задания
+ON CONFLICT DO NOTHING;
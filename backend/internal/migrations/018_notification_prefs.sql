@@ -0,0 +1,18 @@
+-- Per-category notification preferences
+CREATE TABLE IF NOT EXISTS notification_prefs (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    big_win BOOLEAN NOT NULL DEFAULT true,
+    withdrawal BOOLEAN NOT NULL DEFAULT true,
+    quest BOOLEAN NOT NULL DEFAULT true,
+    promo BOOLEAN NOT NULL DEFAULT true,
+    streak_reminder BOOLEAN NOT NULL DEFAULT false,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Existing users get the default preferences
+INSERT INTO notification_prefs (user_id)
+SELECT id FROM users
+ON CONFLICT (user_id) DO NOTHING;
+
+COMMENT ON TABLE notification_prefs IS 'Настройки уведомлений пользователя по категориям';
+COMMENT ON COLUMN notification_prefs.streak_reminder IS 'Напоминания о серии выключены по умолчанию';
@@ -0,0 +1,8 @@
+-- Deposit watcher progress: highest processed logical time per watched address
+CREATE TABLE IF NOT EXISTS deposit_watcher_state (
+    address VARCHAR(100) PRIMARY KEY,
+    last_lt BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE deposit_watcher_state IS 'Последний обработанный lt входящих транзакций платформенного кошелька';
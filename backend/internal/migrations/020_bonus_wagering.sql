@@ -0,0 +1,16 @@
+-- Bonus wagering ledger: bonus funds stay locked until wagered N times
+CREATE TABLE IF NOT EXISTS bonus_wagering (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    currency VARCHAR(10) NOT NULL DEFAULT 'coins',
+    source VARCHAR(50) NOT NULL,          -- admin_bonus, deposit_bonus, ...
+    bonus_amount BIGINT NOT NULL,         -- сколько бонуса заблокировано
+    wager_required BIGINT NOT NULL,       -- bonus_amount * множитель
+    wagered BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_bonus_wagering_open ON bonus_wagering(user_id, currency) WHERE completed_at IS NULL;
+
+COMMENT ON TABLE bonus_wagering IS 'Требования по отыгрышу бонусов; незавершённые бонусы нельзя вывести';
@@ -0,0 +1,17 @@
+-- Client-reported desync (balance, game state) for support
+CREATE TABLE IF NOT EXISTS support_reports (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category VARCHAR(32) NOT NULL,
+    message TEXT NOT NULL DEFAULT '',
+    client_state JSONB,
+    server_state JSONB NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'open',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_support_reports_user_created ON support_reports(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_support_reports_created ON support_reports(created_at DESC);
+
+COMMENT ON TABLE support_reports IS 'Жалобы на рассинхрон клиента и сервера';
+COMMENT ON COLUMN support_reports.server_state IS 'Снимок баланса на сервере в момент жалобы';
@@ -0,0 +1,17 @@
+-- Only one pending/processing withdrawal per user (protects against concurrent requests)
+
+-- Duplicates created by the old race: keep the oldest, cancel the rest (coins were not deducted yet)
+UPDATE ton_withdrawals w
+SET status = 'cancelled',
+    admin_notes = COALESCE(admin_notes || E'\n', '') || 'auto-cancelled: duplicate pending withdrawal'
+WHERE w.status IN ('pending', 'processing')
+  AND EXISTS (
+      SELECT 1 FROM ton_withdrawals o
+      WHERE o.user_id = w.user_id
+        AND o.status IN ('pending', 'processing')
+        AND o.id < w.id
+  );
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ton_withdrawals_one_pending
+    ON ton_withdrawals(user_id)
+    WHERE status IN ('pending', 'processing');
@@ -0,0 +1,4 @@
+-- Last login for the comeback bonus (existing users count as active at migration time)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS last_login_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+COMMENT ON COLUMN users.last_login_at IS 'Последний вход через /auth, для бонуса за возвращение';
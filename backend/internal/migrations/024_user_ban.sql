@@ -0,0 +1,10 @@
+-- Dedicated ban flag instead of the gems = -1 marker
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_banned BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS banned_at TIMESTAMPTZ;
+
+-- Переносим старые баны; реальный баланс маркер уже затёр, восстанавливаем 0
+UPDATE users SET is_banned = true, banned_at = now(), gems = 0 WHERE gems = -1;
+
+CREATE INDEX IF NOT EXISTS idx_users_banned ON users(id) WHERE is_banned;
+
+COMMENT ON COLUMN users.is_banned IS 'Заблокирован админом: вход и операции с балансом запрещены';
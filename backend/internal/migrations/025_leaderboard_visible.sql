@@ -0,0 +1,4 @@
+-- Users can hide their name on public leaderboards; rank still counts
+ALTER TABLE users ADD COLUMN IF NOT EXISTS leaderboard_visible BOOLEAN NOT NULL DEFAULT true;
+
+COMMENT ON COLUMN users.leaderboard_visible IS 'false - в топах вместо имени показывается Anonymous Player';
@@ -0,0 +1,15 @@
+-- Responses of game requests sent with Idempotency-Key, replayed on client retries
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id      BIGINT       NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key          VARCHAR(255) NOT NULL,
+    request_hash CHAR(64)     NOT NULL,
+    status_code  INT,
+    response     BYTEA,
+    created_at   TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    expires_at   TIMESTAMPTZ  NOT NULL,
+    PRIMARY KEY (user_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires ON idempotency_keys(expires_at);
+
+COMMENT ON COLUMN idempotency_keys.status_code IS 'NULL - исходный запрос ещё выполняется';
@@ -0,0 +1,12 @@
+-- Wheel of fortune segments; an empty table means the built-in defaults are used
+CREATE TABLE IF NOT EXISTS wheel_segments (
+    id         SERIAL           PRIMARY KEY,
+    multiplier DOUBLE PRECISION NOT NULL CHECK (multiplier >= 0),
+    color      VARCHAR(16)      NOT NULL,
+    label      VARCHAR(32)      NOT NULL,
+    weight     INT              NOT NULL CHECK (weight >= 0),
+    active     BOOLEAN          NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ      NOT NULL DEFAULT now()
+);
+
+COMMENT ON COLUMN wheel_segments.weight IS 'Вероятность = weight / сумма весов активных сегментов';
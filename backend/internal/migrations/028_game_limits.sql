@@ -0,0 +1,9 @@
+-- Per-game bet limit overrides set from the admin bot (/setlimits)
+CREATE TABLE IF NOT EXISTS game_limits (
+    game_type  VARCHAR(50) NOT NULL,
+    currency   VARCHAR(10) NOT NULL,
+    min_bet    BIGINT      NOT NULL CHECK (min_bet > 0),
+    max_bet    BIGINT      NOT NULL CHECK (max_bet >= min_bet),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (game_type, currency)
+);
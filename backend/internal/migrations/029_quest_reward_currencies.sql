@@ -0,0 +1,10 @@
+-- Quest rewards in coins and GK (columns added in 016 were nullable)
+UPDATE quests SET reward_coins = 0 WHERE reward_coins IS NULL;
+UPDATE quests SET reward_gk = 0 WHERE reward_gk IS NULL;
+ALTER TABLE quests ALTER COLUMN reward_coins SET NOT NULL;
+ALTER TABLE quests ALTER COLUMN reward_gk SET NOT NULL;
+
+-- Actually credited amounts (happy hour included)
+ALTER TABLE user_quests ADD COLUMN IF NOT EXISTS claimed_gems BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE user_quests ADD COLUMN IF NOT EXISTS claimed_coins BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE user_quests ADD COLUMN IF NOT EXISTS claimed_gk BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+-- Responsible gambling: user locks themselves out of games and withdrawals until this time
+ALTER TABLE users ADD COLUMN IF NOT EXISTS excluded_until TIMESTAMPTZ;
@@ -0,0 +1,20 @@
+-- Mines Pro games survive restarts: the bet is locked while a game is active
+CREATE TABLE IF NOT EXISTS mines_pro_games (
+    id             VARCHAR(16)  PRIMARY KEY,
+    user_id        BIGINT       NOT NULL REFERENCES users(id),
+    board_size     INT          NOT NULL,
+    mines_count    INT          NOT NULL,
+    bet            BIGINT       NOT NULL CHECK (bet > 0),
+    currency       VARCHAR(10)  NOT NULL DEFAULT 'gems',
+    mines          INT[]        NOT NULL,
+    revealed_cells INT[]        NOT NULL DEFAULT '{}',
+    status         VARCHAR(20)  NOT NULL DEFAULT 'active', -- active, cashed_out, exploded, refunded
+    win_amount     BIGINT       NOT NULL DEFAULT 0,
+    created_at     TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    last_reveal_at TIMESTAMPTZ,
+    finished_at    TIMESTAMPTZ
+);
+
+-- Не больше одной активной игры на пользователя
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mines_pro_games_active_user
+    ON mines_pro_games (user_id) WHERE status = 'active';
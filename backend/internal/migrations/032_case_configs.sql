@@ -0,0 +1,22 @@
+-- Кейсы с ценой и таблицей призов; пустая таблица - встроенный кейс classic
+CREATE TABLE IF NOT EXISTS case_configs (
+    case_id    VARCHAR(32)  PRIMARY KEY,
+    name       VARCHAR(64)  NOT NULL,
+    cost       BIGINT       NOT NULL CHECK (cost > 0),
+    prizes     JSONB        NOT NULL,
+    active     BOOLEAN      NOT NULL DEFAULT TRUE,
+    sort_order INT          NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+COMMENT ON COLUMN case_configs.prizes IS '[{"id": 1, "amount": 250, "probability": 0.5, "rarity": "Common"}, ...], сумма probability должна быть 1';
+
+-- Прежний захардкоженный кейс
+INSERT INTO case_configs (case_id, name, cost, prizes) VALUES (
+    'classic', 'Classic', 100,
+    '[{"id": 1, "amount": 250, "probability": 0.5, "rarity": "Common"},
+      {"id": 2, "amount": 500, "probability": 0.2, "rarity": "Uncommon"},
+      {"id": 3, "amount": 750, "probability": 0.15, "rarity": "Rare"},
+      {"id": 4, "amount": 1000, "probability": 0.10, "rarity": "Epic"},
+      {"id": 5, "amount": 5000, "probability": 0.05, "rarity": "Legendary"}]'
+) ON CONFLICT (case_id) DO NOTHING;
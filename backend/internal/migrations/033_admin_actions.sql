@@ -0,0 +1,15 @@
+-- Журнал действий админов через бота: кто, что и с кем сделал
+CREATE TABLE IF NOT EXISTS admin_actions (
+    id             BIGSERIAL   PRIMARY KEY,
+    admin_tg_id    BIGINT      NOT NULL,
+    action         VARCHAR(50) NOT NULL,
+    target_user_id BIGINT      REFERENCES users(id) ON DELETE SET NULL,
+    details        JSONB       NOT NULL DEFAULT '{}',
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_actions_created_at ON admin_actions(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_admin_actions_admin ON admin_actions(admin_tg_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_admin_actions_target ON admin_actions(target_user_id) WHERE target_user_id IS NOT NULL;
+
+COMMENT ON COLUMN admin_actions.target_user_id IS 'users.id затронутого пользователя; NULL для квестов и лимитов';
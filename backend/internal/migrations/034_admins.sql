@@ -0,0 +1,6 @@
+-- Админы, добавленные через /addadmin; ADMIN_TELEGRAM_IDS из env действуют всегда и сюда не пишутся
+CREATE TABLE IF NOT EXISTS admins (
+    tg_id      BIGINT      PRIMARY KEY,
+    added_by   BIGINT      NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
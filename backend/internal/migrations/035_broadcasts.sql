@@ -0,0 +1,21 @@
+-- Рассылки админов: отложенные ждут scheduled_at, итоги доставки сохраняются после отправки
+CREATE TABLE IF NOT EXISTS broadcasts (
+    id            BIGSERIAL    PRIMARY KEY,
+    admin_tg_id   BIGINT       NOT NULL,
+    segment       VARCHAR(64)  NOT NULL DEFAULT 'all',
+    text          TEXT         NOT NULL DEFAULT '',
+    photo_file_id VARCHAR(255) NOT NULL DEFAULT '',
+    status        VARCHAR(16)  NOT NULL DEFAULT 'scheduled' CHECK (status IN ('scheduled', 'sending', 'done', 'cancelled', 'failed')),
+    scheduled_at  TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    started_at    TIMESTAMPTZ,
+    finished_at   TIMESTAMPTZ,
+    total         INT          NOT NULL DEFAULT 0,
+    delivered     INT          NOT NULL DEFAULT 0,
+    blocked       INT          NOT NULL DEFAULT 0,
+    errored       INT          NOT NULL DEFAULT 0,
+    created_at    TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_broadcasts_due ON broadcasts(scheduled_at) WHERE status = 'scheduled';
+
+COMMENT ON COLUMN broadcasts.segment IS 'Аудитория в формате /broadcast: all, inactive 7, top 100, nodeposit, level 3-5';
@@ -0,0 +1,8 @@
+-- Прогресс рассылки: после падения отправка продолжается с progress, а не с начала
+ALTER TABLE broadcasts ADD COLUMN IF NOT EXISTS progress INT NOT NULL DEFAULT 0;
+ALTER TABLE broadcasts ADD COLUMN IF NOT EXISTS progress_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_broadcasts_sending ON broadcasts(progress_at) WHERE status = 'sending';
+
+COMMENT ON COLUMN broadcasts.progress IS 'Сколько получателей с начала списка уже обработано';
+COMMENT ON COLUMN broadcasts.progress_at IS 'Последнее сохранение прогресса; давно не обновлялось при status = sending - отправитель упал';
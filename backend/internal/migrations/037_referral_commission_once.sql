@@ -0,0 +1,16 @@
+-- Комиссия рефереру начисляется один раз на вывод (повтор запроса не платит дважды)
+
+-- Дубли, если успели появиться: оставляем самую раннюю запись
+DELETE FROM transactions t
+WHERE t.type = 'referral_commission'
+  AND t.meta ? 'withdrawal_id'
+  AND EXISTS (
+      SELECT 1 FROM transactions o
+      WHERE o.type = 'referral_commission'
+        AND o.meta->>'withdrawal_id' = t.meta->>'withdrawal_id'
+        AND o.id < t.id
+  );
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_referral_commission_withdrawal
+    ON transactions((meta->>'withdrawal_id'))
+    WHERE type = 'referral_commission';
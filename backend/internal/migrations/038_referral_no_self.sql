@@ -0,0 +1,20 @@
+-- Нельзя пригласить самого себя: проверка на уровне БД, циклы отсекает CreateReferral
+
+DELETE FROM referrals WHERE referrer_id = referred_id;
+UPDATE users SET referred_by = NULL WHERE referred_by = id;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint WHERE conname = 'referrals_not_self'
+    ) THEN
+        ALTER TABLE referrals
+            ADD CONSTRAINT referrals_not_self CHECK (referrer_id <> referred_id);
+    END IF;
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint WHERE conname = 'users_referred_by_not_self'
+    ) THEN
+        ALTER TABLE users
+            ADD CONSTRAINT users_referred_by_not_self CHECK (referred_by <> id);
+    END IF;
+END $$;
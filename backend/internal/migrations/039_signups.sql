@@ -0,0 +1,15 @@
+-- Регистрации: IP и auth_date на момент создания аккаунта, для поиска фермы аккаунтов
+CREATE TABLE IF NOT EXISTS signups (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    ip TEXT NOT NULL,
+    auth_date TIMESTAMPTZ,
+    shared_ip_count INT NOT NULL DEFAULT 0,
+    flagged BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_signups_ip_created ON signups(ip, created_at);
+CREATE INDEX IF NOT EXISTS idx_signups_flagged ON signups(created_at DESC) WHERE flagged;
+
+COMMENT ON COLUMN signups.shared_ip_count IS 'Сколько других аккаунтов зарегистрировано с этого IP за окно SIGNUP_IP_WINDOW_HOURS';
+COMMENT ON COLUMN signups.flagged IS 'Превышен SIGNUP_IP_LIMIT: стартовый баланс урезан до SIGNUP_FLAGGED_GEMS';
@@ -0,0 +1,14 @@
+-- Режим игры: pve - против казино (кейсы, dice, wheel, бот в WS), pvp - против игрока.
+-- Кейсы раньше писались как solo.
+
+UPDATE game_history SET mode = 'pve' WHERE mode NOT IN ('pvp', 'pve');
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint WHERE conname = 'game_history_mode_check'
+    ) THEN
+        ALTER TABLE game_history
+            ADD CONSTRAINT game_history_mode_check CHECK (mode IN ('pvp', 'pve'));
+    END IF;
+END $$;
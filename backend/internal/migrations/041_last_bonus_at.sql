@@ -0,0 +1,2 @@
+-- Время последнего бонуса при низком балансе, для кулдауна BONUS_COOLDOWN_HOURS
+ALTER TABLE users ADD COLUMN IF NOT EXISTS last_bonus_at TIMESTAMPTZ;
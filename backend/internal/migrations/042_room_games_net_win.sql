@@ -0,0 +1,13 @@
+-- Игры из WS-комнат (PvP и бот) писали win_amount брутто: выплату победителю,
+-- 0 проигравшему, ставку при ничьей. Приводим к нетто, как у остальных игр.
+-- Условия отличают брутто от нетто, поэтому повторный запуск ничего не меняет:
+-- брутто-выигрыш (2 × ставка − комиссия) больше ставки, нетто - не больше.
+
+UPDATE game_history SET win_amount = win_amount - bet_amount
+WHERE room_id IS NOT NULL AND result = 'win' AND win_amount > bet_amount;
+
+UPDATE game_history SET win_amount = -bet_amount
+WHERE room_id IS NOT NULL AND result = 'lose' AND win_amount = 0 AND bet_amount > 0;
+
+UPDATE game_history SET win_amount = 0
+WHERE room_id IS NOT NULL AND result = 'draw' AND win_amount <> 0;
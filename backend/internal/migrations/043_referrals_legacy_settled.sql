@@ -0,0 +1,6 @@
+-- Рефералы, созданные до удержания награды (REFERRAL_HOLD_MODE), никогда не ждали
+-- квалификации: в статистике они уже числились заработанными. Без этого в режиме
+-- none первая же проверка выплатила бы за каждого старого реферала задним числом.
+-- Миграция применяется один раз, поэтому затрагивает только существующие строки.
+
+UPDATE referrals SET bonus_claimed = true WHERE bonus_claimed = false;
@@ -0,0 +1,17 @@
+-- Классический кейс из 032 отдавал в среднем 687 gems на 100 потраченных.
+-- Кейс должен возвращать меньше цены (CaseConfig.Validate отклоняет EV >= 1),
+-- поэтому заменяем таблицу призов: в среднем 95 на 100. Меняем только
+-- нетронутый сид из 032, настроенный вручную кейс не трогаем.
+
+UPDATE case_configs SET prizes =
+    '[{"id": 1, "amount": 25, "probability": 0.5, "rarity": "Common"},
+      {"id": 2, "amount": 50, "probability": 0.2, "rarity": "Uncommon"},
+      {"id": 3, "amount": 100, "probability": 0.15, "rarity": "Rare"},
+      {"id": 4, "amount": 200, "probability": 0.10, "rarity": "Epic"},
+      {"id": 5, "amount": 750, "probability": 0.05, "rarity": "Legendary"}]'
+WHERE case_id = 'classic' AND cost = 100 AND prizes =
+    '[{"id": 1, "amount": 250, "probability": 0.5, "rarity": "Common"},
+      {"id": 2, "amount": 500, "probability": 0.2, "rarity": "Uncommon"},
+      {"id": 3, "amount": 750, "probability": 0.15, "rarity": "Rare"},
+      {"id": 4, "amount": 1000, "probability": 0.10, "rarity": "Epic"},
+      {"id": 5, "amount": 5000, "probability": 0.05, "rarity": "Legendary"}]'::jsonb;
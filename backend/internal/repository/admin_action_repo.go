@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"telegram_webapp/internal/domain"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AdminActionRepository stores the admin audit log
+type AdminActionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAdminActionRepository(db *pgxpool.Pool) *AdminActionRepository {
+	return &AdminActionRepository{db: db}
+}
+
+// Create inserts an entry; TargetUserID 0 is stored as NULL
+func (r *AdminActionRepository) Create(ctx context.Context, a *domain.AdminAction) error {
+	detailsJSON, err := json.Marshal(a.Details)
+	if err != nil || a.Details == nil {
+		detailsJSON = []byte("{}")
+	}
+
+	var target *int64
+	if a.TargetUserID != 0 {
+		target = &a.TargetUserID
+	}
+
+	return r.db.QueryRow(ctx, `
+		INSERT INTO admin_actions (admin_tg_id, action, target_user_id, details)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, a.AdminTgID, a.Action, target, detailsJSON).Scan(&a.ID, &a.CreatedAt)
+}
+
+// GetRecent returns latest admin actions, newest first
+func (r *AdminActionRepository) GetRecent(ctx context.Context, limit int) ([]domain.AdminAction, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, admin_tg_id, action, COALESCE(target_user_id, 0), details, created_at
+		FROM admin_actions
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	actions := []domain.AdminAction{}
+	for rows.Next() {
+		var a domain.AdminAction
+		var detailsJSON []byte
+		if err := rows.Scan(&a.ID, &a.AdminTgID, &a.Action, &a.TargetUserID, &detailsJSON, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(detailsJSON, &a.Details); err != nil {
+			a.Details = make(map[string]interface{})
+		}
+		actions = append(actions, a)
+	}
+	return actions, rows.Err()
+}
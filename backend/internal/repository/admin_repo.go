@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AdminRepository stores admins added through the admin bot
+type AdminRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAdminRepository(db *pgxpool.Pool) *AdminRepository {
+	return &AdminRepository{db: db}
+}
+
+// ListTgIDs returns Telegram IDs of all persisted admins
+func (r *AdminRepository) ListTgIDs(ctx context.Context) ([]int64, error) {
+	rows, err := r.db.Query(ctx, `SELECT tg_id FROM admins ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Add stores an admin; false if it was already there
+func (r *AdminRepository) Add(ctx context.Context, tgID, addedBy int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		INSERT INTO admins (tg_id, added_by) VALUES ($1, $2)
+		ON CONFLICT (tg_id) DO NOTHING
+	`, tgID, addedBy)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Remove deletes an admin; false if there was none
+func (r *AdminRepository) Remove(ctx context.Context, tgID int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM admins WHERE tg_id = $1`, tgID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
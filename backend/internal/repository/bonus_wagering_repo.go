@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+
+	"telegram_webapp/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// BonusWageringStatus is the open wagering requirement of a user in one currency
+type BonusWageringStatus struct {
+	Currency        domain.Currency `json:"currency"`
+	LockedAmount    int64           `json:"locked_amount"`   // bonus funds not yet withdrawable
+	RemainingWager  int64           `json:"remaining_wager"` // how much more must be bet to unlock
+	OpenRequirement int             `json:"open_requirements"`
+}
+
+type BonusWageringRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewBonusWageringRepository(db *pgxpool.Pool) *BonusWageringRepository {
+	return &BonusWageringRepository{db: db}
+}
+
+// GrantWithTx locks a bonus until amount*multiplier has been wagered. It runs in
+// the transaction that credits the bonus, so the funds are never credited unlocked.
+func (r *BonusWageringRepository) GrantWithTx(ctx context.Context, tx pgx.Tx, userID int64, currency domain.Currency, amount int64, multiplier int, source string) error {
+	if amount <= 0 || multiplier <= 0 {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `
+		INSERT INTO bonus_wagering (user_id, currency, source, bonus_amount, wager_required)
+		VALUES ($1, $2, $3, $4, $5)
+	`, userID, currency, source, amount, amount*int64(multiplier))
+	return err
+}
+
+// AddWager counts a settled bet towards open requirements, oldest first
+func (r *BonusWageringRepository) AddWager(ctx context.Context, userID int64, currency domain.Currency, amount int64) error {
+	if amount <= 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, wager_required - wagered
+		FROM bonus_wagering
+		WHERE user_id = $1 AND currency = $2 AND completed_at IS NULL
+		ORDER BY created_at, id
+		FOR UPDATE
+	`, userID, currency)
+	if err != nil {
+		return err
+	}
+
+	type open struct {
+		id   int64
+		left int64
+	}
+	var list []open
+	for rows.Next() {
+		var o open
+		if err := rows.Scan(&o.id, &o.left); err != nil {
+			rows.Close()
+			return err
+		}
+		list = append(list, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, o := range list {
+		if amount <= 0 {
+			break
+		}
+		step := amount
+		if step > o.left {
+			step = o.left
+		}
+		amount -= step
+
+		if _, err := tx.Exec(ctx, `
+			UPDATE bonus_wagering
+			SET wagered = wagered + $2,
+			    completed_at = CASE WHEN wagered + $2 >= wager_required THEN now() ELSE NULL END
+			WHERE id = $1
+		`, o.id, step); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetStatus returns locked bonus and remaining wagering for a currency
+func (r *BonusWageringRepository) GetStatus(ctx context.Context, userID int64, currency domain.Currency) (*BonusWageringStatus, error) {
+	st := &BonusWageringStatus{Currency: currency}
+	err := r.db.QueryRow(ctx, `
+		SELECT COALESCE(SUM(bonus_amount), 0), COALESCE(SUM(wager_required - wagered), 0), COUNT(*)
+		FROM bonus_wagering
+		WHERE user_id = $1 AND currency = $2 AND completed_at IS NULL
+	`, userID, currency).Scan(&st.LockedAmount, &st.RemainingWager, &st.OpenRequirement)
+	if err != nil {
+		return nil, err
+	}
+	return st, nil
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"telegram_webapp/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type BroadcastRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewBroadcastRepository(db *pgxpool.Pool) *BroadcastRepository {
+	return &BroadcastRepository{db: db}
+}
+
+const broadcastColumns = `id, admin_tg_id, segment, text, photo_file_id, status, scheduled_at,
+	started_at, finished_at, total, delivered, blocked, errored, progress, progress_at, created_at`
+
+func scanBroadcast(row pgx.Row) (*domain.Broadcast, error) {
+	var b domain.Broadcast
+	err := row.Scan(&b.ID, &b.AdminTgID, &b.Segment, &b.Text, &b.PhotoFileID, &b.Status, &b.ScheduledAt,
+		&b.StartedAt, &b.FinishedAt, &b.Total, &b.Delivered, &b.Blocked, &b.Errored, &b.Progress, &b.ProgressAt, &b.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// Create stores a broadcast; status sending marks it started right away
+func (r *BroadcastRepository) Create(ctx context.Context, b *domain.Broadcast) error {
+	return r.db.QueryRow(ctx, `
+		INSERT INTO broadcasts (admin_tg_id, segment, text, photo_file_id, status, scheduled_at, started_at, progress_at)
+		VALUES ($1, $2, $3, $4, $5, $6, CASE WHEN $5 = 'sending' THEN now() END,
+		        CASE WHEN $5 = 'sending' THEN now() END)
+		RETURNING id, started_at, progress_at, created_at
+	`, b.AdminTgID, b.Segment, b.Text, b.PhotoFileID, b.Status, b.ScheduledAt).Scan(&b.ID, &b.StartedAt, &b.ProgressAt, &b.CreatedAt)
+}
+
+// ClaimDue moves scheduled broadcasts whose time has come to sending and returns them.
+// A broadcast is claimed once even with several instances polling.
+func (r *BroadcastRepository) ClaimDue(ctx context.Context) ([]*domain.Broadcast, error) {
+	rows, err := r.db.Query(ctx, `
+		UPDATE broadcasts SET status = 'sending', started_at = now(), progress_at = now()
+		WHERE id IN (
+			SELECT id FROM broadcasts
+			WHERE status = 'scheduled' AND scheduled_at <= now()
+			ORDER BY scheduled_at
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+broadcastColumns)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []*domain.Broadcast
+	for rows.Next() {
+		b, err := scanBroadcast(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, b)
+	}
+	return list, rows.Err()
+}
+
+// ClaimStale takes over broadcasts stuck in sending whose progress hasn't been
+// saved for staleAfter (the sender crashed) and returns them to be resumed
+func (r *BroadcastRepository) ClaimStale(ctx context.Context, staleAfter time.Duration) ([]*domain.Broadcast, error) {
+	rows, err := r.db.Query(ctx, `
+		UPDATE broadcasts SET progress_at = now()
+		WHERE id IN (
+			SELECT id FROM broadcasts
+			WHERE status = 'sending' AND COALESCE(progress_at, started_at, created_at) < now() - $1::interval
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+broadcastColumns, staleAfter)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []*domain.Broadcast
+	for rows.Next() {
+		b, err := scanBroadcast(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, b)
+	}
+	return list, rows.Err()
+}
+
+// SaveProgress stores the resume point and counters of a broadcast being sent
+func (r *BroadcastRepository) SaveProgress(ctx context.Context, b *domain.Broadcast) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE broadcasts
+		SET progress = $2, total = $3, delivered = $4, blocked = $5, errored = $6, progress_at = now()
+		WHERE id = $1 AND status = 'sending'
+	`, b.ID, b.Progress, b.Total, b.Delivered, b.Blocked, b.Errored)
+	return err
+}
+
+// Finish stores delivery results and the final status
+func (r *BroadcastRepository) Finish(ctx context.Context, b *domain.Broadcast) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE broadcasts
+		SET status = $2, total = $3, delivered = $4, blocked = $5, errored = $6, progress = $7, finished_at = now()
+		WHERE id = $1
+	`, b.ID, b.Status, b.Total, b.Delivered, b.Blocked, b.Errored, b.Progress)
+	return err
+}
+
+// Cancel cancels a broadcast that hasn't started yet; false if there is none
+func (r *BroadcastRepository) Cancel(ctx context.Context, id int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE broadcasts SET status = 'cancelled', finished_at = now()
+		WHERE id = $1 AND status = 'scheduled'
+	`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetRecent returns latest broadcasts, newest first
+func (r *BroadcastRepository) GetRecent(ctx context.Context, limit int) ([]*domain.Broadcast, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+broadcastColumns+` FROM broadcasts ORDER BY id DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []*domain.Broadcast
+	for rows.Next() {
+		b, err := scanBroadcast(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, b)
+	}
+	return list, rows.Err()
+}
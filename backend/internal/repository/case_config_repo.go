@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+
+	"telegram_webapp/internal/domain"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type CaseConfigRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCaseConfigRepository(db *pgxpool.Pool) *CaseConfigRepository {
+	return &CaseConfigRepository{db: db}
+}
+
+// GetActive returns active cases in display order.
+// An empty result means the table isn't configured.
+func (r *CaseConfigRepository) GetActive(ctx context.Context) ([]*domain.CaseConfig, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT case_id, name, cost, prizes, active, sort_order
+		FROM case_configs
+		WHERE active
+		ORDER BY sort_order, case_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cases []*domain.CaseConfig
+	for rows.Next() {
+		c := &domain.CaseConfig{}
+		if err := rows.Scan(&c.CaseID, &c.Name, &c.Cost, &c.Prizes, &c.Active, &c.SortOrder); err != nil {
+			return nil, err
+		}
+		cases = append(cases, c)
+	}
+	return cases, rows.Err()
+}
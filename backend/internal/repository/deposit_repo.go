@@ -2,14 +2,20 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"time"
 
 	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/ton"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrInvalidExchangeRate - у депозита не задан курс coins per TON
+var ErrInvalidExchangeRate = errors.New("invalid exchange rate")
+
 type DepositRepository struct {
 	db *pgxpool.Pool
 }
@@ -125,6 +131,92 @@ func (r *DepositRepository) GetTotalDeposited(ctx context.Context, userID int64)
 	return total, err
 }
 
+// CreditDeposit records a confirmed deposit and credits coins in one transaction.
+// Coins are computed only from AmountNano and the ExchangeRate snapshot stored with
+// the deposit, CoinsCredited is overwritten. Returns false if the tx_hash was
+// already processed (idempotent).
+func (r *DepositRepository) CreditDeposit(ctx context.Context, d *domain.Deposit) (bool, error) {
+	if d.ExchangeRate <= 0 {
+		return false, ErrInvalidExchangeRate
+	}
+	d.CoinsCredited = ton.CoinsForNano(d.AmountNano, d.ExchangeRate)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO deposits (user_id, wallet_address, amount_nano, gems_credited, exchange_rate, tx_hash, tx_lt, status, memo, confirmed_at, processed)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, 'confirmed', $8, now(), true)
+		ON CONFLICT (tx_hash) DO NOTHING
+		RETURNING id, created_at
+	`, d.UserID, d.WalletAddress, d.AmountNano, d.GemsCredited, d.ExchangeRate, d.TxHash, d.TxLt, d.Memo).Scan(&d.ID, &d.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE users SET coins = coins + $1 WHERE id = $2`, d.CoinsCredited, d.UserID); err != nil {
+		return false, err
+	}
+
+	meta, _ := json.Marshal(map[string]interface{}{
+		"deposit_id":     d.ID,
+		"tx_hash":        d.TxHash,
+		"tx_lt":          d.TxLt,
+		"amount_nano":    d.AmountNano,
+		"coins_credited": d.CoinsCredited,
+	})
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO transactions (user_id, type, amount, meta) VALUES ($1, $2, $3, $4)`,
+		d.UserID, "ton_deposit", d.CoinsCredited, meta,
+	); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+	d.Status = domain.DepositStatusConfirmed
+	d.Processed = true
+	return true, nil
+}
+
+// GetWatcherLt returns the highest processed logical time for an address (0 if none)
+func (r *DepositRepository) GetWatcherLt(ctx context.Context, address string) (int64, error) {
+	var lt int64
+	err := r.db.QueryRow(ctx, `SELECT last_lt FROM deposit_watcher_state WHERE address = $1`, address).Scan(&lt)
+	if err == pgx.ErrNoRows {
+		return 0, nil
+	}
+	return lt, err
+}
+
+// SaveWatcherLt persists the highest processed logical time; never moves it backwards
+func (r *DepositRepository) SaveWatcherLt(ctx context.Context, address string, lt int64) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO deposit_watcher_state (address, last_lt, updated_at)
+		VALUES ($1, $2, now())
+		ON CONFLICT (address) DO UPDATE
+		SET last_lt = GREATEST(deposit_watcher_state.last_lt, EXCLUDED.last_lt),
+		    updated_at = now()
+	`, address, lt)
+	return err
+}
+
+// depositCoins - монеты депозита по курсу, сохранённому при зачислении.
+// У старых депозитов в гемах (gems_credited > 0) монет нет.
+func depositCoins(d *domain.Deposit) int64 {
+	if d.GemsCredited > 0 {
+		return 0
+	}
+	return ton.CoinsForNano(d.AmountNano, d.ExchangeRate)
+}
+
 func scanDeposit(row pgx.Row) (*domain.Deposit, error) {
 	var d domain.Deposit
 	var txLt *int64
@@ -148,6 +240,7 @@ func scanDeposit(row pgx.Row) (*domain.Deposit, error) {
 		d.Memo = *memo
 	}
 	d.ConfirmedAt = confirmedAt
+	d.CoinsCredited = depositCoins(&d)
 
 	return &d, nil
 }
@@ -175,6 +268,7 @@ func scanDeposits(rows pgx.Rows) ([]domain.Deposit, error) {
 			d.Memo = *memo
 		}
 		d.ConfirmedAt = confirmedAt
+		d.CoinsCredited = depositCoins(&d)
 
 		deposits = append(deposits, d)
 	}
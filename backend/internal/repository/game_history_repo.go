@@ -3,6 +3,8 @@ package repository
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 
 	"telegram_webapp/internal/domain"
@@ -18,17 +20,28 @@ func NewGameHistoryRepository(db *pgxpool.Pool) *GameHistoryRepository {
 	return &GameHistoryRepository{db: db}
 }
 
+// ErrInvalidGameMode - режим игры не pvp и не pve
+var ErrInvalidGameMode = errors.New("invalid game mode")
+
 // Create сохраняет запись игры в историю
 func (r *GameHistoryRepository) Create(ctx context.Context, gh *domain.GameHistory) error {
+	if !gh.Mode.Valid() {
+		return fmt.Errorf("%w: %q", ErrInvalidGameMode, gh.Mode)
+	}
+
 	detailsJSON, err := json.Marshal(gh.Details)
 	if err != nil {
 		detailsJSON = []byte("{}")
 	}
 
+	if gh.Currency == "" {
+		gh.Currency = domain.CurrencyGems
+	}
+
 	err = r.db.QueryRow(ctx,
 		`INSERT INTO game_history 
-			(user_id, game_type, mode, opponent_id, room_id, result, bet_amount, win_amount, details)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			(user_id, game_type, mode, opponent_id, room_id, result, bet_amount, win_amount, currency, details)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		 RETURNING id, created_at`,
 		gh.UserID,
 		gh.GameType,
@@ -38,14 +51,15 @@ func (r *GameHistoryRepository) Create(ctx context.Context, gh *domain.GameHisto
 		gh.Result,
 		gh.BetAmount,
 		gh.WinAmount,
+		gh.Currency,
 		detailsJSON,
 	).Scan(&gh.ID, &gh.CreatedAt)
 
 	return err
 }
 
-// GetByUser возвращает историю игр пользователя
-func (r *GameHistoryRepository) GetByUser(ctx context.Context, userID int64, limit int) ([]*domain.GameHistory, error) {
+// GetByUser возвращает историю игр пользователя; пустой mode - все режимы
+func (r *GameHistoryRepository) GetByUser(ctx context.Context, userID int64, mode domain.GameMode, limit int) ([]*domain.GameHistory, error) {
 	if limit <= 0 {
 		limit = 100
 	}
@@ -54,10 +68,10 @@ func (r *GameHistoryRepository) GetByUser(ctx context.Context, userID int64, lim
 		`SELECT id, user_id, game_type, mode, opponent_id, room_id, result, 
 				bet_amount, win_amount, details, created_at
 		 FROM game_history
-		 WHERE user_id = $1
+		 WHERE user_id = $1 AND ($2 = '' OR mode = $2)
 		 ORDER BY created_at DESC
-		 LIMIT $2`,
-		userID, limit,
+		 LIMIT $3`,
+		userID, string(mode), limit,
 	)
 	if err != nil {
 		return nil, err
@@ -90,6 +104,87 @@ func (r *GameHistoryRepository) GetByUserAndType(ctx context.Context, userID int
 	return r.scanRows(rows)
 }
 
+// gameStreamPageSize - сколько строк StreamByUser читает одним запросом
+const gameStreamPageSize = 500
+
+// StreamByUser отдаёт игры пользователя по одной (новые первыми), не держа всю
+// выборку в памяти; limit > 0 ограничивает число строк. Ошибка fn прерывает чтение.
+// Игры читаются страницами по gameStreamPageSize, и соединение возвращается в пул
+// до вызовов fn: медленный получатель не держит соединение.
+func (r *GameHistoryRepository) StreamByUser(ctx context.Context, userID int64, limit int, fn func(*domain.GameHistory) error) error {
+	var (
+		sent  int
+		after *domain.GameHistory
+	)
+	for {
+		size := gameStreamPageSize
+		if limit > 0 && limit-sent < size {
+			size = limit - sent
+		}
+		if size <= 0 {
+			return nil
+		}
+
+		page, err := r.streamPage(ctx, userID, after, size)
+		if err != nil {
+			return err
+		}
+		for _, gh := range page {
+			if err := fn(gh); err != nil {
+				return err
+			}
+		}
+		sent += len(page)
+		if len(page) < size {
+			return nil
+		}
+		after = page[len(page)-1]
+	}
+}
+
+// streamPage возвращает до size игр пользователя, идущих после after в порядке
+// StreamByUser (created_at DESC, id DESC); after == nil - с самой новой
+func (r *GameHistoryRepository) streamPage(ctx context.Context, userID int64, after *domain.GameHistory, size int) ([]*domain.GameHistory, error) {
+	var (
+		afterAt *time.Time
+		afterID int64
+	)
+	if after != nil {
+		afterAt, afterID = &after.CreatedAt, after.ID
+	}
+
+	rows, err := r.db.Query(ctx,
+		`SELECT id, game_type, mode, result, bet_amount, win_amount, currency, details, created_at
+		 FROM game_history
+		 WHERE user_id = $1 AND ($2::timestamptz IS NULL OR (created_at, id) < ($2, $3))
+		 ORDER BY created_at DESC, id DESC
+		 LIMIT $4`,
+		userID, afterAt, afterID, size,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := make([]*domain.GameHistory, 0, size)
+	for rows.Next() {
+		var (
+			gh          domain.GameHistory
+			detailsJSON []byte
+		)
+		if err := rows.Scan(&gh.ID, &gh.GameType, &gh.Mode, &gh.Result, &gh.BetAmount, &gh.WinAmount,
+			&gh.Currency, &detailsJSON, &gh.CreatedAt); err != nil {
+			return nil, err
+		}
+		gh.UserID = userID
+		if len(detailsJSON) > 0 {
+			_ = json.Unmarshal(detailsJSON, &gh.Details)
+		}
+		page = append(page, &gh)
+	}
+	return page, rows.Err()
+}
+
 // UserStats - статистика пользователя
 type UserStats struct {
 	UserID     int64 `json:"user_id"`
@@ -101,8 +196,9 @@ type UserStats struct {
 	TotalLost  int64 `json:"total_lost"`
 }
 
-// GetUserStats возвращает статистику пользователя за период
-func (r *GameHistoryRepository) GetUserStats(ctx context.Context, userID int64, since time.Time) (*UserStats, error) {
+// GetUserStats возвращает статистику пользователя за период;
+// gameType ограничивает одной игрой, пустой - все игры
+func (r *GameHistoryRepository) GetUserStats(ctx context.Context, userID int64, since time.Time, gameType domain.GameType) (*UserStats, error) {
 	stats := &UserStats{UserID: userID}
 
 	err := r.db.QueryRow(ctx,
@@ -114,8 +210,8 @@ func (r *GameHistoryRepository) GetUserStats(ctx context.Context, userID int64,
 			COALESCE(SUM(win_amount) FILTER (WHERE win_amount > 0), 0) as total_won,
 			COALESCE(ABS(SUM(win_amount) FILTER (WHERE win_amount < 0)), 0) as total_lost
 		 FROM game_history
-		 WHERE user_id = $1 AND created_at >= $2`,
-		userID, since,
+		 WHERE user_id = $1 AND created_at >= $2 AND ($3 = '' OR game_type = $3)`,
+		userID, since, string(gameType),
 	).Scan(&stats.TotalGames, &stats.Wins, &stats.Losses, &stats.Draws, &stats.TotalWon, &stats.TotalLost)
 
 	if err != nil {
@@ -125,6 +221,40 @@ func (r *GameHistoryRepository) GetUserStats(ctx context.Context, userID int64,
 	return stats, nil
 }
 
+// CurrencyPnL - итог игр пользователя в одной валюте
+type CurrencyPnL struct {
+	Games     int   `json:"games"`
+	Wagered   int64 `json:"wagered"`
+	NetProfit int64 `json:"net_profit"` // сумма win_amount: выигрыш минус ставка, проигрыш отрицательный
+}
+
+// GetNetProfit возвращает итог игр пользователя по валютам с момента since;
+// gameType ограничивает одной игрой, пустой - все игры
+func (r *GameHistoryRepository) GetNetProfit(ctx context.Context, userID int64, since time.Time, gameType domain.GameType) (map[domain.Currency]*CurrencyPnL, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT currency, COUNT(*), COALESCE(SUM(bet_amount), 0), COALESCE(SUM(win_amount), 0)
+		 FROM game_history
+		 WHERE user_id = $1 AND created_at >= $2 AND ($3 = '' OR game_type = $3)
+		 GROUP BY currency`,
+		userID, since, string(gameType),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[domain.Currency]*CurrencyPnL)
+	for rows.Next() {
+		var currency domain.Currency
+		p := &CurrencyPnL{}
+		if err := rows.Scan(&currency, &p.Games, &p.Wagered, &p.NetProfit); err != nil {
+			return nil, err
+		}
+		result[currency] = p
+	}
+	return result, rows.Err()
+}
+
 // TopUser - запись в топе
 type TopUser struct {
 	UserID    int64  `json:"user_id"`
@@ -133,6 +263,7 @@ type TopUser struct {
 	Gems      int64  `json:"gems"`
 	Wins      int    `json:"wins"`
 	Games     int    `json:"games"`
+	Anonymous bool   `json:"anonymous"`
 }
 
 // GetTopUsers возвращает топ пользователей за последний месяц
@@ -148,11 +279,12 @@ func (r *GameHistoryRepository) GetTopUsers(ctx context.Context, limit int) ([]*
 			COALESCE(u.first_name, '') as first_name,
 			u.gems,
 			COUNT(*) FILTER (WHERE gh.result = 'win') as wins,
-			COUNT(*) as games
+			COUNT(*) as games,
+			u.leaderboard_visible
 		 FROM users u
 		 LEFT JOIN game_history gh ON u.id = gh.user_id 
 			AND gh.created_at >= now() - interval '1 month'
-		 GROUP BY u.id, u.username, u.first_name, u.gems
+		 GROUP BY u.id, u.username, u.first_name, u.gems, u.leaderboard_visible
 		 ORDER BY wins DESC, games DESC, u.gems DESC
 		 LIMIT $1`,
 		limit,
@@ -165,9 +297,14 @@ func (r *GameHistoryRepository) GetTopUsers(ctx context.Context, limit int) ([]*
 	var result []*TopUser
 	for rows.Next() {
 		var tu TopUser
-		if err := rows.Scan(&tu.UserID, &tu.Username, &tu.FirstName, &tu.Gems, &tu.Wins, &tu.Games); err != nil {
+		var visible bool
+		if err := rows.Scan(&tu.UserID, &tu.Username, &tu.FirstName, &tu.Gems, &tu.Wins, &tu.Games, &visible); err != nil {
 			return nil, err
 		}
+		if !visible {
+			tu.UserID, tu.Username, tu.FirstName = 0, "", AnonymousPlayerName
+			tu.Anonymous = true
+		}
 		result = append(result, &tu)
 	}
 
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+
+	"telegram_webapp/internal/domain"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type GameLimitsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewGameLimitsRepository(db *pgxpool.Pool) *GameLimitsRepository {
+	return &GameLimitsRepository{db: db}
+}
+
+// List returns all per-game bet limit overrides
+func (r *GameLimitsRepository) List(ctx context.Context) ([]*domain.GameLimit, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT game_type, currency, min_bet, max_bet, updated_at
+		FROM game_limits
+		ORDER BY game_type, currency
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var limits []*domain.GameLimit
+	for rows.Next() {
+		l := &domain.GameLimit{}
+		if err := rows.Scan(&l.GameType, &l.Currency, &l.MinBet, &l.MaxBet, &l.UpdatedAt); err != nil {
+			return nil, err
+		}
+		limits = append(limits, l)
+	}
+	return limits, rows.Err()
+}
+
+// Upsert sets the limits of a game in a currency
+func (r *GameLimitsRepository) Upsert(ctx context.Context, l *domain.GameLimit) error {
+	return r.db.QueryRow(ctx, `
+		INSERT INTO game_limits (game_type, currency, min_bet, max_bet)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (game_type, currency)
+		DO UPDATE SET min_bet = EXCLUDED.min_bet, max_bet = EXCLUDED.max_bet, updated_at = now()
+		RETURNING updated_at
+	`, l.GameType, l.Currency, l.MinBet, l.MaxBet).Scan(&l.UpdatedAt)
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"telegram_webapp/internal/domain"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type IdempotencyRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewIdempotencyRepository(db *pgxpool.Pool) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Reserve claims (userID, key) for a new request. Returns nil if the key was free,
+// otherwise the existing record (pending or with the stored response).
+// An expired record is replaced as if the key was never used.
+func (r *IdempotencyRepository) Reserve(ctx context.Context, userID int64, key, requestHash string, ttl time.Duration) (*domain.IdempotencyRecord, error) {
+	if _, err := r.db.Exec(ctx,
+		`DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND expires_at <= now()`,
+		userID, key,
+	); err != nil {
+		return nil, err
+	}
+
+	tag, err := r.db.Exec(ctx, `
+		INSERT INTO idempotency_keys (user_id, key, request_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, key) DO NOTHING
+	`, userID, key, requestHash, time.Now().Add(ttl))
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 1 {
+		return nil, nil
+	}
+
+	rec := &domain.IdempotencyRecord{UserID: userID, Key: key}
+	var status *int
+	err = r.db.QueryRow(ctx, `
+		SELECT request_hash, status_code, response, expires_at
+		FROM idempotency_keys
+		WHERE user_id = $1 AND key = $2
+	`, userID, key).Scan(&rec.RequestHash, &status, &rec.Response, &rec.ExpiresAt)
+	if err != nil {
+		// pgx.ErrNoRows: запись освободили между INSERT и SELECT - клиент повторит запрос
+		return nil, err
+	}
+	if status != nil {
+		rec.StatusCode = *status
+	}
+	return rec, nil
+}
+
+// Complete stores the response for a reserved key
+func (r *IdempotencyRepository) Complete(ctx context.Context, userID int64, key string, statusCode int, response []byte) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE idempotency_keys SET status_code = $3, response = $4
+		WHERE user_id = $1 AND key = $2
+	`, userID, key, statusCode, response)
+	return err
+}
+
+// Release frees a reserved key so the request can be retried (server error, nothing was stored)
+func (r *IdempotencyRepository) Release(ctx context.Context, userID int64, key string) error {
+	_, err := r.db.Exec(ctx,
+		`DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND status_code IS NULL`,
+		userID, key,
+	)
+	return err
+}
+
+// DeleteExpired removes expired keys, returns how many were deleted
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= now()`)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
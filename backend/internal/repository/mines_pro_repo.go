@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"telegram_webapp/internal/game"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrActiveMinesProGame - у пользователя уже есть активная игра Mines Pro
+var ErrActiveMinesProGame = errors.New("you already have an active game")
+
+type MinesProGameRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewMinesProGameRepository(db *pgxpool.Pool) *MinesProGameRepository {
+	return &MinesProGameRepository{db: db}
+}
+
+const minesProGameColumns = `id, user_id, board_size, mines_count, bet, currency, mines, revealed_cells,
+		status, win_amount, created_at, last_reveal_at, finished_at`
+
+// CreateWithTx сохраняет новую игру в транзакции списания ставки
+func (r *MinesProGameRepository) CreateWithTx(ctx context.Context, tx pgx.Tx, g game.MinesPvESnapshot) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO mines_pro_games (id, user_id, board_size, mines_count, bet, currency, mines, revealed_cells, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, g.ID, g.UserID, g.BoardSize, g.MinesCount, g.Bet, g.Currency, g.Mines, g.RevealedCells, g.Status, g.CreatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrActiveMinesProGame
+	}
+	return err
+}
+
+// UpdateProgress сохраняет открытые ячейки активной игры
+func (r *MinesProGameRepository) UpdateProgress(ctx context.Context, g game.MinesPvESnapshot) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE mines_pro_games SET revealed_cells = $1, last_reveal_at = $2
+		WHERE id = $3 AND status = 'active'
+	`, g.RevealedCells, g.LastRevealAt, g.ID)
+	return err
+}
+
+// FinishWithTx записывает итог игры. Возвращает false, если игра уже была
+// завершена (например, свипером) - тогда выплату делать нельзя.
+func (r *MinesProGameRepository) FinishWithTx(ctx context.Context, tx pgx.Tx, g game.MinesPvESnapshot) (bool, error) {
+	tag, err := tx.Exec(ctx, `
+		UPDATE mines_pro_games
+		SET status = $1, revealed_cells = $2, win_amount = $3, last_reveal_at = $4, finished_at = $5
+		WHERE id = $6 AND status = 'active'
+	`, g.Status, g.RevealedCells, g.WinAmount, g.LastRevealAt, g.FinishedAt, g.ID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetActiveByUser returns the user's active game, nil if there is none
+func (r *MinesProGameRepository) GetActiveByUser(ctx context.Context, userID int64) (*game.MinesPvESnapshot, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+minesProGameColumns+` FROM mines_pro_games WHERE user_id = $1 AND status = 'active'`, userID)
+	if err != nil {
+		return nil, err
+	}
+	games, err := scanMinesProGames(rows)
+	if err != nil || len(games) == 0 {
+		return nil, err
+	}
+	return &games[0], nil
+}
+
+// ListActive returns all active games (rehydrated on startup)
+func (r *MinesProGameRepository) ListActive(ctx context.Context) ([]game.MinesPvESnapshot, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+minesProGameColumns+` FROM mines_pro_games WHERE status = 'active'`)
+	if err != nil {
+		return nil, err
+	}
+	return scanMinesProGames(rows)
+}
+
+func scanMinesProGames(rows pgx.Rows) ([]game.MinesPvESnapshot, error) {
+	defer rows.Close()
+
+	var games []game.MinesPvESnapshot
+	for rows.Next() {
+		var g game.MinesPvESnapshot
+		if err := rows.Scan(&g.ID, &g.UserID, &g.BoardSize, &g.MinesCount, &g.Bet, &g.Currency, &g.Mines, &g.RevealedCells,
+			&g.Status, &g.WinAmount, &g.CreatedAt, &g.LastRevealAt, &g.FinishedAt); err != nil {
+			return nil, err
+		}
+		games = append(games, g)
+	}
+	return games, rows.Err()
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"telegram_webapp/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type NotificationRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewNotificationRepository(db *pgxpool.Pool) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// GetPrefs returns user's notification preferences (defaults if none stored)
+func (r *NotificationRepository) GetPrefs(ctx context.Context, userID int64) (*domain.NotificationPrefs, error) {
+	p := domain.NotificationPrefs{UserID: userID}
+	err := r.db.QueryRow(ctx, `
+		SELECT big_win, withdrawal, quest, promo, streak_reminder, updated_at
+		FROM notification_prefs
+		WHERE user_id = $1
+	`, userID).Scan(&p.BigWin, &p.Withdrawal, &p.Quest, &p.Promo, &p.StreakReminder, &p.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.DefaultNotificationPrefs(userID), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// SavePrefs inserts or updates user's notification preferences
+func (r *NotificationRepository) SavePrefs(ctx context.Context, p *domain.NotificationPrefs) error {
+	return r.db.QueryRow(ctx, `
+		INSERT INTO notification_prefs (user_id, big_win, withdrawal, quest, promo, streak_reminder, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, now())
+		ON CONFLICT (user_id) DO UPDATE
+		SET big_win = EXCLUDED.big_win,
+		    withdrawal = EXCLUDED.withdrawal,
+		    quest = EXCLUDED.quest,
+		    promo = EXCLUDED.promo,
+		    streak_reminder = EXCLUDED.streak_reminder,
+		    updated_at = now()
+		RETURNING updated_at
+	`, p.UserID, p.BigWin, p.Withdrawal, p.Quest, p.Promo, p.StreakReminder).Scan(&p.UpdatedAt)
+}
+
+// IsEnabled checks whether user accepts notifications of the given category
+func (r *NotificationRepository) IsEnabled(ctx context.Context, userID int64, category domain.NotificationCategory) (bool, error) {
+	p, err := r.GetPrefs(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return p.Allows(category), nil
+}
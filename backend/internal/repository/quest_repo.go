@@ -2,10 +2,12 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"telegram_webapp/internal/domain"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -21,7 +23,7 @@ func NewQuestRepository(db *pgxpool.Pool) *QuestRepository {
 func (r *QuestRepository) GetActiveQuests(ctx context.Context) ([]*domain.Quest, error) {
 	rows, err := r.db.Query(ctx,
 		`SELECT id, quest_type, title, description, game_type, action_type,
-				target_count, reward_gems, is_active, sort_order, created_at, updated_at
+				target_count, reward_gems, reward_coins, reward_gk, is_active, sort_order, created_at, updated_at
 		 FROM quests
 		 WHERE is_active = true
 		 ORDER BY sort_order, id`,
@@ -38,7 +40,7 @@ func (r *QuestRepository) GetActiveQuests(ctx context.Context) ([]*domain.Quest,
 func (r *QuestRepository) GetQuestsByType(ctx context.Context, questType domain.QuestType) ([]*domain.Quest, error) {
 	rows, err := r.db.Query(ctx,
 		`SELECT id, quest_type, title, description, game_type, action_type,
-				target_count, reward_gems, is_active, sort_order, created_at, updated_at
+				target_count, reward_gems, reward_coins, reward_gk, is_active, sort_order, created_at, updated_at
 		 FROM quests
 		 WHERE is_active = true AND quest_type = $1
 		 ORDER BY sort_order, id`,
@@ -57,12 +59,12 @@ func (r *QuestRepository) GetQuestByID(ctx context.Context, id int64) (*domain.Q
 	var q domain.Quest
 	err := r.db.QueryRow(ctx,
 		`SELECT id, quest_type, title, description, game_type, action_type,
-				target_count, reward_gems, is_active, sort_order, created_at, updated_at
+				target_count, reward_gems, reward_coins, reward_gk, is_active, sort_order, created_at, updated_at
 		 FROM quests
 		 WHERE id = $1`,
 		id,
 	).Scan(&q.ID, &q.QuestType, &q.Title, &q.Description, &q.GameType, &q.ActionType,
-		&q.TargetCount, &q.RewardGems, &q.IsActive, &q.SortOrder, &q.CreatedAt, &q.UpdatedAt)
+		&q.TargetCount, &q.RewardGems, &q.RewardCoins, &q.RewardGK, &q.IsActive, &q.SortOrder, &q.CreatedAt, &q.UpdatedAt)
 
 	if err != nil {
 		return nil, err
@@ -77,7 +79,7 @@ func (r *QuestRepository) GetUserQuests(ctx context.Context, userID int64) ([]*d
 			uq.id, uq.user_id, uq.quest_id, uq.current_count, uq.completed,
 			uq.reward_claimed, uq.started_at, uq.completed_at, uq.reward_claimed_at, uq.period_start,
 			q.id, q.quest_type, q.title, q.description, q.game_type, q.action_type,
-			q.target_count, q.reward_gems, q.is_active, q.sort_order, q.created_at, q.updated_at
+			q.target_count, q.reward_gems, q.reward_coins, q.reward_gk, q.is_active, q.sort_order, q.created_at, q.updated_at
 		 FROM user_quests uq
 		 JOIN quests q ON uq.quest_id = q.id
 		 WHERE uq.user_id = $1 AND q.is_active = true
@@ -97,7 +99,7 @@ func (r *QuestRepository) GetUserQuests(ctx context.Context, userID int64) ([]*d
 			&uqd.RewardClaimed, &uqd.StartedAt, &uqd.CompletedAt, &uqd.RewardClaimedAt, &uqd.PeriodStart,
 			&uqd.Quest.ID, &uqd.Quest.QuestType, &uqd.Quest.Title, &uqd.Quest.Description,
 			&uqd.Quest.GameType, &uqd.Quest.ActionType, &uqd.Quest.TargetCount, &uqd.Quest.RewardGems,
-			&uqd.Quest.IsActive, &uqd.Quest.SortOrder, &uqd.Quest.CreatedAt, &uqd.Quest.UpdatedAt,
+			&uqd.Quest.RewardCoins, &uqd.Quest.RewardGK, &uqd.Quest.IsActive, &uqd.Quest.SortOrder, &uqd.Quest.CreatedAt, &uqd.Quest.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -108,6 +110,27 @@ func (r *QuestRepository) GetUserQuests(ctx context.Context, userID int64) ([]*d
 	return result, nil
 }
 
+// GetUserQuestForPeriod возвращает прогресс пользователя по квесту за текущий период (nil если ещё не начат)
+func (r *QuestRepository) GetUserQuestForPeriod(ctx context.Context, userID int64, quest *domain.Quest) (*domain.UserQuest, error) {
+	var uq domain.UserQuest
+	err := r.db.QueryRow(ctx,
+		`SELECT id, user_id, quest_id, current_count, completed, reward_claimed,
+				started_at, completed_at, reward_claimed_at, period_start
+		 FROM user_quests
+		 WHERE user_id = $1 AND quest_id = $2 AND period_start = $3`,
+		userID, quest.ID, r.getPeriodStart(quest.QuestType),
+	).Scan(&uq.ID, &uq.UserID, &uq.QuestID, &uq.CurrentCount, &uq.Completed,
+		&uq.RewardClaimed, &uq.StartedAt, &uq.CompletedAt, &uq.RewardClaimedAt, &uq.PeriodStart)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &uq, nil
+}
+
 // GetOrCreateUserQuest получает или создаёт прогресс пользователя по квесту
 func (r *QuestRepository) GetOrCreateUserQuest(ctx context.Context, userID, questID int64, periodStart time.Time) (*domain.UserQuest, error) {
 	var uq domain.UserQuest
@@ -154,43 +177,64 @@ func (r *QuestRepository) UpdateProgress(ctx context.Context, uq *domain.UserQue
 	return err
 }
 
-// ClaimReward отмечает награду как полученную и возвращает количество gems
-func (r *QuestRepository) ClaimReward(ctx context.Context, userQuestID int64) (int64, error) {
-	var rewardGems int64
-	now := time.Now()
-
-	err := r.db.QueryRow(ctx,
+// ClaimRewardWithTx отмечает награду пользователя как полученную в рамках транзакции
+// и возвращает награду квеста во всех валютах
+func (r *QuestRepository) ClaimRewardWithTx(ctx context.Context, tx pgx.Tx, userID, userQuestID int64) (*domain.Quest, error) {
+	var q domain.Quest
+	err := tx.QueryRow(ctx,
 		`UPDATE user_quests uq
 		 SET reward_claimed = true, reward_claimed_at = $1
 		 FROM quests q
 		 WHERE uq.id = $2
+		   AND uq.user_id = $3
 		   AND uq.quest_id = q.id
 		   AND uq.completed = true
 		   AND uq.reward_claimed = false
-		 RETURNING q.reward_gems`,
-		now, userQuestID,
-	).Scan(&rewardGems)
+		 RETURNING q.id, q.title, q.reward_gems, q.reward_coins, q.reward_gk`,
+		time.Now(), userQuestID, userID,
+	).Scan(&q.ID, &q.Title, &q.RewardGems, &q.RewardCoins, &q.RewardGK)
 
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
+	return &q, nil
+}
 
-	return rewardGems, nil
+// RewardClaimedWithTx сообщает, получена ли награда; pgx.ErrNoRows - квеста у пользователя нет
+func (r *QuestRepository) RewardClaimedWithTx(ctx context.Context, tx pgx.Tx, userID, userQuestID int64) (bool, error) {
+	var claimed bool
+	err := tx.QueryRow(ctx,
+		`SELECT reward_claimed FROM user_quests WHERE id = $1 AND user_id = $2`,
+		userQuestID, userID,
+	).Scan(&claimed)
+	return claimed, err
 }
 
-// IncrementProgress увеличивает прогресс и проверяет завершение
-func (r *QuestRepository) IncrementProgress(ctx context.Context, userID int64, quest *domain.Quest, increment int) error {
+// SetClaimedWithTx сохраняет фактически начисленную награду
+func (r *QuestRepository) SetClaimedWithTx(ctx context.Context, tx pgx.Tx, userQuestID int64, reward domain.QuestReward) error {
+	_, err := tx.Exec(ctx,
+		`UPDATE user_quests
+		 SET claimed_gems = $1, claimed_coins = $2, claimed_gk = $3
+		 WHERE id = $4`,
+		reward.Gems, reward.Coins, reward.GK, userQuestID,
+	)
+	return err
+}
+
+// IncrementProgress увеличивает прогресс и проверяет завершение.
+// Возвращает true, если квест выполнен именно этим шагом.
+func (r *QuestRepository) IncrementProgress(ctx context.Context, userID int64, quest *domain.Quest, increment int) (bool, error) {
 	periodStart := r.getPeriodStart(quest.QuestType)
 
 	// Получаем или создаём запись прогресса
 	uq, err := r.GetOrCreateUserQuest(ctx, userID, quest.ID, periodStart)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	// Если уже выполнено - ничего не делаем
 	if uq.Completed {
-		return nil
+		return false, nil
 	}
 
 	// Увеличиваем прогресс
@@ -203,7 +247,10 @@ func (r *QuestRepository) IncrementProgress(ctx context.Context, userID int64, q
 		uq.CompletedAt = &now
 	}
 
-	return r.UpdateProgress(ctx, uq)
+	if err := r.UpdateProgress(ctx, uq); err != nil {
+		return false, err
+	}
+	return uq.Completed, nil
 }
 
 // ResetDailyQuests сбрасывает ежедневные квесты (вызывать по cron)
@@ -261,7 +308,7 @@ func (r *QuestRepository) scanQuests(rows interface{ Next() bool; Scan(dest ...i
 	for rows.Next() {
 		var q domain.Quest
 		err := rows.Scan(&q.ID, &q.QuestType, &q.Title, &q.Description, &q.GameType, &q.ActionType,
-			&q.TargetCount, &q.RewardGems, &q.IsActive, &q.SortOrder, &q.CreatedAt, &q.UpdatedAt)
+			&q.TargetCount, &q.RewardGems, &q.RewardCoins, &q.RewardGK, &q.IsActive, &q.SortOrder, &q.CreatedAt, &q.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -4,22 +4,34 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ReferralBonusGems - награда пригласившему за квалифицированного реферала
+const ReferralBonusGems = 500
+
+var (
+	ErrSelfReferral  = errors.New("cannot refer yourself")
+	ErrReferralCycle = errors.New("referral would create a cycle")
+)
+
 type Referral struct {
 	ID          int64     `json:"id"`
 	ReferrerID  int64     `json:"referrer_id"`
 	ReferredID  int64     `json:"referred_id"`
 	BonusClaimed bool     `json:"bonus_claimed"`
+	Status      string    `json:"status"` // pending - награда ждёт квалификации реферала
 	CreatedAt   time.Time `json:"created_at"`
 }
 
 type ReferralStats struct {
-	TotalReferrals  int   `json:"total_referrals"`
-	TotalEarned     int64 `json:"total_earned"`
+	TotalReferrals   int   `json:"total_referrals"`
+	PendingReferrals int   `json:"pending_referrals"`
+	TotalEarned      int64 `json:"total_earned"`
 }
 
 type ReferralRepository struct {
@@ -75,24 +87,75 @@ func (r *ReferralRepository) GetUserByReferralCode(ctx context.Context, code str
 	return userID, err
 }
 
-// CreateReferral creates a new referral relationship
-func (r *ReferralRepository) CreateReferral(ctx context.Context, referrerID, referredID int64) error {
-	_, err := r.db.Exec(ctx,
+// CreateReferral creates a new referral relationship. The referrals row and
+// users.referred_by are written in one transaction so they always agree.
+// Returns false if the user was already referred (nothing is changed),
+// ErrSelfReferral for the user's own code and ErrReferralCycle if referredID
+// is already somewhere up the referrer's chain (A referred B, B refers A).
+func (r *ReferralRepository) CreateReferral(ctx context.Context, referrerID, referredID int64) (bool, error) {
+	if referrerID == referredID {
+		return false, ErrSelfReferral
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Связи создаются по одной, иначе два встречных запроса пройдут проверку цикла
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('referral'))`); err != nil {
+		return false, err
+	}
+
+	var cycle bool
+	err = tx.QueryRow(ctx,
+		`WITH RECURSIVE chain(id, depth) AS (
+		     SELECT referred_by, 1 FROM users WHERE id = $1 AND referred_by IS NOT NULL
+		     UNION
+		     SELECT u.referred_by, c.depth + 1
+		     FROM users u JOIN chain c ON u.id = c.id
+		     WHERE u.referred_by IS NOT NULL AND c.depth < 1000
+		 )
+		 SELECT EXISTS (SELECT 1 FROM chain WHERE id = $2)`,
+		referrerID, referredID,
+	).Scan(&cycle)
+	if err != nil {
+		return false, err
+	}
+	if cycle {
+		return false, ErrReferralCycle
+	}
+
+	var id int64
+	err = tx.QueryRow(ctx,
 		`INSERT INTO referrals (referrer_id, referred_id)
 		 VALUES ($1, $2)
-		 ON CONFLICT (referred_id) DO NOTHING`,
+		 ON CONFLICT (referred_id) DO NOTHING
+		 RETURNING id`,
 		referrerID, referredID,
-	)
+	).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	// Also update the referred_by field in users table
-	_, err = r.db.Exec(ctx,
+	tag, err := tx.Exec(ctx,
 		`UPDATE users SET referred_by = $1 WHERE id = $2 AND referred_by IS NULL`,
 		referrerID, referredID,
 	)
-	return err
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		// referred_by уже задан - откатываем вставку, чтобы не разойтись
+		return false, nil
+	}
+
+	return true, tx.Commit(ctx)
 }
 
 // GetReferralsByUser returns all referrals made by a user
@@ -115,31 +178,131 @@ func (r *ReferralRepository) GetReferralsByUser(ctx context.Context, userID int6
 		if err := rows.Scan(&ref.ID, &ref.ReferrerID, &ref.ReferredID, &ref.BonusClaimed, &ref.CreatedAt); err != nil {
 			continue
 		}
+		ref.Status = "pending"
+		if ref.BonusClaimed {
+			ref.Status = "rewarded"
+		}
 		referrals = append(referrals, ref)
 	}
 
 	return referrals, nil
 }
 
+// ReferralTreeEntry is a direct referral with activity signals
+type ReferralTreeEntry struct {
+	ReferredID    int64     `json:"-"`
+	Name          string    `json:"-"` // raw name, anonymized by handler
+	JoinedAt      time.Time `json:"joined_at"`
+	HasPlayed     bool      `json:"has_played"`
+	HasDeposited  bool      `json:"has_deposited"`
+	EarningsCoins int64     `json:"earnings_coins"` // commission earned by referrer from this user
+}
+
+// GetReferralTree returns a page of user's direct referrals with activity signals and total count
+func (r *ReferralRepository) GetReferralTree(ctx context.Context, userID int64, limit, offset int) ([]ReferralTreeEntry, int, error) {
+	var total int
+	if err := r.db.QueryRow(ctx,
+		`SELECT COUNT(*) FROM referrals WHERE referrer_id = $1`,
+		userID,
+	).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.Query(ctx,
+		`SELECT r.referred_id,
+		        COALESCE(NULLIF(u.first_name, ''), u.username, ''),
+		        r.created_at,
+		        EXISTS (SELECT 1 FROM game_history gh WHERE gh.user_id = r.referred_id),
+		        EXISTS (SELECT 1 FROM deposits d WHERE d.user_id = r.referred_id AND d.status = 'confirmed'),
+		        COALESCE((
+		            SELECT SUM(t.amount) FROM transactions t
+		            WHERE t.user_id = r.referrer_id
+		              AND t.type IN ('referral_commission', 'referral_commission_reversal')
+		              AND (t.meta->>'from_user_id')::bigint = r.referred_id
+		        ), 0)
+		 FROM referrals r
+		 JOIN users u ON u.id = r.referred_id
+		 WHERE r.referrer_id = $1
+		 ORDER BY r.created_at DESC
+		 LIMIT $2 OFFSET $3`,
+		userID, limit, offset,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []ReferralTreeEntry{}
+	for rows.Next() {
+		var e ReferralTreeEntry
+		if err := rows.Scan(&e.ReferredID, &e.Name, &e.JoinedAt, &e.HasPlayed, &e.HasDeposited, &e.EarningsCoins); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, total, rows.Err()
+}
+
 // GetReferralStats returns referral statistics for a user
 func (r *ReferralRepository) GetReferralStats(ctx context.Context, userID int64) (*ReferralStats, error) {
 	stats := &ReferralStats{}
 
-	// Count total referrals
+	// Count total and not yet rewarded referrals
 	err := r.db.QueryRow(ctx,
-		`SELECT COUNT(*) FROM referrals WHERE referrer_id = $1`,
+		`SELECT COUNT(*), COUNT(*) FILTER (WHERE NOT bonus_claimed)
+		 FROM referrals WHERE referrer_id = $1`,
 		userID,
-	).Scan(&stats.TotalReferrals)
+	).Scan(&stats.TotalReferrals, &stats.PendingReferrals)
 	if err != nil {
 		return nil, err
 	}
 
-	// Calculate total earned (500 gems per referral)
-	stats.TotalEarned = int64(stats.TotalReferrals) * 500
+	// Награда начисляется только за квалифицированных рефералов
+	stats.TotalEarned = int64(stats.TotalReferrals-stats.PendingReferrals) * ReferralBonusGems
 
 	return stats, nil
 }
 
+// GetPendingReferral returns the unrewarded referral of a referred user, nil if none
+func (r *ReferralRepository) GetPendingReferral(ctx context.Context, referredID int64) (*Referral, error) {
+	var ref Referral
+	err := r.db.QueryRow(ctx,
+		`SELECT id, referrer_id, referred_id, bonus_claimed, created_at
+		 FROM referrals
+		 WHERE referred_id = $1 AND bonus_claimed = false`,
+		referredID,
+	).Scan(&ref.ID, &ref.ReferrerID, &ref.ReferredID, &ref.BonusClaimed, &ref.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ref.Status = "pending"
+	return &ref, nil
+}
+
+// ReferredActivity - то, по чему решается выплата награды за реферала
+type ReferredActivity struct {
+	Games         int
+	Deposited     bool // есть подтверждённый депозит
+	FlaggedSignup bool // регистрация помечена SignupGuard
+}
+
+// GetReferredActivity returns whether a confirmed deposit exists, whether the
+// signup was flagged and, with countGames, the number of games played
+func (r *ReferralRepository) GetReferredActivity(ctx context.Context, referredID int64, countGames bool) (ReferredActivity, error) {
+	var a ReferredActivity
+	err := r.db.QueryRow(ctx,
+		`SELECT CASE WHEN $2 THEN (SELECT COUNT(*) FROM game_history WHERE user_id = $1) ELSE 0 END,
+		        EXISTS (SELECT 1 FROM deposits WHERE user_id = $1 AND status = 'confirmed'),
+		        EXISTS (SELECT 1 FROM signups WHERE user_id = $1 AND flagged)`,
+		referredID, countGames,
+	).Scan(&a.Games, &a.Deposited, &a.FlaggedSignup)
+	return a, err
+}
+
 // ClaimReferralBonus marks bonus as claimed and gives rewards
 func (r *ReferralRepository) ClaimReferralBonus(ctx context.Context, referralID int64, referrerID int64) error {
 	tx, err := r.db.Begin(ctx)
@@ -164,8 +327,18 @@ func (r *ReferralRepository) ClaimReferralBonus(ctx context.Context, referralID
 
 	// Add bonus gems to referrer
 	_, err = tx.Exec(ctx,
-		`UPDATE users SET gems = gems + 500 WHERE id = $1`,
-		referrerID,
+		`UPDATE users SET gems = gems + $1 WHERE id = $2`,
+		ReferralBonusGems, referrerID,
+	)
+	if err != nil {
+		return err
+	}
+
+	// Награда - промо gems: по записи её не дают перевести другому игроку
+	_, err = tx.Exec(ctx,
+		`INSERT INTO transactions (user_id, type, amount, meta)
+		 VALUES ($1, 'referral_bonus', $2, jsonb_build_object('referral_id', $3::bigint))`,
+		referrerID, ReferralBonusGems, referralID,
 	)
 	if err != nil {
 		return err
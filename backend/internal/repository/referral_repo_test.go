@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"telegram_webapp/internal/domain"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Integration-style test: runs only if TEST_DATABASE_URL env is set.
+func TestCreateReferralAlreadyReferred(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set; skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	db, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+
+	users := NewUserRepository(db)
+	base := time.Now().UnixNano()
+	var ids []int64
+	for i := int64(0); i < 3; i++ {
+		u := &domain.User{TgID: base + i, Username: "referral_test"}
+		if err := users.Create(ctx, u); err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+		ids = append(ids, u.ID)
+	}
+	defer db.Exec(context.Background(), `DELETE FROM users WHERE id = ANY($1)`, ids)
+	first, second, referred := ids[0], ids[1], ids[2]
+
+	repo := NewReferralRepository(db)
+
+	created, err := repo.CreateReferral(ctx, first, referred)
+	if err != nil || !created {
+		t.Fatalf("first referral: created=%v err=%v", created, err)
+	}
+
+	created, err = repo.CreateReferral(ctx, second, referred)
+	if err != nil {
+		t.Fatalf("second referral: %v", err)
+	}
+	if created {
+		t.Fatal("expected already referred user not to get a new referral")
+	}
+
+	var referrerID, referredBy int64
+	if err := db.QueryRow(ctx, `SELECT referrer_id FROM referrals WHERE referred_id = $1`, referred).Scan(&referrerID); err != nil {
+		t.Fatalf("load referral: %v", err)
+	}
+	if err := db.QueryRow(ctx, `SELECT referred_by FROM users WHERE id = $1`, referred).Scan(&referredBy); err != nil {
+		t.Fatalf("load referred_by: %v", err)
+	}
+	if referrerID != first || referredBy != first {
+		t.Fatalf("expected referrer %d in both places, got referrals=%d users=%d", first, referrerID, referredBy)
+	}
+}
+
+// Integration-style test: runs only if TEST_DATABASE_URL env is set.
+func TestCreateReferralRejectsSelfAndCycles(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set; skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	db, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+
+	users := NewUserRepository(db)
+	base := time.Now().UnixNano()
+	var ids []int64
+	for i := int64(0); i < 3; i++ {
+		u := &domain.User{TgID: base + i, Username: "referral_cycle_test"}
+		if err := users.Create(ctx, u); err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+		ids = append(ids, u.ID)
+	}
+	defer db.Exec(context.Background(), `DELETE FROM users WHERE id = ANY($1)`, ids)
+	a, b, c := ids[0], ids[1], ids[2]
+
+	repo := NewReferralRepository(db)
+
+	if _, err := repo.CreateReferral(ctx, a, a); !errors.Is(err, ErrSelfReferral) {
+		t.Fatalf("self-referral: expected ErrSelfReferral, got %v", err)
+	}
+
+	if created, err := repo.CreateReferral(ctx, a, b); err != nil || !created {
+		t.Fatalf("a refers b: created=%v err=%v", created, err)
+	}
+	// Повторное применение - уже есть referred_by
+	if created, err := repo.CreateReferral(ctx, a, b); err != nil || created {
+		t.Fatalf("double apply: expected created=false, got created=%v err=%v", created, err)
+	}
+
+	// b пытается пригласить a
+	if _, err := repo.CreateReferral(ctx, b, a); !errors.Is(err, ErrReferralCycle) {
+		t.Fatalf("mutual referral: expected ErrReferralCycle, got %v", err)
+	}
+
+	// a -> b -> c, c пытается пригласить a
+	if created, err := repo.CreateReferral(ctx, b, c); err != nil || !created {
+		t.Fatalf("b refers c: created=%v err=%v", created, err)
+	}
+	if _, err := repo.CreateReferral(ctx, c, a); !errors.Is(err, ErrReferralCycle) {
+		t.Fatalf("longer loop: expected ErrReferralCycle, got %v", err)
+	}
+
+	var referredBy *int64
+	if err := db.QueryRow(ctx, `SELECT referred_by FROM users WHERE id = $1`, a).Scan(&referredBy); err != nil {
+		t.Fatalf("load referred_by: %v", err)
+	}
+	if referredBy != nil {
+		t.Fatalf("expected a to stay unreferred, got referred_by=%d", *referredBy)
+	}
+}
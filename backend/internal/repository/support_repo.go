@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"telegram_webapp/internal/domain"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SupportRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSupportRepository(db *pgxpool.Pool) *SupportRepository {
+	return &SupportRepository{db: db}
+}
+
+// Create stores a report; server_state is captured by the caller from the users row
+func (r *SupportRepository) Create(ctx context.Context, rep *domain.SupportReport) error {
+	return r.db.QueryRow(ctx, `
+		INSERT INTO support_reports (user_id, category, message, client_state, server_state)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, status, created_at
+	`, rep.UserID, rep.Category, rep.Message, rep.ClientState, rep.ServerState).Scan(&rep.ID, &rep.Status, &rep.CreatedAt)
+}
+
+// CountSince returns how many reports the user sent after the given time
+func (r *SupportRepository) CountSince(ctx context.Context, userID int64, since time.Time) (int, error) {
+	var n int
+	err := r.db.QueryRow(ctx,
+		`SELECT COUNT(*) FROM support_reports WHERE user_id = $1 AND created_at >= $2`,
+		userID, since,
+	).Scan(&n)
+	return n, err
+}
+
+// GetRecent returns latest reports with usernames, newest first
+func (r *SupportRepository) GetRecent(ctx context.Context, limit int) ([]domain.SupportReport, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT s.id, s.user_id, COALESCE(u.username, ''), s.category, s.message,
+		       s.client_state, s.server_state, s.status, s.created_at
+		FROM support_reports s
+		JOIN users u ON u.id = s.user_id
+		ORDER BY s.created_at DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []domain.SupportReport{}
+	for rows.Next() {
+		var rep domain.SupportReport
+		if err := rows.Scan(&rep.ID, &rep.UserID, &rep.Username, &rep.Category, &rep.Message,
+			&rep.ClientState, &rep.ServerState, &rep.Status, &rep.CreatedAt); err != nil {
+			return nil, err
+		}
+		reports = append(reports, rep)
+	}
+	return reports, rows.Err()
+}
@@ -3,6 +3,9 @@ package repository
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
 	"time"
 
 	"telegram_webapp/internal/domain"
@@ -11,6 +14,37 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrMetaTooLarge - сериализованная meta транзакции больше лимита
+var ErrMetaTooLarge = errors.New("transaction meta too large")
+
+// DefaultMaxMetaBytes - лимит meta по умолчанию
+const DefaultMaxMetaBytes = 8 * 1024
+
+var maxMetaBytes atomic.Int64
+
+func init() {
+	maxMetaBytes.Store(DefaultMaxMetaBytes)
+}
+
+// SetMaxMetaBytes sets the limit on serialized transaction meta; non-positive values are ignored
+func SetMaxMetaBytes(n int) {
+	if n > 0 {
+		maxMetaBytes.Store(int64(n))
+	}
+}
+
+// marshalMeta serializes meta, rejecting blobs over the limit
+func marshalMeta(meta map[string]interface{}) ([]byte, error) {
+	metaJSON, err := json.Marshal(meta)
+	if err != nil {
+		return []byte("{}"), nil
+	}
+	if int64(len(metaJSON)) > maxMetaBytes.Load() {
+		return nil, ErrMetaTooLarge
+	}
+	return metaJSON, nil
+}
+
 type TransactionRepository struct {
 	db *pgxpool.Pool
 }
@@ -43,9 +77,9 @@ func (r *TransactionRepository) GetByUserID(ctx context.Context, userID int64, l
 
 // Create inserts a new transaction
 func (r *TransactionRepository) Create(ctx context.Context, tx *domain.Transaction) error {
-	metaJSON, err := json.Marshal(tx.Meta)
+	metaJSON, err := marshalMeta(tx.Meta)
 	if err != nil {
-		metaJSON = []byte("{}")
+		return err
 	}
 
 	return r.db.QueryRow(ctx,
@@ -58,9 +92,9 @@ func (r *TransactionRepository) Create(ctx context.Context, tx *domain.Transacti
 
 // CreateWithTx inserts a transaction using an existing database transaction
 func (r *TransactionRepository) CreateWithTx(ctx context.Context, dbTx pgx.Tx, tx *domain.Transaction) error {
-	metaJSON, err := json.Marshal(tx.Meta)
+	metaJSON, err := marshalMeta(tx.Meta)
 	if err != nil {
-		metaJSON = []byte("{}")
+		return err
 	}
 
 	return dbTx.QueryRow(ctx,
@@ -93,6 +127,54 @@ func (r *TransactionRepository) GetByUserIDAndType(ctx context.Context, userID i
 	return r.scanRows(rows)
 }
 
+// SumByTypes returns the sum of amounts of the user's transactions of the given types since the time
+func (r *TransactionRepository) SumByTypes(ctx context.Context, userID int64, types []string, since time.Time) (int64, error) {
+	var sum int64
+	err := r.db.QueryRow(ctx,
+		`SELECT COALESCE(SUM(amount), 0) FROM transactions
+		 WHERE user_id = $1 AND type = ANY($2) AND created_at >= $3`,
+		userID, types, since,
+	).Scan(&sum)
+	return sum, err
+}
+
+// TransactionFilter - параметры страницы истории транзакций
+type TransactionFilter struct {
+	Type     string // пусто - все типы
+	BeforeID int64  // курсор: только транзакции с id меньше этого, 0 - с начала
+	Limit    int
+}
+
+// List returns a page of user's transactions, newest first
+func (r *TransactionRepository) List(ctx context.Context, userID int64, f TransactionFilter) ([]*domain.Transaction, error) {
+	if f.Limit <= 0 {
+		f.Limit = 100
+	}
+
+	query := `SELECT id, user_id, type, amount, meta, created_at
+		 FROM transactions
+		 WHERE user_id = $1`
+	args := []interface{}{userID}
+	if f.Type != "" {
+		args = append(args, f.Type)
+		query += fmt.Sprintf(" AND type = $%d", len(args))
+	}
+	if f.BeforeID > 0 {
+		args = append(args, f.BeforeID)
+		query += fmt.Sprintf(" AND id < $%d", len(args))
+	}
+	args = append(args, f.Limit)
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d", len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanRows(rows)
+}
+
 // Helper to scan rows into Transaction slice
 func (r *TransactionRepository) scanRows(rows pgx.Rows) ([]*domain.Transaction, error) {
 	var result []*domain.Transaction
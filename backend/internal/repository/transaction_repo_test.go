@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"telegram_webapp/internal/domain"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestMarshalMetaLimit(t *testing.T) {
+	defer SetMaxMetaBytes(DefaultMaxMetaBytes)
+	SetMaxMetaBytes(64)
+
+	if _, err := marshalMeta(map[string]interface{}{"game": "dice"}); err != nil {
+		t.Fatalf("small meta rejected: %v", err)
+	}
+	big := map[string]interface{}{"blob": strings.Repeat("x", 100)}
+	if _, err := marshalMeta(big); !errors.Is(err, ErrMetaTooLarge) {
+		t.Fatalf("expected ErrMetaTooLarge, got %v", err)
+	}
+
+	// non-positive limit is ignored
+	SetMaxMetaBytes(0)
+	if _, err := marshalMeta(big); !errors.Is(err, ErrMetaTooLarge) {
+		t.Fatalf("expected limit to stay at 64 bytes, got %v", err)
+	}
+}
+
+// Integration-style test: runs only if TEST_DATABASE_URL env is set.
+func TestListTransactionsPages(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set; skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	db, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+
+	u := &domain.User{TgID: time.Now().UnixNano(), Username: "tx_list_test"}
+	if err := NewUserRepository(db).Create(ctx, u); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	defer db.Exec(context.Background(), `DELETE FROM users WHERE id = $1`, u.ID)
+	defer db.Exec(context.Background(), `DELETE FROM transactions WHERE user_id = $1`, u.ID)
+
+	repo := NewTransactionRepository(db)
+	for i := 0; i < 5; i++ {
+		txType := "coinflip"
+		if i%2 == 1 {
+			txType = "ton_deposit"
+		}
+		if err := repo.Create(ctx, &domain.Transaction{UserID: u.ID, Type: txType, Amount: int64(i)}); err != nil {
+			t.Fatalf("create tx: %v", err)
+		}
+	}
+
+	first, err := repo.List(ctx, u.ID, TransactionFilter{Limit: 2})
+	if err != nil || len(first) != 2 || first[0].Amount != 4 || first[1].Amount != 3 {
+		t.Fatalf("unexpected first page: %v, %v", first, err)
+	}
+	rest, err := repo.List(ctx, u.ID, TransactionFilter{Limit: 10, BeforeID: first[1].ID})
+	if err != nil || len(rest) != 3 || rest[0].Amount != 2 {
+		t.Fatalf("unexpected second page: %v, %v", rest, err)
+	}
+
+	deposits, err := repo.List(ctx, u.ID, TransactionFilter{Type: "ton_deposit"})
+	if err != nil || len(deposits) != 2 {
+		t.Fatalf("expected 2 deposits, got %v, %v", deposits, err)
+	}
+}
@@ -3,14 +3,19 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"telegram_webapp/internal/domain"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 var ErrInsufficientFunds = errors.New("insufficient funds")
 
+// AnonymousPlayerName replaces the name of users hidden from public leaderboards
+const AnonymousPlayerName = "Anonymous Player"
+
 type UserRepository struct {
 	db *pgxpool.Pool
 }
@@ -49,10 +54,10 @@ func (r *UserRepository) GetByTgID(ctx context.Context, tgID int64) (*domain.Use
 	return &u, nil
 }
 
-func (r *UserRepository) Create(ctx context.Context, u *domain.User) error {
-	// Начальный баланс для новых пользователей
-	const initialGems = 10000
+// InitialGems - начальный баланс новых пользователей
+const InitialGems = 10000
 
+func (r *UserRepository) Create(ctx context.Context, u *domain.User) error {
 	return r.db.QueryRow(ctx,
 		`INSERT INTO users (tg_id, username, first_name, gems)
 		 VALUES ($1, $2, $3, $4)
@@ -60,7 +65,7 @@ func (r *UserRepository) Create(ctx context.Context, u *domain.User) error {
 		u.TgID,
 		u.Username,
 		u.FirstName,
-		initialGems,
+		InitialGems,
 	).Scan(&u.ID)
 }
 
@@ -165,6 +170,52 @@ func (r *UserRepository) GetGems(ctx context.Context, userID int64) (int64, erro
 	return gems, err
 }
 
+// IsBanned reports whether the user is banned by an admin
+func (r *UserRepository) IsBanned(ctx context.Context, userID int64) (bool, error) {
+	var banned bool
+	err := r.db.QueryRow(ctx, `SELECT is_banned FROM users WHERE id = $1`, userID).Scan(&banned)
+	return banned, err
+}
+
+// ExcludedUntil returns the end of the user's active self-exclusion, nil if there is none
+func (r *UserRepository) ExcludedUntil(ctx context.Context, userID int64) (*time.Time, error) {
+	var until *time.Time
+	err := r.db.QueryRow(ctx,
+		`SELECT excluded_until FROM users WHERE id = $1 AND excluded_until > now()`,
+		userID,
+	).Scan(&until)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return until, err
+}
+
+// SelfExclude locks the user out until the given time. An active exclusion is
+// only ever extended, never shortened, so it can't be lifted early this way.
+func (r *UserRepository) SelfExclude(ctx context.Context, userID int64, until time.Time) (time.Time, error) {
+	var excludedUntil time.Time
+	err := r.db.QueryRow(ctx,
+		`UPDATE users SET excluded_until = GREATEST(COALESCE(excluded_until, $2), $2)
+		 WHERE id = $1
+		 RETURNING excluded_until`,
+		userID, until,
+	).Scan(&excludedUntil)
+	return excludedUntil, err
+}
+
+// GetLeaderboardVisible reports whether the user's name is shown on public leaderboards
+func (r *UserRepository) GetLeaderboardVisible(ctx context.Context, userID int64) (bool, error) {
+	var visible bool
+	err := r.db.QueryRow(ctx, `SELECT leaderboard_visible FROM users WHERE id = $1`, userID).Scan(&visible)
+	return visible, err
+}
+
+// SetLeaderboardVisible updates the leaderboard privacy preference
+func (r *UserRepository) SetLeaderboardVisible(ctx context.Context, userID int64, visible bool) error {
+	_, err := r.db.Exec(ctx, `UPDATE users SET leaderboard_visible = $1 WHERE id = $2`, visible, userID)
+	return err
+}
+
 // UpdateGK updates user's GK balance
 func (r *UserRepository) UpdateGK(ctx context.Context, userID int64, delta int64) (int64, error) {
 	var newBalance int64
@@ -220,13 +271,15 @@ type TopUserEntry struct {
 	Rank      int          `json:"rank"`
 	User      domain.User  `json:"user"`
 	WinsCount int64        `json:"wins_count"`
+	Anonymous bool         `json:"anonymous"`
 }
 
 // GetMonthlyTop returns top users by wins count in the current month
 func (r *UserRepository) GetMonthlyTop(ctx context.Context, limit int) ([]TopUserEntry, error) {
 	rows, err := r.db.Query(ctx, `
 		SELECT u.id, u.tg_id, COALESCE(u.username, ''), COALESCE(u.first_name, ''),
-		       u.gems, COALESCE(u.coins, 0), u.created_at, COALESCE(w.wins, 0) as wins_count
+		       u.gems, COALESCE(u.coins, 0), u.created_at, COALESCE(w.wins, 0) as wins_count,
+		       u.leaderboard_visible
 		FROM users u
 		LEFT JOIN (
 			SELECT user_id, COUNT(*) as wins
@@ -246,14 +299,20 @@ func (r *UserRepository) GetMonthlyTop(ctx context.Context, limit int) ([]TopUse
 	for rows.Next() {
 		var u domain.User
 		var winsCount int64
+		var visible bool
 		if err := rows.Scan(&u.ID, &u.TgID, &u.Username, &u.FirstName, &u.Gems, &u.Coins,
-			&u.CreatedAt, &winsCount); err != nil {
+			&u.CreatedAt, &winsCount, &visible); err != nil {
 			return nil, err
 		}
+		// Скрытый пользователь занимает своё место, но без имени и id
+		if !visible {
+			u = domain.User{FirstName: AnonymousPlayerName, Gems: u.Gems, Coins: u.Coins}
+		}
 		res = append(res, TopUserEntry{
 			Rank:      rank,
 			User:      u,
 			WinsCount: winsCount,
+			Anonymous: !visible,
 		})
 		rank++
 	}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+
+	"telegram_webapp/internal/domain"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WheelConfigRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWheelConfigRepository(db *pgxpool.Pool) *WheelConfigRepository {
+	return &WheelConfigRepository{db: db}
+}
+
+// GetActiveSegments returns active wheel segments in display order (by id).
+// An empty result means the table isn't configured.
+func (r *WheelConfigRepository) GetActiveSegments(ctx context.Context) ([]*domain.WheelSegment, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, multiplier, color, label, weight, active
+		FROM wheel_segments
+		WHERE active
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var segments []*domain.WheelSegment
+	for rows.Next() {
+		s := &domain.WheelSegment{}
+		if err := rows.Scan(&s.ID, &s.Multiplier, &s.Color, &s.Label, &s.Weight, &s.Active); err != nil {
+			return nil, err
+		}
+		segments = append(segments, s)
+	}
+	return segments, rows.Err()
+}
@@ -2,14 +2,23 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"time"
 
 	"telegram_webapp/internal/domain"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrWithdrawalPending - у пользователя уже есть вывод в обработке
+var ErrWithdrawalPending = errors.New("withdrawal already pending")
+
+// ErrWithdrawalNotRefundable - вывод уже отправлен или закрыт, монеты не вернуть
+var ErrWithdrawalNotRefundable = errors.New("withdrawal is not refundable")
+
 type WithdrawalRepository struct {
 	db *pgxpool.Pool
 }
@@ -77,6 +86,208 @@ func (r *WithdrawalRepository) Create(ctx context.Context, w *domain.Withdrawal)
 	`, w.UserID, w.WalletAddress, w.CoinsAmount, w.TonAmountNano, w.FeeCoins, w.ExchangeRate, w.Status).Scan(&w.ID, &w.CreatedAt)
 }
 
+// ReferralCommissionCredit - доля комиссии за вывод, которая уходит рефереру
+type ReferralCommissionCredit struct {
+	ReferrerID int64
+	Amount     int64
+	Meta       map[string]interface{} // попадает в meta транзакции referral_commission
+	Credited   bool                   // выставляется, если комиссия начислена этим вызовом
+}
+
+// CreatePending creates a withdrawal only if the user has no pending or processing one.
+// Concurrent requests are serialized by a per-user advisory lock; the unique partial
+// index idx_ton_withdrawals_one_pending is the last line of defence.
+// CoinsAmount is held (deducted) from the user in the same transaction and recorded
+// as a withdraw_hold transaction; ErrInsufficientFunds if the balance is too low.
+func (r *WithdrawalRepository) CreatePending(ctx context.Context, w *domain.Withdrawal) error {
+	return r.CreatePendingWithCommission(ctx, w, nil)
+}
+
+// CreatePendingWithCommission is CreatePending that also credits the referrer's
+// commission in the same transaction: either the withdrawal, the hold and the
+// commission are all stored or none of them is. commission may be nil.
+func (r *WithdrawalRepository) CreatePendingWithCommission(ctx context.Context, w *domain.Withdrawal, commission *ReferralCommissionCredit) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('withdrawal'), $1::int)`, w.UserID); err != nil {
+		return err
+	}
+
+	var exists bool
+	if err := tx.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM ton_withdrawals WHERE user_id = $1 AND status IN ('pending', 'processing'))
+	`, w.UserID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return ErrWithdrawalPending
+	}
+
+	// Блокируем строку пользователя до конца транзакции
+	var coins int64
+	if err := tx.QueryRow(ctx, `SELECT coins FROM users WHERE id = $1 FOR UPDATE`, w.UserID).Scan(&coins); err != nil {
+		return err
+	}
+	if coins < w.CoinsAmount {
+		return ErrInsufficientFunds
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO ton_withdrawals (user_id, wallet_address, coins_amount, ton_amount_nano, fee_coins, exchange_rate, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`, w.UserID, w.WalletAddress, w.CoinsAmount, w.TonAmountNano, w.FeeCoins, w.ExchangeRate, w.Status).Scan(&w.ID, &w.CreatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrWithdrawalPending
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE users SET coins = coins - $1 WHERE id = $2`, w.CoinsAmount, w.UserID); err != nil {
+		return err
+	}
+	if err := insertWithdrawalTx(ctx, tx, w.UserID, "withdraw_hold", -w.CoinsAmount, w.ID, ""); err != nil {
+		return err
+	}
+	if commission != nil {
+		credited, err := creditReferralCommissionTx(ctx, tx, w.ID, w.UserID, commission)
+		if err != nil {
+			return err
+		}
+		commission.Credited = credited
+	}
+
+	return tx.Commit(ctx)
+}
+
+// creditReferralCommissionTx pays the referrer's share of a withdrawal fee at most once
+// per withdrawal: idx_transactions_referral_commission_withdrawal makes a repeated
+// insert a no-op, and then the balance is not touched. Returns whether it paid.
+func creditReferralCommissionTx(ctx context.Context, tx pgx.Tx, withdrawalID, fromUserID int64, c *ReferralCommissionCredit) (bool, error) {
+	if c.ReferrerID <= 0 || c.Amount <= 0 {
+		return false, nil
+	}
+
+	meta := make(map[string]interface{}, len(c.Meta)+3)
+	for k, v := range c.Meta {
+		meta[k] = v
+	}
+	meta["type"] = "referral_commission"
+	meta["from_user_id"] = fromUserID
+	meta["withdrawal_id"] = withdrawalID
+	metaB, _ := json.Marshal(meta)
+
+	var txID int64
+	err := tx.QueryRow(ctx, `
+		INSERT INTO transactions (user_id, type, amount, meta)
+		VALUES ($1, 'referral_commission', $2, $3)
+		ON CONFLICT ((meta->>'withdrawal_id')) WHERE type = 'referral_commission' DO NOTHING
+		RETURNING id
+	`, c.ReferrerID, c.Amount, metaB).Scan(&txID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Уже начислено за этот вывод
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE users
+		SET coins = coins + $1, referral_earnings = COALESCE(referral_earnings, 0) + $1
+		WHERE id = $2
+	`, c.Amount, c.ReferrerID)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() != 1 {
+		return false, pgx.ErrNoRows
+	}
+	return true, nil
+}
+
+// insertWithdrawalTx records a balance movement linked to a withdrawal
+func insertWithdrawalTx(ctx context.Context, tx pgx.Tx, userID int64, txType string, amount, withdrawalID int64, reason string) error {
+	meta := map[string]interface{}{"withdrawal_id": withdrawalID}
+	if reason != "" {
+		meta["reason"] = reason
+	}
+	metaB, _ := json.Marshal(meta)
+	_, err := tx.Exec(ctx,
+		`INSERT INTO transactions (user_id, type, amount, meta) VALUES ($1, $2, $3, $4)`,
+		userID, txType, amount, metaB)
+	return err
+}
+
+// refundTx returns held coins to the user and records a withdraw_refund transaction.
+// Only what a withdraw_hold transaction of this withdrawal actually took is
+// returned: withdrawals created before holds existed never deducted coins.
+// The referral commission paid for the withdrawal is taken back in the same
+// transaction: the fee is refunded, so the referrer's share of it is void.
+func refundTx(ctx context.Context, tx pgx.Tx, userID, coins, withdrawalID int64, reason string) error {
+	var held int64
+	if err := tx.QueryRow(ctx, `
+		SELECT COALESCE(-SUM(amount), 0) FROM transactions
+		WHERE user_id = $1 AND type = 'withdraw_hold' AND meta->>'withdrawal_id' = $2::bigint::text
+	`, userID, withdrawalID).Scan(&held); err != nil {
+		return err
+	}
+	if coins > held {
+		coins = held
+	}
+	if coins > 0 {
+		if _, err := tx.Exec(ctx, `UPDATE users SET coins = coins + $1 WHERE id = $2`, coins, userID); err != nil {
+			return err
+		}
+		if err := insertWithdrawalTx(ctx, tx, userID, "withdraw_refund", coins, withdrawalID, reason); err != nil {
+			return err
+		}
+	}
+	_, err := reverseReferralCommissionTx(ctx, tx, withdrawalID)
+	return err
+}
+
+// reverseReferralCommissionTx takes back the referral commission paid for a
+// withdrawal, at most once, and records it as a referral_commission_reversal.
+// The referrer's balance may go below zero if the commission was already spent.
+// Returns the reversed amount, 0 if nothing was paid or it was already reversed.
+func reverseReferralCommissionTx(ctx context.Context, tx pgx.Tx, withdrawalID int64) (int64, error) {
+	var referrerID, amount int64
+	err := tx.QueryRow(ctx, `
+		INSERT INTO transactions (user_id, type, amount, meta)
+		SELECT c.user_id, 'referral_commission_reversal', -c.amount,
+		       jsonb_build_object('withdrawal_id', $1::bigint, 'from_user_id', c.meta->'from_user_id', 'commission_tx_id', c.id)
+		FROM transactions c
+		WHERE c.type = 'referral_commission' AND c.meta->>'withdrawal_id' = $1::bigint::text
+		  AND NOT EXISTS (
+		      SELECT 1 FROM transactions r
+		      WHERE r.type = 'referral_commission_reversal' AND r.meta->>'withdrawal_id' = $1::bigint::text
+		  )
+		RETURNING user_id, -amount
+	`, withdrawalID).Scan(&referrerID, &amount)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE users
+		SET coins = coins - $1, referral_earnings = GREATEST(COALESCE(referral_earnings, 0) - $1, 0)
+		WHERE id = $2
+	`, amount, referrerID); err != nil {
+		return 0, err
+	}
+	return amount, nil
+}
+
 // UpdateStatus updates withdrawal status
 func (r *WithdrawalRepository) UpdateStatus(ctx context.Context, id int64, status domain.WithdrawalStatus) error {
 	_, err := r.db.Exec(ctx, `
@@ -119,21 +330,66 @@ func (r *WithdrawalRepository) MarkFailed(ctx context.Context, id int64, notes s
 	return err
 }
 
-// Cancel cancels a pending withdrawal
+// Cancel cancels a pending withdrawal and refunds the held coins
 func (r *WithdrawalRepository) Cancel(ctx context.Context, id int64, userID int64) error {
-	result, err := r.db.Exec(ctx, `
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var coins int64
+	err = tx.QueryRow(ctx, `
 		UPDATE ton_withdrawals SET status = 'cancelled'
 		WHERE id = $1 AND user_id = $2 AND status = 'pending'
-	`, id, userID)
+		RETURNING coins_amount
+	`, id, userID).Scan(&coins)
 	if err != nil {
+		return err // pgx.ErrNoRows - не найден или уже не pending
+	}
+
+	if err := refundTx(ctx, tx, userID, coins, id, "cancelled"); err != nil {
 		return err
 	}
-	if result.RowsAffected() == 0 {
-		return pgx.ErrNoRows
+	return tx.Commit(ctx)
+}
+
+// Reject cancels a withdrawal on behalf of an admin and refunds the held coins.
+// Only pending/processing withdrawals can be rejected, see WithdrawalStatus.Refundable.
+func (r *WithdrawalRepository) Reject(ctx context.Context, id int64, reason string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var userID, coins int64
+	var status domain.WithdrawalStatus
+	err = tx.QueryRow(ctx, `
+		SELECT user_id, coins_amount, status FROM ton_withdrawals WHERE id = $1 FOR UPDATE
+	`, id).Scan(&userID, &coins, &status)
+	if err != nil {
+		return err
+	}
+	if !status.Refundable() {
+		return ErrWithdrawalNotRefundable
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE ton_withdrawals SET status = 'cancelled', admin_notes = $2 WHERE id = $1
+	`, id, reason); err != nil {
+		return err
 	}
-	return nil
+
+	if err := refundTx(ctx, tx, userID, coins, id, reason); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
+// Выводы в этих статусах не дошли до пользователя и не занимают дневной лимит
+const withdrawalVoidStatuses = `('failed', 'cancelled', 'rejected')`
+
 // GetTotalWithdrawnToday returns total gems withdrawn by user today (legacy)
 func (r *WithdrawalRepository) GetTotalWithdrawnToday(ctx context.Context, userID int64) (int64, error) {
 	var total int64
@@ -141,7 +397,7 @@ func (r *WithdrawalRepository) GetTotalWithdrawnToday(ctx context.Context, userI
 	err := r.db.QueryRow(ctx, `
 		SELECT COALESCE(SUM(gems_amount), 0)
 		FROM ton_withdrawals
-		WHERE user_id = $1 AND created_at >= $2 AND status NOT IN ('failed', 'cancelled')
+		WHERE user_id = $1 AND created_at >= $2 AND status NOT IN `+withdrawalVoidStatuses+`
 	`, userID, today).Scan(&total)
 	return total, err
 }
@@ -153,7 +409,7 @@ func (r *WithdrawalRepository) GetTotalCoinsWithdrawnToday(ctx context.Context,
 	err := r.db.QueryRow(ctx, `
 		SELECT COALESCE(SUM(coins_amount), 0)
 		FROM ton_withdrawals
-		WHERE user_id = $1 AND created_at >= $2 AND status NOT IN ('failed', 'cancelled')
+		WHERE user_id = $1 AND created_at >= $2 AND status NOT IN `+withdrawalVoidStatuses+`
 	`, userID, today).Scan(&total)
 	return total, err
 }
@@ -169,6 +425,22 @@ func (r *WithdrawalRepository) GetTotalWithdrawn(ctx context.Context, userID int
 	return total, err
 }
 
+// GetTotalCoinsWithdrawn returns total coins withdrawn by user (all time).
+// settledOnly counts only sent/completed withdrawals, otherwise pending and
+// processing ones are included too.
+func (r *WithdrawalRepository) GetTotalCoinsWithdrawn(ctx context.Context, userID int64, settledOnly bool) (int64, error) {
+	filter := `status NOT IN ` + withdrawalVoidStatuses
+	if settledOnly {
+		filter = `status IN ('sent', 'completed')`
+	}
+	var total int64
+	err := r.db.QueryRow(ctx, `
+		SELECT COALESCE(SUM(coins_amount), 0)
+		FROM ton_withdrawals
+		WHERE user_id = $1 AND `+filter, userID).Scan(&total)
+	return total, err
+}
+
 // HasPendingWithdrawal checks if user has a pending withdrawal
 func (r *WithdrawalRepository) HasPendingWithdrawal(ctx context.Context, userID int64) (bool, error) {
 	var exists bool
@@ -0,0 +1,474 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/ton"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Integration-style test: runs only if TEST_DATABASE_URL env is set.
+func TestCreatePendingConcurrent(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set; skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	db, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+
+	users := NewUserRepository(db)
+	u := &domain.User{TgID: time.Now().UnixNano(), Username: "withdraw_race_test"}
+	if err := users.Create(ctx, u); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	defer db.Exec(context.Background(), `DELETE FROM users WHERE id = $1`, u.ID)
+	if _, err := db.Exec(ctx, `UPDATE users SET coins = 100 WHERE id = $1`, u.ID); err != nil {
+		t.Fatalf("set coins: %v", err)
+	}
+
+	repo := NewWithdrawalRepository(db)
+
+	const requests = 10
+	var wg sync.WaitGroup
+	errs := make([]error, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = repo.CreatePending(ctx, &domain.Withdrawal{
+				UserID:        u.ID,
+				WalletAddress: "EQtest",
+				CoinsAmount:   10,
+				TonAmountNano: 900_000_000,
+				FeeCoins:      1,
+				ExchangeRate:  10,
+				Status:        domain.WithdrawalStatusPending,
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	created := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case errors.Is(err, ErrWithdrawalPending):
+		default:
+			t.Fatalf("request %d: unexpected error: %v", i, err)
+		}
+	}
+	if created != 1 {
+		t.Fatalf("expected exactly 1 pending withdrawal, got %d", created)
+	}
+
+	hasPending, err := repo.HasPendingWithdrawal(ctx, u.ID)
+	if err != nil || !hasPending {
+		t.Fatalf("expected pending withdrawal, got %v (err %v)", hasPending, err)
+	}
+
+	var coins int64
+	if err := db.QueryRow(ctx, `SELECT coins FROM users WHERE id = $1`, u.ID).Scan(&coins); err != nil {
+		t.Fatalf("read coins: %v", err)
+	}
+	if coins != 90 {
+		t.Fatalf("expected 10 coins held once (balance 90), got %d", coins)
+	}
+}
+
+func TestWithdrawalHoldAndRefund(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set; skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	db, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+
+	users := NewUserRepository(db)
+	u := &domain.User{TgID: time.Now().UnixNano(), Username: "withdraw_hold_test"}
+	if err := users.Create(ctx, u); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	defer db.Exec(context.Background(), `DELETE FROM users WHERE id = $1`, u.ID)
+	if _, err := db.Exec(ctx, `UPDATE users SET coins = 50 WHERE id = $1`, u.ID); err != nil {
+		t.Fatalf("set coins: %v", err)
+	}
+
+	coinsOf := func() int64 {
+		var coins int64
+		if err := db.QueryRow(ctx, `SELECT coins FROM users WHERE id = $1`, u.ID).Scan(&coins); err != nil {
+			t.Fatalf("read coins: %v", err)
+		}
+		return coins
+	}
+	newWithdrawal := func(amount int64) *domain.Withdrawal {
+		return &domain.Withdrawal{
+			UserID:        u.ID,
+			WalletAddress: "EQtest",
+			CoinsAmount:   amount,
+			TonAmountNano: 900_000_000,
+			FeeCoins:      1,
+			ExchangeRate:  10,
+			Status:        domain.WithdrawalStatusPending,
+		}
+	}
+
+	repo := NewWithdrawalRepository(db)
+	if err := repo.CreatePending(ctx, newWithdrawal(60)); !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("expected ErrInsufficientFunds, got %v", err)
+	}
+
+	w := newWithdrawal(30)
+	if err := repo.CreatePending(ctx, w); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if got := coinsOf(); got != 20 {
+		t.Fatalf("expected 20 coins after hold, got %d", got)
+	}
+
+	if err := repo.Cancel(ctx, w.ID, u.ID); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if got := coinsOf(); got != 50 {
+		t.Fatalf("expected 50 coins after refund, got %d", got)
+	}
+
+	// Повторная отмена не должна вернуть монеты второй раз
+	if err := repo.Cancel(ctx, w.ID, u.ID); err == nil {
+		t.Fatal("expected error cancelling twice")
+	}
+	if err := repo.Reject(ctx, w.ID, "test"); err == nil {
+		t.Fatal("expected error rejecting cancelled withdrawal")
+	}
+	if got := coinsOf(); got != 50 {
+		t.Fatalf("expected balance to stay 50, got %d", got)
+	}
+
+	var holds, refunds int
+	if err := db.QueryRow(ctx, `
+		SELECT COUNT(*) FILTER (WHERE type = 'withdraw_hold'), COUNT(*) FILTER (WHERE type = 'withdraw_refund')
+		FROM transactions WHERE user_id = $1
+	`, u.ID).Scan(&holds, &refunds); err != nil {
+		t.Fatalf("count transactions: %v", err)
+	}
+	if holds != 1 || refunds != 1 {
+		t.Fatalf("expected 1 hold and 1 refund transaction, got %d/%d", holds, refunds)
+	}
+
+	// Вывод, созданный до появления холдов: монеты не списывались, возвращать нечего
+	legacy := newWithdrawal(30)
+	if err := repo.Create(ctx, legacy); err != nil {
+		t.Fatalf("create legacy: %v", err)
+	}
+	if err := repo.Reject(ctx, legacy.ID, "legacy"); err != nil {
+		t.Fatalf("reject legacy: %v", err)
+	}
+	if got := coinsOf(); got != 50 {
+		t.Fatalf("expected no refund without a hold, got %d coins", got)
+	}
+}
+
+// Integration-style test: runs only if TEST_DATABASE_URL env is set.
+func TestRejectRefundsOnlyRefundableWithdrawals(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set; skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	db, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+
+	users := NewUserRepository(db)
+	u := &domain.User{TgID: time.Now().UnixNano(), Username: "withdraw_reject_test"}
+	if err := users.Create(ctx, u); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	defer db.Exec(context.Background(), `DELETE FROM users WHERE id = $1`, u.ID)
+	if _, err := db.Exec(ctx, `UPDATE users SET coins = 50 WHERE id = $1`, u.ID); err != nil {
+		t.Fatalf("set coins: %v", err)
+	}
+
+	coinsOf := func() int64 {
+		var coins int64
+		if err := db.QueryRow(ctx, `SELECT coins FROM users WHERE id = $1`, u.ID).Scan(&coins); err != nil {
+			t.Fatalf("read coins: %v", err)
+		}
+		return coins
+	}
+
+	repo := NewWithdrawalRepository(db)
+	create := func() *domain.Withdrawal {
+		w := &domain.Withdrawal{
+			UserID:        u.ID,
+			WalletAddress: "EQtest",
+			CoinsAmount:   20,
+			TonAmountNano: 1_900_000_000,
+			FeeCoins:      1,
+			ExchangeRate:  10,
+			Status:        domain.WithdrawalStatusPending,
+		}
+		if err := repo.CreatePending(ctx, w); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		return w
+	}
+
+	// processing - ещё не отправлено, монеты возвращаются
+	w := create()
+	if err := repo.MarkProcessing(ctx, w.ID); err != nil {
+		t.Fatalf("mark processing: %v", err)
+	}
+	if err := repo.Reject(ctx, w.ID, "bad wallet"); err != nil {
+		t.Fatalf("reject processing: %v", err)
+	}
+	if got := coinsOf(); got != 50 {
+		t.Fatalf("expected 50 coins after reject, got %d", got)
+	}
+
+	// sent - TON уже ушли, возврата нет
+	w = create()
+	if err := repo.MarkSent(ctx, w.ID, "hash", 1); err != nil {
+		t.Fatalf("mark sent: %v", err)
+	}
+	if err := repo.Reject(ctx, w.ID, "too late"); !errors.Is(err, ErrWithdrawalNotRefundable) {
+		t.Fatalf("expected ErrWithdrawalNotRefundable, got %v", err)
+	}
+	if got := coinsOf(); got != 30 {
+		t.Fatalf("expected balance to stay 30, got %d", got)
+	}
+}
+
+// Integration-style test: runs only if TEST_DATABASE_URL env is set.
+func TestDailyWithdrawLimit(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set; skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	db, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+
+	users := NewUserRepository(db)
+	u := &domain.User{TgID: time.Now().UnixNano(), Username: "withdraw_limit_test"}
+	if err := users.Create(ctx, u); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	defer db.Exec(context.Background(), `DELETE FROM users WHERE id = $1`, u.ID)
+	if _, err := db.Exec(ctx, `UPDATE users SET coins = $1 WHERE id = $2`, ton.MaxWithdrawCoinsPerDay*2, u.ID); err != nil {
+		t.Fatalf("set coins: %v", err)
+	}
+
+	repo := NewWithdrawalRepository(db)
+	create := func(amount int64) *domain.Withdrawal {
+		w := &domain.Withdrawal{
+			UserID:        u.ID,
+			WalletAddress: "EQtest",
+			CoinsAmount:   amount,
+			TonAmountNano: ton.CoinsToNano(amount - 1),
+			FeeCoins:      1,
+			ExchangeRate:  ton.CoinsPerTON,
+			Status:        domain.WithdrawalStatusPending,
+		}
+		if err := repo.CreatePending(ctx, w); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		return w
+	}
+	withdrawnToday := func() int64 {
+		total, err := repo.GetTotalCoinsWithdrawnToday(ctx, u.ID)
+		if err != nil {
+			t.Fatalf("total today: %v", err)
+		}
+		return total
+	}
+
+	// Отменённый вывод не занимает дневной лимит
+	w := create(ton.MaxWithdrawCoinsPerDay)
+	if err := repo.Cancel(ctx, w.ID, u.ID); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if got := withdrawnToday(); got != 0 {
+		t.Fatalf("expected cancelled withdrawal not to count, got %d", got)
+	}
+
+	// Вывод на весь лимит - следующий блокируется
+	w = create(ton.MaxWithdrawCoinsPerDay)
+	if got := withdrawnToday(); got != ton.MaxWithdrawCoinsPerDay {
+		t.Fatalf("expected %d withdrawn today, got %d", ton.MaxWithdrawCoinsPerDay, got)
+	}
+	if remaining := ton.RemainingWithdrawCoinsToday(withdrawnToday()); ton.MinWithdrawCoins <= remaining {
+		t.Fatalf("expected limit to block a %d coins withdrawal, remaining %d", ton.MinWithdrawCoins, remaining)
+	}
+
+	// За всё время: settledOnly учитывает только отправленные
+	if total, err := repo.GetTotalCoinsWithdrawn(ctx, u.ID, true); err != nil || total != 0 {
+		t.Fatalf("expected 0 settled before sending, got %d (err %v)", total, err)
+	}
+	if err := repo.MarkCompleted(ctx, w.ID); err != nil {
+		t.Fatalf("mark completed: %v", err)
+	}
+	if total, err := repo.GetTotalCoinsWithdrawn(ctx, u.ID, true); err != nil || total != ton.MaxWithdrawCoinsPerDay {
+		t.Fatalf("expected %d settled, got %d (err %v)", ton.MaxWithdrawCoinsPerDay, total, err)
+	}
+}
+
+// Integration-style test: runs only if TEST_DATABASE_URL env is set.
+func TestWithdrawalReferralCommissionAtomic(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set; skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	db, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+
+	users := NewUserRepository(db)
+	referrer := &domain.User{TgID: time.Now().UnixNano(), Username: "commission_referrer_test"}
+	if err := users.Create(ctx, referrer); err != nil {
+		t.Fatalf("create referrer: %v", err)
+	}
+	defer db.Exec(context.Background(), `DELETE FROM users WHERE id = $1`, referrer.ID)
+	u := &domain.User{TgID: time.Now().UnixNano() + 1, Username: "commission_user_test"}
+	if err := users.Create(ctx, u); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	defer db.Exec(context.Background(), `DELETE FROM users WHERE id = $1`, u.ID)
+	if _, err := db.Exec(ctx, `UPDATE users SET coins = 50, referred_by = $1 WHERE id = $2`, referrer.ID, u.ID); err != nil {
+		t.Fatalf("set coins: %v", err)
+	}
+
+	balances := func() (userCoins, referrerCoins, earnings int64) {
+		if err := db.QueryRow(ctx, `SELECT coins FROM users WHERE id = $1`, u.ID).Scan(&userCoins); err != nil {
+			t.Fatalf("read coins: %v", err)
+		}
+		if err := db.QueryRow(ctx, `SELECT coins, COALESCE(referral_earnings, 0) FROM users WHERE id = $1`, referrer.ID).Scan(&referrerCoins, &earnings); err != nil {
+			t.Fatalf("read referrer: %v", err)
+		}
+		return
+	}
+	newWithdrawal := func() *domain.Withdrawal {
+		return &domain.Withdrawal{
+			UserID:        u.ID,
+			WalletAddress: "EQtest",
+			CoinsAmount:   20,
+			TonAmountNano: 1_800_000_000,
+			FeeCoins:      2,
+			ExchangeRate:  10,
+			Status:        domain.WithdrawalStatusPending,
+		}
+	}
+
+	repo := NewWithdrawalRepository(db)
+
+	// Сбой посреди операции: начисление рефереру падает, вывод и холд откатываются
+	failing := &ReferralCommissionCredit{ReferrerID: 1 << 62, Amount: 1} // такого пользователя нет
+	if err := repo.CreatePendingWithCommission(ctx, newWithdrawal(), failing); err == nil {
+		t.Fatal("expected error crediting a missing referrer")
+	}
+	if hasPending, err := repo.HasPendingWithdrawal(ctx, u.ID); err != nil || hasPending {
+		t.Fatalf("expected no withdrawal after failure, got %v (err %v)", hasPending, err)
+	}
+	if userCoins, _, _ := balances(); userCoins != 50 {
+		t.Fatalf("expected hold to be rolled back (50 coins), got %d", userCoins)
+	}
+
+	w := newWithdrawal()
+	commission := &ReferralCommissionCredit{ReferrerID: referrer.ID, Amount: 1, Meta: map[string]interface{}{"total_fee": 2}}
+	if err := repo.CreatePendingWithCommission(ctx, w, commission); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if !commission.Credited {
+		t.Fatal("expected commission to be credited")
+	}
+	userCoins, referrerCoins, earnings := balances()
+	if userCoins != 30 || referrerCoins != 1 || earnings != 1 {
+		t.Fatalf("expected 30/1/1 after withdrawal, got %d/%d/%d", userCoins, referrerCoins, earnings)
+	}
+
+	// Повторное начисление за тот же вывод ничего не платит
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	credited, err := creditReferralCommissionTx(ctx, tx, w.ID, u.ID, &ReferralCommissionCredit{ReferrerID: referrer.ID, Amount: 1})
+	if err != nil {
+		tx.Rollback(ctx)
+		t.Fatalf("repeat credit: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if credited {
+		t.Fatal("expected repeated commission to be skipped")
+	}
+	if _, referrerCoins, earnings := balances(); referrerCoins != 1 || earnings != 1 {
+		t.Fatalf("expected referrer paid once (1/1), got %d/%d", referrerCoins, earnings)
+	}
+
+	var count int
+	if err := db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM transactions
+		WHERE user_id = $1 AND type = 'referral_commission' AND (meta->>'withdrawal_id')::bigint = $2
+	`, referrer.ID, w.ID).Scan(&count); err != nil {
+		t.Fatalf("count transactions: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 referral_commission transaction, got %d", count)
+	}
+
+	// Отмена возвращает холд и забирает комиссию: цикл создать/отменить не печатает монеты
+	if err := repo.Cancel(ctx, w.ID, u.ID); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if userCoins, referrerCoins, earnings := balances(); userCoins != 50 || referrerCoins != 0 || earnings != 0 {
+		t.Fatalf("expected 50/0/0 after cancel, got %d/%d/%d", userCoins, referrerCoins, earnings)
+	}
+	tx, err = db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	reversed, err := reverseReferralCommissionTx(ctx, tx, w.ID)
+	_ = tx.Rollback(ctx)
+	if err != nil || reversed != 0 {
+		t.Fatalf("expected commission to be reversed once, reversed %d again (err %v)", reversed, err)
+	}
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"telegram_webapp/internal/domain"
+)
+
+// BroadcastSegment - аудитория рассылки
+type BroadcastSegment string
+
+const (
+	SegmentAll         BroadcastSegment = "all"
+	SegmentInactive    BroadcastSegment = "inactive"  // не заходили N дней
+	SegmentTopSpenders BroadcastSegment = "top"       // N игроков с наибольшей суммой депозитов
+	SegmentNoDeposit   BroadcastSegment = "nodeposit" // ни одного подтверждённого депозита
+	SegmentLevel       BroadcastSegment = "level"     // character_level в диапазоне
+)
+
+// ErrInvalidBroadcastFilter - сегмент рассылки не распознан
+var ErrInvalidBroadcastFilter = errors.New("segment must be: all, inactive <days>, top <n>, nodeposit, level <n>[-<m>]")
+
+// ErrInvalidBroadcastTime - время рассылки не распознано или в прошлом
+var ErrInvalidBroadcastTime = errors.New("time must be: now, +<duration> (e.g. +2h) or YYYY-MM-DD HH:MM")
+
+// BroadcastFilter selects broadcast recipients. Only the fields of the chosen
+// Segment are used.
+type BroadcastFilter struct {
+	Segment      BroadcastSegment
+	InactiveDays int
+	TopN         int
+	MinLevel     int
+	MaxLevel     int
+}
+
+// ParseBroadcastFilter parses an admin's segment choice: "all", "inactive 7",
+// "top 100", "nodeposit", "level 3" or "level 3-5"
+func ParseBroadcastFilter(s string) (BroadcastFilter, error) {
+	fields := strings.Fields(strings.ToLower(s))
+	if len(fields) == 0 {
+		return BroadcastFilter{}, ErrInvalidBroadcastFilter
+	}
+
+	seg := BroadcastSegment(fields[0])
+	args := fields[1:]
+	positive := func() (int, bool) {
+		if len(args) != 1 {
+			return 0, false
+		}
+		n, err := strconv.Atoi(args[0])
+		return n, err == nil && n > 0
+	}
+
+	switch seg {
+	case SegmentAll, SegmentNoDeposit:
+		if len(args) != 0 {
+			return BroadcastFilter{}, ErrInvalidBroadcastFilter
+		}
+		return BroadcastFilter{Segment: seg}, nil
+	case SegmentInactive:
+		n, ok := positive()
+		if !ok {
+			return BroadcastFilter{}, ErrInvalidBroadcastFilter
+		}
+		return BroadcastFilter{Segment: seg, InactiveDays: n}, nil
+	case SegmentTopSpenders:
+		n, ok := positive()
+		if !ok {
+			return BroadcastFilter{}, ErrInvalidBroadcastFilter
+		}
+		return BroadcastFilter{Segment: seg, TopN: n}, nil
+	case SegmentLevel:
+		if len(args) != 1 {
+			return BroadcastFilter{}, ErrInvalidBroadcastFilter
+		}
+		lo, hi, found := strings.Cut(args[0], "-")
+		minLevel, err := strconv.Atoi(lo)
+		if err != nil || minLevel <= 0 {
+			return BroadcastFilter{}, ErrInvalidBroadcastFilter
+		}
+		maxLevel := minLevel
+		if found {
+			if maxLevel, err = strconv.Atoi(hi); err != nil || maxLevel < minLevel {
+				return BroadcastFilter{}, ErrInvalidBroadcastFilter
+			}
+		}
+		return BroadcastFilter{Segment: seg, MinLevel: minLevel, MaxLevel: maxLevel}, nil
+	}
+	return BroadcastFilter{}, ErrInvalidBroadcastFilter
+}
+
+// String returns the filter in the form ParseBroadcastFilter accepts
+func (f BroadcastFilter) String() string {
+	switch f.Segment {
+	case SegmentInactive:
+		return fmt.Sprintf("%s %d", f.Segment, f.InactiveDays)
+	case SegmentTopSpenders:
+		return fmt.Sprintf("%s %d", f.Segment, f.TopN)
+	case SegmentLevel:
+		if f.MinLevel == f.MaxLevel {
+			return fmt.Sprintf("%s %d", f.Segment, f.MinLevel)
+		}
+		return fmt.Sprintf("%s %d-%d", f.Segment, f.MinLevel, f.MaxLevel)
+	case "":
+		return string(SegmentAll)
+	}
+	return string(f.Segment)
+}
+
+// ParseBroadcastTime parses when to send a broadcast: "now" (zero time),
+// "+2h" relative to now, or "YYYY-MM-DD HH:MM" in the server's local time
+func ParseBroadcastTime(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	switch {
+	case s == "now" || s == "сейчас":
+		return time.Time{}, nil
+	case strings.HasPrefix(s, "+"):
+		d, err := time.ParseDuration(s[1:])
+		if err != nil || d <= 0 {
+			return time.Time{}, ErrInvalidBroadcastTime
+		}
+		return now.Add(d), nil
+	}
+
+	t, err := time.ParseInLocation("2006-01-02 15:04", s, now.Location())
+	if err != nil || !t.After(now) {
+		return time.Time{}, ErrInvalidBroadcastTime
+	}
+	return t, nil
+}
+
+// GetUserTgIDsFiltered returns tg_ids of non-banned users in the filter's segment
+// who accept promo notifications. Excluded users get no promo until the exclusion ends.
+func (s *AdminService) GetUserTgIDsFiltered(ctx context.Context, filter BroadcastFilter) ([]int64, error) {
+	defaultPromo := domain.DefaultNotificationPrefs(0).Allows(domain.NotificationPromo)
+	args := []interface{}{defaultPromo}
+
+	from := `users u`
+	where := `u.tg_id IS NOT NULL AND NOT u.is_banned AND COALESCE(np.promo, $1)
+		AND (u.excluded_until IS NULL OR u.excluded_until <= now())`
+	order := `u.id`
+	limit := ""
+
+	switch filter.Segment {
+	case SegmentAll, "":
+	case SegmentInactive:
+		args = append(args, filter.InactiveDays)
+		where += ` AND u.last_login_at < now() - make_interval(days => $2)`
+	case SegmentNoDeposit:
+		where += ` AND NOT EXISTS (SELECT 1 FROM deposits d WHERE d.user_id = u.id AND d.status = 'confirmed')`
+	case SegmentTopSpenders:
+		args = append(args, filter.TopN)
+		from = `users u JOIN (
+			SELECT user_id, SUM(amount_nano) AS total FROM deposits WHERE status = 'confirmed' GROUP BY user_id
+		) d ON d.user_id = u.id`
+		order = `d.total DESC, u.id`
+		limit = ` LIMIT $2`
+	case SegmentLevel:
+		args = append(args, filter.MinLevel, filter.MaxLevel)
+		where += ` AND u.character_level BETWEEN $2 AND $3`
+	default:
+		return nil, ErrInvalidBroadcastFilter
+	}
+
+	query := fmt.Sprintf(`
+		SELECT u.tg_id
+		FROM %s
+		LEFT JOIN notification_prefs np ON np.user_id = u.id
+		WHERE %s
+		ORDER BY %s%s`, from, where, order, limit)
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+
+	return ids, nil
+}
+
+// CreateBroadcast stores a broadcast; with a zero ScheduledAt it starts now
+// (status sending), otherwise it waits for the scheduler
+func (s *AdminService) CreateBroadcast(ctx context.Context, b *domain.Broadcast) error {
+	b.Status = domain.BroadcastScheduled
+	if b.ScheduledAt.IsZero() {
+		b.ScheduledAt = time.Now()
+		b.Status = domain.BroadcastSending
+	}
+	if err := s.broadcasts.Create(ctx, b); err != nil {
+		return err
+	}
+	s.LogAction(ctx, b.AdminTgID, domain.AdminActionBroadcast, 0, map[string]interface{}{
+		"broadcast_id": b.ID,
+		"segment":      b.Segment,
+		"scheduled_at": b.ScheduledAt,
+	})
+	return nil
+}
+
+// ClaimDueBroadcasts returns scheduled broadcasts that are due, already marked as sending
+func (s *AdminService) ClaimDueBroadcasts(ctx context.Context) ([]*domain.Broadcast, error) {
+	return s.broadcasts.ClaimDue(ctx)
+}
+
+// ClaimStaleBroadcasts returns broadcasts whose sender stopped saving progress
+// for staleAfter, so they can be resumed from Progress
+func (s *AdminService) ClaimStaleBroadcasts(ctx context.Context, staleAfter time.Duration) ([]*domain.Broadcast, error) {
+	return s.broadcasts.ClaimStale(ctx, staleAfter)
+}
+
+// SaveBroadcastProgress stores how far a broadcast got, so a crash doesn't restart it
+func (s *AdminService) SaveBroadcastProgress(ctx context.Context, b *domain.Broadcast) error {
+	return s.broadcasts.SaveProgress(ctx, b)
+}
+
+// FinishBroadcast stores delivery results of a broadcast
+func (s *AdminService) FinishBroadcast(ctx context.Context, b *domain.Broadcast) error {
+	return s.broadcasts.Finish(ctx, b)
+}
+
+// CancelBroadcast cancels a scheduled broadcast; false if it isn't scheduled
+func (s *AdminService) CancelBroadcast(ctx context.Context, adminTgID, id int64) (bool, error) {
+	cancelled, err := s.broadcasts.Cancel(ctx, id)
+	if err != nil || !cancelled {
+		return cancelled, err
+	}
+	s.LogAction(ctx, adminTgID, domain.AdminActionCancelBroadcast, 0, map[string]interface{}{"broadcast_id": id})
+	return true, nil
+}
+
+// GetRecentBroadcasts returns the latest broadcasts with their delivery results
+func (s *AdminService) GetRecentBroadcasts(ctx context.Context, limit int) ([]*domain.Broadcast, error) {
+	return s.broadcasts.GetRecent(ctx, limit)
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseBroadcastFilter(t *testing.T) {
+	cases := map[string]BroadcastFilter{
+		"all":         {Segment: SegmentAll},
+		"  NoDeposit": {Segment: SegmentNoDeposit},
+		"inactive 7":  {Segment: SegmentInactive, InactiveDays: 7},
+		"top 100":     {Segment: SegmentTopSpenders, TopN: 100},
+		"level 3":     {Segment: SegmentLevel, MinLevel: 3, MaxLevel: 3},
+		"level 2-5":   {Segment: SegmentLevel, MinLevel: 2, MaxLevel: 5},
+	}
+	for in, want := range cases {
+		got, err := ParseBroadcastFilter(in)
+		if err != nil {
+			t.Fatalf("%q: unexpected error %v", in, err)
+		}
+		if got != want {
+			t.Fatalf("%q: expected %+v, got %+v", in, want, got)
+		}
+		// String даёт форму, которая парсится обратно в тот же фильтр
+		if again, err := ParseBroadcastFilter(got.String()); err != nil || again != got {
+			t.Fatalf("%q: round trip via %q gave %+v, %v", in, got.String(), again, err)
+		}
+	}
+
+	for _, in := range []string{"", "everyone", "inactive", "inactive 0", "top -1", "all 5", "level 5-2", "level x"} {
+		if _, err := ParseBroadcastFilter(in); !errors.Is(err, ErrInvalidBroadcastFilter) {
+			t.Fatalf("%q: expected ErrInvalidBroadcastFilter, got %v", in, err)
+		}
+	}
+}
+
+func TestParseBroadcastTime(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	if got, err := ParseBroadcastTime("now", now); err != nil || !got.IsZero() {
+		t.Fatalf("now: got %v, %v", got, err)
+	}
+	if got, err := ParseBroadcastTime("+2h", now); err != nil || !got.Equal(now.Add(2*time.Hour)) {
+		t.Fatalf("+2h: got %v, %v", got, err)
+	}
+	want := time.Date(2024, 5, 2, 9, 30, 0, 0, time.UTC)
+	if got, err := ParseBroadcastTime("2024-05-02 09:30", now); err != nil || !got.Equal(want) {
+		t.Fatalf("absolute: got %v, %v", got, err)
+	}
+
+	for _, in := range []string{"", "tomorrow", "+0s", "+abc", "2024-05-01 11:00"} {
+		if _, err := ParseBroadcastTime(in, now); !errors.Is(err, ErrInvalidBroadcastTime) {
+			t.Fatalf("%q: expected ErrInvalidBroadcastTime, got %v", in, err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+// DailySummary holds platform activity for one calendar day (server time)
+type DailySummary struct {
+	Day                  time.Time `json:"day"`
+	NewUsers             int64     `json:"new_users"`
+	GamesPlayed          int64     `json:"games_played"`
+	WageredCoins         int64     `json:"wagered_coins"`
+	HouseProfitCoins     int64     `json:"house_profit_coins"` // PvE: ставки минус выплаты
+	Deposits             int64     `json:"deposits"`
+	DepositedCoins       int64     `json:"deposited_coins"`
+	WithdrawalsProcessed int64     `json:"withdrawals_processed"`
+	WithdrawnCoins       int64     `json:"withdrawn_coins"`
+}
+
+// Digest is the daily report for admins: the last full day compared to the day before
+type Digest struct {
+	Day              *DailySummary `json:"day"`
+	Previous         *DailySummary `json:"previous"`
+	PendingWithdraws int           `json:"pending_withdraws"`
+	TotalUsers       int64         `json:"total_users"`
+}
+
+// GetDailySummary aggregates activity for the day containing day.
+// A failing table doesn't break the other numbers.
+func (s *AdminService) GetDailySummary(ctx context.Context, day time.Time) (*DailySummary, error) {
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	to := from.AddDate(0, 0, 1)
+	sum := &DailySummary{Day: from}
+
+	_ = s.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM users WHERE created_at >= $1 AND created_at < $2
+	`, from, to).Scan(&sum.NewUsers)
+
+	// PvP игры - между игроками, в прибыль казино идут только PvE
+	_ = s.db.QueryRow(ctx, `
+		SELECT COUNT(*),
+		       COALESCE(SUM(bet_amount) FILTER (WHERE currency = 'coins'), 0),
+		       COALESCE(-SUM(win_amount) FILTER (WHERE currency = 'coins' AND mode <> 'pvp'), 0)
+		FROM game_history
+		WHERE created_at >= $1 AND created_at < $2
+	`, from, to).Scan(&sum.GamesPlayed, &sum.WageredCoins, &sum.HouseProfitCoins)
+
+	_ = s.db.QueryRow(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(coins_credited), 0)
+		FROM deposits
+		WHERE status = 'confirmed' AND created_at >= $1 AND created_at < $2
+	`, from, to).Scan(&sum.Deposits, &sum.DepositedCoins)
+
+	_ = s.db.QueryRow(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(coins_amount), 0)
+		FROM ton_withdrawals
+		WHERE status IN ('sent', 'completed')
+		  AND COALESCE(processed_at, completed_at) >= $1 AND COALESCE(processed_at, completed_at) < $2
+	`, from, to).Scan(&sum.WithdrawalsProcessed, &sum.WithdrawnCoins)
+
+	return sum, nil
+}
+
+// GetDailyDigest builds the report for the day before now, reusing GetStats for
+// the current queue and totals.
+func (s *AdminService) GetDailyDigest(ctx context.Context, now time.Time) (*Digest, error) {
+	yesterday := now.AddDate(0, 0, -1)
+
+	day, err := s.GetDailySummary(ctx, yesterday)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.GetDailySummary(ctx, yesterday.AddDate(0, 0, -1))
+	if err != nil {
+		return nil, err
+	}
+	stats, err := s.GetStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Digest{
+		Day:              day,
+		Previous:         previous,
+		PendingWithdraws: stats.PendingWithdraws,
+		TotalUsers:       stats.TotalUsers,
+	}, nil
+}
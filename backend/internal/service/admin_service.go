@@ -2,27 +2,93 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/logger"
+	"telegram_webapp/internal/repository"
+
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // AdminService provides admin statistics and operations
 type AdminService struct {
-	db *pgxpool.Pool
+	db            *pgxpool.Pool
+	notifications *repository.NotificationRepository
+	bonus         *repository.BonusWageringRepository
+	support       *repository.SupportRepository
+	users         *repository.UserRepository
+	transactions  *repository.TransactionRepository
+	withdrawals   *repository.WithdrawalRepository
+	gameLimits    *repository.GameLimitsRepository
+	actions       *repository.AdminActionRepository
+	admins        *repository.AdminRepository
+	broadcasts    *repository.BroadcastRepository
+
+	bonusWagerMultiplier int
+
+	// Общие лимиты из конфига и перезагрузка лимитов в GameService после /setlimits
+	defaultLimits      GameLimits
+	onGameLimitsChange func(ctx context.Context) error
+
+	// Кэш /stats, пересчитывается не чаще statsTTL
+	statsMu    sync.Mutex
+	statsCache *Stats
+	statsTTL   time.Duration
 }
 
 // NewAdminService creates a new admin service
 func NewAdminService(db *pgxpool.Pool) *AdminService {
-	return &AdminService{db: db}
+	return &AdminService{
+		db:            db,
+		notifications: repository.NewNotificationRepository(db),
+		bonus:         repository.NewBonusWageringRepository(db),
+		support:       repository.NewSupportRepository(db),
+		users:         repository.NewUserRepository(db),
+		transactions:  repository.NewTransactionRepository(db),
+		withdrawals:   repository.NewWithdrawalRepository(db),
+		gameLimits:    repository.NewGameLimitsRepository(db),
+		actions:       repository.NewAdminActionRepository(db),
+		admins:        repository.NewAdminRepository(db),
+		broadcasts:    repository.NewBroadcastRepository(db),
+		defaultLimits: DefaultGameLimits(),
+		statsTTL:      5 * time.Minute,
+
+		bonusWagerMultiplier: domain.DefaultBonusWagerMultiplier,
+	}
+}
+
+// SetStatsTTL sets how long the /stats snapshot is served from cache (0 = always recompute)
+func (s *AdminService) SetStatsTTL(ttl time.Duration) {
+	s.statsTTL = ttl
+}
+
+// SetBonusWagerMultiplier sets wagering requirement for coins granted by admins
+// (0 = none, domain.DefaultBonusWagerMultiplier by default)
+func (s *AdminService) SetBonusWagerMultiplier(n int) {
+	s.bonusWagerMultiplier = n
+}
+
+// SetDefaultGameLimits sets the global bet limits that per-game overrides apply on top of
+func (s *AdminService) SetDefaultGameLimits(l GameLimits) {
+	s.defaultLimits = l.withDefaults()
+}
+
+// SetOnGameLimitsChanged sets the hook that makes game handlers pick up new limits right away
+func (s *AdminService) SetOnGameLimitsChanged(fn func(ctx context.Context) error) {
+	s.onGameLimitsChange = fn
 }
 
 // Stats represents platform statistics
 type Stats struct {
 	TotalUsers       int64 `json:"total_users"`
+	BannedUsers      int64 `json:"banned_users"` // входят в TotalUsers
 	ActiveUsersToday int64 `json:"active_users_today"`
 	ActiveUsersWeek  int64 `json:"active_users_week"`
 	TotalGamesPlayed int64 `json:"total_games_played"`
@@ -33,89 +99,99 @@ type Stats struct {
 	WageredToday     int64 `json:"wagered_today"`     // Today's wagered
 	PendingWithdraws int   `json:"pending_withdraws"` // Pending withdrawal requests
 	TotalDeposited   int64 `json:"total_deposited"`   // Total TON deposited (in gems)
-	TotalWithdrawn   int64 `json:"total_withdrawn"`   // Total withdrawn (in gems)
+	TotalWithdrawn   int64 `json:"total_withdrawn"`   // Total withdrawn (in coins)
 	// Coins purchased stats
 	CoinsPurchasedToday int64 `json:"coins_purchased_today"`
 	CoinsPurchasedWeek  int64 `json:"coins_purchased_week"`
 	CoinsPurchasedMonth int64 `json:"coins_purchased_month"`
 	CoinsPurchasedTotal int64 `json:"coins_purchased_total"`
+
+	GeneratedAt time.Time `json:"generated_at"` // когда снимок был посчитан
 }
 
-// GetStats returns platform statistics
+// GetStats returns the cached statistics snapshot, recomputing it when older than the TTL
 func (s *AdminService) GetStats(ctx context.Context) (*Stats, error) {
+	s.statsMu.Lock()
+	cached := s.statsCache
+	s.statsMu.Unlock()
+
+	if cached != nil && time.Since(cached.GeneratedAt) < s.statsTTL {
+		return cached, nil
+	}
+	return s.RefreshStats(ctx)
+}
+
+// RefreshStats recomputes statistics right away and replaces the cached snapshot.
+// If any query fails the snapshot is not replaced: the previous one is returned,
+// or the error when there is none yet, so a failing table never shows up as zeros.
+func (s *AdminService) RefreshStats(ctx context.Context) (*Stats, error) {
 	stats := &Stats{}
 	today := time.Now().Truncate(24 * time.Hour)
 	weekAgo := today.Add(-7 * 24 * time.Hour)
 	monthAgo := today.Add(-30 * 24 * time.Hour)
 
-	// Total users
-	_ = s.db.QueryRow(ctx, `SELECT COUNT(*) FROM users`).Scan(&stats.TotalUsers)
-
-	// Active users today (played at least one game)
-	_ = s.db.QueryRow(ctx, `
-		SELECT COUNT(DISTINCT user_id) FROM game_history WHERE created_at >= $1
-	`, today).Scan(&stats.ActiveUsersToday)
-
-	// Active users this week
-	_ = s.db.QueryRow(ctx, `
-		SELECT COUNT(DISTINCT user_id) FROM game_history WHERE created_at >= $1
-	`, weekAgo).Scan(&stats.ActiveUsersWeek)
-
-	// Total games played
-	_ = s.db.QueryRow(ctx, `SELECT COUNT(*) FROM game_history`).Scan(&stats.TotalGamesPlayed)
-
-	// Games today
-	_ = s.db.QueryRow(ctx, `
-		SELECT COUNT(*) FROM game_history WHERE created_at >= $1
-	`, today).Scan(&stats.GamesToday)
-
-	// Total gems in circulation
-	_ = s.db.QueryRow(ctx, `SELECT COALESCE(SUM(gems), 0) FROM users`).Scan(&stats.TotalGems)
-
-	// Total coins in circulation
-	_ = s.db.QueryRow(ctx, `SELECT COALESCE(SUM(coins), 0) FROM users`).Scan(&stats.TotalCoins)
-
-	// Total wagered (all time) - only coins bets
-	_ = s.db.QueryRow(ctx, `
-		SELECT COALESCE(SUM(bet_amount), 0) FROM game_history WHERE currency = 'coins'
-	`).Scan(&stats.TotalWagered)
-
-	// Wagered today - only coins bets
-	_ = s.db.QueryRow(ctx, `
-		SELECT COALESCE(SUM(bet_amount), 0) FROM game_history WHERE created_at >= $1 AND currency = 'coins'
-	`, today).Scan(&stats.WageredToday)
+	// Одна агрегация на таблицу вместо отдельного запроса на каждую цифру
+	var errs []error
+	check := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
 
-	// Pending withdrawals
-	_ = s.db.QueryRow(ctx, `
-		SELECT COUNT(*) FROM withdrawals WHERE status IN ('pending', 'processing')
-	`).Scan(&stats.PendingWithdraws)
+	// Users: count and currency in circulation
+	check(s.db.QueryRow(ctx, `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE is_banned), COALESCE(SUM(gems), 0), COALESCE(SUM(coins), 0) FROM users
+	`).Scan(&stats.TotalUsers, &stats.BannedUsers, &stats.TotalGems, &stats.TotalCoins))
+
+	// Game history: activity and coins wagered
+	check(s.db.QueryRow(ctx, `
+		SELECT COUNT(DISTINCT user_id) FILTER (WHERE created_at >= $1),
+		       COUNT(DISTINCT user_id) FILTER (WHERE created_at >= $2),
+		       COUNT(*),
+		       COUNT(*) FILTER (WHERE created_at >= $1),
+		       COALESCE(SUM(bet_amount) FILTER (WHERE currency = 'coins'), 0),
+		       COALESCE(SUM(bet_amount) FILTER (WHERE currency = 'coins' AND created_at >= $1), 0)
+		FROM game_history
+	`, today, weekAgo).Scan(&stats.ActiveUsersToday, &stats.ActiveUsersWeek, &stats.TotalGamesPlayed,
+		&stats.GamesToday, &stats.TotalWagered, &stats.WageredToday))
+
+	// Withdrawals: queue and total sent
+	check(s.db.QueryRow(ctx, `
+		SELECT COUNT(*) FILTER (WHERE status IN ('pending', 'processing')),
+		       COALESCE(SUM(coins_amount) FILTER (WHERE status IN ('sent', 'completed')), 0)
+		FROM ton_withdrawals
+	`).Scan(&stats.PendingWithdraws, &stats.TotalWithdrawn))
 
 	// Total deposited
-	_ = s.db.QueryRow(ctx, `
+	check(s.db.QueryRow(ctx, `
 		SELECT COALESCE(SUM(gems_credited), 0) FROM deposits WHERE status = 'confirmed'
-	`).Scan(&stats.TotalDeposited)
-
-	// Total withdrawn
-	_ = s.db.QueryRow(ctx, `
-		SELECT COALESCE(SUM(gems_amount), 0) FROM withdrawals WHERE status IN ('sent', 'completed')
-	`).Scan(&stats.TotalWithdrawn)
+	`).Scan(&stats.TotalDeposited))
 
 	// Coins purchased stats (from deposits table)
-	_ = s.db.QueryRow(ctx, `
-		SELECT COALESCE(SUM(coins_credited), 0) FROM deposits WHERE status = 'confirmed' AND created_at >= $1
-	`, today).Scan(&stats.CoinsPurchasedToday)
-
-	_ = s.db.QueryRow(ctx, `
-		SELECT COALESCE(SUM(coins_credited), 0) FROM deposits WHERE status = 'confirmed' AND created_at >= $1
-	`, weekAgo).Scan(&stats.CoinsPurchasedWeek)
-
-	_ = s.db.QueryRow(ctx, `
-		SELECT COALESCE(SUM(coins_credited), 0) FROM deposits WHERE status = 'confirmed' AND created_at >= $1
-	`, monthAgo).Scan(&stats.CoinsPurchasedMonth)
+	check(s.db.QueryRow(ctx, `
+		SELECT COALESCE(SUM(coins_credited) FILTER (WHERE created_at >= $1), 0),
+		       COALESCE(SUM(coins_credited) FILTER (WHERE created_at >= $2), 0),
+		       COALESCE(SUM(coins_credited) FILTER (WHERE created_at >= $3), 0),
+		       COALESCE(SUM(coins_credited), 0)
+		FROM deposits WHERE status = 'confirmed'
+	`, today, weekAgo, monthAgo).Scan(&stats.CoinsPurchasedToday, &stats.CoinsPurchasedWeek,
+		&stats.CoinsPurchasedMonth, &stats.CoinsPurchasedTotal))
+
+	if err := errors.Join(errs...); err != nil {
+		s.statsMu.Lock()
+		prev := s.statsCache
+		s.statsMu.Unlock()
+		if prev == nil {
+			return nil, err
+		}
+		logger.Warn("stats refresh failed, keeping previous snapshot", "error", err, "generated_at", prev.GeneratedAt)
+		return prev, nil
+	}
+	stats.GeneratedAt = time.Now()
 
-	_ = s.db.QueryRow(ctx, `
-		SELECT COALESCE(SUM(coins_credited), 0) FROM deposits WHERE status = 'confirmed'
-	`).Scan(&stats.CoinsPurchasedTotal)
+	s.statsMu.Lock()
+	s.statsCache = stats
+	s.statsMu.Unlock()
 
 	return stats, nil
 }
@@ -175,31 +251,112 @@ func (s *AdminService) GetUser(ctx context.Context, identifier string) (*UserInf
 	return &user, nil
 }
 
+// LogAction records an admin action in admin_actions. targetUserID is users.id,
+// 0 if the action isn't about a user. A failed write is only logged: the action
+// itself has already been applied and must not be retried because of it.
+func (s *AdminService) LogAction(ctx context.Context, adminTgID int64, action string, targetUserID int64, details map[string]interface{}) {
+	a := &domain.AdminAction{
+		AdminTgID:    adminTgID,
+		Action:       action,
+		TargetUserID: targetUserID,
+		Details:      details,
+	}
+	if err := s.actions.Create(ctx, a); err != nil {
+		logger.Error("failed to log admin action", "error", err, "action", action, "admin_tg_id", adminTgID, "target_user_id", targetUserID)
+	}
+}
+
+// GetAuditLog returns the latest admin actions, newest first
+func (s *AdminService) GetAuditLog(ctx context.Context, limit int) ([]domain.AdminAction, error) {
+	return s.actions.GetRecent(ctx, limit)
+}
+
+// ListAdmins returns Telegram IDs of admins added via the bot (without ADMIN_TELEGRAM_IDS)
+func (s *AdminService) ListAdmins(ctx context.Context) ([]int64, error) {
+	return s.admins.ListTgIDs(ctx)
+}
+
+// AddAdmin persists a new admin; false if tgID was already one
+func (s *AdminService) AddAdmin(ctx context.Context, adminTgID, tgID int64) (bool, error) {
+	added, err := s.admins.Add(ctx, tgID, adminTgID)
+	if err != nil || !added {
+		return added, err
+	}
+	s.LogAction(ctx, adminTgID, domain.AdminActionAddAdmin, 0, map[string]interface{}{"tg_id": tgID})
+	return true, nil
+}
+
+// RemoveAdmin deletes an admin added via the bot; false if tgID wasn't one
+func (s *AdminService) RemoveAdmin(ctx context.Context, adminTgID, tgID int64) (bool, error) {
+	removed, err := s.admins.Remove(ctx, tgID)
+	if err != nil || !removed {
+		return removed, err
+	}
+	s.LogAction(ctx, adminTgID, domain.AdminActionRemoveAdmin, 0, map[string]interface{}{"tg_id": tgID})
+	return true, nil
+}
+
 // SetUserGems sets user's gems balance
-func (s *AdminService) SetUserGems(ctx context.Context, userID int64, gems int64) error {
+func (s *AdminService) SetUserGems(ctx context.Context, adminTgID, userID int64, gems int64) error {
 	_, err := s.db.Exec(ctx, `UPDATE users SET gems = $1 WHERE id = $2`, gems, userID)
-	return err
+	if err != nil {
+		return err
+	}
+	s.LogAction(ctx, adminTgID, domain.AdminActionSetGems, userID, map[string]interface{}{"gems": gems})
+	return nil
 }
 
 // AddUserGems adds gems to user's balance
-func (s *AdminService) AddUserGems(ctx context.Context, userID int64, amount int64) (int64, error) {
+func (s *AdminService) AddUserGems(ctx context.Context, adminTgID, userID int64, amount int64) (int64, error) {
 	var newBalance int64
 	err := s.db.QueryRow(ctx, `
 		UPDATE users SET gems = gems + $1 WHERE id = $2 RETURNING gems
 	`, amount, userID).Scan(&newBalance)
-	return newBalance, err
+	if err != nil {
+		return newBalance, err
+	}
+	s.LogAction(ctx, adminTgID, domain.AdminActionAddGems, userID, map[string]interface{}{"amount": amount, "new_balance": newBalance})
+	return newBalance, nil
 }
 
-// BanUser bans a user (sets gems to -1 as a marker)
-func (s *AdminService) BanUser(ctx context.Context, userID int64) error {
-	_, err := s.db.Exec(ctx, `UPDATE users SET gems = -1 WHERE id = $1`, userID)
-	return err
+// BanUser bans a user, the balance is kept as is
+func (s *AdminService) BanUser(ctx context.Context, adminTgID, userID int64) error {
+	_, err := s.db.Exec(ctx, `UPDATE users SET is_banned = true, banned_at = now() WHERE id = $1 AND NOT is_banned`, userID)
+	if err != nil {
+		return err
+	}
+	s.LogAction(ctx, adminTgID, domain.AdminActionBan, userID, nil)
+	return nil
 }
 
 // UnbanUser unbans a user
-func (s *AdminService) UnbanUser(ctx context.Context, userID int64) error {
-	_, err := s.db.Exec(ctx, `UPDATE users SET gems = 0 WHERE id = $1 AND gems = -1`, userID)
-	return err
+func (s *AdminService) UnbanUser(ctx context.Context, adminTgID, userID int64) error {
+	_, err := s.db.Exec(ctx, `UPDATE users SET is_banned = false, banned_at = NULL WHERE id = $1`, userID)
+	if err != nil {
+		return err
+	}
+	s.LogAction(ctx, adminTgID, domain.AdminActionUnban, userID, nil)
+	return nil
+}
+
+// SetUserExclusion locks the user out of games and withdrawals for the given
+// number of days starting now; 0 lifts the exclusion. Unlike the user's own
+// self-exclusion this may shorten or remove an active one.
+func (s *AdminService) SetUserExclusion(ctx context.Context, adminTgID, userID int64, days int) (*time.Time, error) {
+	var until *time.Time
+	if days > 0 {
+		t := time.Now().AddDate(0, 0, days)
+		until = &t
+	}
+	tag, err := s.db.Exec(ctx, `UPDATE users SET excluded_until = $1 WHERE id = $2`, until, userID)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrUserNotFound
+	}
+	s.LogAction(ctx, adminTgID, domain.AdminActionExclude, userID, map[string]interface{}{"days": days})
+	return until, nil
 }
 
 // GetTopUsers returns top users by gems
@@ -207,7 +364,7 @@ func (s *AdminService) GetTopUsers(ctx context.Context, limit int) ([]UserInfo,
 	rows, err := s.db.Query(ctx, `
 		SELECT id, tg_id, username, first_name, gems, created_at
 		FROM users
-		WHERE gems >= 0
+		WHERE NOT is_banned
 		ORDER BY gems DESC
 		LIMIT $1
 	`, limit)
@@ -267,13 +424,18 @@ func (s *AdminService) GetRecentGames(ctx context.Context, limit int) ([]map[str
 	return games, nil
 }
 
+// GetRecentSupportReports returns latest client desync reports
+func (s *AdminService) GetRecentSupportReports(ctx context.Context, limit int) ([]domain.SupportReport, error) {
+	return s.support.GetRecent(ctx, limit)
+}
+
 // PendingWithdrawal represents a pending withdrawal
 type PendingWithdrawal struct {
 	ID            int64     `json:"id"`
 	UserID        int64     `json:"user_id"`
 	Username      string    `json:"username"`
 	WalletAddress string    `json:"wallet_address"`
-	GemsAmount    int64     `json:"gems_amount"`
+	CoinsAmount   int64     `json:"coins_amount"`
 	TonAmount     string    `json:"ton_amount"`
 	Status        string    `json:"status"`
 	CreatedAt     time.Time `json:"created_at"`
@@ -282,9 +444,9 @@ type PendingWithdrawal struct {
 // GetPendingWithdrawals returns pending withdrawal requests
 func (s *AdminService) GetPendingWithdrawals(ctx context.Context) ([]PendingWithdrawal, error) {
 	rows, err := s.db.Query(ctx, `
-		SELECT w.id, w.user_id, u.username, w.wallet_address, w.gems_amount,
+		SELECT w.id, w.user_id, u.username, w.wallet_address, w.coins_amount,
 		       w.ton_amount_nano, w.status, w.created_at
-		FROM withdrawals w
+		FROM ton_withdrawals w
 		JOIN users u ON u.id = w.user_id
 		WHERE w.status IN ('pending', 'processing')
 		ORDER BY w.created_at ASC
@@ -299,7 +461,7 @@ func (s *AdminService) GetPendingWithdrawals(ctx context.Context) ([]PendingWith
 		var w PendingWithdrawal
 		var tonNano int64
 		if err := rows.Scan(&w.ID, &w.UserID, &w.Username, &w.WalletAddress,
-			&w.GemsAmount, &tonNano, &w.Status, &w.CreatedAt); err != nil {
+			&w.CoinsAmount, &tonNano, &w.Status, &w.CreatedAt); err != nil {
 			continue
 		}
 		w.TonAmount = fmt.Sprintf("%.4f TON", float64(tonNano)/1e9)
@@ -309,48 +471,34 @@ func (s *AdminService) GetPendingWithdrawals(ctx context.Context) ([]PendingWith
 }
 
 // ApproveWithdrawal marks withdrawal as sent (after manual sending)
-func (s *AdminService) ApproveWithdrawal(ctx context.Context, id int64, txHash string) error {
-	_, err := s.db.Exec(ctx, `
-		UPDATE withdrawals
+func (s *AdminService) ApproveWithdrawal(ctx context.Context, adminTgID, id int64, txHash string) error {
+	var userID int64
+	err := s.db.QueryRow(ctx, `
+		UPDATE ton_withdrawals
 		SET status = 'sent', tx_hash = $2, processed_at = NOW()
 		WHERE id = $1 AND status IN ('pending', 'processing')
-	`, id, txHash)
-	return err
-}
-
-// RejectWithdrawal rejects a withdrawal and refunds gems
-func (s *AdminService) RejectWithdrawal(ctx context.Context, id int64, reason string) error {
-	// Start transaction
-	tx, err := s.db.Begin(ctx)
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback(ctx)
-
-	// Get withdrawal info
-	var userID, gemsAmount int64
-	err = tx.QueryRow(ctx, `
-		SELECT user_id, gems_amount FROM withdrawals WHERE id = $1 AND status = 'pending'
-	`, id).Scan(&userID, &gemsAmount)
-	if err != nil {
-		return err
+		RETURNING user_id
+	`, id, txHash).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Уже обработан или не существует - ничего не изменилось
+		return nil
 	}
-
-	// Refund gems
-	_, err = tx.Exec(ctx, `UPDATE users SET gems = gems + $1 WHERE id = $2`, gemsAmount, userID)
 	if err != nil {
 		return err
 	}
+	s.LogAction(ctx, adminTgID, domain.AdminActionApproveWithdrawal, userID, map[string]interface{}{"withdrawal_id": id, "tx_hash": txHash})
+	return nil
+}
 
-	// Update withdrawal status
-	_, err = tx.Exec(ctx, `
-		UPDATE withdrawals SET status = 'cancelled', admin_notes = $2 WHERE id = $1
-	`, id, reason)
-	if err != nil {
+// RejectWithdrawal rejects a pending withdrawal and refunds the held coins
+func (s *AdminService) RejectWithdrawal(ctx context.Context, adminTgID, id int64, reason string) error {
+	if err := s.withdrawals.Reject(ctx, id, reason); err != nil {
 		return err
 	}
-
-	return tx.Commit(ctx)
+	var userID int64
+	_ = s.db.QueryRow(ctx, `SELECT user_id FROM ton_withdrawals WHERE id = $1`, id).Scan(&userID)
+	s.LogAction(ctx, adminTgID, domain.AdminActionRejectWithdrawal, userID, map[string]interface{}{"withdrawal_id": id, "reason": reason})
+	return nil
 }
 
 // Broadcast sends a message to all users (returns count)
@@ -379,6 +527,69 @@ func (s *AdminService) GetAllUserTgIDs(ctx context.Context) ([]int64, error) {
 	return ids, nil
 }
 
+// GetUserTgIDsForCategory returns tg_ids of users who accept notifications of the given category
+func (s *AdminService) GetUserTgIDsForCategory(ctx context.Context, category domain.NotificationCategory) ([]int64, error) {
+	column := ""
+	switch category {
+	case domain.NotificationBigWin:
+		column = "big_win"
+	case domain.NotificationWithdrawal:
+		column = "withdrawal"
+	case domain.NotificationQuest:
+		column = "quest"
+	case domain.NotificationPromo:
+		column = "promo"
+	case domain.NotificationStreakReminder:
+		column = "streak_reminder"
+	default:
+		return nil, fmt.Errorf("unknown notification category: %s", category)
+	}
+
+	// Users without a stored row get the defaults
+	defaultValue := domain.DefaultNotificationPrefs(0).Allows(category)
+	query := fmt.Sprintf(`
+		SELECT u.tg_id
+		FROM users u
+		LEFT JOIN notification_prefs np ON np.user_id = u.id
+		WHERE u.tg_id IS NOT NULL AND COALESCE(np.%s, $1)
+		ORDER BY u.id`, column)
+
+	rows, err := s.db.Query(ctx, query, defaultValue)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+
+	return ids, nil
+}
+
+// GetNotificationTarget returns user's tg_id and whether the category is enabled for them
+func (s *AdminService) GetNotificationTarget(ctx context.Context, userID int64, category domain.NotificationCategory) (int64, bool, error) {
+	var tgID int64
+	if err := s.db.QueryRow(ctx, `SELECT tg_id FROM users WHERE id = $1`, userID).Scan(&tgID); err != nil {
+		return 0, false, err
+	}
+
+	enabled, err := s.notifications.IsEnabled(ctx, userID, category)
+	if err != nil {
+		return 0, false, err
+	}
+	return tgID, enabled, nil
+}
+
 // GameRecord represents a single game record
 type GameRecord struct {
 	ID        int64     `json:"id"`
@@ -391,17 +602,18 @@ type GameRecord struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
-// GetUserGamesByTgID returns last games for user by telegram ID
-func (s *AdminService) GetUserGamesByTgID(ctx context.Context, tgID int64, currency string, limit int) ([]GameRecord, error) {
+// GetUserGamesByTgID returns last games for user by telegram ID; empty mode means all modes
+func (s *AdminService) GetUserGamesByTgID(ctx context.Context, tgID int64, currency string, mode domain.GameMode, limit int) ([]GameRecord, error) {
 	rows, err := s.db.Query(ctx, `
 		SELECT gh.id, gh.game_type, gh.mode, gh.result, gh.bet_amount, gh.win_amount,
 		       COALESCE(gh.currency, 'gems') as currency, gh.created_at
 		FROM game_history gh
 		JOIN users u ON u.id = gh.user_id
 		WHERE u.tg_id = $1 AND COALESCE(gh.currency, 'gems') = $2
+		  AND ($3 = '' OR gh.mode = $3)
 		ORDER BY gh.created_at DESC
-		LIMIT $3
-	`, tgID, currency, limit)
+		LIMIT $4
+	`, tgID, currency, string(mode), limit)
 	if err != nil {
 		return nil, err
 	}
@@ -460,13 +672,101 @@ func (s *AdminService) GetTopUsersByWins(ctx context.Context, limit int) ([]User
 	return stats, nil
 }
 
-// AddUserCoins adds coins to user's balance by tg_id
-func (s *AdminService) AddUserCoins(ctx context.Context, tgID int64, amount int64) (int64, error) {
+// GameTypeStat is house performance of one game in one currency.
+// win_amount in game_history is the player's net result, so the payout is bet + net.
+type GameTypeStat struct {
+	GameType    domain.GameType `json:"game_type"`
+	Currency    domain.Currency `json:"currency"`
+	Games       int64           `json:"games"`
+	Wagered     int64           `json:"wagered"`
+	PaidOut     int64           `json:"paid_out"`
+	HouseProfit int64           `json:"house_profit"`
+	RTP         float64         `json:"rtp"` // PaidOut / Wagered, 0 без ставок
+}
+
+// GetGameTypeStats returns totals per game type and currency over all time.
+// PvP games are played between users and are left out, as in GetDailySummary.
+func (s *AdminService) GetGameTypeStats(ctx context.Context) ([]GameTypeStat, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT game_type,
+		       COALESCE(currency, 'gems'),
+		       COUNT(*),
+		       COALESCE(SUM(bet_amount), 0),
+		       COALESCE(SUM(bet_amount + win_amount), 0)
+		FROM game_history
+		WHERE mode <> 'pvp'
+		GROUP BY game_type, COALESCE(currency, 'gems')
+		ORDER BY game_type, COALESCE(currency, 'gems')
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []GameTypeStat
+	for rows.Next() {
+		var st GameTypeStat
+		if err := rows.Scan(&st.GameType, &st.Currency, &st.Games, &st.Wagered, &st.PaidOut); err != nil {
+			return nil, err
+		}
+		st.HouseProfit = st.Wagered - st.PaidOut
+		if st.Wagered > 0 {
+			st.RTP = float64(st.PaidOut) / float64(st.Wagered)
+		}
+		stats = append(stats, st)
+	}
+	return stats, rows.Err()
+}
+
+// AddUserCoins adds coins to user's balance
+// Positive amounts are bonus funds and get a wagering requirement in the same transaction.
+func (s *AdminService) AddUserCoins(ctx context.Context, adminTgID, userID int64, amount int64) (int64, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
 	var newBalance int64
-	err := s.db.QueryRow(ctx, `
-		UPDATE users SET coins = coins + $1 WHERE tg_id = $2 RETURNING coins
-	`, amount, tgID).Scan(&newBalance)
-	return newBalance, err
+	err = tx.QueryRow(ctx, `
+		UPDATE users SET coins = coins + $1 WHERE id = $2 RETURNING coins
+	`, amount, userID).Scan(&newBalance)
+	if err != nil {
+		return 0, err
+	}
+	if amount > 0 {
+		if err := s.bonus.GrantWithTx(ctx, tx, userID, domain.CurrencyCoins, amount, s.bonusWagerMultiplier, "admin_bonus"); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	s.LogAction(ctx, adminTgID, domain.AdminActionAddCoins, userID, map[string]interface{}{"amount": amount, "new_balance": newBalance})
+	return newBalance, nil
+}
+
+// AddUserGK adds (or with a negative amount removes) GK and records an
+// admin_adjust transaction. Returns the new GK balance.
+func (s *AdminService) AddUserGK(ctx context.Context, adminTgID, userID int64, amount int64) (int64, error) {
+	newBalance, err := s.users.UpdateGK(ctx, userID, amount)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrInsufficientBalance
+		}
+		return 0, err
+	}
+	s.LogAction(ctx, adminTgID, domain.AdminActionAddGK, userID, map[string]interface{}{"amount": amount, "new_balance": newBalance})
+
+	if err := s.transactions.Create(ctx, &domain.Transaction{
+		UserID: userID,
+		Type:   "admin_adjust",
+		Amount: amount,
+		Meta:   map[string]interface{}{"currency": string(domain.CurrencyGK), "new_balance": newBalance},
+	}); err != nil {
+		return newBalance, err
+	}
+	return newBalance, nil
 }
 
 // GetUserByTgID returns user info by telegram ID
@@ -483,6 +783,45 @@ func (s *AdminService) GetUserByTgID(ctx context.Context, tgID int64) (*UserInfo
 	return &user, nil
 }
 
+// SuspiciousSignup is an account flagged by SignupGuard
+type SuspiciousSignup struct {
+	UserID        int64     `json:"user_id"`
+	TgID          int64     `json:"tg_id"`
+	Username      string    `json:"username"`
+	IP            string    `json:"ip"`
+	SharedIPCount int       `json:"shared_ip_count"`
+	Gems          int64     `json:"gems"`
+	Banned        bool      `json:"banned"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// GetSuspiciousSignups returns latest accounts flagged for sharing a signup IP
+func (s *AdminService) GetSuspiciousSignups(ctx context.Context, limit int) ([]SuspiciousSignup, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT u.id, u.tg_id, COALESCE(NULLIF(u.username, ''), u.first_name, ''), sg.ip, sg.shared_ip_count,
+		       u.gems, COALESCE(u.is_banned, false), sg.created_at
+		FROM signups sg
+		JOIN users u ON u.id = sg.user_id
+		WHERE sg.flagged
+		ORDER BY sg.created_at DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	signups := []SuspiciousSignup{}
+	for rows.Next() {
+		var sg SuspiciousSignup
+		if err := rows.Scan(&sg.UserID, &sg.TgID, &sg.Username, &sg.IP, &sg.SharedIPCount, &sg.Gems, &sg.Banned, &sg.CreatedAt); err != nil {
+			return nil, err
+		}
+		signups = append(signups, sg)
+	}
+	return signups, rows.Err()
+}
+
 // ReferralStat represents referral statistics for a user
 type ReferralStat struct {
 	UserID    int64  `json:"user_id"`
@@ -555,6 +894,47 @@ func (s *AdminService) GetAllUsers(ctx context.Context, limit, offset int) ([]Us
 	return users, total, nil
 }
 
+// ErrEmptySearchQuery - пустой запрос нашёл бы всех пользователей
+var ErrEmptySearchQuery = errors.New("search query is empty")
+
+// likeEscaper makes %, _ and \ in user input match literally in LIKE patterns
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchUsers returns up to limit users whose username or first name contains
+// query, case-insensitive. A leading @ is ignored.
+func (s *AdminService) SearchUsers(ctx context.Context, query string, limit int) ([]UserListItem, error) {
+	query = strings.TrimPrefix(strings.TrimSpace(query), "@")
+	if query == "" {
+		return nil, ErrEmptySearchQuery
+	}
+	if limit <= 0 || limit > 50 {
+		limit = 10
+	}
+
+	pattern := "%" + likeEscaper.Replace(query) + "%"
+	rows, err := s.db.Query(ctx, `
+		SELECT id, tg_id, COALESCE(username, ''), COALESCE(first_name, ''), gems, COALESCE(coins, 0)
+		FROM users
+		WHERE username ILIKE $1 ESCAPE '\' OR first_name ILIKE $1 ESCAPE '\'
+		ORDER BY id DESC
+		LIMIT $2
+	`, pattern, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []UserListItem
+	for rows.Next() {
+		var u UserListItem
+		if err := rows.Scan(&u.ID, &u.TgID, &u.Username, &u.FirstName, &u.Gems, &u.Coins); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
 // GetUserByUsername returns user by username (without @)
 func (s *AdminService) GetUserByUsername(ctx context.Context, username string) (*UserInfo, error) {
 	// Remove @ if present
@@ -610,7 +990,7 @@ func (s *AdminService) GetWithdrawalNotification(ctx context.Context, withdrawal
 	err := s.db.QueryRow(ctx, `
 		SELECT w.id, w.user_id, COALESCE(u.username, u.first_name, ''), u.tg_id,
 		       w.wallet_address, w.coins_amount, w.ton_amount_nano
-		FROM withdrawals w
+		FROM ton_withdrawals w
 		JOIN users u ON u.id = w.user_id
 		WHERE w.id = $1
 	`, withdrawalID).Scan(&w.ID, &w.UserID, &w.Username, &w.TgID, &w.WalletAddress, &w.CoinsAmount, &tonNano)
@@ -661,18 +1041,28 @@ func (s *AdminService) GetAllQuests(ctx context.Context) ([]QuestInfo, error) {
 }
 
 // CreateQuest creates a new quest
-func (s *AdminService) CreateQuest(ctx context.Context, questType, title, description, actionType string, targetCount int, rewardGems, rewardCoins, rewardGK int64) (int64, error) {
+func (s *AdminService) CreateQuest(ctx context.Context, adminTgID int64, questType, title, description, actionType string, targetCount int, rewardGems, rewardCoins, rewardGK int64) (int64, error) {
 	var id int64
 	err := s.db.QueryRow(ctx, `
 		INSERT INTO quests (quest_type, title, description, action_type, target_count, reward_gems, reward_coins, reward_gk, is_active)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, true)
 		RETURNING id
 	`, questType, title, description, actionType, targetCount, rewardGems, rewardCoins, rewardGK).Scan(&id)
-	return id, err
+	if err != nil {
+		return id, err
+	}
+	s.LogAction(ctx, adminTgID, domain.AdminActionCreateQuest, 0, map[string]interface{}{
+		"quest_id":     id,
+		"title":        title,
+		"reward_gems":  rewardGems,
+		"reward_coins": rewardCoins,
+		"reward_gk":    rewardGK,
+	})
+	return id, nil
 }
 
 // DeleteQuest deletes a quest by ID
-func (s *AdminService) DeleteQuest(ctx context.Context, id int64) error {
+func (s *AdminService) DeleteQuest(ctx context.Context, adminTgID, id int64) error {
 	// First delete all user progress for this quest
 	_, err := s.db.Exec(ctx, `DELETE FROM user_quests WHERE quest_id = $1`, id)
 	if err != nil {
@@ -680,16 +1070,24 @@ func (s *AdminService) DeleteQuest(ctx context.Context, id int64) error {
 	}
 	// Then delete the quest itself
 	_, err = s.db.Exec(ctx, `DELETE FROM quests WHERE id = $1`, id)
-	return err
+	if err != nil {
+		return err
+	}
+	s.LogAction(ctx, adminTgID, domain.AdminActionDeleteQuest, 0, map[string]interface{}{"quest_id": id})
+	return nil
 }
 
 // ToggleQuestActive toggles quest active status
-func (s *AdminService) ToggleQuestActive(ctx context.Context, id int64) (bool, error) {
+func (s *AdminService) ToggleQuestActive(ctx context.Context, adminTgID, id int64) (bool, error) {
 	var newStatus bool
 	err := s.db.QueryRow(ctx, `
 		UPDATE quests SET is_active = NOT is_active WHERE id = $1 RETURNING is_active
 	`, id).Scan(&newStatus)
-	return newStatus, err
+	if err != nil {
+		return newStatus, err
+	}
+	s.LogAction(ctx, adminTgID, domain.AdminActionToggleQuest, 0, map[string]interface{}{"quest_id": id, "is_active": newStatus})
+	return newStatus, nil
 }
 
 // GetQuestCount returns the total number of quests
@@ -698,3 +1096,33 @@ func (s *AdminService) GetQuestCount(ctx context.Context) (int, error) {
 	err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM quests`).Scan(&count)
 	return count, err
 }
+
+// SetGameLimits persists bet limits of a game in a currency and reloads them in game handlers
+func (s *AdminService) SetGameLimits(ctx context.Context, adminTgID int64, gt domain.GameType, currency domain.Currency, minBet, maxBet int64) error {
+	l := &domain.GameLimit{GameType: gt, Currency: currency, MinBet: minBet, MaxBet: maxBet}
+	if err := ValidateGameLimit(l); err != nil {
+		return err
+	}
+	if err := s.gameLimits.Upsert(ctx, l); err != nil {
+		return err
+	}
+	s.LogAction(ctx, adminTgID, domain.AdminActionSetLimits, 0, map[string]interface{}{
+		"game_type": string(gt),
+		"currency":  string(currency),
+		"min_bet":   minBet,
+		"max_bet":   maxBet,
+	})
+	if s.onGameLimitsChange != nil {
+		return s.onGameLimitsChange(ctx)
+	}
+	return nil
+}
+
+// GetGameLimits returns effective bet limits per game and the games that have overrides
+func (s *AdminService) GetGameLimits(ctx context.Context) (map[domain.GameType]GameLimits, []*domain.GameLimit, error) {
+	overrides, err := s.gameLimits.List(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return MergeGameLimits(s.defaultLimits, overrides), overrides, nil
+}
@@ -0,0 +1,18 @@
+package service
+
+import "testing"
+
+func TestLikeEscaper(t *testing.T) {
+	cases := map[string]string{
+		"john":    "john",
+		"100%":    `100\%`,
+		"a_b":     `a\_b`,
+		`back\sl`: `back\\sl`,
+		`%_\`:     `\%\_\\`,
+	}
+	for in, want := range cases {
+		if got := likeEscaper.Replace(in); got != want {
+			t.Fatalf("%q: expected %q, got %q", in, want, got)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"telegram_webapp/internal/domain"
 	"telegram_webapp/internal/repository"
@@ -15,12 +17,27 @@ var (
 	ErrInsufficientFunds = errors.New("insufficient funds")
 	ErrUserNotFound      = errors.New("user not found")
 	ErrInvalidAmount     = errors.New("invalid amount")
+
+	// ErrBonusBalanceTooHigh - gems не ниже порога бонуса
+	ErrBonusBalanceTooHigh = errors.New("balance too high for bonus")
+	// ErrBonusFlaggedSignup - регистрация помечена SignupGuard и депозита ещё не было
+	ErrBonusFlaggedSignup = errors.New("bonus is not available for this account")
 )
 
+// ErrBonusOnCooldown is returned by ClaimBonus while the previous bonus is too recent
+type ErrBonusOnCooldown struct {
+	Remaining time.Duration
+}
+
+func (e *ErrBonusOnCooldown) Error() string {
+	return fmt.Sprintf("bonus on cooldown, %s left", e.Remaining.Round(time.Second))
+}
+
 // BalanceService handles all balance operations
 type BalanceService struct {
 	db              *pgxpool.Pool
 	transactionRepo *repository.TransactionRepository
+	bonus           domain.BonusConfig
 }
 
 // NewBalanceService creates a new balance service
@@ -28,9 +45,20 @@ func NewBalanceService(db *pgxpool.Pool) *BalanceService {
 	return &BalanceService{
 		db:              db,
 		transactionRepo: repository.NewTransactionRepository(db),
+		bonus:           domain.DefaultBonusConfig(),
 	}
 }
 
+// SetBonusConfig replaces the low balance bonus settings
+func (s *BalanceService) SetBonusConfig(cfg domain.BonusConfig) {
+	s.bonus = cfg
+}
+
+// BonusConfig returns the low balance bonus settings
+func (s *BalanceService) BonusConfig() domain.BonusConfig {
+	return s.bonus
+}
+
 // GetBalance returns user's current balance
 func (s *BalanceService) GetBalance(ctx context.Context, userID int64) (int64, error) {
 	var balance int64
@@ -46,9 +74,15 @@ func (s *BalanceService) GetBalance(ctx context.Context, userID int64) (int64, e
 
 // Debit deducts amount from user's balance (for bets, purchases, etc.)
 func (s *BalanceService) Debit(ctx context.Context, userID int64, amount int64, txType string, meta map[string]interface{}) (newBalance int64, err error) {
+	return s.DebitCurrency(ctx, userID, domain.CurrencyGems, amount, txType, meta)
+}
+
+// DebitCurrency deducts amount from user's balance in the given currency and records the transaction
+func (s *BalanceService) DebitCurrency(ctx context.Context, userID int64, currency domain.Currency, amount int64, txType string, meta map[string]interface{}) (newBalance int64, err error) {
 	if amount <= 0 {
 		return 0, ErrInvalidAmount
 	}
+	col := currency.BalanceColumn()
 
 	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
@@ -58,7 +92,7 @@ func (s *BalanceService) Debit(ctx context.Context, userID int64, amount int64,
 
 	// Lock and check balance
 	var balance int64
-	err = tx.QueryRow(ctx, `SELECT gems FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&balance)
+	err = tx.QueryRow(ctx, fmt.Sprintf(`SELECT %s FROM users WHERE id = $1 FOR UPDATE`, col), userID).Scan(&balance)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return 0, ErrUserNotFound
@@ -71,7 +105,7 @@ func (s *BalanceService) Debit(ctx context.Context, userID int64, amount int64,
 	}
 
 	// Deduct
-	err = tx.QueryRow(ctx, `UPDATE users SET gems = gems - $1 WHERE id = $2 RETURNING gems`, amount, userID).Scan(&newBalance)
+	err = tx.QueryRow(ctx, fmt.Sprintf(`UPDATE users SET %[1]s = %[1]s - $1 WHERE id = $2 RETURNING %[1]s`, col), amount, userID).Scan(&newBalance)
 	if err != nil {
 		return 0, err
 	}
@@ -81,7 +115,7 @@ func (s *BalanceService) Debit(ctx context.Context, userID int64, amount int64,
 		UserID: userID,
 		Type:   txType,
 		Amount: -amount,
-		Meta:   meta,
+		Meta:   withCurrencyMeta(meta, currency),
 	}
 	if err = s.transactionRepo.CreateWithTx(ctx, tx, transaction); err != nil {
 		return 0, err
@@ -96,9 +130,15 @@ func (s *BalanceService) Debit(ctx context.Context, userID int64, amount int64,
 
 // Credit adds amount to user's balance (for winnings, deposits, bonuses, etc.)
 func (s *BalanceService) Credit(ctx context.Context, userID int64, amount int64, txType string, meta map[string]interface{}) (newBalance int64, err error) {
+	return s.CreditCurrency(ctx, userID, domain.CurrencyGems, amount, txType, meta)
+}
+
+// CreditCurrency adds amount to user's balance in the given currency and records the transaction
+func (s *BalanceService) CreditCurrency(ctx context.Context, userID int64, currency domain.Currency, amount int64, txType string, meta map[string]interface{}) (newBalance int64, err error) {
 	if amount <= 0 {
 		return 0, ErrInvalidAmount
 	}
+	col := currency.BalanceColumn()
 
 	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
@@ -107,7 +147,7 @@ func (s *BalanceService) Credit(ctx context.Context, userID int64, amount int64,
 	defer func() { _ = tx.Rollback(ctx) }()
 
 	// Credit
-	err = tx.QueryRow(ctx, `UPDATE users SET gems = gems + $1 WHERE id = $2 RETURNING gems`, amount, userID).Scan(&newBalance)
+	err = tx.QueryRow(ctx, fmt.Sprintf(`UPDATE users SET %[1]s = %[1]s + $1 WHERE id = $2 RETURNING %[1]s`, col), amount, userID).Scan(&newBalance)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return 0, ErrUserNotFound
@@ -120,7 +160,7 @@ func (s *BalanceService) Credit(ctx context.Context, userID int64, amount int64,
 		UserID: userID,
 		Type:   txType,
 		Amount: amount,
-		Meta:   meta,
+		Meta:   withCurrencyMeta(meta, currency),
 	}
 	if err = s.transactionRepo.CreateWithTx(ctx, tx, transaction); err != nil {
 		return 0, err
@@ -133,15 +173,33 @@ func (s *BalanceService) Credit(ctx context.Context, userID int64, amount int64,
 	return newBalance, nil
 }
 
-// Transfer moves amount from one user to another
-func (s *BalanceService) Transfer(ctx context.Context, fromUserID, toUserID int64, amount int64, meta map[string]interface{}) error {
-	if amount <= 0 {
-		return ErrInvalidAmount
+// withCurrencyMeta adds the currency to a copy of meta; gems are the default and aren't recorded
+func withCurrencyMeta(meta map[string]interface{}, currency domain.Currency) map[string]interface{} {
+	if currency == domain.CurrencyGems {
+		return meta
+	}
+	withCurrency := make(map[string]interface{}, len(meta)+1)
+	for k, v := range meta {
+		withCurrency[k] = v
+	}
+	withCurrency["currency"] = string(currency)
+	return withCurrency
+}
+
+// Transfer moves amount gems from one user to another within limits. The daily
+// cap is checked after the sender row is locked, so concurrent transfers can't
+// both slip under it. Returns both balances after the transfer.
+func (s *BalanceService) Transfer(ctx context.Context, fromUserID, toUserID int64, amount int64, limits TransferLimits, meta map[string]interface{}) (fromBalance, toBalance int64, err error) {
+	if fromUserID == toUserID {
+		return 0, 0, ErrSelfTransfer
+	}
+	if err := limits.Check(amount); err != nil {
+		return 0, 0, err
 	}
 
 	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
-		return err
+		return 0, 0, err
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
@@ -154,44 +212,89 @@ func (s *BalanceService) Transfer(ctx context.Context, fromUserID, toUserID int6
 	var balance1, balance2 int64
 	err = tx.QueryRow(ctx, `SELECT gems FROM users WHERE id = $1 FOR UPDATE`, firstID).Scan(&balance1)
 	if err != nil {
-		return err
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, 0, ErrUserNotFound
+		}
+		return 0, 0, err
 	}
 	err = tx.QueryRow(ctx, `SELECT gems FROM users WHERE id = $1 FOR UPDATE`, secondID).Scan(&balance2)
 	if err != nil {
-		return err
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, 0, ErrUserNotFound
+		}
+		return 0, 0, err
 	}
 
 	// Check sender's balance
-	var senderBalance int64
-	if fromUserID == firstID {
-		senderBalance = balance1
-	} else {
-		senderBalance = balance2
+	senderBalance, recipientBalance := balance1, balance2
+	if fromUserID != firstID {
+		senderBalance, recipientBalance = balance2, balance1
 	}
 
 	if senderBalance < amount {
-		return ErrInsufficientFunds
+		return 0, 0, ErrInsufficientFunds
+	}
+
+	// Защита от ферм: проверяется после блокировки, баланс уже не изменится
+	sender := TransferSender{Gems: senderBalance, PromoGems: repository.InitialGems}
+	var promo int64
+	err = tx.QueryRow(ctx, `
+		SELECT u.created_at,
+		       (SELECT COALESCE(SUM(bet_amount), 0) FROM game_history
+		        WHERE user_id = u.id AND currency = 'gems'),
+		       (SELECT COALESCE(SUM(amount), 0) FROM transactions
+		        WHERE user_id = u.id AND type = ANY($2) AND COALESCE(meta->>'currency', 'gems') = 'gems')
+		FROM users u WHERE u.id = $1
+	`, fromUserID, promoGemTypes).Scan(&sender.CreatedAt, &sender.Wagered, &promo)
+	if err != nil {
+		return 0, 0, err
+	}
+	sender.PromoGems += promo
+	if err := limits.CheckSender(sender, amount, time.Now()); err != nil {
+		return 0, 0, err
+	}
+
+	// Дневной лимит: transfer_out хранится с минусом
+	if limits.DailyCap > 0 {
+		var sentToday int64
+		err = tx.QueryRow(ctx,
+			`SELECT COALESCE(-SUM(amount), 0) FROM transactions
+			 WHERE user_id = $1 AND type = 'transfer_out' AND created_at >= $2`,
+			fromUserID, transferDayStart(time.Now()),
+		).Scan(&sentToday)
+		if err != nil {
+			return 0, 0, err
+		}
+		if sentToday+amount > limits.DailyCap {
+			return 0, 0, ErrTransferDailyCap
+		}
 	}
 
 	// Execute transfer
 	_, err = tx.Exec(ctx, `UPDATE users SET gems = gems - $1 WHERE id = $2`, amount, fromUserID)
 	if err != nil {
-		return err
+		return 0, 0, err
 	}
 	_, err = tx.Exec(ctx, `UPDATE users SET gems = gems + $1 WHERE id = $2`, amount, toUserID)
 	if err != nil {
-		return err
+		return 0, 0, err
 	}
 
 	// Record transactions
+	fromMeta := make(map[string]interface{})
+	for k, v := range meta {
+		fromMeta[k] = v
+	}
+	fromMeta["to_user_id"] = toUserID
+
 	fromTx := &domain.Transaction{
 		UserID: fromUserID,
 		Type:   "transfer_out",
 		Amount: -amount,
-		Meta:   meta,
+		Meta:   fromMeta,
 	}
 	if err = s.transactionRepo.CreateWithTx(ctx, tx, fromTx); err != nil {
-		return err
+		return 0, 0, err
 	}
 
 	toMeta := make(map[string]interface{})
@@ -207,10 +310,13 @@ func (s *BalanceService) Transfer(ctx context.Context, fromUserID, toUserID int6
 		Meta:   toMeta,
 	}
 	if err = s.transactionRepo.CreateWithTx(ctx, tx, toTx); err != nil {
-		return err
+		return 0, 0, err
 	}
 
-	return tx.Commit(ctx)
+	if err = tx.Commit(ctx); err != nil {
+		return 0, 0, err
+	}
+	return senderBalance - amount, recipientBalance + amount, nil
 }
 
 // DebitWithTx deducts amount within an existing transaction
@@ -262,8 +368,23 @@ func (s *BalanceService) CreditWithTx(ctx context.Context, tx pgx.Tx, userID int
 	return newBalance, nil
 }
 
-// ClaimBonus gives bonus gems to user if balance is low
-func (s *BalanceService) ClaimBonus(ctx context.Context, userID int64, bonusAmount int64, minBalanceThreshold int64) (newBalance int64, err error) {
+// bonusCooldownRemaining returns how long until the next bonus; 0 if it can be claimed now
+func bonusCooldownRemaining(lastBonusAt *time.Time, cooldown time.Duration, now time.Time) time.Duration {
+	if lastBonusAt == nil || cooldown <= 0 {
+		return 0
+	}
+	if left := lastBonusAt.Add(cooldown).Sub(now); left > 0 {
+		return left
+	}
+	return 0
+}
+
+// ClaimBonus gives the configured bonus gems if the user's balance is below the
+// threshold and the cooldown since the previous bonus has passed. The row lock
+// makes parallel claims wait, so only one of them passes the cooldown check.
+func (s *BalanceService) ClaimBonus(ctx context.Context, userID int64) (newBalance int64, err error) {
+	cfg := s.bonus
+
 	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
 		return 0, err
@@ -272,7 +393,8 @@ func (s *BalanceService) ClaimBonus(ctx context.Context, userID int64, bonusAmou
 
 	// Check current balance
 	var balance int64
-	err = tx.QueryRow(ctx, `SELECT gems FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&balance)
+	var lastBonusAt *time.Time
+	err = tx.QueryRow(ctx, `SELECT gems, last_bonus_at FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&balance, &lastBonusAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return 0, ErrUserNotFound
@@ -280,12 +402,30 @@ func (s *BalanceService) ClaimBonus(ctx context.Context, userID int64, bonusAmou
 		return 0, err
 	}
 
-	if balance >= minBalanceThreshold {
-		return balance, errors.New("balance too high for bonus")
+	if left := bonusCooldownRemaining(lastBonusAt, cfg.Cooldown, time.Now()); left > 0 {
+		return balance, &ErrBonusOnCooldown{Remaining: left}
+	}
+	if balance >= cfg.Threshold {
+		return balance, ErrBonusBalanceTooHigh
+	}
+
+	// Иначе ферма аккаунтов доит бонус, урезанный стартовый баланс ничего не даёт
+	var flagged bool
+	if err := tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM signups WHERE user_id = $1 AND flagged)
+		   AND NOT EXISTS (SELECT 1 FROM deposits WHERE user_id = $1 AND status = 'confirmed')
+	`, userID).Scan(&flagged); err != nil {
+		return 0, err
+	}
+	if flagged {
+		return balance, ErrBonusFlaggedSignup
 	}
 
 	// Add bonus
-	err = tx.QueryRow(ctx, `UPDATE users SET gems = gems + $1 WHERE id = $2 RETURNING gems`, bonusAmount, userID).Scan(&newBalance)
+	err = tx.QueryRow(ctx,
+		`UPDATE users SET gems = gems + $1, last_bonus_at = NOW() WHERE id = $2 RETURNING gems`,
+		cfg.Amount, userID,
+	).Scan(&newBalance)
 	if err != nil {
 		return 0, err
 	}
@@ -294,7 +434,7 @@ func (s *BalanceService) ClaimBonus(ctx context.Context, userID int64, bonusAmou
 	transaction := &domain.Transaction{
 		UserID: userID,
 		Type:   "bonus",
-		Amount: bonusAmount,
+		Amount: cfg.Amount,
 		Meta:   map[string]interface{}{"reason": "low_balance_bonus"},
 	}
 	if err = s.transactionRepo.CreateWithTx(ctx, tx, transaction); err != nil {
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestBonusCooldownRemaining(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time { v := now.Add(-d); return &v }
+
+	cases := []struct {
+		last     *time.Time
+		cooldown time.Duration
+		want     time.Duration
+	}{
+		{nil, 6 * time.Hour, 0},
+		{at(time.Hour), 6 * time.Hour, 5 * time.Hour},
+		{at(6 * time.Hour), 6 * time.Hour, 0},
+		{at(7 * time.Hour), 6 * time.Hour, 0},
+		{at(time.Minute), 0, 0}, // кулдаун выключен
+	}
+	for _, tc := range cases {
+		if got := bonusCooldownRemaining(tc.last, tc.cooldown, now); got != tc.want {
+			t.Fatalf("last=%v cooldown=%s: expected %s, got %s", tc.last, tc.cooldown, tc.want, got)
+		}
+	}
+}
+
+// Integration-style test: runs only if TEST_DATABASE_URL env is set.
+func TestClaimBonusCooldown(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set; skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	db, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+
+	u := &domain.User{TgID: time.Now().UnixNano(), Username: "bonus_cooldown_test"}
+	if err := repository.NewUserRepository(db).Create(ctx, u); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	defer db.Exec(context.Background(), `DELETE FROM users WHERE id = $1`, u.ID)
+	defer db.Exec(context.Background(), `DELETE FROM transactions WHERE user_id = $1`, u.ID)
+	if _, err := db.Exec(ctx, `UPDATE users SET gems = 0, last_bonus_at = NULL WHERE id = $1`, u.ID); err != nil {
+		t.Fatalf("reset balance: %v", err)
+	}
+
+	s := NewBalanceService(db)
+	s.SetBonusConfig(domain.BonusConfig{Amount: 500, Threshold: 100, Cooldown: time.Hour})
+
+	gems, err := s.ClaimBonus(ctx, u.ID)
+	if err != nil || gems != 500 {
+		t.Fatalf("first claim: gems=%d err=%v", gems, err)
+	}
+
+	// Спустили баланс в ноль - повторный бонус всё равно ждёт кулдаун
+	if _, err := db.Exec(ctx, `UPDATE users SET gems = 0 WHERE id = $1`, u.ID); err != nil {
+		t.Fatalf("drain balance: %v", err)
+	}
+	_, err = s.ClaimBonus(ctx, u.ID)
+	var cooldown *ErrBonusOnCooldown
+	if !errors.As(err, &cooldown) || cooldown.Remaining <= 0 || cooldown.Remaining > time.Hour {
+		t.Fatalf("expected cooldown error with remaining time, got %v", err)
+	}
+
+	if _, err := db.Exec(ctx, `UPDATE users SET last_bonus_at = NOW() - INTERVAL '2 hours' WHERE id = $1`, u.ID); err != nil {
+		t.Fatalf("age last bonus: %v", err)
+	}
+	if gems, err := s.ClaimBonus(ctx, u.ID); err != nil || gems != 500 {
+		t.Fatalf("claim after cooldown: gems=%d err=%v", gems, err)
+	}
+}
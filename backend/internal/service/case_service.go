@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	"telegram_webapp/internal/logger"
+	"telegram_webapp/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrUnknownCase       = errors.New("unknown case")
+	ErrInvalidCaseConfig = errors.New("invalid case config")
+)
+
+// caseProbTolerance - допустимое отклонение суммы вероятностей от 1
+const caseProbTolerance = 0.001
+
+// CasePrize is one possible case outcome
+type CasePrize struct {
+	ID     int     `json:"id"`
+	Amount int64   `json:"amount"`
+	Prob   float64 `json:"probability"`
+	Rarity string  `json:"rarity"`
+}
+
+// CaseConfig is the authoritative case cost and prize table, served to the
+// frontend by /game/case/info so the two can't drift apart
+type CaseConfig struct {
+	ID     string      `json:"case_id"`
+	Name   string      `json:"name"`
+	Cost   int64       `json:"cost"`
+	Prizes []CasePrize `json:"prizes"`
+}
+
+// Validate checks the cost, that prize ids are unique, that prize probabilities
+// are positive and sum to ~1 and that the case keeps a house edge (EV < cost)
+func (c CaseConfig) Validate() error {
+	if c.ID == "" || c.Cost <= 0 || len(c.Prizes) == 0 {
+		return fmt.Errorf("%w: case %q needs an id, a positive cost and prizes", ErrInvalidCaseConfig, c.ID)
+	}
+	sum := 0.0
+	seen := make(map[int]bool, len(c.Prizes))
+	for _, p := range c.Prizes {
+		if p.Prob <= 0 || p.Amount < 0 {
+			return fmt.Errorf("%w: case %q prize %d has a bad amount or probability", ErrInvalidCaseConfig, c.ID, p.ID)
+		}
+		if seen[p.ID] {
+			return fmt.Errorf("%w: case %q has duplicate prize id %d", ErrInvalidCaseConfig, c.ID, p.ID)
+		}
+		seen[p.ID] = true
+		sum += p.Prob
+	}
+	if math.Abs(sum-1) > caseProbTolerance {
+		return fmt.Errorf("%w: case %q probabilities sum to %.4f, not 1", ErrInvalidCaseConfig, c.ID, sum)
+	}
+	if rtp := c.ExpectedPrize() / float64(c.Cost); rtp >= 1-rtpEpsilon {
+		return fmt.Errorf("%w: case %q returns %.3f per unit spent, must be below 1", ErrInvalidCaseConfig, c.ID, rtp)
+	}
+	return nil
+}
+
+// ExpectedPrize returns the average prize per spin
+func (c CaseConfig) ExpectedPrize() float64 {
+	ev := 0.0
+	for _, p := range c.Prizes {
+		ev += float64(p.Amount) * p.Prob
+	}
+	return ev
+}
+
+// pick returns the prize for a uniform roll in [0,1)
+func (c CaseConfig) pick(r float64) CasePrize {
+	acc := 0.0
+	for _, p := range c.Prizes {
+		acc += p.Prob
+		if r <= acc {
+			return p
+		}
+	}
+	return c.Prizes[len(c.Prizes)-1]
+}
+
+// CaseService keeps the available cases loaded from case_configs, the only
+// source of case tables (seeded by migrations). Until the first Reload, and
+// while no case is active, there are no cases.
+type CaseService struct {
+	repo *repository.CaseConfigRepository
+
+	mu    sync.RWMutex
+	cases []CaseConfig
+}
+
+// NewCaseService creates the service; cases appear after the first Reload
+func NewCaseService(db *pgxpool.Pool) *CaseService {
+	return &CaseService{repo: repository.NewCaseConfigRepository(db)}
+}
+
+// Reload loads active cases from case_configs. Cases that fail validation are
+// skipped and logged; the previous list is kept if none is valid. No active
+// rows means every case was disabled, so the list becomes empty.
+func (s *CaseService) Reload(ctx context.Context) error {
+	rows, err := s.repo.GetActive(ctx)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		s.set(nil)
+		return nil
+	}
+
+	var cases []CaseConfig
+	for _, row := range rows {
+		cfg := CaseConfig{ID: row.CaseID, Name: row.Name, Cost: row.Cost}
+		if err := json.Unmarshal(row.Prizes, &cfg.Prizes); err != nil {
+			logger.Error("invalid case_configs prizes", "case_id", row.CaseID, "error", err)
+			continue
+		}
+		if err := cfg.Validate(); err != nil {
+			logger.Error("invalid case_configs row", "case_id", row.CaseID, "error", err)
+			continue
+		}
+		cases = append(cases, cfg)
+	}
+	if len(cases) == 0 {
+		return fmt.Errorf("%w: no valid active cases", ErrInvalidCaseConfig)
+	}
+	s.set(cases)
+	return nil
+}
+
+func (s *CaseService) set(cases []CaseConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cases = cases
+}
+
+// Cases returns the available cases in display order
+func (s *CaseService) Cases() []CaseConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]CaseConfig(nil), s.cases...)
+}
+
+// Get returns the case by id; an empty id means the first case
+func (s *CaseService) Get(id string) (CaseConfig, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if id == "" {
+		if len(s.cases) == 0 {
+			return CaseConfig{}, ErrUnknownCase
+		}
+		return s.cases[0], nil
+	}
+	for _, c := range s.cases {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return CaseConfig{}, ErrUnknownCase
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"errors"
+	"testing"
+)
+
+// classicCase mirrors the classic case seeded by migrations 032/044
+func classicCase() CaseConfig {
+	return CaseConfig{
+		ID:   "classic",
+		Name: "Classic",
+		Cost: 100,
+		Prizes: []CasePrize{
+			{ID: 1, Amount: 25, Prob: 0.5, Rarity: "Common"},
+			{ID: 2, Amount: 50, Prob: 0.2, Rarity: "Uncommon"},
+			{ID: 3, Amount: 100, Prob: 0.15, Rarity: "Rare"},
+			{ID: 4, Amount: 200, Prob: 0.10, Rarity: "Epic"},
+			{ID: 5, Amount: 750, Prob: 0.05, Rarity: "Legendary"},
+		},
+	}
+}
+
+func TestCaseConfigValidate(t *testing.T) {
+	if err := classicCase().Validate(); err != nil {
+		t.Fatalf("classic case: %v", err)
+	}
+
+	cases := map[string]CaseConfig{
+		"no prizes":     {ID: "a", Cost: 100},
+		"zero cost":     {ID: "a", Prizes: []CasePrize{{ID: 1, Amount: 10, Prob: 1}}},
+		"sum below one": {ID: "a", Cost: 100, Prizes: []CasePrize{{ID: 1, Amount: 10, Prob: 0.5}, {ID: 2, Amount: 20, Prob: 0.4}}},
+		"sum above one": {ID: "a", Cost: 100, Prizes: []CasePrize{{ID: 1, Amount: 10, Prob: 0.7}, {ID: 2, Amount: 20, Prob: 0.4}}},
+		"zero prob":     {ID: "a", Cost: 100, Prizes: []CasePrize{{ID: 1, Amount: 10, Prob: 1}, {ID: 2, Amount: 20, Prob: 0}}},
+		"duplicate id":  {ID: "a", Cost: 100, Prizes: []CasePrize{{ID: 1, Amount: 10, Prob: 0.5}, {ID: 1, Amount: 20, Prob: 0.5}}},
+		"ev of cost":    {ID: "a", Cost: 100, Prizes: []CasePrize{{ID: 1, Amount: 100, Prob: 1}}},
+		"ev above cost": {ID: "a", Cost: 100, Prizes: []CasePrize{{ID: 1, Amount: 50, Prob: 0.5}, {ID: 2, Amount: 200, Prob: 0.5}}},
+	}
+	for name, cfg := range cases {
+		if err := cfg.Validate(); !errors.Is(err, ErrInvalidCaseConfig) {
+			t.Fatalf("%s: expected ErrInvalidCaseConfig, got %v", name, err)
+		}
+	}
+
+	// Округление в конфиге допустимо
+	thirds := CaseConfig{ID: "a", Cost: 100, Prizes: []CasePrize{{ID: 1, Prob: 0.3333}, {ID: 2, Prob: 0.3333}, {ID: 3, Prob: 0.3334}}}
+	if err := thirds.Validate(); err != nil {
+		t.Fatalf("expected rounded probabilities to pass, got %v", err)
+	}
+}
+
+func TestCaseServiceGet(t *testing.T) {
+	if _, err := (&CaseService{}).Get(""); !errors.Is(err, ErrUnknownCase) {
+		t.Fatalf("expected ErrUnknownCase without active cases, got %v", err)
+	}
+
+	s := &CaseService{cases: []CaseConfig{classicCase(), {ID: "big", Cost: 1000}}}
+
+	if c, err := s.Get(""); err != nil || c.ID != "classic" {
+		t.Fatalf("expected first case for empty id, got %q (%v)", c.ID, err)
+	}
+	if c, err := s.Get("big"); err != nil || c.Cost != 1000 {
+		t.Fatalf("expected big case, got %+v (%v)", c, err)
+	}
+	if _, err := s.Get("missing"); !errors.Is(err, ErrUnknownCase) {
+		t.Fatalf("expected ErrUnknownCase, got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Integration-style test: runs only if TEST_DATABASE_URL env is set.
+func TestCaseSpinConcurrentNeverOverdraws(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set; skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	db, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+
+	u := &domain.User{TgID: time.Now().UnixNano(), Username: "case_spin_test"}
+	if err := repository.NewUserRepository(db).Create(ctx, u); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	defer db.Exec(context.Background(), `DELETE FROM users WHERE id = $1`, u.ID)
+
+	// Пустой приз, чтобы выигрыш не пополнял баланс: хватает ровно на 5 спинов
+	s := NewGameService(db)
+	cfg := CaseConfig{ID: "test", Cost: 100, Prizes: []CasePrize{{ID: 1, Amount: 0, Prob: 1}}}
+	if _, err := db.Exec(ctx, `UPDATE users SET gems = 500 WHERE id = $1`, u.ID); err != nil {
+		t.Fatalf("set balance: %v", err)
+	}
+
+	const spins = 20
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+		rejected  int
+	)
+	for i := 0; i < spins; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := s.PlayCaseSpin(ctx, u.ID, cfg, 0)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				succeeded++
+			case errors.Is(err, ErrInsufficientBalance):
+				rejected++
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 5 || rejected != spins-5 {
+		t.Fatalf("expected 5 spins to succeed and %d to be rejected, got %d/%d", spins-5, succeeded, rejected)
+	}
+
+	var gems int64
+	if err := db.QueryRow(ctx, `SELECT gems FROM users WHERE id = $1`, u.ID).Scan(&gems); err != nil {
+		t.Fatalf("load balance: %v", err)
+	}
+	if gems != 0 {
+		t.Fatalf("expected balance 0 after 5 spins, got %d", gems)
+	}
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"errors"
+	"math"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/game"
+)
+
+// ErrCoinsNotAccepted - игра в среднем возвращает не меньше ставки: coins выводятся
+// в TON, поэтому на них можно играть только там, где у казино есть преимущество
+var ErrCoinsNotAccepted = errors.New("coins are not accepted in this game")
+
+// rtpEpsilon - запас на погрешность float: RTP ровно 1 считается неприемлемым
+const rtpEpsilon = 1e-9
+
+// CoinsAllowed reports whether coin bets are allowed in a game whose best
+// expected return per unit bet is rtp
+func CoinsAllowed(rtp float64) bool {
+	return rtp < 1-rtpEpsilon
+}
+
+// MaxRTP returns the best expected return over every mines count a request may pick
+func (m MinesConfig) MaxRTP() float64 {
+	best := 0.0
+	for mines := 1; mines < m.Cells; mines++ {
+		board, err := m.WithMines(mines)
+		if err != nil {
+			continue
+		}
+		best = math.Max(best, board.RTP())
+	}
+	return best
+}
+
+// ExpectedReturn returns the best expected return per unit bet a player can
+// choose in gt with the current payouts, happy hours included. ok is false for
+// games whose payouts GameService doesn't hold: the wheel table is in the
+// database and RPS Pro has RPSProService.ExpectedReturn.
+func (s *GameService) ExpectedReturn(gt domain.GameType) (rtp float64, ok bool) {
+	switch gt {
+	case domain.GameTypeCoinflip:
+		rtp = 0.5 * s.payouts.CoinFlipMultiplier()
+	case domain.GameTypeRPS:
+		rtp = s.payouts.RPSMultiplier() / 3
+	case domain.GameTypeMines:
+		rtp = s.mines.MaxRTP()
+	case domain.GameTypeDice:
+		rtp = game.DiceMaxRTP()
+	case domain.GameTypeMinesPro:
+		rtp = game.MinesProMaxRTP()
+	default:
+		return 0, false
+	}
+	if s.happy.HasWindow(string(gt)) {
+		rtp = math.Max(rtp, s.happy.MaxRTP())
+	}
+	return rtp, true
+}
+
+// checkCoinsAccepted refuses coin bets in games without a house edge
+func (s *GameService) checkCoinsAccepted(gt domain.GameType, currency domain.Currency) error {
+	if currency != domain.CurrencyCoins {
+		return nil
+	}
+	if rtp, ok := s.ExpectedReturn(gt); ok && !CoinsAllowed(rtp) {
+		return ErrCoinsNotAccepted
+	}
+	return nil
+}
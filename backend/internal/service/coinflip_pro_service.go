@@ -2,7 +2,6 @@ package service
 
 import (
 	"context"
-	"errors"
 	"sync"
 	"time"
 
@@ -17,6 +16,8 @@ import (
 type CoinFlipProService struct {
 	db          *pgxpool.Pool
 	activeGames map[int64]*game.CoinFlipProGame // userID -> game
+	fair        *FairRNG
+	fairHolds   map[int64]func() // userID -> release сида активной игры
 	mu          sync.RWMutex
 }
 
@@ -25,6 +26,7 @@ func NewCoinFlipProService(db *pgxpool.Pool) *CoinFlipProService {
 	s := &CoinFlipProService{
 		db:          db,
 		activeGames: make(map[int64]*game.CoinFlipProGame),
+		fairHolds:   make(map[int64]func()),
 	}
 
 	// Start cleanup goroutine for expired games
@@ -33,6 +35,12 @@ func NewCoinFlipProService(db *pgxpool.Pool) *CoinFlipProService {
 	return s
 }
 
+// SetFairRNG makes flips provably fair when fr is enabled: the whole game is
+// one nonce and every flip is the next draw
+func (s *CoinFlipProService) SetFairRNG(fr *FairRNG) {
+	s.fair = fr
+}
+
 // StartGame starts a new CoinFlip Pro game
 func (s *CoinFlipProService) StartGame(ctx context.Context, userID int64, bet int64) (*game.CoinFlipProGame, error) {
 	s.mu.Lock()
@@ -40,7 +48,7 @@ func (s *CoinFlipProService) StartGame(ctx context.Context, userID int64, bet in
 
 	// Check if user already has an active game
 	if existing, ok := s.activeGames[userID]; ok && existing.IsActive() {
-		return nil, errors.New("you already have an active game")
+		return nil, ErrActiveGame
 	}
 
 	// Start transaction
@@ -56,7 +64,7 @@ func (s *CoinFlipProService) StartGame(ctx context.Context, userID int64, bet in
 		return nil, err
 	}
 	if balance < bet {
-		return nil, errors.New("insufficient balance")
+		return nil, ErrInsufficientBalance
 	}
 
 	if _, err := tx.Exec(ctx, `UPDATE users SET gems = gems - $1 WHERE id=$2`, bet, userID); err != nil {
@@ -65,16 +73,21 @@ func (s *CoinFlipProService) StartGame(ctx context.Context, userID int64, bet in
 
 	// Create game
 	gameID := uuid.New().String()[:8]
-	g, err := game.NewCoinFlipProGame(gameID, userID, bet)
+	rng, fair, release := s.fair.BeginHeld(userID)
+	g, err := game.NewCoinFlipProGame(gameID, userID, bet, rng)
 	if err != nil {
+		release()
 		return nil, err
 	}
+	g.Fair = fair
 
 	if err := tx.Commit(ctx); err != nil {
+		release()
 		return nil, err
 	}
 
 	s.activeGames[userID] = g
+	s.fairHolds[userID] = release
 	return g, nil
 }
 
@@ -96,7 +109,7 @@ func (s *CoinFlipProService) Flip(ctx context.Context, userID int64) (win bool,
 	g, ok := s.activeGames[userID]
 	if !ok || !g.IsActive() {
 		s.mu.Unlock()
-		return false, nil, errors.New("no active game")
+		return false, nil, ErrNoActiveGame
 	}
 	s.mu.Unlock()
 
@@ -108,7 +121,7 @@ func (s *CoinFlipProService) Flip(ctx context.Context, userID int64) (win bool,
 	// If game is over, clean up and credit winnings if won
 	if !g.IsActive() {
 		s.mu.Lock()
-		delete(s.activeGames, userID)
+		s.removeGameLocked(userID)
 		s.mu.Unlock()
 
 		// If won (auto-cashed out at max rounds), credit winnings
@@ -126,7 +139,7 @@ func (s *CoinFlipProService) CashOut(ctx context.Context, userID int64) (*game.C
 	g, ok := s.activeGames[userID]
 	if !ok || !g.IsActive() {
 		s.mu.Unlock()
-		return nil, errors.New("no active game")
+		return nil, ErrNoActiveGame
 	}
 	s.mu.Unlock()
 
@@ -142,12 +155,21 @@ func (s *CoinFlipProService) CashOut(ctx context.Context, userID int64) (*game.C
 
 	// Clean up
 	s.mu.Lock()
-	delete(s.activeGames, userID)
+	s.removeGameLocked(userID)
 	s.mu.Unlock()
 
 	return g, nil
 }
 
+// removeGameLocked forgets the user's game and frees its fair seed - caller must hold s.mu
+func (s *CoinFlipProService) removeGameLocked(userID int64) {
+	delete(s.activeGames, userID)
+	if release, ok := s.fairHolds[userID]; ok {
+		release()
+		delete(s.fairHolds, userID)
+	}
+}
+
 // cleanupExpiredGames removes games older than 1 hour
 func (s *CoinFlipProService) cleanupExpiredGames() {
 	ticker := time.NewTicker(5 * time.Minute)
@@ -158,7 +180,7 @@ func (s *CoinFlipProService) cleanupExpiredGames() {
 		now := time.Now()
 		for userID, g := range s.activeGames {
 			if now.Sub(g.CreatedAt) > time.Hour {
-				delete(s.activeGames, userID)
+				s.removeGameLocked(userID)
 			}
 		}
 		s.mu.Unlock()
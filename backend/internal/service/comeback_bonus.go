@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"telegram_webapp/internal/logger"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Значения по умолчанию для бонуса за возвращение
+const (
+	DefaultComebackInactivity = 14 * 24 * time.Hour
+	DefaultComebackBonusGems  = 1000
+)
+
+// ComebackBonusService grants a one-time bonus to users returning after a lapse.
+// Login time is updated on every call, so the next lapse makes the user eligible again.
+type ComebackBonusService struct {
+	db         *pgxpool.Pool
+	balance    *BalanceService
+	inactivity time.Duration
+	amount     int64 // гемов, 0 - выключено
+}
+
+// NewComebackBonusService creates the service
+func NewComebackBonusService(db *pgxpool.Pool, inactivity time.Duration, amount int64) *ComebackBonusService {
+	return &ComebackBonusService{
+		db:         db,
+		balance:    NewBalanceService(db),
+		inactivity: inactivity,
+		amount:     amount,
+	}
+}
+
+// OnLogin records the login and credits the bonus if the user was inactive long enough.
+// Returns granted gems (0 if not eligible).
+func (s *ComebackBonusService) OnLogin(ctx context.Context, userID int64) (int64, error) {
+	if s == nil {
+		return 0, nil
+	}
+
+	// Row lock serializes parallel /auth calls - only the first one sees the old login time
+	var prevLogin time.Time
+	var lastGame *time.Time
+	err := s.db.QueryRow(ctx, `
+		WITH prev AS (
+			SELECT id, last_login_at FROM users WHERE id = $1 FOR UPDATE
+		)
+		UPDATE users u SET last_login_at = now()
+		FROM prev
+		WHERE u.id = prev.id
+		RETURNING prev.last_login_at,
+		          (SELECT MAX(created_at) FROM game_history WHERE user_id = $1)
+	`, userID).Scan(&prevLogin, &lastGame)
+	if err != nil {
+		return 0, err
+	}
+
+	if s.amount <= 0 || s.inactivity <= 0 {
+		return 0, nil
+	}
+
+	lastActive := prevLogin
+	if lastGame != nil && lastGame.After(lastActive) {
+		lastActive = *lastGame
+	}
+	if time.Since(lastActive) < s.inactivity {
+		return 0, nil
+	}
+
+	meta := map[string]interface{}{
+		"last_active_at": lastActive,
+		"inactive_days":  int(time.Since(lastActive).Hours() / 24),
+	}
+	if _, err := s.balance.Credit(ctx, userID, s.amount, "comeback_bonus", meta); err != nil {
+		return 0, err
+	}
+
+	logger.Info("comeback bonus granted", "user_id", userID, "gems", s.amount, "last_active_at", lastActive)
+	return s.amount, nil
+}
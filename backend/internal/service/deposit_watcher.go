@@ -0,0 +1,331 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/logger"
+	"telegram_webapp/internal/repository"
+	"telegram_webapp/internal/ton"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DepositWatcherConfig holds polling settings for the deposit watcher
+type DepositWatcherConfig struct {
+	PollInterval time.Duration
+	PageSize     int // transactions per GetTransactions call and per credit batch
+	Concurrency  int // deposits credited in parallel within a batch
+	MaxPages     int // safety cap on pages fetched per poll
+	CoinsPerTON  int // курс, который сохраняется в депозите; 0 - ton.CoinsPerTON
+}
+
+// DefaultDepositWatcherConfig returns built-in watcher settings
+func DefaultDepositWatcherConfig() DepositWatcherConfig {
+	return DepositWatcherConfig{
+		PollInterval: 30 * time.Second,
+		PageSize:     50,
+		Concurrency:  4,
+		MaxPages:     20,
+		CoinsPerTON:  ton.CoinsPerTON,
+	}
+}
+
+// depositMemoPrefix - memo формата deposit_<user_id>, см. GetDepositInfo
+const depositMemoPrefix = "deposit_"
+
+// DepositWatcher polls incoming transactions of the platform wallet and
+// credits deposits. Crediting is idempotent by tx_hash, the highest
+// processed lt is persisted so restarts resume where they stopped.
+type DepositWatcher struct {
+	client  *ton.Client
+	deposit *repository.DepositRepository
+	address string
+	cfg     DepositWatcherConfig
+
+	// OnCredited is called after a new deposit is credited to the user
+	OnCredited func(ctx context.Context, userID int64)
+
+	// Незаконченный проход по бэклогу длиннее MaxPages страниц: следующий Poll
+	// продолжает листать с sweepBefore вниз, пока не дойдёт до сохранённого lt,
+	// и только тогда сохраняет sweepTop. В памяти: после рестарта проход
+	// начинается заново, зачисление идемпотентно по tx_hash.
+	pollMu      sync.Mutex
+	sweepBefore int64
+	sweepTop    int64
+
+	stopCh chan struct{}
+	once   sync.Once
+}
+
+// NewDepositWatcher creates a watcher for the platform wallet address
+func NewDepositWatcher(db *pgxpool.Pool, client *ton.Client, address string, cfg DepositWatcherConfig) *DepositWatcher {
+	def := DefaultDepositWatcherConfig()
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = def.PollInterval
+	}
+	if cfg.PageSize <= 0 {
+		cfg.PageSize = def.PageSize
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = def.Concurrency
+	}
+	if cfg.MaxPages <= 0 {
+		cfg.MaxPages = def.MaxPages
+	}
+	if cfg.CoinsPerTON <= 0 {
+		cfg.CoinsPerTON = def.CoinsPerTON
+	}
+
+	return &DepositWatcher{
+		client:  client,
+		deposit: repository.NewDepositRepository(db),
+		address: address,
+		cfg:     cfg,
+		stopCh:  make(chan struct{}),
+	}
+}
+
+// Start polls on the configured interval until Stop is called
+func (w *DepositWatcher) Start() {
+	go func() {
+		ticker := time.NewTicker(w.cfg.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := w.Poll(context.Background()); err != nil {
+				logger.Error("deposit watcher poll failed", "error", err)
+			}
+
+			select {
+			case <-w.stopCh:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops polling
+func (w *DepositWatcher) Stop() {
+	w.once.Do(func() { close(w.stopCh) })
+}
+
+// Poll fetches all transactions newer than the saved lt and credits them in
+// batches. A backlog longer than MaxPages pages is worked through over several
+// polls, oldest pages last: the saved lt only moves once every transaction
+// above it has been fetched, so nothing in between is skipped.
+func (w *DepositWatcher) Poll(ctx context.Context) error {
+	w.pollMu.Lock()
+	defer w.pollMu.Unlock()
+
+	lastLt, err := w.deposit.GetWatcherLt(ctx, w.address)
+	if err != nil {
+		return err
+	}
+
+	txs, complete, err := w.fetchNew(ctx, lastLt, w.sweepBefore)
+	if err != nil {
+		return err
+	}
+	if lastLt == 0 {
+		// Первый запуск: всю историю кошелька не листаем, начинаем с последних страниц
+		complete = true
+	}
+	if len(txs) == 0 && w.sweepTop == 0 {
+		return nil
+	}
+
+	// Oldest first, so the saved lt only advances over a processed prefix
+	sort.Slice(txs, func(i, j int) bool { return txs[i].Lt < txs[j].Lt })
+
+	for start := 0; start < len(txs); start += w.cfg.PageSize {
+		end := start + w.cfg.PageSize
+		if end > len(txs) {
+			end = len(txs)
+		}
+		batch := txs[start:end]
+
+		failedAt := w.creditBatch(ctx, batch)
+		if failedAt >= 0 {
+			// Keep progress up to the first failure, retry the rest next poll.
+			// Inside an unfinished sweep older transactions are still unfetched,
+			// so the lt can't move yet.
+			if failedAt > 0 && complete {
+				if err := w.deposit.SaveWatcherLt(ctx, w.address, batch[failedAt-1].Lt); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		if complete {
+			if err := w.deposit.SaveWatcherLt(ctx, w.address, batch[len(batch)-1].Lt); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !complete {
+		// Запоминаем, откуда листать дальше, и верх прохода
+		if top := txs[len(txs)-1].Lt; top > w.sweepTop {
+			w.sweepTop = top
+		}
+		w.sweepBefore = txs[0].Lt
+		logger.Warn("deposit backlog exceeds one poll, continuing next poll", "before_lt", w.sweepBefore, "saved_lt", lastLt)
+		return nil
+	}
+
+	if w.sweepTop > 0 {
+		// Проход дошёл до сохранённого lt: всё до его верха обработано
+		if err := w.deposit.SaveWatcherLt(ctx, w.address, w.sweepTop); err != nil {
+			return err
+		}
+		w.sweepBefore, w.sweepTop = 0, 0
+	}
+	return nil
+}
+
+// fetchNew pages backwards with before_lt (starting below beforeLt, 0 - from the
+// newest) until it reaches lastLt. complete is false when MaxPages ran out first:
+// transactions between lastLt and the oldest returned one are not fetched yet.
+func (w *DepositWatcher) fetchNew(ctx context.Context, lastLt, beforeLt int64) ([]ton.Transaction, bool, error) {
+	var result []ton.Transaction
+
+	for page := 0; page < w.cfg.MaxPages; page++ {
+		txs, err := w.client.GetTransactions(ctx, w.address, w.cfg.PageSize, beforeLt)
+		if err != nil {
+			return nil, false, err
+		}
+		if len(txs) == 0 {
+			return result, true, nil
+		}
+
+		reachedSeen := false
+		for _, tx := range txs {
+			if tx.Lt <= lastLt {
+				reachedSeen = true
+				continue
+			}
+			result = append(result, tx)
+		}
+
+		oldest := txs[len(txs)-1].Lt
+		if reachedSeen || len(txs) < w.cfg.PageSize || (beforeLt != 0 && oldest >= beforeLt) {
+			return result, true, nil
+		}
+		beforeLt = oldest
+	}
+
+	return result, false, nil
+}
+
+// creditBatch credits a batch with bounded concurrency.
+// Returns index of the first failed transaction or -1 if all succeeded.
+func (w *DepositWatcher) creditBatch(ctx context.Context, batch []ton.Transaction) int {
+	errs := make([]error, len(batch))
+	sem := make(chan struct{}, w.cfg.Concurrency)
+	var wg sync.WaitGroup
+
+	for i := range batch {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = w.credit(ctx, &batch[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			logger.Error("deposit credit failed", "tx_hash", batch[i].Hash, "lt", batch[i].Lt, "error", err)
+			return i
+		}
+	}
+	return -1
+}
+
+// credit records one incoming transaction; unrelated transactions are skipped
+func (w *DepositWatcher) credit(ctx context.Context, tx *ton.Transaction) error {
+	if tx.InMsg == nil || tx.InMsg.Value <= 0 || !sameAddress(tx.InMsg.Destination, w.address) {
+		return nil
+	}
+
+	memo := ton.ExtractMemo(tx)
+	userID, ok := parseDepositMemo(memo)
+	if !ok {
+		logger.Warn("deposit without user memo skipped", "tx_hash", tx.Hash, "memo", memo)
+		return nil
+	}
+	if tx.InMsg.Value < ton.MinDepositNano {
+		logger.Warn("deposit below minimum skipped", "tx_hash", tx.Hash, "amount_nano", tx.InMsg.Value)
+		return nil
+	}
+
+	// Уже зачисленные (например, после отката lt) не гоняем через транзакцию
+	exists, err := w.deposit.TxHashExists(ctx, tx.Hash)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	d := &domain.Deposit{
+		UserID:        userID,
+		WalletAddress: tx.InMsg.Source,
+		AmountNano:    tx.InMsg.Value,
+		ExchangeRate:  w.cfg.CoinsPerTON, // монеты CreditDeposit считает по этому курсу
+		TxHash:        tx.Hash,
+		TxLt:          tx.Lt,
+		Memo:          memo,
+	}
+
+	credited, err := w.deposit.CreditDeposit(ctx, d)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		// memo points to a user that doesn't exist - retrying won't help
+		logger.Warn("deposit for unknown user skipped", "tx_hash", tx.Hash, "user_id", userID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if credited {
+		logger.Info("deposit credited", "user_id", userID, "coins", d.CoinsCredited, "tx_hash", tx.Hash)
+		if w.OnCredited != nil {
+			w.OnCredited(ctx, userID)
+		}
+	}
+	return nil
+}
+
+func parseDepositMemo(memo string) (int64, bool) {
+	if !strings.HasPrefix(memo, depositMemoPrefix) {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(memo, depositMemoPrefix), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// sameAddress compares TON addresses in any form (raw, bounceable or not)
+func sameAddress(a, b string) bool {
+	if a == b {
+		return true
+	}
+	na, errA := ton.NormalizeAddress(a)
+	nb, errB := ton.NormalizeAddress(b)
+	return errA == nil && errB == nil && na == nb
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"telegram_webapp/internal/game"
+)
+
+var (
+	// ErrFairRNGDisabled - provably fair выключен конфигом
+	ErrFairRNGDisabled = errors.New("fair rng is disabled")
+	// ErrFairSeedInUse - на текущем сиде идёт многораундовая игра, раскрывать его нельзя
+	ErrFairSeedInUse = errors.New("finish the active game before rotating the seed")
+)
+
+const (
+	// DefaultFairSeedIdleTTL - сид пользователя без игр дольше этого раскрывается и выгружается
+	DefaultFairSeedIdleTTL = 24 * time.Hour
+	// DefaultFairRevealTTL - сколько хранится последний раскрытый сид
+	DefaultFairRevealTTL = 7 * 24 * time.Hour
+)
+
+// FairSeed is the public side of the user's current seed pair, published
+// before the games played on it
+type FairSeed struct {
+	Commitment string `json:"commitment"`  // sha256(server_seed), hex
+	ClientSeed string `json:"client_seed"` // seed mixed into every draw
+	NextNonce  uint64 `json:"next_nonce"`  // nonce of the next game
+}
+
+// RevealedFairSeed is a retired seed pair: with ServerSeed every game played
+// on it (nonces 1..LastNonce) can be recomputed with game.FairRandomizer
+type RevealedFairSeed struct {
+	ServerSeed string    `json:"server_seed"` // hex
+	Commitment string    `json:"commitment"`
+	ClientSeed string    `json:"client_seed"`
+	LastNonce  uint64    `json:"last_nonce"`
+	RevealedAt time.Time `json:"revealed_at"`
+}
+
+type fairSeed struct {
+	serverSeed []byte
+	commitment string
+	clientSeed string
+	nonce      uint64
+}
+
+type fairUser struct {
+	current  *fairSeed // nil после выгрузки, создаётся при следующем обращении
+	previous *RevealedFairSeed
+	held     int // многораундовые игры на текущем сиде
+	lastUsed time.Time
+}
+
+// FairRNG decides game outcomes with commit-reveal seeds: each game gets the
+// next nonce of the user's seed pair and draws from game.FairRandomizer. The
+// server seed stays in memory and only its hash is shown until it is rotated
+// (by the player or after DefaultFairSeedIdleTTL without games).
+type FairRNG struct {
+	enabled   bool
+	idleTTL   time.Duration
+	revealTTL time.Duration
+	now       func() time.Time
+
+	mu    sync.Mutex
+	users map[int64]*fairUser
+}
+
+// NewFairRNG creates a fair RNG; when disabled games use the regular randomizer
+func NewFairRNG(enabled bool) *FairRNG {
+	f := &FairRNG{
+		enabled:   enabled,
+		idleTTL:   DefaultFairSeedIdleTTL,
+		revealTTL: DefaultFairRevealTTL,
+		now:       time.Now,
+		users:     make(map[int64]*fairUser),
+	}
+	if enabled {
+		go f.evictIdleUsers()
+	}
+	return f
+}
+
+// Enabled reports whether fair RNG is turned on
+func (f *FairRNG) Enabled() bool {
+	return f != nil && f.enabled
+}
+
+// Begin starts a single-round game on the user's seed pair. Returns nil, nil
+// when fair RNG is disabled.
+func (f *FairRNG) Begin(userID int64) (game.Randomizer, *game.FairProof) {
+	rng, proof, _ := f.begin(userID, false)
+	if rng == nil {
+		return nil, nil
+	}
+	return rng, proof
+}
+
+// BeginHeld starts a multi-round game: the seed can't be revealed until release
+// is called when the game is over. release is safe to call more than once.
+func (f *FairRNG) BeginHeld(userID int64) (game.Randomizer, *game.FairProof, func()) {
+	rng, proof, release := f.begin(userID, true)
+	if rng == nil {
+		return nil, nil, func() {}
+	}
+	return rng, proof, release
+}
+
+func (f *FairRNG) begin(userID int64, hold bool) (*game.FairRandomizer, *game.FairProof, func()) {
+	if !f.Enabled() {
+		return nil, nil, nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	u := f.userLocked(userID)
+	seed := u.current
+	seed.nonce++
+
+	release := func() {}
+	if hold {
+		u.held++
+		var once sync.Once
+		release = func() {
+			once.Do(func() {
+				f.mu.Lock()
+				defer f.mu.Unlock()
+				u.held--
+				u.lastUsed = f.now()
+			})
+		}
+	}
+
+	return game.NewFairRandomizer(seed.serverSeed, seed.clientSeed, seed.nonce), &game.FairProof{
+		Commitment: seed.commitment,
+		ClientSeed: seed.clientSeed,
+		Nonce:      seed.nonce,
+	}, release
+}
+
+// Seed returns the current seed pair (creating it if needed) and the last
+// revealed one, nil if there is none
+func (f *FairRNG) Seed(userID int64) (FairSeed, *RevealedFairSeed, error) {
+	if !f.Enabled() {
+		return FairSeed{}, nil, ErrFairRNGDisabled
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	u := f.userLocked(userID)
+	return u.current.public(), u.previous, nil
+}
+
+// Rotate reveals the current server seed and starts a new pair with clientSeed
+// (random if empty). Refused while a multi-round game holds the seed.
+func (f *FairRNG) Rotate(userID int64, clientSeed string) (*RevealedFairSeed, FairSeed, error) {
+	if !f.Enabled() {
+		return nil, FairSeed{}, ErrFairRNGDisabled
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	u := f.userLocked(userID)
+	if u.held > 0 {
+		return nil, FairSeed{}, ErrFairSeedInUse
+	}
+	u.previous = u.current.reveal(f.now())
+	u.current = newFairSeed(clientSeed)
+	return u.previous, u.current.public(), nil
+}
+
+// userLocked - caller must hold f.mu
+func (f *FairRNG) userLocked(userID int64) *fairUser {
+	u, ok := f.users[userID]
+	if !ok {
+		u = &fairUser{}
+		f.users[userID] = u
+	}
+	if u.current == nil {
+		u.current = newFairSeed("")
+	}
+	u.lastUsed = f.now()
+	return u
+}
+
+// evictIdleUsers periodically unloads seeds of users without games
+func (f *FairRNG) evictIdleUsers() {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		f.evictIdle(f.now())
+	}
+}
+
+// evictIdle reveals seeds unused for idleTTL and forgets users whose revealed
+// seed is older than revealTTL. Returns how many users were removed.
+func (f *FairRNG) evictIdle(now time.Time) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	removed := 0
+	for userID, u := range f.users {
+		if u.held > 0 || now.Sub(u.lastUsed) < f.idleTTL {
+			continue
+		}
+		if u.current != nil && u.current.nonce > 0 {
+			u.previous = u.current.reveal(now)
+		}
+		u.current = nil
+		if u.previous == nil || now.Sub(u.previous.RevealedAt) >= f.revealTTL {
+			delete(f.users, userID)
+			removed++
+		}
+	}
+	return removed
+}
+
+func (s *fairSeed) public() FairSeed {
+	return FairSeed{Commitment: s.commitment, ClientSeed: s.clientSeed, NextNonce: s.nonce + 1}
+}
+
+func (s *fairSeed) reveal(at time.Time) *RevealedFairSeed {
+	return &RevealedFairSeed{
+		ServerSeed: hex.EncodeToString(s.serverSeed),
+		Commitment: s.commitment,
+		ClientSeed: s.clientSeed,
+		LastNonce:  s.nonce,
+		RevealedAt: at,
+	}
+}
+
+func newFairSeed(clientSeed string) *fairSeed {
+	server := make([]byte, 32)
+	_, _ = rand.Read(server)
+	if clientSeed == "" {
+		client := make([]byte, 8)
+		_, _ = rand.Read(client)
+		clientSeed = hex.EncodeToString(client)
+	}
+
+	hash := sha256.Sum256(server)
+	return &fairSeed{
+		serverSeed: server,
+		commitment: hex.EncodeToString(hash[:]),
+		clientSeed: clientSeed,
+	}
+}
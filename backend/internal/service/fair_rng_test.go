@@ -0,0 +1,99 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"telegram_webapp/internal/game"
+)
+
+func TestFairRNGRevealReproducesGames(t *testing.T) {
+	f := NewFairRNG(true)
+
+	published, _, err := f.Seed(1)
+	if err != nil || published.NextNonce != 1 {
+		t.Fatalf("expected a fresh seed, got %+v err=%v", published, err)
+	}
+
+	var draws []float64
+	for i := 0; i < 3; i++ {
+		rng, proof := f.Begin(1)
+		if proof.Commitment != published.Commitment || proof.Nonce != uint64(i+1) {
+			t.Fatalf("game %d: unexpected proof %+v", i, proof)
+		}
+		draws = append(draws, rng.Float64())
+	}
+
+	revealed, next, err := f.Rotate(1, "my-seed")
+	if err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	if next.Commitment == published.Commitment || next.ClientSeed != "my-seed" || next.NextNonce != 1 {
+		t.Fatalf("expected a new seed pair, got %+v", next)
+	}
+
+	server, _ := hex.DecodeString(revealed.ServerSeed)
+	if sum := sha256.Sum256(server); hex.EncodeToString(sum[:]) != published.Commitment {
+		t.Fatal("revealed seed must match the published commitment")
+	}
+	for i, want := range draws {
+		if got := game.NewFairRandomizer(server, revealed.ClientSeed, uint64(i+1)).Float64(); got != want {
+			t.Fatalf("game %d: revealed seed gives %v, game used %v", i+1, got, want)
+		}
+	}
+}
+
+func TestFairRNGHeldSeedIsNotRevealed(t *testing.T) {
+	f := NewFairRNG(true)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	f.now = func() time.Time { return now }
+
+	_, _, release := f.BeginHeld(1)
+	if _, _, err := f.Rotate(1, ""); !errors.Is(err, ErrFairSeedInUse) {
+		t.Fatalf("expected ErrFairSeedInUse, got %v", err)
+	}
+	if f.evictIdle(now.Add(2*DefaultFairSeedIdleTTL)) != 0 || f.users[1].previous != nil {
+		t.Fatal("seed of an unfinished game must not be revealed by eviction")
+	}
+
+	release()
+	release()
+	if _, _, err := f.Rotate(1, ""); err != nil {
+		t.Fatalf("rotate after the game: %v", err)
+	}
+}
+
+func TestFairRNGEvictsIdleUsers(t *testing.T) {
+	f := NewFairRNG(true)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	f.now = func() time.Time { return now }
+
+	f.Begin(1)
+	f.Seed(2) // сид без игр раскрывать нечего
+
+	later := now.Add(DefaultFairSeedIdleTTL)
+	if removed := f.evictIdle(later); removed != 1 {
+		t.Fatalf("expected the unused seed to be dropped, removed %d", removed)
+	}
+	u := f.users[1]
+	if u == nil || u.current != nil || u.previous == nil || u.previous.LastNonce != 1 {
+		t.Fatalf("idle seed must be revealed and unloaded, got %+v", u)
+	}
+
+	if removed := f.evictIdle(later.Add(DefaultFairRevealTTL)); removed != 1 || len(f.users) != 0 {
+		t.Fatalf("old revealed seed must be forgotten, removed %d, left %d", removed, len(f.users))
+	}
+}
+
+func TestFairRNGDisabled(t *testing.T) {
+	f := NewFairRNG(false)
+	if rng, proof := f.Begin(1); rng != nil || proof != nil {
+		t.Fatal("disabled fair RNG must not issue rounds")
+	}
+	if _, _, err := f.Seed(1); !errors.Is(err, ErrFairRNGDisabled) {
+		t.Fatalf("expected ErrFairRNGDisabled, got %v", err)
+	}
+}
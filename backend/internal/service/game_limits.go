@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"telegram_webapp/internal/domain"
+)
+
+// LimitGames - игры с отдельными лимитами ставок (у кейса фиксированная цена)
+var LimitGames = []domain.GameType{
+	domain.GameTypeCoinflip, domain.GameTypeRPS, domain.GameTypeMines, domain.GameTypeMinesPro,
+	domain.GameTypeDice, domain.GameTypeWheel, domain.GameTypeRPSPro,
+}
+
+var (
+	ErrInvalidLimits    = errors.New("min and max bet must be positive and min <= max")
+	ErrUnknownLimitGame = errors.New("bet limits can't be set for this game")
+)
+
+// ValidateGameLimit checks a per-game override before it is saved
+func ValidateGameLimit(l *domain.GameLimit) error {
+	if !slices.Contains(LimitGames, l.GameType) {
+		return ErrUnknownLimitGame
+	}
+	if l.Currency != domain.CurrencyGems && l.Currency != domain.CurrencyCoins {
+		return ErrInvalidCurrency
+	}
+	if l.MinBet <= 0 || l.MaxBet <= 0 || l.MinBet > l.MaxBet {
+		return ErrInvalidLimits
+	}
+	return nil
+}
+
+// withDefaults fills unset coin limits with the built-in ones
+func (l GameLimits) withDefaults() GameLimits {
+	defaults := DefaultGameLimits()
+	if l.MinBetCoins <= 0 {
+		l.MinBetCoins = defaults.MinBetCoins
+	}
+	if l.MaxBetCoins <= 0 {
+		l.MaxBetCoins = defaults.MaxBetCoins
+	}
+	return l
+}
+
+// check validates a bet against the limits of its currency
+func (l GameLimits) check(bet int64, currency domain.Currency) error {
+	minBet, maxBet := l.MinBet, l.MaxBet
+	if currency == domain.CurrencyCoins {
+		minBet, maxBet = l.MinBetCoins, l.MaxBetCoins
+	}
+	if bet <= 0 {
+		return ErrInvalidBet
+	}
+	if bet < minBet {
+		return ErrBetTooLow
+	}
+	if bet > maxBet {
+		return ErrBetTooHigh
+	}
+	return nil
+}
+
+// MergeGameLimits returns the limits of every game in LimitGames: the global
+// limits with the game's overrides applied on top
+func MergeGameLimits(global GameLimits, overrides []*domain.GameLimit) map[domain.GameType]GameLimits {
+	out := make(map[domain.GameType]GameLimits, len(LimitGames))
+	for _, gt := range LimitGames {
+		out[gt] = global
+	}
+	for _, o := range overrides {
+		l, ok := out[o.GameType]
+		if !ok {
+			continue
+		}
+		switch o.Currency {
+		case domain.CurrencyGems:
+			l.MinBet, l.MaxBet = o.MinBet, o.MaxBet
+		case domain.CurrencyCoins:
+			l.MinBetCoins, l.MaxBetCoins = o.MinBet, o.MaxBet
+		}
+		out[o.GameType] = l
+	}
+	return out
+}
+
+// ReloadLimits reloads per-game overrides from the game_limits table
+func (s *GameService) ReloadLimits(ctx context.Context) error {
+	overrides, err := s.limitsRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+	merged := MergeGameLimits(s.limits, overrides)
+
+	s.gameLimitsMu.Lock()
+	s.gameLimits = merged
+	s.gameLimitsMu.Unlock()
+	return nil
+}
+
+// LimitsFor returns the bet limits of a game (global limits until overrides are loaded)
+func (s *GameService) LimitsFor(gt domain.GameType) GameLimits {
+	s.gameLimitsMu.RLock()
+	defer s.gameLimitsMu.RUnlock()
+	if l, ok := s.gameLimits[gt]; ok {
+		return l
+	}
+	return s.limits
+}
+
+// GameLimitsByGame returns the effective bet limits of every game in LimitGames
+func (s *GameService) GameLimitsByGame() map[domain.GameType]GameLimits {
+	out := make(map[domain.GameType]GameLimits, len(LimitGames))
+	for _, gt := range LimitGames {
+		out[gt] = s.LimitsFor(gt)
+	}
+	return out
+}
+
+// ValidateBetForGame checks a bet against the game's limits in the given currency
+// and refuses coins in games without a house edge (see ExpectedReturn)
+func (s *GameService) ValidateBetForGame(gt domain.GameType, bet int64, currency domain.Currency) error {
+	if err := s.checkCoinsAccepted(gt, currency); err != nil {
+		return err
+	}
+	return s.LimitsFor(gt).check(bet, currency)
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/game"
+)
+
+func TestValidateGameLimit(t *testing.T) {
+	cases := []struct {
+		limit domain.GameLimit
+		want  error
+	}{
+		{domain.GameLimit{GameType: domain.GameTypeDice, Currency: domain.CurrencyGems, MinBet: 5, MaxBet: 5}, nil},
+		{domain.GameLimit{GameType: domain.GameTypeCase, Currency: domain.CurrencyGems, MinBet: 1, MaxBet: 10}, ErrUnknownLimitGame},
+		{domain.GameLimit{GameType: domain.GameTypeDice, Currency: domain.CurrencyGK, MinBet: 1, MaxBet: 10}, ErrInvalidCurrency},
+		{domain.GameLimit{GameType: domain.GameTypeDice, Currency: domain.CurrencyCoins, MinBet: 0, MaxBet: 10}, ErrInvalidLimits},
+		{domain.GameLimit{GameType: domain.GameTypeDice, Currency: domain.CurrencyCoins, MinBet: 20, MaxBet: 10}, ErrInvalidLimits},
+	}
+	for _, tc := range cases {
+		if err := ValidateGameLimit(&tc.limit); !errors.Is(err, tc.want) {
+			t.Fatalf("%+v: expected %v, got %v", tc.limit, tc.want, err)
+		}
+	}
+}
+
+func TestValidateBetForGameUsesOverrides(t *testing.T) {
+	s := NewGameServiceWithLimits(nil, GameLimits{MinBet: 10, MaxBet: 1000})
+
+	// До загрузки переопределений действуют общие лимиты
+	if err := s.ValidateBetForGame(domain.GameTypeDice, 5, domain.CurrencyGems); !errors.Is(err, ErrBetTooLow) {
+		t.Fatalf("expected ErrBetTooLow, got %v", err)
+	}
+
+	s.gameLimits = MergeGameLimits(s.limits, []*domain.GameLimit{
+		{GameType: domain.GameTypeDice, Currency: domain.CurrencyGems, MinBet: 1, MaxBet: 50},
+		{GameType: domain.GameTypeWheel, Currency: domain.CurrencyCoins, MinBet: 2, MaxBet: 3},
+	})
+
+	if err := s.ValidateBetForGame(domain.GameTypeDice, 5, domain.CurrencyGems); err != nil {
+		t.Fatalf("expected dice bet 5 to pass, got %v", err)
+	}
+	if err := s.ValidateBetForGame(domain.GameTypeDice, 100, domain.CurrencyGems); !errors.Is(err, ErrBetTooHigh) {
+		t.Fatalf("expected ErrBetTooHigh, got %v", err)
+	}
+	// Другие игры и валюты не затронуты
+	if err := s.ValidateBetForGame(domain.GameTypeCoinflip, 100, domain.CurrencyGems); err != nil {
+		t.Fatalf("expected coinflip bet 100 to pass, got %v", err)
+	}
+	if err := s.ValidateBetForGame(domain.GameTypeWheel, 100, domain.CurrencyGems); err != nil {
+		t.Fatalf("expected wheel gems bet 100 to pass, got %v", err)
+	}
+	if err := s.ValidateBetForGame(domain.GameTypeWheel, 4, domain.CurrencyCoins); !errors.Is(err, ErrBetTooHigh) {
+		t.Fatalf("expected ErrBetTooHigh for wheel coins, got %v", err)
+	}
+	if l := s.LimitsFor(domain.GameTypeCoinflip); l.MinBetCoins != DefaultGameLimits().MinBetCoins {
+		t.Fatalf("expected default coin limits, got %+v", l)
+	}
+}
+
+func TestValidateBetForGameRefusesCoinsWithoutHouseEdge(t *testing.T) {
+	s := NewGameServiceWithLimits(nil, GameLimits{MinBet: 1, MaxBet: 1000, MinBetCoins: 1, MaxBetCoins: 1000})
+
+	// С комиссией 2% coinflip платит 0.98 на ставку - coins принимаются
+	if err := s.ValidateBetForGame(domain.GameTypeCoinflip, 10, domain.CurrencyCoins); err != nil {
+		t.Fatalf("expected coins on coinflip, got %v", err)
+	}
+	// Честные множители Mines Pro возвращают ставку целиком (5 мин, 1 ячейка: 0.8 * 1.25)
+	if err := s.ValidateBetForGame(domain.GameTypeMinesPro, 10, domain.CurrencyCoins); !errors.Is(err, ErrCoinsNotAccepted) {
+		t.Fatalf("expected ErrCoinsNotAccepted on mines_pro, got %v", err)
+	}
+	if err := s.ValidateBetForGame(domain.GameTypeMinesPro, 10, domain.CurrencyGems); err != nil {
+		t.Fatalf("gems must stay accepted, got %v", err)
+	}
+
+	// Без комиссии RTP = 1
+	s.SetPayouts(Payouts{CoinFlip: 2, RPS: 2, HouseEdge: 0})
+	if err := s.ValidateBetForGame(domain.GameTypeCoinflip, 10, domain.CurrencyCoins); !errors.Is(err, ErrCoinsNotAccepted) {
+		t.Fatalf("expected ErrCoinsNotAccepted without house edge, got %v", err)
+	}
+
+	// Happy hour поднимает RTP до потолка, потолок 1 закрывает игру для coins
+	s.SetPayouts(DefaultPayouts())
+	s.SetHappyHours(NewHappyHours([]game.HappyHour{{Game: "rps", Multiplier: 1.5}}, 1))
+	if err := s.ValidateBetForGame(domain.GameTypeRPS, 10, domain.CurrencyCoins); !errors.Is(err, ErrCoinsNotAccepted) {
+		t.Fatalf("expected ErrCoinsNotAccepted with happy hour max RTP 1, got %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// stubRandomizer replays fixed values; Intn/Int63n take them modulo n
+type stubRandomizer struct {
+	floats []float64
+	ints   []int64
+}
+
+func (r *stubRandomizer) Float64() float64 {
+	f := r.floats[0]
+	r.floats = r.floats[1:]
+	return f
+}
+
+func (r *stubRandomizer) Int63n(n int64) int64 {
+	v := r.ints[0]
+	r.ints = r.ints[1:]
+	return v % n
+}
+
+func (r *stubRandomizer) Intn(n int) int {
+	return int(r.Int63n(int64(n)))
+}
+
+// Integration-style test: runs only if TEST_DATABASE_URL env is set.
+func TestGameOutcomesWithStubRandomizer(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set; skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	db, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+
+	u := &domain.User{TgID: time.Now().UnixNano(), Username: "game_outcome_test"}
+	if err := repository.NewUserRepository(db).Create(ctx, u); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	defer db.Exec(context.Background(), `DELETE FROM users WHERE id = $1`, u.ID)
+	if _, err := db.Exec(ctx, `UPDATE users SET gems = 10000 WHERE id = $1`, u.ID); err != nil {
+		t.Fatalf("set balance: %v", err)
+	}
+
+	s := NewGameService(db)
+	const bet = 100
+
+	s.SetRandomizer(&stubRandomizer{floats: []float64{0.1, 0.9}})
+	if res, _, err := s.PlayCoinFlip(ctx, u.ID, bet, domain.CurrencyGems); err != nil || !res.Win {
+		t.Fatalf("coinflip: expected win on roll 0.1, got %+v (%v)", res, err)
+	} else if want := int64(bet * s.payouts.CoinFlipMultiplier()); res.Awarded != want {
+		t.Fatalf("coinflip: expected %d awarded, got %d", want, res.Awarded)
+	}
+	if res, _, err := s.PlayCoinFlip(ctx, u.ID, bet, domain.CurrencyGems); err != nil || res.Win || res.Awarded != 0 {
+		t.Fatalf("coinflip: expected loss on roll 0.9, got %+v (%v)", res, err)
+	}
+
+	// Ходы бота: 0 - rock, 1 - paper, 2 - scissors
+	s.SetRandomizer(&stubRandomizer{ints: []int64{2, 1, 0}})
+	for _, want := range []int{1, -1, 0} {
+		res, _, err := s.PlayRPS(ctx, u.ID, "rock", bet, domain.CurrencyGems)
+		if err != nil || res.Result != want {
+			t.Fatalf("rps: expected result %d, got %+v (%v)", want, res, err)
+		}
+	}
+
+	// Выигрыш на 0.1, затем мины на 2..5 - выбранная ячейка 1 пустая
+	s.SetRandomizer(&stubRandomizer{floats: []float64{0.1}, ints: []int64{1, 2, 3, 4}})
+	res, _, err := s.PlayMines(ctx, u.ID, 1, 0, bet, domain.CurrencyGems)
+	if err != nil || !res.Win || res.Awarded != s.mines.Payout(bet) {
+		t.Fatalf("mines: expected win of %d, got %+v (%v)", s.mines.Payout(bet), res, err)
+	}
+	for cell := 2; cell <= 5; cell++ {
+		if !res.Mines[cell] {
+			t.Fatalf("mines: expected mine on %d, got %v", cell, res.Mines)
+		}
+	}
+
+	cfg := CaseConfig{ID: "test", Cost: 100, Prizes: []CasePrize{{ID: 1, Amount: 10, Prob: 0.9}, {ID: 2, Amount: 500, Prob: 0.1}}}
+	s.SetRandomizer(&stubRandomizer{floats: []float64{0.95}})
+	if spin, _, err := s.PlayCaseSpin(ctx, u.ID, cfg, 0); err != nil || spin.PrizeID != 2 || spin.Prize != 500 {
+		t.Fatalf("case: expected prize 2, got %+v (%v)", spin, err)
+	}
+}
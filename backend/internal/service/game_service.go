@@ -3,10 +3,13 @@ package service
 import (
 	"context"
 	"errors"
-	"math/rand"
+	"fmt"
+	"math"
+	"sync"
 	"time"
 
 	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/game"
 	"telegram_webapp/internal/repository"
 
 	"github.com/jackc/pgx/v5"
@@ -18,12 +21,118 @@ var (
 	ErrBetTooLow           = errors.New("bet below minimum")
 	ErrBetTooHigh          = errors.New("bet exceeds maximum")
 	ErrInvalidBet          = errors.New("invalid bet amount")
+	ErrInvalidPick         = errors.New("invalid pick")
+	ErrInvalidMinesCount   = errors.New("invalid mines count")
+	ErrCaseCostMismatch    = errors.New("case cost mismatch")
+	ErrInvalidCurrency     = errors.New("invalid currency")
 )
 
+// ParseGameCurrency maps the optional request currency to a playable one:
+// empty means gems, anything but gems/coins is ErrInvalidCurrency
+func ParseGameCurrency(s string) (domain.Currency, error) {
+	switch domain.Currency(s) {
+	case "", domain.CurrencyGems:
+		return domain.CurrencyGems, nil
+	case domain.CurrencyCoins:
+		return domain.CurrencyCoins, nil
+	}
+	return "", ErrInvalidCurrency
+}
+
 // GameLimits holds bet limits configuration
 type GameLimits struct {
-	MinBet int64
-	MaxBet int64
+	MinBet      int64
+	MaxBet      int64
+	MinBetCoins int64 // coins are premium (1 TON = 10 coins), limits are separate
+	MaxBetCoins int64
+}
+
+// DefaultGameLimits returns built-in bet limits
+func DefaultGameLimits() GameLimits {
+	return GameLimits{MinBet: 10, MaxBet: 100000, MinBetCoins: 1, MaxBetCoins: 1000}
+}
+
+// MinesConfig holds the simple Mines board: one pick out of Cells with Mines hidden
+type MinesConfig struct {
+	Cells     int
+	Mines     int
+	HouseEdge float64 // 0.05 = 5%
+}
+
+// DefaultMinesConfig returns built-in simple Mines settings
+func DefaultMinesConfig() MinesConfig {
+	return MinesConfig{Cells: 12, Mines: 4, HouseEdge: 0.05}
+}
+
+// Valid reports whether the board leaves at least one mine and one safe cell
+func (m MinesConfig) Valid() bool {
+	return m.Cells > 1 && m.Mines >= 1 && m.Mines < m.Cells && m.HouseEdge >= 0 && m.HouseEdge < 1
+}
+
+// WinChance returns probability of a safe pick
+func (m MinesConfig) WinChance() float64 {
+	return float64(m.Cells-m.Mines) / float64(m.Cells)
+}
+
+// Multiplier returns the payout for a safe pick: fair odds (as in the Mines Pro
+// tables) minus house edge, floored to 0.01
+func (m MinesConfig) Multiplier() float64 {
+	return math.Floor((1-m.HouseEdge)*game.FairMinesMultiplier(m.Cells, m.Mines, 1)*100) / 100
+}
+
+// WithMines returns the board with minesCount mines instead of the configured
+// count (0 keeps it); at least one cell must stay safe
+func (m MinesConfig) WithMines(minesCount int) (MinesConfig, error) {
+	if minesCount == 0 {
+		return m, nil
+	}
+	if minesCount < 1 || minesCount >= m.Cells {
+		return m, ErrInvalidMinesCount
+	}
+	m.Mines = minesCount
+	return m, nil
+}
+
+// RTP returns expected return per unit bet
+func (m MinesConfig) RTP() float64 {
+	return m.WinChance() * m.Multiplier()
+}
+
+// Payout returns gems awarded for a safe pick (stake included)
+func (m MinesConfig) Payout(bet int64) int64 {
+	return bet * int64(math.Round(m.Multiplier()*100)) / 100
+}
+
+// Payouts holds PvE payout multipliers (stake included) before the house edge
+type Payouts struct {
+	CoinFlip  float64
+	RPS       float64
+	HouseEdge float64 // 0.02 = 2%
+}
+
+// DefaultPayouts returns built-in multipliers: x2 minus 2% edge = 1.96
+func DefaultPayouts() Payouts {
+	return Payouts{CoinFlip: 2, RPS: 2, HouseEdge: 0.02}
+}
+
+// Valid reports whether multipliers pay at least the stake back and the edge is sane
+func (p Payouts) Valid() bool {
+	return p.CoinFlip >= 1 && p.RPS >= 1 && p.HouseEdge >= 0 && p.HouseEdge < 1
+}
+
+// CoinFlipMultiplier returns the effective CoinFlip payout
+func (p Payouts) CoinFlipMultiplier() float64 {
+	return withHouseEdge(p.CoinFlip, p.HouseEdge)
+}
+
+// RPSMultiplier returns the effective RPS payout for a win
+func (p Payouts) RPSMultiplier() float64 {
+	return withHouseEdge(p.RPS, p.HouseEdge)
+}
+
+// withHouseEdge applies the edge and floors to 0.01 like MinesConfig.Multiplier
+func withHouseEdge(multiplier, edge float64) float64 {
+	return math.Floor(multiplier*(1-edge)*100) / 100
 }
 
 // GameService handles game business logic
@@ -31,6 +140,17 @@ type GameService struct {
 	db              *pgxpool.Pool
 	transactionRepo *repository.TransactionRepository
 	limits          GameLimits
+	mines           MinesConfig
+	payouts         Payouts
+	luck            *LuckProtection
+	happy           *HappyHours
+	rng             game.Randomizer
+	fair            *FairRNG
+
+	// Лимиты по играм из game_limits, обновляются ReloadLimits
+	limitsRepo   *repository.GameLimitsRepository
+	gameLimitsMu sync.RWMutex
+	gameLimits   map[domain.GameType]GameLimits
 }
 
 // NewGameService creates a new game service
@@ -38,19 +158,97 @@ func NewGameService(db *pgxpool.Pool) *GameService {
 	return &GameService{
 		db:              db,
 		transactionRepo: repository.NewTransactionRepository(db),
-		limits:          GameLimits{MinBet: 10, MaxBet: 100000}, // defaults
+		limits:          DefaultGameLimits(),
+		mines:           DefaultMinesConfig(),
+		payouts:         DefaultPayouts(),
+		rng:             game.NewCryptoRandomizer(),
+		limitsRepo:      repository.NewGameLimitsRepository(db),
 	}
 }
 
 // NewGameServiceWithLimits creates a game service with custom limits
-func NewGameServiceWithLimits(db *pgxpool.Pool, minBet, maxBet int64) *GameService {
+func NewGameServiceWithLimits(db *pgxpool.Pool, limits GameLimits) *GameService {
 	return &GameService{
 		db:              db,
 		transactionRepo: repository.NewTransactionRepository(db),
-		limits:          GameLimits{MinBet: minBet, MaxBet: maxBet},
+		limits:          limits.withDefaults(),
+		mines:           DefaultMinesConfig(),
+		payouts:         DefaultPayouts(),
+		rng:             game.NewCryptoRandomizer(),
+		limitsRepo:      repository.NewGameLimitsRepository(db),
+	}
+}
+
+// SetRandomizer replaces the source of game outcomes, e.g. with a deterministic stub in tests
+func (s *GameService) SetRandomizer(rng game.Randomizer) {
+	if rng != nil {
+		s.rng = rng
 	}
 }
 
+// Randomizer returns the source of game outcomes, shared with the dice and wheel handlers
+func (s *GameService) Randomizer() game.Randomizer {
+	return s.rng
+}
+
+// SetFairRNG makes outcomes provably fair when fr is enabled (see FairRNG)
+func (s *GameService) SetFairRNG(fr *FairRNG) {
+	s.fair = fr
+}
+
+// RoundRandomizer returns the source of one game of the user: the next fair
+// round with its proof when fair RNG is enabled, the regular randomizer otherwise
+func (s *GameService) RoundRandomizer(userID int64) (game.Randomizer, *game.FairProof) {
+	if rng, proof := s.fair.Begin(userID); rng != nil {
+		return rng, proof
+	}
+	return s.rng, nil
+}
+
+// SetMinesConfig overrides simple Mines settings; invalid boards are ignored
+func (s *GameService) SetMinesConfig(cfg MinesConfig) {
+	if cfg.Valid() {
+		s.mines = cfg
+	}
+}
+
+// SetPayouts overrides CoinFlip/RPS multipliers; invalid values are ignored
+func (s *GameService) SetPayouts(p Payouts) {
+	if p.Valid() {
+		s.payouts = p
+	}
+}
+
+// Payouts returns current CoinFlip/RPS multipliers
+func (s *GameService) Payouts() Payouts {
+	return s.payouts
+}
+
+// SetLuckProtection enables streak-aware odds for CoinFlip and Mines (see LuckProtectionConfig)
+func (s *GameService) SetLuckProtection(cfg LuckProtectionConfig) {
+	s.luck = NewLuckProtection(cfg)
+}
+
+// SetHappyHours sets payout boost windows for coinflip, rps and mines (see HappyHours)
+func (s *GameService) SetHappyHours(h *HappyHours) {
+	s.happy = h
+}
+
+// HappyHours returns the boost schedule, shared with quest rewards
+func (s *GameService) HappyHours() *HappyHours {
+	return s.happy
+}
+
+// LuckProtection returns current bad luck protection settings
+func (s *GameService) LuckProtection() LuckProtectionConfig {
+	return s.luck.Config()
+}
+
+// MinesConfig returns current simple Mines settings
+func (s *GameService) MinesConfig() MinesConfig {
+	return s.mines
+}
+
 // ValidateBet checks if bet is within allowed limits
 func (s *GameService) ValidateBet(bet int64) error {
 	if bet <= 0 {
@@ -65,23 +263,38 @@ func (s *GameService) ValidateBet(bet int64) error {
 	return nil
 }
 
-// GetLimits returns current bet limits
+// ValidateBetForCurrency checks bet against the limits of the given currency
+func (s *GameService) ValidateBetForCurrency(bet int64, currency domain.Currency) error {
+	return s.limits.check(bet, currency)
+}
+
+// validatePlay checks currency and bet before a PvE round
+func (s *GameService) validatePlay(gt domain.GameType, bet int64, currency domain.Currency) error {
+	if _, err := ParseGameCurrency(string(currency)); err != nil {
+		return err
+	}
+	return s.ValidateBetForGame(gt, bet, currency)
+}
+
+// GetLimits returns global bet limits (see LimitsFor for per-game overrides)
 func (s *GameService) GetLimits() GameLimits {
 	return s.limits
 }
 
 // CoinFlipResult contains the result of a coin flip game
 type CoinFlipResult struct {
-	Win        bool  `json:"win"`
-	Awarded    int64 `json:"awarded"`
-	NewBalance int64 `json:"gems"`
+	Win        bool            `json:"win"`
+	Awarded    int64           `json:"awarded"`
+	NewBalance int64           `json:"balance"`
+	Currency   domain.Currency `json:"currency"`
 }
 
-// PlayCoinFlip performs a coin flip game
-func (s *GameService) PlayCoinFlip(ctx context.Context, userID int64, bet int64) (*CoinFlipResult, map[string]interface{}, error) {
-	if err := s.ValidateBet(bet); err != nil {
+// PlayCoinFlip performs a coin flip game, bet and payout are in currency
+func (s *GameService) PlayCoinFlip(ctx context.Context, userID int64, bet int64, currency domain.Currency) (*CoinFlipResult, map[string]interface{}, error) {
+	if err := s.validatePlay(domain.GameTypeCoinflip, bet, currency); err != nil {
 		return nil, nil, err
 	}
+	col := currency.BalanceColumn()
 
 	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
@@ -91,7 +304,7 @@ func (s *GameService) PlayCoinFlip(ctx context.Context, userID int64, bet int64)
 
 	// Lock and check balance
 	var balance int64
-	if err := tx.QueryRow(ctx, `SELECT gems FROM users WHERE id=$1 FOR UPDATE`, userID).Scan(&balance); err != nil {
+	if err := tx.QueryRow(ctx, fmt.Sprintf(`SELECT %s FROM users WHERE id=$1 FOR UPDATE`, col), userID).Scan(&balance); err != nil {
 		return nil, nil, err
 	}
 	if balance < bet {
@@ -99,23 +312,34 @@ func (s *GameService) PlayCoinFlip(ctx context.Context, userID int64, bet int64)
 	}
 
 	// Deduct bet
-	if _, err := tx.Exec(ctx, `UPDATE users SET gems = gems - $1 WHERE id=$2`, bet, userID); err != nil {
+	if _, err := tx.Exec(ctx, fmt.Sprintf(`UPDATE users SET %[1]s = %[1]s - $1 WHERE id=$2`, col), bet, userID); err != nil {
 		return nil, nil, err
 	}
 
 	// Coin flip
-	win := rand.Int63n(2) == 0
+	multiplier, happyBoost := s.happy.BoostPayout("coinflip", time.Now(), s.payouts.CoinFlipMultiplier(), 0.5, 0)
+	rng, fair := s.RoundRandomizer(userID)
+	win, luckAdj := s.luck.Resolve(userID, "coinflip", 0.5, multiplier, bet, rng.Float64())
 
 	awarded := int64(0)
 	if win {
-		awarded = bet * 2
-		if _, err := tx.Exec(ctx, `UPDATE users SET gems = gems + $1 WHERE id=$2`, awarded, userID); err != nil {
+		awarded = int64(float64(bet) * multiplier)
+		if _, err := tx.Exec(ctx, fmt.Sprintf(`UPDATE users SET %[1]s = %[1]s + $1 WHERE id=$2`, col), awarded, userID); err != nil {
 			return nil, nil, err
 		}
 	}
 
 	// Record transaction
-	meta := map[string]interface{}{"bet": bet, "awarded": awarded, "win": win}
+	meta := map[string]interface{}{"bet": bet, "awarded": awarded, "win": win, "multiplier": multiplier, "currency": currency}
+	if luckAdj != 0 {
+		meta["luck_adjustment"] = luckAdj
+	}
+	if happyBoost != 0 {
+		meta["happy_hour"] = happyBoost
+	}
+	if fair != nil {
+		meta["fair"] = fair
+	}
 	transaction := &domain.Transaction{
 		UserID: userID,
 		Type:   "coinflip",
@@ -128,7 +352,7 @@ func (s *GameService) PlayCoinFlip(ctx context.Context, userID int64, bet int64)
 
 	// Get new balance
 	var newBalance int64
-	if err := tx.QueryRow(ctx, `SELECT gems FROM users WHERE id=$1`, userID).Scan(&newBalance); err != nil {
+	if err := tx.QueryRow(ctx, fmt.Sprintf(`SELECT %s FROM users WHERE id=$1`, col), userID).Scan(&newBalance); err != nil {
 		return nil, nil, err
 	}
 
@@ -140,27 +364,33 @@ func (s *GameService) PlayCoinFlip(ctx context.Context, userID int64, bet int64)
 		Win:        win,
 		Awarded:    awarded,
 		NewBalance: newBalance,
+		Currency:   currency,
 	}, meta, nil
 }
 
 // RPSResult contains the result of an RPS game
 type RPSResult struct {
-	UserMove   string `json:"move"`
-	BotMove    string `json:"bot"`
-	Result     int    `json:"result"` // 1=win, 0=draw, -1=lose
-	Awarded    int64  `json:"awarded"`
-	NewBalance int64  `json:"gems"`
+	UserMove   string          `json:"move"`
+	BotMove    string          `json:"bot"`
+	Result     int             `json:"result"` // 1=win, 0=draw, -1=lose
+	Awarded    int64           `json:"awarded"`
+	NewBalance int64           `json:"balance"`
+	Currency   domain.Currency `json:"currency"`
 }
 
-// PlayRPS performs an RPS game
-func (s *GameService) PlayRPS(ctx context.Context, userID int64, move string, bet int64) (*RPSResult, map[string]interface{}, error) {
+// PlayRPS performs an RPS game, bet and payout are in currency
+func (s *GameService) PlayRPS(ctx context.Context, userID int64, move string, bet int64, currency domain.Currency) (*RPSResult, map[string]interface{}, error) {
 	if move != "rock" && move != "paper" && move != "scissors" {
 		return nil, nil, errors.New("invalid move")
 	}
+	if _, err := ParseGameCurrency(string(currency)); err != nil {
+		return nil, nil, err
+	}
+	col := currency.BalanceColumn()
 
 	// Validate bet if provided
 	if bet > 0 {
-		if err := s.ValidateBet(bet); err != nil {
+		if err := s.ValidateBetForGame(domain.GameTypeRPS, bet, currency); err != nil {
 			return nil, nil, err
 		}
 	}
@@ -174,20 +404,21 @@ func (s *GameService) PlayRPS(ctx context.Context, userID int64, move string, be
 	// Handle bet deduction if bet > 0
 	if bet > 0 {
 		var balance int64
-		if err := tx.QueryRow(ctx, `SELECT gems FROM users WHERE id=$1 FOR UPDATE`, userID).Scan(&balance); err != nil {
+		if err := tx.QueryRow(ctx, fmt.Sprintf(`SELECT %s FROM users WHERE id=$1 FOR UPDATE`, col), userID).Scan(&balance); err != nil {
 			return nil, nil, err
 		}
 		if balance < bet {
 			return nil, nil, ErrInsufficientBalance
 		}
-		if _, err := tx.Exec(ctx, `UPDATE users SET gems = gems - $1 WHERE id=$2`, bet, userID); err != nil {
+		if _, err := tx.Exec(ctx, fmt.Sprintf(`UPDATE users SET %[1]s = %[1]s - $1 WHERE id=$2`, col), bet, userID); err != nil {
 			return nil, nil, err
 		}
 	}
 
 	// Bot move
 	moves := []string{"rock", "paper", "scissors"}
-	botMove := moves[rand.Intn(3)]
+	rng, fair := s.RoundRandomizer(userID)
+	botMove := moves[rng.Intn(3)]
 
 	// Determine winner: 1=user win, 0=draw, -1=bot win
 	result := 0
@@ -201,16 +432,24 @@ func (s *GameService) PlayRPS(ctx context.Context, userID int64, move string, be
 		result = -1
 	}
 
+	// Ничья не возвращает ставку, так что RTP = шанс победы * выплата
+	multiplier, happyBoost := s.happy.BoostPayout("rps", time.Now(), s.payouts.RPSMultiplier(), 1.0/3, 0)
 	awarded := int64(0)
 	if result == 1 && bet > 0 {
-		awarded = bet * 2
-		if _, err := tx.Exec(ctx, `UPDATE users SET gems = gems + $1 WHERE id=$2`, awarded, userID); err != nil {
+		awarded = int64(float64(bet) * multiplier)
+		if _, err := tx.Exec(ctx, fmt.Sprintf(`UPDATE users SET %[1]s = %[1]s + $1 WHERE id=$2`, col), awarded, userID); err != nil {
 			return nil, nil, err
 		}
 	}
 
 	// Record transaction
-	meta := map[string]interface{}{"move": move, "bot": botMove, "result": result}
+	meta := map[string]interface{}{"move": move, "bot": botMove, "result": result, "multiplier": multiplier, "currency": currency}
+	if happyBoost != 0 {
+		meta["happy_hour"] = happyBoost
+	}
+	if fair != nil {
+		meta["fair"] = fair
+	}
 	netAmount := awarded - bet
 	transaction := &domain.Transaction{
 		UserID: userID,
@@ -223,7 +462,7 @@ func (s *GameService) PlayRPS(ctx context.Context, userID int64, move string, be
 	}
 
 	var newBalance int64
-	if err := tx.QueryRow(ctx, `SELECT gems FROM users WHERE id=$1`, userID).Scan(&newBalance); err != nil {
+	if err := tx.QueryRow(ctx, fmt.Sprintf(`SELECT %s FROM users WHERE id=$1`, col), userID).Scan(&newBalance); err != nil {
 		return nil, nil, err
 	}
 
@@ -237,25 +476,33 @@ func (s *GameService) PlayRPS(ctx context.Context, userID int64, move string, be
 		Result:     result,
 		Awarded:    awarded,
 		NewBalance: newBalance,
+		Currency:   currency,
 	}, meta, nil
 }
 
 // MinesResult contains the result of a mines game
 type MinesResult struct {
-	Win        bool       `json:"win"`
-	Awarded    int64      `json:"awarded"`
-	NewBalance int64      `json:"gems"`
-	Mines      map[int]bool `json:"-"`
+	Win        bool            `json:"win"`
+	Awarded    int64           `json:"awarded"`
+	NewBalance int64           `json:"balance"`
+	Currency   domain.Currency `json:"currency"`
+	Mines      map[int]bool    `json:"-"`
 }
 
-// PlayMines performs a mines game
-func (s *GameService) PlayMines(ctx context.Context, userID int64, pick int, bet int64) (*MinesResult, map[string]interface{}, error) {
-	if pick < 1 || pick > 12 {
-		return nil, nil, errors.New("invalid pick")
+// PlayMines performs a mines game, bet and payout are in currency. minesCount
+// picks the board density (0 = configured default); denser boards pay more.
+func (s *GameService) PlayMines(ctx context.Context, userID int64, pick, minesCount int, bet int64, currency domain.Currency) (*MinesResult, map[string]interface{}, error) {
+	cfg, err := s.mines.WithMines(minesCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pick < 1 || pick > cfg.Cells {
+		return nil, nil, ErrInvalidPick
 	}
-	if err := s.ValidateBet(bet); err != nil {
+	if err := s.validatePlay(domain.GameTypeMines, bet, currency); err != nil {
 		return nil, nil, err
 	}
+	col := currency.BalanceColumn()
 
 	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
@@ -264,34 +511,55 @@ func (s *GameService) PlayMines(ctx context.Context, userID int64, pick int, bet
 	defer func() { _ = tx.Rollback(ctx) }()
 
 	var balance int64
-	if err := tx.QueryRow(ctx, `SELECT gems FROM users WHERE id=$1 FOR UPDATE`, userID).Scan(&balance); err != nil {
+	if err := tx.QueryRow(ctx, fmt.Sprintf(`SELECT %s FROM users WHERE id=$1 FOR UPDATE`, col), userID).Scan(&balance); err != nil {
 		return nil, nil, err
 	}
 	if balance < bet {
 		return nil, nil, ErrInsufficientBalance
 	}
 
-	if _, err := tx.Exec(ctx, `UPDATE users SET gems = gems - $1 WHERE id=$2`, bet, userID); err != nil {
+	if _, err := tx.Exec(ctx, fmt.Sprintf(`UPDATE users SET %[1]s = %[1]s - $1 WHERE id=$2`, col), bet, userID); err != nil {
 		return nil, nil, err
 	}
 
-	// Place 4 unique mines
+	// Outcome first, then place unique mines consistent with it
+	multiplier, happyBoost := s.happy.BoostPayout("mines", time.Now(), cfg.Multiplier(), cfg.WinChance(), 0)
+	rng, fair := s.RoundRandomizer(userID)
+	win, luckAdj := s.luck.Resolve(userID, "mines", cfg.WinChance(), multiplier, bet, rng.Float64())
 	mines := map[int]bool{}
-	for len(mines) < 4 {
-		n := rand.Intn(12) + 1
+	if !win {
+		mines[pick] = true
+	}
+	for len(mines) < cfg.Mines {
+		n := rng.Intn(cfg.Cells) + 1
+		if n == pick {
+			continue
+		}
 		mines[n] = true
 	}
 
 	pickIsMine := mines[pick]
 	awarded := int64(0)
 	if !pickIsMine {
-		awarded = bet * 2
-		if _, err := tx.Exec(ctx, `UPDATE users SET gems = gems + $1 WHERE id=$2`, awarded, userID); err != nil {
+		awarded = cfg.Payout(bet)
+		if happyBoost != 0 {
+			awarded = int64(float64(bet) * multiplier)
+		}
+		if _, err := tx.Exec(ctx, fmt.Sprintf(`UPDATE users SET %[1]s = %[1]s + $1 WHERE id=$2`, col), awarded, userID); err != nil {
 			return nil, nil, err
 		}
 	}
 
-	meta := map[string]interface{}{"pick": pick, "mines": mines, "win": !pickIsMine}
+	meta := map[string]interface{}{"pick": pick, "mines": mines, "mines_count": cfg.Mines, "cells": cfg.Cells, "win": !pickIsMine, "multiplier": multiplier, "currency": currency}
+	if luckAdj != 0 {
+		meta["luck_adjustment"] = luckAdj
+	}
+	if happyBoost != 0 {
+		meta["happy_hour"] = happyBoost
+	}
+	if fair != nil {
+		meta["fair"] = fair
+	}
 	netAmount := awarded - bet
 	transaction := &domain.Transaction{
 		UserID: userID,
@@ -304,7 +572,7 @@ func (s *GameService) PlayMines(ctx context.Context, userID int64, pick int, bet
 	}
 
 	var newBalance int64
-	if err := tx.QueryRow(ctx, `SELECT gems FROM users WHERE id=$1`, userID).Scan(&newBalance); err != nil {
+	if err := tx.QueryRow(ctx, fmt.Sprintf(`SELECT %s FROM users WHERE id=$1`, col), userID).Scan(&newBalance); err != nil {
 		return nil, nil, err
 	}
 
@@ -316,31 +584,26 @@ func (s *GameService) PlayMines(ctx context.Context, userID int64, pick int, bet
 		Win:        !pickIsMine,
 		Awarded:    awarded,
 		NewBalance: newBalance,
+		Currency:   currency,
 		Mines:      mines,
 	}, meta, nil
 }
 
 // CaseSpinResult contains the result of a case spin game
 type CaseSpinResult struct {
-	CaseID     int   `json:"case_id"`
-	Prize      int64 `json:"prize"`
-	NewBalance int64 `json:"gems"`
+	CaseID     string `json:"case_id"`
+	PrizeID    int    `json:"prize_id"`
+	Prize      int64  `json:"prize"`
+	Cost       int64  `json:"cost"`
+	NewBalance int64  `json:"gems"`
 }
 
-// PlayCaseSpin performs a case spin game
-func (s *GameService) PlayCaseSpin(ctx context.Context, userID int64) (*CaseSpinResult, map[string]interface{}, error) {
-	const cost int64 = 100
-	// Prize distribution
-	cases := []struct {
-		ID     int
-		Amount int64
-		Prob   float64
-	}{
-		{1, 250, 0.5},
-		{2, 500, 0.2},
-		{3, 750, 0.15},
-		{4, 1000, 0.10},
-		{5, 5000, 0.05},
+// PlayCaseSpin opens case cfg (see CaseService). expectedCost is the price the
+// client showed to the player (0 = not sent); a stale price fails with ErrCaseCostMismatch.
+func (s *GameService) PlayCaseSpin(ctx context.Context, userID int64, cfg CaseConfig, expectedCost int64) (*CaseSpinResult, map[string]interface{}, error) {
+	cost := cfg.Cost
+	if expectedCost > 0 && expectedCost != cost {
+		return nil, nil, ErrCaseCostMismatch
 	}
 
 	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
@@ -349,47 +612,34 @@ func (s *GameService) PlayCaseSpin(ctx context.Context, userID int64) (*CaseSpin
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
-	// Lock balance
-	var balance int64
-	if err := tx.QueryRow(ctx, `SELECT gems FROM users WHERE id=$1 FOR UPDATE`, userID).Scan(&balance); err != nil {
-		return nil, nil, err
-	}
-	if balance < cost {
-		return nil, nil, ErrInsufficientBalance
-	}
-
-	if _, err := tx.Exec(ctx, `UPDATE users SET gems = gems - $1 WHERE id=$2`, cost, userID); err != nil {
+	// Проверка и списание одним запросом: параллельные спины не уведут баланс в минус
+	var newBalance int64
+	if err := tx.QueryRow(ctx,
+		`UPDATE users SET gems = gems - $1 WHERE id=$2 AND gems >= $1 RETURNING gems`,
+		cost, userID,
+	).Scan(&newBalance); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, ErrInsufficientBalance
+		}
 		return nil, nil, err
 	}
 
 	// Weighted pick
-	r := rand.Float64()
-	acc := 0.0
-	var picked struct {
-		ID     int
-		Amount int64
-		Prob   float64
-	}
-	for _, cs := range cases {
-		acc += cs.Prob
-		if r <= acc {
-			picked = cs
-			break
-		}
-	}
-	if picked.Amount == 0 {
-		picked = cases[len(cases)-1]
-	}
+	rng, fair := s.RoundRandomizer(userID)
+	picked := cfg.pick(rng.Float64())
 
 	awarded := picked.Amount
 	if awarded > 0 {
-		if _, err := tx.Exec(ctx, `UPDATE users SET gems = gems + $1 WHERE id=$2`, awarded, userID); err != nil {
+		if err := tx.QueryRow(ctx, `UPDATE users SET gems = gems + $1 WHERE id=$2 RETURNING gems`, awarded, userID).Scan(&newBalance); err != nil {
 			return nil, nil, err
 		}
 	}
 
 	netAmount := awarded - cost
-	meta := map[string]interface{}{"case_id": picked.ID, "prize": awarded, "cost": cost}
+	meta := map[string]interface{}{"case_id": cfg.ID, "prize_id": picked.ID, "prize": awarded, "cost": cost}
+	if fair != nil {
+		meta["fair"] = fair
+	}
 	transaction := &domain.Transaction{
 		UserID: userID,
 		Type:   "case",
@@ -400,18 +650,15 @@ func (s *GameService) PlayCaseSpin(ctx context.Context, userID int64) (*CaseSpin
 		return nil, nil, err
 	}
 
-	var newBalance int64
-	if err := tx.QueryRow(ctx, `SELECT gems FROM users WHERE id=$1`, userID).Scan(&newBalance); err != nil {
-		return nil, nil, err
-	}
-
 	if err := tx.Commit(ctx); err != nil {
 		return nil, nil, err
 	}
 
 	return &CaseSpinResult{
-		CaseID:     picked.ID,
+		CaseID:     cfg.ID,
+		PrizeID:    picked.ID,
 		Prize:      awarded,
+		Cost:       cost,
 		NewBalance: newBalance,
 	}, meta, nil
 }
@@ -479,7 +726,3 @@ func (s *GameService) AddTransaction(ctx context.Context, userID int64, txType s
 	}
 	return s.transactionRepo.Create(ctx, transaction)
 }
-
-func init() {
-	rand.Seed(time.Now().UnixNano())
-}
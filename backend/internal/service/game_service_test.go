@@ -0,0 +1,102 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"telegram_webapp/internal/domain"
+)
+
+func TestMinesConfigRTPBelowOne(t *testing.T) {
+	cfg := DefaultMinesConfig()
+	if !cfg.Valid() {
+		t.Fatalf("default mines config is invalid: %+v", cfg)
+	}
+	if rtp := cfg.RTP(); rtp >= 1 {
+		t.Fatalf("expected RTP below 100%%, got %.4f (multiplier %.2f)", rtp, cfg.Multiplier())
+	}
+
+	// Effective RTP of the integer payout must stay below 100% too
+	const bet = 1000
+	if got := cfg.WinChance() * float64(cfg.Payout(bet)) / bet; got >= 1 {
+		t.Fatalf("expected payout RTP below 100%%, got %.4f", got)
+	}
+}
+
+func TestMinesConfigRTPAcrossMineCounts(t *testing.T) {
+	for mines := 1; mines < 12; mines++ {
+		cfg := MinesConfig{Cells: 12, Mines: mines, HouseEdge: 0.05}
+		if rtp := cfg.RTP(); rtp >= 1 {
+			t.Errorf("mines=%d: expected RTP below 100%%, got %.4f", mines, rtp)
+		}
+	}
+}
+
+func TestMinesConfigWithMines(t *testing.T) {
+	cfg := DefaultMinesConfig()
+	if got, err := cfg.WithMines(0); err != nil || got != cfg {
+		t.Fatalf("expected default board for 0, got %+v (%v)", got, err)
+	}
+	for _, n := range []int{-1, cfg.Cells} {
+		if _, err := cfg.WithMines(n); !errors.Is(err, ErrInvalidMinesCount) {
+			t.Fatalf("mines=%d: expected ErrInvalidMinesCount, got %v", n, err)
+		}
+	}
+
+	// Чем больше мин, тем выше множитель
+	prev := 0.0
+	for mines := 1; mines < cfg.Cells; mines++ {
+		board, err := cfg.WithMines(mines)
+		if err != nil {
+			t.Fatalf("mines=%d: %v", mines, err)
+		}
+		if m := board.Multiplier(); m <= prev {
+			t.Fatalf("mines=%d: expected multiplier above %.2f, got %.2f", mines, prev, m)
+		} else {
+			prev = m
+		}
+	}
+	if got := cfg.Multiplier(); got != 1.42 {
+		t.Fatalf("expected default multiplier 1.42, got %.2f", got)
+	}
+}
+
+func TestMinesConfigZeroEdgeIsNotProfitable(t *testing.T) {
+	// Без преимущества казино RTP не должен превышать 100% из-за округления
+	cfg := MinesConfig{Cells: 12, Mines: 4}
+	if rtp := cfg.RTP(); rtp > 1 {
+		t.Fatalf("expected RTP <= 100%% without edge, got %.4f", rtp)
+	}
+}
+
+func TestDefaultPayoutsHaveHouseEdge(t *testing.T) {
+	p := DefaultPayouts()
+	if !p.Valid() {
+		t.Fatalf("default payouts are invalid: %+v", p)
+	}
+	if got := p.CoinFlipMultiplier(); got != 1.96 {
+		t.Fatalf("expected coinflip multiplier 1.96, got %.2f", got)
+	}
+	if rtp := 0.5 * p.CoinFlipMultiplier(); rtp >= 1 {
+		t.Fatalf("expected coinflip RTP below 100%%, got %.4f", rtp)
+	}
+
+	const bet = 100
+	if awarded := int64(float64(bet) * p.CoinFlipMultiplier()); awarded != 196 {
+		t.Fatalf("expected 196 awarded for bet %d, got %d", bet, awarded)
+	}
+}
+
+func TestParseGameCurrency(t *testing.T) {
+	cases := map[string]domain.Currency{"": domain.CurrencyGems, "gems": domain.CurrencyGems, "coins": domain.CurrencyCoins}
+	for in, want := range cases {
+		if got, err := ParseGameCurrency(in); err != nil || got != want {
+			t.Errorf("ParseGameCurrency(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"gk", "ton", "GEMS"} {
+		if _, err := ParseGameCurrency(in); !errors.Is(err, ErrInvalidCurrency) {
+			t.Errorf("ParseGameCurrency(%q): expected ErrInvalidCurrency, got %v", in, err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"math"
+	"time"
+
+	"telegram_webapp/internal/game"
+)
+
+// HappyHours holds configured windows; nil means no boosts
+type HappyHours struct {
+	windows []game.HappyHour
+	maxRTP  float64
+}
+
+// NewHappyHours creates the schedule; maxRTP <= 0 falls back to game.DefaultHappyHourMaxRTP
+func NewHappyHours(windows []game.HappyHour, maxRTP float64) *HappyHours {
+	if maxRTP <= 0 {
+		maxRTP = game.DefaultHappyHourMaxRTP
+	}
+	return &HappyHours{windows: windows, maxRTP: maxRTP}
+}
+
+// Windows returns all configured windows for disclosure
+func (h *HappyHours) Windows() []game.HappyHour {
+	if h == nil {
+		return []game.HappyHour{}
+	}
+	return append([]game.HappyHour{}, h.windows...)
+}
+
+// MaxRTP returns the RTP a boosted game may not exceed
+func (h *HappyHours) MaxRTP() float64 {
+	if h == nil {
+		return game.DefaultHappyHourMaxRTP
+	}
+	return h.maxRTP
+}
+
+// HasWindow reports whether any window boosts key
+func (h *HappyHours) HasWindow(key string) bool {
+	if h == nil {
+		return false
+	}
+	for _, w := range h.windows {
+		if w.Game == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Boost returns the multiplier for key (a game or quests) at now, 1 outside of happy hours.
+// Overlapping windows don't stack - the largest wins.
+func (h *HappyHours) Boost(key string, now time.Time) float64 {
+	boost := 1.0
+	if h == nil {
+		return boost
+	}
+	for _, w := range h.windows {
+		if w.Game == key && w.ActiveAt(now) && w.Multiplier > boost {
+			boost = w.Multiplier
+		}
+	}
+	return boost
+}
+
+// Active returns boosts in effect at now by game
+func (h *HappyHours) Active(now time.Time) map[string]float64 {
+	out := map[string]float64{}
+	if h == nil {
+		return out
+	}
+	for _, w := range h.windows {
+		if b := h.Boost(w.Game, now); b > 1 {
+			out[w.Game] = b
+		}
+	}
+	return out
+}
+
+// BoostPayout applies an active happy hour to a payout multiplier (stake included).
+// winChance and refundChance (draws) give the game's RTP, which is kept at or below
+// MaxRTP - the boost is cut down if needed. Returns the multiplier to pay and the
+// boost actually applied (0 if none) for meta.
+func (h *HappyHours) BoostPayout(key string, now time.Time, multiplier, winChance, refundChance float64) (float64, float64) {
+	boost := h.Boost(key, now)
+	if boost <= 1 || winChance <= 0 {
+		return multiplier, 0
+	}
+
+	boosted := multiplier * boost
+	if ceiling := (h.maxRTP - refundChance) / winChance; boosted > ceiling {
+		boosted = ceiling
+	}
+	boosted = math.Floor(boosted*100) / 100
+	if boosted <= multiplier {
+		return multiplier, 0
+	}
+	return boosted, math.Round(boosted/multiplier*1000) / 1000
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"telegram_webapp/internal/game"
+)
+
+func TestHappyHourWindows(t *testing.T) {
+	hh, err := game.ParseHappyHours("quests@sat/22:00-02:00=1.5,mines@10:00-12:00=1.1")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	h := NewHappyHours(hh, 0)
+
+	// 2026-10-17 - суббота
+	sat := func(hour, min int) time.Time { return time.Date(2026, 10, 17, hour, min, 0, 0, time.UTC) }
+	cases := []struct {
+		game string
+		at   time.Time
+		want float64
+	}{
+		{"quests", sat(23, 0), 1.5},
+		{"quests", sat(23, 0).Add(2 * time.Hour), 1.5}, // воскресенье 01:00 - хвост субботнего окна
+		{"quests", sat(1, 0), 1},                       // суббота 01:00 - хвост пятничного окна, пятницы нет
+		{"quests", sat(21, 59), 1},
+		{"mines", sat(11, 59), 1.1},
+		{"mines", sat(12, 0), 1},
+		{"coinflip", sat(11, 0), 1},
+	}
+	for _, c := range cases {
+		if got := h.Boost(c.game, c.at); got != c.want {
+			t.Errorf("Boost(%s, %s) = %v, want %v", c.game, c.at.Format(time.RFC3339), got, c.want)
+		}
+	}
+
+	var none *HappyHours
+	if got := none.Boost("mines", sat(11, 0)); got != 1 {
+		t.Fatalf("nil schedule must not boost, got %v", got)
+	}
+}
+
+func TestHappyHourBoostKeepsRTPBounded(t *testing.T) {
+	windows, err := game.ParseHappyHours("coinflip@00:00-23:59=1.5")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	h := NewHappyHours(windows, 0.99)
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	base := DefaultPayouts().CoinFlipMultiplier()
+	m, boost := h.BoostPayout("coinflip", now, base, 0.5, 0)
+	if m != 1.98 {
+		t.Fatalf("expected boost capped at 1.98 (RTP 99%%), got %v", m)
+	}
+	if boost <= 1 {
+		t.Fatalf("expected applied boost to be reported, got %v", boost)
+	}
+	if rtp := 0.5 * m; rtp > 0.99 {
+		t.Fatalf("boosted RTP %.4f above bound", rtp)
+	}
+
+	// База уже на пределе - буст не применяется
+	if m, boost := h.BoostPayout("coinflip", now, 1.98, 0.5, 0); m != 1.98 || boost != 0 {
+		t.Fatalf("expected no boost at the RTP bound, got %v/%v", m, boost)
+	}
+	// Другая игра без окна
+	if m, boost := h.BoostPayout("rps", now, 1.96, 1.0/3, 0); m != 1.96 || boost != 0 {
+		t.Fatalf("expected no boost for rps, got %v/%v", m, boost)
+	}
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"telegram_webapp/internal/logger"
+	"telegram_webapp/internal/ton"
+)
+
+// HotWalletStatus is a snapshot of the last hot wallet balance check
+type HotWalletStatus struct {
+	Address       string    `json:"address"`
+	BalanceNano   int64     `json:"balance_nano"`
+	ThresholdNano int64     `json:"threshold_nano"`
+	CheckedAt     time.Time `json:"checked_at"`
+	LastError     string    `json:"last_error,omitempty"`
+	PayoutsPaused bool      `json:"payouts_paused"`
+}
+
+// HotWalletMonitor periodically checks the hot wallet balance. Below the
+// threshold it alerts once and pauses payouts until the balance is topped up
+// again: the admin bot then approves only withdrawals with a tx hash.
+type HotWalletMonitor struct {
+	client    *ton.Client
+	address   string
+	threshold int64 // nanoTON
+	interval  time.Duration
+
+	// OnLow is called once when the balance drops below the threshold
+	OnLow func(ctx context.Context, status HotWalletStatus)
+	// OnRecovered is called once when the balance is back above the threshold
+	OnRecovered func(ctx context.Context, status HotWalletStatus)
+
+	mu     sync.RWMutex
+	status HotWalletStatus
+	stopCh chan struct{}
+	once   sync.Once
+}
+
+// NewHotWalletMonitor creates a monitor for the given wallet address
+func NewHotWalletMonitor(client *ton.Client, address string, thresholdNano int64, interval time.Duration) *HotWalletMonitor {
+	return &HotWalletMonitor{
+		client:    client,
+		address:   address,
+		threshold: thresholdNano,
+		interval:  interval,
+		status:    HotWalletStatus{Address: address, ThresholdNano: thresholdNano},
+		stopCh:    make(chan struct{}),
+	}
+}
+
+// Start runs balance checks on the configured interval until Stop is called
+func (m *HotWalletMonitor) Start() {
+	go func() {
+		m.Check(context.Background())
+
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-m.stopCh:
+				return
+			case <-ticker.C:
+				m.Check(context.Background())
+			}
+		}
+	}()
+}
+
+// Stop stops the periodic checks
+func (m *HotWalletMonitor) Stop() {
+	m.once.Do(func() { close(m.stopCh) })
+}
+
+// Check fetches the balance once and updates the paused state.
+// API errors keep the previous paused state - we don't resume on unknown balance.
+func (m *HotWalletMonitor) Check(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	info, err := m.client.GetAccountInfo(ctx, m.address)
+
+	m.mu.Lock()
+	m.status.CheckedAt = time.Now()
+	if err != nil {
+		m.status.LastError = err.Error()
+		m.mu.Unlock()
+		logger.Error("hot wallet balance check failed", "address", m.address, "error", err)
+		return
+	}
+
+	wasPaused := m.status.PayoutsPaused
+	m.status.LastError = ""
+	m.status.BalanceNano = info.Balance
+	m.status.PayoutsPaused = info.Balance < m.threshold
+	status := m.status
+	m.mu.Unlock()
+
+	switch {
+	case status.PayoutsPaused && !wasPaused:
+		logger.Warn("hot wallet balance below threshold, payouts paused",
+			"balance_ton", ton.NanoToTON(status.BalanceNano), "threshold_ton", ton.NanoToTON(status.ThresholdNano))
+		if m.OnLow != nil {
+			m.OnLow(ctx, status)
+		}
+	case !status.PayoutsPaused && wasPaused:
+		logger.Info("hot wallet topped up, payouts resumed", "balance_ton", ton.NanoToTON(status.BalanceNano))
+		if m.OnRecovered != nil {
+			m.OnRecovered(ctx, status)
+		}
+	}
+}
+
+// Status returns the last check result
+func (m *HotWalletMonitor) Status() HotWalletStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status
+}
+
+// PayoutsPaused reports whether payouts must wait for a top-up
+func (m *HotWalletMonitor) PayoutsPaused() bool {
+	if m == nil {
+		return false
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status.PayoutsPaused
+}
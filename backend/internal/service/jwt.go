@@ -3,12 +3,34 @@ package service
 import (
 	"errors"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtSecret []byte
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token expired")
+)
+
+// Default token lifetimes, overridden by JWT_ACCESS_TTL_MINUTES / JWT_REFRESH_TTL_HOURS
+const (
+	DefaultAccessTokenTTL  = time.Hour
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// Тип токена в claim "typ": access-токены без него выпущены до refresh-токенов
+const (
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+)
+
+var (
+	jwtSecret       []byte
+	accessTokenTTL  = DefaultAccessTokenTTL
+	refreshTokenTTL = DefaultRefreshTokenTTL
+)
 
 func InitJWT() {
 	secret := os.Getenv("JWT_SECRET")
@@ -16,22 +38,62 @@ func InitJWT() {
 		panic("JWT_SECRET is not set")
 	}
 	jwtSecret = []byte(secret)
+
+	// Время жизни access-токена в минутах
+	if v := os.Getenv("JWT_ACCESS_TTL_MINUTES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			accessTokenTTL = time.Duration(n) * time.Minute
+		}
+	}
+	// Время жизни refresh-токена в часах
+	if v := os.Getenv("JWT_REFRESH_TTL_HOURS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			refreshTokenTTL = time.Duration(n) * time.Hour
+		}
+	}
+}
+
+// AccessTokenTTL returns how long access tokens issued now stay valid
+func AccessTokenTTL() time.Duration {
+	return accessTokenTTL
 }
 
 func GenerateJWT(userID int64) (string, error) {
-	now := time.Now().Unix()
+	return signToken(userID, tokenTypeAccess, accessTokenTTL)
+}
+
+// GenerateRefreshToken issues a long-lived token that is only accepted by
+// ParseRefreshToken, never as an access token
+func GenerateRefreshToken(userID int64) (string, error) {
+	return signToken(userID, tokenTypeRefresh, refreshTokenTTL)
+}
+
+func signToken(userID int64, typ string, ttl time.Duration) (string, error) {
+	now := time.Now()
 	claims := jwt.MapClaims{
 		"user_id": userID,
-		"exp":     time.Now().Add(24 * time.Hour).Unix(),
-		"iat":     now,
-		"nbf":     now,
+		"typ":     typ,
+		"exp":     now.Add(ttl).Unix(),
+		"iat":     now.Unix(),
+		"nbf":     now.Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(jwtSecret)
 }
 
+// ParseJWT returns the user of a valid access token. Expired tokens fail with
+// ErrTokenExpired so clients can refresh instead of re-authenticating.
 func ParseJWT(tokenString string) (int64, error) {
+	return parseToken(tokenString, tokenTypeAccess)
+}
+
+// ParseRefreshToken returns the user of a valid refresh token
+func ParseRefreshToken(tokenString string) (int64, error) {
+	return parseToken(tokenString, tokenTypeRefresh)
+}
+
+func parseToken(tokenString, typ string) (int64, error) {
 	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
 		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("unexpected signing method")
@@ -39,31 +101,34 @@ func ParseJWT(tokenString string) (int64, error) {
 		return jwtSecret, nil
 	})
 
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return 0, ErrTokenExpired
+	}
 	if err != nil || !token.Valid {
-		return 0, errors.New("invalid token")
+		return 0, ErrInvalidToken
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return 0, errors.New("invalid claims")
+		return 0, ErrInvalidToken
 	}
 
-	// validate time-based claims
-	now := time.Now().Unix()
-	if exp, ok := claims["exp"].(float64); ok {
-		if int64(exp) < now {
-			return 0, errors.New("token expired")
-		}
+	// exp обязателен: токен без срока жизни не принимаем
+	if _, ok := claims["exp"].(float64); !ok {
+		return 0, ErrInvalidToken
 	}
-	if nbf, ok := claims["nbf"].(float64); ok {
-		if int64(nbf) > now {
-			return 0, errors.New("token not valid yet")
-		}
+
+	tokenTyp, _ := claims["typ"].(string)
+	if tokenTyp == "" {
+		tokenTyp = tokenTypeAccess
+	}
+	if tokenTyp != typ {
+		return 0, ErrInvalidToken
 	}
 
 	userID, ok := claims["user_id"].(float64)
 	if !ok {
-		return 0, errors.New("user_id not found")
+		return 0, ErrInvalidToken
 	}
 
 	return int64(userID), nil
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRefreshTokenRoundTrip(t *testing.T) {
+	t.Setenv("JWT_SECRET", "jwt-test-secret")
+	InitJWT()
+
+	refresh, err := GenerateRefreshToken(7)
+	if err != nil {
+		t.Fatalf("generate refresh: %v", err)
+	}
+	if uid, err := ParseRefreshToken(refresh); err != nil || uid != 7 {
+		t.Fatalf("expected user 7, got %d (%v)", uid, err)
+	}
+
+	// Access- и refresh-токены не взаимозаменяемы
+	access, _ := GenerateJWT(7)
+	if _, err := ParseRefreshToken(access); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected access token to be rejected as refresh, got %v", err)
+	}
+	if _, err := ParseJWT(refresh); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected refresh token to be rejected as access, got %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"math"
+	"sync"
+)
+
+// LuckProtectionConfig - раскрываемая игроку защита от невезения.
+// После LossStreak проигрышей подряд шанс выигрыша растёт на Boost, а ожидаемая
+// стоимость этого бонуса записывается в долг игрока. Во время серии из WinStreak
+// побед шанс снижается ровно настолько, чтобы погасить долг, поэтому RTP в
+// долгую не меняется; неоплаченный остаток ограничен MaxCredit.
+type LuckProtectionConfig struct {
+	Enabled    bool    `json:"enabled"`
+	LossStreak int     `json:"loss_streak"`
+	WinStreak  int     `json:"win_streak"`
+	Boost      float64 `json:"boost"`      // +к шансу выигрыша, 0.05 = +5 п.п.
+	MaxCredit  float64 `json:"max_credit"` // гемов ожидаемого выигрыша в долг на игрока и игру
+}
+
+// DefaultLuckProtectionConfig returns built-in settings, disabled by default
+func DefaultLuckProtectionConfig() LuckProtectionConfig {
+	return LuckProtectionConfig{
+		Enabled:    false,
+		LossStreak: 5,
+		WinStreak:  3,
+		Boost:      0.05,
+		MaxCredit:  5000,
+	}
+}
+
+// LuckProtection tracks streaks and owed expected value per user and game.
+// State is in memory like active Mines Pro games.
+type LuckProtection struct {
+	cfg LuckProtectionConfig
+
+	mu    sync.Mutex
+	state map[luckKey]*luckState
+}
+
+type luckKey struct {
+	userID int64
+	game   string
+}
+
+type luckState struct {
+	losses int
+	wins   int
+	credit float64 // ожидаемый выигрыш, выданный бонусами и ещё не возвращённый
+}
+
+// NewLuckProtection creates the tracker
+func NewLuckProtection(cfg LuckProtectionConfig) *LuckProtection {
+	return &LuckProtection{cfg: cfg, state: make(map[luckKey]*luckState)}
+}
+
+// Config returns settings for disclosure in /game/limits
+func (lp *LuckProtection) Config() LuckProtectionConfig {
+	if lp == nil {
+		return LuckProtectionConfig{}
+	}
+	return lp.cfg
+}
+
+// Resolve decides one play: roll is uniform in [0,1), baseChance is the declared
+// win probability, multiplier the payout including stake. Returns the outcome and
+// the applied chance adjustment (0 if none) for meta.
+func (lp *LuckProtection) Resolve(userID int64, game string, baseChance, multiplier float64, bet int64, roll float64) (bool, float64) {
+	if lp == nil || !lp.cfg.Enabled {
+		return roll < baseChance, 0
+	}
+
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+
+	key := luckKey{userID: userID, game: game}
+	st := lp.state[key]
+	if st == nil {
+		st = &luckState{}
+		lp.state[key] = st
+	}
+
+	// Стоимость одного процентного пункта шанса в гемах ожидаемого выигрыша
+	unit := multiplier * float64(bet)
+
+	adj := 0.0
+	switch {
+	case lp.cfg.LossStreak > 0 && st.losses >= lp.cfg.LossStreak:
+		adj = math.Min(lp.cfg.Boost, 1-baseChance)
+		if room := lp.cfg.MaxCredit - st.credit; adj*unit > room {
+			adj = math.Max(room, 0) / unit
+		}
+	case lp.cfg.WinStreak > 0 && st.wins >= lp.cfg.WinStreak && st.credit > 0:
+		adj = -math.Min(math.Min(lp.cfg.Boost, baseChance), st.credit/unit)
+	}
+	st.credit += adj * unit
+	if st.credit < 1e-9 {
+		st.credit = 0
+	}
+
+	win := roll < baseChance+adj
+	if win {
+		st.wins++
+		st.losses = 0
+	} else {
+		st.losses++
+		st.wins = 0
+	}
+
+	// Без долга серия побед ни на что не влияет - запись не нужна
+	if st.credit == 0 && st.losses == 0 {
+		delete(lp.state, key)
+	}
+
+	return win, adj
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// simulateLuck plays n rounds and returns expected payout with and without
+// protection (by probability, not by realized outcome), total wagered and boosts given.
+func simulateLuck(t *testing.T, lp *LuckProtection, game string, chance, multiplier float64, n int, seed int64) (protected, base, wagered float64, boosts int) {
+	t.Helper()
+	rng := rand.New(rand.NewSource(seed))
+	for i := 0; i < n; i++ {
+		bet := int64(10 + rng.Intn(991))
+		_, adj := lp.Resolve(1, game, chance, multiplier, bet, rng.Float64())
+		if adj > 0 {
+			boosts++
+		}
+		protected += (chance + adj) * multiplier * float64(bet)
+		base += chance * multiplier * float64(bet)
+		wagered += float64(bet)
+	}
+	return protected, base, wagered, boosts
+}
+
+func TestLuckProtectionDisabledByDefault(t *testing.T) {
+	lp := NewLuckProtection(DefaultLuckProtectionConfig())
+	for i := 0; i < 100; i++ {
+		if _, adj := lp.Resolve(1, "coinflip", 0.5, 2, 100, 0.99); adj != 0 {
+			t.Fatalf("expected no adjustment when disabled, got %f", adj)
+		}
+	}
+}
+
+func TestLuckProtectionBoostsAfterLossStreak(t *testing.T) {
+	cfg := DefaultLuckProtectionConfig()
+	cfg.Enabled = true
+	lp := NewLuckProtection(cfg)
+
+	for i := 0; i < cfg.LossStreak; i++ {
+		if _, adj := lp.Resolve(1, "coinflip", 0.5, 2, 100, 0.99); adj != 0 {
+			t.Fatalf("play %d: unexpected adjustment %f before streak", i, adj)
+		}
+	}
+	if _, adj := lp.Resolve(1, "coinflip", 0.5, 2, 100, 0.99); adj != cfg.Boost {
+		t.Fatalf("expected boost %f after %d losses, got %f", cfg.Boost, cfg.LossStreak, adj)
+	}
+}
+
+func TestLuckProtectionKeepsNetRTP(t *testing.T) {
+	cfg := DefaultLuckProtectionConfig()
+	cfg.Enabled = true
+	cfg.LossStreak = 2
+	cfg.WinStreak = 1
+
+	for _, tc := range []struct {
+		name       string
+		chance     float64
+		multiplier float64
+	}{
+		{"coinflip", 0.5, 2},
+		{"mines", DefaultMinesConfig().WinChance(), DefaultMinesConfig().Multiplier()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			lp := NewLuckProtection(cfg)
+			protected, base, wagered, boosts := simulateLuck(t, lp, tc.name, tc.chance, tc.multiplier, 200000, 42)
+			if boosts == 0 {
+				t.Fatal("expected some boosted plays")
+			}
+
+			// Разница - только непогашенный долг, он ограничен MaxCredit
+			if diff := protected - base; diff < -1e-6 || diff > cfg.MaxCredit+1e-6 {
+				t.Fatalf("outstanding credit %f outside [0, %f]", diff, cfg.MaxCredit)
+			}
+			if rtpDiff := math.Abs(protected-base) / wagered; rtpDiff > 1e-4 {
+				t.Fatalf("net RTP changed by %.6f (protected %.6f, base %.6f)", rtpDiff, protected/wagered, base/wagered)
+			}
+		})
+	}
+}
+
+func TestLuckProtectionRepaysCreditOnWinStreak(t *testing.T) {
+	cfg := DefaultLuckProtectionConfig()
+	cfg.Enabled = true
+	cfg.LossStreak = 1
+	cfg.WinStreak = 1
+	lp := NewLuckProtection(cfg)
+
+	lp.Resolve(1, "coinflip", 0.5, 2, 100, 0.99) // loss
+	_, boost := lp.Resolve(1, "coinflip", 0.5, 2, 100, 0.0)
+	if boost <= 0 {
+		t.Fatalf("expected boost, got %f", boost)
+	}
+
+	_, repay := lp.Resolve(1, "coinflip", 0.5, 2, 100, 0.99)
+	if math.Abs(repay+boost) > 1e-9 {
+		t.Fatalf("expected repayment %f, got %f", -boost, repay)
+	}
+	if st, ok := lp.state[luckKey{userID: 1, game: "coinflip"}]; ok && st.credit != 0 {
+		t.Fatalf("expected credit repaid, got %f", st.credit)
+	}
+}
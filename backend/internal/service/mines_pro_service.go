@@ -3,44 +3,122 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log"
 	"sync"
 	"time"
 
+	"telegram_webapp/internal/domain"
 	"telegram_webapp/internal/game"
+	"telegram_webapp/internal/repository"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-// MinesProService manages active Mines Pro games
+// ErrRevealTooFast is returned when reveals come faster than the configured interval
+var ErrRevealTooFast = errors.New("reveal_too_fast")
+
+var (
+	// ErrNoActiveGame - у пользователя нет начатой игры
+	ErrNoActiveGame = errors.New("no active game")
+	// ErrActiveGame - предыдущая игра того же типа ещё не закончена
+	ErrActiveGame = errors.New("you already have an active game")
+)
+
+// DefaultMinesProAbandonTTL - игра без действий дольше этого считается брошенной
+const DefaultMinesProAbandonTTL = time.Hour
+
+// MinesProService manages active Mines Pro games. Games are kept in memory and
+// persisted to mines_pro_games, so a restart doesn't lose them or the locked bet.
 type MinesProService struct {
 	db          *pgxpool.Pool
-	activeGames map[int64]*game.MinesPvEGame // userID -> game
+	games       *repository.MinesProGameRepository // nil - без сохранения (тесты)
+	activeGames map[int64]*game.MinesPvEGame       // userID -> game
 	mu          sync.RWMutex
+	rng         game.Randomizer
+	fair        *FairRNG
+	fairHolds   map[string]func() // gameID -> release сида активной игры
+
+	// Минимальный интервал между открытиями в одной игре (0 - без ограничения)
+	minRevealInterval time.Duration
+	now               func() time.Time
+
+	abandonTTL time.Duration
+	// onAbandon is called for games settled by the sweeper (e.g. to record history)
+	onAbandon func(g *game.MinesPvEGame)
 }
 
 // NewMinesProService creates a new Mines Pro service
 func NewMinesProService(db *pgxpool.Pool) *MinesProService {
 	s := &MinesProService{
 		db:          db,
+		games:       repository.NewMinesProGameRepository(db),
 		activeGames: make(map[int64]*game.MinesPvEGame),
+		rng:         game.NewCryptoRandomizer(),
+		fairHolds:   make(map[string]func()),
+		now:         time.Now,
+		abandonTTL:  DefaultMinesProAbandonTTL,
 	}
 
-	// Start cleanup goroutine for expired games
-	go s.cleanupExpiredGames()
+	// Restore active games, then settle abandoned ones
+	go s.sweepAbandonedGames()
 
 	return s
 }
 
-// StartGame starts a new Mines Pro game
-func (s *MinesProService) StartGame(ctx context.Context, userID int64, bet int64, minesCount int) (*game.MinesPvEGame, error) {
+// SetRandomizer replaces the source of mine positions for new games
+func (s *MinesProService) SetRandomizer(rng game.Randomizer) {
+	if rng != nil {
+		s.rng = rng
+	}
+}
+
+// SetFairRNG makes mine positions provably fair when fr is enabled
+func (s *MinesProService) SetFairRNG(fr *FairRNG) {
+	s.fair = fr
+}
+
+// SetMinRevealInterval limits how often cells can be revealed in one game.
+// Scripted clients clearing the board instantly get ErrRevealTooFast.
+func (s *MinesProService) SetMinRevealInterval(d time.Duration) {
+	s.minRevealInterval = d
+}
+
+// SetAbandonTTL sets after how long without a reveal a game is settled by the sweeper
+func (s *MinesProService) SetAbandonTTL(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.abandonTTL = d
+}
+
+// SetOnAbandon sets the callback for games settled by the sweeper
+func (s *MinesProService) SetOnAbandon(fn func(g *game.MinesPvEGame)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onAbandon = fn
+}
+
+// StartGame starts a new Mines Pro game, the bet is taken in currency and the
+// payout is credited in the same currency
+func (s *MinesProService) StartGame(ctx context.Context, userID int64, bet int64, minesCount int, currency domain.Currency) (*game.MinesPvEGame, error) {
+	if _, err := ParseGameCurrency(string(currency)); err != nil {
+		return nil, err
+	}
+	col := currency.BalanceColumn()
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	// Check if user already has an active game
-	if existing, ok := s.activeGames[userID]; ok && existing.IsActive() {
-		return nil, errors.New("you already have an active game")
+	if existing, err := s.activeGameLocked(ctx, userID); err != nil {
+		return nil, err
+	} else if existing != nil {
+		return nil, repository.ErrActiveMinesProGame
 	}
 
 	// Start transaction
@@ -52,117 +130,296 @@ func (s *MinesProService) StartGame(ctx context.Context, userID int64, bet int64
 
 	// Check and deduct balance
 	var balance int64
-	if err := tx.QueryRow(ctx, `SELECT gems FROM users WHERE id=$1 FOR UPDATE`, userID).Scan(&balance); err != nil {
+	if err := tx.QueryRow(ctx, fmt.Sprintf(`SELECT %s FROM users WHERE id=$1 FOR UPDATE`, col), userID).Scan(&balance); err != nil {
 		return nil, err
 	}
 	if balance < bet {
-		return nil, errors.New("insufficient balance")
+		return nil, ErrInsufficientBalance
 	}
 
-	if _, err := tx.Exec(ctx, `UPDATE users SET gems = gems - $1 WHERE id=$2`, bet, userID); err != nil {
+	if _, err := tx.Exec(ctx, fmt.Sprintf(`UPDATE users SET %[1]s = %[1]s - $1 WHERE id=$2`, col), bet, userID); err != nil {
 		return nil, err
 	}
 
 	// Create game
 	gameID := uuid.New().String()[:8]
-	g, err := game.NewMinesPvEGame(gameID, userID, bet, minesCount)
+	rng, fair, release := s.fair.BeginHeld(userID)
+	if rng == nil {
+		rng = s.rng
+	}
+	g, err := game.NewMinesPvEGame(gameID, userID, bet, minesCount, rng)
 	if err != nil {
+		release()
 		return nil, err
 	}
+	g.Currency = string(currency)
+	g.Fair = fair
+
+	// Игра сохраняется вместе со списанием ставки
+	if s.games != nil {
+		if err := s.games.CreateWithTx(ctx, tx, g.Snapshot()); err != nil {
+			release()
+			return nil, err
+		}
+	}
 
 	if err := tx.Commit(ctx); err != nil {
+		release()
 		return nil, err
 	}
 
 	s.activeGames[userID] = g
+	s.fairHolds[g.ID] = release
 	return g, nil
 }
 
-// GetActiveGame returns user's active game
-func (s *MinesProService) GetActiveGame(userID int64) *game.MinesPvEGame {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// GetActiveGame returns user's active game, loading it from the database if it
+// isn't in memory (e.g. right after a restart)
+func (s *MinesProService) GetActiveGame(ctx context.Context, userID int64) *game.MinesPvEGame {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	g, ok := s.activeGames[userID]
-	if !ok || !g.IsActive() {
+	g, err := s.activeGameLocked(ctx, userID)
+	if err != nil {
+		log.Printf("MinesProService: failed to load game of user=%d: %v", userID, err)
 		return nil
 	}
 	return g
 }
 
+// activeGameLocked - caller must hold s.mu
+func (s *MinesProService) activeGameLocked(ctx context.Context, userID int64) (*game.MinesPvEGame, error) {
+	if g, ok := s.activeGames[userID]; ok && g.IsActive() {
+		return g, nil
+	}
+	if s.games == nil {
+		return nil, nil
+	}
+
+	snap, err := s.games.GetActiveByUser(ctx, userID)
+	if err != nil || snap == nil {
+		return nil, err
+	}
+	g, err := game.RestoreMinesPvEGame(*snap)
+	if err != nil {
+		return nil, err
+	}
+	s.activeGames[userID] = g
+	return g, nil
+}
+
 // RevealCell reveals a cell in user's active game
 func (s *MinesProService) RevealCell(ctx context.Context, userID int64, cell int) (hitMine bool, g *game.MinesPvEGame, err error) {
-	s.mu.Lock()
-	g, ok := s.activeGames[userID]
-	if !ok || !g.IsActive() {
-		s.mu.Unlock()
-		return false, nil, errors.New("no active game")
+	g = s.GetActiveGame(ctx, userID)
+	if g == nil {
+		return false, nil, ErrNoActiveGame
+	}
+
+	if !g.TryRevealSlot(s.minRevealInterval, s.now()) {
+		return false, g, ErrRevealTooFast
 	}
-	s.mu.Unlock()
 
 	hitMine, err = g.Reveal(cell)
 	if err != nil {
 		return false, g, err
 	}
 
-	// If game is over (exploded or all revealed), clean up
-	if !g.IsActive() {
-		s.mu.Lock()
-		delete(s.activeGames, userID)
-		s.mu.Unlock()
-
-		// If auto-cashed out (revealed all), credit winnings
-		if g.Status == game.MinesProStatusCashedOut {
-			_, _ = s.db.Exec(ctx, `UPDATE users SET gems = gems + $1 WHERE id=$2`, g.WinAmount, userID)
+	if g.IsActive() {
+		if s.games != nil {
+			if err := s.games.UpdateProgress(ctx, g.Snapshot()); err != nil {
+				log.Printf("MinesProService: failed to save game %s: %v", g.ID, err)
+			}
 		}
+		return hitMine, g, nil
 	}
 
+	// Game is over (exploded or all revealed): auto-cashout credits winnings.
+	// If settling fails the finished game stays in memory and the next request
+	// reloads the still active one from the database.
+	var payout int64
+	if g.Status == game.MinesProStatusCashedOut {
+		payout = g.WinAmount
+	}
+	if _, err := s.settle(ctx, g, payout); err != nil {
+		return hitMine, g, err
+	}
+	s.mu.Lock()
+	s.removeGameLocked(g)
+	s.mu.Unlock()
 	return hitMine, g, nil
 }
 
 // CashOut cashes out user's active game
 func (s *MinesProService) CashOut(ctx context.Context, userID int64) (*game.MinesPvEGame, error) {
-	s.mu.Lock()
-	g, ok := s.activeGames[userID]
-	if !ok || !g.IsActive() {
-		s.mu.Unlock()
-		return nil, errors.New("no active game")
+	g := s.GetActiveGame(ctx, userID)
+	if g == nil {
+		return nil, ErrNoActiveGame
 	}
-	s.mu.Unlock()
 
 	winAmount, err := g.CashOut()
 	if err != nil {
 		return g, err
 	}
 
-	// Credit winnings
-	if _, err := s.db.Exec(ctx, `UPDATE users SET gems = gems + $1 WHERE id=$2`, winAmount, userID); err != nil {
+	// Credit winnings; on failure the game is reloaded as in RevealCell
+	settled, err := s.settle(ctx, g, winAmount)
+	if err != nil {
 		return g, err
 	}
 
 	// Clean up
 	s.mu.Lock()
-	delete(s.activeGames, userID)
+	s.removeGameLocked(g)
 	s.mu.Unlock()
 
+	if !settled {
+		return g, ErrNoActiveGame
+	}
 	return g, nil
 }
 
-// cleanupExpiredGames removes games older than 1 hour
-func (s *MinesProService) cleanupExpiredGames() {
-	ticker := time.NewTicker(5 * time.Minute)
+// removeGameLocked forgets the game and frees its fair seed - caller must hold s.mu
+func (s *MinesProService) removeGameLocked(g *game.MinesPvEGame) {
+	if cur, ok := s.activeGames[g.UserID]; ok && cur.ID == g.ID {
+		delete(s.activeGames, g.UserID)
+	}
+	if release, ok := s.fairHolds[g.ID]; ok {
+		release()
+		delete(s.fairHolds, g.ID)
+	}
+}
+
+// settle stores the finished game and pays amount to the player in the game's
+// currency in one transaction. Returns false when the game had already been
+// settled elsewhere, in which case nothing is paid. Without a repository the
+// game isn't stored and is always settled here.
+func (s *MinesProService) settle(ctx context.Context, g *game.MinesPvEGame, amount int64) (bool, error) {
+	col := domain.Currency(g.Currency).BalanceColumn()
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if s.games != nil {
+		finished, err := s.games.FinishWithTx(ctx, tx, g.Snapshot())
+		if err != nil || !finished {
+			return false, err
+		}
+	}
+	if amount > 0 {
+		if _, err := tx.Exec(ctx, fmt.Sprintf(`UPDATE users SET %[1]s = %[1]s + $1 WHERE id=$2`, col), amount, g.UserID); err != nil {
+			return false, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// sweepAbandonedGames restores active games after a restart and then settles
+// games without a reveal for abandonTTL
+func (s *MinesProService) sweepAbandonedGames() {
+	s.restoreActiveGames()
+
+	ticker := time.NewTicker(time.Minute)
 	defer ticker.Stop()
 
 	for range ticker.C {
-		s.mu.Lock()
-		now := time.Now()
-		for userID, g := range s.activeGames {
-			// Games older than 1 hour are considered abandoned
-			if now.Sub(g.CreatedAt) > time.Hour {
-				delete(s.activeGames, userID)
-			}
+		s.expireAbandoned(time.Now())
+	}
+}
+
+// restoreActiveGames loads active games from the database into memory
+func (s *MinesProService) restoreActiveGames() {
+	if s.games == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	snaps, err := s.games.ListActive(ctx)
+	if err != nil {
+		log.Printf("MinesProService: failed to restore games: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, snap := range snaps {
+		g, err := game.RestoreMinesPvEGame(snap)
+		if err != nil {
+			log.Printf("MinesProService: skipping game %s: %v", snap.ID, err)
+			continue
+		}
+		if _, ok := s.activeGames[g.UserID]; !ok {
+			s.activeGames[g.UserID] = g
 		}
+	}
+	if len(snaps) > 0 {
+		log.Printf("MinesProService: restored %d active games", len(snaps))
+	}
+}
+
+// expireAbandoned cashes out (or refunds, if nothing was revealed) games idle
+// since before now-abandonTTL. A game leaves memory only once it is settled; if
+// settling fails it is put back as it was and retried on the next sweep.
+func (s *MinesProService) expireAbandoned(now time.Time) int {
+	s.mu.Lock()
+	var expired []*game.MinesPvEGame
+	for _, g := range s.activeGames {
+		if now.Sub(g.IdleSince()) > s.abandonTTL {
+			expired = append(expired, g)
+		}
+	}
+	onAbandon := s.onAbandon
+	s.mu.Unlock()
+
+	settled := 0
+	for _, g := range expired {
+		snap := g.Snapshot()
+		amount, ok := g.Abandon()
+		if !ok {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		done, err := s.settle(ctx, g, amount)
+		cancel()
+		if err != nil {
+			log.Printf("MinesProService: failed to settle abandoned game %s of user=%d: %v", g.ID, g.UserID, err)
+			s.putBack(g, snap)
+			continue
+		}
+		s.mu.Lock()
+		s.removeGameLocked(g)
 		s.mu.Unlock()
+		if !done {
+			continue
+		}
+		settled++
+		log.Printf("MinesProService: abandoned game %s of user=%d settled as %s, paid %d", g.ID, g.UserID, g.Status, amount)
+		if onAbandon != nil {
+			onAbandon(g)
+		}
+	}
+	return settled
+}
+
+// putBack replaces a game whose settlement failed with its state from before
+// the attempt, unless the player has moved on to another game
+func (s *MinesProService) putBack(g *game.MinesPvEGame, snap game.MinesPvESnapshot) {
+	restored, err := game.RestoreMinesPvEGame(snap)
+	if err != nil {
+		log.Printf("MinesProService: failed to restore game %s: %v", g.ID, err)
+		return
+	}
+	restored.Fair = g.Fair
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cur, ok := s.activeGames[g.UserID]; ok && cur.ID == g.ID {
+		s.activeGames[g.UserID] = restored
 	}
 }
 
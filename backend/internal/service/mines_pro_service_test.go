@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"telegram_webapp/internal/game"
+)
+
+// newTestMinesPro builds the service without a database: reveals that don't
+// finish the game never touch the db.
+func newTestMinesPro(t *testing.T, interval time.Duration, now *time.Time) (*MinesProService, []int) {
+	t.Helper()
+	g, err := game.NewMinesPvEGame("test", 1, 100, 1, nil)
+	if err != nil {
+		t.Fatalf("new game: %v", err)
+	}
+
+	mines := make(map[int]bool)
+	for _, m := range g.Mines {
+		mines[m] = true
+	}
+	var safe []int
+	for cell := 0; cell < g.BoardSize; cell++ {
+		if !mines[cell] {
+			safe = append(safe, cell)
+		}
+	}
+
+	s := &MinesProService{
+		activeGames:       map[int64]*game.MinesPvEGame{1: g},
+		minRevealInterval: interval,
+		now:               func() time.Time { return *now },
+	}
+	return s, safe
+}
+
+func TestMinesProRevealThrottled(t *testing.T) {
+	now := time.Now()
+	s, safe := newTestMinesPro(t, 200*time.Millisecond, &now)
+	ctx := context.Background()
+
+	if _, _, err := s.RevealCell(ctx, 1, safe[0]); err != nil {
+		t.Fatalf("first reveal: %v", err)
+	}
+
+	now = now.Add(50 * time.Millisecond)
+	if _, _, err := s.RevealCell(ctx, 1, safe[1]); !errors.Is(err, ErrRevealTooFast) {
+		t.Fatalf("expected ErrRevealTooFast for rapid reveal, got %v", err)
+	}
+
+	// Отклонённая попытка не сдвигает окно
+	now = now.Add(150 * time.Millisecond)
+	if _, g, err := s.RevealCell(ctx, 1, safe[1]); err != nil {
+		t.Fatalf("reveal after interval: %v", err)
+	} else if len(g.RevealedCells) != 2 {
+		t.Fatalf("expected 2 revealed cells, got %d", len(g.RevealedCells))
+	}
+}
+
+func TestMinesProRevealUnlimitedByDefault(t *testing.T) {
+	now := time.Now()
+	s, safe := newTestMinesPro(t, 0, &now)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		if _, _, err := s.RevealCell(ctx, 1, safe[i]); err != nil {
+			t.Fatalf("reveal %d: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"telegram_webapp/internal/domain"
+)
+
+// Notifier delivers notifications to users and admins. The admin bot implements
+// it over Telegram; other channels (email, push) can be plugged in the same way.
+type Notifier interface {
+	// NotifyUser sends a message if the user has the category enabled
+	NotifyUser(ctx context.Context, userID int64, category domain.NotificationCategory, message string)
+	// NotifyAdmins sends an HTML message to all admins
+	NotifyAdmins(ctx context.Context, message string)
+}
+
+// NopNotifier drops all notifications (admin bot disabled)
+type NopNotifier struct{}
+
+func (NopNotifier) NotifyUser(context.Context, int64, domain.NotificationCategory, string) {}
+func (NopNotifier) NotifyAdmins(context.Context, string)                                   {}
+
+// NotifyWithdrawalCreated asks admins to review a new withdrawal request
+func NotifyWithdrawalCreated(ctx context.Context, n Notifier, w *WithdrawalNotification) {
+	n.NotifyAdmins(ctx, fmt.Sprintf(`<b>Новый запрос на вывод!</b>
+
+Пользователь: @%s (TG: %d)
+Сумма: %d coins (%.4f TON)
+Кошелек: <code>%s</code>
+
+ID: #%d
+
+/approve %d - одобрить
+/reject %d причина - отклонить`,
+		w.Username, w.TgID, w.CoinsAmount, w.TonAmount, w.WalletAddress, w.ID, w.ID, w.ID))
+}
+
+// NotifyWithdrawalApproved tells the user their withdrawal was approved
+func NotifyWithdrawalApproved(ctx context.Context, n Notifier, w *WithdrawalNotification) {
+	n.NotifyUser(ctx, w.UserID, domain.NotificationWithdrawal,
+		fmt.Sprintf("Ваш вывод #%d на %d coins (%.4f TON) одобрен.", w.ID, w.CoinsAmount, w.TonAmount))
+}
+
+// NotifyWithdrawalRejected tells the user their withdrawal was rejected and refunded
+func NotifyWithdrawalRejected(ctx context.Context, n Notifier, w *WithdrawalNotification, reason string) {
+	n.NotifyUser(ctx, w.UserID, domain.NotificationWithdrawal,
+		fmt.Sprintf("Ваш вывод #%d отклонён: %s\nСредства возвращены на баланс.", w.ID, reason))
+}
+
+// NotifyQuestCompleted reminds the user to claim a completed quest reward
+func NotifyQuestCompleted(ctx context.Context, n Notifier, userID int64, quest *domain.Quest) {
+	n.NotifyUser(ctx, userID, domain.NotificationQuest,
+		fmt.Sprintf("Квест «%s» выполнен! Заберите награду в приложении.", quest.Title))
+}
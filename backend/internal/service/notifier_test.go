@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"telegram_webapp/internal/domain"
+)
+
+type sentNotification struct {
+	userID   int64 // 0 - админам
+	category domain.NotificationCategory
+	message  string
+}
+
+type mockNotifier struct {
+	sent []sentNotification
+}
+
+func (m *mockNotifier) NotifyUser(_ context.Context, userID int64, category domain.NotificationCategory, message string) {
+	m.sent = append(m.sent, sentNotification{userID: userID, category: category, message: message})
+}
+
+func (m *mockNotifier) NotifyAdmins(_ context.Context, message string) {
+	m.sent = append(m.sent, sentNotification{message: message})
+}
+
+func TestWithdrawalNotifications(t *testing.T) {
+	n := &mockNotifier{}
+	w := &WithdrawalNotification{ID: 7, UserID: 42, Username: "alice", TgID: 1001, WalletAddress: "UQabc", CoinsAmount: 50, TonAmount: 4.9}
+	ctx := context.Background()
+
+	NotifyWithdrawalCreated(ctx, n, w)
+	NotifyWithdrawalApproved(ctx, n, w)
+	NotifyWithdrawalRejected(ctx, n, w, "suspicious activity")
+
+	if len(n.sent) != 3 {
+		t.Fatalf("expected 3 notifications, got %d", len(n.sent))
+	}
+
+	created := n.sent[0]
+	if created.userID != 0 || !strings.Contains(created.message, "/approve 7") || !strings.Contains(created.message, "@alice") {
+		t.Fatalf("unexpected admin notification: %+v", created)
+	}
+	for _, s := range n.sent[1:] {
+		if s.userID != 42 || s.category != domain.NotificationWithdrawal {
+			t.Fatalf("expected withdrawal notification to user 42, got %+v", s)
+		}
+	}
+	if !strings.Contains(n.sent[2].message, "suspicious activity") {
+		t.Fatalf("reject reason missing: %q", n.sent[2].message)
+	}
+}
+
+func TestNotifyQuestCompleted(t *testing.T) {
+	n := &mockNotifier{}
+	NotifyQuestCompleted(context.Background(), n, 42, &domain.Quest{Title: "Сыграй 10 игр"})
+
+	if len(n.sent) != 1 || n.sent[0].userID != 42 || n.sent[0].category != domain.NotificationQuest {
+		t.Fatalf("unexpected notifications: %+v", n.sent)
+	}
+	if !strings.Contains(n.sent[0].message, "Сыграй 10 игр") {
+		t.Fatalf("quest title missing: %q", n.sent[0].message)
+	}
+
+	// NopNotifier ничего не делает и не паникует
+	NotifyQuestCompleted(context.Background(), NopNotifier{}, 42, &domain.Quest{Title: "x"})
+}
@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/logger"
+	"telegram_webapp/internal/repository"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrQuestNotClaimable - квест не выполнен или не принадлежит пользователю
+var ErrQuestNotClaimable = errors.New("quest reward cannot be claimed")
+
+// ErrQuestAlreadyClaimed - награда уже получена (в том числе параллельным запросом)
+var ErrQuestAlreadyClaimed = errors.New("quest reward already claimed")
+
+// Очередь обновлений квестов после игр
+const (
+	questQueueSize    = 1024
+	questRetries      = 3
+	questRetryBackoff = 200 * time.Millisecond
+	questApplyTimeout = 10 * time.Second
+	// questEnqueueTimeout - сколько игра ждёт места в заполненной очереди
+	questEnqueueTimeout = 2 * time.Second
+)
+
+// questEvent - сыгранная игра, по которой обновляется прогресс квестов
+type questEvent struct {
+	userID    int64
+	gameType  domain.GameType
+	result    domain.GameResult
+	currency  domain.Currency
+	betAmount int64
+	winAmount int64 // чистый выигрыш, отрицательный при проигрыше
+}
+
+// QuestService advances quest progress after games. Updates go through a queue
+// handled by one worker, so progress of one user is never updated concurrently;
+// failed repository calls are retried. The caller waits only when the queue is
+// full, at most enqueueTimeout, after which the update is dropped and logged.
+type QuestService struct {
+	db           *pgxpool.Pool
+	quests       *repository.QuestRepository
+	transactions *repository.TransactionRepository
+	bonus        *repository.BonusWageringRepository
+	queue        chan questEvent
+
+	enqueueTimeout       time.Duration
+	bonusWagerMultiplier int
+
+	mu       sync.RWMutex
+	notifier Notifier
+}
+
+// NewQuestService creates the service and starts its worker
+func NewQuestService(db *pgxpool.Pool) *QuestService {
+	s := &QuestService{
+		db:           db,
+		quests:       repository.NewQuestRepository(db),
+		transactions: repository.NewTransactionRepository(db),
+		bonus:        repository.NewBonusWageringRepository(db),
+		queue:        make(chan questEvent, questQueueSize),
+		notifier:     NopNotifier{},
+
+		enqueueTimeout:       questEnqueueTimeout,
+		bonusWagerMultiplier: domain.DefaultBonusWagerMultiplier,
+	}
+	go s.run()
+	return s
+}
+
+// SetNotifier sets where "quest completed" notifications go
+func (s *QuestService) SetNotifier(n Notifier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifier = n
+}
+
+// SetBonusWagerMultiplier sets wagering requirement for quest coin rewards (0 = none).
+// Call before serving requests.
+func (s *QuestService) SetBonusWagerMultiplier(n int) {
+	s.bonusWagerMultiplier = n
+}
+
+// OnGamePlayed queues a quest progress update for a finished game. betAmount and
+// winAmount (net, negative on a loss) count towards spend_gems/earn_gems for gem games.
+// Blocks while the queue is full, up to enqueueTimeout or until ctx is done.
+func (s *QuestService) OnGamePlayed(ctx context.Context, userID int64, gameType domain.GameType, result domain.GameResult, currency domain.Currency, betAmount, winAmount int64) {
+	if s == nil {
+		return
+	}
+	ev := questEvent{userID: userID, gameType: gameType, result: result, currency: currency, betAmount: betAmount, winAmount: winAmount}
+	select {
+	case s.queue <- ev:
+		return
+	default:
+	}
+
+	// Очередь переполнена - ждём воркер, а не обновляем прогресс в обход него
+	timer := time.NewTimer(s.enqueueTimeout)
+	defer timer.Stop()
+	select {
+	case s.queue <- ev:
+	case <-timer.C:
+		logger.Error("quest queue full, progress update dropped", "user_id", userID, "game_type", gameType)
+	case <-ctx.Done():
+		logger.Error("quest progress update dropped", "error", ctx.Err(), "user_id", userID, "game_type", gameType)
+	}
+}
+
+func (s *QuestService) run() {
+	for ev := range s.queue {
+		s.apply(ev)
+	}
+}
+
+// apply increments every matching active quest, retrying each step on errors
+func (s *QuestService) apply(ev questEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), questApplyTimeout)
+	defer cancel()
+
+	var quests []*domain.Quest
+	if err := retryQuest(ctx, func() (err error) {
+		quests, err = s.quests.GetActiveQuests(ctx)
+		return err
+	}); err != nil {
+		logger.Error("failed to load quests", "error", err, "user_id", ev.userID)
+		return
+	}
+
+	s.mu.RLock()
+	notifier := s.notifier
+	s.mu.RUnlock()
+
+	for _, quest := range quests {
+		increment := questIncrement(quest, ev)
+		if increment <= 0 {
+			continue
+		}
+
+		var completed bool
+		if err := retryQuest(ctx, func() (err error) {
+			completed, err = s.quests.IncrementProgress(ctx, ev.userID, quest, increment)
+			return err
+		}); err != nil {
+			logger.Error("failed to update quest progress", "error", err, "user_id", ev.userID, "quest_id", quest.ID)
+			continue
+		}
+		if completed {
+			NotifyQuestCompleted(ctx, notifier, ev.userID, quest)
+		}
+	}
+}
+
+// QuestClaim - результат получения награды за квест
+type QuestClaim struct {
+	Reward domain.QuestReward // начислено с учётом happy hour
+	Gems   int64              // балансы после начисления
+	Coins  int64
+	GK     int64
+}
+
+// ClaimReward marks the user's completed quest as claimed and credits its gems,
+// coins and GK reward (multiplied by boost when > 1) in one transaction, recording
+// a quest_reward transaction per credited currency. Coins are promotional and get a
+// wagering requirement in the same transaction. Of concurrent claims exactly one
+// credits, the others get ErrQuestAlreadyClaimed.
+func (s *QuestService) ClaimReward(ctx context.Context, userID, userQuestID int64, boost float64) (*QuestClaim, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	quest, err := s.quests.ClaimRewardWithTx(ctx, tx, userID, userQuestID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Флаг не поставлен: выясняем, уже получена награда или квест не выполнен
+		claimed, cerr := s.quests.RewardClaimedWithTx(ctx, tx, userID, userQuestID)
+		if cerr == nil && claimed {
+			return nil, ErrQuestAlreadyClaimed
+		}
+		if cerr != nil && !errors.Is(cerr, pgx.ErrNoRows) {
+			return nil, cerr
+		}
+		return nil, ErrQuestNotClaimable
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	reward := questReward(quest, boost)
+	claim := &QuestClaim{Reward: reward}
+	err = tx.QueryRow(ctx,
+		`UPDATE users SET gems = gems + $1, coins = coins + $2, gk = gk + $3
+		 WHERE id = $4
+		 RETURNING gems, coins, gk`,
+		reward.Gems, reward.Coins, reward.GK, userID,
+	).Scan(&claim.Gems, &claim.Coins, &claim.GK)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, credit := range []struct {
+		currency domain.Currency
+		amount   int64
+	}{
+		{domain.CurrencyGems, reward.Gems},
+		{domain.CurrencyCoins, reward.Coins},
+		{domain.CurrencyGK, reward.GK},
+	} {
+		if credit.amount <= 0 {
+			continue
+		}
+		meta := map[string]interface{}{
+			"quest_id":      quest.ID,
+			"user_quest_id": userQuestID,
+			"currency":      string(credit.currency),
+		}
+		if boost > 1 {
+			meta["happy_hour"] = boost
+		}
+		if err := s.transactions.CreateWithTx(ctx, tx, &domain.Transaction{
+			UserID: userID,
+			Type:   "quest_reward",
+			Amount: credit.amount,
+			Meta:   meta,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.bonus.GrantWithTx(ctx, tx, userID, domain.CurrencyCoins, reward.Coins, s.bonusWagerMultiplier, "quest_reward"); err != nil {
+		return nil, err
+	}
+
+	if err := s.quests.SetClaimedWithTx(ctx, tx, userQuestID, reward); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return claim, nil
+}
+
+// questReward applies the happy hour boost to every reward currency
+func questReward(quest *domain.Quest, boost float64) domain.QuestReward {
+	reward := domain.QuestReward{Gems: quest.RewardGems, Coins: quest.RewardCoins, GK: quest.RewardGK}
+	if boost > 1 {
+		reward.Gems = int64(float64(reward.Gems) * boost)
+		reward.Coins = int64(float64(reward.Coins) * boost)
+		reward.GK = int64(float64(reward.GK) * boost)
+	}
+	return reward
+}
+
+// questIncrement returns how much the game advances the quest (0 - not at all)
+func questIncrement(quest *domain.Quest, ev questEvent) int {
+	if quest.GameType != nil && *quest.GameType != "any" && *quest.GameType != string(ev.gameType) {
+		return 0
+	}
+
+	switch quest.ActionType {
+	case domain.ActionTypePlay:
+		return 1
+	case domain.ActionTypeWin:
+		if ev.result == domain.GameResultWin {
+			return 1
+		}
+	case domain.ActionTypeLose:
+		if ev.result == domain.GameResultLose {
+			return 1
+		}
+	case domain.ActionTypeSpendGems:
+		if ev.currency == domain.CurrencyGems && ev.betAmount > 0 {
+			return int(ev.betAmount)
+		}
+	case domain.ActionTypeEarnGems:
+		if ev.currency == domain.CurrencyGems && ev.winAmount > 0 {
+			return int(ev.winAmount)
+		}
+	}
+	return 0
+}
+
+// retryQuest runs fn up to questRetries times with a growing pause
+func retryQuest(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 0; attempt < questRetries; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(questRetryBackoff * time.Duration(attempt+1)):
+		}
+	}
+	return err
+}
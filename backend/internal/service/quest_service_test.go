@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestQuestIncrement(t *testing.T) {
+	dice, anyGame := "dice", "any"
+	win := questEvent{gameType: domain.GameTypeDice, result: domain.GameResultWin, currency: domain.CurrencyGems, betAmount: 100, winAmount: 80}
+	lose := questEvent{gameType: domain.GameTypeDice, result: domain.GameResultLose, currency: domain.CurrencyGems, betAmount: 100, winAmount: -100}
+	coins := questEvent{gameType: domain.GameTypeDice, result: domain.GameResultWin, currency: domain.CurrencyCoins, betAmount: 100, winAmount: 80}
+	wheel := questEvent{gameType: domain.GameTypeWheel, result: domain.GameResultWin, currency: domain.CurrencyGems, betAmount: 100, winAmount: 80}
+
+	cases := []struct {
+		action   domain.ActionType
+		gameType *string
+		ev       questEvent
+		want     int
+	}{
+		{domain.ActionTypePlay, nil, lose, 1},
+		{domain.ActionTypePlay, &anyGame, wheel, 1},
+		{domain.ActionTypePlay, &dice, wheel, 0},
+		{domain.ActionTypeWin, &dice, win, 1},
+		{domain.ActionTypeWin, &dice, lose, 0},
+		{domain.ActionTypeLose, nil, lose, 1},
+		{domain.ActionTypeLose, nil, win, 0},
+		// Gems считаются суммой, а не количеством игр
+		{domain.ActionTypeSpendGems, nil, lose, 100},
+		{domain.ActionTypeSpendGems, nil, coins, 0},
+		{domain.ActionTypeEarnGems, nil, win, 80},
+		{domain.ActionTypeEarnGems, nil, lose, 0},
+		{domain.ActionTypeEarnGems, nil, coins, 0},
+	}
+	for _, tc := range cases {
+		quest := &domain.Quest{ActionType: tc.action, GameType: tc.gameType}
+		if got := questIncrement(quest, tc.ev); got != tc.want {
+			t.Fatalf("%s %+v: expected %d, got %d", tc.action, tc.ev, tc.want, got)
+		}
+	}
+}
+
+func TestQuestRewardBoost(t *testing.T) {
+	quest := &domain.Quest{RewardGems: 100, RewardCoins: 3, RewardGK: 0}
+
+	if got := questReward(quest, 1); got != (domain.QuestReward{Gems: 100, Coins: 3}) {
+		t.Fatalf("expected plain reward, got %+v", got)
+	}
+	if got := questReward(quest, 1.5); got != (domain.QuestReward{Gems: 150, Coins: 4}) {
+		t.Fatalf("expected boosted reward, got %+v", got)
+	}
+}
+
+// Integration-style test: runs only if TEST_DATABASE_URL env is set.
+func TestOnGamePlayedWaitsForFullQueue(t *testing.T) {
+	s := &QuestService{queue: make(chan questEvent, 1), enqueueTimeout: 50 * time.Millisecond}
+	s.OnGamePlayed(context.Background(), 1, domain.GameTypeDice, domain.GameResultWin, domain.CurrencyGems, 10, 10)
+
+	// Воркер освободил место - событие попадает в очередь, а не обрабатывается в обход неё
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		<-s.queue
+	}()
+	s.OnGamePlayed(context.Background(), 2, domain.GameTypeDice, domain.GameResultLose, domain.CurrencyGems, 10, -10)
+	if ev := <-s.queue; ev.userID != 2 {
+		t.Fatalf("expected the second event to be queued, got user %d", ev.userID)
+	}
+
+	// Воркер не успел - событие отбрасывается по таймауту
+	s.OnGamePlayed(context.Background(), 3, domain.GameTypeDice, domain.GameResultWin, domain.CurrencyGems, 10, 10)
+	start := time.Now()
+	s.OnGamePlayed(context.Background(), 4, domain.GameTypeDice, domain.GameResultWin, domain.CurrencyGems, 10, 10)
+	if waited := time.Since(start); waited < s.enqueueTimeout {
+		t.Fatalf("expected to wait for the queue at least %s, waited %s", s.enqueueTimeout, waited)
+	}
+	if ev := <-s.queue; ev.userID != 3 || len(s.queue) != 0 {
+		t.Fatalf("expected only the third event in the queue, got user %d and %d more", ev.userID, len(s.queue))
+	}
+}
+
+func TestClaimRewardConcurrentCreditsOnce(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set; skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	db, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+
+	u := &domain.User{TgID: time.Now().UnixNano(), Username: "quest_claim_test"}
+	if err := repository.NewUserRepository(db).Create(ctx, u); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	defer db.Exec(context.Background(), `DELETE FROM users WHERE id = $1`, u.ID)
+	defer db.Exec(context.Background(), `DELETE FROM transactions WHERE user_id = $1`, u.ID)
+	if _, err := db.Exec(ctx, `UPDATE users SET gems = 0, coins = 0, gk = 0 WHERE id = $1`, u.ID); err != nil {
+		t.Fatalf("reset balance: %v", err)
+	}
+
+	var questID, userQuestID int64
+	if err := db.QueryRow(ctx, `
+		INSERT INTO quests (quest_type, title, action_type, target_count, reward_gems, reward_coins, reward_gk, is_active)
+		VALUES ('one_time', $1, 'play', 1, 100, 5, 0, false)
+		RETURNING id
+	`, fmt.Sprintf("claim test %d", u.TgID)).Scan(&questID); err != nil {
+		t.Fatalf("create quest: %v", err)
+	}
+	defer db.Exec(context.Background(), `DELETE FROM quests WHERE id = $1`, questID)
+	if err := db.QueryRow(ctx, `
+		INSERT INTO user_quests (user_id, quest_id, current_count, completed, completed_at)
+		VALUES ($1, $2, 1, true, now())
+		RETURNING id
+	`, u.ID, questID).Scan(&userQuestID); err != nil {
+		t.Fatalf("create user quest: %v", err)
+	}
+
+	s := NewQuestService(db)
+	const claims = 2
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		credited int
+		already  int
+	)
+	start := make(chan struct{})
+	for i := 0; i < claims; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, err := s.ClaimReward(ctx, u.ID, userQuestID, 1)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				credited++
+			case errors.Is(err, ErrQuestAlreadyClaimed):
+				already++
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if credited != 1 || already != claims-1 {
+		t.Fatalf("expected one credit and %d already_claimed, got %d/%d", claims-1, credited, already)
+	}
+
+	var gems, coins int64
+	if err := db.QueryRow(ctx, `SELECT gems, coins FROM users WHERE id = $1`, u.ID).Scan(&gems, &coins); err != nil {
+		t.Fatalf("load balance: %v", err)
+	}
+	if gems != 100 || coins != 5 {
+		t.Fatalf("expected reward credited once (100 gems, 5 coins), got %d gems, %d coins", gems, coins)
+	}
+	var rewards int
+	if err := db.QueryRow(ctx, `SELECT COUNT(*) FROM transactions WHERE user_id = $1 AND type = 'quest_reward'`, u.ID).Scan(&rewards); err != nil {
+		t.Fatalf("count transactions: %v", err)
+	}
+	if rewards != 2 {
+		t.Fatalf("expected 2 quest_reward transactions (gems and coins), got %d", rewards)
+	}
+
+	// Чужой или невыполненный квест - не already_claimed
+	if _, err := s.ClaimReward(ctx, u.ID+1_000_000_000, userQuestID, 1); !errors.Is(err, ErrQuestNotClaimable) {
+		t.Fatalf("expected ErrQuestNotClaimable for another user, got %v", err)
+	}
+}
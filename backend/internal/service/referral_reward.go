@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+
+	"telegram_webapp/internal/logger"
+	"telegram_webapp/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Условия выплаты награды за реферала
+const (
+	ReferralHoldNone    = "none"    // сразу при регистрации
+	ReferralHoldGames   = "games"   // после N сыгранных игр
+	ReferralHoldDeposit = "deposit" // после первого подтверждённого депозита
+)
+
+// ReferralRewardService pays the referrer bonus once the referred user qualifies.
+// Holding the reward until real activity makes farming with fake accounts pointless.
+type ReferralRewardService struct {
+	repo     *repository.ReferralRepository
+	mode     string
+	minGames int
+}
+
+// NewReferralRewardService creates the service; unknown modes fall back to none
+func NewReferralRewardService(db *pgxpool.Pool, mode string, minGames int) *ReferralRewardService {
+	switch mode {
+	case ReferralHoldGames, ReferralHoldDeposit:
+	default:
+		mode = ReferralHoldNone
+	}
+	if minGames <= 0 {
+		minGames = 1
+	}
+	return &ReferralRewardService{
+		repo:     repository.NewReferralRepository(db),
+		mode:     mode,
+		minGames: minGames,
+	}
+}
+
+// Mode returns the configured hold condition
+func (s *ReferralRewardService) Mode() string {
+	if s == nil {
+		return ReferralHoldNone
+	}
+	return s.mode
+}
+
+// OnReferralCreated is called right after a referral is stored
+func (s *ReferralRewardService) OnReferralCreated(ctx context.Context, referredID int64) {
+	s.CheckQualification(ctx, referredID)
+}
+
+// OnGamePlayed checks the referral after a game; only the games mode can be
+// released by playing, so the other modes skip the lookup
+func (s *ReferralRewardService) OnGamePlayed(ctx context.Context, referredID int64) {
+	if s == nil || s.mode != ReferralHoldGames {
+		return
+	}
+	s.CheckQualification(ctx, referredID)
+}
+
+// CheckQualification releases the held reward if the referred user meets the condition.
+// Safe to call after every game or deposit: claimed referrals are skipped.
+func (s *ReferralRewardService) CheckQualification(ctx context.Context, referredID int64) {
+	if s == nil {
+		return
+	}
+
+	ref, err := s.repo.GetPendingReferral(ctx, referredID)
+	if err != nil {
+		logger.Error("referral pending lookup failed", "user_id", referredID, "error", err)
+		return
+	}
+	if ref == nil {
+		return
+	}
+
+	// Игры считаем только для режима games: таблица истории большая
+	activity, err := s.repo.GetReferredActivity(ctx, referredID, s.mode == ReferralHoldGames)
+	if err != nil {
+		logger.Error("referral activity lookup failed", "user_id", referredID, "error", err)
+		return
+	}
+	if !qualifies(s.mode, s.minGames, activity) {
+		return
+	}
+
+	if err := s.repo.ClaimReferralBonus(ctx, ref.ID, ref.ReferrerID); err != nil {
+		logger.Error("referral bonus release failed", "referral_id", ref.ID, "error", err)
+		return
+	}
+	logger.Info("referral bonus released", "referrer_id", ref.ReferrerID, "referred_id", referredID, "mode", s.mode)
+}
+
+// qualifies applies the hold mode; a signup flagged by SignupGuard waits for a
+// confirmed deposit whatever the mode
+func qualifies(mode string, minGames int, a repository.ReferredActivity) bool {
+	if a.FlaggedSignup && !a.Deposited {
+		return false
+	}
+	switch mode {
+	case ReferralHoldGames:
+		return a.Games >= minGames
+	case ReferralHoldDeposit:
+		return a.Deposited
+	default:
+		return true
+	}
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"testing"
+
+	"telegram_webapp/internal/repository"
+)
+
+func TestReferralQualifies(t *testing.T) {
+	cases := []struct {
+		name string
+		mode string
+		a    repository.ReferredActivity
+		want bool
+	}{
+		{"none pays at once", ReferralHoldNone, repository.ReferredActivity{}, true},
+		{"games waits", ReferralHoldGames, repository.ReferredActivity{Games: 2}, false},
+		{"games reached", ReferralHoldGames, repository.ReferredActivity{Games: 3}, true},
+		{"deposit waits", ReferralHoldDeposit, repository.ReferredActivity{Games: 10}, false},
+		{"deposit done", ReferralHoldDeposit, repository.ReferredActivity{Deposited: true}, true},
+		{"flagged held in none", ReferralHoldNone, repository.ReferredActivity{FlaggedSignup: true}, false},
+		{"flagged held after games", ReferralHoldGames, repository.ReferredActivity{Games: 5, FlaggedSignup: true}, false},
+		{"flagged released by deposit", ReferralHoldNone, repository.ReferredActivity{FlaggedSignup: true, Deposited: true}, true},
+	}
+	for _, c := range cases {
+		if got := qualifies(c.mode, 3, c.a); got != c.want {
+			t.Errorf("%s: qualifies = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/game"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DefaultRPSProMultiplier - выплата за выигранный матч (шанс 50%, преимущество казино 5%)
+const DefaultRPSProMultiplier = 1.9
+
+// DefaultRPSProIdleTimeout - матч без ходов дольше этого считается брошенным
+const DefaultRPSProIdleTimeout = 10 * time.Minute
+
+// RPSProService manages active best-of-N RPS matches against the bot
+type RPSProService struct {
+	db          *pgxpool.Pool
+	activeGames map[int64]*game.RPSProGame // userID -> game
+	mu          sync.RWMutex
+
+	multiplier  float64
+	idleTimeout time.Duration
+
+	// onAbandon is called for matches forfeited by the sweeper (e.g. to record history)
+	onAbandon func(g *game.RPSProGame)
+}
+
+// NewRPSProService creates a new RPS Pro service
+func NewRPSProService(db *pgxpool.Pool) *RPSProService {
+	s := &RPSProService{
+		db:          db,
+		activeGames: make(map[int64]*game.RPSProGame),
+		multiplier:  DefaultRPSProMultiplier,
+		idleTimeout: DefaultRPSProIdleTimeout,
+	}
+
+	// Start sweeper for abandoned matches
+	go s.sweepAbandonedGames()
+
+	return s
+}
+
+// SetMultiplier sets payout for a won match; values below 1 are ignored
+func (s *RPSProService) SetMultiplier(m float64) {
+	if m < 1 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.multiplier = m
+}
+
+// Multiplier returns payout for a won match
+func (s *RPSProService) Multiplier() float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.multiplier
+}
+
+// ExpectedReturn returns the expected return per unit bet: with draws replayed
+// a match is won at most half the time
+func (s *RPSProService) ExpectedReturn() float64 {
+	return s.Multiplier() / 2
+}
+
+// SetIdleTimeout sets after how long without a move a match is forfeited
+func (s *RPSProService) SetIdleTimeout(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.idleTimeout = d
+}
+
+// SetOnAbandon sets the callback for matches forfeited by the sweeper
+func (s *RPSProService) SetOnAbandon(fn func(g *game.RPSProGame)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onAbandon = fn
+}
+
+// StartGame locks the bet in currency and starts a best-of-N match
+func (s *RPSProService) StartGame(ctx context.Context, userID int64, bet int64, bestOf int, currency domain.Currency) (*game.RPSProGame, error) {
+	if _, err := ParseGameCurrency(string(currency)); err != nil {
+		return nil, err
+	}
+	if currency == domain.CurrencyCoins && !CoinsAllowed(s.ExpectedReturn()) {
+		return nil, ErrCoinsNotAccepted
+	}
+	col := currency.BalanceColumn()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Check if user already has an active game
+	if existing, ok := s.activeGames[userID]; ok && existing.IsActive() {
+		return nil, ErrActiveGame
+	}
+
+	gameID := uuid.New().String()[:8]
+	g, err := game.NewRPSProGame(gameID, userID, bet, bestOf, s.multiplier)
+	if err != nil {
+		return nil, err
+	}
+	g.Currency = string(currency)
+
+	// Start transaction
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Check and deduct balance
+	var balance int64
+	if err := tx.QueryRow(ctx, fmt.Sprintf(`SELECT %s FROM users WHERE id=$1 FOR UPDATE`, col), userID).Scan(&balance); err != nil {
+		return nil, err
+	}
+	if balance < bet {
+		return nil, ErrInsufficientBalance
+	}
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf(`UPDATE users SET %[1]s = %[1]s - $1 WHERE id=$2`, col), bet, userID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	s.activeGames[userID] = g
+	return g, nil
+}
+
+// GetActiveGame returns user's active match
+func (s *RPSProService) GetActiveGame(userID int64) *game.RPSProGame {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	g, ok := s.activeGames[userID]
+	if !ok || !g.IsActive() {
+		return nil
+	}
+	return g
+}
+
+// Move plays one round of the user's active match. finished is true for the
+// move that ended the match: the winnings are credited by this call and the
+// caller records the match; a concurrent move on the same match never gets it.
+func (s *RPSProService) Move(ctx context.Context, userID int64, move string) (round game.RPSProRound, g *game.RPSProGame, finished bool, err error) {
+	s.mu.Lock()
+	g, ok := s.activeGames[userID]
+	if !ok || !g.IsActive() {
+		s.mu.Unlock()
+		return game.RPSProRound{}, nil, false, ErrNoActiveGame
+	}
+	s.mu.Unlock()
+
+	round, finished, err = g.Play(move)
+	if err != nil || !finished {
+		return round, g, false, err
+	}
+
+	// Match is over - clean up and credit winnings
+	s.mu.Lock()
+	if s.activeGames[userID] == g {
+		delete(s.activeGames, userID)
+	}
+	s.mu.Unlock()
+
+	if g.Status == game.RPSProStatusWon {
+		if err := s.credit(ctx, g, g.WinAmount); err != nil {
+			return round, g, true, err
+		}
+	}
+	return round, g, true, nil
+}
+
+// credit pays amount to the player in the currency the match was started with
+func (s *RPSProService) credit(ctx context.Context, g *game.RPSProGame, amount int64) error {
+	col := domain.Currency(g.Currency).BalanceColumn()
+	_, err := s.db.Exec(ctx, fmt.Sprintf(`UPDATE users SET %[1]s = %[1]s + $1 WHERE id=$2`, col), amount, g.UserID)
+	return err
+}
+
+// sweepAbandonedGames forfeits matches without a move for idleTimeout
+func (s *RPSProService) sweepAbandonedGames() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.expireIdle(time.Now())
+	}
+}
+
+// expireIdle forfeits matches idle since before now-idleTimeout
+func (s *RPSProService) expireIdle(now time.Time) int {
+	s.mu.Lock()
+	var expired []*game.RPSProGame
+	for userID, g := range s.activeGames {
+		if now.Sub(g.IdleSince()) > s.idleTimeout {
+			delete(s.activeGames, userID)
+			if g.Forfeit() {
+				expired = append(expired, g)
+			}
+		}
+	}
+	onAbandon := s.onAbandon
+	s.mu.Unlock()
+
+	for _, g := range expired {
+		log.Printf("RPSProService: match %s of user=%d forfeited after inactivity", g.ID, g.UserID)
+		if onAbandon != nil {
+			onAbandon(g)
+		}
+	}
+	return len(expired)
+}
+
+// GetActiveGamesCount returns the number of active matches
+func (s *RPSProService) GetActiveGamesCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.activeGames)
+}
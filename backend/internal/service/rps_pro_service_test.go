@@ -0,0 +1,34 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"telegram_webapp/internal/game"
+)
+
+func TestRPSProSweeperForfeitsIdleMatches(t *testing.T) {
+	s := &RPSProService{
+		activeGames: make(map[int64]*game.RPSProGame),
+		multiplier:  DefaultRPSProMultiplier,
+		idleTimeout: time.Minute,
+	}
+	var abandoned []*game.RPSProGame
+	s.SetOnAbandon(func(g *game.RPSProGame) { abandoned = append(abandoned, g) })
+
+	idle, _ := game.NewRPSProGame("idle", 1, 100, 3, s.multiplier)
+	idle.LastMoveAt = time.Now().Add(-2 * time.Minute)
+	fresh, _ := game.NewRPSProGame("fresh", 2, 100, 3, s.multiplier)
+	s.activeGames[1] = idle
+	s.activeGames[2] = fresh
+
+	if n := s.expireIdle(time.Now()); n != 1 {
+		t.Fatalf("expected 1 forfeited match, got %d", n)
+	}
+	if len(abandoned) != 1 || abandoned[0] != idle || idle.Status != game.RPSProStatusLost {
+		t.Fatalf("expected idle match to be forfeited and reported, got %v (status %s)", abandoned, idle.Status)
+	}
+	if s.GetActiveGame(1) != nil || s.GetActiveGame(2) == nil {
+		t.Fatal("expected only the fresh match to stay active")
+	}
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Границы самоисключения
+const (
+	MinSelfExclusion = 24 * time.Hour
+	MaxSelfExclusion = 365 * 24 * time.Hour
+)
+
+// ErrInvalidExclusion - срок самоисключения не распознан или вне границ
+var ErrInvalidExclusion = errors.New("exclusion must be from 24h to 365d, e.g. 24h, 7d, 30d")
+
+// ParseExclusionDuration parses a self-exclusion period given in hours ("24h")
+// or days ("7d", "30d") and checks it is within MinSelfExclusion..MaxSelfExclusion
+func ParseExclusionDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	if len(s) < 2 {
+		return 0, ErrInvalidExclusion
+	}
+
+	unit := time.Hour
+	switch s[len(s)-1] {
+	case 'h':
+	case 'd':
+		unit = 24 * time.Hour
+	default:
+		return 0, ErrInvalidExclusion
+	}
+
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n <= 0 {
+		return 0, ErrInvalidExclusion
+	}
+	d := time.Duration(n) * unit
+	if d < MinSelfExclusion || d > MaxSelfExclusion {
+		return 0, ErrInvalidExclusion
+	}
+	return d, nil
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseExclusionDuration(t *testing.T) {
+	valid := map[string]time.Duration{
+		"24h":  24 * time.Hour,
+		"7d":   7 * 24 * time.Hour,
+		"30D":  30 * 24 * time.Hour,
+		"365d": MaxSelfExclusion,
+	}
+	for in, want := range valid {
+		if got, err := ParseExclusionDuration(in); err != nil || got != want {
+			t.Fatalf("%q: expected %v, got %v (%v)", in, want, got, err)
+		}
+	}
+
+	// Меньше суток, больше года и мусор отклоняются
+	for _, in := range []string{"", "d", "1h", "0d", "-7d", "366d", "7w", "7.5d"} {
+		if _, err := ParseExclusionDuration(in); !errors.Is(err, ErrInvalidExclusion) {
+			t.Fatalf("%q: expected ErrInvalidExclusion, got %v", in, err)
+		}
+	}
+}
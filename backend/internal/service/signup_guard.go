@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/logger"
+	"telegram_webapp/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DefaultSignupIPWindow - окно, в котором считаются регистрации с одного IP
+const DefaultSignupIPWindow = 24 * time.Hour
+
+// SignupGuardConfig configures multi-account detection. MaxPerIP 0 disables flagging;
+// signups are still recorded.
+type SignupGuardConfig struct {
+	MaxPerIP    int           // других аккаунтов с IP за окно, после которых новый помечается
+	Window      time.Duration // 0 - DefaultSignupIPWindow
+	FlaggedGems int64         // стартовый баланс помеченного аккаунта
+}
+
+// SignupCheck is the outcome of SignupGuard.OnSignup
+type SignupCheck struct {
+	SharedIPCount int   // других регистраций с этого IP за окно
+	Flagged       bool  // стартовый баланс урезан
+	Gems          int64 // баланс gems после проверки
+}
+
+// SignupGuard records the IP of every new account and cuts the starting balance of
+// accounts registered from an IP that already has MaxPerIP accounts within Window.
+// Until their first confirmed deposit flagged accounts also get no referral reward
+// for their referrer and no low balance bonus.
+// Best effort: a shared NAT can trip it, so it never blocks the signup itself.
+type SignupGuard struct {
+	db           *pgxpool.Pool
+	transactions *repository.TransactionRepository
+	cfg          SignupGuardConfig
+}
+
+// NewSignupGuard creates the guard
+func NewSignupGuard(db *pgxpool.Pool, cfg SignupGuardConfig) *SignupGuard {
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultSignupIPWindow
+	}
+	if cfg.FlaggedGems < 0 {
+		cfg.FlaggedGems = 0
+	}
+	return &SignupGuard{db: db, transactions: repository.NewTransactionRepository(db), cfg: cfg}
+}
+
+// flag reports whether a signup sharing its IP with sharedCount accounts is suspicious
+func (g *SignupGuard) flag(sharedCount int) bool {
+	return g.cfg.MaxPerIP > 0 && sharedCount >= g.cfg.MaxPerIP
+}
+
+// OnSignup records a new account and applies the reduced starting balance if it is
+// flagged. authDate is the Telegram auth_date, zero if unknown.
+func (g *SignupGuard) OnSignup(ctx context.Context, userID int64, ip string, authDate time.Time) (*SignupCheck, error) {
+	if g == nil || ip == "" {
+		return &SignupCheck{}, nil
+	}
+
+	tx, err := g.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Параллельные регистрации с одного IP считаются по очереди
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('signup:' || $1))`, ip); err != nil {
+		return nil, err
+	}
+
+	check := &SignupCheck{}
+	if err := tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM signups
+		WHERE ip = $1 AND created_at > now() - make_interval(secs => $2) AND user_id <> $3
+	`, ip, g.cfg.Window.Seconds(), userID).Scan(&check.SharedIPCount); err != nil {
+		return nil, err
+	}
+	check.Flagged = g.flag(check.SharedIPCount)
+
+	var authAt *time.Time
+	if !authDate.IsZero() {
+		authAt = &authDate
+	}
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO signups (user_id, ip, auth_date, shared_ip_count, flagged)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO NOTHING
+	`, userID, ip, authAt, check.SharedIPCount, check.Flagged)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		// Уже проверяли - баланс не трогаем
+		check.Flagged = false
+	}
+
+	if err := tx.QueryRow(ctx, `SELECT gems FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&check.Gems); err != nil {
+		return nil, err
+	}
+	if check.Flagged && check.Gems > g.cfg.FlaggedGems {
+		cut := check.Gems - g.cfg.FlaggedGems
+		if _, err := tx.Exec(ctx, `UPDATE users SET gems = gems - $1 WHERE id = $2`, cut, userID); err != nil {
+			return nil, err
+		}
+		// Урезание видно в истории, как любое движение баланса
+		if err := g.transactions.CreateWithTx(ctx, tx, &domain.Transaction{
+			UserID: userID,
+			Type:   "signup_flagged",
+			Amount: -cut,
+			Meta:   map[string]interface{}{"reason": "shared_ip", "shared_ip_count": check.SharedIPCount},
+		}); err != nil {
+			return nil, err
+		}
+		check.Gems -= cut
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	if check.Flagged {
+		logger.Warn("signup flagged: shared ip", "user_id", userID, "ip", ip, "shared", check.SharedIPCount, "gems", check.Gems)
+	}
+	return check, nil
+}
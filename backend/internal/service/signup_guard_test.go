@@ -0,0 +1,34 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSignupGuardFlag(t *testing.T) {
+	g := NewSignupGuard(nil, SignupGuardConfig{MaxPerIP: 3})
+	if g.cfg.Window != DefaultSignupIPWindow {
+		t.Fatalf("expected default window, got %v", g.cfg.Window)
+	}
+
+	for shared, want := range map[int]bool{0: false, 2: false, 3: true, 10: true} {
+		if got := g.flag(shared); got != want {
+			t.Errorf("flag(%d) = %v, want %v", shared, got, want)
+		}
+	}
+
+	// MaxPerIP 0 - только запись, без пометок
+	off := NewSignupGuard(nil, SignupGuardConfig{})
+	if off.flag(100) {
+		t.Fatal("expected no flag when MaxPerIP is 0")
+	}
+}
+
+func TestSignupGuardNil(t *testing.T) {
+	var g *SignupGuard
+	check, err := g.OnSignup(context.Background(), 1, "1.2.3.4", time.Now())
+	if err != nil || check.Flagged {
+		t.Fatalf("nil guard: expected no-op, got %+v (err %v)", check, err)
+	}
+}
@@ -13,7 +13,12 @@ import (
 
 // ValidateTelegramInitData verifies Telegram WebApp init_data HMAC and checks
 // that the auth_date is recent (within 1 hour) to mitigate replay attacks.
+// Without a bot token nothing is valid: anyone could compute the hash.
 func ValidateTelegramInitData(initData, botToken string) (url.Values, bool) {
+	if botToken == "" {
+		return nil, false
+	}
+
 	values, err := url.ParseQuery(initData)
 	if err != nil {
 		return nil, false
@@ -11,8 +11,8 @@ import (
 	"time"
 )
 
-// buildInitData builds a valid init_data string for tests using the same
-// algorithm as ValidateTelegramInitData.
+// buildInitData builds a valid init_data string for tests per the WebApp spec:
+// secret = HMAC_SHA256("WebAppData", bot_token), hash = HMAC_SHA256(secret, data_check_string).
 func buildInitData(t *testing.T, botToken string, fields map[string]string) string {
     t.Helper()
     var parts []string
@@ -22,8 +22,8 @@ func buildInitData(t *testing.T, botToken string, fields map[string]string) stri
     sort.Strings(parts)
     dataString := strings.Join(parts, "\n")
 
-    secret := sha256.Sum256([]byte(botToken))
-    h := hmacNew(secret[:], []byte(dataString))
+    secret := hmacNew([]byte("WebAppData"), []byte(botToken))
+    h := hmacNew(secret, []byte(dataString))
     hash := hex.EncodeToString(h)
 
     // assemble query: include original fields and hash
@@ -100,3 +100,36 @@ func TestValidateTelegramInitData_Tampered(t *testing.T) {
         t.Fatalf("expected tampered init data to be invalid")
     }
 }
+
+func TestValidateTelegramInitData_Rejected(t *testing.T) {
+    botToken := "test-bot-token"
+    user := `{"id":1,"username":"u","first_name":"F"}`
+    now := time.Now().Unix()
+
+    cases := []struct {
+        name     string
+        initData string
+        botToken string
+    }{
+        {"signed with another bot token", buildInitData(t, "other-bot-token", map[string]string{
+            "auth_date": strconv.FormatInt(now, 10), "user": user,
+        }), botToken},
+        {"stale auth_date", buildInitData(t, botToken, map[string]string{
+            "auth_date": strconv.FormatInt(now-2*3600, 10), "user": user,
+        }), botToken},
+        {"auth_date from the future", buildInitData(t, botToken, map[string]string{
+            "auth_date": strconv.FormatInt(now+3600, 10), "user": user,
+        }), botToken},
+        {"no auth_date", buildInitData(t, botToken, map[string]string{"user": user}), botToken},
+        {"no hash", "auth_date=" + strconv.FormatInt(now, 10) + "&user=" + url.QueryEscape(user), botToken},
+        // Без токена бота подпись может посчитать кто угодно
+        {"empty bot token", buildInitData(t, "", map[string]string{
+            "auth_date": strconv.FormatInt(now, 10), "user": user,
+        }), ""},
+    }
+    for _, tc := range cases {
+        if _, ok := ValidateTelegramInitData(tc.initData, tc.botToken); ok {
+            t.Fatalf("%s: expected init data to be rejected", tc.name)
+        }
+    }
+}
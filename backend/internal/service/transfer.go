@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"telegram_webapp/internal/domain"
+)
+
+var (
+	ErrSelfTransfer     = errors.New("cannot transfer to yourself")
+	ErrTransferTooLow   = errors.New("transfer amount below minimum")
+	ErrTransferTooHigh  = errors.New("transfer amount above maximum")
+	ErrTransferDailyCap = errors.New("daily transfer limit exceeded")
+
+	ErrTransferAccountTooNew = errors.New("account is too new to transfer")
+	ErrTransferNotWagered    = errors.New("wager more gems before transferring")
+	ErrTransferPromoGems     = errors.New("signup and bonus gems cannot be transferred")
+)
+
+// promoGemTypes - транзакции, которыми платформа дарит gems. Вместе со стартовым
+// балансом они не переводятся: иначе фермы аккаунтов сливали бы их на один
+var promoGemTypes = []string{"bonus", "comeback_bonus", "referral_bonus", "quest_reward", "signup_flagged"}
+
+// TransferLimits bounds gem transfers between players. Zero Max, DailyCap,
+// MinAccountAge or MinWagered means no limit.
+type TransferLimits struct {
+	Min      int64
+	Max      int64
+	DailyCap int64 // сумма transfer_out отправителя за текущие сутки (UTC)
+
+	MinAccountAge time.Duration // возраст аккаунта отправителя
+	MinWagered    int64         // gems, поставленные отправителем за всё время
+}
+
+// DefaultTransferLimits returns the limits used when nothing is configured
+func DefaultTransferLimits() TransferLimits {
+	return TransferLimits{
+		Min:           domain.DefaultTransferMin,
+		Max:           domain.DefaultTransferMax,
+		DailyCap:      domain.DefaultTransferDailyCap,
+		MinAccountAge: domain.DefaultTransferMinAccountAge,
+		MinWagered:    domain.DefaultTransferMinWagered,
+	}
+}
+
+// Check validates a single transfer amount against Min/Max
+func (l TransferLimits) Check(amount int64) error {
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+	if amount < l.Min {
+		return ErrTransferTooLow
+	}
+	if l.Max > 0 && amount > l.Max {
+		return ErrTransferTooHigh
+	}
+	return nil
+}
+
+// transferDayStart - начало текущих суток по UTC, с него считается дневной лимит
+func transferDayStart(now time.Time) time.Time {
+	return now.UTC().Truncate(24 * time.Hour)
+}
+
+// TransferSender is what the anti-farm checks know about the sender
+type TransferSender struct {
+	CreatedAt time.Time
+	Gems      int64
+	Wagered   int64 // сумма ставок в gems
+	PromoGems int64 // стартовый баланс и подаренные gems
+}
+
+// CheckSender applies the anti-farm rules: the account must be old enough, must
+// have wagered enough, and may send only gems above everything it was given
+func (l TransferLimits) CheckSender(sender TransferSender, amount int64, now time.Time) error {
+	if l.MinAccountAge > 0 && now.Sub(sender.CreatedAt) < l.MinAccountAge {
+		return ErrTransferAccountTooNew
+	}
+	if sender.Wagered < l.MinWagered {
+		return ErrTransferNotWagered
+	}
+	if amount > sender.Transferable() {
+		return ErrTransferPromoGems
+	}
+	return nil
+}
+
+// Transferable returns how many gems the sender may transfer
+func (s TransferSender) Transferable() int64 {
+	if s.PromoGems <= 0 {
+		return s.Gems
+	}
+	if s.Gems <= s.PromoGems {
+		return 0
+	}
+	return s.Gems - s.PromoGems
+}
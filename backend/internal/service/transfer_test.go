@@ -0,0 +1,71 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTransferLimitsCheck(t *testing.T) {
+	limits := TransferLimits{Min: 100, Max: 1000, DailyCap: 5000}
+	cases := []struct {
+		amount int64
+		want   error
+	}{
+		{0, ErrInvalidAmount},
+		{-5, ErrInvalidAmount},
+		{99, ErrTransferTooLow},
+		{100, nil},
+		{1000, nil},
+		{1001, ErrTransferTooHigh},
+	}
+	for _, tc := range cases {
+		if err := limits.Check(tc.amount); !errors.Is(err, tc.want) {
+			t.Errorf("Check(%d) = %v, want %v", tc.amount, err, tc.want)
+		}
+	}
+
+	// Max = 0 - без верхней границы
+	if err := (TransferLimits{Min: 1}).Check(1 << 40); err != nil {
+		t.Errorf("unbounded Check = %v, want nil", err)
+	}
+}
+
+func TestTransferDayStartUTC(t *testing.T) {
+	loc := time.FixedZone("UTC+5", 5*3600)
+	now := time.Date(2024, 3, 10, 2, 30, 0, 0, loc) // 9 марта 21:30 UTC
+	want := time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC)
+	if got := transferDayStart(now); !got.Equal(want) {
+		t.Errorf("transferDayStart = %v, want %v", got, want)
+	}
+}
+
+func TestTransferCheckSender(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	limits := TransferLimits{Min: 100, MinAccountAge: 72 * time.Hour, MinWagered: 1000}
+	old := now.Add(-100 * time.Hour)
+
+	cases := []struct {
+		name   string
+		sender TransferSender
+		amount int64
+		want   error
+	}{
+		{"fresh account", TransferSender{CreatedAt: now.Add(-time.Hour), Gems: 50000, Wagered: 5000, PromoGems: 10000}, 100, ErrTransferAccountTooNew},
+		{"nothing wagered", TransferSender{CreatedAt: old, Gems: 50000, PromoGems: 10000}, 100, ErrTransferNotWagered},
+		{"signup gems only", TransferSender{CreatedAt: old, Gems: 10000, Wagered: 5000, PromoGems: 10000}, 100, ErrTransferPromoGems},
+		{"lost part of signup gems", TransferSender{CreatedAt: old, Gems: 4000, Wagered: 5000, PromoGems: 10500}, 100, ErrTransferPromoGems},
+		{"winnings above promo", TransferSender{CreatedAt: old, Gems: 12000, Wagered: 5000, PromoGems: 10500}, 1500, nil},
+		{"more than winnings", TransferSender{CreatedAt: old, Gems: 12000, Wagered: 5000, PromoGems: 10500}, 1501, ErrTransferPromoGems},
+	}
+	for _, c := range cases {
+		if err := limits.CheckSender(c.sender, c.amount, now); !errors.Is(err, c.want) {
+			t.Errorf("%s: CheckSender = %v, want %v", c.name, err, c.want)
+		}
+	}
+
+	// Нулевые лимиты возраста и ставок не проверяются
+	if err := (TransferLimits{}).CheckSender(TransferSender{CreatedAt: now, Gems: 20000, PromoGems: 10000}, 10000, now); err != nil {
+		t.Fatalf("no limits: %v", err)
+	}
+}
@@ -1,6 +1,9 @@
 package ton
 
-import "time"
+import (
+	"math"
+	"time"
+)
 
 const (
 	// CoinsPerTON is the exchange rate: how many coins for 1 TON
@@ -20,10 +23,10 @@ const (
 	// MinWithdrawCoins is the minimum withdrawal amount in coins (10 coins = 1 TON)
 	MinWithdrawCoins = 10
 
-	// WithdrawFeeCoinsFixed is the fixed platform fee on withdrawals (1 coin = 0.1 TON)
+	// WithdrawFeeCoinsFixed is the default fixed platform fee on withdrawals (1 coin = 0.1 TON)
 	WithdrawFeeCoinsFixed = 1
 
-	// WithdrawFeePercent is kept for backward compatibility but not used (replaced by fixed fee)
+	// WithdrawFeePercent is the default fee share in the percent fee mode
 	WithdrawFeePercent = 5
 
 	// MaxWithdrawCoinsPerDay is the maximum withdrawal per day in coins (1000 coins = 100 TON)
@@ -93,6 +96,25 @@ func CoinsToNano(coins int64) int64 {
 	return TONToNano(ton)
 }
 
+// CoinsForNano converts nanoTON to coins at the given rate (coins per TON), rounding
+// down. Integer math, so the result is exact for a rate stored with a deposit.
+func CoinsForNano(nano int64, rate int) int64 {
+	if nano <= 0 || rate <= 0 {
+		return 0
+	}
+	r := int64(rate)
+	return nano/NanoTON*r + nano%NanoTON*r/NanoTON
+}
+
+// NanoForCoins converts coins to nanoTON at the given rate (coins per TON)
+func NanoForCoins(coins int64, rate int) int64 {
+	if coins <= 0 || rate <= 0 {
+		return 0
+	}
+	r := int64(rate)
+	return coins/r*NanoTON + coins%r*NanoTON/r
+}
+
 // CoinsToTON converts coins to TON
 func CoinsToTON(coins int64) float64 {
 	return float64(coins) / CoinsPerTON
@@ -103,14 +125,70 @@ func TONToCoins(ton float64) int64 {
 	return int64(ton * CoinsPerTON)
 }
 
-// CalculateWithdrawFeeCoins calculates the fee for a withdrawal in coins (fixed 0.1 TON = 1 coin)
-func CalculateWithdrawFeeCoins(coinsAmount int64) int64 {
-	return WithdrawFeeCoinsFixed
+// WithdrawFeeMode - как считается комиссия за вывод
+type WithdrawFeeMode string
+
+const (
+	WithdrawFeeModeFixed   WithdrawFeeMode = "fixed"   // FixedCoins с любого вывода
+	WithdrawFeeModePercent WithdrawFeeMode = "percent" // Percent от суммы, не меньше MinCoins
+)
+
+// WithdrawFee is the platform fee model for coin withdrawals
+type WithdrawFee struct {
+	Mode       WithdrawFeeMode
+	FixedCoins int64   // fixed: комиссия в coins
+	Percent    float64 // percent: доля суммы вывода, 0-100
+	MinCoins   int64   // percent: нижняя граница комиссии
+}
+
+// DefaultWithdrawFee - фиксированная комиссия 1 coin (0.1 TON)
+func DefaultWithdrawFee() WithdrawFee {
+	return WithdrawFee{
+		Mode:       WithdrawFeeModeFixed,
+		FixedCoins: WithdrawFeeCoinsFixed,
+		Percent:    WithdrawFeePercent,
+		MinCoins:   WithdrawFeeCoinsFixed,
+	}
+}
+
+// CalculateWithdrawFeeCoins calculates the fee for a withdrawal in coins. The percent
+// is applied in basis points and rounded up, then raised to the floor; the fee never
+// exceeds the amount itself.
+func CalculateWithdrawFeeCoins(coinsAmount int64, fee WithdrawFee) int64 {
+	if coinsAmount <= 0 {
+		return 0
+	}
+	var coins int64
+	switch fee.Mode {
+	case WithdrawFeeModePercent:
+		bps := int64(math.Round(fee.Percent * 100))
+		coins = (coinsAmount*bps + 9999) / 10000
+		if coins < fee.MinCoins {
+			coins = fee.MinCoins
+		}
+	default:
+		coins = fee.FixedCoins
+	}
+	if coins < 0 {
+		return 0
+	}
+	if coins > coinsAmount {
+		return coinsAmount
+	}
+	return coins
 }
 
 // CalculateWithdrawNetCoins calculates the net coins after fee
-func CalculateWithdrawNetCoins(coinsAmount int64) int64 {
-	return coinsAmount - WithdrawFeeCoinsFixed
+func CalculateWithdrawNetCoins(coinsAmount int64, fee WithdrawFee) int64 {
+	return coinsAmount - CalculateWithdrawFeeCoins(coinsAmount, fee)
+}
+
+// RemainingWithdrawCoinsToday returns how many coins can still be withdrawn today
+func RemainingWithdrawCoinsToday(withdrawnToday int64) int64 {
+	if withdrawnToday >= MaxWithdrawCoinsPerDay {
+		return 0
+	}
+	return MaxWithdrawCoinsPerDay - withdrawnToday
 }
 
 // Legacy functions for backward compatibility
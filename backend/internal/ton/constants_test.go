@@ -0,0 +1,66 @@
+package ton
+
+import "testing"
+
+func TestCoinsForNano(t *testing.T) {
+	cases := []struct {
+		nano int64
+		rate int
+		want int64
+	}{
+		{NanoTON, 10, 10},
+		{1_500_000_000, 10, 15},
+		{1_999_999_999, 10, 19}, // округление вниз
+		{2_500_000_000, 12, 30},
+		{NanoTON, 0, 0},
+		{-NanoTON, 10, 0},
+		{9_000_000 * NanoTON, 1000, 9_000_000_000}, // без переполнения на nano*rate
+	}
+	for _, c := range cases {
+		if got := CoinsForNano(c.nano, c.rate); got != c.want {
+			t.Errorf("CoinsForNano(%d, %d) = %d, want %d", c.nano, c.rate, got, c.want)
+		}
+	}
+}
+
+func TestNanoForCoins(t *testing.T) {
+	cases := []struct {
+		coins int64
+		rate  int
+		want  int64
+	}{
+		{10, 10, NanoTON},
+		{9, 10, 900_000_000},
+		{1, 3, 333_333_333},
+		{10, 0, 0},
+	}
+	for _, c := range cases {
+		if got := NanoForCoins(c.coins, c.rate); got != c.want {
+			t.Errorf("NanoForCoins(%d, %d) = %d, want %d", c.coins, c.rate, got, c.want)
+		}
+	}
+}
+
+func TestWithdrawFeeAtMinimum(t *testing.T) {
+	cases := []struct {
+		name string
+		fee  WithdrawFee
+		want int64
+	}{
+		{"default", DefaultWithdrawFee(), WithdrawFeeCoinsFixed},
+		{"fixed", WithdrawFee{Mode: WithdrawFeeModeFixed, FixedCoins: 2}, 2},
+		{"percent above floor", WithdrawFee{Mode: WithdrawFeeModePercent, Percent: 25, MinCoins: 1}, 3}, // 2.5 вверх
+		{"percent below floor", WithdrawFee{Mode: WithdrawFeeModePercent, Percent: 5, MinCoins: 2}, 2},
+		{"percent without floor", WithdrawFee{Mode: WithdrawFeeModePercent, Percent: 0}, 0},
+		{"fee above amount", WithdrawFee{Mode: WithdrawFeeModeFixed, FixedCoins: MinWithdrawCoins + 5}, MinWithdrawCoins},
+	}
+	for _, c := range cases {
+		fee := CalculateWithdrawFeeCoins(MinWithdrawCoins, c.fee)
+		if fee != c.want {
+			t.Errorf("%s: fee = %d, want %d", c.name, fee, c.want)
+		}
+		if net := CalculateWithdrawNetCoins(MinWithdrawCoins, c.fee); net != MinWithdrawCoins-c.want {
+			t.Errorf("%s: net = %d, want %d", c.name, net, MinWithdrawCoins-c.want)
+		}
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -65,7 +67,10 @@ func VerifyProof(account WalletAccount, proof ConnectProof, allowedDomain string
 	}
 
 	// 5. Build message to verify
-	message := buildProofMessage(account.Address, proof)
+	message, err := buildProofMessage(account.Address, proof)
+	if err != nil {
+		return err
+	}
 
 	// 6. Verify signature
 	if !ed25519.Verify(pubKeyBytes, message, signatureBytes) {
@@ -75,49 +80,45 @@ func VerifyProof(account WalletAccount, proof ConnectProof, allowedDomain string
 	return nil
 }
 
-// buildProofMessage constructs the message that was signed
-func buildProofMessage(address string, proof ConnectProof) []byte {
-	// Message format:
-	// "ton-proof-item-v2/" + address_workchain (4 bytes) + address_hash (32 bytes)
-	// + domain_len (4 bytes) + domain + timestamp (8 bytes) + payload
-
-	// Parse address to get workchain and hash
-	// For simplicity, we'll construct a simpler message hash
-	// In production, you'd want to properly parse the TON address
+// buildProofMessage constructs the hash that the wallet signed:
+// sha256(0xffff ++ "ton-connect" ++ sha256(message)), where message is
+// "ton-proof-item-v2/" ++ workchain (4 bytes BE) ++ address hash (32 bytes)
+// ++ domain length (4 bytes LE) ++ domain ++ timestamp (8 bytes LE) ++ payload
+func buildProofMessage(address string, proof ConnectProof) ([]byte, error) {
+	addr, err := ParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	if proof.Domain.LengthBytes != len(proof.Domain.Value) {
+		return nil, errors.New("domain length mismatch")
+	}
 
-	// The actual TON proof message construction
 	var message []byte
-
-	// "ton-proof-item-v2/"
 	message = append(message, []byte("ton-proof-item-v2/")...)
 
-	// Address (simplified - in real implementation parse properly)
-	message = append(message, []byte(address)...)
+	workchain := make([]byte, 4)
+	binary.BigEndian.PutUint32(workchain, uint32(addr.Workchain))
+	message = append(message, workchain...)
+	message = append(message, addr.Hash[:]...)
 
-	// Domain length (4 bytes, little endian)
 	domainLen := make([]byte, 4)
-	binary.LittleEndian.PutUint32(domainLen, uint32(proof.Domain.LengthBytes))
+	binary.LittleEndian.PutUint32(domainLen, uint32(len(proof.Domain.Value)))
 	message = append(message, domainLen...)
-
-	// Domain value
 	message = append(message, []byte(proof.Domain.Value)...)
 
-	// Timestamp (8 bytes, little endian)
 	timestamp := make([]byte, 8)
 	binary.LittleEndian.PutUint64(timestamp, uint64(proof.Timestamp))
 	message = append(message, timestamp...)
 
-	// Payload
 	message = append(message, []byte(proof.Payload)...)
 
-	// Hash the message
 	hash := sha256.Sum256(message)
 
-	// Prefix with "ton-connect" and hash again
-	finalMessage := append([]byte("ton-connect"), hash[:]...)
+	finalMessage := append([]byte{0xff, 0xff}, []byte("ton-connect")...)
+	finalMessage = append(finalMessage, hash[:]...)
 	finalHash := sha256.Sum256(finalMessage)
 
-	return finalHash[:]
+	return finalHash[:], nil
 }
 
 // GeneratePayload generates a random payload for TON Connect
@@ -129,57 +130,86 @@ func GeneratePayload() string {
 	return payload[:32] // Truncate to reasonable length
 }
 
-// ValidateAddress checks if the TON address format is valid
-func ValidateAddress(address string) bool {
-	// TON addresses are typically:
-	// - Raw: 0:hex (workchain:hash)
-	// - User-friendly: Base64 encoded (48 chars with bounceable/non-bounceable flag)
+// Address is a parsed TON account address
+type Address struct {
+	Workchain  int32
+	Hash       [32]byte
+	Bounceable bool
+	Testnet    bool
+}
+
+// Raw returns the address in raw format (workchain:hex)
+func (a Address) Raw() string {
+	return fmt.Sprintf("%d:%s", a.Workchain, hex.EncodeToString(a.Hash[:]))
+}
+
+// ParseAddress parses a raw (0:hex) or user-friendly (EQ.../UQ..., base64 or base64url) address.
+// User-friendly addresses are 36 bytes: 1 byte flags + 1 byte workchain + 32 bytes hash + 2 bytes CRC16.
+func ParseAddress(address string) (Address, error) {
+	var addr Address
 
-	if len(address) == 0 {
-		return false
+	if wc, hashHex, ok := strings.Cut(address, ":"); ok {
+		workchain, err := strconv.ParseInt(wc, 10, 32)
+		if err != nil {
+			return addr, errors.New("invalid workchain")
+		}
+		hash, err := hex.DecodeString(hashHex)
+		if err != nil || len(hash) != 32 {
+			return addr, errors.New("invalid address hash")
+		}
+		addr.Workchain = int32(workchain)
+		addr.Bounceable = true
+		copy(addr.Hash[:], hash)
+		return addr, nil
 	}
 
-	// Check for raw format (0:hex or -1:hex)
-	if len(address) >= 66 && (address[0:2] == "0:" || address[0:3] == "-1:") {
-		return true
+	if len(address) != 48 {
+		return addr, errors.New("unknown address format")
+	}
+	encoding := base64.URLEncoding
+	if strings.ContainsAny(address, "+/") {
+		encoding = base64.StdEncoding
+	}
+	decoded, err := encoding.DecodeString(address)
+	if err != nil {
+		return addr, fmt.Errorf("invalid address format: %w", err)
+	}
+	if len(decoded) != 36 {
+		return addr, errors.New("invalid address length")
+	}
+	if crc16(decoded[:34]) != binary.BigEndian.Uint16(decoded[34:]) {
+		return addr, errors.New("invalid address checksum")
 	}
 
-	// Check for user-friendly format (base64, 48 chars)
-	if len(address) == 48 {
-		_, err := base64.URLEncoding.DecodeString(address)
-		return err == nil
+	flags := decoded[0]
+	addr.Testnet = flags&0x80 != 0
+	switch flags &^ 0x80 {
+	case 0x11:
+		addr.Bounceable = true
+	case 0x51:
+		addr.Bounceable = false
+	default:
+		return addr, errors.New("invalid address flags")
 	}
 
-	return false
+	addr.Workchain = int32(int8(decoded[1]))
+	copy(addr.Hash[:], decoded[2:34])
+	return addr, nil
+}
+
+// ValidateAddress checks if the TON address format is valid
+func ValidateAddress(address string) bool {
+	_, err := ParseAddress(address)
+	return err == nil
 }
 
 // NormalizeAddress converts address to raw format
 func NormalizeAddress(address string) (string, error) {
-	// If already raw format, return as is
-	if len(address) >= 66 && (address[0:2] == "0:" || address[0:3] == "-1:") {
-		return address, nil
-	}
-
-	// Try to decode user-friendly format
-	if len(address) == 48 {
-		decoded, err := base64.URLEncoding.DecodeString(address)
-		if err != nil {
-			return "", fmt.Errorf("invalid address format: %w", err)
-		}
-
-		// User-friendly address is 36 bytes:
-		// 1 byte flags + 1 byte workchain + 32 bytes hash + 2 bytes CRC
-		if len(decoded) != 36 {
-			return "", errors.New("invalid address length")
-		}
-
-		workchain := int8(decoded[1])
-		hash := decoded[2:34]
-
-		return fmt.Sprintf("%d:%s", workchain, hex.EncodeToString(hash)), nil
+	addr, err := ParseAddress(address)
+	if err != nil {
+		return "", err
 	}
-
-	return "", errors.New("unknown address format")
+	return addr.Raw(), nil
 }
 
 // RawToUserFriendly converts raw address (0:xxx) to user-friendly format (EQ.../UQ...)
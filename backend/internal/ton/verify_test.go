@@ -0,0 +1,106 @@
+package ton
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+// Кошелёк TON Foundation - известная пара raw/user-friendly
+const (
+	knownRaw        = "0:83dfd552e63729b472fcbcc8c45ebcc6691702558b68ec7527e1ba403a0f31a8"
+	knownBounceable = "EQCD39VS5jcptHL8vMjEXrzGaRcCVYto7HUn4bpAOg8xqB2N"
+)
+
+func TestParseAddressKnownVectors(t *testing.T) {
+	nonBounceable, err := RawToUserFriendly(knownRaw, false)
+	if err != nil {
+		t.Fatalf("RawToUserFriendly: %v", err)
+	}
+	if friendly, _ := RawToUserFriendly(knownRaw, true); friendly != knownBounceable {
+		t.Fatalf("expected %s, got %s", knownBounceable, friendly)
+	}
+
+	for _, address := range []string{knownRaw, knownBounceable, nonBounceable} {
+		raw, err := NormalizeAddress(address)
+		if err != nil {
+			t.Fatalf("NormalizeAddress(%s): %v", address, err)
+		}
+		if raw != knownRaw {
+			t.Fatalf("NormalizeAddress(%s) = %s, want %s", address, raw, knownRaw)
+		}
+	}
+
+	addr, err := ParseAddress(knownBounceable)
+	if err != nil || !addr.Bounceable || addr.Testnet || addr.Workchain != 0 {
+		t.Fatalf("unexpected parse result: %+v, %v", addr, err)
+	}
+
+	master, err := ParseAddress("-1:" + knownRaw[2:])
+	if err != nil || master.Workchain != -1 {
+		t.Fatalf("expected masterchain address, got %+v, %v", master, err)
+	}
+
+	// Испорченная контрольная сумма
+	broken := knownBounceable[:47] + "M"
+	if _, err := ParseAddress(broken); err == nil {
+		t.Fatal("expected checksum error")
+	}
+	for _, bad := range []string{"", "0:abc", "x:" + knownRaw[2:], "EQ" + knownBounceable[2:40]} {
+		if ValidateAddress(bad) {
+			t.Errorf("expected %q to be invalid", bad)
+		}
+	}
+}
+
+func TestVerifyProof(t *testing.T) {
+	priv := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	pub := priv.Public().(ed25519.PublicKey)
+
+	proof := ConnectProof{
+		Timestamp: time.Now().Unix(),
+		Domain:    Domain{LengthBytes: len("example.com"), Value: "example.com"},
+		Payload:   "test-payload",
+	}
+
+	// Сообщение по спецификации TON Connect собираем независимо от buildProofMessage
+	hash, _ := hex.DecodeString(knownRaw[2:])
+	msg := []byte("ton-proof-item-v2/")
+	msg = append(msg, 0, 0, 0, 0) // workchain 0, big endian
+	msg = append(msg, hash...)
+	msg = append(msg, byte(len("example.com")), 0, 0, 0)
+	msg = append(msg, "example.com"...)
+	ts := uint64(proof.Timestamp)
+	for i := 0; i < 8; i++ {
+		msg = append(msg, byte(ts>>(8*i)))
+	}
+	msg = append(msg, proof.Payload...)
+	inner := sha256.Sum256(msg)
+	signed := sha256.Sum256(append(append([]byte{0xff, 0xff}, "ton-connect"...), inner[:]...))
+	proof.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, signed[:]))
+
+	// Тот же кошелёк в обоих форматах
+	for _, address := range []string{knownRaw, knownBounceable} {
+		account := WalletAccount{Address: address, Chain: "-239", PublicKey: hex.EncodeToString(pub)}
+		if err := VerifyProof(account, proof, "example.com"); err != nil {
+			t.Fatalf("VerifyProof(%s): %v", address, err)
+		}
+	}
+
+	account := WalletAccount{Address: knownRaw, PublicKey: hex.EncodeToString(pub)}
+	if err := VerifyProof(account, proof, "other.com"); err == nil {
+		t.Fatal("expected domain mismatch")
+	}
+	tampered := proof
+	tampered.Payload = "other-payload"
+	if err := VerifyProof(account, tampered, "example.com"); err == nil {
+		t.Fatal("expected invalid signature for tampered payload")
+	}
+	account.Address = "-1:" + knownRaw[2:]
+	if err := VerifyProof(account, proof, "example.com"); err == nil {
+		t.Fatal("expected invalid signature for another workchain")
+	}
+}
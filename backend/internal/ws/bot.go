@@ -0,0 +1,96 @@
+package ws
+
+import (
+	"log"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/game"
+)
+
+// BotPlayerID - зарезервированный id бота; отрицательный, с users.id не пересекается
+const BotPlayerID int64 = -1
+
+var rpsBotMoves = []string{"rock", "paper", "scissors"}
+
+var botRandom = game.NewCryptoRandomizer()
+
+// botMoveValue returns a random move in the shape game.HandleMove expects.
+// Mines picks random cells / mines itself when given nil.
+func botMoveValue(gameType game.GameType) interface{} {
+	if gameType == game.TypeRPS {
+		return rpsBotMoves[botRandom.Intn(len(rpsBotMoves))]
+	}
+	return nil
+}
+
+// SetBotFallback sets how long a live client waits for a human opponent before
+// its room is played against the bot (0 = never, the client waits for the match timeout)
+func (h *Hub) SetBotFallback(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.botFallback = d
+}
+
+// botAllowed reports whether a room staked in currency may be played against the
+// bot. The bot pays the usual win_amount, so the rake is the only house edge of a
+// bot game; without it coins would be staked on an even game. Caller holds h.mu.
+func (h *Hub) botAllowed(currency string) bool {
+	return currency != string(domain.CurrencyCoins) || h.rakePercent > 0
+}
+
+// handleBotJoin seats the bot as the second player of a room that found no
+// opponent in time: the player's stake is held, they get bot_opponent and the
+// usual matched. Returns false if the match was aborted.
+func (r *Room) handleBotJoin() bool {
+	r.mu.Lock()
+	players := r.game.Players()
+	if players[1] != 0 || len(r.Clients) != 1 || r.game.IsFinished() {
+		// соперник успел найтись или игрок ушёл
+		r.mu.Unlock()
+		return true
+	}
+	r.game.SetSecondPlayer(BotPlayerID)
+	r.botOpponent = true
+	c := r.Clients[players[0]]
+	r.mu.Unlock()
+
+	if c == nil {
+		return true
+	}
+
+	log.Printf("Room.handleBotJoin: room=%s user=%d plays against the bot", r.ID, c.UserID)
+
+	if failed, err := r.holdStakes(players[0], BotPlayerID); err != nil {
+		r.abortMatch(c, nil, failed, err)
+		return false
+	}
+
+	// Бот расставляет мины сразу, игрок - после matched
+	r.mu.Lock()
+	if !r.game.IsSetupComplete() && !r.game.HasMoved(BotPlayerID) {
+		r.game.HandleMove(BotPlayerID, botMoveValue(r.game.Type()))
+	}
+	r.mu.Unlock()
+
+	r.sendTo(c, Message{Type: "bot_opponent", Payload: map[string]any{"room_id": r.ID, "opponent_id": BotPlayerID}})
+	r.sendTo(c, Message{Type: "matched", Payload: r.matchedPayload(BotPlayerID)})
+
+	if r.game.IsSetupComplete() {
+		r.startRound()
+	}
+	return true
+}
+
+// botRespond makes the bot's move once the player has moved in the playing phase
+func (r *Room) botRespond() {
+	r.mu.RLock()
+	bot := r.botOpponent
+	r.mu.RUnlock()
+	if !bot || !r.game.IsSetupComplete() || r.game.IsFinished() || r.game.HasMoved(BotPlayerID) {
+		return
+	}
+	if err := r.game.HandleMove(BotPlayerID, botMoveValue(r.game.Type())); err != nil {
+		log.Printf("Room.botRespond: room=%s bot move failed: %v", r.ID, err)
+	}
+}
@@ -0,0 +1,123 @@
+package ws
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"telegram_webapp/internal/game"
+)
+
+func TestExpireWaitingHandsRoomToBot(t *testing.T) {
+	h := NewHub(nil, nil)
+	h.SetDefaultMatchTimeout(time.Minute)
+	h.SetBotFallback(10 * time.Second)
+
+	room := NewRoom("room", game.NewRPSGame("room", [2]int64{1, 0}), h)
+	c := &Client{UserID: 1, Send: make(chan []byte, 4), waitingSince: time.Now().Add(-11 * time.Second)}
+	key := WaitingKey{GameType: game.TypeRPS, BetAmount: 10, Currency: "gems"}
+	h.Rooms[room.ID] = room
+	h.UserRoom[1] = room.ID
+	h.WaitingByKey[key] = c
+
+	h.expireWaiting()
+
+	if h.WaitingByKey[key] != nil {
+		t.Fatal("expected the waiting slot to be released")
+	}
+	select {
+	case <-room.botJoin:
+	default:
+		t.Fatal("expected the room to be asked to seat the bot")
+	}
+	if len(c.Send) != 0 {
+		t.Fatalf("client should not get no_match, got %s", <-c.Send)
+	}
+}
+
+func TestExpireWaitingKeepsCoinRoomFromBotWithoutRake(t *testing.T) {
+	h := NewHub(nil, nil)
+	h.SetDefaultMatchTimeout(time.Minute)
+	h.SetBotFallback(10 * time.Second)
+
+	room := NewRoom("room", game.NewRPSGame("room", [2]int64{1, 0}), h)
+	c := &Client{UserID: 1, Send: make(chan []byte, 4), waitingSince: time.Now().Add(-11 * time.Second)}
+	key := WaitingKey{GameType: game.TypeRPS, BetAmount: 10, Currency: "coins"}
+	h.Rooms[room.ID] = room
+	h.UserRoom[1] = room.ID
+	h.WaitingByKey[key] = c
+
+	// Без рейка у бота нет преимущества - монеты на такую игру не принимаются
+	h.expireWaiting()
+	if h.WaitingByKey[key] != c {
+		t.Fatal("coin client should keep waiting for a human without a rake")
+	}
+	select {
+	case <-room.botJoin:
+		t.Fatal("coin room must not get the bot without a rake")
+	default:
+	}
+
+	h.SetRake(5, 42)
+	h.expireWaiting()
+	if h.WaitingByKey[key] != nil {
+		t.Fatal("expected the waiting slot to be released once a rake is set")
+	}
+	select {
+	case <-room.botJoin:
+	default:
+		t.Fatal("expected the room to be asked to seat the bot")
+	}
+}
+
+func TestBotMatchPlaysRound(t *testing.T) {
+	room := NewRoom("room", game.NewRPSGame("room", [2]int64{1, 0}), nil)
+	c := &Client{UserID: 1, Send: make(chan []byte, 32)}
+	room.Clients[1] = c
+	defer room.cleanup()
+
+	if !room.handleBotJoin() {
+		t.Fatal("bot match aborted")
+	}
+	if players := room.game.Players(); players[1] != BotPlayerID {
+		t.Fatalf("expected the bot as second player, got %v", players)
+	}
+
+	var types []string
+	var matched map[string]any
+	for len(c.Send) > 0 {
+		var msg struct {
+			Type    string         `json:"type"`
+			Payload map[string]any `json:"payload"`
+		}
+		if err := json.Unmarshal(<-c.Send, &msg); err != nil {
+			t.Fatal(err)
+		}
+		types = append(types, msg.Type)
+		if msg.Type == "matched" {
+			matched = msg.Payload
+		}
+	}
+	if len(types) != 3 || types[0] != "bot_opponent" || types[1] != "matched" || types[2] != "start" {
+		t.Fatalf("expected bot_opponent, matched, start - got %v", types)
+	}
+	if matched["bot"] != true {
+		t.Fatalf("matched should flag the bot opponent: %v", matched)
+	}
+
+	// Бот отвечает на ход игрока, раунд завершается без второго клиента
+	room.HandleMessage(c, []byte(`{"type":"move","value":"rock"}`))
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case raw := <-c.Send:
+			var msg Message
+			json.Unmarshal(raw, &msg)
+			if msg.Type == "result" || msg.Type == "round_draw" {
+				return
+			}
+		case <-deadline:
+			t.Fatal("round against the bot did not complete")
+		}
+	}
+}
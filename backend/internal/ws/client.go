@@ -2,6 +2,7 @@ package ws
 
 import (
 	"bytes"
+	"encoding/json"
 	"log"
 	"sync"
 	"time"
@@ -33,6 +34,17 @@ type Client struct {
 	Done       chan struct{}
 	pendingMu  sync.Mutex
 	pending    [][]byte
+
+	// when the client took a waiting slot (guarded by Hub.mu)
+	waitingSince time.Time
+
+	// запрос на закрытие с кодом (см. close.go)
+	closing chan closeFrame
+	// why AssignClient refused the client (set only when it returns nil)
+	rejectCode   int
+	rejectReason string
+	// called after the connection is closed (see SetOnClose)
+	onClose func()
 }
 
 func NewClient(userID int64, conn *websocket.Conn, hub *Hub, gameType string, betAmount int64, currency string) *Client {
@@ -48,6 +60,7 @@ func NewClient(userID int64, conn *websocket.Conn, hub *Hub, gameType string, be
 		Registered: make(chan struct{}, 1),
 		ResultAck:  make(chan struct{}, 1),
 		Done:       make(chan struct{}),
+		closing:    make(chan closeFrame, 1),
 	}
 }
 
@@ -58,7 +71,7 @@ func (c *Client) Run() {
 	close(c.Ready)
 
 	// send explicit ready handshake so tests/clients can wait for it
-	readyMsg := []byte(`{"type":"ready"}`)
+	readyMsg, _ := json.Marshal(Message{Type: MsgReady, Payload: map[string]int{"protocol": ProtocolVersion}})
 	select {
 	case c.Send <- readyMsg:
 		log.Printf("Client.Run: user=%d ready message queued", c.UserID)
@@ -77,7 +90,10 @@ func (c *Client) Run() {
 
 	if c.Room == nil {
 		log.Printf("Client.Run: failed to assign room for user=%d", c.UserID)
-		c.Conn.Close()
+		if c.rejectCode == 0 {
+			c.rejectCode, c.rejectReason = websocket.CloseInternalServerErr, "room_unavailable"
+		}
+		c.CloseWith(c.rejectCode, c.rejectReason)
 		return
 	}
 
@@ -92,6 +108,9 @@ func (c *Client) readPump() {
 	log.Printf("Client.readPump: START for user=%d", c.UserID)
 	defer func() {
 		c.disconnect()
+		if c.onClose != nil {
+			c.onClose()
+		}
 		close(c.Done)
 	}()
 
@@ -108,7 +127,14 @@ func (c *Client) readPump() {
 			log.Println("read error:", err)
 			break
 		}
+		// любое сообщение клиента - признак живого соединения, как и pong
+		c.Conn.SetReadDeadline(time.Now().Add(pongWait))
 		log.Printf("Client.readPump: user=%d received %d bytes: %s", c.UserID, len(msg), string(msg))
+		if isPing(msg) {
+			// heartbeat отвечаем сразу, даже до назначения комнаты
+			c.pong()
+			continue
+		}
 		if c.Room != nil {
 			c.Room.HandleMessage(c, msg)
 		} else {
@@ -121,6 +147,14 @@ func (c *Client) readPump() {
 	}
 }
 
+// pong answers a heartbeat ping; dropped if the send buffer is full
+func (c *Client) pong() {
+	select {
+	case c.Send <- []byte(`{"type":"pong"}`):
+	default:
+	}
+}
+
 //write
 func (c *Client) writePump() {
 	ticker := time.NewTicker(pingPeriod)
@@ -156,6 +190,26 @@ func (c *Client) writePump() {
 			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
 			}
+
+		case cf := <-c.closing:
+			// сначала отдаём уже поставленные в очередь сообщения (например no_match)
+			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+			for flushed := false; !flushed; {
+				select {
+				case msg, ok := <-c.Send:
+					if !ok {
+						flushed = true
+						break
+					}
+					if err := c.Conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+						return
+					}
+				default:
+					flushed = true
+				}
+			}
+			_ = c.Conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(cf.code, cf.reason))
+			return
 		}
 	}
 }
@@ -0,0 +1,58 @@
+package ws
+
+import (
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Коды закрытия WebSocket (диапазон 4000-4999 отведён приложению по RFC 6455).
+// Причина (reason) передаётся вместе с кодом и совпадает с именем ниже.
+//
+//	4001 unauthorized      - нет или неверный JWT: переавторизоваться и переподключиться
+//	4002 already_connected - у пользователя уже есть живое соединение: не переподключаться
+//	4003 server_shutdown   - сервер перезапускается: ставки возвращены, повторить с backoff
+//	4004 match_timeout     - соперник не найден за отведённое время: предложить повтор или PvE
+//	4005 bad_request       - неверные параметры (ставка, валюта, баланс): не повторять как есть
+//	4006 kicked / banned   - соединение разорвано сервером или пользователь заблокирован
+//	4007 too_many_connections - у пользователя открыто WS_MAX_CONNS_PER_USER сокетов: закрыть лишние
+//	4008 already_in_game   - пользователь уже играет в другой комнате: вернуться в неё, не начинать новую
+//	1011 room_unavailable  - внутренняя ошибка при создании комнаты: можно повторить
+const (
+	CloseUnauthorized     = 4001
+	CloseAlreadyConnected = 4002
+	CloseServerShutdown   = 4003
+	CloseMatchTimeout     = 4004
+	CloseBadRequest       = 4005
+	CloseKicked           = 4006
+	CloseTooManyConns     = 4007
+	CloseAlreadyInGame    = 4008
+)
+
+// closeFrame is a pending close request handled by writePump
+type closeFrame struct {
+	code   int
+	reason string
+}
+
+// CloseConn sends a close frame with code and reason, then closes the connection.
+// Used for connections that never got a Client (e.g. auth failure after upgrade).
+func CloseConn(conn *websocket.Conn, code int, reason string) {
+	msg := websocket.FormatCloseMessage(code, reason)
+	if err := conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(writeWait)); err != nil {
+		log.Printf("ws.CloseConn: failed to send close code=%d reason=%s: %v", code, reason, err)
+	}
+	_ = conn.Close()
+}
+
+// CloseWith asks writePump to flush already queued messages, send a close frame
+// with code and reason and close the connection. Safe to call more than once.
+func (c *Client) CloseWith(code int, reason string) {
+	select {
+	case c.closing <- closeFrame{code: code, reason: reason}:
+		log.Printf("Client.CloseWith: user=%d code=%d reason=%s", c.UserID, code, reason)
+	default:
+		// закрытие уже запрошено
+	}
+}
@@ -0,0 +1,121 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/service"
+
+	"github.com/gorilla/websocket"
+	"github.com/jackc/pgx/v5"
+)
+
+// CheckStake reports whether userID can cover bet in currency when connecting to
+// /ws. Nothing is debited here: the room is the only owner of stakes and holds
+// them once the opponent is found (holdStakes). Returns service.ErrInsufficientFunds
+// when the balance is too low; without a user repository every stake passes.
+func (h *Hub) CheckStake(ctx context.Context, userID, bet int64, currency string) error {
+	if bet <= 0 || h.UserRepo == nil {
+		return nil
+	}
+	var (
+		balance int64
+		err     error
+	)
+	if currency == string(domain.CurrencyCoins) {
+		balance, err = h.UserRepo.GetCoins(ctx, userID)
+	} else {
+		balance, err = h.UserRepo.GetGems(ctx, userID)
+	}
+	if err != nil {
+		return err
+	}
+	if balance < bet {
+		return service.ErrInsufficientFunds
+	}
+	return nil
+}
+
+// holdStakes debits BetAmount from both players as pvp_bet_hold before the first
+// round. If the second debit fails the first one is refunded; failedUserID is the
+// player whose stake couldn't be held. Free rooms hold nothing.
+func (r *Room) holdStakes(p1, p2 int64) (failedUserID int64, err error) {
+	if r.BetAmount <= 0 || (r.UserRepo == nil && r.Balance == nil) {
+		return 0, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := r.debit(ctx, p1, p2, r.BetAmount, "pvp_bet_hold"); err != nil {
+		return p1, err
+	}
+	if err := r.debit(ctx, p2, p1, r.BetAmount, "pvp_bet_hold"); err != nil {
+		if rerr := r.credit(ctx, p1, p2, r.BetAmount, "pvp_refund"); rerr != nil {
+			log.Printf("Room.holdStakes: failed to refund user=%d %d %s in room=%s: %v", p1, r.BetAmount, r.Currency, r.ID, rerr)
+		}
+		return p2, err
+	}
+
+	r.mu.Lock()
+	r.escrowed = true
+	r.mu.Unlock()
+
+	log.Printf("Room.holdStakes: room=%s held %d %s from users %d and %d", r.ID, r.BetAmount, r.Currency, p1, p2)
+	return 0, nil
+}
+
+// debit takes amount in the room currency; through BalanceService when set, so a transaction is recorded
+func (r *Room) debit(ctx context.Context, userID, opponentID, amount int64, txType string) error {
+	if userID == BotPlayerID {
+		return nil // у бота нет баланса
+	}
+	if r.Balance != nil {
+		_, err := r.Balance.DebitCurrency(ctx, userID, domain.Currency(r.Currency), amount, txType, map[string]interface{}{
+			"room_id":     r.ID,
+			"game_type":   string(r.game.Type()),
+			"opponent_id": opponentID,
+		})
+		return err
+	}
+
+	var err error
+	if r.Currency == string(domain.CurrencyCoins) {
+		_, err = r.UserRepo.UpdateCoins(ctx, userID, -amount)
+	} else {
+		_, err = r.UserRepo.UpdateGems(ctx, userID, -amount)
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return service.ErrInsufficientFunds
+	}
+	return err
+}
+
+// abortMatch tells both players the match is off because failedUserID's stake
+// couldn't be held, closes their connections and removes the room
+func (r *Room) abortMatch(c1, c2 *Client, failedUserID int64, err error) {
+	log.Printf("Room.abortMatch: room=%s stake of user=%d not held: %v", r.ID, failedUserID, err)
+
+	insufficient := errors.Is(err, service.ErrInsufficientFunds)
+	for _, c := range []*Client{c1, c2} {
+		if c == nil {
+			continue
+		}
+		reason := "opponent_unavailable"
+		code, closeReason := websocket.CloseInternalServerErr, "room_unavailable"
+		if c.UserID == failedUserID {
+			reason = "stake_unavailable"
+			if insufficient {
+				reason = "insufficient_balance"
+				code, closeReason = CloseBadRequest, "insufficient_balance"
+			}
+		}
+		r.sendTo(c, Message{Type: "match_aborted", Payload: map[string]any{"reason": reason}})
+		c.CloseWith(code, closeReason)
+	}
+
+	r.cleanup()
+}
@@ -2,9 +2,9 @@ package ws
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
-	"os"
 	"strconv"
 	"time"
 
@@ -13,7 +13,6 @@ import (
 	"telegram_webapp/internal/service"
 
 	"github.com/gin-gonic/gin"
-	"github.com/gorilla/websocket"
 )
 
 // WSHandler holds dependencies for WebSocket handling
@@ -31,7 +30,7 @@ func NewWSHandler(hub *Hub, userRepo *repository.UserRepository) *WSHandler {
 
 func (h *WSHandler) HandleWS() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		token := c.Query("token")
+		token, _ := h.Hub.RequestToken(c.Request)
 		if token == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "token required"})
 			return
@@ -63,67 +62,27 @@ func (h *WSHandler) HandleWS() gin.HandlerFunc {
 			currency = string(domain.CurrencyGems)
 		}
 
-		// Validate user has enough balance for the bet
-		if betAmount > 0 && h.UserRepo != nil {
+		// Validate user has enough balance for the bet. Nothing is debited yet:
+		// the room holds both stakes once the opponent is found (see Room.holdStakes)
+		if betAmount > 0 {
 			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
 
-			user, err := h.UserRepo.GetByID(ctx, userID)
-			if err != nil || user == nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "user not found"})
-				return
-			}
-
-			var balance int64
-			if currency == string(domain.CurrencyCoins) {
-				balance = user.Coins
-			} else {
-				balance = user.Gems
-			}
-
-			if balance < betAmount {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "insufficient balance"})
-				return
-			}
-
-			// Deduct bet from user balance (reserve it)
-			if currency == string(domain.CurrencyCoins) {
-				if _, err := h.UserRepo.UpdateCoins(ctx, userID, -betAmount); err != nil {
-					log.Printf("HandleWS: failed to deduct coins: %v", err)
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reserve bet"})
-					return
-				}
-			} else {
-				if _, err := h.UserRepo.UpdateGems(ctx, userID, -betAmount); err != nil {
-					log.Printf("HandleWS: failed to deduct gems: %v", err)
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reserve bet"})
-					return
+			if err := h.Hub.CheckStake(ctx, userID, betAmount, currency); err != nil {
+				if errors.Is(err, service.ErrInsufficientFunds) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "insufficient balance"})
+				} else {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "user not found"})
 				}
+				return
 			}
 		}
 
-		allowedOrigin := os.Getenv("ALLOWED_ORIGIN")
-		upgrader := websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				if allowedOrigin == "" {
-					return true
-				}
-				return r.Header.Get("Origin") == allowedOrigin
-			},
-		}
+		upgrader := h.Hub.Upgrader()
 
 		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 		if err != nil {
 			log.Println("ws upgrade error:", err)
-			// Refund the bet if WebSocket upgrade fails
-			if betAmount > 0 && h.UserRepo != nil {
-				ctx := context.Background()
-				if currency == string(domain.CurrencyCoins) {
-					h.UserRepo.UpdateCoins(ctx, userID, betAmount)
-				} else {
-					h.UserRepo.UpdateGems(ctx, userID, betAmount)
-				}
-			}
 			return
 		}
 
@@ -136,7 +95,7 @@ func (h *WSHandler) HandleWS() gin.HandlerFunc {
 // Legacy handler for backwards compatibility (no betting)
 func HandleWS(hub *Hub) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		token := c.Query("token")
+		token, _ := hub.RequestToken(c.Request)
 		if token == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "token required"})
 			return
@@ -154,15 +113,7 @@ func HandleWS(hub *Hub) gin.HandlerFunc {
 			gameType = "rps"
 		}
 
-		allowedOrigin := os.Getenv("ALLOWED_ORIGIN")
-		upgrader := websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				if allowedOrigin == "" {
-					return true
-				}
-				return r.Header.Get("Origin") == allowedOrigin
-			},
-		}
+		upgrader := hub.Upgrader()
 
 		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 		if err != nil {
@@ -0,0 +1,118 @@
+package ws
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// TokenSubprotocol - подпротокол, с которым клиент передаёт JWT:
+// new WebSocket(url, ["bearer", token]). Сервер отвечает только "bearer",
+// сам токен в ответ и в логи (в отличие от ?token= в URL) не попадает.
+const TokenSubprotocol = "bearer"
+
+// SetAllowedOrigins sets which Origin headers may open a socket (empty = any).
+// Requests without Origin (not a browser) are not affected.
+func (h *Hub) SetAllowedOrigins(origins []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.allowedOrigins = nil
+	for _, o := range origins {
+		if o = strings.TrimRight(strings.TrimSpace(o), "/"); o != "" {
+			h.allowedOrigins = append(h.allowedOrigins, o)
+		}
+	}
+}
+
+// SetAllowQueryToken allows the legacy ?token= in the URL next to the subprotocol
+func (h *Hub) SetAllowQueryToken(allow bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.allowQueryToken = allow
+}
+
+// SetMaxConnsPerUser caps open sockets of one user, counted from the handshake (0 = no cap)
+func (h *Hub) SetMaxConnsPerUser(n int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.maxConnsPerUser = n
+}
+
+// CheckOrigin is the Upgrader.CheckOrigin of the hub
+func (h *Hub) CheckOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if len(h.allowedOrigins) == 0 {
+		return true
+	}
+	for _, o := range h.allowedOrigins {
+		if strings.EqualFold(origin, o) {
+			return true
+		}
+	}
+	return false
+}
+
+// Upgrader returns an upgrader that checks the origin and accepts the token subprotocol
+func (h *Hub) Upgrader() websocket.Upgrader {
+	return websocket.Upgrader{
+		CheckOrigin:  h.CheckOrigin,
+		Subprotocols: []string{TokenSubprotocol},
+	}
+}
+
+// RequestToken extracts the JWT from Sec-WebSocket-Protocol ("bearer", <token>)
+// or, when allowed, from ?token=. fromQuery reports a query token that the hub refuses.
+func (h *Hub) RequestToken(r *http.Request) (token string, fromQuery bool) {
+	protocols := websocket.Subprotocols(r)
+	for i := 0; i+1 < len(protocols); i++ {
+		if protocols[i] == TokenSubprotocol {
+			return protocols[i+1], false
+		}
+	}
+
+	token = r.URL.Query().Get("token")
+	if token == "" {
+		return "", false
+	}
+	h.mu.RLock()
+	allow := h.allowQueryToken
+	h.mu.RUnlock()
+	if !allow {
+		return "", true
+	}
+	return token, false
+}
+
+// AcquireConn takes one of the user's socket slots; false when the cap is reached.
+// Every successful call must be paired with ReleaseConn.
+func (h *Hub) AcquireConn(userID int64) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.maxConnsPerUser > 0 && h.userConns[userID] >= h.maxConnsPerUser {
+		return false
+	}
+	h.userConns[userID]++
+	return true
+}
+
+// ReleaseConn frees a slot taken by AcquireConn
+func (h *Hub) ReleaseConn(userID int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.userConns[userID] <= 1 {
+		delete(h.userConns, userID)
+		return
+	}
+	h.userConns[userID]--
+}
+
+// SetOnClose registers fn to run once the client's connection is gone
+func (c *Client) SetOnClose(fn func()) {
+	c.onClose = fn
+}
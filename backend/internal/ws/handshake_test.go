@@ -0,0 +1,73 @@
+package ws
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckOrigin(t *testing.T) {
+	h := NewHub(nil, nil)
+	req := httptest.NewRequest("GET", "/ws", nil)
+	req.Header.Set("Origin", "https://evil.example")
+	if !h.CheckOrigin(req) {
+		t.Fatal("without a list any origin is allowed")
+	}
+
+	h.SetAllowedOrigins([]string{"https://app.example/", " https://t.me "})
+	if h.CheckOrigin(req) {
+		t.Fatal("origin outside the list must be refused")
+	}
+	req.Header.Set("Origin", "https://app.example")
+	if !h.CheckOrigin(req) {
+		t.Fatal("listed origin must be allowed")
+	}
+	req.Header.Del("Origin")
+	if !h.CheckOrigin(req) {
+		t.Fatal("non-browser request without Origin must be allowed")
+	}
+}
+
+func TestRequestToken(t *testing.T) {
+	h := NewHub(nil, nil)
+
+	req := httptest.NewRequest("GET", "/ws?token=query", nil)
+	req.Header.Set("Sec-WebSocket-Protocol", "bearer, header")
+	if token, fromQuery := h.RequestToken(req); token != "header" || fromQuery {
+		t.Fatalf("expected the subprotocol token, got %q %v", token, fromQuery)
+	}
+
+	req = httptest.NewRequest("GET", "/ws?token=query", nil)
+	if token, fromQuery := h.RequestToken(req); token != "" || !fromQuery {
+		t.Fatalf("query token must be refused by default, got %q %v", token, fromQuery)
+	}
+
+	h.SetAllowQueryToken(true)
+	if token, fromQuery := h.RequestToken(req); token != "query" || fromQuery {
+		t.Fatalf("expected the allowed query token, got %q %v", token, fromQuery)
+	}
+}
+
+func TestAcquireConnCap(t *testing.T) {
+	h := NewHub(nil, nil)
+	h.SetMaxConnsPerUser(2)
+
+	if !h.AcquireConn(1) || !h.AcquireConn(1) {
+		t.Fatal("expected two slots")
+	}
+	if h.AcquireConn(1) {
+		t.Fatal("third socket must be refused")
+	}
+	if !h.AcquireConn(2) {
+		t.Fatal("the cap is per user")
+	}
+
+	h.ReleaseConn(1)
+	if !h.AcquireConn(1) {
+		t.Fatal("released slot must be reusable")
+	}
+	h.ReleaseConn(1)
+	h.ReleaseConn(1)
+	if _, ok := h.userConns[1]; ok {
+		t.Fatal("user without sockets must not be kept in the map")
+	}
+}
@@ -1,14 +1,24 @@
 package ws
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
 	"fmt"
 	"log"
-	"strconv"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"telegram_webapp/internal/domain"
 	"telegram_webapp/internal/game"
 	"telegram_webapp/internal/repository"
+	"telegram_webapp/internal/service"
+
+	"github.com/gorilla/websocket"
 )
 
 // WaitingKey uniquely identifies a matchmaking queue
@@ -27,7 +37,8 @@ type Hub struct {
 	Rooms    map[string]*Room
 	UserRoom map[int64]string
 	mu       sync.RWMutex
-	roomSeq  int64
+	// Generates room IDs, default randomRoomID; replaceable in tests
+	roomIDGen func() (string, error)
 	// Separate waiting queues for each game type + bet + currency
 	WaitingByKey map[WaitingKey]*Client
 	// Legacy: for backwards compatibility
@@ -35,6 +46,57 @@ type Hub struct {
 	GameRepo        *repository.GameRepository
 	GameHistoryRepo *repository.GameHistoryRepository
 	UserRepo        *repository.UserRepository
+	BonusRepo       *repository.BonusWageringRepository // settled coin stakes count towards bonus wagering
+	Balance         *service.BalanceService             // PvP payouts are recorded as transactions when set
+	Quests          *service.QuestService               // finished PvP games advance quest progress
+
+	// Number of running cleanup goroutines (see StartCleanup)
+	cleanupWorkers atomic.Int32
+
+	// Max time a live client may wait for an opponent (0 = no limit)
+	defaultMatchTimeout time.Duration
+	matchTimeouts       map[game.GameType]time.Duration
+
+	// After this wait the room is played against the bot (0 = off), see bot.go
+	botFallback time.Duration
+
+	// How long before TurnTimeout idle players get turn_warning (0 = off)
+	turnWarning time.Duration
+
+	// Board for PvP Mines, zero value - game.DefaultMinesConfig
+	minesConfig game.MinesConfig
+
+	// How long a finished room accepts rematch offers (0 = rematch off)
+	rematchWindow time.Duration
+
+	// How long a player who dropped mid-game may reconnect before forfeiting (0 = forfeit at once)
+	reconnectGrace time.Duration
+
+	// House cut of decided PvP pots, credited to rakeAccountID as platform_rake
+	rakePercent   float64
+	rakeAccountID int64
+
+	// set by Shutdown, new clients are refused with CloseServerShutdown
+	shuttingDown bool
+
+	// Handshake checks, see handshake.go
+	allowedOrigins  []string
+	allowQueryToken bool
+	maxConnsPerUser int
+	userConns       map[int64]int
+}
+
+// cleanupWorkerCount is how many cleanup goroutines StartCleanup launches
+const cleanupWorkerCount = 3
+
+// matchExpiryInterval - как часто проверяются истёкшие слоты ожидания
+const matchExpiryInterval = 5 * time.Second
+
+// HubStats is a snapshot of hub counters for health checks
+type HubStats struct {
+	Rooms          int  `json:"rooms"`
+	Waiting        int  `json:"waiting"`
+	CleanupRunning bool `json:"cleanup_running"`
 }
 
 func NewHub(gameRepo *repository.GameRepository, gameHistoryRepo *repository.GameHistoryRepository) *Hub {
@@ -42,7 +104,9 @@ func NewHub(gameRepo *repository.GameRepository, gameHistoryRepo *repository.Gam
 		Rooms:           make(map[string]*Room),
 		UserRoom:        make(map[int64]string),
 		WaitingByKey:    make(map[WaitingKey]*Client),
+		matchTimeouts:   make(map[game.GameType]time.Duration),
 		WaitingByGame:   make(map[game.GameType]*Client),
+		userConns:       make(map[int64]int),
 		GameRepo:        gameRepo,
 		GameHistoryRepo: gameHistoryRepo,
 	}
@@ -73,6 +137,47 @@ func (h *Hub) AssignClient(c *Client) *Room {
 	log.Printf("Hub.AssignClient: user=%d game=%s bet=%d currency=%s - assign via waiting slot (rooms=%d)",
 		c.UserID, gameType, c.BetAmount, c.Currency, len(h.Rooms))
 
+	if h.shuttingDown {
+		h.mu.Unlock()
+		h.rejectClient(c, CloseServerShutdown, "server_shutdown")
+		return nil
+	}
+
+	// Второе живое соединение того же пользователя не пускаем (другая вкладка/устройство)
+	if h.liveClientUnlocked(c.UserID, c) != nil {
+		code, reason := CloseAlreadyConnected, "already_connected"
+		if h.activeRoomUnlocked(c.UserID) != nil {
+			code, reason = CloseAlreadyInGame, "already_in_game"
+		}
+		log.Printf("Hub.AssignClient: user=%d already has a live connection, refusing (%s)", c.UserID, reason)
+		h.mu.Unlock()
+		h.rejectClient(c, code, reason)
+		return nil
+	}
+
+	// Игрок вернулся в идущую игру в пределах grace-периода
+	if room := h.resumableRoomUnlocked(c); room != nil {
+		h.mu.Unlock()
+
+		select {
+		case room.Resume <- c:
+			log.Printf("Hub.AssignClient: user=%d resumed room=%s", c.UserID, room.ID)
+		case <-time.After(5 * time.Second):
+			log.Printf("Hub.AssignClient: TIMEOUT resuming user=%d in room=%s", c.UserID, room.ID)
+			return nil
+		}
+		return room
+	}
+
+	// Старый сокет уже закрыт, но комната ещё идёт (уход не обработан) - вторую игру
+	// не начинаем, иначе UserRoom укажет на новую комнату, а старая останется без игрока
+	if room := h.activeRoomUnlocked(c.UserID); room != nil {
+		log.Printf("Hub.AssignClient: user=%d is still playing in room=%s, refusing", c.UserID, room.ID)
+		h.mu.Unlock()
+		h.rejectClient(c, CloseAlreadyInGame, "already_in_game")
+		return nil
+	}
+
 	// Clean up any stale state for this user (e.g., from previous game/reconnect)
 	if oldRoomID, exists := h.UserRoom[c.UserID]; exists {
 		log.Printf("Hub.AssignClient: user=%d has stale room mapping to %s, cleaning up", c.UserID, oldRoomID)
@@ -177,6 +282,7 @@ func (h *Hub) AssignClient(c *Client) *Room {
 	if room == nil {
 		log.Printf("Hub.AssignClient: failed to create room for user=%d", c.UserID)
 		h.mu.Unlock()
+		h.rejectClient(c, websocket.CloseInternalServerErr, "room_unavailable")
 		return nil
 	}
 
@@ -192,6 +298,7 @@ func (h *Hub) AssignClient(c *Client) *Room {
 	h.UserRoom[c.UserID] = room.ID
 	// mark this client as waiting for a peer with same bet
 	h.WaitingByKey[waitingKey] = c
+	c.waitingSince = time.Now()
 
 	h.mu.Unlock()
 
@@ -214,10 +321,13 @@ func (h *Hub) newRoom(gameType game.GameType, players [2]int64) *Room {
 }
 
 func (h *Hub) newRoomWithBet(gameType game.GameType, players [2]int64, betAmount int64, currency string) *Room {
-	h.roomSeq++
-	id := strconv.FormatInt(h.roomSeq, 10)
+	id, err := h.uniqueRoomIDUnlocked()
+	if err != nil {
+		log.Printf("Hub.newRoom: failed to generate room id: %v", err)
+		return nil
+	}
 
-	factory := game.NewFactory()
+	factory := game.NewFactoryWithMines(h.minesConfig)
 	g, err := factory.CreateGame(gameType, id, players)
 	if err != nil {
 		log.Printf("Hub.newRoom: failed to create game: %v", err)
@@ -228,6 +338,11 @@ func (h *Hub) newRoomWithBet(gameType game.GameType, players [2]int64, betAmount
 	room.BetAmount = betAmount
 	room.Currency = currency
 	room.UserRepo = h.UserRepo
+	room.BonusRepo = h.BonusRepo
+	room.Balance = h.Balance
+	room.Quests = h.Quests
+	room.RakePercent = h.rakePercent
+	room.RakeAccountID = h.rakeAccountID
 	h.Rooms[id] = room
 
 	log.Printf("Hub.newRoom: created room=%s game=%s bet=%d currency=%s, starting Run()", id, gameType, betAmount, currency)
@@ -236,6 +351,235 @@ func (h *Hub) newRoomWithBet(gameType game.GameType, players [2]int64, betAmount
 	return room
 }
 
+// roomIDBytes - 80 random bits, 16 base32 characters
+const roomIDBytes = 10
+
+var roomIDEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// randomRoomID returns an unguessable room ID so rooms can't be found by enumerating IDs
+func randomRoomID() (string, error) {
+	b := make([]byte, roomIDBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return strings.ToLower(roomIDEncoding.EncodeToString(b)), nil
+}
+
+// uniqueRoomIDUnlocked returns an ID not used by any live room. Caller must hold h.mu.
+func (h *Hub) uniqueRoomIDUnlocked() (string, error) {
+	gen := h.roomIDGen
+	if gen == nil {
+		gen = randomRoomID
+	}
+	for attempt := 0; attempt < 5; attempt++ {
+		id, err := gen()
+		if err != nil {
+			return "", err
+		}
+		if _, exists := h.Rooms[id]; !exists {
+			return id, nil
+		}
+	}
+	return "", errors.New("no free room id")
+}
+
+// rejectClient records why the client was refused
+func (h *Hub) rejectClient(c *Client, code int, reason string) {
+	c.rejectCode, c.rejectReason = code, reason
+}
+
+// activeRoomUnlocked returns the user's mapped room if a game with an opponent is
+// going on there (not waiting, not finished), or nil - caller must hold hub lock
+func (h *Hub) activeRoomUnlocked(userID int64) *Room {
+	room, ok := h.Rooms[h.UserRoom[userID]]
+	if !ok {
+		return nil
+	}
+	room.mu.RLock()
+	defer room.mu.RUnlock()
+	if room.game.Players()[1] == 0 || room.game.IsFinished() {
+		return nil
+	}
+	return room
+}
+
+// liveClientUnlocked returns another still connected client of the user in its
+// mapped room, or nil - caller must hold lock
+func (h *Hub) liveClientUnlocked(userID int64, except *Client) *Client {
+	roomID, ok := h.UserRoom[userID]
+	if !ok {
+		return nil
+	}
+	room, ok := h.Rooms[roomID]
+	if !ok {
+		return nil
+	}
+	room.mu.RLock()
+	old := room.Clients[userID]
+	room.mu.RUnlock()
+	if old == nil || old == except {
+		return nil
+	}
+	select {
+	case <-old.Done:
+		return nil // readPump уже завершился
+	default:
+		return old
+	}
+}
+
+// Kick closes the user's live connection with CloseKicked. The room handles it as
+// a regular disconnect. Returns false if the user has no connection.
+func (h *Hub) Kick(userID int64, reason string) bool {
+	h.mu.RLock()
+	c := h.liveClientUnlocked(userID, nil)
+	h.mu.RUnlock()
+	if c == nil {
+		return false
+	}
+	if reason == "" {
+		reason = "kicked"
+	}
+	c.CloseWith(CloseKicked, reason)
+	return true
+}
+
+// Shutdown closes every connection with CloseServerShutdown. Stakes of unfinished
+// rooms are refunded first so the disconnects don't count as forfeits. Waits for
+// the clients to go away or ctx to expire.
+func (h *Hub) Shutdown(ctx context.Context) {
+	h.mu.Lock()
+	h.shuttingDown = true
+	rooms := make([]*Room, 0, len(h.Rooms))
+	for _, room := range h.Rooms {
+		rooms = append(rooms, room)
+	}
+	h.mu.Unlock()
+
+	var clients []*Client
+	for _, room := range rooms {
+		room.mu.Lock()
+		var refund []int64
+		if room.escrowed && !room.betPaid {
+			room.betPaid = true
+			for _, uid := range room.game.Players() {
+				if uid != 0 {
+					refund = append(refund, uid)
+				}
+			}
+		}
+		for _, c := range room.Clients {
+			clients = append(clients, c)
+		}
+		room.mu.Unlock()
+
+		for _, uid := range refund {
+			room.refundBet(uid)
+		}
+	}
+
+	log.Printf("Hub.Shutdown: closing %d clients in %d rooms", len(clients), len(rooms))
+	for _, c := range clients {
+		c.CloseWith(CloseServerShutdown, "server_shutdown")
+	}
+	for _, c := range clients {
+		select {
+		case <-c.Done:
+		case <-ctx.Done():
+			log.Printf("Hub.Shutdown: timeout waiting for clients to disconnect")
+			return
+		}
+	}
+}
+
+// ErrRematchInsufficientBalance - одному из игроков не хватает на повторную ставку
+var ErrRematchInsufficientBalance = errors.New("insufficient balance for rematch")
+
+// StartRematch moves both players of a finished room into a new room with the same
+// game type and stake, skipping matchmaking. The new room holds the stakes again.
+func (h *Hub) StartRematch(prev *Room, clients [2]*Client) (*Room, error) {
+	for _, c := range clients {
+		if err := h.checkStake(c); err != nil {
+			return nil, err
+		}
+	}
+
+	h.mu.Lock()
+	var room *Room
+	if !h.shuttingDown {
+		room = h.newRoomWithBet(prev.game.Type(), [2]int64{clients[0].UserID, clients[1].UserID}, prev.BetAmount, prev.Currency)
+	}
+	if room == nil {
+		h.mu.Unlock()
+		return nil, errors.New("room unavailable")
+	}
+	for _, c := range clients {
+		h.UserRoom[c.UserID] = room.ID
+	}
+	h.mu.Unlock()
+
+	log.Printf("Hub.StartRematch: room=%s -> room=%s players=%d,%d", prev.ID, room.ID, clients[0].UserID, clients[1].UserID)
+
+	// Регистрируем по одному, как при обычном подборе - "matched" уйдёт один раз
+	for _, c := range clients {
+		room.mu.Lock()
+		room.Clients[c.UserID] = c
+		room.mu.Unlock()
+		c.Room = room
+
+		select {
+		case room.Register <- c:
+		case <-time.After(5 * time.Second):
+			log.Printf("Hub.StartRematch: TIMEOUT registering user=%d to room=%s", c.UserID, room.ID)
+		}
+	}
+	return room, nil
+}
+
+// checkStake reports whether the client can still cover the stake (rematch).
+// Nothing is debited here - the new room holds the stakes at match start.
+func (h *Hub) checkStake(c *Client) error {
+	if h.UserRepo == nil || c.BetAmount <= 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.UserRepo.GetByID(ctx, c.UserID)
+	if err != nil {
+		return err
+	}
+	balance := user.Gems
+	if c.Currency == string(domain.CurrencyCoins) {
+		balance = user.Coins
+	}
+	if balance < c.BetAmount {
+		return ErrRematchInsufficientBalance
+	}
+	return nil
+}
+
+// SetRematchWindow sets how long a finished room accepts rematch offers (0 = off)
+func (h *Hub) SetRematchWindow(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.rematchWindow = d
+}
+
+// SetRake sets the house cut of decided PvP pots in percent (0 = winner takes the
+// whole pot) and the account it is credited to. Without an account the cut
+// would vanish, so the rake stays off.
+func (h *Hub) SetRake(percent float64, accountID int64) {
+	if accountID <= 0 {
+		percent = 0
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.rakePercent = percent
+	h.rakeAccountID = accountID
+}
+
 func (h *Hub) OnDisconnect(c *Client) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
@@ -270,8 +614,117 @@ func (h *Hub) OnDisconnect(c *Client) {
 	}
 }
 
+// Stats returns current room/waiting counters read under lock
+func (h *Hub) Stats() HubStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	waiting := 0
+	for _, c := range h.WaitingByKey {
+		if c != nil {
+			waiting++
+		}
+	}
+	for _, c := range h.WaitingByGame {
+		if c != nil {
+			waiting++
+		}
+	}
+
+	return HubStats{
+		Rooms:          len(h.Rooms),
+		Waiting:        waiting,
+		CleanupRunning: h.cleanupWorkers.Load() == cleanupWorkerCount,
+	}
+}
+
+// QueueBucket is the number of players waiting for an opponent with the same bet
+type QueueBucket struct {
+	GameType  game.GameType `json:"game_type"`
+	BetAmount int64         `json:"bet"`
+	Currency  string        `json:"currency"`
+	Waiting   int           `json:"waiting"`
+}
+
+// QueueStats returns waiting players per bet bucket, optionally filtered by game
+// type ("" - all). The snapshot is copied under lock and sorted for stable output.
+func (h *Hub) QueueStats(gameType game.GameType) []QueueBucket {
+	h.mu.RLock()
+	buckets := h.queueBucketsUnlocked(gameType)
+	h.mu.RUnlock()
+	return buckets
+}
+
+// MatchmakingStats is a snapshot of the queues and rooms with a game in progress
+type MatchmakingStats struct {
+	Queues      []QueueBucket `json:"queues"`
+	Waiting     int           `json:"waiting"`
+	ActiveRooms int           `json:"active_rooms"`
+}
+
+// MatchmakingStats returns queues and the number of rooms where both seats are
+// taken and the game is not finished, read under one lock so the numbers agree.
+// Only counts leave the hub, no clients or user ids.
+func (h *Hub) MatchmakingStats(gameType game.GameType) MatchmakingStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stats := MatchmakingStats{Queues: h.queueBucketsUnlocked(gameType)}
+	for _, b := range stats.Queues {
+		stats.Waiting += b.Waiting
+	}
+	for _, room := range h.Rooms {
+		room.mu.RLock()
+		g := room.game
+		room.mu.RUnlock()
+		if g == nil || (gameType != "" && g.Type() != gameType) {
+			continue
+		}
+		if g.Players()[1] != 0 && !g.IsFinished() {
+			stats.ActiveRooms++
+		}
+	}
+	return stats
+}
+
+// queueBucketsUnlocked - caller must hold lock
+func (h *Hub) queueBucketsUnlocked(gameType game.GameType) []QueueBucket {
+	buckets := make([]QueueBucket, 0, len(h.WaitingByKey))
+	for key, c := range h.WaitingByKey {
+		if c == nil || (gameType != "" && key.GameType != gameType) {
+			continue
+		}
+		select {
+		case <-c.Done:
+			continue // отключился, слот ещё не вычищен
+		default:
+		}
+		buckets = append(buckets, QueueBucket{
+			GameType:  key.GameType,
+			BetAmount: key.BetAmount,
+			Currency:  key.Currency,
+			Waiting:   1,
+		})
+	}
+
+	sort.Slice(buckets, func(i, j int) bool {
+		a, b := buckets[i], buckets[j]
+		if a.GameType != b.GameType {
+			return a.GameType < b.GameType
+		}
+		if a.Currency != b.Currency {
+			return a.Currency < b.Currency
+		}
+		return a.BetAmount < b.BetAmount
+	})
+	return buckets
+}
+
 func (h *Hub) StartCleanup() {
 	go func() {
+		h.cleanupWorkers.Add(1)
+		defer h.cleanupWorkers.Add(-1)
+
 		ticker := time.NewTicker(10 * time.Minute)
 		defer ticker.Stop()
 
@@ -280,8 +733,24 @@ func (h *Hub) StartCleanup() {
 		}
 	}()
 
+	// Expire live clients that waited too long for an opponent
+	go func() {
+		h.cleanupWorkers.Add(1)
+		defer h.cleanupWorkers.Add(-1)
+
+		ticker := time.NewTicker(matchExpiryInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			h.expireWaiting()
+		}
+	}()
+
 	// More frequent cleanup for waiting slots (every 30 seconds)
 	go func() {
+		h.cleanupWorkers.Add(1)
+		defer h.cleanupWorkers.Add(-1)
+
 		ticker := time.NewTicker(30 * time.Second)
 		defer ticker.Stop()
 
@@ -291,6 +760,123 @@ func (h *Hub) StartCleanup() {
 	}()
 }
 
+// SetDefaultMatchTimeout sets max matchmaking wait for game types without an override
+func (h *Hub) SetDefaultMatchTimeout(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.defaultMatchTimeout = d
+}
+
+// SetMatchTimeout sets max matchmaking wait for a game type (0 = no limit)
+func (h *Hub) SetMatchTimeout(gameType game.GameType, d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.matchTimeouts[gameType] = d
+}
+
+// SetTurnWarning sets how long before the turn timeout idle players are warned (0 = off)
+func (h *Hub) SetTurnWarning(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.turnWarning = d
+}
+
+// SetMinesConfig sets board size and mine count for new Mines rooms; invalid config is ignored
+func (h *Hub) SetMinesConfig(cfg game.MinesConfig) {
+	if !cfg.Valid() {
+		log.Printf("Hub.SetMinesConfig: invalid config %+v, keeping default", cfg)
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.minesConfig = cfg
+}
+
+// matchTimeoutFor returns max wait for a game type - caller must hold lock
+func (h *Hub) matchTimeoutFor(gameType game.GameType) time.Duration {
+	if d, ok := h.matchTimeouts[gameType]; ok {
+		return d
+	}
+	return h.defaultMatchTimeout
+}
+
+// expireWaiting removes live clients that waited longer than the match timeout.
+// The client gets {"type":"no_match"} followed by close code CloseMatchTimeout and
+// may retry or pick PvE; its room is terminated via the Disconnect path. No
+// stake is held until a match, so there is nothing to refund.
+// With botFallback set, clients that waited that long get the bot as an opponent
+// instead (see Room.handleBotJoin). Coin rooms get the bot only while a rake is
+// set: the rake is the house edge of a bot game, and coins are never staked on a
+// game without one; such clients keep waiting for a human until the match timeout.
+func (h *Hub) expireWaiting() {
+	type expired struct {
+		client *Client
+		room   *Room
+	}
+
+	h.mu.Lock()
+	now := time.Now()
+	var list []expired
+	var botRooms []*Room
+	for key, waiting := range h.WaitingByKey {
+		if waiting == nil || waiting.waitingSince.IsZero() {
+			continue
+		}
+		waited := now.Sub(waiting.waitingSince)
+
+		if h.botFallback > 0 && waited >= h.botFallback && h.botAllowed(key.Currency) {
+			if roomID, ok := h.UserRoom[waiting.UserID]; ok && h.Rooms[roomID] != nil {
+				log.Printf("Hub.expireWaiting: user=%d waited %s for key=%s, playing the bot", waiting.UserID, waited.Round(time.Second), key)
+				// слот освобождается сразу - живой соперник сюда уже не попадёт
+				delete(h.WaitingByKey, key)
+				botRooms = append(botRooms, h.Rooms[roomID])
+				continue
+			}
+		}
+
+		timeout := h.matchTimeoutFor(key.GameType)
+		if timeout <= 0 || waited < timeout {
+			continue
+		}
+
+		log.Printf("Hub.expireWaiting: user=%d waited %s for key=%s, no match", waiting.UserID, waited.Round(time.Second), key)
+		delete(h.WaitingByKey, key)
+
+		var room *Room
+		if roomID, ok := h.UserRoom[waiting.UserID]; ok {
+			room = h.Rooms[roomID]
+		}
+		list = append(list, expired{client: waiting, room: room})
+	}
+	h.mu.Unlock()
+
+	for _, room := range botRooms {
+		select {
+		case room.botJoin <- struct{}{}:
+		default:
+		}
+	}
+
+	// Send and terminate without holding hub lock (room cleanup takes it)
+	for _, e := range list {
+		select {
+		case e.client.Send <- []byte(`{"type":"no_match"}`):
+		default:
+			log.Printf("Hub.expireWaiting: user=%d Send channel blocked", e.client.UserID)
+		}
+		e.client.CloseWith(CloseMatchTimeout, "match_timeout")
+
+		if e.room == nil {
+			continue
+		}
+		select {
+		case e.room.Disconnect <- e.client:
+		default:
+			log.Printf("Hub.expireWaiting: room=%s Disconnect channel full/closed", e.room.ID)
+		}
+	}
+}
+
 func (h *Hub) cleanupStaleWaiting() {
 	h.mu.Lock()
 	defer h.mu.Unlock()
@@ -0,0 +1,104 @@
+package ws
+
+import (
+	"testing"
+
+	"telegram_webapp/internal/game"
+)
+
+func TestRandomRoomIDIsUnguessable(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		id, err := randomRoomID()
+		if err != nil {
+			t.Fatalf("randomRoomID: %v", err)
+		}
+		if len(id) != 16 {
+			t.Fatalf("expected 16 characters, got %q", id)
+		}
+		if seen[id] {
+			t.Fatalf("duplicate room id %q", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestUniqueRoomIDSkipsTakenIDs(t *testing.T) {
+	h := NewHub(nil, nil)
+	h.Rooms["taken"] = &Room{ID: "taken"}
+
+	ids := []string{"taken", "taken", "free"}
+	h.roomIDGen = func() (string, error) {
+		id := ids[0]
+		ids = ids[1:]
+		return id, nil
+	}
+	if id, err := h.uniqueRoomIDUnlocked(); err != nil || id != "free" {
+		t.Fatalf("expected free, got %q, %v", id, err)
+	}
+
+	h.roomIDGen = func() (string, error) { return "taken", nil }
+	if _, err := h.uniqueRoomIDUnlocked(); err == nil {
+		t.Fatal("expected error when every generated id is taken")
+	}
+}
+
+func TestMatchmakingStats(t *testing.T) {
+	h := NewHub(nil, nil)
+	waiting := &Client{UserID: 1, Done: make(chan struct{})}
+	h.WaitingByKey[WaitingKey{GameType: game.TypeRPS, BetAmount: 10, Currency: "gems"}] = waiting
+	h.WaitingByKey[WaitingKey{GameType: game.TypeMines, BetAmount: 50, Currency: "coins"}] = &Client{UserID: 2, Done: make(chan struct{})}
+	h.Rooms["waiting"] = NewRoom("waiting", game.NewRPSGame("waiting", [2]int64{1, 0}), h)
+	h.Rooms["playing"] = NewRoom("playing", game.NewRPSGame("playing", [2]int64{3, 4}), h)
+	h.Rooms["mines"] = NewRoom("mines", game.NewMinesGame("mines", [2]int64{5, 6}), h)
+
+	stats := h.MatchmakingStats("")
+	if stats.Waiting != 2 || len(stats.Queues) != 2 || stats.ActiveRooms != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	stats = h.MatchmakingStats(game.TypeRPS)
+	if stats.Waiting != 1 || stats.ActiveRooms != 1 || stats.Queues[0].BetAmount != 10 {
+		t.Fatalf("unexpected rps stats: %+v", stats)
+	}
+}
+
+func TestSecondSocketDoesNotStartSecondGame(t *testing.T) {
+	h := NewHub(nil, nil)
+	room, c1, _ := startTestMatch(t, h)
+
+	// Вторая вкладка того же игрока, пока первая играет
+	c1b := newTestClient(h, 1)
+	if got := h.AssignClient(c1b); got != nil {
+		t.Fatalf("second socket must not get a room, got %s", got.ID)
+	}
+	if c1b.rejectReason != "already_in_game" || c1b.rejectCode != CloseAlreadyInGame {
+		t.Fatalf("expected already_in_game, got %d %s", c1b.rejectCode, c1b.rejectReason)
+	}
+
+	// Первый сокет закрылся, но комната ещё не обработала уход
+	close(c1.Done)
+	c1c := newTestClient(h, 1)
+	if got := h.AssignClient(c1c); got != nil || c1c.rejectReason != "already_in_game" {
+		t.Fatalf("expected already_in_game while the room is running, got %v %s", got, c1c.rejectReason)
+	}
+
+	h.mu.RLock()
+	rooms, mapped := len(h.Rooms), h.UserRoom[1]
+	h.mu.RUnlock()
+	if rooms != 1 || mapped != room.ID {
+		t.Fatalf("expected only the original match, rooms=%d user room=%s", rooms, mapped)
+	}
+}
+
+func TestSetRakeNeedsAccount(t *testing.T) {
+	h := NewHub(nil, nil)
+	h.SetRake(5, 0)
+	if h.rakePercent != 0 {
+		t.Fatalf("rake without account = %v, want 0", h.rakePercent)
+	}
+	h.SetRake(5, 42)
+	if h.rakePercent != 5 || h.rakeAccountID != 42 {
+		t.Fatalf("rake = %v to %d, want 5 to 42", h.rakePercent, h.rakeAccountID)
+	}
+}
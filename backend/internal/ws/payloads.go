@@ -16,6 +16,8 @@ type ResultPayload struct {
 	Moves  map[int64]string `json:"moves"`
 }
 
+// ErrorPayload - payload сообщения error; Code из списка в protocol.go
 type ErrorPayload struct {
+	Code    string `json:"code"`
 	Message string `json:"message"`
 }
@@ -0,0 +1,89 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"telegram_webapp/internal/game"
+)
+
+// ProtocolVersion - версия набора сообщений клиента, приходит в ready.
+// Новые типы сообщений добавляются вместе с увеличением версии.
+const ProtocolVersion = 1
+
+// clientMessageTypes - сообщения клиента, которые принимает сервер (версия 1)
+var clientMessageTypes = map[string]bool{
+	MsgMove:    true,
+	MsgSetup:   true,
+	MsgReady:   true,
+	MsgPing:    true,
+	MsgRematch: true,
+}
+
+// Коды ошибок в {"type":"error","payload":{"code":...,"message":...}}
+const (
+	ErrCodeBadMessage      = "bad_message"       // не JSON или нет type
+	ErrCodeUnknownMessage  = "unknown_message"   // type не из clientMessageTypes
+	ErrCodeInvalidPayload  = "invalid_payload"   // value не той формы для этой игры
+	ErrCodeInvalidMove     = "invalid_move"      // игра отклонила ход
+	ErrCodeGameNotFinished = "game_not_finished" // rematch до конца игры
+)
+
+// clientMessage is a raw message from the client; Value is decoded per game type
+type clientMessage struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// parseClientMessage decodes the envelope and checks the type against clientMessageTypes
+func parseClientMessage(raw []byte) (clientMessage, *ErrorPayload) {
+	var msg clientMessage
+	if err := json.Unmarshal(raw, &msg); err != nil || msg.Type == "" {
+		return msg, &ErrorPayload{Code: ErrCodeBadMessage, Message: "message must be a JSON object with a type"}
+	}
+	if !clientMessageTypes[msg.Type] {
+		return msg, &ErrorPayload{Code: ErrCodeUnknownMessage, Message: fmt.Sprintf("unknown message type %q", msg.Type)}
+	}
+	return msg, nil
+}
+
+// isPing reports whether raw is a heartbeat ping; cheap enough to call on every frame
+func isPing(raw []byte) bool {
+	var msg struct {
+		Type string `json:"type"`
+	}
+	return json.Unmarshal(raw, &msg) == nil && msg.Type == MsgPing
+}
+
+var errInvalidPayload = errors.New("invalid payload")
+
+// decodeMoveValue converts value into what game.HandleMove expects: RPS - string,
+// Mines - []int while mines are being placed (setupDone false) and int for a picked
+// cell afterwards. The phase decides the shape, not the JSON type, so a board sent
+// during play or a cell sent during setup is an error. Values are checked by the game.
+func decodeMoveValue(gameType game.GameType, msgType string, setupDone bool, value json.RawMessage) (interface{}, error) {
+	switch gameType {
+	case game.TypeRPS:
+		var move string
+		if msgType != MsgMove || json.Unmarshal(value, &move) != nil {
+			return nil, fmt.Errorf("%w: rps expects move with a string value", errInvalidPayload)
+		}
+		return move, nil
+
+	case game.TypeMines:
+		if !setupDone {
+			var cells []int
+			if string(value) == "null" || json.Unmarshal(value, &cells) != nil {
+				return nil, fmt.Errorf("%w: mines are being placed, expected an array of cell numbers", errInvalidPayload)
+			}
+			return cells, nil
+		}
+		var cell int
+		if msgType != MsgMove || string(value) == "null" || json.Unmarshal(value, &cell) != nil {
+			return nil, fmt.Errorf("%w: mines are placed, expected move with a cell number", errInvalidPayload)
+		}
+		return cell, nil
+	}
+	return nil, fmt.Errorf("%w: unsupported game type %q", errInvalidPayload, gameType)
+}
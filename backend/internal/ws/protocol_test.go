@@ -0,0 +1,167 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"telegram_webapp/internal/game"
+)
+
+func TestParseClientMessage(t *testing.T) {
+	cases := []struct {
+		raw  string
+		code string
+	}{
+		{`{"type":"move","value":"rock"}`, ""},
+		{`{"type":"setup","value":[1,2,3,4]}`, ""},
+		{`{"type":"ready"}`, ""},
+		{`{"type":"ping"}`, ""},
+		{`{"type":"rematch"}`, ""},
+		{`{"type":"chat","value":"hi"}`, ErrCodeUnknownMessage},
+		{`{"value":5}`, ErrCodeBadMessage},
+		{`not json`, ErrCodeBadMessage},
+	}
+	for _, tc := range cases {
+		_, perr := parseClientMessage([]byte(tc.raw))
+		code := ""
+		if perr != nil {
+			code = perr.Code
+		}
+		if code != tc.code {
+			t.Fatalf("%s: expected code %q, got %q", tc.raw, tc.code, code)
+		}
+	}
+}
+
+func TestDecodeMoveValue(t *testing.T) {
+	cases := []struct {
+		gameType  game.GameType
+		msgType   string
+		setupDone bool
+		value     string
+		want      interface{}
+	}{
+		{game.TypeRPS, MsgMove, true, `"rock"`, "rock"},
+		{game.TypeRPS, MsgMove, true, `5`, nil},
+		{game.TypeRPS, MsgSetup, true, `"rock"`, nil},
+		{game.TypeMines, MsgSetup, false, `[1,2,3,4]`, []int{1, 2, 3, 4}},
+		{game.TypeMines, MsgMove, false, `[1,2,3,4]`, []int{1, 2, 3, 4}},
+		{game.TypeMines, MsgSetup, false, `[1.5,2]`, nil},
+		{game.TypeMines, MsgSetup, false, `null`, nil},
+		{game.TypeMines, MsgMove, true, `5`, 5},
+		{game.TypeMines, MsgMove, true, `"5"`, nil},
+		{game.TypeMines, MsgMove, true, `null`, nil},
+		{game.TypeMines, MsgMove, true, ``, nil},
+		// фаза решает, а не тип JSON
+		{game.TypeMines, MsgMove, false, `5`, nil},
+		{game.TypeMines, MsgSetup, false, `5`, nil},
+		{game.TypeMines, MsgMove, true, `[1,2,3,4]`, nil},
+		{game.TypeMines, MsgSetup, true, `[1,2,3,4]`, nil},
+		{game.TypeMines, MsgSetup, true, `5`, nil},
+	}
+	for _, tc := range cases {
+		got, err := decodeMoveValue(tc.gameType, tc.msgType, tc.setupDone, json.RawMessage(tc.value))
+		if tc.want == nil {
+			if !errors.Is(err, errInvalidPayload) {
+				t.Fatalf("%s %s setupDone=%v %s: expected invalid payload, got %v, %v", tc.gameType, tc.msgType, tc.setupDone, tc.value, got, err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("%s %s setupDone=%v %s: expected %v, got %v, %v", tc.gameType, tc.msgType, tc.setupDone, tc.value, tc.want, got, err)
+		}
+	}
+}
+
+// readSent returns the next message queued for the client
+func readSent(t *testing.T, c *Client) map[string]any {
+	t.Helper()
+	select {
+	case raw := <-c.Send:
+		var msg map[string]any
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			t.Fatalf("bad message %s: %v", raw, err)
+		}
+		return msg
+	default:
+		t.Fatal("expected a message for the client")
+		return nil
+	}
+}
+
+func TestHandleMessageStructuredErrors(t *testing.T) {
+	r := NewRoom("room", game.NewRPSGame("room", [2]int64{1, 2}), nil)
+	c := &Client{UserID: 1, Send: make(chan []byte, 4)}
+	r.Clients[1] = c
+
+	r.HandleMessage(c, []byte(`{"type":"chat"}`))
+	msg := readSent(t, c)
+	payload, _ := msg["payload"].(map[string]any)
+	if msg["type"] != MsgError || payload["code"] != ErrCodeUnknownMessage {
+		t.Fatalf("expected unknown_message error, got %v", msg)
+	}
+
+	r.HandleMessage(c, []byte(`{"type":"move","value":["rock"]}`))
+	if payload, _ := readSent(t, c)["payload"].(map[string]any); payload["code"] != ErrCodeInvalidPayload {
+		t.Fatalf("expected invalid_payload, got %v", payload)
+	}
+
+	r.HandleMessage(c, []byte(`{"type":"move","value":"lizard"}`))
+	if payload, _ := readSent(t, c)["payload"].(map[string]any); payload["code"] != ErrCodeInvalidMove {
+		t.Fatalf("expected invalid_move, got %v", payload)
+	}
+
+	r.HandleMessage(c, []byte(`{"type":"ping"}`))
+	if msg := readSent(t, c); msg["type"] != MsgPong {
+		t.Fatalf("expected pong, got %v", msg)
+	}
+
+	r.HandleMessage(c, []byte(`{"type":"move","value":"rock"}`))
+	if len(c.Send) != 0 {
+		t.Fatalf("valid move should not produce a reply, got %s", <-c.Send)
+	}
+	if !r.game.HasMoved(1) {
+		t.Fatal("expected the move to reach the game")
+	}
+}
+
+func TestHandleMessageMinesPhaseMismatch(t *testing.T) {
+	r := NewRoom("room", game.NewMinesGame("room", [2]int64{1, 2}), nil)
+	c1 := &Client{UserID: 1, Send: make(chan []byte, 4)}
+	c2 := &Client{UserID: 2, Send: make(chan []byte, 4)}
+	r.Clients[1], r.Clients[2] = c1, c2
+
+	// Ход во время расстановки не считается расстановкой
+	r.HandleMessage(c1, []byte(`{"type":"move","value":5}`))
+	if payload, _ := readSent(t, c1)["payload"].(map[string]any); payload["code"] != ErrCodeInvalidPayload {
+		t.Fatalf("expected invalid_payload for a move during setup, got %v", payload)
+	}
+	if r.game.HasMoved(1) {
+		t.Fatal("a cell number must not place mines")
+	}
+
+	r.HandleMessage(c1, []byte(`{"type":"setup","value":[1,2,3,4]}`))
+	r.HandleMessage(c2, []byte(`{"type":"setup","value":[5,6,7,8]}`))
+	if !r.game.IsSetupComplete() {
+		t.Fatal("expected setup to be complete")
+	}
+
+	// Расстановка во время игры не считается ходом
+	r.HandleMessage(c1, []byte(`{"type":"move","value":[9,10,11,12]}`))
+	if payload, _ := readSent(t, c1)["payload"].(map[string]any); payload["code"] != ErrCodeInvalidPayload {
+		t.Fatalf("expected invalid_payload for a board during play, got %v", payload)
+	}
+	if r.game.HasMoved(1) {
+		t.Fatal("an array must not be taken as a picked cell")
+	}
+
+	r.HandleMessage(c1, []byte(`{"type":"move","value":5}`))
+	if len(c1.Send) != 0 {
+		t.Fatalf("valid move should not produce a reply, got %s", <-c1.Send)
+	}
+	if !r.game.HasMoved(1) {
+		t.Fatal("expected the picked cell to reach the game")
+	}
+}
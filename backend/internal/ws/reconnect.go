@@ -0,0 +1,165 @@
+package ws
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// SetReconnectGrace sets how long a player who dropped mid-game may reconnect
+// and resume the room before losing by forfeit (0 = forfeit at once)
+func (h *Hub) SetReconnectGrace(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.reconnectGrace = d
+}
+
+// resumableRoomUnlocked re-attaches the client to the room where its user is
+// waiting to reconnect, or returns nil - caller must hold hub lock
+func (h *Hub) resumableRoomUnlocked(c *Client) *Room {
+	roomID, ok := h.UserRoom[c.UserID]
+	if !ok {
+		return nil
+	}
+	room, ok := h.Rooms[roomID]
+	if !ok {
+		return nil
+	}
+
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	t, ok := room.disconnected[c.UserID]
+	if !ok {
+		return nil
+	}
+	t.Stop()
+	delete(room.disconnected, c.UserID)
+	room.Clients[c.UserID] = c
+	return room
+}
+
+// reconnectGrace reads the hub setting; no grace while the server shuts down
+func (r *Room) reconnectGrace() time.Duration {
+	if r.hub == nil {
+		return 0
+	}
+	r.hub.mu.RLock()
+	defer r.hub.mu.RUnlock()
+	if r.hub.shuttingDown {
+		return 0
+	}
+	return r.hub.reconnectGrace
+}
+
+// awaitReconnectUnlocked starts the forfeit timer of a dropped player - caller must hold lock
+func (r *Room) awaitReconnectUnlocked(userID int64, grace time.Duration) {
+	if t, ok := r.disconnected[userID]; ok {
+		t.Stop()
+	}
+	r.disconnected[userID] = time.AfterFunc(grace, func() {
+		select {
+		case r.reconnectExpired <- userID:
+		default:
+		}
+	})
+}
+
+// stopReconnectTimersUnlocked - caller must hold lock
+func (r *Room) stopReconnectTimersUnlocked() {
+	for uid, t := range r.disconnected {
+		t.Stop()
+		delete(r.disconnected, uid)
+	}
+}
+
+// handleResume sends the reconnected player the current game state and tells the opponent
+func (r *Room) handleResume(c *Client) {
+	r.mu.RLock()
+	var opponentID int64
+	for _, uid := range r.game.Players() {
+		if uid != c.UserID {
+			opponentID = uid
+		}
+	}
+	opponent := r.Clients[opponentID]
+	payload := r.matchedPayload(opponentID)
+	payload["game_type"] = string(r.game.Type())
+	payload["bet"] = r.BetAmount
+	payload["currency"] = r.Currency
+	payload["state"] = r.game.SerializeState(c.UserID)
+	r.mu.RUnlock()
+
+	if c.Registered != nil {
+		close(c.Registered)
+	}
+
+	r.sendTo(c, Message{Type: "resumed", Payload: payload})
+	if opponent != nil {
+		r.sendTo(opponent, Message{Type: "opponent_reconnected"})
+	}
+
+	// Ходы, присланные до назначения комнаты
+	c.pendingMu.Lock()
+	pending := c.pending
+	c.pending = nil
+	c.pendingMu.Unlock()
+	for _, m := range pending {
+		r.HandleMessage(c, m)
+	}
+}
+
+// handleReconnectTimeout gives the win to the opponent of a player who didn't
+// come back in time. Returns true if the room terminated.
+func (r *Room) handleReconnectTimeout(userID int64) bool {
+	r.mu.Lock()
+	if _, ok := r.disconnected[userID]; !ok || r.game.IsFinished() {
+		// Успел переподключиться или игра уже закончилась сама
+		r.mu.Unlock()
+		return false
+	}
+	delete(r.disconnected, userID)
+
+	var winnerID int64
+	for _, uid := range r.game.Players() {
+		if uid != userID {
+			winnerID = uid
+		}
+	}
+	forfeit := r.game.ForceFinish(winnerID, "opponent_left")
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+	r.stopWarnTimerUnlocked()
+	r.finishedAt = time.Now()
+	winner := r.Clients[winnerID]
+	r.mu.Unlock()
+
+	log.Printf("Room.handleReconnectTimeout: room=%s user=%d didn't reconnect, winner=%d forfeit=%v",
+		r.ID, userID, winnerID, forfeit)
+	r.saveResult()
+	if forfeit && winner != nil {
+		r.sendForfeitWin(winner)
+	}
+	r.cleanup()
+	return true
+}
+
+// sendForfeitWin tells the remaining player they won because the opponent left
+func (r *Room) sendForfeitWin(c *Client) {
+	data, _ := json.Marshal(Message{
+		Type: "result",
+		Payload: map[string]any{
+			"you":        "win",
+			"reason":     "opponent_left",
+			"win_amount": r.winnerPayout(),
+			"currency":   r.Currency,
+		},
+	})
+	select {
+	case c.Send <- data:
+		log.Printf("Room.sendForfeitWin: sent win to user=%d", c.UserID)
+	case <-time.After(2 * time.Second):
+		log.Printf("Room.sendForfeitWin: timeout sending win to user=%d", c.UserID)
+	}
+}
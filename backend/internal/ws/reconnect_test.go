@@ -0,0 +1,103 @@
+package ws
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client without a connection, its Send channel is read by the test
+func newTestClient(h *Hub, userID int64) *Client {
+	c := NewClient(userID, nil, h, "rps", 0, "gems")
+	close(c.Ready)
+	return c
+}
+
+// waitMessage reads the client's outgoing messages until one of the given type arrives
+func waitMessage(t *testing.T, c *Client, msgType string) map[string]any {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case raw := <-c.Send:
+			var msg struct {
+				Type    string         `json:"type"`
+				Payload map[string]any `json:"payload"`
+			}
+			if err := json.Unmarshal(raw, &msg); err == nil && msg.Type == msgType {
+				return msg.Payload
+			}
+		case <-timeout:
+			t.Fatalf("user=%d: timeout waiting for %q", c.UserID, msgType)
+		}
+	}
+}
+
+func startTestMatch(t *testing.T, h *Hub) (*Room, *Client, *Client) {
+	t.Helper()
+	c1, c2 := newTestClient(h, 1), newTestClient(h, 2)
+	room := h.AssignClient(c1)
+	if got := h.AssignClient(c2); got == nil || got != room {
+		t.Fatalf("expected both players in one room")
+	}
+	waitMessage(t, c1, "matched")
+	waitMessage(t, c2, "matched")
+	return room, c1, c2
+}
+
+func TestReconnectResumesRoom(t *testing.T) {
+	h := NewHub(nil, nil)
+	h.SetReconnectGrace(time.Minute)
+	room, c1, c2 := startTestMatch(t, h)
+
+	close(c2.Done)
+	h.OnDisconnect(c2)
+	if p := waitMessage(t, c1, "opponent_disconnected"); p["reconnect_in"] != float64(60) {
+		t.Fatalf("unexpected opponent_disconnected payload: %v", p)
+	}
+
+	c2b := newTestClient(h, 2)
+	if got := h.AssignClient(c2b); got != room {
+		t.Fatalf("expected reconnect to resume room %s, got %v", room.ID, got)
+	}
+	if p := waitMessage(t, c2b, "resumed"); p["room_id"] != room.ID || p["state"] == nil {
+		t.Fatalf("unexpected resumed payload: %v", p)
+	}
+	waitMessage(t, c1, "opponent_reconnected")
+
+	// Запоздалый disconnect старого соединения не выбивает новое
+	h.OnDisconnect(c2)
+	time.Sleep(300 * time.Millisecond)
+	room.mu.RLock()
+	cur := room.Clients[2]
+	room.mu.RUnlock()
+	if cur != c2b || room.game.IsFinished() {
+		t.Fatalf("stale disconnect affected the resumed player")
+	}
+}
+
+func TestReconnectDeadlineForfeits(t *testing.T) {
+	h := NewHub(nil, nil)
+	h.SetReconnectGrace(100 * time.Millisecond)
+	_, c1, c2 := startTestMatch(t, h)
+
+	close(c2.Done)
+	h.OnDisconnect(c2)
+
+	if p := waitMessage(t, c1, "result"); p["you"] != "win" || p["reason"] != "opponent_left" {
+		t.Fatalf("unexpected result: %v", p)
+	}
+	for i := 0; i < 50 && h.Stats().Rooms > 0; i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if h.Stats().Rooms != 0 {
+		t.Fatal("expected the room to be cleaned up")
+	}
+
+	// После техпоражения новое подключение идёт в обычный матчмейкинг
+	c2b := newTestClient(h, 2)
+	if room := h.AssignClient(c2b); room == nil {
+		t.Fatal("expected a new room")
+	}
+	waitMessage(t, c2b, "state")
+}
@@ -3,13 +3,17 @@ package ws
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
+	"math"
 	"sync"
 	"time"
 
 	"telegram_webapp/internal/domain"
 	"telegram_webapp/internal/game"
+	"telegram_webapp/internal/metrics"
 	"telegram_webapp/internal/repository"
+	"telegram_webapp/internal/service"
 )
 
 const (
@@ -22,17 +26,18 @@ const (
 	StateFinished = "finished"
 )
 
-
 type Room struct {
 	ID      string
 	Clients map[int64]*Client
 
 	Register   chan *Client
 	Disconnect chan *Client
+	Resume     chan *Client // игрок переподключился в grace-период (см. Hub.AssignClient)
 
-	mu         sync.RWMutex
-	timer      *time.Timer
-	createdAt  time.Time
+	mu        sync.RWMutex
+	timer     *time.Timer
+	warnTimer *time.Timer // turn_warning перед авто-ходом
+	createdAt time.Time
 
 	game            game.Game // ← игра через интерфейс
 	GameRepo        *repository.GameRepository
@@ -43,17 +48,45 @@ type Room struct {
 	BetAmount int64
 	Currency  string // "gems" or "coins"
 	UserRepo  *repository.UserRepository
+	BonusRepo *repository.BonusWageringRepository
+	Balance   *service.BalanceService // settles stakes with a transaction record
+	Quests    *service.QuestService
 	betPaid   bool // track if bet has been paid out
+	escrowed  bool // stakes of both players are held (see holdStakes)
+
+	// Комиссия платформы, фиксируется при создании комнаты
+	RakePercent   float64
+	RakeAccountID int64
+
+	// Rematch: finished room lingers for Hub.rematchWindow waiting for both offers
+	finishedAt   time.Time
+	rematchReqs  map[int64]bool
+	rematchDone  bool
+	rematchTimer *time.Timer
+
+	// Отключившиеся посреди игры игроки: по таймеру засчитывается поражение
+	disconnected     map[int64]*time.Timer
+	reconnectExpired chan int64
+
+	// Соперник не нашёлся за Hub.botFallback - играет бот (см. bot.go)
+	botJoin     chan struct{}
+	botOpponent bool
 }
+
 func NewRoom(id string, g game.Game, hub *Hub) *Room {
 	return &Room{
-		ID:        id,
-		Clients:   make(map[int64]*Client),
-		Register:  make(chan *Client, 2),
+		ID:         id,
+		Clients:    make(map[int64]*Client),
+		Register:   make(chan *Client, 2),
 		Disconnect: make(chan *Client, 2),
-		createdAt: time.Now(),
-		game:      g,
-		hub:       hub,
+		Resume:     make(chan *Client, 2),
+		createdAt:  time.Now(),
+
+		disconnected:     make(map[int64]*time.Timer),
+		reconnectExpired: make(chan int64, 2),
+		botJoin:          make(chan struct{}, 1),
+		game:             g,
+		hub:              hub,
 	}
 }
 
@@ -64,8 +97,6 @@ func NewRoomWithRepo(id string, g game.Game, gameRepo *repository.GameRepository
 	return r
 }
 
-
-
 func (r *Room) Run() {
 	log.Printf("Room.Run: starting room=%s", r.ID)
 
@@ -97,6 +128,9 @@ func (r *Room) Run() {
 		// Check if game is finished BEFORE blocking on select
 		if r.game.IsFinished() {
 			log.Printf("Room.Run: room=%s game finished, exiting", r.ID)
+			r.mu.Lock()
+			r.finishedAt = time.Now()
+			r.mu.Unlock()
 			r.saveResult()
 			r.cleanup()
 			return
@@ -105,7 +139,10 @@ func (r *Room) Run() {
 		select {
 		case c := <-r.Register:
 			log.Printf("Room.Run: room=%s received Register for user=%d", r.ID, c.UserID)
-			r.handleRegister(c)
+			if !r.handleRegister(c) {
+				log.Printf("Room.Run: room=%s match aborted, exiting", r.ID)
+				return
+			}
 
 			// Если setup завершён и оба игрока подключены
 			r.mu.RLock()
@@ -144,6 +181,22 @@ func (r *Room) Run() {
 				return
 			}
 
+		case c := <-r.Resume:
+			log.Printf("Room.Run: room=%s received Resume for user=%d", r.ID, c.UserID)
+			r.handleResume(c)
+
+		case <-r.botJoin:
+			if !r.handleBotJoin() {
+				log.Printf("Room.Run: room=%s bot match aborted, exiting", r.ID)
+				return
+			}
+
+		case uid := <-r.reconnectExpired:
+			if r.handleReconnectTimeout(uid) {
+				log.Printf("Room.Run: room=%s terminated, user=%d didn't reconnect", r.ID, uid)
+				return
+			}
+
 		case <-time.After(100 * time.Millisecond):
 			// Periodic check - allows IsFinished() check at top of loop
 			continue
@@ -155,7 +208,7 @@ func (r *Room) completeSetup() {
 	r.mu.Lock()
 	// Для каждого игрока который не завершил setup - вызываем HandleMove с nil (бот сделает)
 	for _, playerID := range r.game.Players() {
-		if !r.game.IsSetupComplete() {
+		if !r.game.IsSetupComplete() && !r.game.HasMoved(playerID) {
 			r.game.HandleMove(playerID, nil)
 		}
 	}
@@ -168,6 +221,9 @@ func (r *Room) completeSetup() {
 }
 
 func (r *Room) startRound() {
+	// hub lock is taken before room lock elsewhere - read settings first
+	warning := r.turnWarning()
+
 	r.mu.Lock()
 	// Collect clients while holding lock
 	clients := r.getClientsUnlocked()
@@ -179,6 +235,12 @@ func (r *Room) startRound() {
 	r.timer = time.AfterFunc(r.game.TurnTimeout(), func() {
 		r.handleRoundTimeout()
 	})
+	r.stopWarnTimerUnlocked()
+	if warning > 0 && warning < r.game.TurnTimeout() {
+		r.warnTimer = time.AfterFunc(r.game.TurnTimeout()-warning, func() {
+			r.sendTurnWarning(warning)
+		})
+	}
 	r.mu.Unlock()
 
 	// Send start with timestamp to ensure frontend detects new round
@@ -192,11 +254,55 @@ func (r *Room) startRound() {
 	})
 }
 
+// turnWarning returns how long before TurnTimeout inactive players are warned
+func (r *Room) turnWarning() time.Duration {
+	if r.hub == nil {
+		return 0
+	}
+	r.hub.mu.RLock()
+	defer r.hub.mu.RUnlock()
+	return r.hub.turnWarning
+}
+
+// stopWarnTimerUnlocked - caller must hold lock
+func (r *Room) stopWarnTimerUnlocked() {
+	if r.warnTimer != nil {
+		r.warnTimer.Stop()
+		r.warnTimer = nil
+	}
+}
+
+// sendTurnWarning notifies players who haven't moved yet that a bot move is coming
+func (r *Room) sendTurnWarning(left time.Duration) {
+	r.mu.RLock()
+	idle := make(map[int64]*Client)
+	for _, playerID := range r.game.Players() {
+		if c, ok := r.Clients[playerID]; ok && !r.game.HasMoved(playerID) {
+			idle[playerID] = c
+		}
+	}
+	r.mu.RUnlock()
+
+	if len(idle) == 0 || r.game.IsFinished() {
+		return
+	}
+	r.broadcastToClients(idle, Message{
+		Type: "turn_warning",
+		Payload: map[string]any{
+			"seconds_left": int(left.Seconds()),
+		},
+	})
+}
+
 func (r *Room) handleRoundTimeout() {
 	r.mu.Lock()
-	// Для каждого игрока который не сходил - бот делает ход
+	r.stopWarnTimerUnlocked()
+	// Бот ходит только за тех, кто не сходил в этом раунде
 	for _, playerID := range r.game.Players() {
-		r.game.HandleMove(playerID, nil)
+		if r.game.HasMoved(playerID) {
+			continue
+		}
+		r.game.HandleMove(playerID, botMoveValue(r.game.Type()))
 	}
 	isComplete := r.game.IsRoundComplete()
 	r.mu.Unlock()
@@ -206,6 +312,23 @@ func (r *Room) handleRoundTimeout() {
 	}
 }
 
+// matchedPayload builds the "matched" message; Mines rooms also get the board size
+func (r *Room) matchedPayload(opponentID int64) map[string]any {
+	payload := map[string]any{
+		"room_id":      r.ID,
+		"opponent":     map[string]any{"id": opponentID},
+		"rake_percent": r.RakePercent,
+		"win_amount":   r.winnerPayout(),
+	}
+	if mg, ok := r.game.(*game.MinesGame); ok {
+		payload["board"] = mg.Config()
+	}
+	if opponentID == BotPlayerID {
+		payload["bot"] = true
+	}
+	return payload
+}
+
 // getClientsUnlocked returns a copy of clients map - caller must hold lock
 func (r *Room) getClientsUnlocked() map[int64]*Client {
 	clients := make(map[int64]*Client, len(r.Clients))
@@ -311,6 +434,8 @@ func (r *Room) cleanup() {
 		r.timer.Stop()
 		r.timer = nil
 	}
+	r.stopWarnTimerUnlocked()
+	r.stopReconnectTimersUnlocked()
 	players := r.game.Players()
 	gameType := r.game.Type()
 	clientIDs := make([]int64, 0, len(r.Clients))
@@ -355,7 +480,9 @@ func (r *Room) cleanup() {
 	log.Printf("Room.cleanup: room=%s cleaned up", roomID)
 }
 
-func (r *Room) handleRegister(c *Client) {
+// handleRegister adds the client; with both players in, their stakes are held
+// before "matched" is sent. Returns false if the match was aborted.
+func (r *Room) handleRegister(c *Client) bool {
 	r.mu.Lock()
 
 	r.Clients[c.UserID] = c
@@ -379,8 +506,6 @@ func (r *Room) handleRegister(c *Client) {
 		log.Printf("Room.handleRegister: closed Registered for user=%d room=%s", c.UserID, r.ID)
 	}
 
-
-
 	if len(r.Clients) == 2 {
 		log.Printf("Room.handleRegister: room=%s BOTH PLAYERS REGISTERED; will send matched messages", r.ID)
 
@@ -393,14 +518,17 @@ func (r *Room) handleRegister(c *Client) {
 		// Release lock before sending to avoid deadlock
 		r.mu.Unlock()
 
+		// Ставки списываются только когда оба игрока на месте, иначе раунд не начинается
+		if failed, err := r.holdStakes(p1, p2); err != nil {
+			r.abortMatch(c1, c2, failed, err)
+			return false
+		}
+
 		// Send matched to both players
 		if c1 != nil {
 			data1, _ := json.Marshal(Message{
-				Type: "matched",
-				Payload: map[string]any{
-					"room_id":  r.ID,
-					"opponent": map[string]any{"id": p2},
-				},
+				Type:    "matched",
+				Payload: r.matchedPayload(p2),
 			})
 			select {
 			case c1.Send <- data1:
@@ -412,11 +540,8 @@ func (r *Room) handleRegister(c *Client) {
 
 		if c2 != nil {
 			data2, _ := json.Marshal(Message{
-				Type: "matched",
-				Payload: map[string]any{
-					"room_id":  r.ID,
-					"opponent": map[string]any{"id": p1},
-				},
+				Type:    "matched",
+				Payload: r.matchedPayload(p1),
 			})
 			select {
 			case c2.Send <- data2:
@@ -466,13 +591,38 @@ func (r *Room) handleRegister(c *Client) {
 			break
 		}
 	}
+	return true
 }
 
 // handleDisconnect handles client disconnection.
 // Returns true if room should be terminated (either empty or winner declared).
 func (r *Room) handleDisconnect(c *Client) bool {
+	// hub lock is taken before room lock elsewhere - read settings first
+	grace := r.reconnectGrace()
+
 	r.mu.Lock()
 
+	if cur, ok := r.Clients[c.UserID]; ok && cur != c {
+		// Старое соединение игрока, который уже переподключился
+		r.mu.Unlock()
+		return false
+	}
+
+	// Посреди игры не засчитываем поражение сразу - ждём переподключения
+	if grace > 0 && r.game.Players()[1] != 0 && !r.game.IsFinished() {
+		delete(r.Clients, c.UserID)
+		r.awaitReconnectUnlocked(c.UserID, grace)
+		opponents := r.getClientsUnlocked()
+		r.mu.Unlock()
+
+		log.Printf("Room.handleDisconnect: room=%s user=%d may reconnect within %s", r.ID, c.UserID, grace)
+		r.broadcastToClients(opponents, Message{
+			Type:    "opponent_disconnected",
+			Payload: map[string]any{"reconnect_in": int(grace.Seconds())},
+		})
+		return false
+	}
+
 	delete(r.Clients, c.UserID)
 
 	log.Printf("Room.handleDisconnect: room=%s user=%d bet=%d currency=%s", r.ID, c.UserID, r.BetAmount, r.Currency)
@@ -496,45 +646,45 @@ func (r *Room) handleDisconnect(c *Client) bool {
 
 	clientsLeft := len(r.Clients)
 
-	// Handle bet payouts
-	shouldPayWinner := r.BetAmount > 0 && !r.betPaid && hadTwoPlayers && shouldNotifyWinner
-	shouldRefundDisconnecting := r.BetAmount > 0 && !r.betPaid && !hadTwoPlayers // Game never started (waiting for opponent)
-	if shouldPayWinner || shouldRefundDisconnecting {
-		r.betPaid = true
+	// Против бота уходит единственный игрок - ставка достаётся боту
+	if r.botOpponent {
+		remainingUID = BotPlayerID
+	}
+
+	// Opponent left mid-game: remaining player wins by forfeit.
+	// Run won't reach saveResult after we terminate, so settle here either way.
+	shouldSaveResult := hadTwoPlayers && (shouldNotifyWinner || r.botOpponent)
+	forfeit := shouldSaveResult && r.game.ForceFinish(remainingUID, "opponent_left")
+	if shouldSaveResult {
+		if r.timer != nil {
+			r.timer.Stop()
+			r.timer = nil
+		}
+		r.stopWarnTimerUnlocked()
+		r.finishedAt = time.Now()
 	}
+
+	// Waiting for an opponent holds no stake, nothing to refund
 	r.mu.Unlock()
 
 	// Handle bet payouts outside of lock
-	if shouldPayWinner && remainingClient != nil {
-		// Winner gets both bets (opponent forfeited)
-		log.Printf("Room.handleDisconnect: opponent left, paying winner=%d pot=%d %s",
-			remainingUID, r.BetAmount*2, r.Currency)
-		r.payoutWinner(&remainingUID, remainingUID, c.UserID)
-	} else if shouldRefundDisconnecting {
-		// Game never started, refund disconnecting player
-		log.Printf("Room.handleDisconnect: game never started, refunding user=%d", c.UserID)
-		r.refundBet(c.UserID)
+	if shouldSaveResult {
+		// saveResult pays the pot to the winner and writes game_history
+		log.Printf("Room.handleDisconnect: opponent left, winner=%d forfeit=%v pot=%d %s",
+			remainingUID, forfeit, r.BetAmount*2, r.Currency)
+		r.saveResult()
 	}
 
 	// Send win notification without holding lock (avoids deadlock with r.send)
 	if shouldNotifyWinner && remainingClient != nil {
-		winAmount := r.BetAmount * 2
-		data, _ := json.Marshal(Message{
-			Type: "result",
-			Payload: map[string]any{
-				"you":        "win",
-				"reason":     "opponent_left",
-				"win_amount": winAmount,
-				"currency":   r.Currency,
-			},
-		})
-		select {
-		case remainingClient.Send <- data:
-			log.Printf("Room.handleDisconnect: sent win to user=%d", remainingUID)
-		case <-time.After(2 * time.Second):
-			log.Printf("Room.handleDisconnect: timeout sending win to user=%d", remainingUID)
+		// A game that had already ended on its own was announced by broadcastResult
+		if shouldSaveResult && !forfeit {
+			r.cleanup()
+			return true
 		}
 
+		r.sendForfeitWin(remainingClient)
+
 		// Cleanup without holding room lock (cleanup takes its own lock)
 		r.cleanup()
 		return true // Room should terminate
@@ -549,53 +699,45 @@ func (r *Room) handleDisconnect(c *Client) bool {
 	return false // Room continues (still has 2 players somehow, or waiting for second)
 }
 
+// HandleMessage dispatches a client message (see clientMessageTypes); anything
+// else gets an error with a code from protocol.go
 func (r *Room) HandleMessage(c *Client, raw []byte) {
-	var msg struct {
-		Type  string      `json:"type"`
-		Value interface{} `json:"value"`
-	}
-
-	if err := json.Unmarshal(raw, &msg); err != nil {
-		log.Printf("Room.HandleMessage: failed to unmarshal: %v", err)
+	msg, perr := parseClientMessage(raw)
+	if perr != nil {
+		log.Printf("Room.HandleMessage: room=%s user=%d rejected: %s", r.ID, c.UserID, perr.Message)
+		r.sendError(c, perr.Code, perr.Message)
 		return
 	}
 
-	log.Printf("Room.HandleMessage: room=%s user=%d type=%s value=%v valueType=%T", r.ID, c.UserID, msg.Type, msg.Value, msg.Value)
+	log.Printf("Room.HandleMessage: room=%s user=%d type=%s value=%s", r.ID, c.UserID, msg.Type, string(msg.Value))
 
-	// Convert value to appropriate type for the game
-	var moveValue interface{} = msg.Value
+	switch msg.Type {
+	case MsgPing:
+		c.pong()
+		return
+	case MsgReady:
+		// клиент готов - отвечать нечего, соединение живо
+		return
+	case MsgRematch:
+		r.handleRematch(c)
+		return
+	}
 
-	// For RPS, value should be a string
-	// For Mines setup, value should be []int
-	// For Mines move, value should be int
-	if r.game.Type() == "mines" {
-		// Handle setup (array of mine positions)
-		if arr, ok := msg.Value.([]interface{}); ok {
-			intArr := make([]int, len(arr))
-			for i, v := range arr {
-				if num, ok := v.(float64); ok {
-					intArr[i] = int(num)
-				}
-			}
-			moveValue = intArr
-			log.Printf("Room.HandleMessage: converted mines setup value to []int: %v", intArr)
-		}
-		// Handle move (single cell number)
-		if num, ok := msg.Value.(float64); ok {
-			moveValue = int(num)
-			log.Printf("Room.HandleMessage: converted mines move value to int: %d", int(num))
-		}
+	// move / setup: форма value зависит от игры и фазы (расстановка или ход)
+	moveValue, err := decodeMoveValue(r.game.Type(), msg.Type, r.game.IsSetupComplete(), msg.Value)
+	if err != nil {
+		log.Printf("Room.HandleMessage: invalid payload from user=%d: %v", c.UserID, err)
+		r.sendError(c, ErrCodeInvalidPayload, err.Error())
+		return
 	}
 
 	// Обрабатываем ход через игру
 	if err := r.game.HandleMove(c.UserID, moveValue); err != nil {
 		log.Printf("Room.HandleMessage: invalid move from user=%d: %v", c.UserID, err)
-		r.send(c.UserID, Message{
-			Type: "error",
-			Payload: map[string]string{"message": err.Error()},
-		})
+		r.sendError(c, ErrCodeInvalidMove, err.Error())
 		return
 	}
+	r.botRespond()
 
 	// Если раунд завершён - проверяем результат
 	if r.game.IsRoundComplete() {
@@ -606,6 +748,7 @@ func (r *Room) HandleMessage(c *Client, raw []byte) {
 			r.timer.Stop()
 			r.timer = nil
 		}
+		r.stopWarnTimerUnlocked()
 		r.mu.Unlock()
 
 		// call checkRound, but retry briefly to avoid races between game state updates
@@ -624,6 +767,121 @@ func (r *Room) HandleMessage(c *Client, raw []byte) {
 	}
 }
 
+// rematchWindow returns how long a finished room accepts rematch offers
+func (r *Room) rematchWindow() time.Duration {
+	if r.hub == nil {
+		return 0
+	}
+	r.hub.mu.RLock()
+	defer r.hub.mu.RUnlock()
+	return r.hub.rematchWindow
+}
+
+// handleRematch records a rematch offer; once both players asked within the
+// window they are moved to a fresh room with the same game and stake
+func (r *Room) handleRematch(c *Client) {
+	// hub lock is taken before room lock elsewhere - read settings first
+	window := r.rematchWindow()
+
+	r.mu.Lock()
+	if r.finishedAt.IsZero() {
+		r.mu.Unlock()
+		r.sendError(c, ErrCodeGameNotFinished, "game is not finished")
+		return
+	}
+	if r.rematchDone || window <= 0 || time.Since(r.finishedAt) > window {
+		r.mu.Unlock()
+		r.sendTo(c, Message{Type: "rematch_expired", Payload: map[string]any{"reason": "timeout"}})
+		return
+	}
+
+	players := r.game.Players()
+	opponentID := players[0]
+	if opponentID == c.UserID {
+		opponentID = players[1]
+	}
+	opponent := r.Clients[opponentID]
+	if opponent == nil || !clientAlive(opponent) {
+		r.rematchDone = true
+		r.mu.Unlock()
+		r.sendTo(c, Message{Type: "rematch_expired", Payload: map[string]any{"reason": "opponent_left"}})
+		return
+	}
+
+	if r.rematchReqs == nil {
+		r.rematchReqs = make(map[int64]bool)
+	}
+	r.rematchReqs[c.UserID] = true
+	both := r.rematchReqs[opponentID]
+	left := window - time.Since(r.finishedAt)
+	if both {
+		r.rematchDone = true
+		if r.rematchTimer != nil {
+			r.rematchTimer.Stop()
+		}
+	} else if r.rematchTimer == nil {
+		r.rematchTimer = time.AfterFunc(left, r.expireRematch)
+	}
+	r.mu.Unlock()
+
+	if !both {
+		expiresIn := int(left.Seconds())
+		r.sendTo(c, Message{Type: "rematch_waiting", Payload: map[string]any{"expires_in": expiresIn}})
+		r.sendTo(opponent, Message{Type: "rematch_offer", Payload: map[string]any{"expires_in": expiresIn}})
+		return
+	}
+
+	if _, err := r.hub.StartRematch(r, [2]*Client{opponent, c}); err != nil {
+		log.Printf("Room.handleRematch: room=%s rematch failed: %v", r.ID, err)
+		reason := "room_unavailable"
+		if errors.Is(err, ErrRematchInsufficientBalance) {
+			reason = "insufficient_balance"
+		}
+		failed := Message{Type: "rematch_failed", Payload: map[string]any{"reason": reason}}
+		r.sendTo(c, failed)
+		r.sendTo(opponent, failed)
+	}
+}
+
+// expireRematch tells players still waiting for a rematch that the window closed
+func (r *Room) expireRematch() {
+	r.mu.Lock()
+	if r.rematchDone {
+		r.mu.Unlock()
+		return
+	}
+	r.rematchDone = true
+	waiting := make(map[int64]*Client)
+	for uid := range r.rematchReqs {
+		if cl := r.Clients[uid]; cl != nil {
+			waiting[uid] = cl
+		}
+	}
+	r.mu.Unlock()
+
+	r.broadcastToClients(waiting, Message{Type: "rematch_expired", Payload: map[string]any{"reason": "timeout"}})
+}
+
+// sendError sends a structured error to the client that caused it
+func (r *Room) sendError(c *Client, code, message string) {
+	r.sendTo(c, Message{Type: MsgError, Payload: ErrorPayload{Code: code, Message: message}})
+}
+
+// sendTo sends to a specific connection, even if the room no longer tracks it
+func (r *Room) sendTo(c *Client, msg Message) {
+	r.broadcastToClients(map[int64]*Client{c.UserID: c}, msg)
+}
+
+// clientAlive reports whether the client's readPump is still running
+func clientAlive(c *Client) bool {
+	select {
+	case <-c.Done:
+		return false
+	default:
+		return true
+	}
+}
+
 func (r *Room) broadcastResult(result *game.GameResult) {
 	r.mu.RLock()
 	players := r.game.Players()
@@ -691,7 +949,6 @@ func (r *Room) broadcastResult(result *game.GameResult) {
 	}
 }
 
-
 func (r *Room) send(userID int64, msg Message) {
 	data, err := json.Marshal(msg)
 	if err != nil {
@@ -811,31 +1068,22 @@ func (r *Room) saveResult() {
 
 	log.Printf("Room.saveResult: room=%s storing game bet=%d currency=%s", r.ID, r.BetAmount, r.Currency)
 
-	// Pay out the winner (if there's a bet and it hasn't been paid yet)
+	// Settle the held stakes: pay out the winner or refund both (once)
 	r.mu.Lock()
-	shouldPay := r.BetAmount > 0 && !r.betPaid
+	shouldPay := r.escrowed && !r.betPaid
 	if shouldPay {
 		r.betPaid = true
 	}
+	vsBot := r.botOpponent
 	r.mu.Unlock()
 
 	if shouldPay {
 		r.payoutWinner(result.WinnerID, p1, p2)
+		r.addWager(result.WinnerID, p1, p2)
 	}
 
-	// Calculate win amounts for history
-	var winAmount1, winAmount2 int64
-	if result.WinnerID != nil {
-		if *result.WinnerID == p1 {
-			winAmount1 = r.BetAmount * winnerPayoutMultiplier
-		} else {
-			winAmount2 = r.BetAmount * winnerPayoutMultiplier
-		}
-	} else {
-		// Draw - both get refunded (handled in payoutWinner)
-		winAmount1 = r.BetAmount
-		winAmount2 = r.BetAmount
-	}
+	// win_amount в game_history - нетто, как у PvE: выплата минус ставка
+	winAmount1, winAmount2 := r.netWinAmount(result.WinnerID, p1), r.netWinAmount(result.WinnerID, p2)
 
 	// Save to old games table (for backwards compatibility)
 	if r.GameRepo != nil {
@@ -853,10 +1101,12 @@ func (r *Room) saveResult() {
 		}(g)
 	}
 
+	r.updateQuests(result.WinnerID, p1, p2)
+	r.observeResult(result.WinnerID, p1, winAmount1)
+	r.observeResult(result.WinnerID, p2, winAmount2)
+
 	// Save to new game_history table
 	if r.GameHistoryRepo != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-
 		gameType := string(r.game.Type())
 		details := result.Details
 
@@ -876,12 +1126,20 @@ func (r *Room) saveResult() {
 
 		currency := domain.Currency(r.Currency)
 
+		// Игра с ботом пишется как PvE, без соперника (бота нет в users)
+		mode := domain.GameModePVP
+		opponentID := &p2
+		if vsBot {
+			mode = domain.GameModePVE
+			opponentID = nil
+		}
+
 		// Save for player 1
 		gh1 := &domain.GameHistory{
 			UserID:     p1,
 			GameType:   domain.GameType(gameType),
-			Mode:       domain.GameModePVP,
-			OpponentID: &p2,
+			Mode:       mode,
+			OpponentID: opponentID,
 			RoomID:     &r.ID,
 			Result:     result1,
 			BetAmount:  r.BetAmount,
@@ -890,95 +1148,226 @@ func (r *Room) saveResult() {
 			Details:    details,
 		}
 		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
 			if err := r.GameHistoryRepo.Create(ctx, gh1); err != nil {
 				log.Printf("Room.saveResult: game_history p1 failed: %v", err)
 			}
 		}()
 
-		// Save for player 2
-		gh2 := &domain.GameHistory{
-			UserID:     p2,
-			GameType:   domain.GameType(gameType),
-			Mode:       domain.GameModePVP,
-			OpponentID: &p1,
-			RoomID:     &r.ID,
-			Result:     result2,
-			BetAmount:  r.BetAmount,
-			WinAmount:  winAmount2,
-			Currency:   currency,
-			Details:    details,
-		}
-		go func() {
-			if err := r.GameHistoryRepo.Create(ctx, gh2); err != nil {
-				log.Printf("Room.saveResult: game_history p2 failed: %v", err)
+		if !vsBot {
+			// Save for player 2
+			gh2 := &domain.GameHistory{
+				UserID:     p2,
+				GameType:   domain.GameType(gameType),
+				Mode:       domain.GameModePVP,
+				OpponentID: &p1,
+				RoomID:     &r.ID,
+				Result:     result2,
+				BetAmount:  r.BetAmount,
+				WinAmount:  winAmount2,
+				Currency:   currency,
+				Details:    details,
 			}
-		}()
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				if err := r.GameHistoryRepo.Create(ctx, gh2); err != nil {
+					log.Printf("Room.saveResult: game_history p2 failed: %v", err)
+				}
+			}()
+		}
+	}
+}
+
+// observeResult counts a player's finished game in the game metrics. In PvP the
+// house result of both players adds up to the rake; the bot is the house itself.
+func (r *Room) observeResult(winnerID *int64, uid, net int64) {
+	if uid == BotPlayerID {
+		return
+	}
+	result := domain.GameResultDraw
+	if winnerID != nil {
+		result = domain.GameResultLose
+		if *winnerID == uid {
+			result = domain.GameResultWin
+		}
+	}
+	metrics.ObserveGame(string(r.game.Type()), string(result), r.Currency, r.BetAmount, net)
+}
+
+// updateQuests advances quest progress of both players; amounts are net of the stake
+func (r *Room) updateQuests(winnerID *int64, p1, p2 int64) {
+	if r.Quests == nil {
+		return
+	}
+
+	gameType := domain.GameType(r.game.Type())
+	currency := domain.Currency(r.Currency)
+	for _, uid := range []int64{p1, p2} {
+		if uid == BotPlayerID {
+			continue
+		}
+		switch {
+		case winnerID == nil:
+			// Ничья - ставка возвращена, gems не потрачены
+			r.Quests.OnGamePlayed(context.Background(), uid, gameType, domain.GameResultDraw, currency, 0, 0)
+		case *winnerID == uid:
+			r.Quests.OnGamePlayed(context.Background(), uid, gameType, domain.GameResultWin, currency, r.BetAmount, r.netWinAmount(winnerID, uid))
+		default:
+			r.Quests.OnGamePlayed(context.Background(), uid, gameType, domain.GameResultLose, currency, r.BetAmount, r.netWinAmount(winnerID, uid))
+		}
+	}
+}
+
+// addWager counts decided coin stakes towards bonus wagering (draws are refunded, so they don't count)
+func (r *Room) addWager(winnerID *int64, p1, p2 int64) {
+	if r.BonusRepo == nil || winnerID == nil || r.Currency != string(domain.CurrencyCoins) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, uid := range []int64{p1, p2} {
+		if uid == BotPlayerID {
+			continue
+		}
+		if err := r.BonusRepo.AddWager(ctx, uid, domain.CurrencyCoins, r.BetAmount); err != nil {
+			log.Printf("Room.addWager: failed for user=%d: %v", uid, err)
+		}
 	}
 }
 
 // payoutWinner pays out the bet to the winner, or refunds both on draw
 func (r *Room) payoutWinner(winnerID *int64, p1, p2 int64) {
-	if r.UserRepo == nil || r.BetAmount == 0 {
+	if (r.UserRepo == nil && r.Balance == nil) || r.BetAmount == 0 {
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	totalPot := r.BetAmount * 2 // Both players bet the same amount
-
 	if winnerID == nil {
 		// Draw - refund both players
 		log.Printf("Room.payoutWinner: draw in room=%s, refunding both players %d %s", r.ID, r.BetAmount, r.Currency)
-		if r.Currency == string(domain.CurrencyCoins) {
-			if _, err := r.UserRepo.UpdateCoins(ctx, p1, r.BetAmount); err != nil {
-				log.Printf("Room.payoutWinner: failed to refund p1: %v", err)
-			}
-			if _, err := r.UserRepo.UpdateCoins(ctx, p2, r.BetAmount); err != nil {
-				log.Printf("Room.payoutWinner: failed to refund p2: %v", err)
-			}
-		} else {
-			if _, err := r.UserRepo.UpdateGems(ctx, p1, r.BetAmount); err != nil {
-				log.Printf("Room.payoutWinner: failed to refund p1: %v", err)
-			}
-			if _, err := r.UserRepo.UpdateGems(ctx, p2, r.BetAmount); err != nil {
-				log.Printf("Room.payoutWinner: failed to refund p2: %v", err)
-			}
+		if err := r.credit(ctx, p1, p2, r.BetAmount, "pvp_refund"); err != nil {
+			log.Printf("Room.payoutWinner: failed to refund p1: %v", err)
 		}
-	} else {
-		// Winner gets the entire pot (2x bet)
-		log.Printf("Room.payoutWinner: winner=%d in room=%s gets %d %s", *winnerID, r.ID, totalPot, r.Currency)
-		if r.Currency == string(domain.CurrencyCoins) {
-			if _, err := r.UserRepo.UpdateCoins(ctx, *winnerID, totalPot); err != nil {
-				log.Printf("Room.payoutWinner: failed to pay winner: %v", err)
-			}
-		} else {
-			if _, err := r.UserRepo.UpdateGems(ctx, *winnerID, totalPot); err != nil {
-				log.Printf("Room.payoutWinner: failed to pay winner: %v", err)
-			}
+		if err := r.credit(ctx, p2, p1, r.BetAmount, "pvp_refund"); err != nil {
+			log.Printf("Room.payoutWinner: failed to refund p2: %v", err)
 		}
+		return
+	}
+
+	// Winner gets the entire pot
+	loserID := p1
+	if *winnerID == p1 {
+		loserID = p2
+	}
+	payout := r.winnerPayout()
+	log.Printf("Room.payoutWinner: winner=%d in room=%s gets %d %s", *winnerID, r.ID, payout, r.Currency)
+	if err := r.credit(ctx, *winnerID, loserID, payout, "pvp_win"); err != nil {
+		log.Printf("Room.payoutWinner: failed to pay winner: %v", err)
+	}
+	r.collectRake(ctx, *winnerID)
+}
+
+// netWinAmount returns uid's result net of the stake: payout minus bet for the
+// winner, minus bet for the loser, 0 for a draw (both stakes are refunded)
+func (r *Room) netWinAmount(winnerID *int64, uid int64) int64 {
+	switch {
+	case winnerID == nil:
+		return 0
+	case *winnerID == uid:
+		return r.winnerPayout() - r.BetAmount
+	default:
+		return -r.BetAmount
+	}
+}
+
+// winnerPayout returns what the winner of a decided game receives (stakes are held at match start)
+func (r *Room) winnerPayout() int64 {
+	return r.BetAmount*winnerPayoutMultiplier - r.rakeAmount()
+}
+
+// rakeAmount is the house cut of the pot, rounded down in favour of the winner
+func (r *Room) rakeAmount() int64 {
+	if r.RakePercent <= 0 {
+		return 0
 	}
+	bps := int64(math.Round(r.RakePercent * 100))
+	return r.BetAmount * winnerPayoutMultiplier * bps / 10000
 }
 
-// refundBet refunds a player's bet (used when game is cancelled or player disconnects early)
+// collectRake credits the house cut to the rake account as a platform_rake transaction
+func (r *Room) collectRake(ctx context.Context, winnerID int64) {
+	rake := r.rakeAmount()
+	if rake <= 0 || r.Balance == nil || r.RakeAccountID == 0 {
+		return
+	}
+	r.mu.RLock()
+	vsBot := r.botOpponent
+	r.mu.RUnlock()
+	if vsBot {
+		// ставку бота платит сама платформа, комиссию с неё не записываем
+		return
+	}
+	_, err := r.Balance.CreditCurrency(ctx, r.RakeAccountID, domain.Currency(r.Currency), rake, "platform_rake", map[string]interface{}{
+		"room_id":      r.ID,
+		"game_type":    string(r.game.Type()),
+		"winner_id":    winnerID,
+		"pot":          r.BetAmount * winnerPayoutMultiplier,
+		"rake_percent": r.RakePercent,
+	})
+	if err != nil {
+		log.Printf("Room.collectRake: failed to credit rake %d %s in room=%s: %v", rake, r.Currency, r.ID, err)
+		return
+	}
+	metrics.ObserveRake(string(r.game.Type()), r.Currency, rake)
+}
+
+// credit pays amount in the room currency; through BalanceService when set, so a transaction is recorded
+func (r *Room) credit(ctx context.Context, userID, opponentID, amount int64, txType string) error {
+	if userID == BotPlayerID {
+		return nil // у бота нет баланса
+	}
+	if r.Balance != nil {
+		_, err := r.Balance.CreditCurrency(ctx, userID, domain.Currency(r.Currency), amount, txType, map[string]interface{}{
+			"room_id":     r.ID,
+			"game_type":   string(r.game.Type()),
+			"opponent_id": opponentID,
+		})
+		return err
+	}
+
+	var err error
+	if r.Currency == string(domain.CurrencyCoins) {
+		_, err = r.UserRepo.UpdateCoins(ctx, userID, amount)
+	} else {
+		_, err = r.UserRepo.UpdateGems(ctx, userID, amount)
+	}
+	return err
+}
+
+// refundBet returns a player's held stake (used when the game is cancelled, e.g. on shutdown)
 func (r *Room) refundBet(userID int64) {
-	if r.UserRepo == nil || r.BetAmount == 0 {
+	if (r.UserRepo == nil && r.Balance == nil) || r.BetAmount == 0 {
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	log.Printf("Room.refundBet: refunding %d %s to user=%d", r.BetAmount, r.Currency, userID)
-
-	if r.Currency == string(domain.CurrencyCoins) {
-		if _, err := r.UserRepo.UpdateCoins(ctx, userID, r.BetAmount); err != nil {
-			log.Printf("Room.refundBet: failed to refund coins: %v", err)
-		}
-	} else {
-		if _, err := r.UserRepo.UpdateGems(ctx, userID, r.BetAmount); err != nil {
-			log.Printf("Room.refundBet: failed to refund gems: %v", err)
+	var opponentID int64
+	for _, uid := range r.game.Players() {
+		if uid != userID {
+			opponentID = uid
 		}
 	}
+
+	log.Printf("Room.refundBet: refunding %d %s to user=%d", r.BetAmount, r.Currency, userID)
+	if err := r.credit(ctx, userID, opponentID, r.BetAmount, "pvp_refund"); err != nil {
+		log.Printf("Room.refundBet: failed to refund user=%d: %v", userID, err)
+	}
 }
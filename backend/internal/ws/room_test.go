@@ -0,0 +1,268 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/repository"
+	"telegram_webapp/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// readUntil reads messages until one of the given type arrives and returns its payload
+func readUntil(t *testing.T, conn *websocket.Conn, msgType string) map[string]any {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		conn.SetReadDeadline(deadline)
+		_, raw, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("waiting for %q: %v", msgType, err)
+		}
+		var msg struct {
+			Type    string         `json:"type"`
+			Payload map[string]any `json:"payload"`
+		}
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			continue
+		}
+		if msg.Type == msgType {
+			return msg.Payload
+		}
+	}
+}
+
+// Integration-style test: runs only if TEST_DATABASE_URL env is set.
+func TestOpponentDisconnectRecordsForfeit(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set; skipping integration test")
+	}
+	t.Setenv("JWT_SECRET", "ws-test-secret")
+	service.InitJWT()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	db, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+
+	users := repository.NewUserRepository(db)
+	history := repository.NewGameHistoryRepository(db)
+
+	const bet = 10
+	var ids [2]int64
+	for i := range ids {
+		u := &domain.User{TgID: time.Now().UnixNano(), Username: fmt.Sprintf("ws_forfeit_test_%d", i)}
+		if err := users.Create(ctx, u); err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+		ids[i] = u.ID
+		defer db.Exec(context.Background(), `DELETE FROM users WHERE id = $1`, u.ID)
+		defer db.Exec(context.Background(), `DELETE FROM game_history WHERE user_id = $1`, u.ID)
+		defer db.Exec(context.Background(), `DELETE FROM transactions WHERE user_id = $1`, u.ID)
+		if _, err := db.Exec(ctx, `UPDATE users SET coins = 100 WHERE id = $1`, u.ID); err != nil {
+			t.Fatalf("set coins: %v", err)
+		}
+	}
+
+	hub := NewHubWithUserRepo(nil, history, users)
+	hub.Balance = service.NewBalanceService(db)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/ws", NewWSHandler(hub, users).HandleWS())
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	var conns [2]*websocket.Conn
+	for i, uid := range ids {
+		token, err := service.GenerateJWT(uid)
+		if err != nil {
+			t.Fatalf("jwt: %v", err)
+		}
+		url := fmt.Sprintf("ws%s/ws?game=rps&bet=%d&currency=coins", strings.TrimPrefix(srv.URL, "http"), bet)
+		dialer := websocket.Dialer{Subprotocols: []string{TokenSubprotocol, token}}
+		conn, _, err := dialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("dial user %d: %v", uid, err)
+		}
+		defer conn.Close()
+		conns[i] = conn
+	}
+	for _, conn := range conns {
+		readUntil(t, conn, "matched")
+	}
+
+	// Второй игрок уходит посреди игры
+	conns[1].Close()
+
+	res := readUntil(t, conns[0], "result")
+	if res["you"] != "win" || res["reason"] != "opponent_left" {
+		t.Fatalf("unexpected result for remaining player: %v", res)
+	}
+
+	// game_history пишется асинхронно
+	var gh *domain.GameHistory
+	for i := 0; i < 50 && gh == nil; i++ {
+		rows, err := history.GetByUserAndType(ctx, ids[0], domain.GameTypeRPS, 1)
+		if err != nil {
+			t.Fatalf("game history: %v", err)
+		}
+		if len(rows) > 0 {
+			gh = rows[0]
+		} else {
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+	if gh == nil {
+		t.Fatal("expected game_history row for the winner")
+	}
+	if gh.Result != domain.GameResultWin || gh.Mode != domain.GameModePVP || gh.WinAmount != bet {
+		t.Fatalf("unexpected history row: %+v", gh)
+	}
+	if gh.Details["reason"] != "opponent_left" {
+		t.Fatalf("expected reason opponent_left in details, got %v", gh.Details)
+	}
+
+	winner, err := users.GetByID(ctx, ids[0])
+	if err != nil {
+		t.Fatalf("get winner: %v", err)
+	}
+	if winner.Coins != 100+bet {
+		t.Fatalf("expected winner balance %d, got %d", 100+bet, winner.Coins)
+	}
+}
+
+// Integration-style test: runs only if TEST_DATABASE_URL env is set.
+func TestMatchAbortedWhenStakeCannotBeHeld(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set; skipping integration test")
+	}
+	t.Setenv("JWT_SECRET", "ws-test-secret")
+	service.InitJWT()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	db, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+
+	users := repository.NewUserRepository(db)
+
+	const bet = 10
+	var ids [2]int64
+	for i := range ids {
+		u := &domain.User{TgID: time.Now().UnixNano(), Username: fmt.Sprintf("ws_escrow_test_%d", i)}
+		if err := users.Create(ctx, u); err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+		ids[i] = u.ID
+		defer db.Exec(context.Background(), `DELETE FROM users WHERE id = $1`, u.ID)
+		defer db.Exec(context.Background(), `DELETE FROM transactions WHERE user_id = $1`, u.ID)
+		if _, err := db.Exec(ctx, `UPDATE users SET coins = 100 WHERE id = $1`, u.ID); err != nil {
+			t.Fatalf("set coins: %v", err)
+		}
+	}
+
+	hub := NewHubWithUserRepo(nil, nil, users)
+	hub.Balance = service.NewBalanceService(db)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/ws", NewWSHandler(hub, users).HandleWS())
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	var conns [2]*websocket.Conn
+	for i, uid := range ids {
+		// Первый игрок тратит баланс, пока ждёт соперника
+		if i == 1 {
+			time.Sleep(200 * time.Millisecond)
+			if _, err := db.Exec(ctx, `UPDATE users SET coins = 0 WHERE id = $1`, ids[0]); err != nil {
+				t.Fatalf("spend coins: %v", err)
+			}
+		}
+		token, err := service.GenerateJWT(uid)
+		if err != nil {
+			t.Fatalf("jwt: %v", err)
+		}
+		url := fmt.Sprintf("ws%s/ws?game=rps&bet=%d&currency=coins", strings.TrimPrefix(srv.URL, "http"), bet)
+		dialer := websocket.Dialer{Subprotocols: []string{TokenSubprotocol, token}}
+		conn, _, err := dialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("dial user %d: %v", uid, err)
+		}
+		defer conn.Close()
+		conns[i] = conn
+	}
+
+	if res := readUntil(t, conns[0], "match_aborted"); res["reason"] != "insufficient_balance" {
+		t.Fatalf("unexpected abort for the player without balance: %v", res)
+	}
+	if res := readUntil(t, conns[1], "match_aborted"); res["reason"] != "opponent_unavailable" {
+		t.Fatalf("unexpected abort for the opponent: %v", res)
+	}
+
+	// Ставка второго игрока не удерживается
+	opponent, err := users.GetByID(ctx, ids[1])
+	if err != nil {
+		t.Fatalf("get opponent: %v", err)
+	}
+	if opponent.Coins != 100 {
+		t.Fatalf("expected opponent balance 100, got %d", opponent.Coins)
+	}
+}
+
+func TestWinnerPayoutRake(t *testing.T) {
+	cases := []struct {
+		bet     int64
+		percent float64
+		payout  int64
+	}{
+		{100, 0, 200},
+		{100, 5, 190},
+		{7, 5, 14},    // 0.7 округляется вниз в пользу победителя
+		{10, 2.5, 20}, // 0.5 -> 0
+		{1000, 2.5, 1950},
+	}
+	for _, tc := range cases {
+		r := &Room{BetAmount: tc.bet, RakePercent: tc.percent}
+		if got := r.winnerPayout(); got != tc.payout {
+			t.Fatalf("bet=%d rake=%v%%: expected payout %d, got %d", tc.bet, tc.percent, tc.payout, got)
+		}
+		if got := r.rakeAmount() + r.winnerPayout(); got != 2*tc.bet {
+			t.Fatalf("bet=%d rake=%v%%: payout and rake don't add up to the pot", tc.bet, tc.percent)
+		}
+	}
+}
+
+func TestNetWinAmount(t *testing.T) {
+	r := &Room{BetAmount: 100, RakePercent: 5}
+	winner, loser := int64(1), int64(2)
+
+	if got := r.netWinAmount(&winner, winner); got != 90 {
+		t.Fatalf("winner: expected payout minus bet 90, got %d", got)
+	}
+	if got := r.netWinAmount(&winner, loser); got != -100 {
+		t.Fatalf("loser: expected -100, got %d", got)
+	}
+	if got := r.netWinAmount(nil, winner); got != 0 {
+		t.Fatalf("draw: expected 0, got %d", got)
+	}
+}
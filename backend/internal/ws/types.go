@@ -1,12 +1,16 @@
 package ws
 
 const (
-	// клиент к серверу
-	MsgMove = "move"
-	MsgPing = "ping"
+	// клиент к серверу (см. clientMessageTypes)
+	MsgMove    = "move"
+	MsgSetup   = "setup"
+	MsgReady   = "ready"
+	MsgPing    = "ping"
+	MsgRematch = "rematch"
 
 	// сервер к клиенту
 	MsgMatchFound = "match_found"
 	MsgResult     = "result"
 	MsgError      = "error"
+	MsgPong       = "pong"
 )
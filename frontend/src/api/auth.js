@@ -1,4 +1,4 @@
-import { api, setToken } from './client'
+import { api, setToken, setRefreshToken } from './client'
 
 export async function authenticate() {
   const tg = window.Telegram?.WebApp
@@ -13,6 +13,7 @@ export async function authenticate() {
 
   if (response.token) {
     setToken(response.token)
+    setRefreshToken(response.refresh_token)
   }
 
   return response
@@ -1,6 +1,7 @@
 const API_BASE = import.meta.env.VITE_API_URL || '/api'
 
 let authToken = null
+let refreshing = null
 
 export function setToken(token) {
   authToken = token
@@ -18,7 +19,43 @@ export function getToken() {
   return authToken
 }
 
-export async function apiRequest(endpoint, options = {}) {
+export function setRefreshToken(token) {
+  if (token) {
+    localStorage.setItem('refresh_token', token)
+  } else {
+    localStorage.removeItem('refresh_token')
+  }
+}
+
+// Exchanges the refresh token for a new pair; concurrent callers share one request
+function refreshTokens() {
+  if (!refreshing) {
+    refreshing = (async () => {
+      const refreshToken = localStorage.getItem('refresh_token')
+      if (!refreshToken) return false
+      const response = await fetch(`${API_BASE}/auth/refresh`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ refresh_token: refreshToken }),
+      })
+      if (!response.ok) {
+        // Refresh token expired or revoked - full Telegram re-auth is needed
+        setToken(null)
+        setRefreshToken(null)
+        return false
+      }
+      const data = await response.json()
+      setToken(data.token)
+      setRefreshToken(data.refresh_token)
+      return true
+    })().finally(() => {
+      refreshing = null
+    })
+  }
+  return refreshing
+}
+
+export async function apiRequest(endpoint, options = {}, retried = false) {
   const token = getToken()
 
   const headers = {
@@ -37,7 +74,18 @@ export async function apiRequest(endpoint, options = {}) {
 
   if (!response.ok) {
     const error = await response.json().catch(() => ({ error: 'Request failed' }))
-    throw new Error(error.error || 'Request failed')
+    if (response.status === 401 && error.error === 'token_expired' && !retried) {
+      if (await refreshTokens().catch(() => false)) {
+        return apiRequest(endpoint, options, true)
+      }
+    }
+    const err = new Error(typeof error.error === 'string' ? error.error : 'Request failed')
+    // Машиночитаемый код ошибки (INSUFFICIENT_BALANCE, VALIDATION_FAILED, ...)
+    err.code = error.code
+    // Поля с ошибками валидации: { bet: 'required' }
+    err.fields = error.fields
+    err.status = response.status
+    throw err
   }
 
   return response.json()
@@ -12,14 +12,26 @@ export async function playMines(bet, pick) {
   return api.post('/game/mines', { bet, pick })
 }
 
-export async function spinCase() {
-  return api.post('/game/case', {})
+export async function spinCase(cost, caseId) {
+  const body = cost ? { cost } : {}
+  if (caseId) body.case_id = caseId
+  return api.post('/game/case', body)
+}
+
+export async function getCaseInfo() {
+  return api.get('/game/case/info')
 }
 
 export async function getMyGames() {
   return api.get('/me/games')
 }
 
+export async function getMyStats(period = 'all', gameType) {
+  const params = new URLSearchParams({ period })
+  if (gameType) params.set('game_type', gameType)
+  return api.get(`/me/stats?${params}`)
+}
+
 export async function getTopUsers() {
   return api.get('/top')
 }
@@ -84,6 +96,19 @@ export async function getCoinFlipProInfo() {
   return api.get('/game/coinflip-pro/info')
 }
 
+// RPS Pro (best-of-3/5 against the bot)
+export async function startRPSPro(bet, bestOf, currency = 'gems') {
+  return api.post('/game/rps-pro/start', { bet, best_of: bestOf, currency })
+}
+
+export async function moveRPSPro(move) {
+  return api.post('/game/rps-pro/move', { move })
+}
+
+export async function getRPSProState() {
+  return api.get('/game/rps-pro/state')
+}
+
 // Leaderboard
 export async function getLeaderboard() {
   return api.get('/leaderboard')
@@ -3,8 +3,9 @@ import { Modal } from '../ui/Overlay'
 import { Button } from '../ui'
 import { useWebSocket } from '../../hooks/useWebSocket'
 
-const GRID_SIZE = 12
-const MINES_COUNT = 4
+// Defaults until the server sends the board size in "matched"
+const DEFAULT_GRID_SIZE = 12
+const DEFAULT_MINES_COUNT = 4
 const TURN_TIMEOUT = 10 // seconds
 
 export function PvPMinesGame({ user, onClose, onResult, embedded = false, initialBet = 0, initialCurrency = 'gems' }) {
@@ -16,11 +17,15 @@ export function PvPMinesGame({ user, onClose, onResult, embedded = false, initia
     result,
     roundResult,
     moveHistory,
+    board,
     connect,
     send,
     disconnect,
   } = useWebSocket('mines')
 
+  const gridSize = board?.cells || DEFAULT_GRID_SIZE
+  const minesCount = board?.mines || DEFAULT_MINES_COUNT
+
   const [phase, setPhase] = useState('connecting') // connecting, setup, playing, finished
   const [selectedMines, setSelectedMines] = useState([])
   const [setupSubmitted, setSetupSubmitted] = useState(false)
@@ -139,13 +144,13 @@ export function PvPMinesGame({ user, onClose, onResult, embedded = false, initia
     const cellNum = index + 1
     if (selectedMines.includes(cellNum)) {
       setSelectedMines(selectedMines.filter(m => m !== cellNum))
-    } else if (selectedMines.length < MINES_COUNT) {
+    } else if (selectedMines.length < minesCount) {
       setSelectedMines([...selectedMines, cellNum])
     }
   }
 
   const submitSetup = () => {
-    if (selectedMines.length !== MINES_COUNT) return
+    if (selectedMines.length !== minesCount) return
 
     send({ type: 'setup', value: selectedMines })
     setSetupSubmitted(true)
@@ -262,7 +267,7 @@ export function PvPMinesGame({ user, onClose, onResult, embedded = false, initia
           )}
           {phase === 'setup' && !setupSubmitted && (
             <div className="text-primary font-medium">
-              Place {MINES_COUNT} mines on your field ({selectedMines.length}/{MINES_COUNT})
+              Place {minesCount} mines on your field ({selectedMines.length}/{minesCount})
             </div>
           )}
           {phase === 'setup' && setupSubmitted && (
@@ -313,7 +318,7 @@ export function PvPMinesGame({ user, onClose, onResult, embedded = false, initia
           <div className="space-y-3">
             <div className="text-center text-sm text-white/60">Your field - tap to place mines</div>
             <div className="grid grid-cols-4 gap-2">
-              {Array.from({ length: GRID_SIZE }).map((_, index) => {
+              {Array.from({ length: gridSize }).map((_, index) => {
                 const cellNum = index + 1
                 const hasMine = selectedMines.includes(cellNum)
                 return (
@@ -333,10 +338,10 @@ export function PvPMinesGame({ user, onClose, onResult, embedded = false, initia
             </div>
             <Button
               onClick={submitSetup}
-              disabled={selectedMines.length !== MINES_COUNT}
+              disabled={selectedMines.length !== minesCount}
               className="w-full"
             >
-              Confirm Mines ({selectedMines.length}/{MINES_COUNT})
+              Confirm Mines ({selectedMines.length}/{minesCount})
             </Button>
           </div>
         )}
@@ -357,7 +362,7 @@ export function PvPMinesGame({ user, onClose, onResult, embedded = false, initia
               {getOpponentName()}'s field - find safe cells!
             </div>
             <div className="grid grid-cols-4 gap-2">
-              {Array.from({ length: GRID_SIZE }).map((_, index) => {
+              {Array.from({ length: gridSize }).map((_, index) => {
                 const cellNum = index + 1
                 const state = getCellState(index)
                 const isDisabled = waitingForOpponent || state !== 'unknown'
@@ -9,6 +9,8 @@ export function useWebSocket(gameType = 'rps') {
   const [result, setResult] = useState(null)
   const [roundResult, setRoundResult] = useState(null) // Last round result for Mines
   const [moveHistory, setMoveHistory] = useState([]) // History of player's moves
+  const [board, setBoard] = useState(null) // Mines board size from server: { cells, mines }
+  const [rematch, setRematch] = useState(null) // { status: waiting|offered|expired|failed, reason?, expiresIn? }
 
   const wsRef = useRef(null)
   const handlersRef = useRef({})
@@ -23,14 +25,15 @@ export function useWebSocket(gameType = 'rps') {
     let wsUrl
     const wsBase = import.meta.env.VITE_WS_URL
     if (wsBase) {
-      wsUrl = `${wsBase}/ws?token=${token}&bet=${betAmount}&game=${gameType}&currency=${currency}`
+      wsUrl = `${wsBase}/ws?bet=${betAmount}&game=${gameType}&currency=${currency}`
     } else {
       const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:'
-      wsUrl = `${protocol}//${window.location.host}/ws?token=${token}&bet=${betAmount}&game=${gameType}&currency=${currency}`
+      wsUrl = `${protocol}//${window.location.host}/ws?bet=${betAmount}&game=${gameType}&currency=${currency}`
     }
 
     setStatus('connecting')
-    const ws = new WebSocket(wsUrl)
+    // JWT идёт в Sec-WebSocket-Protocol, а не в URL (не попадает в логи)
+    const ws = new WebSocket(wsUrl, ['bearer', token])
     wsRef.current = ws
 
     ws.onopen = () => {
@@ -67,6 +70,29 @@ export function useWebSocket(gameType = 'rps') {
         setStatus('matched')
         setOpponent(payload.opponent)
         setRoomId(payload.room_id)
+        setBoard(payload.board || null)
+        // Rematch reuses the connection - clear the previous game
+        setResult(null)
+        setRoundResult(null)
+        setMoveHistory([])
+        setGameState(null)
+        setRematch(null)
+        break
+
+      case 'rematch_waiting':
+        setRematch({ status: 'waiting', expiresIn: payload.expires_in })
+        break
+
+      case 'rematch_offer':
+        setRematch({ status: 'offered', expiresIn: payload.expires_in })
+        break
+
+      case 'rematch_expired':
+        setRematch({ status: 'expired', reason: payload.reason })
+        break
+
+      case 'rematch_failed':
+        setRematch({ status: 'failed', reason: payload.reason })
         break
 
       case 'start':
@@ -106,6 +132,16 @@ export function useWebSocket(gameType = 'rps') {
         setStatus('disconnected')
         break
 
+      case 'match_aborted':
+        // Stake couldn't be held at match start - nothing was charged
+        setResult({
+          type: 'match_aborted',
+          payload,
+          message: payload.reason === 'insufficient_balance' ? 'Not enough balance for this bet' : 'Opponent could not cover the bet',
+        })
+        setStatus('disconnected')
+        break
+
       default:
         if (handlersRef.current[msg.type]) {
           handlersRef.current[msg.type](msg)
@@ -119,6 +155,13 @@ export function useWebSocket(gameType = 'rps') {
     }
   }, [])
 
+  // Ask for a rematch after the game ended; on expired/failed - reconnect to matchmaking
+  const requestRematch = useCallback(() => {
+    if (wsRef.current?.readyState === WebSocket.OPEN) {
+      wsRef.current.send(JSON.stringify({ type: 'rematch' }))
+    }
+  }, [])
+
   const disconnect = useCallback(() => {
     if (wsRef.current) {
       wsRef.current.close()
@@ -131,6 +174,8 @@ export function useWebSocket(gameType = 'rps') {
     setResult(null)
     setRoundResult(null)
     setMoveHistory([])
+    setBoard(null)
+    setRematch(null)
   }, [])
 
   const onMessage = useCallback((type, handler) => {
@@ -153,8 +198,11 @@ export function useWebSocket(gameType = 'rps') {
     result,
     roundResult,
     moveHistory,
+    board,
+    rematch,
     connect,
     send,
+    requestRematch,
     disconnect,
     onMessage,
   }
@@ -1,23 +1,34 @@
-import { useState } from 'react'
+import { useEffect, useState } from 'react'
 import { Card, CardTitle, Button } from '../components/ui'
-import { spinCase } from '../api/games'
-
-const CASE_COST = 100
-
-const PRIZES = [
-  { id: 1, amount: 250, color: 'from-gray-500 to-gray-600', rarity: 'Common' },
-  { id: 2, amount: 500, color: 'from-green-500 to-emerald-600', rarity: 'Uncommon' },
-  { id: 3, amount: 750, color: 'from-blue-500 to-indigo-600', rarity: 'Rare' },
-  { id: 4, amount: 1000, color: 'from-purple-500 to-pink-600', rarity: 'Epic' },
-  { id: 5, amount: 5000, color: 'from-yellow-400 to-orange-500', rarity: 'Legendary' },
-]
+import { spinCase, getCaseInfo } from '../api/games'
+
+// Only colors live here, cost and prizes come from /game/case/info
+const PRIZE_COLORS = {
+  1: 'from-gray-500 to-gray-600',
+  2: 'from-green-500 to-emerald-600',
+  3: 'from-blue-500 to-indigo-600',
+  4: 'from-purple-500 to-pink-600',
+  5: 'from-yellow-400 to-orange-500',
+}
 
 export function CasesPage({ user, setUser }) {
   const [spinning, setSpinning] = useState(false)
   const [result, setResult] = useState(null)
   const [error, setError] = useState(null)
+  const [caseInfo, setCaseInfo] = useState(null)
+
+  useEffect(() => {
+    getCaseInfo().then(setCaseInfo).catch(err => setError(err.message))
+  }, [])
+
+  const CASE_COST = caseInfo?.cost ?? 0
+  const PRIZES = (caseInfo?.prizes || []).map(p => ({
+    ...p,
+    color: PRIZE_COLORS[p.id] || PRIZE_COLORS[1],
+  }))
 
   const handleSpin = async () => {
+    if (!caseInfo) return
     if (!user || (user.gems || 0) < CASE_COST) {
       setError('Not enough gems')
       return
@@ -28,7 +39,7 @@ export function CasesPage({ user, setUser }) {
       setResult(null)
       setError(null)
 
-      const response = await spinCase()
+      const response = await spinCase(CASE_COST, caseInfo.case_id)
 
       // Animation delay
       setTimeout(() => {
@@ -41,10 +52,12 @@ export function CasesPage({ user, setUser }) {
     } catch (err) {
       setSpinning(false)
       setError(err.message)
+      // Цена могла измениться на сервере - подтягиваем актуальную
+      getCaseInfo().then(setCaseInfo).catch(() => {})
     }
   }
 
-  const getPrize = (id) => PRIZES.find(p => p.id === id) || PRIZES[0]
+  const getPrize = (id) => PRIZES.find(p => p.id === id) || PRIZES[0] || { color: PRIZE_COLORS[1], rarity: '' }
 
   return (
     <div className="space-y-6 animate-fadeIn">
@@ -58,10 +71,10 @@ export function CasesPage({ user, setUser }) {
 
         {result && (
           <div className="space-y-2 animate-slideUp">
-            <div className={`text-3xl font-bold bg-gradient-to-r ${getPrize(result.case_id).color} bg-clip-text text-transparent`}>
+            <div className={`text-3xl font-bold bg-gradient-to-r ${getPrize(result.prize_id).color} bg-clip-text text-transparent`}>
               +{result.prize} gems!
             </div>
-            <div className="text-white/60">{getPrize(result.case_id).rarity}</div>
+            <div className="text-white/60">{getPrize(result.prize_id).rarity}</div>
           </div>
         )}
 
@@ -77,7 +90,7 @@ export function CasesPage({ user, setUser }) {
       {/* Spin button */}
       <Button
         onClick={handleSpin}
-        disabled={spinning || (user?.gems || 0) < CASE_COST}
+        disabled={spinning || !caseInfo || (user?.gems || 0) < CASE_COST}
         size="xl"
         className="w-full"
       >
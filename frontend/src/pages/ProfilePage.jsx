@@ -303,9 +303,24 @@ export function ProfilePage({ user, games, stats, quests, fetchProfile }) {
                     <div className="font-semibold">{q.quest?.title}</div>
                     <div className="text-white/60 text-sm">{q.quest?.description}</div>
                   </div>
-                  <div className="flex items-center gap-1 bg-primary/20 text-primary px-2 py-1 rounded-lg">
-                    <span>💎</span>
-                    <span className="font-bold">{q.quest?.reward_gems}</span>
+                  <div className="flex items-center gap-1">
+                    {q.quest?.reward_gems > 0 && (
+                      <div className="flex items-center gap-1 bg-primary/20 text-primary px-2 py-1 rounded-lg">
+                        <span>💎</span>
+                        <span className="font-bold">{q.quest.reward_gems}</span>
+                      </div>
+                    )}
+                    {q.quest?.reward_coins > 0 && (
+                      <div className="flex items-center gap-1 bg-primary/20 text-primary px-2 py-1 rounded-lg">
+                        <span>🪙</span>
+                        <span className="font-bold">{q.quest.reward_coins}</span>
+                      </div>
+                    )}
+                    {q.quest?.reward_gk > 0 && (
+                      <div className="flex items-center gap-1 bg-primary/20 text-primary px-2 py-1 rounded-lg">
+                        <span className="font-bold">{q.quest.reward_gk} GK</span>
+                      </div>
+                    )}
                   </div>
                 </div>
 